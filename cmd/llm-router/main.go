@@ -6,24 +6,57 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/tributary-ai/llm-router-waf/internal/config"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
 	"github.com/tributary-ai/llm-router-waf/internal/providers/anthropic"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/bedrock"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/cohere"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/mistral"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/ollama"
 	"github.com/tributary-ai/llm-router-waf/internal/providers/openai"
 	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
 	"github.com/tributary-ai/llm-router-waf/internal/server"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
 )
 
+// Build information, injected at link time via -ldflags (see Makefile)
+var (
+	Version   = "v1.0.0"
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+// secretRefreshInterval is how often expired secret leases are re-read
+const secretRefreshInterval = 30 * time.Second
+
 // Application represents the main application
 type Application struct {
-	config *config.Config
-	router *routing.Router
-	server *server.Server
-	logger *logrus.Logger
+	configPath string
+	config     *config.Config
+	router     *routing.Router
+	server     *server.Server
+	logger     *logrus.Logger
+
+	// stopBackground cancels provider background tasks such as model discovery
+	stopBackground context.CancelFunc
+
+	// rateLimiter holds the router's per-model and per-provider limiter
+	// across reloads
+	rateLimiter *routerRateLimiter
+
+	// shutdownTracing flushes buffered spans; nil when tracing is disabled
+	shutdownTracing func(context.Context) error
+
+	// reloadMu serializes configuration reloads
+	reloadMu sync.Mutex
 }
 
 // NewApplication creates a new application instance
@@ -40,25 +73,51 @@ func NewApplication(configPath string) (*Application, error) {
 		return nil, fmt.Errorf("failed to setup logger: %w", err)
 	}
 
-	// Create router
-	routerInstance := routing.NewRouter(logger)
+	// Setup tracing
+	var shutdownTracing func(context.Context) error
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err = tracing.Init(context.Background(), cfg.Tracing.OTLPEndpoint, cfg.Tracing.ServiceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup tracing: %w", err)
+		}
+		logger.WithField("endpoint", cfg.Tracing.OTLPEndpoint).Info("OpenTelemetry tracing enabled")
+	}
 
-	// Register providers
-	if err := registerProviders(routerInstance, cfg, logger); err != nil {
-		return nil, fmt.Errorf("failed to register providers: %w", err)
+	// Create router and register providers
+	routerInstance := routing.NewRouter(logger)
+	routerInstance.SetMaxConcurrentRequests(cfg.Router.MaxConcurrentRequests)
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	rateLimiter := &routerRateLimiter{}
+	if err := configureRouter(backgroundCtx, routerInstance, rateLimiter, cfg, logger); err != nil {
+		stopBackground()
+		return nil, err
 	}
 
 	// Create server
-	serverInstance, err := server.NewServer(routerInstance, cfg.ToServerConfig(), logger)
+	serverConfig := cfg.ToServerConfig()
+	serverConfig.BuildInfo = &server.BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildTime,
+	}
+	if cfg.Security.Moderation.Enabled {
+		serverConfig.ModerationClient = openai.NewModerationClient(cfg.Providers.OpenAI, cfg.Security.Moderation.Model)
+	}
+	serverInstance, err := server.NewServer(routerInstance, serverConfig, logger)
 	if err != nil {
+		stopBackground()
 		return nil, fmt.Errorf("failed to create server: %w", err)
 	}
 
 	return &Application{
-		config: cfg,
-		router: routerInstance,
-		server: serverInstance,
-		logger: logger,
+		configPath:      configPath,
+		config:          cfg,
+		router:          routerInstance,
+		server:          serverInstance,
+		logger:          logger,
+		stopBackground:  stopBackground,
+		rateLimiter:     rateLimiter,
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
@@ -69,10 +128,13 @@ func (app *Application) Run() error {
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	defer func() { app.stopBackground() }() // replaced on reload
 
-	// Setup signal handling for graceful shutdown
+	// Setup signal handling for graceful shutdown and config reload
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
 	// Start server in a goroutine
 	serverErrors := make(chan error, 1)
@@ -83,12 +145,36 @@ func (app *Application) Run() error {
 		}
 	}()
 
-	// Wait for shutdown signal or server error
-	select {
-	case err := <-serverErrors:
-		return fmt.Errorf("server error: %w", err)
-	case sig := <-sigChan:
-		app.logger.WithField("signal", sig.String()).Info("Shutdown signal received")
+	// Re-read expired secret leases, reloading when a value rotates
+	secretRefresh := time.NewTicker(secretRefreshInterval)
+	defer secretRefresh.Stop()
+
+	// Wait for shutdown signal or server error, reloading on SIGHUP
+wait:
+	for {
+		select {
+		case err := <-serverErrors:
+			return fmt.Errorf("server error: %w", err)
+		case <-secretRefresh.C:
+			changed, err := config.Secrets.Refresh(ctx)
+			if err != nil {
+				app.logger.WithError(err).Warn("Failed to refresh secrets, keeping previous values")
+			}
+			if changed {
+				app.logger.Info("Secrets rotated, reloading configuration")
+				if err := app.Reload(); err != nil {
+					app.logger.WithError(err).Error("Configuration reload failed, keeping current configuration")
+				}
+			}
+		case <-reloadChan:
+			app.logger.Info("Reload signal received")
+			if err := app.Reload(); err != nil {
+				app.logger.WithError(err).Error("Configuration reload failed, keeping current configuration")
+			}
+		case sig := <-sigChan:
+			app.logger.WithField("signal", sig.String()).Info("Shutdown signal received")
+			break wait
+		}
 	}
 
 	// Graceful shutdown
@@ -104,10 +190,73 @@ func (app *Application) Run() error {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	// Flush remaining spans
+	if app.shutdownTracing != nil {
+		if err := app.shutdownTracing(shutdownCtx); err != nil {
+			app.logger.WithError(err).Warn("Tracing shutdown error")
+		}
+	}
+
 	app.logger.Info("Graceful shutdown completed")
 	return nil
 }
 
+// Reload re-reads the configuration file and swaps provider model lists,
+// pricing, API keys, routing strategy and per-model/per-provider rate limits
+// into the running router. In-flight requests finish on the providers they
+// were routed to. If the new configuration fails validation or its providers
+// can't be built, the current configuration stays in place.
+func (app *Application) Reload() error {
+	app.reloadMu.Lock()
+	defer app.reloadMu.Unlock()
+
+	cfg, err := app.config.Reload(app.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	if err := configureRouter(backgroundCtx, app.router, app.rateLimiter, cfg, app.logger); err != nil {
+		stopBackground()
+		return err
+	}
+	app.stopBackground()
+	app.stopBackground = stopBackground
+
+	warnRestartRequired(app.config, cfg, app.logger)
+	app.config = cfg
+	app.logger.Info("Configuration reloaded")
+	return nil
+}
+
+// warnRestartRequired logs configuration changes that a reload can't apply
+func warnRestartRequired(old, new *config.Config, logger *logrus.Logger) {
+	// Per-model and per-provider limits live in the router and do reload
+	oldSecurity, newSecurity := old.Security, new.Security
+	oldSecurity.RateLimiting.PerModelLimits, oldSecurity.RateLimiting.PerProviderLimits = nil, nil
+	newSecurity.RateLimiting.PerModelLimits, newSecurity.RateLimiting.PerProviderLimits = nil, nil
+
+	sections := map[string][2]interface{}{
+		"server":                         {old.Server, new.Server},
+		"logging":                        {old.Logging, new.Logging},
+		"security":                       {oldSecurity, newSecurity},
+		"cache":                          {old.Cache, new.Cache},
+		"idempotency":                    {old.Idempotency, new.Idempotency},
+		"privacy":                        {old.Privacy, new.Privacy},
+		"accounting":                     {old.Accounting, new.Accounting},
+		"tracing":                        {old.Tracing, new.Tracing},
+		"alerts":                         {old.Alerts, new.Alerts},
+		"router.request_timeout":         {old.Router.RequestTimeout, new.Router.RequestTimeout},
+		"router.max_concurrent_requests": {old.Router.MaxConcurrentRequests, new.Router.MaxConcurrentRequests},
+		"router.allowed_models":          {old.Router.AllowedModels, new.Router.AllowedModels},
+	}
+	for name, values := range sections {
+		if !reflect.DeepEqual(values[0], values[1]) {
+			logger.WithField("section", name).Warn("Configuration change requires a restart to take effect")
+		}
+	}
+}
+
 // setupLogger configures the logger based on configuration
 func setupLogger(logger *logrus.Logger, config config.LoggingConfig) error {
 	// Set log level
@@ -150,38 +299,181 @@ func setupLogger(logger *logrus.Logger, config config.LoggingConfig) error {
 	return nil
 }
 
-// registerProviders registers all configured providers with the router
-func registerProviders(router *routing.Router, cfg *config.Config, logger *logrus.Logger) error {
-	providersRegistered := 0
+// configureRouter builds the configured providers and routing settings and
+// swaps them into the router in one step. Provider background tasks run
+// until ctx is cancelled. The rate limiter is taken from limiter, which
+// keeps it while its configuration is unchanged.
+func configureRouter(ctx context.Context, router *routing.Router, limiter *routerRateLimiter, cfg *config.Config, logger *logrus.Logger) error {
+	registered, err := buildProviders(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to register providers: %w", err)
+	}
+	requestTransformers, err := routing.RequestTransformersByName(cfg.Router.RequestTransformers)
+	if err != nil {
+		return err
+	}
+
+	settings := routing.Settings{
+		DefaultStrategy:  routing.RoutingStrategy(cfg.Router.DefaultStrategy),
+		Weights:          cfg.Router.Weights,
+		ModelEquivalents: cfg.Router.ModelEquivalents,
+		ModelAliases:     cfg.Router.Aliases,
+		ModelPrefixes:    cfg.Router.ModelPrefixMap,
+		ModelOverrides:   cfg.Router.ModelOverrides,
+		MaxCostThreshold: cfg.Router.MaxCostThreshold,
+		BalancedAlpha:    cfg.Router.BalancedAlpha,
+		PerformanceMetric: routing.PerformanceMetric(cfg.Router.PerformanceMetric),
+		HealthCheckInterval: cfg.Router.HealthCheckInterval,
+		HealthCheckTimeout:  cfg.Router.HealthCheckTimeout,
+		HealthCheckConcurrency: cfg.Router.HealthCheckConcurrency,
+		HealthCachePath:     cfg.Router.HealthCachePath,
+		ProviderConcurrency: cfg.Router.ProviderConcurrency,
+		Shadow:              cfg.Router.Shadow,
+		DefaultRetry:        cfg.Router.DefaultRetry,
+		RetryBudgetRatio:    cfg.Router.RetryBudgetRatio,
+		Guardrail:           cfg.Guardrail,
+		RequestTransformers: requestTransformers,
+	}
+	rateLimiter, release, err := limiter.update(cfg.ToSecurityMiddlewareConfig().RateLimit, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create router rate limiter: %w", err)
+	}
+	settings.RateLimiter = rateLimiter
+
+	router.Reload(settings, registered)
+	release()
+	return nil
+}
+
+// routerRateLimiter owns the limiter behind the router's per-model and
+// per-provider limits. Reloads that leave its configuration unchanged, such
+// as secret rotations, keep the limiter and the budgets it has counted.
+type routerRateLimiter struct {
+	config  *security.RateLimitConfig // nil without resource limits
+	limiter security.RateLimiter
+}
+
+// update returns the limiter for config, nil when config sets no per-model
+// or per-provider limits, creating one only when config changed. The
+// returned release stops a replaced limiter; call it once the router no
+// longer uses that limiter.
+func (l *routerRateLimiter) update(config *security.RateLimitConfig, logger *logrus.Logger) (security.RateLimiter, func(), error) {
+	if config.HasResourceLimits() {
+		enabled := *config
+		enabled.Enabled = true
+		config = &enabled
+	} else {
+		config = nil
+	}
+	if reflect.DeepEqual(config, l.config) {
+		return l.limiter, func() {}, nil
+	}
+
+	var limiter security.RateLimiter
+	if config != nil {
+		var err error
+		if limiter, err = security.NewRateLimiter(config, logger); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	replaced := l.limiter
+	l.config, l.limiter = config, limiter
+	release := func() {
+		if stopper, ok := replaced.(interface{ Stop() }); ok {
+			stopper.Stop()
+		}
+	}
+	return limiter, release, nil
+}
+
+// buildProviders creates all configured providers
+func buildProviders(ctx context.Context, cfg *config.Config, logger *logrus.Logger) ([]routing.NamedProvider, error) {
+	var registered []routing.NamedProvider
+
+	// Every provider sends its requests through one client, so proxy and
+	// TLS settings apply everywhere and connections are pooled together
+	httpClient, err := providers.NewHTTPClient(&cfg.Providers.HTTPClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider HTTP client: %w", err)
+	}
 
 	// Register OpenAI provider if configured
-	if cfg.Providers.OpenAI != nil && cfg.Providers.OpenAI.APIKey != "" {
+	if cfg.Providers.OpenAI != nil && len(cfg.Providers.OpenAI.Keys()) > 0 {
+		cfg.Providers.OpenAI.HTTPClient = httpClient
 		openaiProvider := openai.NewOpenAIProvider(cfg.Providers.OpenAI, logger)
-		router.RegisterProvider("openai", openaiProvider)
+		openaiProvider.StartDiscovery(ctx)
+		registered = append(registered, routing.NamedProvider{Name: "openai", Provider: openaiProvider})
 		logger.WithFields(logrus.Fields{
 			"provider": "openai",
-			"models":   len(cfg.Providers.OpenAI.Models),
+			"models":   len(openaiProvider.GetCapabilities().SupportedModels),
 		}).Info("OpenAI provider registered")
-		providersRegistered++
 	}
 
 	// Register Anthropic provider if configured
 	if cfg.Providers.Anthropic != nil && cfg.Providers.Anthropic.APIKey != "" {
+		cfg.Providers.Anthropic.HTTPClient = httpClient
 		anthropicProvider := anthropic.NewAnthropicProvider(cfg.Providers.Anthropic, logger)
-		router.RegisterProvider("anthropic", anthropicProvider)
+		registered = append(registered, routing.NamedProvider{Name: "anthropic", Provider: anthropicProvider})
 		logger.WithFields(logrus.Fields{
 			"provider": "anthropic",
 			"models":   len(cfg.Providers.Anthropic.Models),
 		}).Info("Anthropic provider registered")
-		providersRegistered++
 	}
 
-	if providersRegistered == 0 {
-		return fmt.Errorf("no providers were registered - check your configuration and API keys")
+	// Register Cohere provider if configured
+	if cfg.Providers.Cohere != nil && cfg.Providers.Cohere.APIKey != "" {
+		cfg.Providers.Cohere.HTTPClient = httpClient
+		cohereProvider := cohere.NewCohereProvider(cfg.Providers.Cohere, logger)
+		registered = append(registered, routing.NamedProvider{Name: "cohere", Provider: cohereProvider})
+		logger.WithFields(logrus.Fields{
+			"provider": "cohere",
+			"models":   len(cfg.Providers.Cohere.Models),
+		}).Info("Cohere provider registered")
 	}
 
-	logger.WithField("count", providersRegistered).Info("Provider registration completed")
-	return nil
+	// Register Mistral provider if configured
+	if cfg.Providers.Mistral != nil && cfg.Providers.Mistral.APIKey != "" {
+		cfg.Providers.Mistral.HTTPClient = httpClient
+		mistralProvider := mistral.NewMistralProvider(cfg.Providers.Mistral, logger)
+		registered = append(registered, routing.NamedProvider{Name: "mistral", Provider: mistralProvider})
+		logger.WithFields(logrus.Fields{
+			"provider": "mistral",
+			"models":   len(cfg.Providers.Mistral.Models),
+		}).Info("Mistral provider registered")
+	}
+
+	// Register Ollama provider if configured; it needs no API key
+	if cfg.Providers.Ollama != nil {
+		cfg.Providers.Ollama.HTTPClient = httpClient
+		ollamaProvider := ollama.NewOllamaProvider(cfg.Providers.Ollama, logger)
+		registered = append(registered, routing.NamedProvider{Name: "ollama", Provider: ollamaProvider})
+		logger.WithFields(logrus.Fields{
+			"provider": "ollama",
+			"models":   len(cfg.Providers.Ollama.Models),
+		}).Info("Ollama provider registered")
+	}
+
+	// Register Bedrock provider if configured; credentials come from the AWS chain
+	if cfg.Providers.Bedrock != nil {
+		cfg.Providers.Bedrock.HTTPClient = httpClient
+		bedrockProvider, err := bedrock.NewBedrockProvider(ctx, cfg.Providers.Bedrock, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Bedrock provider: %w", err)
+		}
+		registered = append(registered, routing.NamedProvider{Name: "bedrock", Provider: bedrockProvider})
+		logger.WithFields(logrus.Fields{
+			"provider": "bedrock",
+			"models":   len(cfg.Providers.Bedrock.Models),
+		}).Info("Bedrock provider registered")
+	}
+
+	if len(registered) == 0 {
+		return nil, fmt.Errorf("no providers were registered - check your configuration and API keys")
+	}
+
+	logger.WithField("count", len(registered)).Info("Provider registration completed")
+	return registered, nil
 }
 
 // printUsage prints application usage information
@@ -192,6 +484,10 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
 	fmt.Fprintf(os.Stderr, "  OPENAI_API_KEY         OpenAI API key\n")
 	fmt.Fprintf(os.Stderr, "  ANTHROPIC_API_KEY      Anthropic API key\n")
+	fmt.Fprintf(os.Stderr, "  COHERE_API_KEY         Cohere API key\n")
+	fmt.Fprintf(os.Stderr, "  MISTRAL_API_KEY        Mistral API key\n")
+	fmt.Fprintf(os.Stderr, "  OLLAMA_BASE_URL        Ollama OpenAI-compatible URL (requires providers.ollama)\n")
+	fmt.Fprintf(os.Stderr, "  AWS_REGION, AWS_PROFILE  AWS settings for Bedrock (requires providers.bedrock)\n")
 	fmt.Fprintf(os.Stderr, "  LLM_ROUTER_PORT        Server port (default: 8080)\n")
 	fmt.Fprintf(os.Stderr, "  LLM_ROUTER_LOG_LEVEL   Log level (debug,info,warn,error,fatal)\n")
 	fmt.Fprintf(os.Stderr, "  LLM_ROUTER_LOG_FORMAT  Log format (json,text)\n")
@@ -218,8 +514,9 @@ func main() {
 
 	// Show version if requested
 	if *version {
-		fmt.Printf("LLM Router WAF v1.0.0\n")
-		fmt.Printf("Build Date: %s\n", time.Now().Format("2006-01-02"))
+		fmt.Printf("LLM Router WAF %s\n", Version)
+		fmt.Printf("Git Commit: %s\n", GitCommit)
+		fmt.Printf("Build Date: %s\n", BuildTime)
 		os.Exit(0)
 	}
 
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// writePricedConfig writes a config with a single OpenAI model at the given
+// input price per 1K tokens
+func writePricedConfig(t *testing.T, path, baseURL string, inputCost float64) {
+	content := fmt.Sprintf(`
+logging:
+  level: "error"
+  format: "text"
+  output: "stderr"
+
+providers:
+  openai:
+    api_key: "test-openai-key"
+    base_url: %q
+    models:
+      - name: "gpt-4o"
+        provider_model_id: "gpt-4o"
+        input_cost_per_1k: %g
+        output_cost_per_1k: 0.015
+        max_context_window: 128000
+        max_output_tokens: 4096
+`, baseURL, inputCost)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func routedCost(t *testing.T, app *Application) float64 {
+	req := &types.ChatRequest{
+		ID:        "reload-request",
+		Model:     "gpt-4o",
+		Messages:  []types.Message{{Role: "user", Content: "Hello"}},
+		MaxTokens: intPtr(100),
+	}
+	metadata, _, err := app.router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	return metadata.EstimatedCost
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestApplication_ReloadPricing(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEYS", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	// Answer health checks locally
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writePricedConfig(t, path, upstream.URL, 0.005)
+
+	app, err := NewApplication(path)
+	if err != nil {
+		t.Fatalf("NewApplication failed: %v", err)
+	}
+	defer app.stopBackground()
+
+	before := routedCost(t, app)
+
+	writePricedConfig(t, path, upstream.URL, 0.5)
+	if err := app.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	after := routedCost(t, app)
+	if after <= before {
+		t.Errorf("Expected routing cost to rise after repricing, got %f before and %f after", before, after)
+	}
+	if app.config.Providers.OpenAI.Models[0].InputCostPer1K != 0.5 {
+		t.Errorf("Expected reloaded config to be applied, got input cost %f", app.config.Providers.OpenAI.Models[0].InputCostPer1K)
+	}
+
+	// An invalid file keeps the current configuration
+	if err := os.WriteFile(path, []byte("router:\n  default_strategy: \"fastest\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := app.Reload(); err == nil {
+		t.Error("Expected reload of invalid config to fail")
+	}
+	if cost := routedCost(t, app); cost != after {
+		t.Errorf("Expected routing to keep previous pricing after failed reload, got %f want %f", cost, after)
+	}
+}
+
+// stoppableLimiter records whether it was stopped
+type stoppableLimiter struct {
+	security.RateLimiter
+	stopped bool
+}
+
+func (l *stoppableLimiter) Stop() { l.stopped = true }
+
+func TestRouterRateLimiter_Update(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	limits := func(rpm int) *security.RateLimitConfig {
+		return &security.RateLimitConfig{PerModelLimits: map[string]int{"gpt-4o": rpm}}
+	}
+
+	current := &stoppableLimiter{}
+	enabled := *limits(60)
+	enabled.Enabled = true
+	holder := &routerRateLimiter{config: &enabled, limiter: current}
+
+	// An unchanged configuration, e.g. on a secret rotation, keeps the limiter
+	limiter, release, err := holder.update(limits(60), logger)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	release()
+	if limiter != current || current.stopped {
+		t.Errorf("Expected the current limiter to be kept, got %v (stopped %v)", limiter, current.stopped)
+	}
+
+	// A changed one replaces it, stopping the old limiter on release
+	limiter, release, err = holder.update(limits(120), logger)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if limiter == nil || limiter == security.RateLimiter(current) {
+		t.Fatalf("Expected a new limiter, got %v", limiter)
+	}
+	if current.stopped {
+		t.Error("Expected the old limiter to run until released")
+	}
+	release()
+	if !current.stopped {
+		t.Error("Expected the replaced limiter to be stopped")
+	}
+
+	// Removing every resource limit drops the limiter
+	limiter, release, err = holder.update(&security.RateLimitConfig{}, logger)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	release()
+	if limiter != nil {
+		t.Errorf("Expected no limiter without resource limits, got %v", limiter)
+	}
+}
@@ -0,0 +1,112 @@
+// Package accounting records per-request token usage and cost for billing.
+package accounting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// UsageRecord is the accounting entry written for each completed request
+type UsageRecord struct {
+	RequestID        string    `json:"request_id"`
+	UserID           string    `json:"user_id,omitempty"`
+	CallerID         string    `json:"caller_id,omitempty"` // authenticated user whose credentials made the request
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	Timestamp        time.Time `json:"timestamp"`
+
+	Tags map[string]string `json:"tags,omitempty"` // request tags, e.g. tenant_id for billing
+}
+
+// UsageRecorder persists usage records
+type UsageRecorder interface {
+	Record(record UsageRecord) error
+	Close() error
+}
+
+// Config holds accounting sink configuration
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"` // JSONL file records are appended to
+
+	// Records kept in memory for /v1/usage reports, defaults to 100000
+	MaxRecords int `yaml:"max_records"`
+
+	// Limit /v1/usage to requests made with the caller's own credentials
+	ScopeToCaller bool `yaml:"scope_to_caller"`
+
+	// Daily and monthly USD caps by user ID or "scope:<key scope>"
+	SpendLimits SpendLimits `yaml:"spend_limits"`
+}
+
+// WriterRecorder writes usage records as JSON lines to an io.Writer
+type WriterRecorder struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	encoder *json.Encoder
+}
+
+// NewWriterRecorder creates a recorder writing JSON lines to w. If w is an
+// io.Closer it is closed by Close.
+func NewWriterRecorder(w io.Writer) *WriterRecorder {
+	return &WriterRecorder{
+		writer:  w,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// NewFileRecorder creates a recorder appending JSON lines to the file at path
+func NewFileRecorder(path string) (*WriterRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounting file: %w", err)
+	}
+	return NewWriterRecorder(file), nil
+}
+
+// Record writes a single usage record
+func (w *WriterRecorder) Record(record UsageRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying writer when it supports closing
+func (w *WriterRecorder) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Cost returns the actual cost of usage at the configured per-1K pricing of
+// model. The second return value is false when the model has no pricing.
+func Cost(capabilities types.ProviderCapabilities, model string, usage *types.Usage) (float64, bool) {
+	if usage == nil {
+		return 0, false
+	}
+	for _, info := range capabilities.SupportedModels {
+		if info.Name == model || info.ProviderModelID == model {
+			inputCost := float64(usage.PromptTokens) * info.InputCostPer1K / 1000
+			outputCost := float64(usage.CompletionTokens) * info.OutputCostPer1K / 1000
+			return inputCost + outputCost, true
+		}
+	}
+	return 0, false
+}
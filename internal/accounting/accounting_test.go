@@ -0,0 +1,73 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestWriterRecorder_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewWriterRecorder(&buf)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, recorder.Record(UsageRecord{RequestID: "req-1", Provider: "openai", Model: "gpt-4o", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.1, Timestamp: now}))
+	require.NoError(t, recorder.Record(UsageRecord{RequestID: "req-2", Provider: "anthropic", Model: "claude", Timestamp: now}))
+	require.NoError(t, recorder.Close())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first UsageRecord
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Equal(t, 10, first.PromptTokens)
+	assert.Equal(t, 0.1, first.CostUSD)
+	assert.True(t, now.Equal(first.Timestamp))
+}
+
+func TestFileRecorder_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+
+	for _, id := range []string{"req-1", "req-2"} {
+		recorder, err := NewFileRecorder(path)
+		require.NoError(t, err)
+		require.NoError(t, recorder.Record(UsageRecord{RequestID: id}))
+		require.NoError(t, recorder.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(data, []byte("\n")))
+}
+
+func TestCost(t *testing.T) {
+	capabilities := types.ProviderCapabilities{
+		SupportedModels: []types.ModelInfo{
+			{Name: "gpt-4o", ProviderModelID: "gpt-4o-2024-08-06", InputCostPer1K: 0.005, OutputCostPer1K: 0.015},
+		},
+	}
+	usage := &types.Usage{PromptTokens: 1000, CompletionTokens: 2000}
+
+	cost, ok := Cost(capabilities, "gpt-4o", usage)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.035, cost, 1e-12)
+
+	cost, ok = Cost(capabilities, "gpt-4o-2024-08-06", usage)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.035, cost, 1e-12)
+
+	_, ok = Cost(capabilities, "unknown", usage)
+	assert.False(t, ok)
+
+	_, ok = Cost(capabilities, "gpt-4o", nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,134 @@
+package accounting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpendScopePrefix marks a SpendLimits key as an API key scope rather than a
+// user ID: "scope:models:gpt-4o" caps every caller whose key carries the
+// "models:gpt-4o" permission.
+const SpendScopePrefix = "scope:"
+
+// Spend limit periods
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// SpendLimit caps cumulative USD spend per UTC calendar day and month. A zero
+// cap leaves that period unlimited.
+type SpendLimit struct {
+	DailyUSD   float64 `yaml:"daily_usd"`
+	MonthlyUSD float64 `yaml:"monthly_usd"`
+}
+
+// SpendLimits maps user IDs, or SpendScopePrefix plus a key scope, to caps
+type SpendLimits map[string]SpendLimit
+
+// Validate checks that caps are not negative
+func (l SpendLimits) Validate() error {
+	for key, limit := range l {
+		if strings.TrimPrefix(key, SpendScopePrefix) == "" {
+			return fmt.Errorf("spend limit key %q is empty", key)
+		}
+		if limit.DailyUSD < 0 || limit.MonthlyUSD < 0 {
+			return fmt.Errorf("spend limit for %s must not be negative", key)
+		}
+	}
+	return nil
+}
+
+// SpendLimitError is returned when a user has reached a spend cap
+type SpendLimitError struct {
+	Subject  string // user ID or caller the cap applies to
+	Period   string // PeriodDaily or PeriodMonthly
+	LimitUSD float64
+	SpentUSD float64
+	ResetAt  time.Time
+}
+
+func (e *SpendLimitError) Error() string {
+	return fmt.Sprintf("%s spend limit of $%.2f reached for %s ($%.2f spent); resets at %s",
+		e.Period, e.LimitUSD, e.Subject, e.SpentUSD, e.ResetAt.Format(time.RFC3339))
+}
+
+// SpendLimiter enforces SpendLimits against the cumulative cost in a
+// UsageStore
+type SpendLimiter struct {
+	store  UsageStore
+	limits SpendLimits
+}
+
+// NewSpendLimiter creates a limiter reading spend from store
+func NewSpendLimiter(store UsageStore, limits SpendLimits) *SpendLimiter {
+	return &SpendLimiter{store: store, limits: limits}
+}
+
+// Check returns a SpendLimitError when the request's user has reached a cap
+// at now. userID is the request's user, callerID and permissions describe
+// the authenticated credentials. A cap on the user ID is counted against
+// that user's requests; caps on the caller or one of its scopes are counted
+// against requests made with the caller's credentials.
+func (l *SpendLimiter) Check(userID, callerID string, permissions []string, now time.Time) error {
+	limit, query, ok := l.resolve(userID, callerID, permissions)
+	if !ok {
+		return nil
+	}
+
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	periods := []struct {
+		name  string
+		cap   float64
+		start time.Time
+		reset time.Time
+	}{
+		{PeriodDaily, limit.DailyUSD, day, day.AddDate(0, 0, 1)},
+		{PeriodMonthly, limit.MonthlyUSD, month, month.AddDate(0, 1, 0)},
+	}
+	for _, period := range periods {
+		if period.cap <= 0 {
+			continue
+		}
+		query.From, query.To = period.start, period.reset
+		report, err := l.store.Query(query)
+		if err != nil {
+			return fmt.Errorf("failed to query spend: %w", err)
+		}
+		if report.Totals.CostUSD >= period.cap {
+			return &SpendLimitError{
+				Subject:  query.UserID + query.CallerID,
+				Period:   period.name,
+				LimitUSD: period.cap,
+				SpentUSD: report.Totals.CostUSD,
+				ResetAt:  period.reset,
+			}
+		}
+	}
+	return nil
+}
+
+// resolve finds the cap for a request and the query selecting the spend it
+// is counted against: the user ID first, then the caller, then the first
+// capped scope of the caller's key
+func (l *SpendLimiter) resolve(userID, callerID string, permissions []string) (SpendLimit, UsageQuery, bool) {
+	if limit, ok := l.limits[userID]; ok && userID != "" {
+		return limit, UsageQuery{UserID: userID}, true
+	}
+	if callerID == "" {
+		return SpendLimit{}, UsageQuery{}, false
+	}
+	if limit, ok := l.limits[callerID]; ok {
+		return limit, UsageQuery{CallerID: callerID}, true
+	}
+	for _, permission := range permissions {
+		if limit, ok := l.limits[SpendScopePrefix+permission]; ok {
+			return limit, UsageQuery{CallerID: callerID}, true
+		}
+	}
+	return SpendLimit{}, UsageQuery{}, false
+}
@@ -0,0 +1,228 @@
+package accounting
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRecords bounds how many records an in-memory store keeps
+const DefaultMaxRecords = 100000
+
+// Usage report groupings
+const (
+	GroupByProvider = "provider"
+	GroupByModel    = "model"
+)
+
+// UsageQuery selects the records a usage report covers. Zero From or To
+// leaves that end of the window open; the window includes From and
+// excludes To.
+type UsageQuery struct {
+	UserID   string
+	CallerID string // only requests made with these credentials, when set
+	From     time.Time
+	To       time.Time
+	GroupBy  string // "", GroupByProvider or GroupByModel
+}
+
+// UsageTotals aggregates a set of usage records
+type UsageTotals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// UsageGroup is the totals for one provider or model
+type UsageGroup struct {
+	Key string `json:"key"`
+	UsageTotals
+}
+
+// UsageReport is the result of a usage query
+type UsageReport struct {
+	UserID  string       `json:"user_id,omitempty"`
+	From    *time.Time   `json:"from,omitempty"`
+	To      *time.Time   `json:"to,omitempty"`
+	GroupBy string       `json:"group_by,omitempty"`
+	Totals  UsageTotals  `json:"totals"`
+	Groups  []UsageGroup `json:"groups,omitempty"`
+}
+
+// UsageStore records usage and answers aggregate queries over it
+type UsageStore interface {
+	UsageRecorder
+	Query(query UsageQuery) (*UsageReport, error)
+}
+
+// MemoryStore keeps recent usage records in memory for reporting, dropping
+// the oldest once MaxRecords is reached
+type MemoryStore struct {
+	mu         sync.RWMutex
+	records    []UsageRecord
+	maxRecords int
+}
+
+// NewMemoryStore creates an in-memory usage store. maxRecords <= 0 uses
+// DefaultMaxRecords.
+func NewMemoryStore(maxRecords int) *MemoryStore {
+	if maxRecords <= 0 {
+		maxRecords = DefaultMaxRecords
+	}
+	return &MemoryStore{maxRecords: maxRecords}
+}
+
+// Load adds the JSON-line records read from r, such as an accounting file
+// written by a WriterRecorder, so reports cover usage from before a restart
+func (m *MemoryStore) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("invalid usage record: %w", err)
+		}
+		m.Record(record)
+	}
+	return scanner.Err()
+}
+
+// Record adds a usage record
+func (m *MemoryStore) Record(record UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.records) >= m.maxRecords {
+		// Drop the oldest tenth at once rather than shifting on every record
+		drop := max(m.maxRecords/10, 1)
+		m.records = append(m.records[:0], m.records[drop:]...)
+	}
+	m.records = append(m.records, record)
+	return nil
+}
+
+// Close implements UsageRecorder
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// Query aggregates the records matching query. Groups are sorted by cost,
+// highest first.
+func (m *MemoryStore) Query(query UsageQuery) (*UsageReport, error) {
+	if query.GroupBy != "" && query.GroupBy != GroupByProvider && query.GroupBy != GroupByModel {
+		return nil, fmt.Errorf("group_by must be %q or %q", GroupByProvider, GroupByModel)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, errors.New("from must be before to")
+	}
+
+	report := &UsageReport{UserID: query.UserID, GroupBy: query.GroupBy}
+	if !query.From.IsZero() {
+		report.From = &query.From
+	}
+	if !query.To.IsZero() {
+		report.To = &query.To
+	}
+
+	groups := make(map[string]*UsageGroup)
+	m.mu.RLock()
+	for _, record := range m.records {
+		if !query.matches(record) {
+			continue
+		}
+		report.Totals.add(record)
+
+		key := ""
+		switch query.GroupBy {
+		case GroupByProvider:
+			key = record.Provider
+		case GroupByModel:
+			key = record.Model
+		default:
+			continue
+		}
+		group, exists := groups[key]
+		if !exists {
+			group = &UsageGroup{Key: key}
+			groups[key] = group
+		}
+		group.add(record)
+	}
+	m.mu.RUnlock()
+
+	if query.GroupBy != "" {
+		report.Groups = make([]UsageGroup, 0, len(groups))
+		for _, group := range groups {
+			report.Groups = append(report.Groups, *group)
+		}
+		sort.Slice(report.Groups, func(i, j int) bool {
+			if report.Groups[i].CostUSD != report.Groups[j].CostUSD {
+				return report.Groups[i].CostUSD > report.Groups[j].CostUSD
+			}
+			return report.Groups[i].Key < report.Groups[j].Key
+		})
+	}
+	return report, nil
+}
+
+// matches reports whether a record falls in the query's user and window
+func (q UsageQuery) matches(record UsageRecord) bool {
+	if q.UserID != "" && record.UserID != q.UserID {
+		return false
+	}
+	if q.CallerID != "" && record.CallerID != q.CallerID {
+		return false
+	}
+	if !q.From.IsZero() && record.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !record.Timestamp.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// add folds a record into the totals
+func (t *UsageTotals) add(record UsageRecord) {
+	t.Requests++
+	t.PromptTokens += record.PromptTokens
+	t.CompletionTokens += record.CompletionTokens
+	t.TotalTokens += record.PromptTokens + record.CompletionTokens
+	t.CostUSD += record.CostUSD
+}
+
+// MultiRecorder writes each record to every recorder, such as a JSONL file
+// and a MemoryStore
+type MultiRecorder []UsageRecorder
+
+// Record writes the record to each recorder, returning their joined errors
+func (m MultiRecorder) Record(record UsageRecord) error {
+	var errs []error
+	for _, recorder := range m {
+		if err := recorder.Record(record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes each recorder, returning their joined errors
+func (m MultiRecorder) Close() error {
+	var errs []error
+	for _, recorder := range m {
+		if err := recorder.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package accounting
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedStore(t *testing.T) *MemoryStore {
+	store := NewMemoryStore(0)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	records := []UsageRecord{
+		{RequestID: "1", UserID: "alice", Provider: "openai", Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 50, CostUSD: 0.01, Timestamp: day.Add(time.Hour)},
+		{RequestID: "2", UserID: "alice", Provider: "anthropic", Model: "claude-3-5-sonnet", PromptTokens: 200, CompletionTokens: 100, CostUSD: 0.03, Timestamp: day.Add(2 * time.Hour)},
+		{RequestID: "3", UserID: "bob", Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.001, Timestamp: day.Add(24 * time.Hour)},
+		{RequestID: "4", UserID: "alice", Provider: "openai", Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 50, CostUSD: 0.01, Timestamp: day.Add(48 * time.Hour)},
+	}
+	for _, record := range records {
+		require.NoError(t, store.Record(record))
+	}
+	return store
+}
+
+func TestMemoryStore_GroupByProvider(t *testing.T) {
+	store := seedStore(t)
+
+	report, err := store.Query(UsageQuery{GroupBy: GroupByProvider})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Totals.Requests)
+	assert.Equal(t, 615, report.Totals.TotalTokens)
+	assert.InDelta(t, 0.051, report.Totals.CostUSD, 1e-12)
+
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "anthropic", report.Groups[0].Key) // most expensive first
+	assert.Equal(t, 1, report.Groups[0].Requests)
+	assert.Equal(t, "openai", report.Groups[1].Key)
+	assert.Equal(t, 3, report.Groups[1].Requests)
+	assert.Equal(t, 210, report.Groups[1].PromptTokens)
+	assert.Equal(t, 105, report.Groups[1].CompletionTokens)
+	assert.InDelta(t, 0.021, report.Groups[1].CostUSD, 1e-12)
+
+	report, err = store.Query(UsageQuery{UserID: "alice", GroupBy: GroupByModel})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Totals.Requests)
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "claude-3-5-sonnet", report.Groups[0].Key)
+	assert.Equal(t, "gpt-4o", report.Groups[1].Key)
+	assert.Equal(t, 2, report.Groups[1].Requests)
+
+	_, err = store.Query(UsageQuery{GroupBy: "user"})
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_DateRange(t *testing.T) {
+	store := seedStore(t)
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// The window includes from and excludes to
+	report, err := store.Query(UsageQuery{From: day, To: day.Add(24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Totals.Requests)
+	assert.Empty(t, report.Groups)
+
+	report, err = store.Query(UsageQuery{From: day.Add(24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Totals.Requests)
+
+	report, err = store.Query(UsageQuery{UserID: "alice", To: day.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Totals.Requests)
+
+	_, err = store.Query(UsageQuery{From: day, To: day})
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_LoadAndLimit(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewWriterRecorder(&buf)
+	for i := 0; i < 25; i++ {
+		require.NoError(t, recorder.Record(UsageRecord{Provider: "openai", PromptTokens: 1, Timestamp: time.Now()}))
+	}
+
+	store := NewMemoryStore(20)
+	require.NoError(t, store.Load(&buf))
+
+	report, err := store.Query(UsageQuery{})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, report.Totals.Requests, 20)
+	assert.Greater(t, report.Totals.Requests, 0)
+
+	assert.Error(t, store.Load(bytes.NewBufferString("not json\n")))
+}
+
+func TestMultiRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	store := NewMemoryStore(0)
+	recorder := MultiRecorder{NewWriterRecorder(&buf), store}
+
+	require.NoError(t, recorder.Record(UsageRecord{RequestID: "req-1", Provider: "openai"}))
+	require.NoError(t, recorder.Close())
+
+	assert.Contains(t, buf.String(), `"request_id":"req-1"`)
+	report, err := store.Query(UsageQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Totals.Requests)
+}
+
+func TestSpendLimiter_MonthlyResetAndScopes(t *testing.T) {
+	store := NewMemoryStore(0)
+	now := time.Date(2024, 12, 20, 15, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Record(UsageRecord{RequestID: "1", UserID: "alice", CallerID: "key-1", CostUSD: 30, Timestamp: now.AddDate(0, 0, -10)}))
+	require.NoError(t, store.Record(UsageRecord{RequestID: "2", UserID: "alice", CallerID: "key-1", CostUSD: 25, Timestamp: now.Add(-time.Hour)}))
+	require.NoError(t, store.Record(UsageRecord{RequestID: "3", UserID: "alice", CallerID: "key-1", CostUSD: 500, Timestamp: now.AddDate(0, -1, 0)}))
+
+	limiter := NewSpendLimiter(store, SpendLimits{
+		"alice":              {DailyUSD: 100, MonthlyUSD: 50},
+		"scope:models:gpt-*": {MonthlyUSD: 60},
+	})
+
+	err := limiter.Check("alice", "", nil, now)
+	var spendErr *SpendLimitError
+	require.ErrorAs(t, err, &spendErr)
+	assert.Equal(t, PeriodMonthly, spendErr.Period)
+	assert.InDelta(t, 55.0, spendErr.SpentUSD, 1e-9)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), spendErr.ResetAt)
+
+	// Callers without a user cap fall back to their key scope
+	assert.NoError(t, limiter.Check("", "key-1", []string{"models:gpt-*"}, now))
+	assert.NoError(t, limiter.Check("bob", "key-2", []string{"models:claude-*"}, now))
+}
@@ -0,0 +1,92 @@
+// Package cache provides response caching for deterministic chat completions.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ResponseCache stores chat completion responses by request key
+type ResponseCache interface {
+	Get(key string) (*types.ChatResponse, bool)
+	Set(key string, resp *types.ChatResponse, ttl time.Duration)
+}
+
+// CacheConfig holds response cache configuration
+type CacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+
+	// Serve cached responses to prompts similar in meaning; enabled
+	// independently of exact-match caching
+	Semantic SemanticConfig `yaml:"semantic"`
+}
+
+// Default cache settings
+const (
+	DefaultTTL        = 5 * time.Minute
+	DefaultMaxEntries = 1000
+)
+
+// Cacheable reports whether a request is deterministic enough to cache:
+// non-streaming with temperature unset or zero
+func Cacheable(req *types.ChatRequest) bool {
+	if req.Stream {
+		return false
+	}
+	return req.Temperature == nil || *req.Temperature == 0
+}
+
+// cacheKeyFields are the request fields that determine the response
+type cacheKeyFields struct {
+	Model            string                `json:"model"`
+	Messages         []types.Message       `json:"messages"`
+	Temperature      *float32              `json:"temperature,omitempty"`
+	MaxTokens        *int                  `json:"max_tokens,omitempty"`
+	TopP             *float32              `json:"top_p,omitempty"`
+	FrequencyPenalty *float32              `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32              `json:"presence_penalty,omitempty"`
+	Stop             []string              `json:"stop,omitempty"`
+	Functions        []types.Function      `json:"functions,omitempty"`
+	FunctionCall     interface{}           `json:"function_call,omitempty"`
+	Tools            []types.Tool          `json:"tools,omitempty"`
+	ToolChoice       interface{}           `json:"tool_choice,omitempty"`
+	ResponseFormat   *types.ResponseFormat `json:"response_format,omitempty"`
+	Seed             *int                  `json:"seed,omitempty"`
+	N                *int                  `json:"n,omitempty"`
+}
+
+// Key computes a stable cache key from the model, messages and sampling
+// parameters of a request. Request IDs, timestamps and routing hints are
+// ignored so identical prompts share an entry.
+func Key(req *types.ChatRequest) (string, error) {
+	// encoding/json sorts map keys, so equal requests marshal identically
+	data, err := json.Marshal(cacheKeyFields{
+		Model:            req.Model,
+		Messages:         req.Messages,
+		Temperature:      req.Temperature,
+		MaxTokens:        req.MaxTokens,
+		TopP:             req.TopP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		Stop:             req.Stop,
+		Functions:        req.Functions,
+		FunctionCall:     req.FunctionCall,
+		Tools:            req.Tools,
+		ToolChoice:       req.ToolChoice,
+		ResponseFormat:   req.ResponseFormat,
+		Seed:             req.Seed,
+		N:                req.N,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func float32Ptr(v float32) *float32 { return &v }
+
+func TestKey_StableAndSensitive(t *testing.T) {
+	base := func() *types.ChatRequest {
+		return &types.ChatRequest{
+			ID:       "req-1",
+			Model:    "gpt-4o",
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		}
+	}
+
+	key, err := Key(base())
+	require.NoError(t, err)
+
+	// Request IDs don't affect the key
+	other := base()
+	other.ID = "req-2"
+	otherKey, err := Key(other)
+	require.NoError(t, err)
+	assert.Equal(t, key, otherKey)
+
+	// Model, messages and sampling params do
+	changed := []func(*types.ChatRequest){
+		func(r *types.ChatRequest) { r.Model = "gpt-4o-mini" },
+		func(r *types.ChatRequest) { r.Messages[0].Content = "Hi" },
+		func(r *types.ChatRequest) { r.TopP = float32Ptr(0.5) },
+		func(r *types.ChatRequest) { r.Stop = []string{"\n"} },
+	}
+	for _, change := range changed {
+		req := base()
+		change(req)
+		changedKey, err := Key(req)
+		require.NoError(t, err)
+		assert.NotEqual(t, key, changedKey)
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	assert.True(t, Cacheable(&types.ChatRequest{}))
+	assert.True(t, Cacheable(&types.ChatRequest{Temperature: float32Ptr(0)}))
+	assert.False(t, Cacheable(&types.ChatRequest{Temperature: float32Ptr(0.7)}))
+	assert.False(t, Cacheable(&types.ChatRequest{Stream: true}))
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", &types.ChatResponse{ID: "a"}, time.Minute)
+	c.Set("b", &types.ChatResponse{ID: "b"}, time.Minute)
+
+	// Touch "a" so "b" becomes least recently used
+	_, ok := c.Get("a")
+	require.True(t, ok)
+
+	c.Set("c", &types.ChatResponse{ID: "c"}, time.Minute)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	resp, ok := c.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "a", resp.ID)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUCache_Expiry(t *testing.T) {
+	c := NewLRUCache(10)
+
+	c.Set("short", &types.ChatResponse{ID: "short"}, time.Millisecond)
+	c.Set("forever", &types.ChatResponse{ID: "forever"}, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := c.Get("short")
+	assert.False(t, ok)
+	_, ok = c.Get("forever")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLRUCache_ReturnsCopies(t *testing.T) {
+	c := NewLRUCache(10)
+	c.Set("k", &types.ChatResponse{ID: "original"}, time.Minute)
+
+	resp, _ := c.Get("k")
+	resp.ID = "mutated"
+
+	again, _ := c.Get("k")
+	assert.Equal(t, "original", again.ID)
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyConfig holds Idempotency-Key handling configuration
+type IdempotencyConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`         // How long keys and responses are kept
+	MaxEntries int           `yaml:"max_entries"` // In-memory store capacity
+}
+
+// Default idempotency settings
+const (
+	DefaultIdempotencyTTL        = 24 * time.Hour
+	DefaultIdempotencyMaxEntries = 10000
+)
+
+// StoredResponse is an HTTP response recorded for replay
+type StoredResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// IdempotencyRecord is the state of a claimed idempotency key
+type IdempotencyRecord struct {
+	BodyHash string          `json:"body_hash"`
+	Response *StoredResponse `json:"response,omitempty"` // nil while the first request is in flight
+}
+
+// IdempotencyStore tracks requests by idempotency key. Reserve must be atomic
+// so that concurrent first requests can't both proceed; a shared backend can
+// implement it with a set-if-absent.
+type IdempotencyStore interface {
+	// Reserve claims key for a request body hash and returns true, or returns
+	// the existing record when the key is already claimed
+	Reserve(key, bodyHash string, ttl time.Duration) (*IdempotencyRecord, bool)
+
+	// Complete stores the response for a reserved key
+	Complete(key string, resp *StoredResponse, ttl time.Duration)
+
+	// Release drops a reservation so the request can be retried
+	Release(key string)
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. Once full, the
+// oldest keys are evicted first.
+type MemoryIdempotencyStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // front is newest
+}
+
+// idempotencyEntry is a record with its expiry
+type idempotencyEntry struct {
+	key       string
+	record    IdempotencyRecord
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore creates a store holding at most maxEntries keys
+func NewMemoryIdempotencyStore(maxEntries int) *MemoryIdempotencyStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultIdempotencyMaxEntries
+	}
+
+	return &MemoryIdempotencyStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Reserve implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Reserve(key, bodyHash string, ttl time.Duration) (*IdempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.entries[key]; exists {
+		entry := element.Value.(*idempotencyEntry)
+		if !expired(entry.expiresAt) {
+			record := entry.record
+			return &record, false
+		}
+		s.removeElement(element)
+	}
+
+	s.entries[key] = s.order.PushFront(&idempotencyEntry{
+		key:       key,
+		record:    IdempotencyRecord{BodyHash: bodyHash},
+		expiresAt: expiry(ttl),
+	})
+
+	for s.order.Len() > s.maxEntries {
+		s.removeElement(s.order.Back())
+	}
+	return nil, true
+}
+
+// Complete implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(key string, resp *StoredResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.entries[key]
+	if !exists {
+		return
+	}
+
+	entry := element.Value.(*idempotencyEntry)
+	entry.record.Response = resp
+	entry.expiresAt = expiry(ttl)
+}
+
+// Release implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, exists := s.entries[key]; exists {
+		s.removeElement(element)
+	}
+}
+
+// Len returns the number of stored keys, including expired ones not yet evicted
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *MemoryIdempotencyStore) removeElement(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*idempotencyEntry).key)
+}
+
+// expiry returns the expiry time for ttl (zero ttl never expires)
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// expired reports whether an expiry time has passed
+func expired(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Now().After(expiresAt)
+}
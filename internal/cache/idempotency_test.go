@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryIdempotencyStore_ReserveCompleteRelease(t *testing.T) {
+	s := NewMemoryIdempotencyStore(10)
+
+	record, reserved := s.Reserve("k", "hash-a", time.Minute)
+	require.True(t, reserved)
+	assert.Nil(t, record)
+
+	// In flight: the record has no response yet
+	record, reserved = s.Reserve("k", "hash-a", time.Minute)
+	require.False(t, reserved)
+	assert.Equal(t, "hash-a", record.BodyHash)
+	assert.Nil(t, record.Response)
+
+	s.Complete("k", &StoredResponse{StatusCode: 200, Body: []byte("ok")}, time.Minute)
+	record, reserved = s.Reserve("k", "hash-b", time.Minute)
+	require.False(t, reserved)
+	assert.Equal(t, "hash-a", record.BodyHash)
+	require.NotNil(t, record.Response)
+	assert.Equal(t, []byte("ok"), record.Response.Body)
+
+	// Released keys can be claimed again
+	s.Release("k")
+	_, reserved = s.Reserve("k", "hash-b", time.Minute)
+	assert.True(t, reserved)
+}
+
+func TestMemoryIdempotencyStore_ExpiryAndEviction(t *testing.T) {
+	s := NewMemoryIdempotencyStore(2)
+
+	s.Reserve("short", "h", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, reserved := s.Reserve("short", "h", time.Minute)
+	assert.True(t, reserved, "expired keys can be claimed again")
+
+	s.Reserve("b", "h", time.Minute)
+	s.Reserve("c", "h", time.Minute)
+	assert.Equal(t, 2, s.Len())
+	_, reserved = s.Reserve("short", "h", time.Minute)
+	assert.True(t, reserved, "oldest key is evicted first")
+}
+
+func TestMemoryIdempotencyStore_ConcurrentReserve(t *testing.T) {
+	s := NewMemoryIdempotencyStore(10)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, reserved := s.Reserve("k", "h", time.Minute); reserved {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, winners)
+}
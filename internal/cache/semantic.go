@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// Default semantic cache settings
+const (
+	DefaultSemanticThreshold      = 0.95
+	DefaultSemanticMaxTemperature = 0.3
+)
+
+// SemanticConfig holds semantic cache configuration. The semantic cache
+// serves a cached response when a prompt is close enough in meaning to one
+// answered before, rather than identical.
+type SemanticConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	EmbeddingModel string `yaml:"embedding_model"` // routed like any embeddings request
+
+	// Cosine similarity from 0 to 1 a prompt needs to a cached one to hit,
+	// defaults to DefaultSemanticThreshold
+	Threshold float64 `yaml:"threshold"`
+
+	// Requests sampled above this temperature are never cached, defaults to
+	// DefaultSemanticMaxTemperature. Unset temperatures count as 0.
+	MaxTemperature float32 `yaml:"max_temperature"`
+
+	TTL        time.Duration `yaml:"ttl"`         // defaults to DefaultTTL
+	MaxEntries int           `yaml:"max_entries"` // defaults to DefaultMaxEntries
+}
+
+// Validate checks that an enabled semantic cache names an embedding model and
+// has a threshold between 0 and 1
+func (c SemanticConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.EmbeddingModel == "" {
+		return fmt.Errorf("semantic cache embedding_model is required when the semantic cache is enabled")
+	}
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("semantic cache threshold must be between 0 and 1")
+	}
+	if c.MaxTemperature < 0 {
+		return fmt.Errorf("semantic cache max_temperature must not be negative")
+	}
+	return nil
+}
+
+// Embedder turns text into an embedding vector
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// callerKey marks a context with the caller a semantic cache lookup is for
+type callerKey struct{}
+
+// WithCaller returns a context whose semantic cache lookups only match
+// responses stored for the same caller, e.g. an API key or tenant, so a
+// similar prompt never returns another caller's answer
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// SemanticProbe is the embedded prompt of a semantic cache lookup, used to
+// store the response to the same request without embedding it again
+type SemanticProbe struct {
+	scope  string
+	vector []float32
+}
+
+// SemanticHit is a cached response for a similar prompt
+type SemanticHit struct {
+	Response   *types.ChatResponse
+	Similarity float64
+}
+
+// semanticEntry is a cached response with its prompt's embedding
+type semanticEntry struct {
+	scope     string
+	vector    []float32
+	resp      types.ChatResponse
+	expiresAt time.Time
+}
+
+// SemanticCache is an in-memory cache matching prompts by embedding
+// similarity. Lookups scan every entry, so MaxEntries bounds their cost.
+type SemanticCache struct {
+	config   SemanticConfig
+	embedder Embedder
+
+	mu      sync.Mutex
+	entries []*semanticEntry // oldest first
+}
+
+// NewSemanticCache creates a semantic cache embedding prompts with embedder
+func NewSemanticCache(config SemanticConfig, embedder Embedder) *SemanticCache {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultSemanticThreshold
+	}
+	if config.MaxTemperature <= 0 {
+		config.MaxTemperature = DefaultSemanticMaxTemperature
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	return &SemanticCache{config: config, embedder: embedder}
+}
+
+// Lookup embeds the prompt of req, the text of its last message, and returns
+// the most similar cached response at or above the threshold. Only responses
+// to requests that match req in everything but that prompt, including the
+// caller (see WithCaller) and req.UserID, are considered.
+// The probe is nil when req can't be cached: streaming, sampled above the
+// maximum temperature, or not ending in a user message.
+func (c *SemanticCache) Lookup(ctx context.Context, req *types.ChatRequest) (*SemanticProbe, *SemanticHit, error) {
+	prompt, scope, ok := c.split(ctx, req)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	vector, err := c.embedder.Embed(ctx, prompt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to embed prompt: %w", err)
+	}
+	probe := &SemanticProbe{scope: scope, vector: vector}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	var best *semanticEntry
+	bestSimilarity := 0.0
+	live := c.entries[:0]
+	for _, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		live = append(live, entry)
+		if entry.scope != scope {
+			continue
+		}
+		if similarity := cosineSimilarity(vector, entry.vector); similarity >= c.config.Threshold && similarity > bestSimilarity {
+			best, bestSimilarity = entry, similarity
+		}
+	}
+	clear(c.entries[len(live):])
+	c.entries = live
+
+	if best == nil {
+		return probe, nil, nil
+	}
+	resp := best.resp
+	return probe, &SemanticHit{Response: &resp, Similarity: bestSimilarity}, nil
+}
+
+// Store caches resp for the request probe was looked up for, evicting the
+// oldest entry once full
+func (c *SemanticCache) Store(probe *SemanticProbe, resp *types.ChatResponse) {
+	if probe == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = append(c.entries, &semanticEntry{
+		scope:     probe.scope,
+		vector:    probe.vector,
+		resp:      *resp,
+		expiresAt: time.Now().Add(c.config.TTL),
+	})
+	if excess := len(c.entries) - c.config.MaxEntries; excess > 0 {
+		clear(c.entries[:excess])
+		c.entries = c.entries[excess:]
+	}
+}
+
+// split returns the prompt a request is matched on and a key for everything
+// else about it, including who sent it, reporting false if the request can't
+// be cached
+func (c *SemanticCache) split(ctx context.Context, req *types.ChatRequest) (string, string, bool) {
+	if req.Stream || len(req.Messages) == 0 {
+		return "", "", false
+	}
+	if req.Temperature != nil && *req.Temperature > c.config.MaxTemperature {
+		return "", "", false
+	}
+
+	last := req.Messages[len(req.Messages)-1]
+	prompt := types.MessageText(last.Content)
+	if last.Role != "user" || prompt == "" {
+		return "", "", false
+	}
+
+	scoped := *req
+	scoped.Messages = append(append([]types.Message(nil), req.Messages[:len(req.Messages)-1]...), types.Message{Role: last.Role, Name: last.Name})
+	key, err := Key(&scoped)
+	if err != nil {
+		return "", "", false
+	}
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return prompt, fmt.Sprintf("%q %q %s", caller, req.UserID, key), true
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 if
+// they differ in length or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
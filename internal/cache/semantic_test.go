@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// stubEmbedder maps prompts to fixed vectors by keyword, so paraphrases
+// share a direction
+type stubEmbedder struct {
+	calls int
+}
+
+func (e *stubEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	text = strings.ToLower(text)
+	switch {
+	case strings.Contains(text, "capital of france"):
+		return []float32{0.9, 0.1, 0}, nil
+	case strings.Contains(text, "france's capital"):
+		return []float32{0.88, 0.12, 0.01}, nil
+	case strings.Contains(text, "fail"):
+		return nil, errors.New("embedding service down")
+	}
+	return []float32{0, 0.2, 0.95}, nil
+}
+
+func chatRequest(prompt string) *types.ChatRequest {
+	return &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{
+		{Role: "system", Content: "Answer briefly"},
+		{Role: "user", Content: prompt},
+	}}
+}
+
+func TestSemanticCache_NearDuplicateHits(t *testing.T) {
+	embedder := &stubEmbedder{}
+	semantic := NewSemanticCache(SemanticConfig{Enabled: true, Threshold: 0.99}, embedder)
+	ctx := context.Background()
+
+	probe, hit, err := semantic.Lookup(ctx, chatRequest("What is the capital of France?"))
+	require.NoError(t, err)
+	require.NotNil(t, probe)
+	assert.Nil(t, hit)
+	semantic.Store(probe, &types.ChatResponse{ID: "paris"})
+
+	// A paraphrase hits
+	_, hit, err = semantic.Lookup(ctx, chatRequest("Tell me France's capital"))
+	require.NoError(t, err)
+	require.NotNil(t, hit)
+	assert.Equal(t, "paris", hit.Response.ID)
+	assert.Greater(t, hit.Similarity, 0.99)
+
+	// A different question misses
+	probe, hit, err = semantic.Lookup(ctx, chatRequest("How do I bake bread?"))
+	require.NoError(t, err)
+	assert.NotNil(t, probe)
+	assert.Nil(t, hit)
+
+	// So does the same question under a different system prompt or model
+	other := chatRequest("What is the capital of France?")
+	other.Messages[0].Content = "Answer in French"
+	_, hit, _ = semantic.Lookup(ctx, other)
+	assert.Nil(t, hit)
+	other = chatRequest("What is the capital of France?")
+	other.Model = "gpt-4o-mini"
+	_, hit, _ = semantic.Lookup(ctx, other)
+	assert.Nil(t, hit)
+}
+
+func TestSemanticCache_ScopedToCaller(t *testing.T) {
+	embedder := &stubEmbedder{}
+	semantic := NewSemanticCache(SemanticConfig{Enabled: true, Threshold: 0.99}, embedder)
+	tenantA := WithCaller(context.Background(), "tenant-a")
+	tenantB := WithCaller(context.Background(), "tenant-b")
+
+	probe, _, err := semantic.Lookup(tenantA, chatRequest("What is the capital of France?"))
+	require.NoError(t, err)
+	semantic.Store(probe, &types.ChatResponse{ID: "tenant-a-answer"})
+
+	// A near-identical prompt from another caller misses
+	_, hit, err := semantic.Lookup(tenantB, chatRequest("Tell me France's capital"))
+	require.NoError(t, err)
+	assert.Nil(t, hit)
+
+	// So does one from another end user of the same caller
+	other := chatRequest("Tell me France's capital")
+	other.UserID = "user-2"
+	_, hit, _ = semantic.Lookup(tenantA, other)
+	assert.Nil(t, hit)
+
+	// The caller itself still hits
+	_, hit, _ = semantic.Lookup(tenantA, chatRequest("Tell me France's capital"))
+	require.NotNil(t, hit)
+	assert.Equal(t, "tenant-a-answer", hit.Response.ID)
+}
+
+func TestSemanticCache_Eligibility(t *testing.T) {
+	embedder := &stubEmbedder{}
+	semantic := NewSemanticCache(SemanticConfig{Enabled: true, MaxTemperature: 0.2}, embedder)
+	ctx := context.Background()
+
+	hot := chatRequest("What is the capital of France?")
+	hot.Temperature = float32Ptr(0.7)
+	streamed := chatRequest("What is the capital of France?")
+	streamed.Stream = true
+	tool := chatRequest("What is the capital of France?")
+	tool.Messages = append(tool.Messages, types.Message{Role: "tool", Content: "Paris", ToolCallID: "call_1"})
+
+	for _, req := range []*types.ChatRequest{hot, streamed, tool} {
+		probe, hit, err := semantic.Lookup(ctx, req)
+		assert.NoError(t, err)
+		assert.Nil(t, probe)
+		assert.Nil(t, hit)
+	}
+	assert.Zero(t, embedder.calls, "ineligible requests aren't embedded")
+
+	_, _, err := semantic.Lookup(ctx, chatRequest("please fail"))
+	assert.Error(t, err)
+}
+
+func TestSemanticCache_EvictsOldest(t *testing.T) {
+	semantic := NewSemanticCache(SemanticConfig{Enabled: true, MaxEntries: 1}, &stubEmbedder{})
+	ctx := context.Background()
+
+	probe, _, _ := semantic.Lookup(ctx, chatRequest("What is the capital of France?"))
+	semantic.Store(probe, &types.ChatResponse{ID: "paris"})
+	probe, _, _ = semantic.Lookup(ctx, chatRequest("How do I bake bread?"))
+	semantic.Store(probe, &types.ChatResponse{ID: "bread"})
+
+	_, hit, _ := semantic.Lookup(ctx, chatRequest("What is the capital of France?"))
+	assert.Nil(t, hit)
+	_, hit, _ = semantic.Lookup(ctx, chatRequest("How do I bake bread?"))
+	require.NotNil(t, hit)
+	assert.Equal(t, "bread", hit.Response.ID)
+}
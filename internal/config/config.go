@@ -1,15 +1,29 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/cache"
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
 	"github.com/tributary-ai/llm-router-waf/internal/middleware"
+	"github.com/tributary-ai/llm-router-waf/internal/privacy"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
 	"github.com/tributary-ai/llm-router-waf/internal/providers/anthropic"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/bedrock"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/cohere"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/mistral"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/ollama"
 	"github.com/tributary-ai/llm-router-waf/internal/providers/openai"
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
 	"github.com/tributary-ai/llm-router-waf/internal/security"
 	"github.com/tributary-ai/llm-router-waf/internal/server"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
@@ -17,11 +31,25 @@ import (
 
 // Config represents the complete application configuration
 type Config struct {
-	Server    ServerConfig     `yaml:"server"`
-	Router    RouterConfig     `yaml:"router"`
-	Providers ProvidersConfig  `yaml:"providers"`
-	Logging   LoggingConfig    `yaml:"logging"`
-	Security  SecurityConfig   `yaml:"security"`
+	Server     ServerConfig      `yaml:"server"`
+	Router     RouterConfig      `yaml:"router"`
+	Providers  ProvidersConfig   `yaml:"providers"`
+	Logging    LoggingConfig     `yaml:"logging"`
+	Security   SecurityConfig    `yaml:"security"`
+	Cache      cache.CacheConfig `yaml:"cache"`
+	Accounting accounting.Config `yaml:"accounting"`
+	Tracing    TracingConfig     `yaml:"tracing"`
+	
+	// Mandatory system prompt injected into every chat request
+	Guardrail routing.GuardrailConfig `yaml:"guardrail"`
+	
+	// PII redaction applied to prompts before routing
+	Privacy privacy.Config `yaml:"privacy"`
+	
+	Idempotency cache.IdempotencyConfig `yaml:"idempotency"`
+	
+	// Webhook for operational alerts, e.g. a provider rejecting its API key
+	Alerts server.AlertConfig `yaml:"alerts"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -30,21 +58,98 @@ type ServerConfig struct {
 	ReadTimeout    time.Duration `yaml:"read_timeout"`
 	WriteTimeout   time.Duration `yaml:"write_timeout"`
 	MaxHeaderBytes int           `yaml:"max_header_bytes"`
+	
+	// Debug-log request bodies (never for telemetry opt-out traffic)
+	LogRequestBodies bool `yaml:"log_request_bodies"`
+	
+	// Debug-log redacted request and response bodies
+	DebugLogging server.DebugLoggingConfig `yaml:"debug_logging"`
+	
+	// Idle time before a stream gets an SSE keepalive comment (0 = 15s, negative disables)
+	StreamHeartbeatInterval time.Duration `yaml:"stream_heartbeat_interval"`
+	
+	// Provider response headers returned as X-Upstream-* ([] disables)
+	UpstreamHeaders []string `yaml:"upstream_headers"`
+	
+	// Request tag keys added as labels to the tagged usage metrics
+	MetricTagLabels []string `yaml:"metric_tag_labels"`
 }
 
 // RouterConfig holds routing engine configuration
 type RouterConfig struct {
 	DefaultStrategy         string        `yaml:"default_strategy"`
 	HealthCheckInterval     time.Duration `yaml:"health_check_interval"`
+	HealthCheckTimeout      time.Duration `yaml:"health_check_timeout"`     // a check taking longer marks the provider unhealthy
+	HealthCheckConcurrency  int           `yaml:"health_check_concurrency"` // providers checked at once
+	HealthCachePath         string        `yaml:"health_cache_path"` // persisted provider health for warm starts
 	MaxCostThreshold        float64       `yaml:"max_cost_threshold"`
 	EnableFallbackChaining  bool          `yaml:"enable_fallback_chaining"`
 	RequestTimeout          time.Duration `yaml:"request_timeout"`
+	
+	// Requests served at once before new ones are shed with 503 (0 = unlimited)
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+	
+	// Requests each provider serves at once; a provider at its limit is
+	// skipped in favour of a fallback (absent or 0 = unlimited)
+	ProviderConcurrency map[string]int `yaml:"provider_concurrency"`
+	
+	// Cross-provider model equivalents used for substitution when a model's
+	// owning provider is unhealthy (requires allow_model_substitution per request)
+	ModelEquivalents map[string][]routing.ModelEquivalent `yaml:"model_equivalents"`
+	
+	// Relative provider weights for the weighted round-robin strategy
+	Weights map[string]int `yaml:"weights"`
+	
+	// Logical model names mapped to model IDs, e.g. fast: gpt-4o-mini
+	Aliases map[string]string `yaml:"aliases"`
+	
+	// Model name prefixes mapped to the provider serving them. Entries are
+	// merged over the defaults; an empty provider disables a default prefix.
+	ModelPrefixMap map[string]string `yaml:"model_prefix_map"`
+	
+	// Exact model names pinned to a provider ahead of the prefix map, e.g. a
+	// fine-tuned model served by another provider
+	ModelOverrides map[string]string `yaml:"model_overrides"`
+	
+	// Weight of cost against latency for the balanced strategy, from 0
+	// (latency only) to 1 (cost only)
+	BalancedAlpha float64 `yaml:"balanced_alpha"`
+	
+	// Latency statistic the performance strategy ranks providers by: "mean"
+	// (default), "p95" or "p99"
+	PerformanceMetric string `yaml:"performance_metric"`
+	
+	// Mirror a sample of completions to a candidate provider for evaluation
+	Shadow routing.ShadowConfig `yaml:"shadow"`
+	
+	// Retry policy for requests without their own retry_config
+	DefaultRetry *types.RetryConfig `yaml:"default_retry"`
+	
+	// Fraction of requests that may be retried across all requests, e.g.
+	// 0.1 for 10%; once spent, failures skip their retries and fall back
+	// immediately. 0 leaves retries unlimited.
+	RetryBudgetRatio float64 `yaml:"retry_budget_ratio"`
+	
+	// Glob patterns of the models the router serves, e.g. gpt-4o*; other
+	// models are rejected with 403 and hidden from /v1/models. Empty allows all.
+	AllowedModels []string `yaml:"allowed_models"`
+	
+	// Built-in request transformers run in order on every chat request
+	// before routing, e.g. auth_user
+	RequestTransformers []string `yaml:"request_transformers"`
 }
 
 // ProvidersConfig holds configuration for all providers
 type ProvidersConfig struct {
 	OpenAI    *openai.OpenAIConfig       `yaml:"openai"`
 	Anthropic *anthropic.AnthropicConfig `yaml:"anthropic"`
+	Cohere    *cohere.CohereConfig       `yaml:"cohere"`
+	Mistral   *mistral.MistralConfig     `yaml:"mistral"`
+	Ollama    *ollama.OllamaConfig       `yaml:"ollama"` // self-hosted, no API key
+	Bedrock   *bedrock.BedrockConfig     `yaml:"bedrock"` // AWS credential chain
+	
+	// Proxy, TLS and connection pool settings shared by every provider
+	HTTPClient providers.HTTPClientConfig `yaml:"http_client"`
 }
 
 // LoggingConfig holds logging configuration
@@ -54,12 +159,45 @@ type LoggingConfig struct {
 	Output string `yaml:"output"` // "stdout", "stderr", or file path
 }
 
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	OTLPEndpoint string `yaml:"otlp_endpoint"` // OTLP/HTTP URL, e.g. "http://localhost:4318"
+	ServiceName  string `yaml:"service_name"`
+}
+
 // SecurityConfig holds security-related configuration
 type SecurityConfig struct {
 	APIKeys          []string          `yaml:"api_keys"`
 	RateLimiting     RateLimitConfig   `yaml:"rate_limiting"`
 	CORS             CORSConfig        `yaml:"cors"`
 	RequestValidation ValidationConfig `yaml:"request_validation"`
+	JWT              JWTConfig         `yaml:"jwt"`
+	
+	// HMAC request signing, accepted alongside API keys when a secret is set
+	HMAC security.HMACAuthConfig `yaml:"hmac"`
+	
+	// API keys (also listed in api_keys) whose traffic opts out of telemetry
+	TelemetryOptOutKeys []string `yaml:"telemetry_opt_out_keys"`
+	
+	// Permission scopes per API key (also listed in api_keys), e.g.
+	// ["models:gpt-4o", "endpoints:/v1/chat/completions"]
+	KeyScopes map[string][]string `yaml:"key_scopes"`
+	
+	// Screen prompts with a moderation API before routing
+	Moderation security.ModerationConfig `yaml:"moderation"`
+	
+	// Security audit log, including what happens to events when its
+	// buffer is full
+	Audit security.AuditConfig `yaml:"audit"`
+}
+
+// JWTConfig holds JWT issuance configuration. Tokens are issued from
+// /v1/auth/token only when a secret is set.
+type JWTConfig struct {
+	Secret             string        `yaml:"secret"`
+	Expiry             time.Duration `yaml:"expiry"`
+	RefreshTokenExpiry time.Duration `yaml:"refresh_token_expiry"`
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -68,13 +206,28 @@ type RateLimitConfig struct {
 	RequestsPerMin  int           `yaml:"requests_per_minute"`
 	BurstSize       int           `yaml:"burst_size"`
 	WindowDuration  time.Duration `yaml:"window_duration"`
+	
+	// Share limits across instances via Redis (e.g. redis://localhost:6379/0)
+	RedisURL string `yaml:"redis_url"`
+	
+	// Requests per minute for individual models and providers. These apply
+	// inside the router independently of the per-client limit above.
+	PerModelLimits    map[string]int `yaml:"per_model_limits"`
+	PerProviderLimits map[string]int `yaml:"per_provider_limits"`
+	
+	// Models grouped into tiers and each tier's requests per minute per
+	// client, replacing requests_per_minute for the tier's models
+	ModelTiers map[string][]string `yaml:"model_tiers"`
+	TierLimits map[string]int      `yaml:"tier_limits"`
 }
 
 // CORSConfig holds CORS configuration
 type CORSConfig struct {
-	AllowedOrigins []string `yaml:"allowed_origins"`
-	AllowedMethods []string `yaml:"allowed_methods"`
-	AllowedHeaders []string `yaml:"allowed_headers"`
+	AllowedOrigins   []string `yaml:"allowed_origins"` // "*", exact origins, or wildcards like https://*.example.com
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age"` // preflight cache lifetime in seconds
 }
 
 // ValidationConfig holds request validation configuration
@@ -82,6 +235,7 @@ type ValidationConfig struct {
 	MaxRequestSize   int64 `yaml:"max_request_size"`
 	MaxMessageLength int   `yaml:"max_message_length"`
 	MaxMessages      int   `yaml:"max_messages"`
+	MaxChoices       int   `yaml:"max_choices"` // completions per chat request (n), 0 = unlimited
 }
 
 // LoadConfig loads configuration from file and environment variables
@@ -101,6 +255,11 @@ func LoadConfig(configPath string) (*Config, error) {
 	// Override with environment variables
 	config.loadFromEnv()
 	
+	// Replace ${env:...}, ${file:...} and ${vault:...} references
+	if err := config.resolveSecrets(context.Background(), Secrets); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+	
 	// Validate configuration
 	if err := config.validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -109,6 +268,14 @@ func LoadConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// Reload re-reads configuration from path, applying defaults and environment
+// overrides as LoadConfig does. The new configuration is validated before it
+// is returned; on error the caller should keep using c, which Reload never
+// modifies.
+func (c *Config) Reload(path string) (*Config, error) {
+	return LoadConfig(path)
+}
+
 // setDefaults sets default configuration values
 func (c *Config) setDefaults() {
 	// Server defaults
@@ -117,15 +284,33 @@ func (c *Config) setDefaults() {
 		ReadTimeout:    30 * time.Second,
 		WriteTimeout:   30 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1MB
+		UpstreamHeaders: slices.Clone(providers.DefaultUpstreamHeaders),
 	}
 	
 	// Router defaults
 	c.Router = RouterConfig{
 		DefaultStrategy:         "cost_optimized",
 		HealthCheckInterval:     30 * time.Second,
+		HealthCheckTimeout:      routing.DefaultHealthCheckTimeout,
+		HealthCheckConcurrency:  routing.DefaultHealthCheckConcurrency,
 		MaxCostThreshold:        1.0,
 		EnableFallbackChaining:  true,
 		RequestTimeout:          120 * time.Second,
+		BalancedAlpha:           routing.DefaultBalancedAlpha,
+		ModelPrefixMap:          maps.Clone(routing.DefaultModelPrefixes),
+	}
+	
+	// Response cache defaults (disabled)
+	c.Cache = cache.CacheConfig{
+		TTL:        cache.DefaultTTL,
+		MaxEntries: cache.DefaultMaxEntries,
+	}
+	
+	// Idempotency-Key defaults (enabled; only applies to requests sending the header)
+	c.Idempotency = cache.IdempotencyConfig{
+		Enabled:    true,
+		TTL:        cache.DefaultIdempotencyTTL,
+		MaxEntries: cache.DefaultIdempotencyMaxEntries,
 	}
 	
 	// Logging defaults
@@ -135,6 +320,12 @@ func (c *Config) setDefaults() {
 		Output: "stdout",
 	}
 	
+	// Tracing defaults
+	c.Tracing = TracingConfig{
+		Enabled:     false,
+		ServiceName: "llm-router",
+	}
+	
 	// Security defaults
 	c.Security = SecurityConfig{
 		APIKeys: []string{},
@@ -147,12 +338,19 @@ func (c *Config) setDefaults() {
 		CORS: CORSConfig{
 			AllowedOrigins: []string{"*"},
 			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "Idempotency-Key"},
 		},
 		RequestValidation: ValidationConfig{
 			MaxRequestSize:   10 << 20, // 10MB
 			MaxMessageLength: 100000,   // 100k characters
 			MaxMessages:      50,
+			MaxChoices:       8,
+		},
+		Audit: security.AuditConfig{
+			Enabled:        true,
+			BufferSize:     1000,
+			FlushInterval:  10 * time.Second,
+			OverflowPolicy: security.OverflowDrop,
 		},
 	}
 	
@@ -208,6 +406,56 @@ func (c *Config) setDefaults() {
 			},
 			Timeout: 120 * time.Second,
 		},
+		Cohere: &cohere.CohereConfig{
+			Models: []types.ModelInfo{
+				{
+					Name:              "command-r-plus-08-2024",
+					ProviderModelID:   "command-r-plus-08-2024",
+					InputCostPer1K:    0.0025,
+					OutputCostPer1K:   0.01,
+					MaxContextWindow:  128000,
+					MaxOutputTokens:   4096,
+				},
+				{
+					Name:              "command-r-08-2024",
+					ProviderModelID:   "command-r-08-2024",
+					InputCostPer1K:    0.00015,
+					OutputCostPer1K:   0.0006,
+					MaxContextWindow:  128000,
+					MaxOutputTokens:   4096,
+				},
+			},
+			Timeout: 120 * time.Second,
+		},
+		Mistral: &mistral.MistralConfig{
+			Models: []types.ModelInfo{
+				{
+					Name:              "mistral-large-latest",
+					ProviderModelID:   "mistral-large-latest",
+					InputCostPer1K:    0.002,
+					OutputCostPer1K:   0.006,
+					MaxContextWindow:  128000,
+					MaxOutputTokens:   8192,
+				},
+				{
+					Name:              "mistral-small-latest",
+					ProviderModelID:   "mistral-small-latest",
+					InputCostPer1K:    0.0002,
+					OutputCostPer1K:   0.0006,
+					MaxContextWindow:  32000,
+					MaxOutputTokens:   8192,
+				},
+				{
+					Name:              "open-mixtral-8x7b",
+					ProviderModelID:   "open-mixtral-8x7b",
+					InputCostPer1K:    0.0007,
+					OutputCostPer1K:   0.0007,
+					MaxContextWindow:  32000,
+					MaxOutputTokens:   4096,
+				},
+			},
+			Timeout: 120 * time.Second,
+		},
 	}
 }
 
@@ -237,7 +485,11 @@ func (c *Config) loadFromEnv() {
 		if c.Providers.OpenAI != nil {
 			c.Providers.OpenAI.APIKey = openaiKey
 		}
-	} else if c.Providers.OpenAI != nil && c.Providers.OpenAI.APIKey == "" {
+	}
+	if openaiKeys := os.Getenv("OPENAI_API_KEYS"); openaiKeys != "" && c.Providers.OpenAI != nil {
+		c.Providers.OpenAI.APIKeys = splitList(openaiKeys)
+	}
+	if c.Providers.OpenAI != nil && len(c.Providers.OpenAI.Keys()) == 0 {
 		// Disable OpenAI provider if no API key is provided
 		c.Providers.OpenAI = nil
 	}
@@ -251,6 +503,28 @@ func (c *Config) loadFromEnv() {
 		c.Providers.Anthropic = nil
 	}
 
+	if cohereKey := os.Getenv("COHERE_API_KEY"); cohereKey != "" {
+		if c.Providers.Cohere != nil {
+			c.Providers.Cohere.APIKey = cohereKey
+		}
+	} else if c.Providers.Cohere != nil && c.Providers.Cohere.APIKey == "" {
+		// Disable Cohere provider if no API key is provided
+		c.Providers.Cohere = nil
+	}
+
+	if mistralKey := os.Getenv("MISTRAL_API_KEY"); mistralKey != "" {
+		if c.Providers.Mistral != nil {
+			c.Providers.Mistral.APIKey = mistralKey
+		}
+	} else if c.Providers.Mistral != nil && c.Providers.Mistral.APIKey == "" {
+		// Disable Mistral provider if no API key is provided
+		c.Providers.Mistral = nil
+	}
+
+	if ollamaURL := os.Getenv("OLLAMA_BASE_URL"); ollamaURL != "" && c.Providers.Ollama != nil {
+		c.Providers.Ollama.BaseURL = ollamaURL
+	}
+
 	// Logging configuration
 	if level := os.Getenv("LLM_ROUTER_LOG_LEVEL"); level != "" {
 		c.Logging.Level = level
@@ -260,6 +534,18 @@ func (c *Config) loadFromEnv() {
 		c.Logging.Format = format
 	}
 
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		c.Security.RateLimiting.RedisURL = redisURL
+	}
+
+	if jwtSecret := os.Getenv("LLM_ROUTER_JWT_SECRET"); jwtSecret != "" {
+		c.Security.JWT.Secret = jwtSecret
+	}
+
+	if hmacSecret := os.Getenv("LLM_ROUTER_HMAC_SECRET"); hmacSecret != "" {
+		c.Security.HMAC.Secret = hmacSecret
+	}
+
 	// Server timeout configuration
 	if rt := os.Getenv("SERVER_READ_TIMEOUT"); rt != "" {
 		if d, err := time.ParseDuration(rt); err == nil {
@@ -290,12 +576,18 @@ func (c *Config) validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("server port cannot be empty")
 	}
+	if err := metrics.ValidateTagLabels(c.Server.MetricTagLabels); err != nil {
+		return fmt.Errorf("invalid server.metric_tag_labels: %w", err)
+	}
 	
 	// Validate router strategy
 	validStrategies := map[string]bool{
 		"cost_optimized": true,
 		"performance":    true,
 		"round_robin":    true,
+		"weighted":       true,
+		"sticky":         true,
+		"balanced":       true,
 		"specific":       true,
 	}
 	
@@ -303,6 +595,152 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid default strategy: %s", c.Router.DefaultStrategy)
 	}
 	
+	if c.Router.MaxCostThreshold < 0 {
+		return fmt.Errorf("max cost threshold cannot be negative")
+	}
+	
+	if c.Router.BalancedAlpha < 0 || c.Router.BalancedAlpha > 1 {
+		return fmt.Errorf("balanced alpha must be between 0 and 1")
+	}
+	
+	if err := routing.PerformanceMetric(c.Router.PerformanceMetric).Validate(); err != nil {
+		return err
+	}
+	
+	if err := c.Guardrail.Validate(); err != nil {
+		return err
+	}
+	
+	if err := c.Privacy.Validate(); err != nil {
+		return err
+	}
+	
+	if err := c.Alerts.Validate(); err != nil {
+		return err
+	}
+	
+	if err := routing.ValidateRetryConfig(c.Router.DefaultRetry); err != nil {
+		return err
+	}
+	
+	if err := routing.ValidateRetryBudgetRatio(c.Router.RetryBudgetRatio); err != nil {
+		return err
+	}
+	
+	if c.Router.HealthCheckTimeout < 0 {
+		return fmt.Errorf("router.health_check_timeout must not be negative")
+	}
+	
+	if c.Router.HealthCheckConcurrency < 0 {
+		return fmt.Errorf("router.health_check_concurrency must not be negative")
+	}
+	
+	if err := server.ValidateModelPatterns(c.Router.AllowedModels); err != nil {
+		return err
+	}
+	
+	if _, err := routing.RequestTransformersByName(c.Router.RequestTransformers); err != nil {
+		return err
+	}
+	
+	if err := c.Router.Shadow.Validate(); err != nil {
+		return err
+	}
+	if c.Router.Shadow.Enabled && !slices.Contains(c.GetEnabledProviders(), c.Router.Shadow.Provider) {
+		return fmt.Errorf("shadow provider %s is not enabled", c.Router.Shadow.Provider)
+	}
+	
+	if c.Router.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max concurrent requests cannot be negative")
+	}
+	
+	// Validate provider weights
+	for provider, weight := range c.Router.Weights {
+		if weight < 0 {
+			return fmt.Errorf("weight for provider %s cannot be negative", provider)
+		}
+	}
+	
+	// Validate model aliases
+	for alias, target := range c.Router.Aliases {
+		if target == "" {
+			return fmt.Errorf("model alias %s must specify a target model", alias)
+		}
+	}
+	
+	// Validate model-to-provider mappings
+	for prefix := range c.Router.ModelPrefixMap {
+		if prefix == "" {
+			return fmt.Errorf("model prefix map cannot contain an empty prefix")
+		}
+	}
+	for model, provider := range c.Router.ModelOverrides {
+		if provider == "" {
+			return fmt.Errorf("model override for %s must specify a provider", model)
+		}
+	}
+	
+	// Validate model equivalents
+	for model, targets := range c.Router.ModelEquivalents {
+		for _, target := range targets {
+			if target.Provider == "" || target.Model == "" {
+				return fmt.Errorf("model equivalent for %s must specify provider and model", model)
+			}
+		}
+	}
+	
+	// Validate response cache
+	if c.Cache.Enabled && c.Cache.TTL <= 0 {
+		return fmt.Errorf("cache ttl must be positive when caching is enabled")
+	}
+	if err := c.Cache.Semantic.Validate(); err != nil {
+		return err
+	}
+	if c.Idempotency.Enabled && c.Idempotency.TTL <= 0 {
+		return fmt.Errorf("idempotency ttl must be positive when idempotency keys are enabled")
+	}
+	
+	// Validate tracing
+	if c.Tracing.Enabled && c.Tracing.ServiceName == "" {
+		return fmt.Errorf("tracing service name is required when tracing is enabled")
+	}
+	
+	// Validate usage accounting
+	if c.Accounting.Enabled && c.Accounting.Path == "" {
+		return fmt.Errorf("accounting path is required when accounting is enabled")
+	}
+	if err := c.Accounting.SpendLimits.Validate(); err != nil {
+		return err
+	}
+	
+	if err := c.Security.Audit.Validate(); err != nil {
+		return err
+	}
+	
+	if err := c.Providers.HTTPClient.Validate(); err != nil {
+		return err
+	}
+	
+	// Validate content moderation
+	if err := c.Security.Moderation.Validate(); err != nil {
+		return err
+	}
+	if c.Security.Moderation.Enabled && !slices.Contains(c.GetEnabledProviders(), c.Security.Moderation.Provider) {
+		return fmt.Errorf("moderation provider %s is not enabled", c.Security.Moderation.Provider)
+	}
+	
+	// Validate API key scopes
+	for key := range c.Security.KeyScopes {
+		if !slices.Contains(c.Security.APIKeys, key) {
+			return fmt.Errorf("key_scopes entry must reference a configured API key")
+		}
+	}
+	
+	// Validate CORS; browsers reject credentials for a wildcard origin
+	if c.Security.CORS.AllowCredentials && slices.Contains(c.Security.CORS.AllowedOrigins, "*") {
+		return fmt.Errorf("cors allow_credentials requires explicit allowed_origins, not \"*\"")
+	}
+	
 	// Validate logging level
 	validLogLevels := map[string]bool{
 		"debug": true,
@@ -320,11 +758,15 @@ func (c *Config) validate() error {
 	providerCount := 0
 	
 	if c.Providers.OpenAI != nil {
-		if c.Providers.OpenAI.APIKey == "" {
+		if len(c.Providers.OpenAI.Keys()) == 0 {
 			return fmt.Errorf("OpenAI API key is required when OpenAI provider is enabled")
 		}
-		if len(c.Providers.OpenAI.Models) == 0 {
-			return fmt.Errorf("OpenAI provider must have at least one model configured")
+		discoveryEnabled := c.Providers.OpenAI.Discovery != nil && c.Providers.OpenAI.Discovery.Enabled
+		if len(c.Providers.OpenAI.Models) == 0 && !discoveryEnabled {
+			return fmt.Errorf("OpenAI provider must have at least one model configured or discovery enabled")
+		}
+		if err := validateProviderCommon("OpenAI", &c.Providers.OpenAI.CommonConfig); err != nil {
+			return err
 		}
 		providerCount++
 	}
@@ -336,6 +778,63 @@ func (c *Config) validate() error {
 		if len(c.Providers.Anthropic.Models) == 0 {
 			return fmt.Errorf("Anthropic provider must have at least one model configured")
 		}
+		if err := validateProviderCommon("Anthropic", &c.Providers.Anthropic.CommonConfig); err != nil {
+			return err
+		}
+		providerCount++
+	}
+	
+	if c.Providers.Cohere != nil {
+		if c.Providers.Cohere.APIKey == "" {
+			return fmt.Errorf("Cohere API key is required when Cohere provider is enabled")
+		}
+		if len(c.Providers.Cohere.Models) == 0 {
+			return fmt.Errorf("Cohere provider must have at least one model configured")
+		}
+		if err := validateProviderCommon("Cohere", &c.Providers.Cohere.CommonConfig); err != nil {
+			return err
+		}
+		providerCount++
+	}
+	
+	if c.Providers.Mistral != nil {
+		if c.Providers.Mistral.APIKey == "" {
+			return fmt.Errorf("Mistral API key is required when Mistral provider is enabled")
+		}
+		if len(c.Providers.Mistral.Models) == 0 {
+			return fmt.Errorf("Mistral provider must have at least one model configured")
+		}
+		if err := validateProviderCommon("Mistral", &c.Providers.Mistral.CommonConfig); err != nil {
+			return err
+		}
+		providerCount++
+	}
+	
+	if c.Providers.Ollama != nil {
+		if len(c.Providers.Ollama.Models) == 0 {
+			return fmt.Errorf("Ollama provider must have at least one model configured")
+		}
+		if err := validateProviderCommon("Ollama", &c.Providers.Ollama.CommonConfig); err != nil {
+			return err
+		}
+		providerCount++
+	}
+	
+	if c.Providers.Bedrock != nil {
+		if len(c.Providers.Bedrock.Models) == 0 {
+			return fmt.Errorf("Bedrock provider must have at least one model configured")
+		}
+		if (c.Providers.Bedrock.AccessKeyID == "") != (c.Providers.Bedrock.SecretAccessKey == "") {
+			return fmt.Errorf("Bedrock access_key_id and secret_access_key must be set together")
+		}
+		// Bedrock requests must be SigV4 signed, which neither a custom
+		// probe nor a ping can do
+		if c.Providers.Bedrock.HealthProbe != nil || c.Providers.Bedrock.DisableLiveHealthCheck {
+			return fmt.Errorf("Bedrock does not support health_probe or disable_live_health_check")
+		}
+		if err := validateProviderCommon("Bedrock", &c.Providers.Bedrock.CommonConfig); err != nil {
+			return err
+		}
 		providerCount++
 	}
 	
@@ -346,6 +845,23 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// validateProviderCommon validates the settings every provider shares, naming
+// the provider in errors
+func validateProviderCommon(name string, common *providers.CommonConfig) error {
+	if common.HealthProbe != nil {
+		if err := common.HealthProbe.Validate(); err != nil {
+			return fmt.Errorf("invalid %s health probe: %w", name, err)
+		}
+	}
+	if common.HealthCheckInterval < 0 {
+		return fmt.Errorf("%s health check interval cannot be negative", name)
+	}
+	if err := common.Capabilities.Validate(); err != nil {
+		return fmt.Errorf("invalid %s capabilities: %w", name, err)
+	}
+	return nil
+}
+
 // ToServerConfig converts to server.ServerConfig
 func (c *Config) ToServerConfig() *server.ServerConfig {
 	return &server.ServerConfig{
@@ -354,16 +870,47 @@ func (c *Config) ToServerConfig() *server.ServerConfig {
 		WriteTimeout:   c.Server.WriteTimeout,
 		MaxHeaderBytes: c.Server.MaxHeaderBytes,
 		Security:       c.ToSecurityMiddlewareConfig(),
+		LogRequestBodies: c.Server.LogRequestBodies,
+		DebugLogging:     &c.Server.DebugLogging,
+		StreamHeartbeatInterval: c.Server.StreamHeartbeatInterval,
+		UpstreamHeaders:         c.Server.UpstreamHeaders,
+		MetricTagLabels:         c.Server.MetricTagLabels,
+		MessageLimits: &server.MessageLimits{
+			MaxMessages:      c.Security.RequestValidation.MaxMessages,
+			MaxMessageLength: c.Security.RequestValidation.MaxMessageLength,
+			MaxChoices:       c.Security.RequestValidation.MaxChoices,
+		},
+		Cache:          &c.Cache,
+		Idempotency:    &c.Idempotency,
+		Accounting:     &c.Accounting,
+		Privacy:        &c.Privacy,
+		Moderation:     &c.Security.Moderation,
+		RequestTimeout: c.Router.RequestTimeout,
+		AllowedModels:  c.Router.AllowedModels,
+		Alerts:         &c.Alerts,
 	}
 }
 
 // ToSecurityMiddlewareConfig converts to middleware.SecurityMiddlewareConfig
 func (c *Config) ToSecurityMiddlewareConfig() *middleware.SecurityMiddlewareConfig {
+	var hmacConfig *security.HMACAuthConfig
+	if c.Security.HMAC.Secret != "" {
+		hmacConfig = &c.Security.HMAC
+	}
+	
+	// NewAuditLogger fills in defaults on the config it is given
+	audit := c.Security.Audit
+	
 	return &middleware.SecurityMiddlewareConfig{
 		Auth: &security.Config{
 			APIKeys:        c.Security.APIKeys,
-			RequireAuth:    len(c.Security.APIKeys) > 0,
+			RequireAuth:    len(c.Security.APIKeys) > 0 || hmacConfig != nil,
 			AllowedOrigins: c.Security.CORS.AllowedOrigins,
+			TelemetryOptOutKeys: c.Security.TelemetryOptOutKeys,
+			KeyScopes:          c.Security.KeyScopes,
+			JWTSecret:          c.Security.JWT.Secret,
+			JWTExpiry:          c.Security.JWT.Expiry,
+			RefreshTokenExpiry: c.Security.JWT.RefreshTokenExpiry,
 		},
 		RateLimit: &security.RateLimitConfig{
 			Enabled:           c.Security.RateLimiting.Enabled,
@@ -371,19 +918,28 @@ func (c *Config) ToSecurityMiddlewareConfig() *middleware.SecurityMiddlewareConf
 			BurstSize:         c.Security.RateLimiting.BurstSize,
 			WindowDuration:    c.Security.RateLimiting.WindowDuration,
 			CleanupInterval:   5 * time.Minute,
+			RedisURL:          c.Security.RateLimiting.RedisURL,
+			PerModelLimits:    c.Security.RateLimiting.PerModelLimits,
+			PerProviderLimits: c.Security.RateLimiting.PerProviderLimits,
+			ModelTiers:        c.Security.RateLimiting.ModelTiers,
+			TierLimits:        c.Security.RateLimiting.TierLimits,
 		},
 		Validation: &security.ValidationConfig{
-			MaxRequestSize:    10 * 1024 * 1024, // 10MB
+			MaxRequestSize:    c.Security.RequestValidation.MaxRequestSize,
 			AllowedMethods:    c.Security.CORS.AllowedMethods,
 			ContentTypes:      []string{"application/json", "text/plain"},
 			MaxJSONDepth:      20,
 			MaxFieldLength:    1024,
 		},
-		Audit: &security.AuditConfig{
-			Enabled:     true,
-			BufferSize:  1000,
-			FlushInterval: 10 * time.Second,
+		Audit: &audit,
+		CORS: &middleware.CORSConfig{
+			AllowedOrigins:   c.Security.CORS.AllowedOrigins,
+			AllowedMethods:   c.Security.CORS.AllowedMethods,
+			AllowedHeaders:   c.Security.CORS.AllowedHeaders,
+			AllowCredentials: c.Security.CORS.AllowCredentials,
+			MaxAge:           c.Security.CORS.MaxAge,
 		},
+		HMAC: hmacConfig,
 	}
 }
 
@@ -405,7 +961,7 @@ func (c *Config) SaveToFile(path string) error {
 func (c *Config) GetEnabledProviders() []string {
 	var providers []string
 	
-	if c.Providers.OpenAI != nil && c.Providers.OpenAI.APIKey != "" {
+	if c.Providers.OpenAI != nil && len(c.Providers.OpenAI.Keys()) > 0 {
 		providers = append(providers, "openai")
 	}
 	
@@ -413,5 +969,32 @@ func (c *Config) GetEnabledProviders() []string {
 		providers = append(providers, "anthropic")
 	}
 	
+	if c.Providers.Cohere != nil && c.Providers.Cohere.APIKey != "" {
+		providers = append(providers, "cohere")
+	}
+	
+	if c.Providers.Mistral != nil && c.Providers.Mistral.APIKey != "" {
+		providers = append(providers, "mistral")
+	}
+	
+	if c.Providers.Ollama != nil {
+		providers = append(providers, "ollama")
+	}
+	
+	if c.Providers.Bedrock != nil {
+		providers = append(providers, "bedrock")
+	}
+	
 	return providers
-}
\ No newline at end of file
+}
+
+// splitList splits a comma-separated environment value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
 )
 
 func TestLoadConfig_Defaults(t *testing.T) {
@@ -168,8 +170,11 @@ logging:
 providers:
   openai:
     api_key: "file-openai-key"
+    health_check_interval: 45s
   anthropic:
     api_key: "file-anthropic-key"
+    capabilities:
+      supports_batch: true
 `
 	
 	tmpFile, err := os.CreateTemp("", "test_config_*.yaml")
@@ -209,6 +214,130 @@ providers:
 	if cfg.Providers.OpenAI.APIKey != "file-openai-key" {
 		t.Errorf("Expected OpenAI key 'file-openai-key', got %s", cfg.Providers.OpenAI.APIKey)
 	}
+	
+	if cfg.Providers.OpenAI.HealthCheckInterval != 45*time.Second {
+		t.Errorf("Expected OpenAI health check interval 45s, got %v", cfg.Providers.OpenAI.HealthCheckInterval)
+	}
+	
+	if !cfg.Providers.Anthropic.Capabilities["supports_batch"] {
+		t.Errorf("Expected Anthropic capability override supports_batch, got %v", cfg.Providers.Anthropic.Capabilities)
+	}
+}
+
+func TestLoadConfig_ModelPrefixMap(t *testing.T) {
+	configContent := `
+router:
+  model_prefix_map:
+    "llama": "ollama"
+    "command-": ""
+  model_overrides:
+    "ft:gpt-4o-mini:acme": "azure"
+
+providers:
+  openai:
+    api_key: "file-openai-key"
+`
+	
+	tmpFile, err := os.CreateTemp("", "test_config_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	
+	if _, err := tmpFile.WriteString(configContent); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	tmpFile.Close()
+	
+	cfg, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	
+	// Configured prefixes are merged over the defaults
+	prefixes := cfg.Router.ModelPrefixMap
+	if prefixes["gpt-"] != "openai" || prefixes["llama"] != "ollama" {
+		t.Errorf("Expected defaults plus configured prefixes, got %v", prefixes)
+	}
+	if provider, ok := prefixes["command-"]; !ok || provider != "" {
+		t.Errorf("Expected command- prefix to be disabled, got %v", prefixes)
+	}
+	if cfg.Router.ModelOverrides["ft:gpt-4o-mini:acme"] != "azure" {
+		t.Errorf("Expected model override to load, got %v", cfg.Router.ModelOverrides)
+	}
+	
+	// Loading must not modify the package defaults
+	if routing.DefaultModelPrefixes["command-"] != "cohere" {
+		t.Errorf("Expected defaults to be untouched, got %v", routing.DefaultModelPrefixes)
+	}
+}
+
+func TestLoadConfig_ProviderHealthSettings(t *testing.T) {
+	load := func(configContent string) (*Config, error) {
+		tmpFile, err := os.CreateTemp("", "test_config_*.yaml")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpFile.Name())
+		
+		if _, err := tmpFile.WriteString(configContent); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		tmpFile.Close()
+		return LoadConfig(tmpFile.Name())
+	}
+	
+	// Health settings are shared by every provider
+	cfg, err := load(`
+providers:
+  openai:
+    api_key: "file-openai-key"
+  mistral:
+    api_key: "file-mistral-key"
+    models:
+      - name: "mistral-small-latest"
+    health_check_model: "mistral-small-latest"
+    health_probe:
+      path: "/models"
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	mistral := cfg.Providers.Mistral
+	if mistral.HealthCheckModel != "mistral-small-latest" || mistral.HealthProbe == nil || mistral.HealthProbe.Path != "/models" {
+		t.Errorf("Expected Mistral health settings to load, got %+v", mistral.CommonConfig)
+	}
+	
+	// and validated the same way for each
+	_, err = load(`
+providers:
+  openai:
+    api_key: "file-openai-key"
+  mistral:
+    api_key: "file-mistral-key"
+    models:
+      - name: "mistral-small-latest"
+    health_probe:
+      method: "GET"
+`)
+	if err == nil || !containsString(err.Error(), "invalid Mistral health probe") {
+		t.Errorf("Expected invalid Mistral health probe error, got %v", err)
+	}
+	
+	// Bedrock can't sign a probe or ping
+	_, err = load(`
+providers:
+  openai:
+    api_key: "file-openai-key"
+  bedrock:
+    region: "us-east-1"
+    models:
+      - name: "anthropic.claude-3-haiku-20240307-v1:0"
+    disable_live_health_check: true
+`)
+	if err == nil || !containsString(err.Error(), "Bedrock does not support") {
+		t.Errorf("Expected Bedrock health settings error, got %v", err)
+	}
 }
 
 func TestConfig_GetEnabledProviders(t *testing.T) {
@@ -384,4 +513,59 @@ func BenchmarkConfig_GetEnabledProviders(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = cfg.GetEnabledProviders()
 	}
-}
\ No newline at end of file
+}
+func TestConfig_Reload(t *testing.T) {
+	pricedConfig := func(inputCost string) string {
+		return `
+router:
+  default_strategy: "cost_optimized"
+
+providers:
+  openai:
+    api_key: "file-openai-key"
+    models:
+      - name: "gpt-4o"
+        input_cost_per_1k: ` + inputCost + `
+        output_cost_per_1k: 0.015
+`
+	}
+	
+	tmpFile, err := os.CreateTemp("", "test_reload_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+	
+	if err := os.WriteFile(tmpFile.Name(), []byte(pricedConfig("0.005")), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadConfig(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	
+	// Changed pricing is picked up
+	if err := os.WriteFile(tmpFile.Name(), []byte(pricedConfig("0.001")), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	reloaded, err := cfg.Reload(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if cost := reloaded.Providers.OpenAI.Models[0].InputCostPer1K; cost != 0.001 {
+		t.Errorf("Expected reloaded input cost 0.001, got %f", cost)
+	}
+	if cost := cfg.Providers.OpenAI.Models[0].InputCostPer1K; cost != 0.005 {
+		t.Errorf("Expected original config to be untouched, got input cost %f", cost)
+	}
+	
+	// Invalid configuration is rejected
+	invalid := pricedConfig("0.001") + "\nlogging:\n  level: \"loud\"\n"
+	if err := os.WriteFile(tmpFile.Name(), []byte(invalid), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := cfg.Reload(tmpFile.Name()); err == nil {
+		t.Error("Expected reload of invalid config to fail")
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretRefPattern matches secret references such as ${env:OPENAI_API_KEY},
+// ${file:/run/secrets/openai} and ${vault:secret/data/llm#openai}
+var secretRefPattern = regexp.MustCompile(`^\$\{([a-z]+):(.+)\}$`)
+
+// SecretResolver resolves references of one scheme to their secret value.
+// A positive TTL lets the value be cached until it expires; zero means the
+// value is cheap to re-read and isn't cached.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (value string, ttl time.Duration, err error)
+}
+
+// EnvResolver resolves ${env:VAR} from the process environment
+type EnvResolver struct{}
+
+func (EnvResolver) Resolve(ctx context.Context, ref string) (string, time.Duration, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", 0, fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, 0, nil
+}
+
+// FileResolver resolves ${file:/path} to the file's contents, trimming the
+// trailing newline secret mounts usually carry
+type FileResolver struct{}
+
+func (FileResolver) Resolve(ctx context.Context, ref string) (string, time.Duration, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", 0, err
+	}
+	return strings.TrimRight(string(data), "\r\n"), 0, nil
+}
+
+// VaultResolver resolves ${vault:path#field} by reading path from the
+// HashiCorp Vault HTTP API. KV version 2 responses are unwrapped, so
+// secret/data/llm#openai reads field openai of KV v2 secret llm.
+type VaultResolver struct {
+	Address string // defaults to VAULT_ADDR
+	Token   string // defaults to VAULT_TOKEN
+	Client  *http.Client
+
+	// How long secrets without a lease, such as KV entries, are cached
+	// before being re-read. Defaults to 5 minutes.
+	DefaultTTL time.Duration
+}
+
+func (v *VaultResolver) Resolve(ctx context.Context, ref string) (string, time.Duration, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", 0, fmt.Errorf("vault reference %q must be path#field", ref)
+	}
+
+	address := v.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return "", 0, errors.New("vault address is not configured (set VAULT_ADDR)")
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(address, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		LeaseDuration int                    `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	data := body.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = inner // KV version 2
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("vault secret %s has no string field %s", path, field)
+	}
+
+	ttl := time.Duration(body.LeaseDuration) * time.Second
+	if ttl <= 0 {
+		ttl = v.DefaultTTL
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return value, ttl, nil
+}
+
+// SecretStore resolves secret references through per-scheme resolvers,
+// caching values that have a TTL
+type SecretStore struct {
+	resolvers map[string]SecretResolver
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+	now   func() time.Time
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// Secrets resolves the references in configurations loaded by LoadConfig
+var Secrets = NewSecretStore(nil)
+
+// NewSecretStore creates a store using resolvers keyed by scheme. Nil
+// resolvers selects env, file and vault, with Vault reached through
+// VAULT_ADDR and VAULT_TOKEN.
+func NewSecretStore(resolvers map[string]SecretResolver) *SecretStore {
+	if resolvers == nil {
+		resolvers = map[string]SecretResolver{
+			"env":   EnvResolver{},
+			"file":  FileResolver{},
+			"vault": &VaultResolver{},
+		}
+	}
+	return &SecretStore{
+		resolvers: resolvers,
+		cache:     make(map[string]cachedSecret),
+		now:       time.Now,
+	}
+}
+
+// Resolve returns the secret value references. Values that aren't secret
+// references are returned unchanged.
+func (s *SecretStore) Resolve(ctx context.Context, value string) (string, error) {
+	match := secretRefPattern.FindStringSubmatch(value)
+	if match == nil {
+		return value, nil
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[value]
+	s.mu.Unlock()
+	if ok && s.now().Before(cached.expires) {
+		return cached.value, nil
+	}
+	return s.resolve(ctx, value, match[1], match[2])
+}
+
+// Refresh re-resolves cached secrets whose TTL has expired, such as Vault
+// leases, and reports whether any value changed. Secrets that fail to
+// refresh keep their previous value until the next attempt.
+func (s *SecretStore) Refresh(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	var expired []string
+	for ref, cached := range s.cache {
+		if !s.now().Before(cached.expires) {
+			expired = append(expired, ref)
+		}
+	}
+	s.mu.Unlock()
+
+	changed := false
+	var errs []error
+	for _, ref := range expired {
+		s.mu.Lock()
+		previous := s.cache[ref].value
+		s.mu.Unlock()
+
+		match := secretRefPattern.FindStringSubmatch(ref)
+		value, err := s.resolve(ctx, ref, match[1], match[2])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changed = changed || value != previous
+	}
+	return changed, errors.Join(errs...)
+}
+
+// resolve looks up ref with its scheme's resolver and caches the result
+func (s *SecretStore) resolve(ctx context.Context, ref, scheme, key string) (string, error) {
+	resolver, ok := s.resolvers[scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown secret scheme %q", scheme)
+	}
+
+	value, ttl, err := resolver.Resolve(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret %s: %w", scheme, key, err)
+	}
+
+	if ttl > 0 {
+		s.mu.Lock()
+		s.cache[ref] = cachedSecret{value: value, expires: s.now().Add(ttl)}
+		s.mu.Unlock()
+	}
+	return value, nil
+}
+
+// resolveSecrets replaces secret references in provider API keys and the
+// JWT secret with their values
+func (c *Config) resolveSecrets(ctx context.Context, store *SecretStore) error {
+	var fields []*string
+	if c.Providers.OpenAI != nil {
+		fields = append(fields, &c.Providers.OpenAI.APIKey)
+		for i := range c.Providers.OpenAI.APIKeys {
+			fields = append(fields, &c.Providers.OpenAI.APIKeys[i])
+		}
+	}
+	if c.Providers.Anthropic != nil {
+		fields = append(fields, &c.Providers.Anthropic.APIKey)
+	}
+	if c.Providers.Cohere != nil {
+		fields = append(fields, &c.Providers.Cohere.APIKey)
+	}
+	if c.Providers.Mistral != nil {
+		fields = append(fields, &c.Providers.Mistral.APIKey)
+	}
+	if c.Providers.Bedrock != nil {
+		fields = append(fields, &c.Providers.Bedrock.AccessKeyID, &c.Providers.Bedrock.SecretAccessKey, &c.Providers.Bedrock.SessionToken)
+	}
+	fields = append(fields, &c.Security.JWT.Secret)
+
+	for _, field := range fields {
+		value, err := store.Resolve(ctx, *field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
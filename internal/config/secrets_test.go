@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newVaultServer serves a KV v2 secret with field openai, returning the
+// value of the current version
+func newVaultServer(t *testing.T, version *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/llm" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		value := "vault-key-v1"
+		if version.Load() > 1 {
+			value = "vault-key-v2"
+		}
+		w.Write([]byte(`{"lease_duration":0,"data":{"data":{"openai":"` + value + `"},"metadata":{"version":1}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSecretStore_Resolve(t *testing.T) {
+	os.Setenv("TEST_SECRET_KEY", "env-key")
+	defer os.Unsetenv("TEST_SECRET_KEY")
+
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("file-key\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	var version atomic.Int32
+	version.Store(1)
+	vault := newVaultServer(t, &version)
+
+	store := NewSecretStore(map[string]SecretResolver{
+		"env":   EnvResolver{},
+		"file":  FileResolver{},
+		"vault": &VaultResolver{Address: vault.URL, Token: "test-token"},
+	})
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "${env:TEST_SECRET_KEY}", want: "env-key"},
+		{value: "${file:" + path + "}", want: "file-key"},
+		{value: "${vault:secret/data/llm#openai}", want: "vault-key-v1"},
+		{value: "sk-literal-key", want: "sk-literal-key"},
+		{value: "${ANTHROPIC_API_KEY}", want: "${ANTHROPIC_API_KEY}"}, // not a scheme reference
+		{value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		got, err := store.Resolve(context.Background(), tt.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.value, tt.want, got)
+		}
+	}
+
+	for _, value := range []string{"${env:TEST_SECRET_UNSET}", "${file:/nonexistent/key}", "${vault:secret/data/llm#missing}", "${vault:secret/data/llm}", "${aws:key}"} {
+		if _, err := store.Resolve(context.Background(), value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestSecretStore_CacheAndRefresh(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	vault := newVaultServer(t, &version)
+
+	store := NewSecretStore(map[string]SecretResolver{
+		"vault": &VaultResolver{Address: vault.URL, Token: "test-token", DefaultTTL: time.Minute},
+	})
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	ref := "${vault:secret/data/llm#openai}"
+	if got, _ := store.Resolve(context.Background(), ref); got != "vault-key-v1" {
+		t.Fatalf("Expected vault-key-v1, got %q", got)
+	}
+
+	// Cached until the TTL expires
+	version.Store(2)
+	if got, _ := store.Resolve(context.Background(), ref); got != "vault-key-v1" {
+		t.Errorf("Expected cached vault-key-v1, got %q", got)
+	}
+	if changed, err := store.Refresh(context.Background()); changed || err != nil {
+		t.Errorf("Expected no refresh before expiry, got changed=%v err=%v", changed, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	changed, err := store.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if !changed {
+		t.Error("Expected refresh to report the rotated secret")
+	}
+	if got, _ := store.Resolve(context.Background(), ref); got != "vault-key-v2" {
+		t.Errorf("Expected vault-key-v2 after refresh, got %q", got)
+	}
+}
+
+func TestLoadConfig_SecretReferences(t *testing.T) {
+	t.Setenv("TEST_OPENAI_SECRET", "sk-from-env")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	dir := t.TempDir()
+	jwtPath := filepath.Join(dir, "jwt")
+	os.WriteFile(jwtPath, []byte("jwt-from-file\n"), 0600)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(configPath, []byte(`
+providers:
+  openai:
+    api_key: "${env:TEST_OPENAI_SECRET}"
+  anthropic:
+    api_key: "sk-ant-literal"
+security:
+  jwt:
+    secret: "${file:`+jwtPath+`}"
+`), 0600)
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Providers.OpenAI.APIKey != "sk-from-env" {
+		t.Errorf("Expected OpenAI key from env reference, got %q", cfg.Providers.OpenAI.APIKey)
+	}
+	if cfg.Providers.Anthropic.APIKey != "sk-ant-literal" {
+		t.Errorf("Expected literal Anthropic key unchanged, got %q", cfg.Providers.Anthropic.APIKey)
+	}
+	if cfg.Security.JWT.Secret != "jwt-from-file" {
+		t.Errorf("Expected JWT secret from file reference, got %q", cfg.Security.JWT.Secret)
+	}
+
+	os.Unsetenv("TEST_OPENAI_SECRET")
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("Expected an error for an unresolvable secret reference")
+	}
+}
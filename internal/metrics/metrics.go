@@ -0,0 +1,280 @@
+// Package metrics exposes Prometheus metrics for the LLM router.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "llm_router"
+
+// Request status label values
+const (
+	StatusSuccess          = "success"
+	StatusError            = "error"
+	StatusRoutingError     = "routing_error"
+	StatusRateLimited      = "rate_limited"
+	StatusCacheHit         = "cache_hit"
+	StatusSemanticCacheHit = "semantic_cache_hit"
+	StatusTimeout          = "timeout"
+	StatusOverloaded       = "overloaded"
+	StatusBlocked          = "blocked"   // rejected by content moderation
+	StatusCancelled        = "cancelled" // client disconnected mid-stream
+)
+
+// tagLabelPattern matches valid Prometheus label names, excluding the
+// reserved double-underscore prefix
+var tagLabelPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$|^_[a-zA-Z0-9][a-zA-Z0-9_]*$`)
+
+// ValidateTagLabels checks request tag keys allowlisted as metric labels:
+// each must be a valid Prometheus label name, listed once and not clash with
+// the labels the tagged metrics already have
+func ValidateTagLabels(tagLabels []string) error {
+	seen := make(map[string]bool, len(tagLabels))
+	for _, label := range tagLabels {
+		if !tagLabelPattern.MatchString(label) {
+			return fmt.Errorf("metric tag label %q is not a valid Prometheus label name", label)
+		}
+		switch label {
+		case "provider", "model", "type":
+			return fmt.Errorf("metric tag label %q clashes with a built-in label", label)
+		}
+		if seen[label] {
+			return fmt.Errorf("metric tag label %q is listed twice", label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+// Metrics holds the router's Prometheus collectors. Each instance owns its
+// own registry so independent servers (and tests) don't collide.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestsTotal        *prometheus.CounterVec
+	requestDuration      *prometheus.HistogramVec
+	estimatedCost        *prometheus.GaugeVec
+	tokensTotal          *prometheus.CounterVec
+	providerHealth       *prometheus.GaugeVec
+	inFlightRequests     *prometheus.GaugeVec
+	httpRequestsTotal    *prometheus.CounterVec
+	clientRequestsTotal  *prometheus.CounterVec
+	retryBudgetExhausted prometheus.Counter
+
+	// Usage by request tag, registered only when tag labels are allowlisted
+	tagLabels           []string
+	taggedRequestsTotal *prometheus.CounterVec
+	taggedTokensTotal   *prometheus.CounterVec
+	taggedCost          *prometheus.CounterVec
+}
+
+// New creates and registers the router metrics. Each of tagLabels is a
+// request tag key, such as tenant_id, added as a label to the tagged usage
+// metrics; other tags never become labels, which keeps cardinality bounded.
+func New(tagLabels ...string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of completion requests by provider, model, status and fallback use",
+		}, []string{"provider", "model", "status", "fallback"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Completion request duration in seconds",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"provider"}),
+		estimatedCost: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "estimated_cost_usd_total",
+			Help:      "Cumulative estimated cost in USD of routed requests",
+		}, []string{"provider"}),
+		tokensTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_total",
+			Help:      "Total number of tokens processed",
+		}, []string{"provider", "type"}),
+		providerHealth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "provider_health",
+			Help:      "Provider health status (1=healthy, 0=unhealthy)",
+		}, []string{"provider"}),
+		inFlightRequests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "in_flight_requests",
+			Help:      "Requests currently holding a concurrency slot, by priority",
+		}, []string{"priority"}),
+		httpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests",
+		}, []string{"method", "status_code"}),
+		clientRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "client_requests_total",
+			Help:      "HTTP requests per client (excludes telemetry opt-out traffic)",
+		}, []string{"method", "status_code", "client_ip"}),
+		retryBudgetExhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retry_budget_exhausted_total",
+			Help:      "Completion requests whose retries were skipped because the retry budget was exhausted",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requestsTotal,
+		m.requestDuration,
+		m.estimatedCost,
+		m.tokensTotal,
+		m.providerHealth,
+		m.inFlightRequests,
+		m.httpRequestsTotal,
+		m.clientRequestsTotal,
+		m.retryBudgetExhausted,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	if len(tagLabels) > 0 {
+		m.tagLabels = append([]string(nil), tagLabels...)
+		m.taggedRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tagged_requests_total",
+			Help:      "Completed requests by provider, model and allowlisted request tags",
+		}, append([]string{"provider", "model"}, m.tagLabels...))
+		m.taggedTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tagged_tokens_total",
+			Help:      "Tokens used by provider, type and allowlisted request tags",
+		}, append([]string{"provider", "type"}, m.tagLabels...))
+		m.taggedCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tagged_cost_usd_total",
+			Help:      "Priced cost in USD by provider and allowlisted request tags",
+		}, append([]string{"provider"}, m.tagLabels...))
+		m.registry.MustRegister(m.taggedRequestsTotal, m.taggedTokensTotal, m.taggedCost)
+	}
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in Prometheus format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the underlying Prometheus registry
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// RecordRequest records the outcome and duration of a completion request
+func (m *Metrics) RecordRequest(provider, model, status string, fallback bool, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	if provider == "" {
+		provider = "none"
+	}
+	m.requestsTotal.WithLabelValues(provider, model, status, strconv.FormatBool(fallback)).Inc()
+	m.requestDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
+// RecordEstimatedCost adds a routed request's estimated cost for a provider
+func (m *Metrics) RecordEstimatedCost(provider string, cost float64) {
+	if m == nil || cost <= 0 {
+		return
+	}
+	m.estimatedCost.WithLabelValues(provider).Add(cost)
+}
+
+// RecordTokens adds prompt and completion token usage for a provider
+func (m *Metrics) RecordTokens(provider string, promptTokens, completionTokens int) {
+	if m == nil {
+		return
+	}
+	if promptTokens > 0 {
+		m.tokensTotal.WithLabelValues(provider, "input").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		m.tokensTotal.WithLabelValues(provider, "output").Add(float64(completionTokens))
+	}
+}
+
+// SetProviderHealth updates a provider's health gauge
+func (m *Metrics) SetProviderHealth(provider string, healthy bool) {
+	if m == nil {
+		return
+	}
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	m.providerHealth.WithLabelValues(provider).Set(value)
+}
+
+// TrackInFlight counts a request of the given priority as in flight until
+// the returned func is called
+func (m *Metrics) TrackInFlight(priority string) func() {
+	if m == nil {
+		return func() {}
+	}
+	gauge := m.inFlightRequests.WithLabelValues(priority)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// RecordHTTPRequest counts an HTTP request. The per-client series is skipped
+// when the request opted out of telemetry; the aggregate is always counted.
+func (m *Metrics) RecordHTTPRequest(method string, statusCode int, clientIP string, optedOut bool) {
+	if m == nil {
+		return
+	}
+	code := strconv.Itoa(statusCode)
+	m.httpRequestsTotal.WithLabelValues(method, code).Inc()
+	if !optedOut {
+		m.clientRequestsTotal.WithLabelValues(method, code, clientIP).Inc()
+	}
+}
+
+// RecordRetryBudgetExhausted counts a request whose retries were skipped
+// because the router-wide retry budget was spent
+func (m *Metrics) RecordRetryBudgetExhausted() {
+	if m == nil {
+		return
+	}
+	m.retryBudgetExhausted.Inc()
+}
+
+// RecordTaggedUsage attributes a completed request's tokens and cost to its
+// allowlisted tags. Tags the request doesn't carry get an empty label value.
+// It does nothing unless New was given tag labels.
+func (m *Metrics) RecordTaggedUsage(provider, model string, tags map[string]string, promptTokens, completionTokens int, cost float64) {
+	if m == nil || len(m.tagLabels) == 0 {
+		return
+	}
+	values := make([]string, len(m.tagLabels))
+	for i, label := range m.tagLabels {
+		values[i] = tags[label]
+	}
+
+	m.taggedRequestsTotal.WithLabelValues(append([]string{provider, model}, values...)...).Inc()
+	if promptTokens > 0 {
+		m.taggedTokensTotal.WithLabelValues(append([]string{provider, "input"}, values...)...).Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		m.taggedTokensTotal.WithLabelValues(append([]string{provider, "output"}, values...)...).Add(float64(completionTokens))
+	}
+	if cost > 0 {
+		m.taggedCost.WithLabelValues(append([]string{provider}, values...)...).Add(cost)
+	}
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_RecordRequest_FallbackLabel(t *testing.T) {
+	m := New()
+
+	m.RecordRequest("openai", "gpt-4o", StatusSuccess, false, 200*time.Millisecond)
+	m.RecordRequest("anthropic", "gpt-4o", StatusSuccess, true, 900*time.Millisecond)
+	m.RecordRequest("", "gpt-4o", StatusRoutingError, false, time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requestsTotal.WithLabelValues("openai", "gpt-4o", StatusSuccess, "false")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requestsTotal.WithLabelValues("anthropic", "gpt-4o", StatusSuccess, "true")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requestsTotal.WithLabelValues("none", "gpt-4o", StatusRoutingError, "false")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestMetrics_RecordEstimatedCost(t *testing.T) {
+	m := New()
+
+	m.RecordEstimatedCost("openai", 0.25)
+	m.RecordEstimatedCost("openai", 0.5)
+	m.RecordEstimatedCost("openai", 0) // ignored
+
+	assert.InDelta(t, 0.75, testutil.ToFloat64(m.estimatedCost.WithLabelValues("openai")), 1e-9)
+}
+
+func TestMetrics_RecordHTTPRequest_OptOut(t *testing.T) {
+	m := New()
+
+	m.RecordHTTPRequest("POST", 200, "10.0.0.1", false)
+	m.RecordHTTPRequest("POST", 200, "10.0.0.2", true)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequestsTotal.WithLabelValues("POST", "200")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.clientRequestsTotal))
+}
+
+func TestMetrics_RecordRetryBudgetExhausted(t *testing.T) {
+	m := New()
+
+	m.RecordRetryBudgetExhausted()
+	m.RecordRetryBudgetExhausted()
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.retryBudgetExhausted))
+}
+
+func TestMetrics_RecordTaggedUsage(t *testing.T) {
+	m := New("tenant_id")
+
+	m.RecordTaggedUsage("openai", "gpt-4o", map[string]string{"tenant_id": "acme", "team": "search"}, 10, 5, 0.5)
+	m.RecordTaggedUsage("openai", "gpt-4o", nil, 3, 0, 0)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.taggedRequestsTotal.WithLabelValues("openai", "gpt-4o", "acme")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.taggedRequestsTotal.WithLabelValues("openai", "gpt-4o", "")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.taggedTokensTotal.WithLabelValues("openai", "input", "acme")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(m.taggedTokensTotal.WithLabelValues("openai", "output", "acme")))
+	assert.InDelta(t, 0.5, testutil.ToFloat64(m.taggedCost.WithLabelValues("openai", "acme")), 1e-9)
+
+	// Without allowlisted labels the tagged metrics aren't registered
+	untagged := New()
+	assert.NotPanics(t, func() {
+		untagged.RecordTaggedUsage("openai", "gpt-4o", map[string]string{"tenant_id": "acme"}, 1, 1, 1)
+	})
+	families, err := untagged.Registry().Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		assert.NotContains(t, family.GetName(), "tagged")
+	}
+}
+
+func TestValidateTagLabels(t *testing.T) {
+	assert.NoError(t, ValidateTagLabels(nil))
+	assert.NoError(t, ValidateTagLabels([]string{"tenant_id", "cost_center"}))
+	assert.Error(t, ValidateTagLabels([]string{"tenant-id"}))
+	assert.Error(t, ValidateTagLabels([]string{"__name"}))
+	assert.Error(t, ValidateTagLabels([]string{"provider"}))
+	assert.Error(t, ValidateTagLabels([]string{"tenant_id", "tenant_id"}))
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.SetProviderHealth("openai", true)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Contains(t, w.Body.String(), `llm_router_provider_health{provider="openai"} 1`)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+func TestMetrics_NilSafe(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.RecordRequest("openai", "gpt-4o", StatusSuccess, false, time.Second)
+		m.RecordEstimatedCost("openai", 1)
+		m.RecordTokens("openai", 1, 1)
+		m.SetProviderHealth("openai", true)
+		m.RecordHTTPRequest("GET", 200, "127.0.0.1", false)
+		m.RecordRetryBudgetExhausted()
+		m.RecordTaggedUsage("openai", "gpt-4o", nil, 1, 1, 1)
+	})
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig holds cross-origin resource sharing configuration
+type CORSConfig struct {
+	// Origins allowed to call the API: "*" for any, an exact origin, or a
+	// subdomain wildcard such as "https://*.example.com"
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"` // never sent for origins matched by "*"
+	MaxAge           int      `yaml:"max_age"`           // preflight cache lifetime in seconds
+}
+
+// Default CORS settings, used for fields left empty
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-API-Key", "Idempotency-Key"}
+)
+
+const defaultCORSMaxAge = 86400
+
+// CORS creates middleware answering cross-origin requests from allowed
+// origins. The request origin is echoed back only when it matches an allowed
+// entry; other origins get no CORS headers, so browsers block them. A nil
+// config allows any origin without credentials.
+func CORS(config *CORSConfig) func(http.Handler) http.Handler {
+	if config == nil {
+		config = &CORSConfig{AllowedOrigins: []string{"*"}}
+	}
+
+	methods := strings.Join(orDefault(config.AllowedMethods, defaultCORSMethods), ", ")
+	headers := strings.Join(orDefault(config.AllowedHeaders, defaultCORSHeaders), ", ")
+	maxAge := config.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" {
+				w.Header().Add("Vary", "Origin")
+
+				switch match := matchOrigin(origin, config.AllowedOrigins); match {
+				case originAny:
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				case originListed:
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					if config.AllowCredentials {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+				}
+				if w.Header().Get("Access-Control-Allow-Origin") != "" {
+					w.Header().Set("Access-Control-Allow-Methods", methods)
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+				}
+			}
+
+			// Handle preflight OPTIONS requests
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// originMatch is how an origin matched the allowed list
+type originMatch int
+
+const (
+	originDenied originMatch = iota
+	originAny                // matched "*"
+	originListed             // matched an exact or wildcard entry
+)
+
+// matchOrigin checks origin against the allowed entries. Listed entries take
+// precedence over "*" so that they can be sent credentials.
+func matchOrigin(origin string, allowed []string) originMatch {
+	match := originDenied
+	for _, entry := range allowed {
+		switch {
+		case entry == "*":
+			match = originAny
+		case strings.EqualFold(entry, origin), matchWildcardOrigin(entry, origin):
+			return originListed
+		}
+	}
+	return match
+}
+
+// matchWildcardOrigin matches an origin against a pattern such as
+// "https://*.example.com", where "*" stands for one or more subdomain labels
+func matchWildcardOrigin(pattern, origin string) bool {
+	prefix, suffix, ok := strings.Cut(strings.ToLower(pattern), "*")
+	if !ok || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+
+	// The wildcard covers host labels only, not ports or paths
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(subdomain, "/:@") && !strings.HasPrefix(subdomain, ".")
+}
+
+// orDefault returns values, or fallback when values is empty
+func orDefault(values, fallback []string) []string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveCORS(config *CORSConfig, method, origin string) *httptest.ResponseRecorder {
+	handler := CORS(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(method, "/v1/chat/completions", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_AllowedOrigin(t *testing.T) {
+	config := &CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+	}
+
+	w := serveCORS(config, http.MethodOptions, "https://app.example.com")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+}
+
+func TestCORS_DeniedOrigin(t *testing.T) {
+	config := &CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.com"},
+		AllowCredentials: true,
+	}
+
+	for _, origin := range []string{
+		"https://evil.com",
+		"https://example.com.evil.com",
+		"http://app.example.com",        // scheme must match
+		"https://app.example.com:8443",  // port must match
+		"https://evil.com/.example.com", // wildcard covers host labels only
+	} {
+		w := serveCORS(config, http.MethodGet, origin)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), origin)
+	}
+}
+
+func TestCORS_WildcardSubdomain(t *testing.T) {
+	config := &CORSConfig{AllowedOrigins: []string{"https://*.example.com"}}
+
+	for _, origin := range []string{"https://app.example.com", "https://eu.app.example.com"} {
+		w := serveCORS(config, http.MethodGet, origin)
+		assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// The bare domain isn't a subdomain
+	w := serveCORS(config, http.MethodGet, "https://example.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	// Credentials are never granted to origins matched only by "*"
+	config := &CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+
+	w := serveCORS(config, http.MethodGet, "https://anywhere.example")
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -2,9 +2,11 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/tributary-ai/llm-router-waf/internal/security"
@@ -16,12 +18,18 @@ type SecurityMiddlewareConfig struct {
 	RateLimit  *security.RateLimitConfig  `yaml:"rate_limit"`
 	Validation *security.ValidationConfig `yaml:"validation"`
 	Audit      *security.AuditConfig      `yaml:"audit"`
+	CORS       *CORSConfig                `yaml:"cors"`
+	
+	// Request signing, accepted as an alternative to API keys and JWTs
+	HMAC *security.HMACAuthConfig `yaml:"hmac"`
 }
 
 // SecurityMiddleware combines all security middleware components
 type SecurityMiddleware struct {
 	authProvider    *security.DefaultAuthProvider
+	hmacVerifier    *security.HMACVerifier
 	rateLimiter     security.RateLimiter
+	rateLimitConfig *security.RateLimitConfig
 	validator       *security.RequestValidator
 	auditor         *security.AuditLogger
 	logger          *logrus.Logger
@@ -35,15 +43,27 @@ func NewSecurityMiddleware(config *SecurityMiddlewareConfig, logger *logrus.Logg
 		authProvider = security.NewDefaultAuthProvider(config.Auth, logger)
 	}
 	
+	// Initialize request signature verification
+	var hmacVerifier *security.HMACVerifier
+	var err error
+	if config.HMAC != nil && config.HMAC.Secret != "" {
+		hmacVerifier, err = security.NewHMACVerifier(config.HMAC, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize HMAC verifier: %w", err)
+		}
+	}
+	
 	// Initialize rate limiter
 	var rateLimiter security.RateLimiter
 	if config.RateLimit != nil && config.RateLimit.Enabled {
-		rateLimiter = security.NewInMemoryRateLimiter(config.RateLimit, logger)
+		rateLimiter, err = security.NewRateLimiter(config.RateLimit, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize rate limiter: %w", err)
+		}
 	}
 	
 	// Initialize request validator
 	var validator *security.RequestValidator
-	var err error
 	if config.Validation != nil {
 		validator, err = security.NewRequestValidator(config.Validation, logger)
 		if err != nil {
@@ -59,7 +79,9 @@ func NewSecurityMiddleware(config *SecurityMiddlewareConfig, logger *logrus.Logg
 	
 	return &SecurityMiddleware{
 		authProvider: authProvider,
+		hmacVerifier: hmacVerifier,
 		rateLimiter:  rateLimiter,
+		rateLimitConfig: config.RateLimit,
 		validator:    validator,
 		auditor:      auditor,
 		logger:       logger,
@@ -72,20 +94,24 @@ func (s *SecurityMiddleware) Handler() func(http.Handler) http.Handler {
 		// Build middleware chain in reverse order (innermost first)
 		handler := next
 		
+		// 0. Authorization (runs after auth has attached the caller's scopes)
+		if s.authenticates() {
+			handler = s.authorizationMiddleware()(handler)
+		}
+		
 		// 1. Audit logging (outermost - logs everything)
 		if s.auditor != nil {
 			handler = s.auditor.AuditMiddleware()(handler)
 		}
 		
 		// 2. Authentication (before rate limiting to identify users)
-		if s.authProvider != nil {
-			handler = s.authProvider.AuthMiddleware()(handler)
+		if s.authenticates() {
+			handler = s.authenticationMiddleware()(handler)
 		}
 		
 		// 3. Rate limiting (after auth to use user-based limits)
 		if s.rateLimiter != nil {
-			keyExtractor := security.DefaultKeyExtractor
-			handler = security.RateLimitMiddleware(s.rateLimiter, keyExtractor)(handler)
+			handler = security.RateLimitMiddleware(s.rateLimiter, s.rateLimitKeyExtractor())(handler)
 		}
 		
 		// 4. Request validation (innermost - validates each request)
@@ -100,23 +126,66 @@ func (s *SecurityMiddleware) Handler() func(http.Handler) http.Handler {
 	}
 }
 
+// AuthProvider returns the authentication provider, nil if authentication
+// isn't configured
+func (s *SecurityMiddleware) AuthProvider() *security.DefaultAuthProvider {
+	return s.authProvider
+}
+
 // AuthenticationOnly returns only the authentication middleware
 func (s *SecurityMiddleware) AuthenticationOnly() func(http.Handler) http.Handler {
-	if s.authProvider != nil {
-		return s.authProvider.AuthMiddleware()
+	if s.authenticates() {
+		return s.authenticationMiddleware()
 	}
 	return func(next http.Handler) http.Handler { return next }
 }
 
+// authenticates reports whether any authentication mode is configured
+func (s *SecurityMiddleware) authenticates() bool {
+	return s.authProvider != nil || s.hmacVerifier != nil
+}
+
+// authenticationMiddleware verifies signed requests by their HMAC signature
+// and authenticates the rest with an API key or JWT. Without an auth
+// provider, every request must be signed.
+func (s *SecurityMiddleware) authenticationMiddleware() func(http.Handler) http.Handler {
+	if s.hmacVerifier == nil {
+		return s.authProvider.AuthMiddleware()
+	}
+	if s.authProvider == nil {
+		return s.hmacVerifier.HMACMiddleware()
+	}
+	
+	return func(next http.Handler) http.Handler {
+		signed := s.hmacVerifier.HMACMiddleware()(next)
+		unsigned := s.authProvider.AuthMiddleware()(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.hmacVerifier.IsSigned(r) {
+				signed.ServeHTTP(w, r)
+				return
+			}
+			unsigned.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RateLimitingOnly returns only the rate limiting middleware
 func (s *SecurityMiddleware) RateLimitingOnly() func(http.Handler) http.Handler {
 	if s.rateLimiter != nil {
-		keyExtractor := security.DefaultKeyExtractor
-		return security.RateLimitMiddleware(s.rateLimiter, keyExtractor)
+		return security.RateLimitMiddleware(s.rateLimiter, s.rateLimitKeyExtractor())
 	}
 	return func(next http.Handler) http.Handler { return next }
 }
 
+// rateLimitKeyExtractor keys requests by client, and by model tier too when
+// tier limits are configured
+func (s *SecurityMiddleware) rateLimitKeyExtractor() func(*http.Request) string {
+	if s.rateLimitConfig != nil && s.rateLimitConfig.HasTierLimits() {
+		return security.TierKeyExtractor(s.rateLimitConfig, security.DefaultKeyExtractor)
+	}
+	return security.DefaultKeyExtractor
+}
+
 // ValidationOnly returns only the validation middleware
 func (s *SecurityMiddleware) ValidationOnly() func(http.Handler) http.Handler {
 	if s.validator != nil {
@@ -164,7 +233,7 @@ func (s *SecurityMiddleware) Stop() {
 		s.auditor.Stop()
 	}
 	
-	if rateLimiter, ok := s.rateLimiter.(*security.InMemoryRateLimiter); ok {
+	if rateLimiter, ok := s.rateLimiter.(interface{ Stop() }); ok {
 		rateLimiter.Stop()
 	}
 }
@@ -176,6 +245,8 @@ func (s *SecurityMiddleware) GetStats() map[string]interface{} {
 	// Add audit stats
 	if s.auditor != nil {
 		stats["audit_events_logged"] = s.auditor.GetEventCount()
+		stats["audit_events_dropped"] = s.auditor.GetDroppedCount()
+		stats["audit_events_overflowed"] = s.auditor.GetOverflowCount()
 	}
 	
 	// Add rate limiter stats (would need to implement this in rate limiter)
@@ -185,7 +256,8 @@ func (s *SecurityMiddleware) GetStats() map[string]interface{} {
 	stats["validation_enabled"] = s.validator != nil
 	
 	// Add auth stats
-	stats["authentication_enabled"] = s.authProvider != nil
+	stats["authentication_enabled"] = s.authenticates()
+	stats["request_signing_enabled"] = s.hmacVerifier != nil
 	
 	return stats
 }
@@ -193,7 +265,7 @@ func (s *SecurityMiddleware) GetStats() map[string]interface{} {
 // HealthCheck performs health checks on all security components
 func (s *SecurityMiddleware) HealthCheck() error {
 	// Check components are initialized
-	if s.authProvider == nil {
+	if !s.authenticates() {
 		return fmt.Errorf("authentication provider not initialized")
 	}
 	
@@ -210,6 +282,59 @@ func (s *SecurityMiddleware) LogSecurityEvent(ctx context.Context, eventType sec
 	}
 }
 
+// authorizationMiddleware rejects requests for models or endpoints outside
+// the authenticated key's permission scopes, and admin operations from keys
+// without admin:write
+func (s *SecurityMiddleware) authorizationMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authInfo, ok := security.GetAuthInfo(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			
+			var model string
+			if authInfo.RequiresModelCheck() {
+				var err error
+				if model, err = security.RequestModel(r); err != nil {
+					http.Error(w, "Failed to read request body", http.StatusBadRequest)
+					return
+				}
+			}
+			
+			err := authInfo.Authorize(r.URL.Path, model)
+			if err == nil {
+				err = authInfo.AuthorizeAdmin(r.Method, r.URL.Path)
+			}
+			if err != nil {
+				var authzErr *security.AuthorizationError
+				errors.As(err, &authzErr)
+				s.LogSecurityEvent(r.Context(), security.AuthorizationFailure, err.Error(), map[string]interface{}{
+					"scope":     authzErr.Scope,
+					"user_id":   authInfo.UserID,
+					"path":      r.URL.Path,
+					"method":    r.Method,
+					"model":     model,
+					"remote_ip": security.ClientIP(r),
+				})
+				s.writeForbidden(w, err.Error())
+				return
+			}
+			
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *SecurityMiddleware) writeForbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	
+	response := fmt.Sprintf(`{"error":{"message":%q,"type":"authorization_error","code":"forbidden","status":403},"timestamp":%d}`, message, time.Now().Unix())
+	w.Write([]byte(response))
+}
+
 // Custom middleware for specific security scenarios
 
 // APIKeyOnlyMiddleware creates middleware that only accepts API key authentication
@@ -283,35 +408,7 @@ func (s *SecurityMiddleware) JWTOnlyMiddleware() func(http.Handler) http.Handler
 
 // CORSMiddleware creates CORS middleware for cross-origin requests
 func (s *SecurityMiddleware) CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-			
-			// Check if origin is allowed
-			allowed := false
-			for _, allowedOrigin := range allowedOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-			
-			if allowed {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-				w.Header().Set("Access-Control-Max-Age", "86400")
-			}
-			
-			// Handle preflight OPTIONS requests
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
-	}
+	return CORS(&CORSConfig{AllowedOrigins: allowedOrigins})
 }
 
 // Helper functions
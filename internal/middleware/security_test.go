@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tributary-ai/llm-router-waf/internal/security"
@@ -176,6 +180,187 @@ func TestSecurityMiddleware_AuthenticationOnly(t *testing.T) {
 	assert.Equal(t, "authenticated", w.Body.String())
 }
 
+func TestSecurityMiddleware_AuthenticationOnly_HMAC(t *testing.T) {
+	secret := "hmac-secret"
+	config := &SecurityMiddlewareConfig{
+		Auth: &security.Config{
+			APIKeys:     []string{"valid-key"},
+			RequireAuth: true,
+		},
+		HMAC: &security.HMACAuthConfig{Secret: secret, MaxSkew: time.Minute},
+	}
+	middleware, err := NewSecurityMiddleware(config, logrus.New())
+	require.NoError(t, err)
+	defer middleware.Stop()
+
+	handler := middleware.AuthenticationOnly()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authInfo, ok := security.GetAuthInfo(r.Context())
+		require.True(t, ok)
+		w.Write([]byte(authInfo.Metadata["auth_type"]))
+	}))
+
+	signed := func(body string, signedAt time.Time, signature string) *http.Request {
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		if signature == "" {
+			signature = security.SignRequest(secret, timestamp, []byte(body))
+		}
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("X-Signature", signature)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		return req
+	}
+	body := `{"model":"gpt-4o"}`
+
+	// Valid signature
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signed(body, time.Now(), ""))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hmac", w.Body.String())
+
+	// Body changed after signing
+	tampered := signed(body, time.Now(), "")
+	tampered.Body = io.NopCloser(strings.NewReader(`{"model":"gpt-4"}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, tampered)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Stale timestamp
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signed(body, time.Now().Add(-5*time.Minute), ""))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A bad signature isn't rescued by also sending an API key
+	req := signed(body, time.Now(), "deadbeef")
+	req.Header.Set("X-API-Key", "valid-key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Unsigned requests still authenticate by API key
+	req = httptest.NewRequest("GET", "/v1/models", nil)
+	req.Header.Set("X-API-Key", "valid-key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "api_key", w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/models", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSecurityMiddleware_Handler_KeyScopes(t *testing.T) {
+	config := &SecurityMiddlewareConfig{
+		Auth: &security.Config{
+			APIKeys:     []string{"scoped-key", "open-key"},
+			RequireAuth: true,
+			KeyScopes: map[string][]string{
+				"scoped-key": {"models:gpt-4o", "endpoints:/v1/chat/completions"},
+			},
+		},
+		Audit: &security.AuditConfig{
+			Enabled: true,
+		},
+	}
+	logger, hook := test.NewNullLogger()
+	middleware, err := NewSecurityMiddleware(config, logger)
+	require.NoError(t, err)
+
+	var handledBody string
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handledBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.Handler()(testHandler)
+
+	send := func(apiKey, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Allowed model, and the handler still sees the full body
+	w := send("scoped-key", "/v1/chat/completions", `{"model":"gpt-4o"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"model":"gpt-4o"}`, handledBody)
+
+	// Denied model
+	w = send("scoped-key", "/v1/chat/completions", `{"model":"claude-3-opus"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "authorization_error")
+
+	// Denied endpoint
+	w = send("scoped-key", "/v1/embeddings", `{"model":"gpt-4o"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Unscoped keys are unrestricted
+	w = send("open-key", "/v1/chat/completions", `{"model":"claude-3-opus"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Each denial is audited with the scope it fell outside of
+	middleware.Stop()
+	var deniedScopes []interface{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["event_type"] == security.AuthorizationFailure && entry.Data["detail_scope"] != nil {
+			deniedScopes = append(deniedScopes, entry.Data["detail_scope"])
+		}
+	}
+	assert.ElementsMatch(t, []interface{}{"models:claude-3-opus", "endpoints:/v1/embeddings"}, deniedScopes)
+}
+
+func TestSecurityMiddleware_Handler_AdminScope(t *testing.T) {
+	config := &SecurityMiddlewareConfig{
+		Auth: &security.Config{
+			APIKeys:     []string{"admin-key", "open-key"},
+			RequireAuth: true,
+			KeyScopes: map[string][]string{
+				"admin-key": {"admin:write"},
+			},
+		},
+	}
+	logger, _ := test.NewNullLogger()
+	middleware, err := NewSecurityMiddleware(config, logger)
+	require.NoError(t, err)
+	defer middleware.Stop()
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := middleware.Handler()(testHandler)
+
+	send := func(apiKey, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// api:access alone can't change provider state
+	w := send("open-key", "DELETE", "/v1/providers/openai")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "admin:write")
+	w = send("open-key", "POST", "/v1/providers/openai/health/recheck")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send("open-key", "POST", "/v1/providers/openai/maintenance")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// but can still read it
+	w = send("open-key", "GET", "/v1/providers/openai")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("admin-key", "DELETE", "/v1/providers/openai")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("admin-key", "POST", "/v1/providers/openai/health/recheck")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("admin-key", "POST", "/v1/providers/openai/maintenance")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestSecurityMiddleware_RateLimitingOnly(t *testing.T) {
 	config := &SecurityMiddlewareConfig{
 		RateLimit: &security.RateLimitConfig{
@@ -247,7 +432,7 @@ func TestSecurityMiddleware_ValidationOnly(t *testing.T) {
 	req.ContentLength = 200 // Exceeds limit of 100
 	w = httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
 }
 
 func TestSecurityMiddleware_AuditOnly(t *testing.T) {
@@ -297,6 +482,7 @@ func TestSecurityMiddleware_GetStats(t *testing.T) {
 	stats := middleware.GetStats()
 
 	assert.Contains(t, stats, "audit_events_logged")
+	assert.Contains(t, stats, "audit_events_dropped")
 	assert.Contains(t, stats, "rate_limiter_enabled")
 	assert.Contains(t, stats, "validation_enabled")
 	assert.Contains(t, stats, "authentication_enabled")
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -165,8 +166,14 @@ func (vm *ValidationMiddleware) validateRequest(r *http.Request) error {
 
 // writeValidationError writes a validation error response
 func (vm *ValidationMiddleware) writeValidationError(w http.ResponseWriter, err error) {
+	// A body over the security size limit fails as it is read
+	status, code := http.StatusBadRequest, "invalid_request"
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		status, code = http.StatusRequestEntityTooLarge, "request_too_large"
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(status)
 
 	// Parse validation error for better formatting
 	errorDetail := vm.parseValidationError(err)
@@ -175,7 +182,8 @@ func (vm *ValidationMiddleware) writeValidationError(w http.ResponseWriter, err
 		"error": map[string]interface{}{
 			"message": errorDetail.Message,
 			"type":    "validation_error",
-			"code":    "400",
+			"code":    code,
+			"status":  status,
 			"details": errorDetail.Details,
 		},
 		"timestamp": getCurrentTimestamp(),
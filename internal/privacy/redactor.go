@@ -0,0 +1,243 @@
+// Package privacy detects and redacts personally identifiable information in
+// prompts before they are sent to providers.
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// Built-in PII categories
+const (
+	CategoryEmail      = "email"
+	CategorySSN        = "ssn"
+	CategoryCreditCard = "credit_card"
+)
+
+// Action is what happens to requests containing PII
+type Action string
+
+const (
+	ActionRedact Action = "redact" // replace matches and forward the request
+	ActionBlock  Action = "block"  // reject the request
+)
+
+// DefaultPatterns are the regular expressions used for the built-in
+// categories. Credit card matches must also pass a Luhn check.
+var DefaultPatterns = map[string]string{
+	CategoryEmail:      `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	CategorySSN:        `\b\d{3}-\d{2}-\d{4}\b`,
+	CategoryCreditCard: `\b\d(?:[ -]?\d){12,18}\b`,
+}
+
+// Config configures PII detection
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Action  Action `yaml:"action"` // redact (default) or block
+
+	// Regular expressions by category, added to DefaultPatterns. A category
+	// named after a default overrides it, and an empty pattern disables it.
+	Patterns map[string]string `yaml:"patterns"`
+}
+
+// Validate checks the action and that every pattern compiles
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := NewRedactor(c)
+	return err
+}
+
+// Findings counts PII matches by category
+type Findings map[string]int
+
+// Total returns the number of matches across categories
+func (f Findings) Total() int {
+	total := 0
+	for _, count := range f {
+		total += count
+	}
+	return total
+}
+
+// Categories returns the categories with matches, sorted
+func (f Findings) Categories() []string {
+	categories := make([]string, 0, len(f))
+	for category, count := range f {
+		if count > 0 {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// DetectedError is returned when a blocking redactor finds PII. It carries
+// counts only, never the matched values.
+type DetectedError struct {
+	Findings Findings
+}
+
+func (e *DetectedError) Error() string {
+	parts := make([]string, 0, len(e.Findings))
+	for _, category := range e.Findings.Categories() {
+		parts = append(parts, fmt.Sprintf("%s (%d)", category, e.Findings[category]))
+	}
+	return "request contains PII: " + strings.Join(parts, ", ")
+}
+
+type pattern struct {
+	category string
+	re       *regexp.Regexp
+	valid    func(match string) bool // optional check on each match
+}
+
+// Redactor finds and replaces PII in text
+type Redactor struct {
+	action   Action
+	patterns []pattern
+}
+
+// NewRedactor compiles the configured patterns
+func NewRedactor(config *Config) (*Redactor, error) {
+	action := config.Action
+	switch action {
+	case "":
+		action = ActionRedact
+	case ActionRedact, ActionBlock:
+	default:
+		return nil, fmt.Errorf("invalid PII action %q: must be redact or block", action)
+	}
+
+	sources := make(map[string]string, len(DefaultPatterns)+len(config.Patterns))
+	for category, expr := range DefaultPatterns {
+		sources[category] = expr
+	}
+	for category, expr := range config.Patterns {
+		if category == "" {
+			return nil, fmt.Errorf("PII pattern category must not be empty")
+		}
+		sources[category] = expr
+	}
+
+	categories := make([]string, 0, len(sources))
+	for category, expr := range sources {
+		if expr != "" {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	redactor := &Redactor{action: action}
+	for _, category := range categories {
+		re, err := regexp.Compile(sources[category])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern for %s: %w", category, err)
+		}
+		p := pattern{category: category, re: re}
+		if category == CategoryCreditCard {
+			p.valid = luhnValid
+		}
+		redactor.patterns = append(redactor.patterns, p)
+	}
+	return redactor, nil
+}
+
+// Action returns what the redactor does with requests containing PII
+func (r *Redactor) Action() Action {
+	return r.action
+}
+
+// Redact replaces each match in text with a [REDACTED_<CATEGORY>] marker and
+// counts the matches by category
+func (r *Redactor) Redact(text string) (string, Findings) {
+	findings := Findings{}
+	for _, p := range r.patterns {
+		marker := "[REDACTED_" + strings.ToUpper(p.category) + "]"
+		text = p.re.ReplaceAllStringFunc(text, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			findings[p.category]++
+			return marker
+		})
+	}
+	return text, findings
+}
+
+// RedactMessages returns a copy of messages with PII redacted from their
+// content, which may be a string or content parts. The input is not
+// modified.
+func (r *Redactor) RedactMessages(messages []types.Message) ([]types.Message, Findings) {
+	findings := Findings{}
+	redact := func(text string) string {
+		redacted, found := r.Redact(text)
+		for category, count := range found {
+			findings[category] += count
+		}
+		return redacted
+	}
+
+	out := make([]types.Message, len(messages))
+	for i, msg := range messages {
+		switch content := msg.Content.(type) {
+		case string:
+			msg.Content = redact(content)
+		case []types.ContentPart:
+			parts := make([]types.ContentPart, len(content))
+			for j, part := range content {
+				if part.Type == "text" {
+					part.Text = redact(part.Text)
+				}
+				parts[j] = part
+			}
+			msg.Content = parts
+		case []interface{}:
+			// Content parts as decoded from JSON
+			parts := make([]interface{}, len(content))
+			for j, item := range content {
+				if part, ok := item.(map[string]interface{}); ok && part["type"] == "text" {
+					if text, ok := part["text"].(string); ok {
+						copied := make(map[string]interface{}, len(part))
+						for key, value := range part {
+							copied[key] = value
+						}
+						copied["text"] = redact(text)
+						item = copied
+					}
+				}
+				parts[j] = item
+			}
+			msg.Content = parts
+		}
+		out[i] = msg
+	}
+	return out, findings
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum, ruling
+// out most digit runs that aren't card numbers
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,67 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor, err := NewRedactor(&Config{Enabled: true})
+	require.NoError(t, err)
+
+	text, findings := redactor.Redact("Card 4111 1111 1111 1111, SSN 123-45-6789, mail jane.doe@example.com")
+	assert.Equal(t, "Card [REDACTED_CREDIT_CARD], SSN [REDACTED_SSN], mail [REDACTED_EMAIL]", text)
+	assert.Equal(t, Findings{CategoryCreditCard: 1, CategorySSN: 1, CategoryEmail: 1}, findings)
+
+	// Digit runs failing the Luhn check are left alone
+	text, findings = redactor.Redact("order 4111 1111 1111 1112")
+	assert.Equal(t, "order 4111 1111 1111 1112", text)
+	assert.Zero(t, findings.Total())
+}
+
+func TestRedactor_CustomPatterns(t *testing.T) {
+	redactor, err := NewRedactor(&Config{Enabled: true, Patterns: map[string]string{
+		"employee_id": `\bEMP-\d{6}\b`,
+		CategoryEmail: "",
+	}})
+	require.NoError(t, err)
+
+	text, findings := redactor.Redact("EMP-123456 at jane@example.com")
+	assert.Equal(t, "[REDACTED_EMPLOYEE_ID] at jane@example.com", text)
+	assert.Equal(t, []string{"employee_id"}, findings.Categories())
+
+	_, err = NewRedactor(&Config{Enabled: true, Patterns: map[string]string{"bad": "("}})
+	assert.Error(t, err)
+	_, err = NewRedactor(&Config{Enabled: true, Action: "drop"})
+	assert.Error(t, err)
+}
+
+func TestRedactor_RedactMessages(t *testing.T) {
+	redactor, err := NewRedactor(&Config{Enabled: true})
+	require.NoError(t, err)
+
+	parts := []types.ContentPart{
+		{Type: "text", Text: "pay with 4111-1111-1111-1111"},
+		{Type: "image_url", ImageURL: &types.ImageURL{URL: "https://example.com/a.png"}},
+	}
+	decoded := []interface{}{map[string]interface{}{"type": "text", "text": "ssn 123-45-6789"}}
+	messages := []types.Message{
+		{Role: "system", Content: "Be helpful"},
+		{Role: "user", Content: parts},
+		{Role: "user", Content: decoded},
+	}
+
+	redacted, findings := redactor.RedactMessages(messages)
+	assert.Equal(t, Findings{CategoryCreditCard: 1, CategorySSN: 1}, findings)
+	assert.Equal(t, "Be helpful", redacted[0].Content)
+	assert.Equal(t, "pay with [REDACTED_CREDIT_CARD]", redacted[1].Content.([]types.ContentPart)[0].Text)
+	assert.Equal(t, "ssn [REDACTED_SSN]", types.MessageText(redacted[2].Content))
+
+	// The caller's messages are untouched
+	assert.Equal(t, "pay with 4111-1111-1111-1111", parts[0].Text)
+	assert.Equal(t, "ssn 123-45-6789", types.MessageText(decoded))
+}
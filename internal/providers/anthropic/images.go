@@ -0,0 +1,120 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// maxImageBytes is Anthropic's per-image size limit
+const maxImageBytes = 5 << 20
+
+// convertImagePart converts an image_url content part into an Anthropic
+// base64 image block. Data URLs are passed through; http(s) URLs are fetched
+// and encoded, since the API can't reach every URL a client can.
+func (p *AnthropicProvider) convertImagePart(ctx context.Context, part types.ContentPart) (anthropic.ContentBlockParamUnion, error) {
+	if part.ImageURL == nil || part.ImageURL.URL == "" {
+		return anthropic.ContentBlockParamUnion{}, fmt.Errorf("image_url part has no url")
+	}
+
+	var mediaType, data string
+	var err error
+	switch url := part.ImageURL.URL; {
+	case strings.HasPrefix(url, "data:"):
+		mediaType, data, err = parseDataURL(url)
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		mediaType, data, err = p.fetchImage(ctx, url)
+	default:
+		err = fmt.Errorf("unsupported image url: must be a data URL or http(s) URL")
+	}
+	if err != nil {
+		return anthropic.ContentBlockParamUnion{}, err
+	}
+
+	if !p.supportsImageType(mediaType) {
+		return anthropic.ContentBlockParamUnion{}, fmt.Errorf("unsupported image type %s (supported: %s)", mediaType, strings.Join(p.GetSupportedImageFormats(), ", "))
+	}
+
+	return anthropic.NewImageBlockBase64(mediaType, data), nil
+}
+
+// parseDataURL splits a base64 data URL into its media type and payload
+func parseDataURL(url string) (string, string, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok {
+		return "", "", fmt.Errorf("malformed image data URL")
+	}
+
+	mediaType, encoding, _ := strings.Cut(header, ";")
+	if encoding != "base64" {
+		return "", "", fmt.Errorf("image data URL must be base64 encoded")
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > maxImageBytes {
+		return "", "", fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+
+	return normalizeMediaType(mediaType), payload, nil
+}
+
+// fetchImage downloads a remote image, returning its media type and base64
+// encoded contents
+func (p *AnthropicProvider) fetchImage(ctx context.Context, url string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image url: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to fetch image: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(body) > maxImageBytes {
+		return "", "", fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+
+	// Trust the declared type, sniffing only when the server doesn't say
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		mediaType = http.DetectContentType(body)
+	}
+
+	return normalizeMediaType(mediaType), base64.StdEncoding.EncodeToString(body), nil
+}
+
+// supportsImageType reports whether mediaType is one of the supported formats
+func (p *AnthropicProvider) supportsImageType(mediaType string) bool {
+	for _, format := range p.GetSupportedImageFormats() {
+		if mediaType == "image/"+format {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeMediaType lowercases a media type and maps aliases to the names
+// Anthropic accepts
+func normalizeMediaType(mediaType string) string {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "image/jpg" {
+		return "image/jpeg"
+	}
+	return mediaType
+}
@@ -0,0 +1,174 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// JSONModeConfig enables a best-effort JSON mode. Claude has no native
+// structured output, so requests with a json_object or json_schema
+// response_format get a system instruction asking for JSON instead. Unlike
+// OpenAI's strict mode nothing enforces it, which is why it is advertised
+// as SupportsJSONMode rather than SupportsStructuredOutput.
+type JSONModeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Strip code fences and surrounding prose from responses, and fail
+	// responses that still aren't JSON or don't match the requested schema
+	ValidateOutput bool `yaml:"validate_output"`
+}
+
+// errInvalidJSONOutput means a response didn't honor the requested format
+var errInvalidJSONOutput = errors.New("response does not match the requested format")
+
+// jsonModeInstruction returns the system instruction asking for format, or
+// "" when format doesn't ask for JSON
+func jsonModeInstruction(format *types.ResponseFormat) (string, error) {
+	if !wantsJSON(format) {
+		return "", nil
+	}
+
+	const plain = "Do not wrap it in markdown code fences or add any text before or after it."
+	if format.Type != "json_schema" || format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+		return "Respond only with a single valid JSON object. " + plain, nil
+	}
+
+	schema, err := json.Marshal(format.JSONSchema.Schema)
+	if err != nil {
+		return "", fmt.Errorf("invalid json_schema: %w", err)
+	}
+	return "Respond only with valid JSON conforming to this JSON Schema:\n" + string(schema) + "\n" + plain, nil
+}
+
+// wantsJSON reports whether format asks for JSON output
+func wantsJSON(format *types.ResponseFormat) bool {
+	return format != nil && (format.Type == "json_object" || format.Type == "json_schema")
+}
+
+// repairJSONOutput returns the JSON document in content, dropping code fences
+// and any prose around it, and checks it against format's schema
+func repairJSONOutput(content string, format *types.ResponseFormat) (string, error) {
+	document := extractJSON(content)
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		return "", fmt.Errorf("%w: not valid JSON: %v", errInvalidJSONOutput, err)
+	}
+
+	if format.Type == "json_schema" && format.JSONSchema != nil && format.JSONSchema.Schema != nil {
+		if err := validateSchema(value, format.JSONSchema.Schema, "$"); err != nil {
+			return "", fmt.Errorf("%w: %v", errInvalidJSONOutput, err)
+		}
+	}
+	return document, nil
+}
+
+// extractJSON returns the outermost JSON object or array in content, or the
+// trimmed content when there is none
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if json.Valid([]byte(content)) {
+		return content
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(content, closing)
+	if end < start {
+		return content
+	}
+	return content[start : end+1]
+}
+
+// validateSchema checks value against the subset of JSON Schema clients use
+// for response formats: type, enum, properties, required, items and
+// additionalProperties: false. Other keywords are ignored.
+func validateSchema(value interface{}, schema map[string]interface{}, path string) error {
+	if expected, ok := schema["type"].(string); ok && !hasJSONType(value, expected) {
+		return fmt.Errorf("%s must be of type %s", path, expected)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, option := range enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s is missing required property %q", path, key)
+			}
+		}
+		for key, field := range v {
+			propertySchema, known := properties[key].(map[string]interface{})
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s has unexpected property %q", path, key)
+				}
+				continue
+			}
+			if err := validateSchema(field, propertySchema, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range v {
+			if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasJSONType reports whether a decoded JSON value is of the JSON Schema type
+func hasJSONType(value interface{}, expected string) bool {
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true // unknown types aren't checked
+}
@@ -2,8 +2,13 @@ package anthropic
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -11,14 +16,18 @@ import (
 	"github.com/sirupsen/logrus"
 	
 	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
 // AnthropicProvider implements the LLMProvider interface for Anthropic Claude
 type AnthropicProvider struct {
-	client *anthropic.Client
-	config *AnthropicConfig
-	logger *logrus.Logger
+	client     *anthropic.Client
+	config     *AnthropicConfig
+	logger     *logrus.Logger
+	httpClient *http.Client // also used to fetch remote images and for health probes
+	modelIndex *providers.ModelIndex
 }
 
 // AnthropicConfig holds Anthropic-specific configuration
@@ -27,12 +36,22 @@ type AnthropicConfig struct {
 	BaseURL string            `yaml:"base_url"`
 	Models  []types.ModelInfo `yaml:"models"`
 	Timeout time.Duration     `yaml:"timeout"`
+	
+	// Best-effort JSON output for response_format requests
+	JSONMode JSONModeConfig `yaml:"json_mode"`
+
+	providers.CommonConfig `yaml:",inline"`
 }
 
+// defaultHealthCheckModel is used when no models are configured
+const defaultHealthCheckModel = "claude-3-haiku-20240307"
+
 // NewAnthropicProvider creates a new Anthropic provider instance
 func NewAnthropicProvider(config *AnthropicConfig, logger *logrus.Logger) *AnthropicProvider {
+	httpClient := providers.ProviderHTTPClient(config.HTTPClient, config.Timeout, true)
 	opts := []option.RequestOption{
 		option.WithAPIKey(config.APIKey),
+		option.WithHTTPClient(httpClient),
 	}
 	
 	if config.BaseURL != "" {
@@ -40,11 +59,17 @@ func NewAnthropicProvider(config *AnthropicConfig, logger *logrus.Logger) *Anthr
 	}
 	
 	client := anthropic.NewClient(opts...)
+	// The SDK appends per-call options onto the service's option slice;
+	// clipping it makes every call copy, so concurrent n-way requests
+	// never write into a shared backing array.
+	client.Messages.Options = slices.Clip(client.Messages.Options)
 	
 	return &AnthropicProvider{
-		client: &client,
-		config: config,
-		logger: logger,
+		client:     &client,
+		config:     config,
+		logger:     logger,
+		httpClient: httpClient,
+		modelIndex: providers.NewModelIndex(config.Models),
 	}
 }
 
@@ -55,16 +80,18 @@ func (p *AnthropicProvider) GetProviderName() string {
 
 // GetCapabilities returns the capabilities of the Anthropic provider
 func (p *AnthropicProvider) GetCapabilities() types.ProviderCapabilities {
-	return types.ProviderCapabilities{
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
 		ProviderName:              "anthropic",
 		SupportedModels:           p.config.Models,
 		SupportsFunctions:         true, // Tool use
 		SupportsParallelFunctions: false, // Claude doesn't support parallel tool calls
 		SupportsVision:            true,
 		SupportsStructuredOutput:  false, // No strict JSON schema mode
+		SupportsJSONMode:          p.config.JSONMode.Enabled,
 		SupportsStreaming:         true,
 		SupportsAssistants:        false, // No assistants API
 		SupportsBatch:             false, // No batch API yet
+		SupportsEmbeddings:        false, // No embeddings API
 		MaxContextWindow:          200000, // Claude-3.5 Sonnet context window
 		SupportedImageFormats:     []string{"png", "jpeg", "webp", "gif"},
 		CostPer1KTokens: types.CostStructure{
@@ -80,51 +107,175 @@ func (p *AnthropicProvider) GetCapabilities() types.ProviderCapabilities {
 			MaxToolCalls:              5,
 			SupportedStopSequences:    []string{"\n\nHuman:", "\n\nAssistant:"},
 		},
-	}
+	})
 }
 
 // ChatCompletion performs a chat completion request
 func (p *AnthropicProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "anthropic.chat_completion",
+		tracing.AttrProvider.String("anthropic"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
 	// Convert our request to Anthropic format
-	anthropicReq, err := p.convertToAnthropicRequest(req)
+	anthropicReq, err := p.convertToAnthropicRequest(ctx, req)
 	if err != nil {
 		p.logger.WithError(err).Error("Failed to convert request to Anthropic format")
+		tracing.End(span, err)
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
 
-	// Make the API call
-	resp, err := p.client.Messages.New(ctx, *anthropicReq)
-	if err != nil {
-		p.logger.WithError(err).Error("Anthropic API call failed")
-		return nil, fmt.Errorf("anthropic api call failed: %w", err)
+	// The Messages API returns one completion per call, so n completions
+	// are requested in parallel
+	n := req.ChoiceCount()
+	responses := make([]*anthropic.Message, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = p.client.Messages.New(ctx, *anthropicReq)
+		}()
 	}
+	wg.Wait()
 
-	// Convert response back to our format
-	return p.convertFromAnthropicResponse(resp, req), nil
+	for _, err := range errs {
+		if err != nil {
+			p.logger.WithError(err).Error("Anthropic API call failed")
+			tracing.End(span, err)
+			return nil, newProviderError(err)
+		}
+	}
+	tracing.End(span, nil)
+
+	// Convert responses back to our format
+	resp := p.convertFromAnthropicResponse(responses[0], req)
+	for i, extra := range responses[1:] {
+		converted := p.convertFromAnthropicResponse(extra, req)
+		for _, choice := range converted.Choices {
+			choice.Index = i + 1
+			resp.Choices = append(resp.Choices, choice)
+		}
+		resp.Usage = addUsage(resp.Usage, converted.Usage)
+	}
+
+	if p.config.JSONMode.Enabled && p.config.JSONMode.ValidateOutput && wantsJSON(req.ResponseFormat) {
+		for i := range resp.Choices {
+			content, _ := resp.Choices[i].Message.Content.(string)
+			repaired, err := repairJSONOutput(content, req.ResponseFormat)
+			if err != nil {
+				p.logger.WithError(err).Warn("Anthropic response failed JSON mode validation")
+				return nil, fmt.Errorf("anthropic: %w", err)
+			}
+			resp.Choices[i].Message.Content = repaired
+		}
+	}
+	return resp, nil
+}
+
+// addUsage sums the usage of two responses, either of which may be nil
+func addUsage(a, b *types.Usage) *types.Usage {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return &types.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
 }
 
 // StreamCompletion performs a streaming chat completion request
 func (p *AnthropicProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
-	// For now, return an error as streaming implementation needs to be updated for the current SDK
-	return nil, fmt.Errorf("streaming not yet implemented for current Anthropic SDK version")
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "anthropic.stream_completion",
+		tracing.AttrProvider.String("anthropic"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	// Each stream carries one completion
+	if req.ChoiceCount() > 1 {
+		err := fmt.Errorf("anthropic: streaming supports a single choice, got n=%d", req.ChoiceCount())
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	// The timeout covers the whole stream, so it is released when the
+	// stream ends rather than when this function returns
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+
+	anthropicReq, err := p.convertToAnthropicRequest(ctx, req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Anthropic format")
+		tracing.End(span, err)
+		cancel()
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	// Read the first event before returning so a rejected request fails
+	// here, where the router can still fall back, rather than mid-stream
+	stream := p.client.Messages.NewStreaming(ctx, *anthropicReq)
+	if !stream.Next() {
+		err := stream.Err()
+		if err == nil {
+			err = fmt.Errorf("stream ended before any events")
+		}
+		p.logger.WithError(err).Error("Anthropic streaming API call failed")
+		tracing.End(span, err)
+		stream.Close()
+		cancel()
+		return nil, newProviderError(err)
+	}
+
+	chunks := make(chan *types.ChatChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer cancel()
+		defer stream.Close()
+
+		state := newStreamState(req.Model)
+		for {
+			event := stream.Current()
+			if chunk := state.convert(&event); chunk != nil {
+				select {
+				case chunks <- chunk:
+				case <-ctx.Done():
+					tracing.End(span, ctx.Err())
+					return
+				}
+			}
+			if !stream.Next() {
+				break
+			}
+		}
+
+		err := stream.Err()
+		if err != nil {
+			p.logger.WithError(err).Error("Error receiving stream chunk")
+			providers.SendStreamError(ctx, chunks, newProviderError(err))
+		}
+		tracing.End(span, err)
+	}()
+
+	return chunks, nil
 }
 
 // EstimateCost estimates the cost for a chat completion request
 func (p *AnthropicProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
-	// Find model info
-	var modelInfo *types.ModelInfo
-	for _, model := range p.config.Models {
-		if model.Name == req.Model || model.ProviderModelID == req.Model {
-			modelInfo = &model
-			break
-		}
-	}
+	modelInfo := p.modelIndex.Lookup(req.Model)
 
 	if modelInfo == nil {
 		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
 	}
 
-	// Estimate input tokens (rough approximation)
+	// Estimate input tokens
 	inputTokens := p.estimateTokens(req)
 
 	// Estimate output tokens (use max_tokens or default)
@@ -133,6 +284,10 @@ func (p *AnthropicProvider) EstimateCost(req *types.ChatRequest) (*types.CostEst
 		outputTokens = *req.MaxTokens
 	}
 
+	// Each of n completions is a separate call billing the prompt again
+	inputTokens *= req.ChoiceCount()
+	outputTokens *= req.ChoiceCount()
+
 	totalTokens := inputTokens + outputTokens
 	inputCost := float64(inputTokens) * modelInfo.InputCostPer1K / 1000
 	outputCost := float64(outputTokens) * modelInfo.OutputCostPer1K / 1000
@@ -151,9 +306,36 @@ func (p *AnthropicProvider) EstimateCost(req *types.ChatRequest) (*types.CostEst
 
 // HealthCheck performs a health check on the Anthropic API
 func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	
+	// Use the configured custom probe when present
+	if p.config.HealthProbe != nil {
+		headers := map[string]string{
+			"x-api-key":         p.config.APIKey,
+			"anthropic-version": "2023-06-01",
+		}
+		if err := p.config.HealthProbe.Run(ctx, p.httpClient, baseURL, headers); err != nil {
+			p.logger.WithError(err).Error("Anthropic health probe failed")
+			return fmt.Errorf("anthropic health check failed: %w", err)
+		}
+		p.logger.Debug("Anthropic health probe passed")
+		return nil
+	}
+	
+	if p.config.DisableLiveHealthCheck {
+		if err := providers.Ping(ctx, p.httpClient, baseURL); err != nil {
+			p.logger.WithError(err).Error("Anthropic health ping failed")
+			return fmt.Errorf("anthropic health check failed: %w", err)
+		}
+		return nil
+	}
+	
 	// Simple health check using a minimal message
 	testReq := anthropic.MessageNewParams{
-		Model: anthropic.Model("claude-3-haiku-20240307"), // Use cheapest model for health check
+		Model: anthropic.Model(p.healthCheckModel()),
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock("test")),
 		},
@@ -170,6 +352,22 @@ func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *AnthropicProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// healthCheckModel returns the model used by the live health check
+func (p *AnthropicProvider) healthCheckModel() string {
+	if p.config.HealthCheckModel != "" {
+		return p.config.HealthCheckModel
+	}
+	if model := providers.CheapestModel(p.config.Models); model != "" {
+		return model
+	}
+	return defaultHealthCheckModel
+}
+
 // Interface implementations for advanced features
 
 // SupportsFunctionCalling implements FunctionCallingProvider
@@ -212,6 +410,21 @@ func (p *AnthropicProvider) CreateBatch(ctx context.Context, req *types.BatchReq
 	return nil, fmt.Errorf("batch processing not supported by Anthropic provider")
 }
 
+// GetBatch implements BatchProvider (returns not supported error)
+func (p *AnthropicProvider) GetBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	return nil, fmt.Errorf("batch processing not supported by Anthropic provider")
+}
+
+// CancelBatch implements BatchProvider (returns not supported error)
+func (p *AnthropicProvider) CancelBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	return nil, fmt.Errorf("batch processing not supported by Anthropic provider")
+}
+
+// ListBatches implements BatchProvider (returns not supported error)
+func (p *AnthropicProvider) ListBatches(ctx context.Context) (*types.BatchListResponse, error) {
+	return nil, fmt.Errorf("batch processing not supported by Anthropic provider")
+}
+
 // SupportsAssistants implements AssistantProvider
 func (p *AnthropicProvider) SupportsAssistants() bool {
 	return false // No assistants API
@@ -222,10 +435,25 @@ func (p *AnthropicProvider) CreateAssistant(ctx context.Context, req *types.Assi
 	return nil, fmt.Errorf("assistants not supported by Anthropic provider")
 }
 
+// SupportsEmbeddings implements EmbeddingProvider
+func (p *AnthropicProvider) SupportsEmbeddings() bool {
+	return false // No embeddings API
+}
+
+// CreateEmbedding implements EmbeddingProvider (returns not supported error)
+func (p *AnthropicProvider) CreateEmbedding(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	return nil, fmt.Errorf("anthropic: %w", providers.ErrEmbeddingsNotSupported)
+}
+
+// EstimateEmbeddingCost implements EmbeddingProvider (returns not supported error)
+func (p *AnthropicProvider) EstimateEmbeddingCost(req *types.EmbeddingRequest) (*types.CostEstimate, error) {
+	return nil, fmt.Errorf("anthropic: %w", providers.ErrEmbeddingsNotSupported)
+}
+
 // Helper functions
 
 // convertToAnthropicRequest converts our unified request to Anthropic's format
-func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*anthropic.MessageNewParams, error) {
+func (p *AnthropicProvider) convertToAnthropicRequest(ctx context.Context, req *types.ChatRequest) (*anthropic.MessageNewParams, error) {
 	// Extract system message if present
 	var systemMessage string
 	var messages []anthropic.MessageParam
@@ -235,7 +463,10 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*
 			// Claude handles system messages separately
 			switch content := msg.Content.(type) {
 			case string:
-				systemMessage = content
+				if systemMessage != "" {
+					systemMessage += "\n\n"
+				}
+				systemMessage += content
 			default:
 				return nil, fmt.Errorf("system messages must be text only for Anthropic")
 			}
@@ -243,13 +474,27 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*
 		}
 		
 		// Convert regular messages
-		anthropicMsg, err := p.convertMessage(msg)
+		anthropicMsg, err := p.convertMessage(ctx, msg)
 		if err != nil {
 			return nil, err
 		}
 		messages = append(messages, anthropicMsg)
 	}
 
+	// Ask for JSON since Claude can't be constrained to it
+	if p.config.JSONMode.Enabled {
+		instruction, err := jsonModeInstruction(req.ResponseFormat)
+		if err != nil {
+			return nil, err
+		}
+		if instruction != "" {
+			if systemMessage != "" {
+				systemMessage += "\n\n"
+			}
+			systemMessage += instruction
+		}
+	}
+
 	// Build the request
 	anthropicReq := &anthropic.MessageNewParams{
 		Model:    anthropic.Model(req.Model),
@@ -292,8 +537,11 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*
 				// Convert parameters schema if available
 				var inputSchema anthropic.ToolInputSchemaParam
 				if tool.Function.Parameters != nil {
-					// For now, use an empty schema as direct conversion is complex
-					inputSchema = anthropic.ToolInputSchemaParam{}
+					schema, err := convertToolInputSchema(tool.Function.Parameters)
+					if err != nil {
+						return nil, fmt.Errorf("invalid parameters for tool %s: %w", tool.Function.Name, err)
+					}
+					inputSchema = schema
 				}
 				
 				// Create tool using the union constructor
@@ -301,6 +549,9 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*
 					inputSchema,
 					tool.Function.Name,
 				)
+				if tool.Function.Description != "" {
+					anthropicTool.OfTool.Description = anthropic.String(tool.Function.Description)
+				}
 				
 				tools = append(tools, anthropicTool)
 			}
@@ -311,8 +562,71 @@ func (p *AnthropicProvider) convertToAnthropicRequest(req *types.ChatRequest) (*
 	return anthropicReq, nil
 }
 
+// convertToolInputSchema maps an OpenAI-style JSON schema for function
+// parameters onto Anthropic's tool input schema. Keywords other than
+// properties, required and type are passed through as extra fields.
+func convertToolInputSchema(parameters interface{}) (anthropic.ToolInputSchemaParam, error) {
+	var schema map[string]interface{}
+	switch params := parameters.(type) {
+	case map[string]interface{}:
+		schema = params
+	default:
+		// Normalize typed schemas (structs, json.RawMessage, etc.) through JSON
+		data, err := json.Marshal(parameters)
+		if err != nil {
+			return anthropic.ToolInputSchemaParam{}, err
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return anthropic.ToolInputSchemaParam{}, fmt.Errorf("parameters must be a JSON object: %w", err)
+		}
+	}
+	
+	var inputSchema anthropic.ToolInputSchemaParam
+	for key, value := range schema {
+		switch key {
+		case "type":
+			// Anthropic tool inputs are always objects
+		case "properties":
+			inputSchema.Properties = value
+		case "required":
+			required, err := toStringSlice(value)
+			if err != nil {
+				return anthropic.ToolInputSchemaParam{}, fmt.Errorf("invalid required list: %w", err)
+			}
+			inputSchema.Required = required
+		default:
+			if inputSchema.ExtraFields == nil {
+				inputSchema.ExtraFields = make(map[string]any)
+			}
+			inputSchema.ExtraFields[key] = value
+		}
+	}
+	
+	return inputSchema, nil
+}
+
+// toStringSlice converts a decoded JSON array into a slice of strings
+func toStringSlice(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string, got %T", item)
+			}
+			result = append(result, str)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected array, got %T", value)
+	}
+}
+
 // convertMessage converts a unified message to Anthropic format
-func (p *AnthropicProvider) convertMessage(msg types.Message) (anthropic.MessageParam, error) {
+func (p *AnthropicProvider) convertMessage(ctx context.Context, msg types.Message) (anthropic.MessageParam, error) {
 	// Handle content based on type and create appropriate message
 	switch content := msg.Content.(type) {
 	case string:
@@ -324,13 +638,19 @@ func (p *AnthropicProvider) convertMessage(msg types.Message) (anthropic.Message
 		}
 		
 	case []types.ContentPart:
-		// Multimodal message - only handle text parts for now
+		// Multimodal message
 		var blocks []anthropic.ContentBlockParamUnion
 		for _, part := range content {
-			if part.Type == "text" {
+			switch part.Type {
+			case "text":
 				blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+			case "image_url":
+				block, err := p.convertImagePart(ctx, part)
+				if err != nil {
+					return anthropic.MessageParam{}, err
+				}
+				blocks = append(blocks, block)
 			}
-			// Skip image parts for now - would need base64 conversion
 		}
 		
 		if msg.Role == "user" {
@@ -398,37 +718,22 @@ func (p *AnthropicProvider) convertFromAnthropicResponse(resp *anthropic.Message
 }
 
 
-// estimateTokens provides a rough estimate of tokens in the request
+// estimateTokens estimates the prompt tokens in the request
 func (p *AnthropicProvider) estimateTokens(req *types.ChatRequest) int {
-	totalChars := 0
-	
-	for _, msg := range req.Messages {
-		switch content := msg.Content.(type) {
-		case string:
-			totalChars += len(content)
-		case []types.ContentPart:
-			for _, part := range content {
-				if part.Type == "text" {
-					totalChars += len(part.Text)
-				}
-				// Images add significant token cost for Claude
-				if part.Type == "image_url" {
-					totalChars += 1500 // Rough image token equivalent for Claude
-				}
-			}
-		}
-		
-		// Add role tokens
-		totalChars += len(msg.Role)
+	// Always count with the Claude approximation, even for aliased model names
+	model := req.Model
+	if !strings.HasPrefix(model, "claude") {
+		model = "claude"
 	}
 	
+	total := tokenizer.CountTokens(model, req.Messages)
+	
 	// Add tool tokens
 	for _, tool := range req.Tools {
-		totalChars += len(tool.Function.Name) + len(tool.Function.Description)
+		total += tokenizer.CountText(model, tool.Function.Name) + tokenizer.CountText(model, tool.Function.Description)
 	}
 	
-	// Claude token estimation: approximately 3.5 chars per token
-	return totalChars * 10 / 35
+	return total
 }
 
 // Ensure AnthropicProvider implements all the interfaces
@@ -437,4 +742,33 @@ var _ providers.FunctionCallingProvider = (*AnthropicProvider)(nil)
 var _ providers.VisionProvider = (*AnthropicProvider)(nil)
 var _ providers.StructuredOutputProvider = (*AnthropicProvider)(nil)
 var _ providers.BatchProvider = (*AnthropicProvider)(nil)
-var _ providers.AssistantProvider = (*AnthropicProvider)(nil)
\ No newline at end of file
+var _ providers.AssistantProvider = (*AnthropicProvider)(nil)
+var _ providers.EmbeddingProvider = (*AnthropicProvider)(nil)
+
+// apiStatusCode extracts the HTTP status code from an Anthropic SDK error
+func apiStatusCode(err error) int {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// newProviderError normalizes an Anthropic SDK error, reading the error type
+// and message from the API's error body, e.g.
+// {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+func newProviderError(err error) *providers.ProviderError {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return providers.NewProviderError("anthropic", 0, "", "", err)
+	}
+
+	var body struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal([]byte(apiErr.RawJSON()), &body)
+	return providers.NewProviderError("anthropic", apiErr.StatusCode, body.Error.Type, body.Error.Message, err)
+}
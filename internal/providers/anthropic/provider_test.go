@@ -1,10 +1,22 @@
 package anthropic
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
@@ -184,7 +196,7 @@ func TestAnthropicProvider_ConvertRequest(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req, err := provider.convertToAnthropicRequest(tt.request)
+			req, err := provider.convertToAnthropicRequest(context.Background(), tt.request)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("convertToAnthropicRequest() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -197,6 +209,73 @@ func TestAnthropicProvider_ConvertRequest(t *testing.T) {
 	}
 }
 
+func TestAnthropicProvider_ConvertRequest_ToolSchema(t *testing.T) {
+	provider := createTestProvider(t)
+	
+	req := &types.ChatRequest{
+		Model: "claude-3-5-sonnet-20241022",
+		Messages: []types.Message{
+			{Role: "user", Content: "What's the weather in Paris?"},
+		},
+		Tools: []types.Tool{
+			{
+				Type: "function",
+				Function: types.Function{
+					Name:        "get_weather",
+					Description: "Get the current weather for a location",
+					Parameters: map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"location": map[string]interface{}{
+								"type":        "string",
+								"description": "City name",
+							},
+							"unit": map[string]interface{}{
+								"type": "string",
+								"enum": []interface{}{"celsius", "fahrenheit"},
+							},
+						},
+						"required": []interface{}{"location"},
+					},
+				},
+			},
+		},
+	}
+	
+	anthropicReq, err := provider.convertToAnthropicRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("convertToAnthropicRequest() error = %v", err)
+	}
+	
+	if len(anthropicReq.Tools) != 1 || anthropicReq.Tools[0].OfTool == nil {
+		t.Fatalf("Expected one converted tool, got %+v", anthropicReq.Tools)
+	}
+	
+	tool := anthropicReq.Tools[0].OfTool
+	if tool.Name != "get_weather" {
+		t.Errorf("Expected tool name 'get_weather', got %s", tool.Name)
+	}
+	if tool.Description.Value != "Get the current weather for a location" {
+		t.Errorf("Expected tool description to be carried through, got %q", tool.Description.Value)
+	}
+	
+	properties, ok := tool.InputSchema.Properties.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected schema properties map, got %T", tool.InputSchema.Properties)
+	}
+	location, ok := properties["location"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected 'location' property in converted schema")
+	}
+	if location["type"] != "string" {
+		t.Errorf("Expected 'location' to be a string property, got %v", location["type"])
+	}
+	
+	if len(tool.InputSchema.Required) != 1 || tool.InputSchema.Required[0] != "location" {
+		t.Errorf("Expected required [location], got %v", tool.InputSchema.Required)
+	}
+}
+
 func TestAnthropicProvider_Interfaces(t *testing.T) {
 	provider := createTestProvider(t)
 	
@@ -233,6 +312,12 @@ func TestAnthropicProvider_Interfaces(t *testing.T) {
 	if provider.SupportsBatch() {
 		t.Error("Anthropic should not support batch processing yet")
 	}
+	if _, err := provider.GetBatch(context.Background(), "batch_123"); err == nil {
+		t.Error("Expected GetBatch to report batch processing as unsupported")
+	}
+	if _, err := provider.CancelBatch(context.Background(), "batch_123"); err == nil {
+		t.Error("Expected CancelBatch to report batch processing as unsupported")
+	}
 	
 	// Test AssistantProvider interface
 	if provider.SupportsAssistants() {
@@ -240,6 +325,79 @@ func TestAnthropicProvider_Interfaces(t *testing.T) {
 	}
 }
 
+// onePixelPNG is a base64 encoded 1x1 PNG
+const onePixelPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+func imageMessage(url string) types.Message {
+	return types.Message{
+		Role: "user",
+		Content: []types.ContentPart{
+			{Type: "text", Text: "What's in this image?"},
+			{Type: "image_url", ImageURL: &types.ImageURL{URL: url}},
+		},
+	}
+}
+
+func TestAnthropicProvider_ConvertMessage_DataURLImage(t *testing.T) {
+	provider := createTestProvider(t)
+	
+	msg, err := provider.convertMessage(context.Background(), imageMessage("data:image/png;base64,"+onePixelPNG))
+	if err != nil {
+		t.Fatalf("convertMessage() error = %v", err)
+	}
+	
+	if len(msg.Content) != 2 {
+		t.Fatalf("Expected text and image blocks, got %d blocks", len(msg.Content))
+	}
+	image := msg.Content[1].OfImage
+	if image == nil || image.Source.OfBase64 == nil {
+		t.Fatalf("Expected a base64 image block, got %+v", msg.Content[1])
+	}
+	if image.Source.OfBase64.MediaType != "image/png" {
+		t.Errorf("Expected media type image/png, got %s", image.Source.OfBase64.MediaType)
+	}
+	if image.Source.OfBase64.Data != onePixelPNG {
+		t.Error("Expected image data to be passed through unchanged")
+	}
+}
+
+func TestAnthropicProvider_ConvertMessage_RemoteImage(t *testing.T) {
+	png, _ := base64.StdEncoding.DecodeString(onePixelPNG)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	defer server.Close()
+	
+	provider := createTestProvider(t)
+	msg, err := provider.convertMessage(context.Background(), imageMessage(server.URL+"/cat.png"))
+	if err != nil {
+		t.Fatalf("convertMessage() error = %v", err)
+	}
+	
+	image := msg.Content[1].OfImage
+	if image == nil || image.Source.OfBase64 == nil {
+		t.Fatalf("Expected a base64 image block, got %+v", msg.Content[1])
+	}
+	if image.Source.OfBase64.Data != onePixelPNG {
+		t.Error("Expected fetched image to be base64 encoded")
+	}
+}
+
+func TestAnthropicProvider_ConvertMessage_UnsupportedImage(t *testing.T) {
+	provider := createTestProvider(t)
+	
+	for _, url := range []string{
+		"data:image/bmp;base64,Qk0=",
+		"data:image/png,not-base64",
+		"ftp://example.com/cat.png",
+	} {
+		if _, err := provider.convertMessage(context.Background(), imageMessage(url)); err == nil {
+			t.Errorf("Expected an error for image url %s", url)
+		}
+	}
+}
+
 func TestAnthropicProvider_TokenEstimation(t *testing.T) {
 	provider := createTestProvider(t)
 	
@@ -293,7 +451,478 @@ func TestAnthropicProvider_TokenEstimation(t *testing.T) {
 	}
 }
 
+func TestAnthropicProvider_ChatCompletionTimeout(t *testing.T) {
+	release := make(chan struct{})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer gateway.Close()
+	defer close(release)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	config := withBaseURL(createTestProvider(t).config, gateway.URL)
+	config.Timeout = 50 * time.Millisecond
+	provider := NewAnthropicProvider(config, logger)
+
+	start := time.Now()
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected a timeout error from a slow upstream")
+	}
+	if !providers.IsTimeout(err) {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the call to give up after the configured timeout, took %s", elapsed)
+	}
+}
+
+func TestAnthropicProvider_MultipleChoices(t *testing.T) {
+	var calls atomic.Int32
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fmt.Sprintf(`{"id":"msg_%d","type":"message","role":"assistant","model":"claude-3-haiku-20240307",`+
+			`"content":[{"type":"text","text":"candidate %d"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":5}}`, n, n)))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewAnthropicProvider(withBaseURL(createTestProvider(t).config, gateway.URL), logger)
+
+	n := 3
+	resp, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		N:        &n,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 API calls, got %d", calls.Load())
+	}
+	if len(resp.Choices) != 3 {
+		t.Fatalf("Expected 3 choices, got %d", len(resp.Choices))
+	}
+	texts := map[string]bool{}
+	for i, choice := range resp.Choices {
+		if choice.Index != i {
+			t.Errorf("Expected choice %d to have index %d, got %d", i, i, choice.Index)
+		}
+		texts[choice.Message.Content.(string)] = true
+	}
+	if len(texts) != 3 {
+		t.Errorf("Expected 3 distinct candidates, got %v", texts)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 30 || resp.Usage.CompletionTokens != 15 {
+		t.Errorf("Expected usage summed across calls, got %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicProvider_StreamToolUse(t *testing.T) {
+	// A text block followed by two tool_use blocks whose input arrives as
+	// input_json_delta fragments
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku-20240307","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_a","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_b","name":"get_time","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"zone\":\"Europe/Paris\"}"}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":40}}`,
+		`{"type":"message_stop"}`,
+	}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewAnthropicProvider(withBaseURL(createTestProvider(t).config, gateway.URL), logger)
+
+	chunks, err := provider.StreamCompletion(context.Background(), &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Weather and time in Paris?"}},
+		Stream:   true,
+	})
+	if err != nil {
+		t.Fatalf("StreamCompletion() error = %v", err)
+	}
+
+	var calls types.ToolCallAccumulator
+	var text strings.Builder
+	var finishReason string
+	var usage *types.Usage
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("Unexpected stream error: %v", chunk.Err)
+		}
+		if chunk.ID != "msg_1" || chunk.Object != "chat.completion.chunk" {
+			t.Errorf("Expected chunks for msg_1, got %s %s", chunk.ID, chunk.Object)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta == nil {
+				continue
+			}
+			text.WriteString(types.MessageText(choice.Delta.Content))
+			for _, fragment := range choice.Delta.ToolCalls {
+				if fragment.Index == nil {
+					t.Fatalf("Expected streamed tool call fragments to carry their index")
+				}
+				if fragment.Function.Parameters != nil {
+					t.Errorf("Expected arguments in Function.Arguments only, got parameters %v", fragment.Function.Parameters)
+				}
+			}
+			calls.Add(choice.Delta.ToolCalls)
+		}
+	}
+
+	if text.String() != "Checking." {
+		t.Errorf("Expected streamed text, got %q", text.String())
+	}
+	if finishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 25 || usage.CompletionTokens != 40 || usage.TotalTokens != 65 {
+		t.Errorf("Expected usage from message_start and message_delta, got %+v", usage)
+	}
+
+	// The reassembled calls have the same shape as the OpenAI stream's
+	got := calls.ToolCalls()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 reassembled tool calls, got %d", len(got))
+	}
+	want := []struct{ id, name, arguments string }{
+		{"toolu_a", "get_weather", `{"city":"Paris"}`},
+		{"toolu_b", "get_time", `{"zone":"Europe/Paris"}`},
+	}
+	for i, call := range got {
+		if *call.Index != i || call.ID != want[i].id || call.Function.Name != want[i].name || call.Type != "function" {
+			t.Errorf("Call %d: expected %s %s, got %+v", i, want[i].id, want[i].name, call)
+		}
+		if call.Function.Arguments != want[i].arguments {
+			t.Errorf("Call %d: expected arguments %s, got %s", i, want[i].arguments, call.Function.Arguments)
+		}
+	}
+}
+
+func TestAnthropicProvider_StreamRejected(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewAnthropicProvider(withBaseURL(createTestProvider(t).config, gateway.URL), logger)
+
+	// The rejection surfaces before any chunks, so the router can fall back
+	_, err := provider.StreamCompletion(context.Background(), &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		Stream:   true,
+	})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 provider error, got %v", err)
+	}
+}
+
+func TestAnthropicProvider_ErrorClass(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Keep the SDK from retrying on its own
+			w.Header().Set("X-Should-Retry", "false")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"type":"error","error":{"type":"api_error","message":"upstream failure"}}`))
+		}))
+
+		provider := NewAnthropicProvider(withBaseURL(createTestProvider(t).config, gateway.URL), logger)
+		_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+			Model:    "claude-3-haiku-20240307",
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		gateway.Close()
+
+		if got, want := providers.ErrorClass(err), strconv.Itoa(status); got != want {
+			t.Errorf("Expected class %s for status %d, got %s (%v)", want, status, got, err)
+		}
+	}
+}
+
+func TestAnthropicProvider_NormalizedError(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Should-Retry", "false")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewAnthropicProvider(withBaseURL(createTestProvider(t).config, gateway.URL), logger)
+
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected a provider error, got %v", err)
+	}
+	if providerErr.Provider != "anthropic" || providerErr.StatusCode != 529 {
+		t.Errorf("Expected an anthropic 529, got %s %d", providerErr.Provider, providerErr.StatusCode)
+	}
+	if providerErr.Type != providers.ErrorTypeOverloaded || providerErr.Code != "overloaded_error" || !providerErr.Retryable {
+		t.Errorf("Expected a retryable overloaded error, got type %s, code %s, retryable %v", providerErr.Type, providerErr.Code, providerErr.Retryable)
+	}
+	if providerErr.Message != "Overloaded" {
+		t.Errorf("Expected the API's message, got %q", providerErr.Message)
+	}
+	if got := err.Error(); got != "anthropic api call failed (status 529): Overloaded" {
+		t.Errorf("Expected a normalized error message, got %q", got)
+	}
+}
+
 // Helper functions
+func TestAnthropicProvider_HealthCheckModel(t *testing.T) {
+	var mu sync.Mutex
+	var gotModels []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		gotModels = append(gotModels, body.Model)
+		mu.Unlock()
+		
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"` + body.Model + `","content":[{"type":"text","text":"ok"}],"stop_reason":"max_tokens","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer gateway.Close()
+	
+	logger := logrus.New()
+	
+	// Defaults to the cheapest configured model, wherever it is listed
+	config := withBaseURL(createTestProvider(t).config, gateway.URL)
+	config.Models[0], config.Models[1] = config.Models[1], config.Models[0]
+	if err := NewAnthropicProvider(config, logger).HealthCheck(context.Background()); err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	
+	// A configured health check model takes precedence
+	config = withBaseURL(createTestProvider(t).config, gateway.URL)
+	config.HealthCheckModel = "claude-custom-health"
+	if err := NewAnthropicProvider(config, logger).HealthCheck(context.Background()); err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gotModels) != 2 || gotModels[0] != "claude-3-haiku-20240307" || gotModels[1] != "claude-custom-health" {
+		t.Errorf("Expected health checks against [claude-3-haiku-20240307 claude-custom-health], got %v", gotModels)
+	}
+}
+
+func TestAnthropicProvider_HealthCheckPing(t *testing.T) {
+	var paths []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer gateway.Close()
+	
+	config := withBaseURL(createTestProvider(t).config, gateway.URL)
+	config.DisableLiveHealthCheck = true
+	provider := NewAnthropicProvider(config, logrus.New())
+	if err := provider.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("Health ping failed: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "HEAD /" {
+		t.Errorf("Expected a single HEAD ping instead of a messages call, got %v", paths)
+	}
+	
+	gateway.Close()
+	if err := provider.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected health ping to fail when the API is unreachable")
+	}
+}
+
+func TestAnthropicProvider_JSONModeInstruction(t *testing.T) {
+	provider := createTestProvider(t)
+	req := &types.ChatRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "system", Content: "You are helpful"}, {Role: "user", Content: "Hi"}},
+		ResponseFormat: &types.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &types.JSONSchema{Name: "greeting", Schema: map[string]interface{}{"type": "object", "required": []interface{}{"text"}}},
+		},
+	}
+
+	// Disabled by default, leaving the system prompt alone
+	converted, err := provider.convertToAnthropicRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("convertToAnthropicRequest() error = %v", err)
+	}
+	if len(converted.System) != 1 || converted.System[0].Text != "You are helpful" {
+		t.Errorf("Expected the system prompt unchanged, got %+v", converted.System)
+	}
+	if provider.GetCapabilities().SupportsJSONMode {
+		t.Error("Expected JSON mode not to be advertised when disabled")
+	}
+
+	provider.config.JSONMode.Enabled = true
+	converted, err = provider.convertToAnthropicRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("convertToAnthropicRequest() error = %v", err)
+	}
+	system := converted.System[0].Text
+	if !strings.HasPrefix(system, "You are helpful\n\nRespond only with valid JSON") {
+		t.Errorf("Expected the JSON instruction after the system prompt, got %q", system)
+	}
+	if !strings.Contains(system, `{"required":["text"],"type":"object"}`) {
+		t.Errorf("Expected the schema in the instruction, got %q", system)
+	}
+	if !provider.GetCapabilities().SupportsJSONMode || provider.GetCapabilities().SupportsStructuredOutput {
+		t.Error("Expected JSON mode to be advertised as soft structured output")
+	}
+
+	// Plain text requests get no instruction
+	req.ResponseFormat = nil
+	converted, err = provider.convertToAnthropicRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("convertToAnthropicRequest() error = %v", err)
+	}
+	if converted.System[0].Text != "You are helpful" {
+		t.Errorf("Expected no instruction for text output, got %q", converted.System[0].Text)
+	}
+}
+
+func TestRepairJSONOutput(t *testing.T) {
+	format := &types.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &types.JSONSchema{Name: "person", Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name", "age"},
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{"type": "string"},
+				"age":  map[string]interface{}{"type": "integer"},
+				"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "valid", content: `{"name":"Ada","age":36}`, want: `{"name":"Ada","age":36}`},
+		{name: "code fence", content: "```json\n{\"name\":\"Ada\",\"age\":36}\n```", want: `{"name":"Ada","age":36}`},
+		{name: "surrounding prose", content: `Here you go: {"name":"Ada","age":36} Hope that helps!`, want: `{"name":"Ada","age":36}`},
+		{name: "not JSON", content: "Ada is 36", wantErr: "not valid JSON"},
+		{name: "missing required", content: `{"name":"Ada"}`, wantErr: `$ is missing required property "age"`},
+		{name: "wrong type", content: `{"name":"Ada","age":36.5}`, wantErr: "$.age must be of type integer"},
+		{name: "wrong item type", content: `{"name":"Ada","age":36,"tags":["a",1]}`, wantErr: "$.tags[1] must be of type string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repairJSONOutput(tt.content, format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("repairJSONOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAnthropicProvider_JSONModeValidation(t *testing.T) {
+	text := "```json\n{\"name\":\"Ada\"}\n```"
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(text)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku-20240307",` +
+			`"content":[{"type":"text","text":` + string(body) + `}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer gateway.Close()
+
+	config := withBaseURL(createTestProvider(t).config, gateway.URL)
+	config.JSONMode = JSONModeConfig{Enabled: true, ValidateOutput: true}
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewAnthropicProvider(config, logger)
+
+	req := &types.ChatRequest{
+		Model:          "claude-3-haiku-20240307",
+		Messages:       []types.Message{{Role: "user", Content: "Who?"}},
+		ResponseFormat: &types.ResponseFormat{Type: "json_object"},
+	}
+	resp, err := provider.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if resp.Choices[0].Message.Content != `{"name":"Ada"}` {
+		t.Errorf("Expected the code fence stripped, got %q", resp.Choices[0].Message.Content)
+	}
+
+	// Output missing a required property fails the request
+	req.ResponseFormat = &types.ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &types.JSONSchema{Name: "person", Schema: map[string]interface{}{"required": []interface{}{"age"}}},
+	}
+	if _, err := provider.ChatCompletion(context.Background(), req); !errors.Is(err, errInvalidJSONOutput) {
+		t.Errorf("Expected the schema mismatch to fail the request, got %v", err)
+	}
+}
+
+func withBaseURL(config *AnthropicConfig, baseURL string) *AnthropicConfig {
+	config.BaseURL = baseURL
+	return config
+}
+
 func createTestProvider(t *testing.T) *AnthropicProvider {
 	logger := logrus.New()
 	logger.SetLevel(logrus.WarnLevel)
@@ -356,6 +985,6 @@ func BenchmarkAnthropicProvider_ConvertRequest(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = provider.convertToAnthropicRequest(req)
+		_, _ = provider.convertToAnthropicRequest(context.Background(), req)
 	}
 }
\ No newline at end of file
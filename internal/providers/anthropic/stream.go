@@ -0,0 +1,105 @@
+package anthropic
+
+import (
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// streamState tracks a streamed Messages API response across events, so
+// tool_use blocks come out as OpenAI-style tool call deltas: the call's ID
+// and name first, then its input_json_delta fragments as arguments, all
+// under the call's index among the response's tool calls
+type streamState struct {
+	id          string
+	model       string
+	created     int64
+	inputTokens int
+	toolCalls   int           // tool_use blocks seen so far
+	toolIndex   map[int64]int // content block index -> tool call index
+}
+
+// newStreamState starts converting a streamed response for model
+func newStreamState(model string) *streamState {
+	return &streamState{model: model, created: time.Now().Unix(), toolIndex: make(map[int64]int)}
+}
+
+// convert returns the chunk for one stream event, or nil for events that
+// carry nothing for the client
+func (s *streamState) convert(event *anthropic.MessageStreamEventUnion) *types.ChatChunk {
+	switch event.Type {
+	case "message_start":
+		s.id = event.Message.ID
+		s.inputTokens = int(event.Message.Usage.InputTokens)
+		return s.chunk(types.ChoiceChunk{Delta: &types.Message{Role: "assistant"}}, nil)
+
+	case "content_block_start":
+		if event.ContentBlock.Type != "tool_use" {
+			return nil
+		}
+		index := s.toolCalls
+		s.toolIndex[event.Index] = index
+		s.toolCalls++
+		return s.chunk(types.ChoiceChunk{Delta: &types.Message{ToolCalls: []types.ToolCall{{
+			Index:    &index,
+			ID:       event.ContentBlock.ID,
+			Type:     "function",
+			Function: types.Function{Name: event.ContentBlock.Name},
+		}}}}, nil)
+
+	case "content_block_delta":
+		switch event.Delta.Type {
+		case "text_delta":
+			return s.chunk(types.ChoiceChunk{Delta: &types.Message{Content: event.Delta.Text}}, nil)
+		case "input_json_delta":
+			index, ok := s.toolIndex[event.Index]
+			if !ok || event.Delta.PartialJSON == "" {
+				return nil
+			}
+			return s.chunk(types.ChoiceChunk{Delta: &types.Message{ToolCalls: []types.ToolCall{{
+				Index:    &index,
+				Function: types.Function{Arguments: event.Delta.PartialJSON},
+			}}}}, nil)
+		}
+		return nil
+
+	case "message_delta":
+		outputTokens := int(event.Usage.OutputTokens)
+		usage := &types.Usage{
+			PromptTokens:     s.inputTokens,
+			CompletionTokens: outputTokens,
+			TotalTokens:      s.inputTokens + outputTokens,
+		}
+		return s.chunk(types.ChoiceChunk{FinishReason: finishReason(event.Delta.StopReason)}, usage)
+	}
+
+	return nil
+}
+
+// chunk wraps a choice in a chat chunk for the stream
+func (s *streamState) chunk(choice types.ChoiceChunk, usage *types.Usage) *types.ChatChunk {
+	return &types.ChatChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []types.ChoiceChunk{choice},
+		Usage:   usage,
+	}
+}
+
+// finishReason maps Anthropic stop reasons to OpenAI finish reasons
+func finishReason(stopReason anthropic.StopReason) string {
+	switch stopReason {
+	case anthropic.StopReasonEndTurn, anthropic.StopReasonStopSequence:
+		return "stop"
+	case anthropic.StopReasonMaxTokens:
+		return "length"
+	case anthropic.StopReasonToolUse:
+		return "tool_calls"
+	default:
+		return string(stopReason)
+	}
+}
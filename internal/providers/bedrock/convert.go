@@ -0,0 +1,559 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// anthropicVersion is the Messages API version Bedrock expects in Claude
+// payloads
+const anthropicVersion = "bedrock-2023-05-31"
+
+// defaultMaxTokens is sent when the request sets no limit, since Claude
+// requires one
+const defaultMaxTokens = 1024
+
+// modelFamily identifies the payload format a Bedrock model speaks
+type modelFamily int
+
+const (
+	familyUnknown modelFamily = iota
+	familyClaude
+	familyTitan
+)
+
+// familyOf returns the family of a Bedrock model ID such as
+// "anthropic.claude-3-haiku-20240307-v1:0" or "amazon.titan-text-express-v1".
+// Cross-region inference profiles like "us.anthropic.claude-..." match too.
+func familyOf(modelID string) modelFamily {
+	switch {
+	case strings.Contains(modelID, "anthropic.claude"):
+		return familyClaude
+	case strings.Contains(modelID, "amazon.titan"):
+		return familyTitan
+	default:
+		return familyUnknown
+	}
+}
+
+// Claude Messages API wire types, as hosted by Bedrock
+
+type claudeRequest struct {
+	AnthropicVersion string          `json:"anthropic_version"`
+	MaxTokens        int             `json:"max_tokens"`
+	System           string          `json:"system,omitempty"`
+	Messages         []claudeMessage `json:"messages"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	StopSequences    []string        `json:"stop_sequences,omitempty"`
+	Tools            []claudeTool    `json:"tools,omitempty"`
+}
+
+type claudeMessage struct {
+	Role    string        `json:"role"` // "user" or "assistant"
+	Content []claudeBlock `json:"content"`
+}
+
+type claudeBlock struct {
+	Type      string          `json:"type"` // "text", "image", "tool_use" or "tool_result"
+	Text      string          `json:"text,omitempty"`
+	Source    *imageSource    `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"` // always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type claudeTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type claudeResponse struct {
+	ID         string        `json:"id"`
+	Model      string        `json:"model"`
+	Content    []claudeBlock `json:"content"`
+	StopReason string        `json:"stop_reason"`
+	Usage      claudeUsage   `json:"usage"`
+}
+
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// claudeStreamEvent is one event of a streamed Claude response; which fields
+// are set depends on Type
+type claudeStreamEvent struct {
+	Type         string          `json:"type"`
+	Index        int             `json:"index"`
+	Message      *claudeResponse `json:"message,omitempty"`       // message_start
+	ContentBlock *claudeBlock    `json:"content_block,omitempty"` // content_block_start
+	Delta        *struct {
+		Type        string `json:"type"` // "text_delta" or "input_json_delta"
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"` // message_delta
+	} `json:"delta,omitempty"`
+	Usage *claudeUsage `json:"usage,omitempty"` // message_delta
+}
+
+// Titan text wire types
+
+type titanRequest struct {
+	InputText            string                `json:"inputText"`
+	TextGenerationConfig titanGenerationConfig `json:"textGenerationConfig"`
+}
+
+type titanGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type titanResponse struct {
+	InputTextTokenCount int           `json:"inputTextTokenCount"`
+	Results             []titanResult `json:"results"`
+}
+
+type titanResult struct {
+	TokenCount       int    `json:"tokenCount"`
+	OutputText       string `json:"outputText"`
+	CompletionReason string `json:"completionReason"`
+}
+
+type titanStreamChunk struct {
+	OutputText                string `json:"outputText"`
+	CompletionReason          string `json:"completionReason"`
+	InputTextTokenCount       int    `json:"inputTextTokenCount"`
+	TotalOutputTextTokenCount int    `json:"totalOutputTextTokenCount"`
+}
+
+// convertClaudeRequest builds the Bedrock Claude payload for a unified request
+func convertClaudeRequest(req *types.ChatRequest) ([]byte, error) {
+	claudeReq := claudeRequest{
+		AnthropicVersion: anthropicVersion,
+		MaxTokens:        defaultMaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		StopSequences:    req.Stop,
+	}
+	if req.MaxTokens != nil {
+		claudeReq.MaxTokens = *req.MaxTokens
+	}
+
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			text, ok := msg.Content.(string)
+			if !ok {
+				return nil, fmt.Errorf("system messages must be text only for Bedrock Claude models")
+			}
+			if claudeReq.System != "" {
+				claudeReq.System += "\n\n"
+			}
+			claudeReq.System += text
+			continue
+		}
+
+		message, err := convertClaudeMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		// Claude requires alternating roles, so consecutive turns from the
+		// same side, such as several tool results, are merged
+		if n := len(claudeReq.Messages); n > 0 && claudeReq.Messages[n-1].Role == message.Role {
+			claudeReq.Messages[n-1].Content = append(claudeReq.Messages[n-1].Content, message.Content...)
+			continue
+		}
+		claudeReq.Messages = append(claudeReq.Messages, message)
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		claudeReq.Tools = append(claudeReq.Tools, claudeTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	return json.Marshal(claudeReq)
+}
+
+// convertClaudeMessage converts a unified message to a Claude message. Tool
+// results become user turns; assistant tool calls become tool_use blocks.
+func convertClaudeMessage(msg types.Message) (claudeMessage, error) {
+	if msg.Role == "tool" {
+		text, _ := msg.Content.(string)
+		return claudeMessage{
+			Role:    "user",
+			Content: []claudeBlock{{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: text}},
+		}, nil
+	}
+
+	role := "user"
+	if msg.Role == "assistant" {
+		role = "assistant"
+	}
+
+	var blocks []claudeBlock
+	switch content := msg.Content.(type) {
+	case nil:
+	case string:
+		if content != "" {
+			blocks = append(blocks, claudeBlock{Type: "text", Text: content})
+		}
+	case []types.ContentPart:
+		for _, part := range content {
+			switch part.Type {
+			case "text":
+				blocks = append(blocks, claudeBlock{Type: "text", Text: part.Text})
+			case "image_url":
+				source, err := convertImagePart(part)
+				if err != nil {
+					return claudeMessage{}, err
+				}
+				blocks = append(blocks, claudeBlock{Type: "image", Source: source})
+			}
+		}
+	default:
+		blocks = append(blocks, claudeBlock{Type: "text", Text: fmt.Sprintf("%v", content)})
+	}
+
+	for _, call := range msg.ToolCalls {
+		input := json.RawMessage(call.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		if !json.Valid(input) {
+			return claudeMessage{}, fmt.Errorf("tool call %s has invalid JSON arguments", call.ID)
+		}
+		blocks = append(blocks, claudeBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+
+	return claudeMessage{Role: role, Content: blocks}, nil
+}
+
+// convertImagePart converts an image_url part to a base64 image source.
+// Bedrock only accepts inline images, so the URL must be a base64 data URL.
+func convertImagePart(part types.ContentPart) (*imageSource, error) {
+	if part.ImageURL == nil || part.ImageURL.URL == "" {
+		return nil, fmt.Errorf("image_url part has no url")
+	}
+	if !strings.HasPrefix(part.ImageURL.URL, "data:") {
+		return nil, fmt.Errorf("bedrock only accepts images as base64 data URLs")
+	}
+
+	header, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("malformed image data URL")
+	}
+	mediaType, encoding, _ := strings.Cut(header, ";")
+	if encoding != "base64" {
+		return nil, fmt.Errorf("image data URL must be base64 encoded")
+	}
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "image/jpg" {
+		mediaType = "image/jpeg"
+	}
+	if !supportsImageType(mediaType) {
+		return nil, fmt.Errorf("unsupported image type %s (supported: %s)", mediaType, strings.Join(supportedImageFormats, ", "))
+	}
+
+	return &imageSource{Type: "base64", MediaType: mediaType, Data: data}, nil
+}
+
+// supportsImageType reports whether mediaType is one of the supported formats
+func supportsImageType(mediaType string) bool {
+	for _, format := range supportedImageFormats {
+		if mediaType == "image/"+format {
+			return true
+		}
+	}
+	return false
+}
+
+// convertClaudeResponse converts a Bedrock Claude response body to the
+// unified format
+func convertClaudeResponse(body []byte, model string) (*types.ChatResponse, error) {
+	var resp claudeResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode Claude response: %w", err)
+	}
+
+	message := types.Message{Role: "assistant"}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: types.Function{
+					Name:      block.Name,
+					Arguments: string(block.Input),
+				},
+			})
+		}
+	}
+	message.Content = text.String()
+
+	return &types.ChatResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []types.Choice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: claudeFinishReason(resp.StopReason),
+		}},
+		Usage: newUsage(resp.Usage.InputTokens, resp.Usage.OutputTokens),
+	}, nil
+}
+
+// claudeStream tracks the state of a streamed Claude response across events
+type claudeStream struct {
+	id          string
+	model       string
+	inputTokens int
+	toolCalls   int // tool_use blocks seen so far
+	toolIndex   map[int]int
+}
+
+// newClaudeStream starts decoding a streamed response for model
+func newClaudeStream(model string) *claudeStream {
+	return &claudeStream{model: model, toolIndex: make(map[int]int)}
+}
+
+// convert decodes one streamed event, returning the chunk it produces or nil
+// for events that carry nothing for the client
+func (s *claudeStream) convert(payload []byte) (*types.ChatChunk, error) {
+	var event claudeStreamEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode Claude stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.id = event.Message.ID
+			s.inputTokens = event.Message.Usage.InputTokens
+		}
+		return s.chunk(types.ChoiceChunk{Delta: &types.Message{Role: "assistant"}}, nil), nil
+
+	case "content_block_start":
+		if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+			return nil, nil
+		}
+		index := s.toolCalls
+		s.toolIndex[event.Index] = index
+		s.toolCalls++
+		return s.chunk(types.ChoiceChunk{Delta: &types.Message{ToolCalls: []types.ToolCall{{
+			Index:    &index,
+			ID:       event.ContentBlock.ID,
+			Type:     "function",
+			Function: types.Function{Name: event.ContentBlock.Name},
+		}}}}, nil), nil
+
+	case "content_block_delta":
+		if event.Delta == nil {
+			return nil, nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			return s.chunk(types.ChoiceChunk{Delta: &types.Message{Content: event.Delta.Text}}, nil), nil
+		case "input_json_delta":
+			index := s.toolIndex[event.Index]
+			return s.chunk(types.ChoiceChunk{Delta: &types.Message{ToolCalls: []types.ToolCall{{
+				Index:    &index,
+				Function: types.Function{Arguments: event.Delta.PartialJSON},
+			}}}}, nil), nil
+		}
+		return nil, nil
+
+	case "message_delta":
+		var stopReason string
+		if event.Delta != nil {
+			stopReason = event.Delta.StopReason
+		}
+		var usage *types.Usage
+		if event.Usage != nil {
+			usage = newUsage(s.inputTokens, event.Usage.OutputTokens)
+		}
+		return s.chunk(types.ChoiceChunk{FinishReason: claudeFinishReason(stopReason)}, usage), nil
+	}
+
+	return nil, nil
+}
+
+// chunk wraps a choice in a chat chunk for the stream
+func (s *claudeStream) chunk(choice types.ChoiceChunk, usage *types.Usage) *types.ChatChunk {
+	return &types.ChatChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   s.model,
+		Choices: []types.ChoiceChunk{choice},
+		Usage:   usage,
+	}
+}
+
+// claudeFinishReason maps Claude stop reasons to OpenAI finish reasons
+func claudeFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return stopReason
+	}
+}
+
+// convertTitanRequest builds the Titan text payload for a unified request.
+// Titan takes a single prompt, so the conversation is flattened into
+// "User:"/"Bot:" turns ending with an open bot turn.
+func convertTitanRequest(req *types.ChatRequest) ([]byte, error) {
+	if len(req.Tools) > 0 {
+		return nil, fmt.Errorf("titan models do not support tools")
+	}
+
+	var prompt strings.Builder
+	for _, msg := range req.Messages {
+		text, ok := msg.Content.(string)
+		if !ok {
+			return nil, fmt.Errorf("titan models only accept text messages")
+		}
+		switch msg.Role {
+		case "system":
+			prompt.WriteString(text)
+		case "assistant":
+			prompt.WriteString("Bot: " + text)
+		default:
+			prompt.WriteString("User: " + text)
+		}
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("Bot:")
+
+	titanReq := titanRequest{
+		InputText: prompt.String(),
+		TextGenerationConfig: titanGenerationConfig{
+			Temperature:   req.Temperature,
+			TopP:          req.TopP,
+			StopSequences: req.Stop,
+		},
+	}
+	if req.MaxTokens != nil {
+		titanReq.TextGenerationConfig.MaxTokenCount = *req.MaxTokens
+	}
+
+	return json.Marshal(titanReq)
+}
+
+// convertTitanResponse converts a Titan text response body to the unified
+// format
+func convertTitanResponse(body []byte, model string) (*types.ChatResponse, error) {
+	var resp titanResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode Titan response: %w", err)
+	}
+
+	var outputTokens int
+	choices := make([]types.Choice, 0, len(resp.Results))
+	for i, result := range resp.Results {
+		outputTokens += result.TokenCount
+		choices = append(choices, types.Choice{
+			Index:        i,
+			Message:      types.Message{Role: "assistant", Content: strings.TrimSpace(result.OutputText)},
+			FinishReason: titanFinishReason(result.CompletionReason),
+		})
+	}
+
+	return &types.ChatResponse{
+		ID:      fmt.Sprintf("bedrock-%d", time.Now().UnixNano()),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: choices,
+		Usage:   newUsage(resp.InputTextTokenCount, outputTokens),
+	}, nil
+}
+
+// convertTitanChunk converts one streamed Titan chunk to a chat chunk
+func convertTitanChunk(payload []byte, id, model string) (*types.ChatChunk, error) {
+	var chunk titanStreamChunk
+	if err := json.Unmarshal(payload, &chunk); err != nil {
+		return nil, fmt.Errorf("failed to decode Titan stream chunk: %w", err)
+	}
+
+	choice := types.ChoiceChunk{FinishReason: titanFinishReason(chunk.CompletionReason)}
+	if chunk.OutputText != "" {
+		choice.Delta = &types.Message{Role: "assistant", Content: chunk.OutputText}
+	}
+
+	var usage *types.Usage
+	if chunk.CompletionReason != "" {
+		usage = newUsage(chunk.InputTextTokenCount, chunk.TotalOutputTextTokenCount)
+	}
+
+	return &types.ChatChunk{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []types.ChoiceChunk{choice},
+		Usage:   usage,
+	}, nil
+}
+
+// titanFinishReason maps Titan completion reasons to OpenAI finish reasons
+func titanFinishReason(reason string) string {
+	switch reason {
+	case "FINISH":
+		return "stop"
+	case "LENGTH":
+		return "length"
+	case "CONTENT_FILTERED":
+		return "content_filter"
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// newUsage builds usage from token counts, or nil when none were reported
+func newUsage(inputTokens, outputTokens int) *types.Usage {
+	if inputTokens == 0 && outputTokens == 0 {
+		return nil
+	}
+	return &types.Usage{
+		PromptTokens:     inputTokens,
+		CompletionTokens: outputTokens,
+		TotalTokens:      inputTokens + outputTokens,
+	}
+}
@@ -0,0 +1,426 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// supportedImageFormats are the image types Claude accepts on Bedrock
+var supportedImageFormats = []string{"png", "jpeg", "webp", "gif"}
+
+// runtimeClient is the subset of the Bedrock runtime API the provider uses
+type runtimeClient interface {
+	InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
+	InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error)
+}
+
+// BedrockProvider implements the LLMProvider interface for Claude and Titan
+// models hosted on AWS Bedrock
+type BedrockProvider struct {
+	client     runtimeClient
+	config     *BedrockConfig
+	logger     *logrus.Logger
+	modelIndex *providers.ModelIndex
+}
+
+// BedrockConfig holds Bedrock-specific configuration. Credentials come from
+// the standard AWS chain (environment, shared config, instance role) unless
+// static keys are set. A model's ProviderModelID may be a model ID, an
+// inference profile ID or a provisioned throughput ARN; its Name must contain
+// the model family, e.g. "anthropic.claude-3-haiku-20240307-v1:0".
+type BedrockConfig struct {
+	Region          string            `yaml:"region"`
+	Profile         string            `yaml:"profile"` // shared config profile
+	AccessKeyID     string            `yaml:"access_key_id"`
+	SecretAccessKey string            `yaml:"secret_access_key"`
+	SessionToken    string            `yaml:"session_token"`
+	Endpoint        string            `yaml:"endpoint"` // overrides the regional runtime endpoint
+	Models          []types.ModelInfo `yaml:"models"`
+	Timeout         time.Duration     `yaml:"timeout"`
+
+	providers.CommonConfig `yaml:",inline"`
+}
+
+// NewBedrockProvider creates a new Bedrock provider, resolving AWS
+// credentials and region
+func NewBedrockProvider(ctx context.Context, config *BedrockConfig, logger *logrus.Logger) (*BedrockProvider, error) {
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithHTTPClient(providers.ProviderHTTPClient(config.HTTPClient, 0, false)),
+	}
+	if config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(config.Region))
+	}
+	if config.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(config.Profile))
+	}
+	if config.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, config.SessionToken),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return nil, fmt.Errorf("bedrock region is required")
+	}
+
+	client := bedrockruntime.NewFromConfig(awsCfg, func(o *bedrockruntime.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+
+	return newBedrockProvider(client, config, logger), nil
+}
+
+// newBedrockProvider creates a provider calling Bedrock through client
+func newBedrockProvider(client runtimeClient, config *BedrockConfig, logger *logrus.Logger) *BedrockProvider {
+	return &BedrockProvider{client: client, config: config, logger: logger, modelIndex: providers.NewModelIndex(config.Models)}
+}
+
+// GetProviderName returns the provider name
+func (p *BedrockProvider) GetProviderName() string {
+	return "bedrock"
+}
+
+// GetCapabilities returns the capabilities of the Bedrock provider
+func (p *BedrockProvider) GetCapabilities() types.ProviderCapabilities {
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
+		ProviderName:              "bedrock",
+		SupportedModels:           p.config.Models,
+		SupportsFunctions:         true, // Claude tool use
+		SupportsParallelFunctions: false,
+		SupportsVision:            true, // Claude only
+		SupportsStructuredOutput:  false,
+		SupportsStreaming:         true,
+		SupportsAssistants:        false,
+		SupportsBatch:             false,
+		SupportsEmbeddings:        false,
+		MaxContextWindow:          maxContextWindow(p.config.Models),
+		SupportedImageFormats:     supportedImageFormats,
+		CostPer1KTokens: types.CostStructure{
+			InputCostPer1K:  0.003, // Default Claude-3.5 Sonnet pricing
+			OutputCostPer1K: 0.015,
+			Currency:        "USD",
+		},
+	})
+}
+
+// ChatCompletion performs a chat completion request
+func (p *BedrockProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "bedrock.chat_completion",
+		tracing.AttrProvider.String("bedrock"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	modelID, family, body, err := p.convertRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Bedrock format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	output, err := p.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		p.logger.WithError(err).Error("Bedrock API call failed")
+		tracing.End(span, err)
+		return nil, newProviderError(err)
+	}
+
+	var resp *types.ChatResponse
+	if family == familyTitan {
+		resp, err = convertTitanResponse(output.Body, req.Model)
+	} else {
+		resp, err = convertClaudeResponse(output.Body, req.Model)
+	}
+	tracing.End(span, err)
+	return resp, err
+}
+
+// StreamCompletion performs a streaming chat completion request
+func (p *BedrockProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "bedrock.stream_completion",
+		tracing.AttrProvider.String("bedrock"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+
+	modelID, family, body, err := p.convertRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Bedrock format")
+		tracing.End(span, err)
+		cancel()
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	output, err := p.client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(modelID),
+		Body:        body,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		p.logger.WithError(err).Error("Bedrock streaming API call failed")
+		tracing.End(span, err)
+		cancel()
+		return nil, newProviderError(err)
+	}
+
+	convert := newChunkConverter(family, req.Model)
+	stream := output.GetStream()
+	chunks := make(chan *types.ChatChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer cancel()
+		defer stream.Close()
+
+		for event := range stream.Events() {
+			part, ok := event.(*bedrocktypes.ResponseStreamMemberChunk)
+			if !ok {
+				continue
+			}
+			chunk, err := convert(part.Value.Bytes)
+			if err != nil {
+				p.logger.WithError(err).Error("Error decoding stream chunk")
+				providers.SendStreamError(ctx, chunks, providers.NewProviderError("bedrock", 0, "", "", err))
+				tracing.End(span, err)
+				return
+			}
+			if chunk == nil {
+				continue
+			}
+
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				tracing.End(span, ctx.Err())
+				return
+			}
+		}
+
+		err := stream.Err()
+		if err != nil {
+			p.logger.WithError(err).Error("Error receiving stream chunk")
+			providers.SendStreamError(ctx, chunks, newProviderError(err))
+		}
+		tracing.End(span, err)
+	}()
+
+	return chunks, nil
+}
+
+// EstimateCost estimates the cost for a chat completion request
+func (p *BedrockProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	modelInfo := p.findModel(req.Model)
+	if modelInfo == nil {
+		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
+	}
+
+	inputTokens := estimateTokens(req)
+
+	// Estimate output tokens (use max_tokens or default)
+	outputTokens := 100
+	if req.MaxTokens != nil {
+		outputTokens = *req.MaxTokens
+	}
+
+	totalTokens := inputTokens + outputTokens
+	inputCost := float64(inputTokens) * modelInfo.InputCostPer1K / 1000
+	outputCost := float64(outputTokens) * modelInfo.OutputCostPer1K / 1000
+	totalCost := inputCost + outputCost
+
+	return &types.CostEstimate{
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		TotalTokens:     totalTokens,
+		InputCost:       inputCost,
+		OutputCost:      outputCost,
+		TotalCost:       totalCost,
+		CostPer1KTokens: totalCost / float64(totalTokens) * 1000,
+	}, nil
+}
+
+// HealthCheck sends a one-token request to the cheapest configured model,
+// which verifies credentials, region and model access together
+func (p *BedrockProvider) HealthCheck(ctx context.Context) error {
+	model := p.config.HealthCheckModel
+	if model == "" {
+		model = providers.CheapestModel(p.config.Models)
+	}
+	if model == "" {
+		return fmt.Errorf("bedrock health check failed: no models configured")
+	}
+
+	maxTokens := 1
+	_, err := p.ChatCompletion(ctx, &types.ChatRequest{
+		Model:     model,
+		Messages:  []types.Message{{Role: "user", Content: "test"}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		p.logger.WithError(err).Error("Bedrock health check failed")
+		return fmt.Errorf("bedrock health check failed: %w", err)
+	}
+
+	p.logger.Debug("Bedrock health check passed")
+	return nil
+}
+
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *BedrockProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// Interface implementations for advanced features
+
+// SupportsFunctionCalling implements FunctionCallingProvider
+func (p *BedrockProvider) SupportsFunctionCalling() bool {
+	return true // Claude tool use; Titan requests with tools are rejected
+}
+
+// SupportsParallelFunctions implements FunctionCallingProvider
+func (p *BedrockProvider) SupportsParallelFunctions() bool {
+	return false
+}
+
+// SupportsVision implements VisionProvider
+func (p *BedrockProvider) SupportsVision() bool {
+	return true
+}
+
+// GetSupportedImageFormats implements VisionProvider
+func (p *BedrockProvider) GetSupportedImageFormats() []string {
+	return supportedImageFormats
+}
+
+// Helper functions
+
+// convertRequest resolves the Bedrock model ID for a request and builds its
+// payload in the model family's format
+func (p *BedrockProvider) convertRequest(req *types.ChatRequest) (string, modelFamily, []byte, error) {
+	modelID := req.Model
+	family := familyOf(req.Model)
+	if model := p.findModel(req.Model); model != nil {
+		if model.ProviderModelID != "" {
+			modelID = model.ProviderModelID
+		}
+		// ARNs don't name the family, so fall back to the other identifier
+		if family == familyUnknown {
+			family = familyOf(model.Name)
+		}
+		if family == familyUnknown {
+			family = familyOf(model.ProviderModelID)
+		}
+	}
+
+	var body []byte
+	var err error
+	switch family {
+	case familyClaude:
+		body, err = convertClaudeRequest(req)
+	case familyTitan:
+		body, err = convertTitanRequest(req)
+	default:
+		err = fmt.Errorf("unsupported Bedrock model %s: only Anthropic Claude and Amazon Titan models are supported", req.Model)
+	}
+	return modelID, family, body, err
+}
+
+// newChunkConverter returns a decoder for the stream payloads of a model
+// family
+func newChunkConverter(family modelFamily, model string) func([]byte) (*types.ChatChunk, error) {
+	if family == familyTitan {
+		id := fmt.Sprintf("bedrock-%d", time.Now().UnixNano())
+		return func(payload []byte) (*types.ChatChunk, error) {
+			return convertTitanChunk(payload, id, model)
+		}
+	}
+	return newClaudeStream(model).convert
+}
+
+// findModel returns the configured model matching name, or nil
+func (p *BedrockProvider) findModel(name string) *types.ModelInfo {
+	return p.modelIndex.Lookup(name)
+}
+
+// maxContextWindow returns the largest context window among models
+func maxContextWindow(models []types.ModelInfo) int {
+	largest := 0
+	for _, model := range models {
+		largest = max(largest, model.MaxContextWindow)
+	}
+	return largest
+}
+
+// estimateTokens estimates the prompt tokens in the request
+func estimateTokens(req *types.ChatRequest) int {
+	// Count Claude models with the Claude approximation despite the prefix
+	model := req.Model
+	if familyOf(model) == familyClaude {
+		model = "claude"
+	}
+
+	total := tokenizer.CountTokens(model, req.Messages)
+
+	// Add tool tokens
+	for _, tool := range req.Tools {
+		total += tokenizer.CountText(model, tool.Function.Name) + tokenizer.CountText(model, tool.Function.Description)
+	}
+
+	return total
+}
+
+// apiStatusCode extracts the HTTP status code from an AWS SDK error
+func apiStatusCode(err error) int {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
+
+// newProviderError normalizes an AWS SDK error, taking the error code, such
+// as ThrottlingException, and message from the API error
+func newProviderError(err error) *providers.ProviderError {
+	var code, message string
+	var apiErr interface {
+		ErrorCode() string
+		ErrorMessage() string
+	}
+	if errors.As(err, &apiErr) {
+		code, message = apiErr.ErrorCode(), apiErr.ErrorMessage()
+	}
+	return providers.NewProviderError("bedrock", apiStatusCode(err), code, message, err)
+}
+
+// Ensure BedrockProvider implements all the interfaces
+var _ providers.LLMProvider = (*BedrockProvider)(nil)
+var _ providers.FunctionCallingProvider = (*BedrockProvider)(nil)
+var _ providers.VisionProvider = (*BedrockProvider)(nil)
+var _ providers.HealthCheckScheduler = (*BedrockProvider)(nil)
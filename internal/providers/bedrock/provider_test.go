@@ -0,0 +1,351 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// fakeClient records InvokeModel calls and returns a canned body
+type fakeClient struct {
+	input *bedrockruntime.InvokeModelInput
+	body  string
+	err   error
+}
+
+func (c *fakeClient) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	c.input = params
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &bedrockruntime.InvokeModelOutput{Body: []byte(c.body)}, nil
+}
+
+func (c *fakeClient) InvokeModelWithResponseStream(ctx context.Context, params *bedrockruntime.InvokeModelWithResponseStreamInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelWithResponseStreamOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func createTestProvider(client runtimeClient) *BedrockProvider {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return newBedrockProvider(client, &BedrockConfig{
+		Region: "us-east-1",
+		Models: []types.ModelInfo{
+			{
+				Name:             "anthropic.claude-3-haiku-20240307-v1:0",
+				ProviderModelID:  "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc123",
+				MaxContextWindow: 200000,
+				InputCostPer1K:   0.00025,
+				OutputCostPer1K:  0.00125,
+			},
+			{
+				Name:             "amazon.titan-text-express-v1",
+				MaxContextWindow: 8192,
+				InputCostPer1K:   0.0002,
+				OutputCostPer1K:  0.0006,
+			},
+		},
+	}, logger)
+}
+
+func TestBedrockProvider_Interfaces(t *testing.T) {
+	provider := createTestProvider(&fakeClient{})
+
+	var _ providers.VisionProvider = provider
+	if provider.GetProviderName() != "bedrock" {
+		t.Errorf("Expected provider name 'bedrock', got %s", provider.GetProviderName())
+	}
+
+	caps := provider.GetCapabilities()
+	if !caps.SupportsVision || !caps.SupportsStreaming {
+		t.Error("Bedrock should support vision and streaming")
+	}
+	if caps.MaxContextWindow != 200000 {
+		t.Errorf("Expected max context window 200000, got %d", caps.MaxContextWindow)
+	}
+}
+
+func TestConvertClaudeRequest(t *testing.T) {
+	maxTokens := 256
+	req := &types.ChatRequest{
+		Model:     "anthropic.claude-3-haiku-20240307-v1:0",
+		MaxTokens: &maxTokens,
+		Stop:      []string{"END"},
+		Messages: []types.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: []types.ContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &types.ImageURL{URL: "data:image/jpg;base64,aGVsbG8="}},
+			}},
+			{Role: "assistant", ToolCalls: []types.ToolCall{{
+				ID: "toolu_1", Type: "function",
+				Function: types.Function{Name: "lookup", Arguments: `{"q":"x"}`},
+			}}},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "found"},
+		},
+		Tools: []types.Tool{{
+			Type:     "function",
+			Function: types.Function{Name: "lookup", Description: "Look things up"},
+		}},
+	}
+
+	body, err := convertClaudeRequest(req)
+	if err != nil {
+		t.Fatalf("convertClaudeRequest failed: %v", err)
+	}
+
+	var got claudeRequest
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if got.AnthropicVersion != anthropicVersion {
+		t.Errorf("Expected anthropic_version %s, got %s", anthropicVersion, got.AnthropicVersion)
+	}
+	if got.MaxTokens != 256 || got.System != "Be brief." {
+		t.Errorf("Unexpected max_tokens %d or system %q", got.MaxTokens, got.System)
+	}
+	if len(got.StopSequences) != 1 || got.StopSequences[0] != "END" {
+		t.Errorf("Expected stop sequences [END], got %v", got.StopSequences)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(got.Messages))
+	}
+
+	image := got.Messages[0].Content[1]
+	if image.Type != "image" || image.Source == nil || image.Source.MediaType != "image/jpeg" || image.Source.Data != "aGVsbG8=" {
+		t.Errorf("Unexpected image block: %+v", image)
+	}
+
+	toolUse := got.Messages[1].Content[0]
+	if toolUse.Type != "tool_use" || toolUse.ID != "toolu_1" || string(toolUse.Input) != `{"q":"x"}` {
+		t.Errorf("Unexpected tool_use block: %+v", toolUse)
+	}
+
+	toolResult := got.Messages[2]
+	if toolResult.Role != "user" || toolResult.Content[0].Type != "tool_result" || toolResult.Content[0].ToolUseID != "toolu_1" {
+		t.Errorf("Unexpected tool result message: %+v", toolResult)
+	}
+
+	if len(got.Tools) != 1 || got.Tools[0].Name != "lookup" || got.Tools[0].InputSchema == nil {
+		t.Errorf("Unexpected tools: %+v", got.Tools)
+	}
+}
+
+func TestConvertClaudeRequest_RejectsRemoteImage(t *testing.T) {
+	req := &types.ChatRequest{
+		Model: "anthropic.claude-3-haiku-20240307-v1:0",
+		Messages: []types.Message{{Role: "user", Content: []types.ContentPart{
+			{Type: "image_url", ImageURL: &types.ImageURL{URL: "https://example.com/cat.png"}},
+		}}},
+	}
+
+	if _, err := convertClaudeRequest(req); err == nil {
+		t.Error("Expected an error for a remote image URL")
+	}
+}
+
+func TestConvertClaudeResponse(t *testing.T) {
+	body := `{
+		"id": "msg_1",
+		"type": "message",
+		"model": "claude-3-haiku-20240307",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "x"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 12, "output_tokens": 7}
+	}`
+
+	resp, err := convertClaudeResponse([]byte(body), "anthropic.claude-3-haiku-20240307-v1:0")
+	if err != nil {
+		t.Fatalf("convertClaudeResponse failed: %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Let me check." {
+		t.Errorf("Unexpected content %v", choice.Message.Content)
+	}
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %s", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"q": "x"}` {
+		t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 7 || resp.Usage.TotalTokens != 19 {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestClaudeStream_Convert(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	stream := newClaudeStream("anthropic.claude-3-haiku-20240307-v1:0")
+	var chunks []*types.ChatChunk
+	for _, event := range events {
+		chunk, err := stream.convert([]byte(event))
+		if err != nil {
+			t.Fatalf("convert failed: %v", err)
+		}
+		if chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) != 5 {
+		t.Fatalf("Expected 5 chunks, got %d", len(chunks))
+	}
+	if chunks[0].ID != "msg_1" || chunks[0].Choices[0].Delta.Role != "assistant" {
+		t.Errorf("Unexpected first chunk: %+v", chunks[0])
+	}
+	if chunks[1].Choices[0].Delta.Content != "Hello" {
+		t.Errorf("Expected text delta Hello, got %v", chunks[1].Choices[0].Delta.Content)
+	}
+	if call := chunks[2].Choices[0].Delta.ToolCalls[0]; call.ID != "toolu_1" || *call.Index != 0 {
+		t.Errorf("Unexpected tool call start: %+v", call)
+	}
+	if call := chunks[3].Choices[0].Delta.ToolCalls[0]; call.Function.Arguments != `{"q":` || *call.Index != 0 {
+		t.Errorf("Unexpected tool call arguments: %+v", call)
+	}
+
+	last := chunks[4]
+	if last.Choices[0].FinishReason != "stop" {
+		t.Errorf("Expected finish reason stop, got %s", last.Choices[0].FinishReason)
+	}
+	if last.Usage == nil || last.Usage.PromptTokens != 10 || last.Usage.CompletionTokens != 5 {
+		t.Errorf("Unexpected final usage: %+v", last.Usage)
+	}
+}
+
+func TestConvertTitanRequest(t *testing.T) {
+	maxTokens := 50
+	req := &types.ChatRequest{
+		Model:     "amazon.titan-text-express-v1",
+		MaxTokens: &maxTokens,
+		Messages: []types.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello!"},
+			{Role: "user", Content: "Bye"},
+		},
+	}
+
+	body, err := convertTitanRequest(req)
+	if err != nil {
+		t.Fatalf("convertTitanRequest failed: %v", err)
+	}
+
+	var got titanRequest
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	want := "Be brief.\nUser: Hi\nBot: Hello!\nUser: Bye\nBot:"
+	if got.InputText != want {
+		t.Errorf("Expected prompt %q, got %q", want, got.InputText)
+	}
+	if got.TextGenerationConfig.MaxTokenCount != 50 {
+		t.Errorf("Expected maxTokenCount 50, got %d", got.TextGenerationConfig.MaxTokenCount)
+	}
+
+	req.Tools = []types.Tool{{Type: "function", Function: types.Function{Name: "lookup"}}}
+	if _, err := convertTitanRequest(req); err == nil {
+		t.Error("Expected an error for tools on a Titan model")
+	}
+}
+
+func TestConvertTitanResponseAndChunk(t *testing.T) {
+	body := `{"inputTextTokenCount":8,"results":[{"tokenCount":3,"outputText":" Goodbye!","completionReason":"FINISH"}]}`
+	resp, err := convertTitanResponse([]byte(body), "amazon.titan-text-express-v1")
+	if err != nil {
+		t.Fatalf("convertTitanResponse failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Goodbye!" || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("Unexpected choice: %+v", resp.Choices[0])
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 11 {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+
+	chunk, err := convertTitanChunk([]byte(`{"outputText":"Bye","completionReason":"LENGTH","inputTextTokenCount":8,"totalOutputTextTokenCount":4}`), "id", "amazon.titan-text-express-v1")
+	if err != nil {
+		t.Fatalf("convertTitanChunk failed: %v", err)
+	}
+	if chunk.Choices[0].Delta.Content != "Bye" || chunk.Choices[0].FinishReason != "length" {
+		t.Errorf("Unexpected chunk choice: %+v", chunk.Choices[0])
+	}
+	if chunk.Usage == nil || chunk.Usage.CompletionTokens != 4 {
+		t.Errorf("Unexpected chunk usage: %+v", chunk.Usage)
+	}
+}
+
+func TestBedrockProvider_ChatCompletion_UsesProviderModelID(t *testing.T) {
+	client := &fakeClient{body: `{"id":"msg_1","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`}
+	provider := createTestProvider(client)
+
+	resp, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "anthropic.claude-3-haiku-20240307-v1:0",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	if got := aws.ToString(client.input.ModelId); !strings.HasPrefix(got, "arn:aws:bedrock:") {
+		t.Errorf("Expected the provisioned model ARN, got %s", got)
+	}
+	if !strings.Contains(string(client.input.Body), `"anthropic_version":"bedrock-2023-05-31"`) {
+		t.Errorf("Expected a Claude payload, got %s", client.input.Body)
+	}
+	if resp.Model != "anthropic.claude-3-haiku-20240307-v1:0" || resp.Choices[0].Message.Content != "Hi" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestBedrockProvider_ChatCompletion_UnsupportedModel(t *testing.T) {
+	client := &fakeClient{}
+	provider := createTestProvider(client)
+
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "meta.llama3-70b-instruct-v1:0",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported model family")
+	}
+	if client.input != nil {
+		t.Error("Unsupported models should not reach the API")
+	}
+}
+
+func TestBedrockProvider_EstimateCost(t *testing.T) {
+	provider := createTestProvider(&fakeClient{})
+
+	estimate, err := provider.EstimateCost(&types.ChatRequest{
+		Model:    "amazon.titan-text-express-v1",
+		Messages: []types.Message{{Role: "user", Content: "Hello there"}},
+	})
+	if err != nil {
+		t.Fatalf("EstimateCost failed: %v", err)
+	}
+	if estimate.TotalCost <= 0 || estimate.OutputTokens != 100 {
+		t.Errorf("Unexpected estimate: %+v", estimate)
+	}
+}
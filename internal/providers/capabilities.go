@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// CapabilityOverrides sets capability flags over a provider's built-in
+// capabilities, keyed by their JSON names (e.g. supports_batch), so a feature
+// a provider turns on upstream can be routed to without a rebuild
+type CapabilityOverrides map[string]bool
+
+// capabilityFields maps override keys to the capability flags they set
+var capabilityFields = map[string]func(*types.ProviderCapabilities) *bool{
+	"supports_functions":          func(c *types.ProviderCapabilities) *bool { return &c.SupportsFunctions },
+	"supports_parallel_functions": func(c *types.ProviderCapabilities) *bool { return &c.SupportsParallelFunctions },
+	"supports_vision":             func(c *types.ProviderCapabilities) *bool { return &c.SupportsVision },
+	"supports_structured_output":  func(c *types.ProviderCapabilities) *bool { return &c.SupportsStructuredOutput },
+	"supports_json_mode":          func(c *types.ProviderCapabilities) *bool { return &c.SupportsJSONMode },
+	"supports_streaming":          func(c *types.ProviderCapabilities) *bool { return &c.SupportsStreaming },
+	"supports_assistants":         func(c *types.ProviderCapabilities) *bool { return &c.SupportsAssistants },
+	"supports_batch":              func(c *types.ProviderCapabilities) *bool { return &c.SupportsBatch },
+	"supports_embeddings":         func(c *types.ProviderCapabilities) *bool { return &c.SupportsEmbeddings },
+}
+
+// Validate rejects keys that aren't capability flags, so a typo doesn't
+// silently leave the built-in value in place
+func (o CapabilityOverrides) Validate() error {
+	for key := range o {
+		if _, ok := capabilityFields[key]; !ok {
+			known := make([]string, 0, len(capabilityFields))
+			for name := range capabilityFields {
+				known = append(known, name)
+			}
+			slices.Sort(known)
+			return fmt.Errorf("unknown capability %q, expected one of %s", key, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// Apply returns capabilities with the overrides set. Unknown keys are
+// ignored; Validate reports them.
+func (o CapabilityOverrides) Apply(capabilities types.ProviderCapabilities) types.ProviderCapabilities {
+	for key, value := range o {
+		if field, ok := capabilityFields[key]; ok {
+			*field(&capabilities) = value
+		}
+	}
+	return capabilities
+}
@@ -0,0 +1,318 @@
+package cohere
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// Cohere v2 chat API wire types
+
+type chatRequest struct {
+	Model            string          `json:"model"`
+	Messages         []chatMessage   `json:"messages"`
+	Tools            []types.Tool    `json:"tools,omitempty"` // same shape as OpenAI function tools
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	P                *float32        `json:"p,omitempty"`
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	StopSequences    []string        `json:"stop_sequences,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	ResponseFormat   *responseFormat `json:"response_format,omitempty"`
+	Stream           bool            `json:"stream,omitempty"`
+}
+
+type chatMessage struct {
+	Role       string           `json:"role"` // "system", "user", "assistant" or "tool"
+	Content    interface{}      `json:"content,omitempty"`
+	ToolCalls  []types.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string                 `json:"type"` // "text" or "json_object"
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
+}
+
+type chatResponse struct {
+	ID           string          `json:"id"`
+	FinishReason string          `json:"finish_reason"`
+	Message      responseMessage `json:"message"`
+	Usage        *usage          `json:"usage"`
+}
+
+type responseMessage struct {
+	Role      string           `json:"role"`
+	Content   []contentBlock   `json:"content"`
+	ToolPlan  string           `json:"tool_plan"`
+	ToolCalls []types.ToolCall `json:"tool_calls"`
+}
+
+type usage struct {
+	BilledUnits struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"billed_units"`
+	Tokens struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"tokens"`
+}
+
+// streamEvent is a server-sent event from a streaming chat
+type streamEvent struct {
+	Type  string `json:"type"` // "message-start", "content-delta", "tool-call-start", ...
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Delta struct {
+		Message struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+			ToolCalls *types.ToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+		Usage        *usage `json:"usage"`
+	} `json:"delta"`
+}
+
+// convertToCohereRequest converts our unified request to Cohere's format
+func convertToCohereRequest(req *types.ChatRequest) (*chatRequest, error) {
+	cohereReq := &chatRequest{
+		Model:            req.Model,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		P:                req.TopP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		StopSequences:    req.Stop,
+		Seed:             req.Seed,
+	}
+
+	for _, msg := range req.Messages {
+		converted, err := convertMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		cohereReq.Messages = append(cohereReq.Messages, converted)
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("unsupported tool type for Cohere: %s", tool.Type)
+		}
+		cohereReq.Tools = append(cohereReq.Tools, tool)
+	}
+
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			cohereReq.ResponseFormat = &responseFormat{Type: "json_object"}
+		case "json_schema":
+			format := &responseFormat{Type: "json_object"}
+			if req.ResponseFormat.JSONSchema != nil {
+				format.JSONSchema = req.ResponseFormat.JSONSchema.Schema
+			}
+			cohereReq.ResponseFormat = format
+		}
+	}
+
+	return cohereReq, nil
+}
+
+// convertMessage converts a unified message to Cohere format. Cohere accepts
+// the OpenAI roles, with tool results sent as role "tool".
+func convertMessage(msg types.Message) (chatMessage, error) {
+	converted := chatMessage{
+		Role:       msg.Role,
+		ToolCalls:  msg.ToolCalls,
+		ToolCallID: msg.ToolCallID,
+	}
+
+	switch msg.Role {
+	case "system", "user", "assistant", "tool":
+	case "function":
+		converted.Role = "tool"
+	default:
+		return chatMessage{}, fmt.Errorf("unsupported message role for Cohere: %s", msg.Role)
+	}
+
+	switch content := msg.Content.(type) {
+	case nil:
+	case string:
+		converted.Content = content
+	case []types.ContentPart:
+		blocks, err := textBlocks(content)
+		if err != nil {
+			return chatMessage{}, err
+		}
+		converted.Content = blocks
+	case []interface{}:
+		// Content parts decoded from JSON
+		data, err := json.Marshal(content)
+		if err != nil {
+			return chatMessage{}, err
+		}
+		var parts []types.ContentPart
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return chatMessage{}, fmt.Errorf("invalid message content: %w", err)
+		}
+		blocks, err := textBlocks(parts)
+		if err != nil {
+			return chatMessage{}, err
+		}
+		converted.Content = blocks
+	default:
+		converted.Content = fmt.Sprintf("%v", content)
+	}
+
+	return converted, nil
+}
+
+// textBlocks converts content parts to Cohere text blocks. Cohere chat
+// models don't accept images.
+func textBlocks(parts []types.ContentPart) ([]contentBlock, error) {
+	blocks := make([]contentBlock, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return nil, fmt.Errorf("cohere does not support %s content", part.Type)
+		}
+		blocks = append(blocks, contentBlock{Type: "text", Text: part.Text})
+	}
+	return blocks, nil
+}
+
+// convertFromCohereResponse converts Cohere's response to our format
+func convertFromCohereResponse(resp *chatResponse, req *types.ChatRequest) *types.ChatResponse {
+	var text strings.Builder
+	for _, block := range resp.Message.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	message := types.Message{
+		Role:      "assistant",
+		Content:   text.String(),
+		ToolCalls: resp.Message.ToolCalls,
+	}
+	if len(message.ToolCalls) > 0 && text.Len() == 0 {
+		// The tool plan is Cohere's reasoning ahead of the tool calls
+		message.Content = resp.Message.ToolPlan
+	}
+
+	return &types.ChatResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []types.Choice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: convertFinishReason(resp.FinishReason),
+			},
+		},
+		Usage: convertUsage(resp.Usage),
+	}
+}
+
+// convertFinishReason maps Cohere finish reasons to OpenAI ones
+func convertFinishReason(reason string) string {
+	switch reason {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
+	case "":
+		return ""
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// convertUsage reports billed tokens, which is what Cohere charges for
+func convertUsage(u *usage) *types.Usage {
+	if u == nil {
+		return nil
+	}
+	input, output := u.BilledUnits.InputTokens, u.BilledUnits.OutputTokens
+	if input == 0 && output == 0 {
+		input, output = u.Tokens.InputTokens, u.Tokens.OutputTokens
+	}
+	if input == 0 && output == 0 {
+		return nil
+	}
+	return &types.Usage{
+		PromptTokens:     input,
+		CompletionTokens: output,
+		TotalTokens:      input + output,
+	}
+}
+
+// streamState tracks a streaming chat to build OpenAI-style chunks
+type streamState struct {
+	id      string
+	model   string
+	created int64
+}
+
+// convert turns a stream event into a chunk, or nil for events that carry
+// nothing to forward
+func (s *streamState) convert(event *streamEvent) *types.ChatChunk {
+	var choice types.ChoiceChunk
+	var chunkUsage *types.Usage
+
+	switch event.Type {
+	case "message-start":
+		if event.ID != "" {
+			s.id = event.ID
+		}
+		choice.Delta = &types.Message{Role: "assistant"}
+	case "content-delta":
+		choice.Delta = &types.Message{Content: event.Delta.Message.Content.Text}
+	case "tool-plan-delta":
+		return nil
+	case "tool-call-start":
+		if event.Delta.Message.ToolCalls == nil {
+			return nil
+		}
+		call := *event.Delta.Message.ToolCalls
+		index := event.Index
+		call.Index = &index
+		choice.Delta = &types.Message{ToolCalls: []types.ToolCall{call}}
+	case "tool-call-delta":
+		if event.Delta.Message.ToolCalls == nil {
+			return nil
+		}
+		call := *event.Delta.Message.ToolCalls
+		index := event.Index
+		choice.Delta = &types.Message{ToolCalls: []types.ToolCall{{Index: &index, Function: types.Function{Arguments: call.Function.Arguments}}}}
+	case "message-end":
+		choice.Delta = &types.Message{}
+		choice.FinishReason = convertFinishReason(event.Delta.FinishReason)
+		chunkUsage = convertUsage(event.Delta.Usage)
+	default:
+		return nil
+	}
+
+	return &types.ChatChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []types.ChoiceChunk{choice},
+		Usage:   chunkUsage,
+	}
+}
@@ -0,0 +1,331 @@
+package cohere
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+const (
+	defaultBaseURL = "https://api.cohere.com"
+
+	// defaultRerankModel is used when no rerank model is configured
+	defaultRerankModel = "rerank-v3.5"
+)
+
+// CohereProvider implements the LLMProvider interface for Cohere Command
+// models, plus document reranking
+type CohereProvider struct {
+	client     *http.Client
+	config     *CohereConfig
+	logger     *logrus.Logger
+	modelIndex *providers.ModelIndex
+}
+
+// CohereConfig holds Cohere-specific configuration
+type CohereConfig struct {
+	APIKey      string            `yaml:"api_key"`
+	BaseURL     string            `yaml:"base_url"`
+	Models      []types.ModelInfo `yaml:"models"`
+	RerankModel string            `yaml:"rerank_model"` // defaults to rerank-v3.5
+	Timeout     time.Duration     `yaml:"timeout"`
+
+	providers.CommonConfig `yaml:",inline"`
+}
+
+// modelsProbe lists a single model, which validates the API key without
+// generating tokens
+var modelsProbe = &providers.HealthProbeConfig{Path: "/v1/models?page_size=1"}
+
+// NewCohereProvider creates a new Cohere provider instance
+func NewCohereProvider(config *CohereConfig, logger *logrus.Logger) *CohereProvider {
+	return &CohereProvider{
+		client:     providers.ProviderHTTPClient(config.HTTPClient, 0, false),
+		config:     config,
+		logger:     logger,
+		modelIndex: providers.NewModelIndex(config.Models),
+	}
+}
+
+// GetProviderName returns the provider name
+func (p *CohereProvider) GetProviderName() string {
+	return "cohere"
+}
+
+// GetCapabilities returns the capabilities of the Cohere provider
+func (p *CohereProvider) GetCapabilities() types.ProviderCapabilities {
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
+		ProviderName:              "cohere",
+		SupportedModels:           p.config.Models,
+		SupportsFunctions:         true, // Cohere tools
+		SupportsParallelFunctions: true,
+		SupportsVision:            false,
+		SupportsStructuredOutput:  false,
+		SupportsStreaming:         true,
+		SupportsAssistants:        false,
+		SupportsBatch:             false,
+		SupportsEmbeddings:        false,
+		MaxContextWindow:          128000, // Command R context window
+		CostPer1KTokens: types.CostStructure{
+			InputCostPer1K:  0.0025, // Default Command R+ pricing
+			OutputCostPer1K: 0.01,
+			Currency:        "USD",
+		},
+	})
+}
+
+// ChatCompletion performs a chat completion request
+func (p *CohereProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "cohere.chat_completion",
+		tracing.AttrProvider.String("cohere"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	cohereReq, err := convertToCohereRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Cohere format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	var resp chatResponse
+	if err := p.post(ctx, "/v2/chat", cohereReq, &resp); err != nil {
+		p.logger.WithError(err).Error("Cohere API call failed")
+		tracing.End(span, err)
+		return nil, err
+	}
+	tracing.End(span, nil)
+
+	return convertFromCohereResponse(&resp, req), nil
+}
+
+// StreamCompletion performs a streaming chat completion request
+func (p *CohereProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "cohere.stream_completion",
+		tracing.AttrProvider.String("cohere"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	cohereReq, err := convertToCohereRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Cohere format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+	cohereReq.Stream = true
+
+	body, err := p.do(ctx, "/v2/chat", cohereReq)
+	if err != nil {
+		p.logger.WithError(err).Error("Cohere streaming API call failed")
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	chunks := make(chan *types.ChatChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer body.Close()
+
+		stream := &streamState{id: req.ID, model: req.Model, created: time.Now().Unix()}
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+
+			var event streamEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+				p.logger.WithError(err).Warn("Skipping malformed Cohere stream event")
+				continue
+			}
+
+			chunk := stream.convert(&event)
+			if chunk == nil {
+				continue
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				tracing.End(span, ctx.Err())
+				return
+			}
+		}
+
+		err := scanner.Err()
+		if err != nil {
+			p.logger.WithError(err).Error("Error receiving stream chunk")
+			providers.SendStreamError(ctx, chunks, providers.NewProviderError("cohere", 0, "", "", err))
+		}
+		tracing.End(span, err)
+	}()
+
+	return chunks, nil
+}
+
+// EstimateCost estimates the cost for a chat completion request
+func (p *CohereProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	modelInfo := p.modelIndex.Lookup(req.Model)
+
+	if modelInfo == nil {
+		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
+	}
+
+	inputTokens := estimateTokens(req)
+
+	// Estimate output tokens (use max_tokens or default)
+	outputTokens := 100
+	if req.MaxTokens != nil {
+		outputTokens = *req.MaxTokens
+	}
+
+	totalTokens := inputTokens + outputTokens
+	inputCost := float64(inputTokens) * modelInfo.InputCostPer1K / 1000
+	outputCost := float64(outputTokens) * modelInfo.OutputCostPer1K / 1000
+	totalCost := inputCost + outputCost
+
+	return &types.CostEstimate{
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		TotalTokens:     totalTokens,
+		InputCost:       inputCost,
+		OutputCost:      outputCost,
+		TotalCost:       totalCost,
+		CostPer1KTokens: totalCost / float64(totalTokens) * 1000,
+	}, nil
+}
+
+// HealthCheck performs a health check on the Cohere API
+func (p *CohereProvider) HealthCheck(ctx context.Context) error {
+	headers := map[string]string{"Authorization": "Bearer " + p.config.APIKey}
+
+	// Use the configured custom probe when present
+	if p.config.HealthProbe != nil {
+		if err := p.config.HealthProbe.Run(ctx, p.client, p.baseURL(), headers); err != nil {
+			p.logger.WithError(err).Error("Cohere health probe failed")
+			return fmt.Errorf("cohere health check failed: %w", err)
+		}
+		p.logger.Debug("Cohere health probe passed")
+		return nil
+	}
+
+	if err := modelsProbe.Run(ctx, p.client, p.baseURL(), headers); err != nil {
+		p.logger.WithError(err).Error("Cohere health check failed")
+		return fmt.Errorf("cohere health check failed: %w", err)
+	}
+
+	p.logger.Debug("Cohere health check passed")
+	return nil
+}
+
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *CohereProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// Interface implementations for advanced features
+
+// SupportsFunctionCalling implements FunctionCallingProvider
+func (p *CohereProvider) SupportsFunctionCalling() bool {
+	return true // Cohere tools
+}
+
+// SupportsParallelFunctions implements FunctionCallingProvider
+func (p *CohereProvider) SupportsParallelFunctions() bool {
+	return true
+}
+
+// Helper functions
+
+// baseURL returns the configured API base URL
+func (p *CohereProvider) baseURL() string {
+	if p.config.BaseURL != "" {
+		return strings.TrimRight(p.config.BaseURL, "/")
+	}
+	return defaultBaseURL
+}
+
+// post sends a JSON request and decodes the JSON response into out
+func (p *CohereProvider) post(ctx context.Context, path string, payload, out interface{}) error {
+	body, err := p.do(ctx, path, payload)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return providers.NewProviderError("cohere", 0, "", "", fmt.Errorf("invalid response: %w", err))
+	}
+	return nil
+}
+
+// do sends a JSON request, returning the response body on success. Failed
+// calls are returned as *providers.ProviderError.
+func (p *CohereProvider) do(ctx context.Context, path string, payload interface{}) (io.ReadCloser, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, providers.NewProviderError("cohere", 0, "", "", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		message := strings.TrimSpace(string(raw))
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		return nil, providers.NewProviderError("cohere", resp.StatusCode, "", message, fmt.Errorf("%s", message))
+	}
+
+	return resp.Body, nil
+}
+
+// estimateTokens estimates the prompt tokens in the request
+func estimateTokens(req *types.ChatRequest) int {
+	total := tokenizer.CountTokens(req.Model, req.Messages)
+
+	// Add tool tokens
+	for _, tool := range req.Tools {
+		total += tokenizer.CountText(req.Model, tool.Function.Name) + tokenizer.CountText(req.Model, tool.Function.Description)
+	}
+
+	return total
+}
+
+// Ensure CohereProvider implements all the interfaces
+var _ providers.LLMProvider = (*CohereProvider)(nil)
+var _ providers.FunctionCallingProvider = (*CohereProvider)(nil)
+var _ providers.RerankProvider = (*CohereProvider)(nil)
+var _ providers.HealthCheckScheduler = (*CohereProvider)(nil)
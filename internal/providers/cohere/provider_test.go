@@ -0,0 +1,305 @@
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createTestProvider(t *testing.T, baseURL string) *CohereProvider {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	return NewCohereProvider(&CohereConfig{
+		APIKey:  "test-key",
+		BaseURL: baseURL,
+		Models: []types.ModelInfo{
+			{
+				Name:            "command-r-08-2024",
+				ProviderModelID: "command-r-08-2024",
+				InputCostPer1K:  0.00015,
+				OutputCostPer1K: 0.0006,
+			},
+		},
+	}, logger)
+}
+
+func TestCohereProvider_GetCapabilities(t *testing.T) {
+	caps := createTestProvider(t, "").GetCapabilities()
+
+	if caps.ProviderName != "cohere" {
+		t.Errorf("Expected provider name 'cohere', got %s", caps.ProviderName)
+	}
+	if !caps.SupportsFunctions {
+		t.Error("Cohere should support functions (tools)")
+	}
+	if caps.SupportsVision {
+		t.Error("Cohere should not support vision")
+	}
+	if caps.SupportsAssistants {
+		t.Error("Cohere should not support assistants")
+	}
+}
+
+func TestConvertToCohereRequest(t *testing.T) {
+	maxTokens := 256
+	topP := float32(0.9)
+	req := &types.ChatRequest{
+		Model: "command-r-08-2024",
+		Messages: []types.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Weather in Paris?"},
+			{
+				Role: "assistant",
+				ToolCalls: []types.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: types.Function{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+				}},
+			},
+			{Role: "tool", ToolCallID: "call_1", Content: "18C and sunny"},
+		},
+		Tools: []types.Tool{{
+			Type:     "function",
+			Function: types.Function{Name: "get_weather", Description: "Look up the weather"},
+		}},
+		MaxTokens: &maxTokens,
+		TopP:      &topP,
+		Stop:      []string{"END"},
+	}
+
+	cohereReq, err := convertToCohereRequest(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(cohereReq.Messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(cohereReq.Messages))
+	}
+	if cohereReq.Messages[0].Role != "system" || cohereReq.Messages[0].Content != "Be brief." {
+		t.Errorf("Expected system message to pass through, got %+v", cohereReq.Messages[0])
+	}
+	if len(cohereReq.Messages[2].ToolCalls) != 1 || cohereReq.Messages[2].ToolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("Expected assistant tool call, got %+v", cohereReq.Messages[2])
+	}
+	if cohereReq.Messages[3].Role != "tool" || cohereReq.Messages[3].ToolCallID != "call_1" {
+		t.Errorf("Expected tool result for call_1, got %+v", cohereReq.Messages[3])
+	}
+	if len(cohereReq.Tools) != 1 {
+		t.Errorf("Expected 1 tool, got %d", len(cohereReq.Tools))
+	}
+	if cohereReq.P == nil || *cohereReq.P != topP {
+		t.Error("Expected top_p to map to p")
+	}
+	if len(cohereReq.StopSequences) != 1 || cohereReq.StopSequences[0] != "END" {
+		t.Errorf("Expected stop sequences [END], got %v", cohereReq.StopSequences)
+	}
+
+	// The wire format uses Cohere's field names
+	data, err := json.Marshal(cohereReq)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	var wire map[string]interface{}
+	json.Unmarshal(data, &wire)
+	for _, field := range []string{"p", "max_tokens", "stop_sequences", "tools"} {
+		if _, ok := wire[field]; !ok {
+			t.Errorf("Expected %s in wire request", field)
+		}
+	}
+}
+
+func TestConvertToCohereRequest_RejectsImages(t *testing.T) {
+	req := &types.ChatRequest{
+		Model: "command-r-08-2024",
+		Messages: []types.Message{{
+			Role: "user",
+			Content: []types.ContentPart{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &types.ImageURL{URL: "https://example.com/cat.png"}},
+			},
+		}},
+	}
+
+	if _, err := convertToCohereRequest(req); err == nil {
+		t.Error("Expected an error for image content")
+	}
+}
+
+func TestConvertFromCohereResponse(t *testing.T) {
+	req := &types.ChatRequest{Model: "command-r-08-2024"}
+
+	t.Run("text", func(t *testing.T) {
+		var resp chatResponse
+		body := `{
+			"id": "abc",
+			"finish_reason": "COMPLETE",
+			"message": {"role": "assistant", "content": [{"type": "text", "text": "Hello"}]},
+			"usage": {"billed_units": {"input_tokens": 5, "output_tokens": 2}, "tokens": {"input_tokens": 70, "output_tokens": 2}}
+		}`
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		result := convertFromCohereResponse(&resp, req)
+		if result.ID != "abc" || result.Model != "command-r-08-2024" {
+			t.Errorf("Unexpected response identity: %s %s", result.ID, result.Model)
+		}
+		if result.Choices[0].Message.Content != "Hello" {
+			t.Errorf("Expected content 'Hello', got %v", result.Choices[0].Message.Content)
+		}
+		if result.Choices[0].FinishReason != "stop" {
+			t.Errorf("Expected finish reason 'stop', got %s", result.Choices[0].FinishReason)
+		}
+		if result.Usage == nil || result.Usage.PromptTokens != 5 || result.Usage.CompletionTokens != 2 {
+			t.Errorf("Expected billed usage 5/2, got %+v", result.Usage)
+		}
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		var resp chatResponse
+		body := `{
+			"id": "def",
+			"finish_reason": "TOOL_CALL",
+			"message": {
+				"role": "assistant",
+				"tool_plan": "I will look up the weather.",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+			}
+		}`
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		result := convertFromCohereResponse(&resp, req)
+		choice := result.Choices[0]
+		if choice.FinishReason != "tool_calls" {
+			t.Errorf("Expected finish reason 'tool_calls', got %s", choice.FinishReason)
+		}
+		if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+			t.Errorf("Unexpected tool calls: %+v", choice.Message.ToolCalls)
+		}
+		if choice.Message.Content != "I will look up the weather." {
+			t.Errorf("Expected tool plan as content, got %v", choice.Message.Content)
+		}
+	})
+}
+
+func TestStreamState_Convert(t *testing.T) {
+	state := &streamState{id: "req-1", model: "command-r-08-2024"}
+
+	events := []string{
+		`{"type":"message-start","id":"abc"}`,
+		`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hi"}}}}`,
+		`{"type":"content-end","index":0}`,
+		`{"type":"message-end","delta":{"finish_reason":"MAX_TOKENS","usage":{"billed_units":{"input_tokens":3,"output_tokens":1}}}}`,
+	}
+
+	var chunks []*types.ChatChunk
+	for _, raw := range events {
+		var event streamEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			t.Fatalf("Failed to parse event: %v", err)
+		}
+		if chunk := state.convert(&event); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	if chunks[1].ID != "abc" || chunks[1].Choices[0].Delta.Content != "Hi" {
+		t.Errorf("Unexpected content chunk: %+v", chunks[1])
+	}
+	last := chunks[2]
+	if last.Choices[0].FinishReason != "length" {
+		t.Errorf("Expected finish reason 'length', got %s", last.Choices[0].FinishReason)
+	}
+	if last.Usage == nil || last.Usage.TotalTokens != 4 {
+		t.Errorf("Expected usage on final chunk, got %+v", last.Usage)
+	}
+}
+
+func TestCohereProvider_Rerank(t *testing.T) {
+	var received rerankRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/rerank" {
+			t.Errorf("Expected /v2/rerank, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Expected bearer auth, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"r1","results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.4}]}`))
+	}))
+	defer server.Close()
+
+	provider := createTestProvider(t, server.URL)
+	documents := []string{"Paris is in France", "Bananas are yellow", "The capital of France is Paris"}
+
+	ranked, err := provider.Rerank(context.Background(), "capital of France", documents)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if received.Model != defaultRerankModel {
+		t.Errorf("Expected default rerank model, got %s", received.Model)
+	}
+	if received.Query != "capital of France" || len(received.Documents) != 3 {
+		t.Errorf("Unexpected rerank request: %+v", received)
+	}
+
+	if len(ranked) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(ranked))
+	}
+	if ranked[0].Index != 2 || ranked[0].Document != documents[2] || ranked[0].RelevanceScore != 0.9 {
+		t.Errorf("Unexpected top result: %+v", ranked[0])
+	}
+	if ranked[1].Index != 0 || ranked[1].Document != documents[0] {
+		t.Errorf("Unexpected second result: %+v", ranked[1])
+	}
+}
+
+func TestConvertFromRerankResponse_IndexOutOfRange(t *testing.T) {
+	var resp rerankResponse
+	json.Unmarshal([]byte(`{"results":[{"index":5,"relevance_score":0.5}]}`), &resp)
+
+	if _, err := convertFromRerankResponse(&resp, []string{"only one"}); err == nil {
+		t.Error("Expected an error for an out of range index")
+	}
+}
+
+func TestCohereProvider_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message":"too many requests"}`))
+	}))
+	defer server.Close()
+
+	provider := createTestProvider(t, server.URL)
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "command-r-08-2024",
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected a ProviderError, got %v", err)
+	}
+	if providerErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", providerErr.StatusCode)
+	}
+	if providerErr.Err.Error() != "too many requests" {
+		t.Errorf("Expected the API message, got %q", providerErr.Err.Error())
+	}
+}
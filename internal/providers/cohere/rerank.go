@@ -0,0 +1,85 @@
+package cohere
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+type rerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+type rerankResponse struct {
+	ID      string `json:"id"`
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Rerank implements providers.RerankProvider, returning documents ordered
+// from most to least relevant to the query
+func (p *CohereProvider) Rerank(ctx context.Context, query string, documents []string) ([]types.RankedDoc, error) {
+	model := p.rerankModel()
+	ctx, span := tracing.Start(ctx, "cohere.rerank",
+		tracing.AttrProvider.String("cohere"),
+		tracing.AttrModel.String(model),
+	)
+
+	var resp rerankResponse
+	err := p.post(ctx, "/v2/rerank", convertToRerankRequest(model, query, documents), &resp)
+	if err != nil {
+		p.logger.WithError(err).Error("Cohere rerank call failed")
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	ranked, err := convertFromRerankResponse(&resp, documents)
+	tracing.End(span, err)
+	return ranked, err
+}
+
+// rerankModel returns the configured rerank model
+func (p *CohereProvider) rerankModel() string {
+	if p.config.RerankModel != "" {
+		return p.config.RerankModel
+	}
+	return defaultRerankModel
+}
+
+// convertToRerankRequest builds a Cohere rerank request
+func convertToRerankRequest(model, query string, documents []string) *rerankRequest {
+	return &rerankRequest{
+		Model:     model,
+		Query:     query,
+		Documents: documents,
+	}
+}
+
+// convertFromRerankResponse attaches the original documents to Cohere's
+// scores, most relevant first
+func convertFromRerankResponse(resp *rerankResponse, documents []string) ([]types.RankedDoc, error) {
+	ranked := make([]types.RankedDoc, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		if result.Index < 0 || result.Index >= len(documents) {
+			return nil, providers.NewProviderError("cohere", 0, "", "", fmt.Errorf("rerank result index %d out of range", result.Index))
+		}
+		ranked = append(ranked, types.RankedDoc{
+			Index:          result.Index,
+			RelevanceScore: result.RelevanceScore,
+			Document:       documents[result.Index],
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].RelevanceScore > ranked[j].RelevanceScore
+	})
+	return ranked, nil
+}
@@ -0,0 +1,31 @@
+package providers
+
+import (
+	"net/http"
+	"time"
+)
+
+// CommonConfig holds the settings every provider's configuration shares.
+// Provider configs embed it inline, so its fields sit alongside the
+// provider's own under the provider's YAML key.
+type CommonConfig struct {
+	// How often the provider is health checked, defaults to the router interval
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+
+	// Custom HTTP probe replacing the provider's built-in health check
+	HealthProbe *HealthProbeConfig `yaml:"health_probe"`
+
+	// Model used by a live health check that calls a model, defaults to the
+	// cheapest configured model. Providers whose check lists models ignore it.
+	HealthCheckModel string `yaml:"health_check_model"`
+
+	// Replace a live health check that calls a model with a reachability ping
+	DisableLiveHealthCheck bool `yaml:"disable_live_health_check"`
+
+	// Capability flags set over the built-in ones, e.g. supports_batch
+	Capabilities CapabilityOverrides `yaml:"capabilities"`
+
+	// Shared client whose proxy and TLS settings requests go through, set
+	// from providers.http_client at startup; nil uses the default transport
+	HTTPClient *http.Client `yaml:"-"`
+}
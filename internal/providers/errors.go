@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ProviderError wraps a failed upstream API call with its HTTP status code so
+// the router can decide whether the failure is worth retrying. Errors built
+// by NewProviderError are also normalized, so failures read the same
+// whichever provider they came from.
+type ProviderError struct {
+	Provider   string
+	StatusCode int // 0 when no response was received
+	Err        error
+
+	Type      string // normalized ErrorType*, empty when not normalized
+	Code      string // the provider's own error code or type, e.g. rate_limit_exceeded
+	Message   string // the provider's error message without SDK decoration
+	Retryable bool   // whether the same request may succeed if tried again
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode == 0 && IsTimeout(e.Err) {
+		return fmt.Sprintf("%s api call timed out: %v", e.Provider, e.Err)
+	}
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s api call failed: %v", e.Provider, e.Err)
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("%s api call failed (status %d): %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s api call failed (status %d): %v", e.Provider, e.StatusCode, e.Err)
+}
+
+// Normalized provider error types in ProviderError.Type
+const (
+	ErrorTypeRateLimit      = "rate_limit"      // throttled, retry after backing off
+	ErrorTypeQuotaExceeded  = "quota_exceeded"  // billing quota spent, retrying won't help
+	ErrorTypeOverloaded     = "overloaded"      // provider out of capacity
+	ErrorTypeAuthentication = "authentication"  // the router's credentials were rejected
+	ErrorTypePermission     = "permission"      // credentials lack access to the model or feature
+	ErrorTypeInvalidRequest = "invalid_request" // the request itself was rejected
+	ErrorTypeNotFound       = "not_found"
+	ErrorTypeTimeout        = "timeout"
+	ErrorTypeConnection     = "connection"
+	ErrorTypeServer         = "server_error"
+	ErrorTypeCanceled       = "canceled"
+	ErrorTypeUnknown        = "unknown"
+)
+
+// retryableErrorTypes are the normalized types worth retrying
+var retryableErrorTypes = map[string]bool{
+	ErrorTypeRateLimit:  true,
+	ErrorTypeOverloaded: true,
+	ErrorTypeTimeout:    true,
+	ErrorTypeConnection: true,
+	ErrorTypeServer:     true,
+}
+
+// NewProviderError normalizes a failed API call. status, code and message
+// come from the provider's error body where there was one; code refines the
+// type where the status alone is ambiguous.
+func NewProviderError(provider string, status int, code, message string, err error) *ProviderError {
+	errorType := normalizeErrorType(status, code, err)
+	return &ProviderError{
+		Provider:   provider,
+		StatusCode: status,
+		Err:        err,
+		Type:       errorType,
+		Code:       code,
+		Message:    message,
+		Retryable:  retryableErrorTypes[errorType],
+	}
+}
+
+// normalizeErrorType maps a provider's status and error code to an
+// ErrorType* value
+func normalizeErrorType(status int, code string, err error) string {
+	switch code {
+	case "insufficient_quota": // OpenAI sends it with 429, but it's billing, not throttling
+		return ErrorTypeQuotaExceeded
+	case "overloaded_error": // Anthropic, with status 529
+		return ErrorTypeOverloaded
+	}
+
+	switch {
+	case status == 0:
+		switch ErrorClass(err) {
+		case ErrorClassTimeout:
+			return ErrorTypeTimeout
+		case ErrorClassConnection:
+			return ErrorTypeConnection
+		case ErrorClassCanceled:
+			return ErrorTypeCanceled
+		}
+		return ErrorTypeUnknown
+	case status == 429:
+		return ErrorTypeRateLimit
+	case status == 401:
+		return ErrorTypeAuthentication
+	case status == 403:
+		return ErrorTypePermission
+	case status == 404:
+		return ErrorTypeNotFound
+	case status == 408 || status == 504:
+		return ErrorTypeTimeout
+	case status == 503 || status == 529:
+		return ErrorTypeOverloaded
+	case status >= 500:
+		return ErrorTypeServer
+	case status >= 400:
+		return ErrorTypeInvalidRequest
+	}
+	return ErrorTypeUnknown
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the upstream HTTP status code carried by err, or 0
+func StatusCode(err error) int {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.StatusCode
+	}
+	return 0
+}
+
+// IsAuthenticationError reports whether err is the provider rejecting the
+// router's own credentials, e.g. a revoked API key. Retrying can't fix it.
+func IsAuthenticationError(err error) bool {
+	return StatusCode(err) == 401
+}
+
+// IsTimeout reports whether err is a deadline or network timeout rather than
+// an upstream rejection
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// Error classes ErrorClass returns for failures without an upstream status
+const (
+	ErrorClassTimeout    = "timeout"    // deadline or network timeout
+	ErrorClassConnection = "connection" // the provider couldn't be reached
+	ErrorClassCanceled   = "canceled"   // the caller gave up
+	ErrorClassUnknown    = "unknown"
+)
+
+// ErrorClass buckets a failed provider call: the upstream HTTP status code
+// ("429", "500") when the provider answered, otherwise ErrorClassTimeout,
+// ErrorClassConnection, ErrorClassCanceled or ErrorClassUnknown
+func ErrorClass(err error) string {
+	if status := StatusCode(err); status != 0 {
+		return strconv.Itoa(status)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case IsTimeout(err):
+		return ErrorClassTimeout
+	case isConnectionError(err):
+		return ErrorClassConnection
+	}
+	return ErrorClassUnknown
+}
+
+// isConnectionError reports whether err is a failure to reach the provider
+// or a connection dropped before a response: dial and DNS errors, which the
+// HTTP client reports as net.Error, resets and unexpected EOFs
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// contextLengthMarkers are fragments of the errors providers return when a
+// prompt exceeds the model's context window
+var contextLengthMarkers = []string{
+	"context_length_exceeded", // OpenAI error code
+	"maximum context length",  // OpenAI message
+	"prompt is too long",      // Anthropic
+	"exceed context limit",    // Anthropic, prompt plus max_tokens
+	"context window",
+}
+
+// IsContextLengthError reports whether err is a provider rejecting a prompt
+// too long for the model's context window
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if status := StatusCode(err); status != 0 && status != 400 && status != 413 {
+		return false
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTimeout bounds ctx by a provider's configured timeout; a zero timeout
+// leaves ctx unbounded
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SendStreamError ends a stream that failed part way with a chunk carrying
+// err, so consumers can tell a failure from a complete stream. It gives up
+// if the chunk can't be delivered before ctx is done.
+func SendStreamError(ctx context.Context, chunks chan<- *types.ChatChunk, err error) {
+	chunk := &types.ChatChunk{Err: err}
+	// Prefer delivery when ctx has expired mid-stream and there is room
+	select {
+	case chunks <- chunk:
+		return
+	default:
+	}
+	select {
+	case chunks <- chunk:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// pingTimeout bounds a reachability ping
+const pingTimeout = 10 * time.Second
+
+// CheapestModel returns the ID of the configured model with the lowest
+// combined input and output price, for use in live health checks. Unpriced
+// models are only chosen when no model has pricing. It returns "" when no
+// models are configured.
+func CheapestModel(models []types.ModelInfo) string {
+	var cheapest *types.ModelInfo
+	for i := range models {
+		model := &models[i]
+		if cheapest == nil {
+			cheapest = model
+			continue
+		}
+		cost := model.InputCostPer1K + model.OutputCostPer1K
+		cheapestCost := cheapest.InputCostPer1K + cheapest.OutputCostPer1K
+		if cost > 0 && (cheapestCost == 0 || cost < cheapestCost) {
+			cheapest = model
+		}
+	}
+
+	if cheapest == nil {
+		return ""
+	}
+	if cheapest.ProviderModelID != "" {
+		return cheapest.ProviderModelID
+	}
+	return cheapest.Name
+}
+
+// Ping checks that baseURL is reachable without calling a billed API. Any
+// response short of a server error counts, since unauthenticated requests to
+// a provider's API root typically return 401 or 404.
+func Ping(ctx context.Context, client *http.Client, baseURL string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build ping request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestCheapestModel(t *testing.T) {
+	assert.Empty(t, CheapestModel(nil))
+
+	models := []types.ModelInfo{
+		{Name: "unpriced"},
+		{Name: "premium", InputCostPer1K: 0.01, OutputCostPer1K: 0.03},
+		{Name: "budget", ProviderModelID: "budget-2024", InputCostPer1K: 0.0005, OutputCostPer1K: 0.0015},
+	}
+	assert.Equal(t, "budget-2024", CheapestModel(models))
+
+	// Unpriced models are only used when nothing has pricing
+	assert.Equal(t, "unpriced", CheapestModel(models[:1]))
+}
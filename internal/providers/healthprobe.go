@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultHealthProbeTimeout bounds a probe that doesn't set its own timeout
+const DefaultHealthProbeTimeout = 10 * time.Second
+
+// HealthProbeConfig describes a custom HTTP health probe that overrides a
+// provider's built-in health check (useful behind gateways or proxies)
+type HealthProbeConfig struct {
+	Method         string            `yaml:"method"`          // HTTP method, defaults to GET
+	Path           string            `yaml:"path"`            // Path relative to the provider base URL, or an absolute URL
+	ExpectedStatus []int             `yaml:"expected_status"` // Accepted status codes, defaults to [200]
+	Body           string            `yaml:"body"`            // Optional request payload
+	Headers        map[string]string `yaml:"headers"`         // Extra headers sent with the probe
+	Timeout        time.Duration     `yaml:"timeout"`         // Probe timeout, defaults to 10s
+}
+
+// Validate checks the probe configuration and fills in defaults. It is
+// called once when the configuration is loaded.
+func (c *HealthProbeConfig) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("health probe path is required")
+	}
+
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	c.Method = strings.ToUpper(c.Method)
+
+	switch c.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodOptions:
+	default:
+		return fmt.Errorf("unsupported health probe method: %s", c.Method)
+	}
+
+	if len(c.ExpectedStatus) == 0 {
+		c.ExpectedStatus = []int{http.StatusOK}
+	}
+	for _, status := range c.ExpectedStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("invalid health probe expected status: %d", status)
+		}
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("health probe timeout cannot be negative")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultHealthProbeTimeout
+	}
+
+	return nil
+}
+
+// Run executes the probe against baseURL. Default headers (typically provider
+// authentication) are sent first and may be overridden by the probe's headers.
+// Unset fields take their defaults as they are read, so a probe that was never
+// validated still runs; Run doesn't modify the probe, so concurrent health
+// checks can share it.
+func (c *HealthProbeConfig) Run(ctx context.Context, client *http.Client, baseURL string, defaultHeaders map[string]string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+
+	var body io.Reader
+	if c.Body != "" {
+		body = strings.NewReader(c.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method(), c.url(baseURL), body)
+	if err != nil {
+		return fmt.Errorf("failed to build health probe request: %w", err)
+	}
+
+	for key, value := range defaultHeaders {
+		req.Header.Set(key, value)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+	if c.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health probe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	expected := c.expectedStatus()
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("health probe returned status %d, expected one of %v", resp.StatusCode, expected)
+}
+
+// method returns the probe's HTTP method, defaulting to GET
+func (c *HealthProbeConfig) method() string {
+	if c.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(c.Method)
+}
+
+// expectedStatus returns the accepted status codes, defaulting to 200
+func (c *HealthProbeConfig) expectedStatus() []int {
+	if len(c.ExpectedStatus) == 0 {
+		return []int{http.StatusOK}
+	}
+	return c.ExpectedStatus
+}
+
+// timeout returns the probe timeout, defaulting to DefaultHealthProbeTimeout
+func (c *HealthProbeConfig) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultHealthProbeTimeout
+	}
+	return c.Timeout
+}
+
+// url resolves the probe path against the provider base URL
+func (c *HealthProbeConfig) url(baseURL string) string {
+	if strings.HasPrefix(c.Path, "http://") || strings.HasPrefix(c.Path, "https://") {
+		return c.Path
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(c.Path, "/")
+}
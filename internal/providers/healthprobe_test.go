@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthProbeConfig_Validate(t *testing.T) {
+	probe := &HealthProbeConfig{Path: "/status"}
+	require.NoError(t, probe.Validate())
+	assert.Equal(t, http.MethodGet, probe.Method)
+	assert.Equal(t, []int{http.StatusOK}, probe.ExpectedStatus)
+	assert.NotZero(t, probe.Timeout)
+
+	assert.Error(t, (&HealthProbeConfig{}).Validate(), "path is required")
+	assert.Error(t, (&HealthProbeConfig{Path: "/status", Method: "DELETE"}).Validate())
+	assert.Error(t, (&HealthProbeConfig{Path: "/status", ExpectedStatus: []int{42}}).Validate())
+}
+
+func TestHealthProbeConfig_Run(t *testing.T) {
+	var gotMethod, gotBody, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		if r.URL.Path == "/gateway/ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Authorization": "Bearer test-key"}
+
+	t.Run("passes on configured status", func(t *testing.T) {
+		probe := &HealthProbeConfig{
+			Method:         "post",
+			Path:           "/gateway/ping",
+			ExpectedStatus: []int{http.StatusNoContent},
+			Body:           `{"ping":true}`,
+		}
+		require.NoError(t, probe.Validate())
+
+		err := probe.Run(context.Background(), server.Client(), server.URL, headers)
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPost, gotMethod)
+		assert.Equal(t, `{"ping":true}`, gotBody)
+		assert.Equal(t, "Bearer test-key", gotAuth)
+	})
+
+	t.Run("fails on unexpected status", func(t *testing.T) {
+		probe := &HealthProbeConfig{
+			Path:           "/gateway/ping",
+			ExpectedStatus: []int{http.StatusOK},
+		}
+		require.NoError(t, probe.Validate())
+
+		err := probe.Run(context.Background(), server.Client(), server.URL, headers)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 204")
+	})
+
+	t.Run("unvalidated probe uses defaults", func(t *testing.T) {
+		probe := &HealthProbeConfig{Path: "/gateway/ping", ExpectedStatus: []int{http.StatusNoContent}}
+
+		err := probe.Run(context.Background(), server.Client(), server.URL, headers)
+		require.NoError(t, err, "a zero timeout must not expire the probe immediately")
+		assert.Equal(t, http.MethodGet, gotMethod)
+		assert.Equal(t, &HealthProbeConfig{Path: "/gateway/ping", ExpectedStatus: []int{http.StatusNoContent}}, probe)
+
+		err = (&HealthProbeConfig{Path: server.URL + "/other"}).Run(context.Background(), server.Client(), server.URL, headers)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "expected one of [200]")
+	})
+
+	t.Run("fails when endpoint is unhealthy", func(t *testing.T) {
+		probe := &HealthProbeConfig{Path: server.URL + "/other"}
+		require.NoError(t, probe.Validate())
+
+		err := probe.Run(context.Background(), server.Client(), "http://unused.invalid", headers)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 503")
+	})
+}
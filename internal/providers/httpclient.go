@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+)
+
+// defaultMaxIdleConns matches http.DefaultTransport
+const defaultMaxIdleConns = 100
+
+// HTTPClientConfig configures the HTTP client every provider calls its API
+// with, for networks where egress has to go through a proxy that presents
+// its own CA
+type HTTPClientConfig struct {
+	ProxyURL           string        `yaml:"proxy_url"`            // empty uses HTTP_PROXY/HTTPS_PROXY from the environment
+	CABundlePath       string        `yaml:"ca_bundle_path"`       // PEM CAs trusted in addition to the system pool
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify"` // disables TLS verification, for testing only
+	MaxIdleConns       int           `yaml:"max_idle_conns"`       // across all providers, defaults to 100
+	Timeout            time.Duration `yaml:"timeout"`              // per request for providers without their own timeout
+}
+
+// Validate checks the proxy URL and loads the CA bundle, so a bad bundle
+// fails startup rather than every request
+func (c *HTTPClientConfig) Validate() error {
+	if _, err := c.proxyURL(); err != nil {
+		return err
+	}
+	if _, err := c.rootCAs(); err != nil {
+		return err
+	}
+	if c.MaxIdleConns < 0 {
+		return fmt.Errorf("http_client.max_idle_conns must not be negative")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("http_client.timeout must not be negative")
+	}
+	return nil
+}
+
+// proxyURL parses ProxyURL, returning nil when it is unset
+func (c *HTTPClientConfig) proxyURL() (*url.URL, error) {
+	if c.ProxyURL == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(c.ProxyURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("http_client.proxy_url %q is not a valid URL", c.ProxyURL)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+		return parsed, nil
+	}
+	return nil, fmt.Errorf("http_client.proxy_url must use http, https or socks5, got %q", parsed.Scheme)
+}
+
+// rootCAs returns the system pool plus the CA bundle, or nil for the system
+// pool alone when no bundle is configured
+func (c *HTTPClientConfig) rootCAs() (*x509.CertPool, error) {
+	if c.CABundlePath == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(c.CABundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read http_client.ca_bundle_path: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("http_client.ca_bundle_path %s contains no PEM certificates", c.CABundlePath)
+	}
+	return pool, nil
+}
+
+// NewHTTPClient builds the client shared by all providers. A nil config
+// gives a client on a copy of http.DefaultTransport.
+func NewHTTPClient(config *HTTPClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config == nil {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	proxy, err := config.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	rootCAs, err := config.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	if rootCAs != nil || config.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:            rootCAs,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		}
+	}
+
+	transport.MaxIdleConns = defaultMaxIdleConns
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+
+	return &http.Client{Transport: transport, Timeout: config.Timeout}, nil
+}
+
+// ProviderHTTPClient returns the client a provider calls its API with. It
+// sends requests through shared's transport, so proxy, TLS and connection
+// pool settings apply to every provider, with tracing and, when
+// captureHeaders is set, upstream header capture. A positive timeout
+// overrides shared's. A nil shared client uses http.DefaultTransport.
+func ProviderHTTPClient(shared *http.Client, timeout time.Duration, captureHeaders bool) *http.Client {
+	var base http.RoundTripper
+	if shared != nil {
+		base = shared.Transport
+		if timeout <= 0 {
+			timeout = shared.Timeout
+		}
+	}
+
+	transport := tracing.Transport(base)
+	if captureHeaders {
+		transport = CaptureTransport(transport)
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
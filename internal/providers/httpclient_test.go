@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	// A forward proxy sees the absolute URL of every request it relays
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+		io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	config := &HTTPClientConfig{ProxyURL: proxy.URL, MaxIdleConns: 10, Timeout: 5 * time.Second}
+	require.NoError(t, config.Validate())
+	client, err := NewHTTPClient(config)
+	require.NoError(t, err)
+
+	transport := client.Transport.(*http.Transport)
+	req, _ := http.NewRequest(http.MethodGet, "http://api.example.invalid/v1/models", nil)
+	proxyURL, err := transport.Proxy(req)
+	require.NoError(t, err)
+	assert.Equal(t, proxy.URL, proxyURL.String())
+	assert.Equal(t, 10, transport.MaxIdleConns)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+
+	// Provider clients built on it go through the proxy too
+	resp, err := ProviderHTTPClient(client, 0, true).Get("http://api.example.invalid/v1/models")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "via proxy", string(body))
+	assert.Equal(t, "http://api.example.invalid/v1/models", <-proxied)
+}
+
+func TestNewHTTPClient_CABundle(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	// The test server's self-signed certificate stands in for a corporate CA
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, ca, 0600))
+
+	config := &HTTPClientConfig{CABundlePath: path}
+	require.NoError(t, config.Validate())
+	client, err := NewHTTPClient(config)
+	require.NoError(t, err)
+
+	resp, err := client.Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Without the bundle the certificate isn't trusted
+	client, err = NewHTTPClient(&HTTPClientConfig{})
+	require.NoError(t, err)
+	_, err = client.Get(upstream.URL)
+	assert.Error(t, err)
+}
+
+func TestHTTPClientConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+
+	assert.NoError(t, (&HTTPClientConfig{}).Validate())
+	assert.Error(t, (&HTTPClientConfig{ProxyURL: "proxy.internal:3128"}).Validate(), "proxy URL needs a scheme")
+	assert.Error(t, (&HTTPClientConfig{ProxyURL: "ftp://proxy.internal"}).Validate())
+	assert.Error(t, (&HTTPClientConfig{CABundlePath: filepath.Join(dir, "missing.pem")}).Validate())
+	assert.Error(t, (&HTTPClientConfig{CABundlePath: notPEM}).Validate())
+	assert.Error(t, (&HTTPClientConfig{MaxIdleConns: -1}).Validate())
+}
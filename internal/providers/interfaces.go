@@ -2,6 +2,8 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
@@ -11,6 +13,8 @@ type LLMProvider interface {
 	GetCapabilities() types.ProviderCapabilities
 	GetProviderName() string
 	ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error)
+	// StreamCompletion returns an error if the stream can't be started. A
+	// stream that fails once started ends with a chunk whose Err is set.
 	StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error)
 	EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error)
 	HealthCheck(ctx context.Context) error
@@ -39,10 +43,36 @@ type BatchProvider interface {
 	LLMProvider
 	SupportsBatch() bool
 	CreateBatch(ctx context.Context, req *types.BatchRequest) (*types.BatchResponse, error)
+	GetBatch(ctx context.Context, id string) (*types.BatchResponse, error)
+	CancelBatch(ctx context.Context, id string) (*types.BatchResponse, error)
+	ListBatches(ctx context.Context) (*types.BatchListResponse, error)
 }
 
 type AssistantProvider interface {
 	LLMProvider
 	SupportsAssistants() bool
 	CreateAssistant(ctx context.Context, req *types.AssistantRequest) (*types.AssistantResponse, error)
-}
\ No newline at end of file
+}
+
+// ErrEmbeddingsNotSupported is returned by providers without an embeddings API
+var ErrEmbeddingsNotSupported = errors.New("embeddings are not supported by this provider")
+
+type EmbeddingProvider interface {
+	LLMProvider
+	SupportsEmbeddings() bool
+	CreateEmbedding(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error)
+	EstimateEmbeddingCost(req *types.EmbeddingRequest) (*types.CostEstimate, error)
+}
+
+// RerankProvider is implemented by providers that can rank documents by
+// relevance to a query. Results are ordered most relevant first.
+type RerankProvider interface {
+	LLMProvider
+	Rerank(ctx context.Context, query string, documents []string) ([]types.RankedDoc, error)
+}
+
+// HealthCheckScheduler is implemented by providers that set their own health
+// check interval. A zero interval uses the router default.
+type HealthCheckScheduler interface {
+	HealthCheckInterval() time.Duration
+}
@@ -0,0 +1,297 @@
+package mistral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	openaiprovider "github.com/tributary-ai/llm-router-waf/internal/providers/openai"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// DefaultBaseURL is Mistral's OpenAI-compatible API
+const DefaultBaseURL = "https://api.mistral.ai/v1"
+
+// MistralProvider implements the LLMProvider interface for Mistral and
+// Mixtral models served through Mistral's OpenAI-compatible API
+type MistralProvider struct {
+	client     *openai.Client
+	httpClient *http.Client // health probes
+	config     *MistralConfig
+	logger     *logrus.Logger
+	modelIndex *providers.ModelIndex
+}
+
+// MistralConfig holds Mistral-specific configuration
+type MistralConfig struct {
+	APIKey  string            `yaml:"api_key"`
+	BaseURL string            `yaml:"base_url"` // defaults to https://api.mistral.ai/v1
+	Models  []types.ModelInfo `yaml:"models"`
+	Timeout time.Duration     `yaml:"timeout"`
+
+	providers.CommonConfig `yaml:",inline"`
+}
+
+// NewMistralProvider creates a new Mistral provider instance
+func NewMistralProvider(config *MistralConfig, logger *logrus.Logger) *MistralProvider {
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	clientConfig.BaseURL = config.baseURL()
+	httpClient := providers.ProviderHTTPClient(config.HTTPClient, config.Timeout, false)
+	clientConfig.HTTPClient = httpClient
+
+	return &MistralProvider{
+		client:     openai.NewClientWithConfig(clientConfig),
+		httpClient: httpClient,
+		config:     config,
+		logger:     logger,
+		modelIndex: providers.NewModelIndex(config.Models),
+	}
+}
+
+// GetProviderName returns the provider name
+func (p *MistralProvider) GetProviderName() string {
+	return "mistral"
+}
+
+// GetCapabilities returns the capabilities of the Mistral provider
+func (p *MistralProvider) GetCapabilities() types.ProviderCapabilities {
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
+		ProviderName:              "mistral",
+		SupportedModels:           p.config.Models,
+		SupportsFunctions:         true,
+		SupportsParallelFunctions: true,
+		SupportsVision:            false,
+		SupportsStructuredOutput:  false,
+		SupportsStreaming:         true,
+		SupportsAssistants:        false,
+		SupportsBatch:             false,
+		SupportsEmbeddings:        false,
+		MaxContextWindow:          maxContextWindow(p.config.Models),
+		CostPer1KTokens: types.CostStructure{
+			InputCostPer1K:  0.002, // Default Mistral Large pricing
+			OutputCostPer1K: 0.006,
+			Currency:        "USD",
+		},
+	})
+}
+
+// ChatCompletion performs a chat completion request
+func (p *MistralProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "mistral.chat_completion",
+		tracing.AttrProvider.String("mistral"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	mistralReq, err := p.convertRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Mistral format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, *mistralReq)
+	if err != nil {
+		p.logger.WithError(err).Error("Mistral API call failed")
+		tracing.End(span, err)
+		return nil, openaiprovider.NewProviderError("mistral", err)
+	}
+	tracing.End(span, nil)
+
+	return openaiprovider.ConvertResponse(&resp), nil
+}
+
+// StreamCompletion performs a streaming chat completion request
+func (p *MistralProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "mistral.stream_completion",
+		tracing.AttrProvider.String("mistral"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+
+	mistralReq, err := p.convertRequest(req)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Mistral format")
+		tracing.End(span, err)
+		cancel()
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	// Mistral rejects stream_options but reports usage on the final chunk
+	mistralReq.Stream = true
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, *mistralReq)
+	if err != nil {
+		p.logger.WithError(err).Error("Mistral streaming API call failed")
+		tracing.End(span, err)
+		cancel()
+		return nil, openaiprovider.NewProviderError("mistral", err)
+	}
+
+	chunks := make(chan *types.ChatChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer cancel()
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				} else {
+					p.logger.WithError(err).Error("Error receiving stream chunk")
+					providers.SendStreamError(ctx, chunks, openaiprovider.NewProviderError("mistral", err))
+				}
+				tracing.End(span, err)
+				return
+			}
+
+			select {
+			case chunks <- openaiprovider.ConvertChunk(&response):
+			case <-ctx.Done():
+				tracing.End(span, ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// EstimateCost estimates the cost for a chat completion request
+func (p *MistralProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	modelInfo := p.findModel(req.Model)
+	if modelInfo == nil {
+		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
+	}
+
+	inputTokens := estimateTokens(req)
+
+	// Estimate output tokens (use max_tokens or default)
+	outputTokens := 100
+	if req.MaxTokens != nil {
+		outputTokens = *req.MaxTokens
+	}
+
+	totalTokens := inputTokens + outputTokens
+	inputCost := float64(inputTokens) * modelInfo.InputCostPer1K / 1000
+	outputCost := float64(outputTokens) * modelInfo.OutputCostPer1K / 1000
+	totalCost := inputCost + outputCost
+
+	return &types.CostEstimate{
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		TotalTokens:     totalTokens,
+		InputCost:       inputCost,
+		OutputCost:      outputCost,
+		TotalCost:       totalCost,
+		CostPer1KTokens: totalCost / float64(totalTokens) * 1000,
+	}, nil
+}
+
+// HealthCheck performs a health check on the Mistral API
+func (p *MistralProvider) HealthCheck(ctx context.Context) error {
+	// Use the configured custom probe when present
+	if p.config.HealthProbe != nil {
+		headers := map[string]string{"Authorization": "Bearer " + p.config.APIKey}
+		if err := p.config.HealthProbe.Run(ctx, p.httpClient, p.config.baseURL(), headers); err != nil {
+			p.logger.WithError(err).Error("Mistral health probe failed")
+			return fmt.Errorf("mistral health check failed: %w", err)
+		}
+		p.logger.Debug("Mistral health probe passed")
+		return nil
+	}
+
+	// Listing models validates the API key without generating tokens
+	if _, err := p.client.ListModels(ctx); err != nil {
+		p.logger.WithError(err).Error("Mistral health check failed")
+		return fmt.Errorf("mistral health check failed: %w", err)
+	}
+
+	p.logger.Debug("Mistral health check passed")
+	return nil
+}
+
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *MistralProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// Interface implementations for advanced features
+
+// SupportsFunctionCalling implements FunctionCallingProvider
+func (p *MistralProvider) SupportsFunctionCalling() bool {
+	return true
+}
+
+// SupportsParallelFunctions implements FunctionCallingProvider
+func (p *MistralProvider) SupportsParallelFunctions() bool {
+	return true
+}
+
+// Helper functions
+
+// baseURL returns the configured API base URL
+func (c *MistralConfig) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return DefaultBaseURL
+}
+
+// convertRequest converts a unified request to Mistral's OpenAI-compatible
+// format, sending the configured provider model ID
+func (p *MistralProvider) convertRequest(req *types.ChatRequest) (*openai.ChatCompletionRequest, error) {
+	mistralReq, err := openaiprovider.ConvertRequest(req, p.logger)
+	if err != nil {
+		return nil, err
+	}
+	if model := p.findModel(req.Model); model != nil && model.ProviderModelID != "" {
+		mistralReq.Model = model.ProviderModelID
+	}
+	return mistralReq, nil
+}
+
+// findModel returns the configured model matching name, or nil
+func (p *MistralProvider) findModel(name string) *types.ModelInfo {
+	return p.modelIndex.Lookup(name)
+}
+
+// maxContextWindow returns the largest context window among models
+func maxContextWindow(models []types.ModelInfo) int {
+	largest := 0
+	for _, model := range models {
+		largest = max(largest, model.MaxContextWindow)
+	}
+	return largest
+}
+
+// estimateTokens estimates the prompt tokens in the request
+func estimateTokens(req *types.ChatRequest) int {
+	total := tokenizer.CountTokens(req.Model, req.Messages)
+
+	// Add tool tokens
+	for _, tool := range req.Tools {
+		total += tokenizer.CountText(req.Model, tool.Function.Name) + tokenizer.CountText(req.Model, tool.Function.Description)
+	}
+
+	return total
+}
+
+// Ensure MistralProvider implements all the interfaces
+var _ providers.LLMProvider = (*MistralProvider)(nil)
+var _ providers.FunctionCallingProvider = (*MistralProvider)(nil)
+var _ providers.HealthCheckScheduler = (*MistralProvider)(nil)
@@ -0,0 +1,106 @@
+package mistral
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createTestProvider(t *testing.T, baseURL string) *MistralProvider {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	return NewMistralProvider(&MistralConfig{
+		APIKey:  "test-api-key",
+		BaseURL: baseURL,
+		Models: []types.ModelInfo{
+			{Name: "mistral-large", ProviderModelID: "mistral-large-latest", InputCostPer1K: 0.002, OutputCostPer1K: 0.006, MaxContextWindow: 128000},
+			{Name: "open-mixtral-8x7b", ProviderModelID: "open-mixtral-8x7b", InputCostPer1K: 0.0007, OutputCostPer1K: 0.0007, MaxContextWindow: 32000},
+		},
+	}, logger)
+}
+
+func TestMistralProvider_GetCapabilities(t *testing.T) {
+	caps := createTestProvider(t, "").GetCapabilities()
+
+	if caps.ProviderName != "mistral" {
+		t.Errorf("Expected provider name 'mistral', got %s", caps.ProviderName)
+	}
+	if !caps.SupportsFunctions {
+		t.Error("Mistral should support function calling")
+	}
+	if caps.SupportsVision || caps.SupportsAssistants {
+		t.Error("Mistral should not advertise vision or assistants")
+	}
+	if caps.MaxContextWindow != 128000 {
+		t.Errorf("Expected context window from models, got %d", caps.MaxContextWindow)
+	}
+}
+
+func TestMistralProvider_EstimateCost(t *testing.T) {
+	provider := createTestProvider(t, "")
+	maxTokens := 1000
+
+	estimate, err := provider.EstimateCost(&types.ChatRequest{
+		Model:     "mistral-large",
+		Messages:  []types.Message{{Role: "user", Content: "Hello there"}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if estimate.OutputCost != 0.006 {
+		t.Errorf("Expected output cost 0.006, got %f", estimate.OutputCost)
+	}
+
+	if _, err := provider.EstimateCost(&types.ChatRequest{Model: "gpt-4o"}); err == nil {
+		t.Error("Expected an error for an unconfigured model")
+	}
+}
+
+func TestMistralProvider_ChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-api-key" {
+			t.Errorf("Expected bearer API key, got %q", got)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "mistral-large-latest" {
+			t.Errorf("Expected provider model ID mistral-large-latest, got %v", body["model"])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "cmpl-1",
+			"object": "chat.completion",
+			"model": "mistral-large-latest",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Bonjour!"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := createTestProvider(t, server.URL+"/v1")
+	resp, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "mistral-large",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Bonjour!" {
+		t.Errorf("Expected content 'Bonjour!', got %v", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 5 {
+		t.Errorf("Expected usage to be converted, got %+v", resp.Usage)
+	}
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"sync"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ModelIndex finds a provider's models by Name or ProviderModelID in
+// constant time. A name resolves to the same model a scan of the list for
+// the first Name or ProviderModelID match would find. Providers whose model
+// list changes after construction, e.g. through discovery, call Set so the
+// index never goes stale.
+type ModelIndex struct {
+	mu     sync.RWMutex
+	models map[string]*types.ModelInfo
+}
+
+// NewModelIndex indexes models
+func NewModelIndex(models []types.ModelInfo) *ModelIndex {
+	index := &ModelIndex{}
+	index.Set(models)
+	return index
+}
+
+// Set replaces the indexed models with a copy of models
+func (i *ModelIndex) Set(models []types.ModelInfo) {
+	owned := append([]types.ModelInfo(nil), models...)
+	byName := make(map[string]*types.ModelInfo, 2*len(owned))
+	for j := range owned {
+		model := &owned[j]
+		for _, key := range []string{model.Name, model.ProviderModelID} {
+			if _, exists := byName[key]; !exists {
+				byName[key] = model
+			}
+		}
+	}
+
+	i.mu.Lock()
+	i.models = byName
+	i.mu.Unlock()
+}
+
+// Lookup returns the model named name, by Name or ProviderModelID, or nil.
+// The model is shared and must not be modified.
+func (i *ModelIndex) Lookup(name string) *types.ModelInfo {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.models[name]
+}
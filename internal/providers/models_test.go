@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestModelIndex_Lookup(t *testing.T) {
+	index := NewModelIndex([]types.ModelInfo{
+		{Name: "claude-3-haiku", ProviderModelID: "anthropic.claude-3-haiku-20240307-v1:0", InputCostPer1K: 0.00025},
+		{Name: "gpt-4o", ProviderModelID: "gpt-4o-2024-08-06", InputCostPer1K: 0.005},
+		{Name: "gpt-4o-2024-08-06", InputCostPer1K: 0.004}, // shadowed by gpt-4o's provider model ID
+	})
+
+	// Both the name and the provider model ID resolve
+	byName := index.Lookup("claude-3-haiku")
+	require.NotNil(t, byName)
+	byID := index.Lookup("anthropic.claude-3-haiku-20240307-v1:0")
+	assert.Same(t, byName, byID)
+
+	// The first matching model wins, as with a scan of the list
+	model := index.Lookup("gpt-4o-2024-08-06")
+	require.NotNil(t, model)
+	assert.Equal(t, "gpt-4o", model.Name)
+
+	assert.Nil(t, index.Lookup("unknown"))
+}
+
+func TestModelIndex_Set(t *testing.T) {
+	models := []types.ModelInfo{{Name: "gpt-4o", InputCostPer1K: 0.005}}
+	index := NewModelIndex(models)
+
+	// The index keeps its own copy of the models
+	models[0].InputCostPer1K = 1
+	assert.Equal(t, 0.005, index.Lookup("gpt-4o").InputCostPer1K)
+
+	index.Set([]types.ModelInfo{{Name: "llama-3-70b"}})
+	assert.Nil(t, index.Lookup("gpt-4o"))
+	assert.NotNil(t, index.Lookup("llama-3-70b"))
+}
+
+// BenchmarkModelLookup compares the index with the linear scan it replaced,
+// looking up the last of a large model list
+func BenchmarkModelLookup(b *testing.B) {
+	models := make([]types.ModelInfo, 500)
+	for i := range models {
+		models[i] = types.ModelInfo{Name: fmt.Sprintf("model-%d", i), ProviderModelID: fmt.Sprintf("provider-model-%d", i)}
+	}
+	name := models[len(models)-1].ProviderModelID
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range models {
+				if models[j].Name == name || models[j].ProviderModelID == name {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("index", func(b *testing.B) {
+		index := NewModelIndex(models)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			index.Lookup(name)
+		}
+	})
+}
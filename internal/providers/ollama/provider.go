@@ -0,0 +1,273 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	openaiprovider "github.com/tributary-ai/llm-router-waf/internal/providers/openai"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+const (
+	// DefaultBaseURL is Ollama's OpenAI-compatible endpoint on the local host
+	DefaultBaseURL = "http://localhost:11434/v1"
+
+	// placeholderAPIKey is sent because the client requires a key; Ollama
+	// ignores it
+	placeholderAPIKey = "ollama"
+)
+
+// OllamaProvider implements the LLMProvider interface for self-hosted models
+// served through Ollama's OpenAI-compatible API. Local inference is free, so
+// it always wins cost-optimized routing for the models it serves.
+type OllamaProvider struct {
+	client     *openai.Client
+	httpClient *http.Client // health probes
+	config     *OllamaConfig
+	logger     *logrus.Logger
+	modelIndex *providers.ModelIndex
+}
+
+// OllamaConfig holds Ollama-specific configuration
+type OllamaConfig struct {
+	BaseURL string            `yaml:"base_url"` // defaults to http://localhost:11434/v1
+	Models  []types.ModelInfo `yaml:"models"`
+	Timeout time.Duration     `yaml:"timeout"`
+
+	providers.CommonConfig `yaml:",inline"`
+}
+
+// NewOllamaProvider creates a new Ollama provider instance
+func NewOllamaProvider(config *OllamaConfig, logger *logrus.Logger) *OllamaProvider {
+	clientConfig := openai.DefaultConfig(placeholderAPIKey)
+	clientConfig.BaseURL = config.baseURL()
+	httpClient := providers.ProviderHTTPClient(config.HTTPClient, 0, false)
+	clientConfig.HTTPClient = httpClient
+
+	return &OllamaProvider{
+		client:     openai.NewClientWithConfig(clientConfig),
+		httpClient: httpClient,
+		config:     config,
+		logger:     logger,
+		modelIndex: providers.NewModelIndex(config.Models),
+	}
+}
+
+// GetProviderName returns the provider name
+func (p *OllamaProvider) GetProviderName() string {
+	return "ollama"
+}
+
+// GetCapabilities returns the capabilities of the Ollama provider
+func (p *OllamaProvider) GetCapabilities() types.ProviderCapabilities {
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
+		ProviderName:              "ollama",
+		SupportedModels:           p.config.Models,
+		SupportsFunctions:         true, // Model dependent, e.g. Llama 3.1+
+		SupportsParallelFunctions: false,
+		SupportsVision:            false,
+		SupportsStructuredOutput:  false,
+		SupportsStreaming:         true,
+		SupportsAssistants:        false,
+		SupportsBatch:             false,
+		SupportsEmbeddings:        false,
+		MaxContextWindow:          maxContextWindow(p.config.Models),
+		CostPer1KTokens: types.CostStructure{
+			Currency: "USD",
+		},
+	})
+}
+
+// ChatCompletion performs a chat completion request
+func (p *OllamaProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "ollama.chat_completion",
+		tracing.AttrProvider.String("ollama"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	ollamaReq, err := openaiprovider.ConvertRequest(req, p.logger)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Ollama format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, *ollamaReq)
+	if err != nil {
+		p.logger.WithError(err).Error("Ollama API call failed")
+		tracing.End(span, err)
+		return nil, openaiprovider.NewProviderError("ollama", err)
+	}
+	tracing.End(span, nil)
+
+	return openaiprovider.ConvertResponse(&resp), nil
+}
+
+// StreamCompletion performs a streaming chat completion request
+func (p *OllamaProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "ollama.stream_completion",
+		tracing.AttrProvider.String("ollama"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	ollamaReq, err := openaiprovider.ConvertRequest(req, p.logger)
+	if err != nil {
+		p.logger.WithError(err).Error("Failed to convert request to Ollama format")
+		tracing.End(span, err)
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	// Enable streaming, with a final usage chunk for accounting
+	ollamaReq.Stream = true
+	ollamaReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, *ollamaReq)
+	if err != nil {
+		p.logger.WithError(err).Error("Ollama streaming API call failed")
+		tracing.End(span, err)
+		return nil, openaiprovider.NewProviderError("ollama", err)
+	}
+
+	chunks := make(chan *types.ChatChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				} else {
+					p.logger.WithError(err).Error("Error receiving stream chunk")
+					providers.SendStreamError(ctx, chunks, openaiprovider.NewProviderError("ollama", err))
+				}
+				tracing.End(span, err)
+				return
+			}
+
+			select {
+			case chunks <- openaiprovider.ConvertChunk(&response):
+			case <-ctx.Done():
+				tracing.End(span, ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// EstimateCost estimates the cost for a chat completion request. Local
+// inference has no per-token cost, so only token counts are reported.
+func (p *OllamaProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	if !p.servesModel(req.Model) {
+		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
+	}
+
+	inputTokens := estimateTokens(req)
+
+	// Estimate output tokens (use max_tokens or default)
+	outputTokens := 100
+	if req.MaxTokens != nil {
+		outputTokens = *req.MaxTokens
+	}
+
+	return &types.CostEstimate{
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		TotalTokens:  inputTokens + outputTokens,
+	}, nil
+}
+
+// HealthCheck checks that the Ollama server is up and serving models
+func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
+	// Use the configured custom probe when present
+	if p.config.HealthProbe != nil {
+		if err := p.config.HealthProbe.Run(ctx, p.httpClient, p.config.baseURL(), nil); err != nil {
+			p.logger.WithError(err).Error("Ollama health probe failed")
+			return fmt.Errorf("ollama health check failed: %w", err)
+		}
+		p.logger.Debug("Ollama health probe passed")
+		return nil
+	}
+
+	// Listing models is free and confirms the server is answering
+	if _, err := p.client.ListModels(ctx); err != nil {
+		p.logger.WithError(err).Error("Ollama health check failed")
+		return fmt.Errorf("ollama health check failed: %w", err)
+	}
+
+	p.logger.Debug("Ollama health check passed")
+	return nil
+}
+
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *OllamaProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// Interface implementations for advanced features
+
+// SupportsFunctionCalling implements FunctionCallingProvider
+func (p *OllamaProvider) SupportsFunctionCalling() bool {
+	return true
+}
+
+// SupportsParallelFunctions implements FunctionCallingProvider
+func (p *OllamaProvider) SupportsParallelFunctions() bool {
+	return false
+}
+
+// Helper functions
+
+// baseURL returns the configured API base URL
+func (c *OllamaConfig) baseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	return DefaultBaseURL
+}
+
+// servesModel reports whether model is configured for this provider
+func (p *OllamaProvider) servesModel(model string) bool {
+	return p.modelIndex.Lookup(model) != nil
+}
+
+// maxContextWindow returns the largest context window among models
+func maxContextWindow(models []types.ModelInfo) int {
+	largest := 0
+	for _, model := range models {
+		largest = max(largest, model.MaxContextWindow)
+	}
+	return largest
+}
+
+// estimateTokens estimates the prompt tokens in the request
+func estimateTokens(req *types.ChatRequest) int {
+	total := tokenizer.CountTokens(req.Model, req.Messages)
+
+	// Add tool tokens
+	for _, tool := range req.Tools {
+		total += tokenizer.CountText(req.Model, tool.Function.Name) + tokenizer.CountText(req.Model, tool.Function.Description)
+	}
+
+	return total
+}
+
+// Ensure OllamaProvider implements all the interfaces
+var _ providers.LLMProvider = (*OllamaProvider)(nil)
+var _ providers.FunctionCallingProvider = (*OllamaProvider)(nil)
+var _ providers.HealthCheckScheduler = (*OllamaProvider)(nil)
@@ -0,0 +1,128 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createTestProvider(t *testing.T, baseURL string) *OllamaProvider {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	return NewOllamaProvider(&OllamaConfig{
+		BaseURL: baseURL,
+		Models: []types.ModelInfo{
+			{Name: "llama3.1:8b", ProviderModelID: "llama3.1:8b", MaxContextWindow: 131072},
+		},
+	}, logger)
+}
+
+func TestOllamaProvider_EstimateCost(t *testing.T) {
+	provider := createTestProvider(t, "")
+	maxTokens := 50
+
+	estimate, err := provider.EstimateCost(&types.ChatRequest{
+		Model:     "llama3.1:8b",
+		Messages:  []types.Message{{Role: "user", Content: "Hello there"}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if estimate.TotalCost != 0 || estimate.CostPer1KTokens != 0 {
+		t.Errorf("Expected zero cost, got %+v", estimate)
+	}
+	if estimate.InputTokens == 0 || estimate.OutputTokens != 50 {
+		t.Errorf("Expected token counts to be estimated, got %+v", estimate)
+	}
+
+	// Unconfigured models can't be served locally
+	if _, err := provider.EstimateCost(&types.ChatRequest{Model: "gpt-4o"}); err == nil {
+		t.Error("Expected an error for an unconfigured model")
+	}
+}
+
+func TestOllamaProvider_GetCapabilities(t *testing.T) {
+	caps := createTestProvider(t, "").GetCapabilities()
+
+	if caps.ProviderName != "ollama" {
+		t.Errorf("Expected provider name 'ollama', got %s", caps.ProviderName)
+	}
+	if caps.MaxContextWindow != 131072 {
+		t.Errorf("Expected context window from models, got %d", caps.MaxContextWindow)
+	}
+	if caps.CostPer1KTokens.InputCostPer1K != 0 || caps.CostPer1KTokens.OutputCostPer1K != 0 {
+		t.Errorf("Expected zero cost, got %+v", caps.CostPer1KTokens)
+	}
+}
+
+func TestOllamaProvider_ChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "llama3.1:8b" {
+			t.Errorf("Expected model llama3.1:8b, got %v", body["model"])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"model": "llama3.1:8b",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hi!"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := createTestProvider(t, server.URL+"/v1")
+	resp, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "llama3.1:8b",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Hi!" {
+		t.Errorf("Expected content 'Hi!', got %v", resp.Choices[0].Message.Content)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 5 {
+		t.Errorf("Expected usage to be converted, got %+v", resp.Usage)
+	}
+}
+
+func TestOllamaProvider_HealthCheck(t *testing.T) {
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("Expected /v1/models, got %s", r.URL.Path)
+		}
+		if !up {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"llama3.1:8b","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	provider := createTestProvider(t, server.URL+"/v1")
+	if err := provider.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+
+	up = false
+	if err := provider.HealthCheck(context.Background()); err == nil {
+		t.Error("Expected health check to fail")
+	}
+}
@@ -0,0 +1,129 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// DiscoveryConfig controls automatic model discovery from an OpenAI-compatible
+// gateway's /v1/models endpoint (e.g. LiteLLM)
+type DiscoveryConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // Refresh interval, 0 disables periodic refresh
+
+	// Defaults applied to discovered models that are not explicitly configured
+	DefaultInputCostPer1K    float64 `yaml:"default_input_cost_per_1k"`
+	DefaultOutputCostPer1K   float64 `yaml:"default_output_cost_per_1k"`
+	DefaultContextWindow     int     `yaml:"default_context_window"`
+	DefaultMaxOutputTokens   int     `yaml:"default_max_output_tokens"`
+	DefaultSupportsFunctions bool    `yaml:"default_supports_functions"`
+}
+
+// models returns the explicitly configured models merged with any discovered
+// models. Explicit configuration always wins for a given model name.
+func (p *OpenAIProvider) models() []types.ModelInfo {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+
+	if len(p.discovered) == 0 {
+		return p.config.Models
+	}
+
+	configured := make(map[string]bool, len(p.config.Models))
+	models := make([]types.ModelInfo, 0, len(p.config.Models)+len(p.discovered))
+	for _, model := range p.config.Models {
+		configured[model.Name] = true
+		configured[model.ProviderModelID] = true
+		models = append(models, model)
+	}
+
+	for _, model := range p.discovered {
+		if !configured[model.Name] {
+			models = append(models, model)
+		}
+	}
+
+	return models
+}
+
+// DiscoverModels fetches the model list from the upstream endpoint and updates
+// the provider's discovered models. On failure the last-known list is kept.
+func (p *OpenAIProvider) DiscoverModels(ctx context.Context) error {
+	var resp openai.ModelsList
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.ListModels(ctx)
+		return callErr
+	})
+	if err != nil {
+		p.logger.WithError(err).Warn("OpenAI model discovery failed, keeping last-known models")
+		return fmt.Errorf("openai model discovery failed: %w", err)
+	}
+
+	discovery := p.config.Discovery
+	if discovery == nil {
+		discovery = &DiscoveryConfig{}
+	}
+
+	discovered := make([]types.ModelInfo, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		if model.ID == "" {
+			continue
+		}
+		discovered = append(discovered, types.ModelInfo{
+			Name:              model.ID,
+			DisplayName:       model.ID,
+			ProviderModelID:   model.ID,
+			MaxContextWindow:  discovery.DefaultContextWindow,
+			MaxOutputTokens:   discovery.DefaultMaxOutputTokens,
+			SupportsFunctions: discovery.DefaultSupportsFunctions,
+			InputCostPer1K:    discovery.DefaultInputCostPer1K,
+			OutputCostPer1K:   discovery.DefaultOutputCostPer1K,
+			Tags:              []string{"discovered"},
+		})
+	}
+
+	p.discoveryMu.Lock()
+	p.discovered = discovered
+	p.discoveryMu.Unlock()
+	p.modelIndex.Set(p.models())
+
+	p.logger.WithField("models", len(discovered)).Info("OpenAI model discovery completed")
+	return nil
+}
+
+// StartDiscovery runs an initial discovery and, when an interval is configured,
+// keeps refreshing the model list until ctx is cancelled
+func (p *OpenAIProvider) StartDiscovery(ctx context.Context) {
+	if p.config.Discovery == nil || !p.config.Discovery.Enabled {
+		return
+	}
+
+	p.DiscoverModels(ctx)
+
+	interval := p.config.Discovery.Interval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.DiscoverModels(ctx)
+			case <-ctx.Done():
+				p.logger.WithFields(logrus.Fields{"provider": "openai"}).Debug("Model discovery stopped")
+				return
+			}
+		}
+	}()
+}
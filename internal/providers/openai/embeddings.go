@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// embeddingCostsPer1K holds list prices for OpenAI embedding models, used when
+// the model isn't configured explicitly
+var embeddingCostsPer1K = map[string]float64{
+	"text-embedding-3-small": 0.00002,
+	"text-embedding-3-large": 0.00013,
+	"text-embedding-ada-002": 0.0001,
+}
+
+// SupportsEmbeddings implements EmbeddingProvider
+func (p *OpenAIProvider) SupportsEmbeddings() bool {
+	return true
+}
+
+// CreateEmbedding implements EmbeddingProvider
+func (p *OpenAIProvider) CreateEmbedding(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp openai.EmbeddingResponse
+	err = p.withKey(func(client *openai.Client) error {
+		resp, err = client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+			Input:          inputs,
+			Model:          openai.EmbeddingModel(req.Model),
+			User:           req.User,
+			EncodingFormat: openai.EmbeddingEncodingFormat(req.EncodingFormat),
+			Dimensions:     req.Dimensions,
+		})
+		return err
+	})
+	if err != nil {
+		p.logger.WithError(err).Error("OpenAI embeddings call failed")
+		return nil, fmt.Errorf("openai embeddings call failed: %w", err)
+	}
+
+	data := make([]types.Embedding, len(resp.Data))
+	for i, embedding := range resp.Data {
+		data[i] = types.Embedding{
+			Object:    embedding.Object,
+			Index:     embedding.Index,
+			Embedding: embedding.Embedding,
+		}
+	}
+
+	return &types.EmbeddingResponse{
+		Object: resp.Object,
+		Data:   data,
+		Model:  string(resp.Model),
+		Usage: &types.Usage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// EstimateEmbeddingCost implements EmbeddingProvider
+func (p *OpenAIProvider) EstimateEmbeddingCost(req *types.EmbeddingRequest) (*types.CostEstimate, error) {
+	costPer1K, found := embeddingCostsPer1K[req.Model]
+	if model := p.modelIndex.Lookup(req.Model); model != nil {
+		costPer1K, found = model.InputCostPer1K, true
+	}
+	if !found {
+		return nil, fmt.Errorf("embedding model %s not found in configuration", req.Model)
+	}
+
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, err
+	}
+
+	inputTokens := 0
+	for _, input := range inputs {
+		inputTokens += tokenizer.CountText(req.Model, input)
+	}
+
+	inputCost := float64(inputTokens) * costPer1K / 1000
+
+	return &types.CostEstimate{
+		InputTokens:     inputTokens,
+		TotalTokens:     inputTokens,
+		InputCost:       inputCost,
+		TotalCost:       inputCost,
+		CostPer1KTokens: costPer1K,
+	}, nil
+}
+
+var _ providers.EmbeddingProvider = (*OpenAIProvider)(nil)
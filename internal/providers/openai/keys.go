@@ -0,0 +1,133 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+)
+
+// Keys returns the configured API keys: APIKeys followed by APIKey when it is
+// not already listed
+func (c *OpenAIConfig) Keys() []string {
+	keys := make([]string, 0, len(c.APIKeys)+1)
+	seen := make(map[string]bool)
+	for _, key := range append(append([]string{}, c.APIKeys...), c.APIKey) {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// apiKey is an API key with its own client
+type apiKey struct {
+	key    string
+	client *openai.Client
+	dead   bool
+}
+
+// keyPool hands out API keys round-robin, skipping keys that were rejected
+// as invalid or revoked
+type keyPool struct {
+	mu   sync.Mutex
+	keys []*apiKey
+	next int
+}
+
+func newKeyPool(config *OpenAIConfig) *keyPool {
+	pool := &keyPool{}
+	for _, key := range config.Keys() {
+		clientConfig := openai.DefaultConfig(key)
+		if config.BaseURL != "" {
+			clientConfig.BaseURL = config.BaseURL
+		}
+		if config.OrgID != "" {
+			clientConfig.OrgID = config.OrgID
+		}
+		clientConfig.HTTPClient = providers.ProviderHTTPClient(config.HTTPClient, config.Timeout, true)
+		pool.keys = append(pool.keys, &apiKey{key: key, client: openai.NewClientWithConfig(clientConfig)})
+	}
+	return pool
+}
+
+// acquire returns the next live key
+func (kp *keyPool) acquire() (*apiKey, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	for i := 0; i < len(kp.keys); i++ {
+		key := kp.keys[(kp.next+i)%len(kp.keys)]
+		if !key.dead {
+			kp.next = (kp.next + i + 1) % len(kp.keys)
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no valid openai api keys available")
+}
+
+// report marks key dead when err shows it was rejected by the API
+func (kp *keyPool) report(key *apiKey, err error) bool {
+	if err == nil || APIStatusCode(err) != http.StatusUnauthorized {
+		return false
+	}
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	key.dead = true
+	return true
+}
+
+// live returns the number of keys that have not been marked dead
+func (kp *keyPool) live() int {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	count := 0
+	for _, key := range kp.keys {
+		if !key.dead {
+			count++
+		}
+	}
+	return count
+}
+
+// withKey runs call with the client for the next live API key. Keys rejected
+// as unauthorized are removed from rotation and the call is retried with the
+// next key.
+func (p *OpenAIProvider) withKey(call func(client *openai.Client) error) error {
+	for {
+		key, err := p.keys.acquire()
+		if err != nil {
+			return err
+		}
+
+		err = call(key.client)
+		if !p.keys.report(key, err) {
+			return err
+		}
+
+		remaining := p.keys.live()
+		p.logger.WithFields(logrus.Fields{
+			"key":            keySuffix(key.key),
+			"remaining_keys": remaining,
+		}).Warn("OpenAI API key rejected, removing it from rotation")
+		if remaining == 0 {
+			return err
+		}
+	}
+}
+
+// keySuffix identifies a key in logs without revealing it
+func keySuffix(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "..." + key[len(key)-4:]
+}
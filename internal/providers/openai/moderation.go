@@ -0,0 +1,57 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ModerationClient scores text with OpenAI's moderation endpoint using the
+// provider's API keys
+type ModerationClient struct {
+	keys  *keyPool
+	model string
+}
+
+// NewModerationClient creates a moderation client. An empty model uses
+// omni-moderation-latest.
+func NewModerationClient(config *OpenAIConfig, model string) *ModerationClient {
+	if model == "" {
+		model = openai.ModerationOmniLatest
+	}
+	return &ModerationClient{keys: newKeyPool(config), model: model}
+}
+
+// Moderate returns the score of each moderation category for text
+func (c *ModerationClient) Moderate(ctx context.Context, text string) (map[string]float64, error) {
+	key, err := c.keys.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := key.client.Moderations(ctx, openai.ModerationRequest{Input: text, Model: c.model})
+	c.keys.report(key, err)
+	if err != nil {
+		return nil, NewProviderError("openai", err)
+	}
+
+	// Category scores are a struct in the SDK; its JSON form keys them by
+	// the API's category names
+	scores := make(map[string]float64)
+	for _, result := range resp.Results {
+		data, err := json.Marshal(result.CategoryScores)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read moderation scores: %w", err)
+		}
+		var resultScores map[string]float64
+		if err := json.Unmarshal(data, &resultScores); err != nil {
+			return nil, fmt.Errorf("failed to read moderation scores: %w", err)
+		}
+		for category, score := range resultScores {
+			scores[category] = max(scores[category], score)
+		}
+	}
+	return scores, nil
+}
@@ -2,49 +2,58 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
 	
 	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
 // OpenAIProvider implements the LLMProvider interface for OpenAI
 type OpenAIProvider struct {
-	client *openai.Client
-	config *OpenAIConfig
-	logger *logrus.Logger
+	keys       *keyPool
+	httpClient *http.Client // health probes and pings
+	config     *OpenAIConfig
+	logger     *logrus.Logger
+	
+	// Models discovered from the upstream /v1/models endpoint
+	discoveryMu sync.RWMutex
+	discovered  []types.ModelInfo
+	
+	// Configured and discovered models by name, refreshed by discovery
+	modelIndex *providers.ModelIndex
 }
 
 // OpenAIConfig holds OpenAI-specific configuration
 type OpenAIConfig struct {
-	APIKey      string            `yaml:"api_key"`
-	BaseURL     string            `yaml:"base_url"`
-	OrgID       string            `yaml:"org_id"`
-	Models      []types.ModelInfo `yaml:"models"`
-	Timeout     time.Duration     `yaml:"timeout"`
+	APIKey    string            `yaml:"api_key"`
+	APIKeys   []string          `yaml:"api_keys"` // rotated round-robin per request
+	BaseURL   string            `yaml:"base_url"`
+	OrgID     string            `yaml:"org_id"`
+	Models    []types.ModelInfo `yaml:"models"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	Discovery *DiscoveryConfig  `yaml:"discovery"`
+
+	providers.CommonConfig `yaml:",inline"`
 }
 
 // NewOpenAIProvider creates a new OpenAI provider instance
 func NewOpenAIProvider(config *OpenAIConfig, logger *logrus.Logger) *OpenAIProvider {
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	
-	if config.BaseURL != "" {
-		clientConfig.BaseURL = config.BaseURL
-	}
-	if config.OrgID != "" {
-		clientConfig.OrgID = config.OrgID
-	}
-	
-	client := openai.NewClientWithConfig(clientConfig)
-	
 	return &OpenAIProvider{
-		client: client,
-		config: config,
-		logger: logger,
+		keys:       newKeyPool(config),
+		httpClient: providers.ProviderHTTPClient(config.HTTPClient, config.Timeout, false),
+		config:     config,
+		logger:     logger,
+		modelIndex: providers.NewModelIndex(config.Models),
 	}
 }
 
@@ -55,9 +64,9 @@ func (p *OpenAIProvider) GetProviderName() string {
 
 // GetCapabilities returns the capabilities of the OpenAI provider
 func (p *OpenAIProvider) GetCapabilities() types.ProviderCapabilities {
-	return types.ProviderCapabilities{
+	return p.config.Capabilities.Apply(types.ProviderCapabilities{
 		ProviderName:              "openai",
-		SupportedModels:           p.config.Models,
+		SupportedModels:           p.models(),
 		SupportsFunctions:         true,
 		SupportsParallelFunctions: true,
 		SupportsVision:            true,
@@ -65,7 +74,9 @@ func (p *OpenAIProvider) GetCapabilities() types.ProviderCapabilities {
 		SupportsStreaming:         true,
 		SupportsAssistants:        true,
 		SupportsBatch:             true,
+		SupportsEmbeddings:        true,
 		MaxContextWindow:          128000, // GPT-4 context window
+		MaxStopSequences:          4,
 		SupportedImageFormats:     []string{"png", "jpeg", "webp", "gif"},
 		CostPer1KTokens: types.CostStructure{
 			InputCostPer1K:  0.005, // Default GPT-4 pricing
@@ -82,46 +93,79 @@ func (p *OpenAIProvider) GetCapabilities() types.ProviderCapabilities {
 			MaxFunctionCalls:          10,
 			SupportedResponseFormats:  []string{"text", "json_object", "json_schema"},
 		},
-	}
+	})
 }
 
 // ChatCompletion performs a chat completion request
 func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	ctx, span := tracing.Start(ctx, "openai.chat_completion",
+		tracing.AttrProvider.String("openai"),
+		tracing.AttrModel.String(req.Model),
+	)
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
 	// Convert our request to OpenAI format
 	openaiReq, err := p.convertToOpenAIRequest(req)
 	if err != nil {
 		p.logger.WithError(err).Error("Failed to convert request to OpenAI format")
+		tracing.End(span, err)
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
 
 	// Make the API call
-	resp, err := p.client.CreateChatCompletion(ctx, *openaiReq)
+	var resp openai.ChatCompletionResponse
+	err = p.withKey(func(client *openai.Client) error {
+		resp, err = client.CreateChatCompletion(ctx, *openaiReq)
+		return err
+	})
 	if err != nil {
 		p.logger.WithError(err).Error("OpenAI API call failed")
-		return nil, fmt.Errorf("openai api call failed: %w", err)
+		tracing.End(span, err)
+		return nil, NewProviderError("openai", err)
 	}
+	tracing.End(span, nil)
 
 	// Convert response back to our format
-	return p.convertFromOpenAIResponse(&resp, req), nil
+	return ConvertResponse(&resp), nil
 }
 
 // StreamCompletion performs a streaming chat completion request
 func (p *OpenAIProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	// The span stays open until the stream is fully consumed
+	ctx, span := tracing.Start(ctx, "openai.stream_completion",
+		tracing.AttrProvider.String("openai"),
+		tracing.AttrModel.String(req.Model),
+	)
+
+	// The timeout covers the whole stream, so it is released when the
+	// stream ends rather than when this function returns
+	ctx, cancel := providers.WithTimeout(ctx, p.config.Timeout)
+
 	// Convert our request to OpenAI format
 	openaiReq, err := p.convertToOpenAIRequest(req)
 	if err != nil {
 		p.logger.WithError(err).Error("Failed to convert request to OpenAI format")
+		tracing.End(span, err)
+		cancel()
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
 
-	// Enable streaming
+	// Enable streaming, with a final usage chunk for accounting
 	openaiReq.Stream = true
+	openaiReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
 
 	// Make the streaming API call
-	stream, err := p.client.CreateChatCompletionStream(ctx, *openaiReq)
+	var stream *openai.ChatCompletionStream
+	err = p.withKey(func(client *openai.Client) error {
+		stream, err = client.CreateChatCompletionStream(ctx, *openaiReq)
+		return err
+	})
 	if err != nil {
 		p.logger.WithError(err).Error("OpenAI streaming API call failed")
-		return nil, fmt.Errorf("openai streaming api call failed: %w", err)
+		tracing.End(span, err)
+		cancel()
+		return nil, NewProviderError("openai", err)
 	}
 
 	// Create our response channel
@@ -130,22 +174,32 @@ func (p *OpenAIProvider) StreamCompletion(ctx context.Context, req *types.ChatRe
 	// Start goroutine to process stream
 	go func() {
 		defer close(chunks)
+		defer cancel()
 		defer stream.Close()
 
 		for {
 			response, err := stream.Recv()
 			if err != nil {
-				if err.Error() != "EOF" {
+				if err.Error() == "EOF" {
+					err = nil
+				} else if errors.Is(ctx.Err(), context.Canceled) {
+					// The consumer cancelled, e.g. the client disconnected,
+					// which aborted the upstream read; nobody is listening
+					p.logger.WithError(err).Debug("Stream cancelled")
+				} else {
 					p.logger.WithError(err).Error("Error receiving stream chunk")
+					providers.SendStreamError(ctx, chunks, NewProviderError("openai", err))
 				}
+				tracing.End(span, err)
 				return
 			}
 
 			// Convert chunk to our format
-			chunk := p.convertFromOpenAIChunk(&response, req)
+			chunk := ConvertChunk(&response)
 			select {
 			case chunks <- chunk:
 			case <-ctx.Done():
+				tracing.End(span, ctx.Err())
 				return
 			}
 		}
@@ -156,27 +210,22 @@ func (p *OpenAIProvider) StreamCompletion(ctx context.Context, req *types.ChatRe
 
 // EstimateCost estimates the cost for a chat completion request
 func (p *OpenAIProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
-	// Find model info
-	var modelInfo *types.ModelInfo
-	for _, model := range p.config.Models {
-		if model.Name == req.Model || model.ProviderModelID == req.Model {
-			modelInfo = &model
-			break
-		}
-	}
+	modelInfo := p.modelIndex.Lookup(req.Model)
 
 	if modelInfo == nil {
 		return nil, fmt.Errorf("model %s not found in configuration", req.Model)
 	}
 
-	// Estimate input tokens (rough approximation)
+	// Estimate input tokens
 	inputTokens := p.estimateTokens(req)
 
-	// Estimate output tokens (use max_tokens or default)
+	// Estimate output tokens (use max_tokens or default). The prompt is
+	// billed once, each of the n completions separately.
 	outputTokens := 100 // default
 	if req.MaxTokens != nil {
 		outputTokens = *req.MaxTokens
 	}
+	outputTokens *= req.ChoiceCount()
 
 	totalTokens := inputTokens + outputTokens
 	inputCost := float64(inputTokens) * modelInfo.InputCostPer1K / 1000
@@ -196,8 +245,47 @@ func (p *OpenAIProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstima
 
 // HealthCheck performs a health check on the OpenAI API
 func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
-	// Simple health check using models endpoint
-	_, err := p.client.ListModels(ctx)
+	baseURL := p.config.BaseURL
+	if baseURL == "" {
+		baseURL = openai.DefaultConfig("").BaseURL
+	}
+	
+	// Use the configured custom probe when present
+	if p.config.HealthProbe != nil {
+		key, err := p.keys.acquire()
+		if err != nil {
+			return fmt.Errorf("openai health check failed: %w", err)
+		}
+		headers := map[string]string{"Authorization": "Bearer " + key.key}
+		if p.config.OrgID != "" {
+			headers["OpenAI-Organization"] = p.config.OrgID
+		}
+		if err := p.config.HealthProbe.Run(ctx, p.httpClient, baseURL, headers); err != nil {
+			p.logger.WithError(err).Error("OpenAI health probe failed")
+			return fmt.Errorf("openai health check failed: %w", err)
+		}
+		p.logger.Debug("OpenAI health probe passed")
+		return nil
+	}
+	
+	if p.config.DisableLiveHealthCheck {
+		if err := providers.Ping(ctx, p.httpClient, baseURL); err != nil {
+			p.logger.WithError(err).Error("OpenAI health ping failed")
+			return fmt.Errorf("openai health check failed: %w", err)
+		}
+		return nil
+	}
+	
+	// Look up the health check model, or list models when none is configured
+	model := p.healthCheckModel()
+	err := p.withKey(func(client *openai.Client) error {
+		if model != "" {
+			_, err := client.GetModel(ctx, model)
+			return err
+		}
+		_, err := client.ListModels(ctx)
+		return err
+	})
 	if err != nil {
 		p.logger.WithError(err).Error("OpenAI health check failed")
 		return fmt.Errorf("openai health check failed: %w", err)
@@ -207,6 +295,19 @@ func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// HealthCheckInterval implements providers.HealthCheckScheduler
+func (p *OpenAIProvider) HealthCheckInterval() time.Duration {
+	return p.config.HealthCheckInterval
+}
+
+// healthCheckModel returns the model used by the live health check
+func (p *OpenAIProvider) healthCheckModel() string {
+	if p.config.HealthCheckModel != "" {
+		return p.config.HealthCheckModel
+	}
+	return providers.CheapestModel(p.config.Models)
+}
+
 // Interface implementations for advanced features
 
 // SupportsFunctionCalling implements FunctionCallingProvider
@@ -254,51 +355,72 @@ func (p *OpenAIProvider) CreateBatch(ctx context.Context, req *types.BatchReques
 		Metadata:         req.Metadata,
 	}
 
-	resp, err := p.client.CreateBatch(ctx, openaiReq)
+	var resp openai.BatchResponse
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.CreateBatch(ctx, openaiReq)
+		return callErr
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create batch: %w", err)
+		return nil, fmt.Errorf("failed to create batch: %w", batchError(err))
 	}
 
-	// Helper function to safely get string from pointer
-	getStringPtr := func(s *string) string {
-		if s == nil {
-			return ""
-		}
-		return *s
+	return convertBatch(&resp.Batch), nil
+}
+
+// GetBatch implements BatchProvider
+func (p *OpenAIProvider) GetBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	var resp openai.BatchResponse
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.RetrieveBatch(ctx, id)
+		return callErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve batch: %w", batchError(err))
 	}
 
-	// Helper function to safely get int64 from pointer int
-	getInt64Ptr := func(i *int) int64 {
-		if i == nil {
-			return 0
-		}
-		return int64(*i)
-	}
-
-	// Convert response
-	return &types.BatchResponse{
-		ID:               resp.ID,
-		Object:           resp.Object,
-		Endpoint:         string(resp.Endpoint),
-		InputFileID:      resp.InputFileID,
-		CompletionWindow: resp.CompletionWindow,
-		Status:           resp.Status,
-		OutputFileID:     getStringPtr(resp.OutputFileID),
-		ErrorFileID:      getStringPtr(resp.ErrorFileID),
-		CreatedAt:        int64(resp.CreatedAt),
-		InProgressAt:     getInt64Ptr(resp.InProgressAt),
-		ExpiresAt:        getInt64Ptr(resp.ExpiresAt),
-		CompletedAt:      getInt64Ptr(resp.CompletedAt),
-		FailedAt:         getInt64Ptr(resp.FailedAt),
-		ExpiredAt:        getInt64Ptr(resp.ExpiredAt),
-		CancelledAt:      getInt64Ptr(resp.CancelledAt),
-		RequestCounts: types.BatchRequestCounts{
-			Total:     resp.RequestCounts.Total,
-			Completed: resp.RequestCounts.Completed,
-			Failed:    resp.RequestCounts.Failed,
-		},
-		Metadata: resp.Metadata,
-	}, nil
+	return convertBatch(&resp.Batch), nil
+}
+
+// CancelBatch implements BatchProvider
+func (p *OpenAIProvider) CancelBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	var resp openai.BatchResponse
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.CancelBatch(ctx, id)
+		return callErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel batch: %w", batchError(err))
+	}
+
+	return convertBatch(&resp.Batch), nil
+}
+
+// ListBatches implements BatchProvider, returning the most recent page
+func (p *OpenAIProvider) ListBatches(ctx context.Context) (*types.BatchListResponse, error) {
+	var resp openai.ListBatchResponse
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.ListBatch(ctx, nil, nil)
+		return callErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", batchError(err))
+	}
+
+	list := &types.BatchListResponse{
+		Object:  "list",
+		Data:    make([]types.BatchResponse, 0, len(resp.Data)),
+		FirstID: resp.FirstID,
+		LastID:  resp.LastID,
+		HasMore: resp.HasMore,
+	}
+	for i := range resp.Data {
+		list.Data = append(list.Data, *convertBatch(&resp.Data[i]))
+	}
+	return list, nil
 }
 
 // SupportsAssistants implements AssistantProvider
@@ -334,7 +456,12 @@ func (p *OpenAIProvider) CreateAssistant(ctx context.Context, req *types.Assista
 		Metadata:     req.Metadata,
 	}
 
-	resp, err := p.client.CreateAssistant(ctx, openaiReq)
+	var resp openai.Assistant
+	err := p.withKey(func(client *openai.Client) error {
+		var callErr error
+		resp, callErr = client.CreateAssistant(ctx, openaiReq)
+		return callErr
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create assistant: %w", err)
 	}
@@ -372,6 +499,12 @@ func (p *OpenAIProvider) CreateAssistant(ctx context.Context, req *types.Assista
 
 // convertToOpenAIRequest converts our unified request to OpenAI's format
 func (p *OpenAIProvider) convertToOpenAIRequest(req *types.ChatRequest) (*openai.ChatCompletionRequest, error) {
+	return ConvertRequest(req, p.logger)
+}
+
+// ConvertRequest converts a unified request to go-openai's format. It is
+// shared with providers that serve OpenAI-compatible APIs.
+func ConvertRequest(req *types.ChatRequest, logger *logrus.Logger) (*openai.ChatCompletionRequest, error) {
 	// Convert messages
 	var messages []openai.ChatCompletionMessage
 	for _, msg := range req.Messages {
@@ -454,6 +587,9 @@ func (p *OpenAIProvider) convertToOpenAIRequest(req *types.ChatRequest) (*openai
 	if req.Seed != nil {
 		openaiReq.Seed = req.Seed
 	}
+	if req.N != nil {
+		openaiReq.N = *req.N
+	}
 
 	// Handle functions (legacy)
 	if len(req.Functions) > 0 {
@@ -494,19 +630,25 @@ func (p *OpenAIProvider) convertToOpenAIRequest(req *types.ChatRequest) (*openai
 			Type: openai.ChatCompletionResponseFormatType(req.ResponseFormat.Type),
 		}
 
-		// Handle JSON schema (if supported by OpenAI SDK version)
 		if req.ResponseFormat.JSONSchema != nil {
-			// Note: Some versions of the OpenAI SDK may not support JSONSchema
-			// This is a placeholder for when it becomes available
-			p.logger.Debug("JSON Schema response format requested but may not be fully supported in current SDK version")
+			schema, err := json.Marshal(req.ResponseFormat.JSONSchema.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("invalid json_schema: %w", err)
+			}
+			openaiReq.ResponseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        req.ResponseFormat.JSONSchema.Name,
+				Description: req.ResponseFormat.JSONSchema.Description,
+				Schema:      json.RawMessage(schema),
+				Strict:      req.ResponseFormat.JSONSchema.Strict,
+			}
 		}
 	}
 
 	return openaiReq, nil
 }
 
-// convertFromOpenAIResponse converts OpenAI's response to our format
-func (p *OpenAIProvider) convertFromOpenAIResponse(resp *openai.ChatCompletionResponse, req *types.ChatRequest) *types.ChatResponse {
+// ConvertResponse converts a go-openai response to our format
+func ConvertResponse(resp *openai.ChatCompletionResponse) *types.ChatResponse {
 	// Convert choices
 	var choices []types.Choice
 	for _, choice := range resp.Choices {
@@ -561,8 +703,8 @@ func (p *OpenAIProvider) convertFromOpenAIResponse(resp *openai.ChatCompletionRe
 	}
 }
 
-// convertFromOpenAIChunk converts OpenAI's streaming chunk to our format
-func (p *OpenAIProvider) convertFromOpenAIChunk(chunk *openai.ChatCompletionStreamResponse, req *types.ChatRequest) *types.ChatChunk {
+// ConvertChunk converts a go-openai streaming chunk to our format
+func ConvertChunk(chunk *openai.ChatCompletionStreamResponse) *types.ChatChunk {
 	// Convert choices
 	var choices []types.ChoiceChunk
 	for _, choice := range chunk.Choices {
@@ -583,8 +725,9 @@ func (p *OpenAIProvider) convertFromOpenAIChunk(chunk *openai.ChatCompletionStre
 				var toolCalls []types.ToolCall
 				for _, tc := range choice.Delta.ToolCalls {
 					toolCall := types.ToolCall{
-						ID:   tc.ID,
-						Type: string(tc.Type),
+						Index: tc.Index,
+						ID:    tc.ID,
+						Type:  string(tc.Type),
 						Function: types.Function{
 							Name:      tc.Function.Name,
 							Arguments: tc.Function.Arguments,
@@ -620,40 +763,47 @@ func (p *OpenAIProvider) convertFromOpenAIChunk(chunk *openai.ChatCompletionStre
 	}
 }
 
-// estimateTokens provides a rough estimate of tokens in the request
+// estimateTokens estimates the prompt tokens in the request
 func (p *OpenAIProvider) estimateTokens(req *types.ChatRequest) int {
-	totalChars := 0
-	
-	for _, msg := range req.Messages {
-		switch content := msg.Content.(type) {
-		case string:
-			totalChars += len(content)
-		case []types.ContentPart:
-			for _, part := range content {
-				if part.Type == "text" {
-					totalChars += len(part.Text)
-				}
-				// Images add significant token cost, rough estimate
-				if part.Type == "image_url" {
-					totalChars += 1000 // Rough image token equivalent
-				}
-			}
-		}
-		
-		// Add role and name tokens
-		totalChars += len(msg.Role) + len(msg.Name)
-	}
+	total := tokenizer.CountTokens(req.Model, req.Messages)
 	
 	// Add function/tool tokens
 	for _, fn := range req.Functions {
-		totalChars += len(fn.Name) + len(fn.Description)
+		total += tokenizer.CountText(req.Model, fn.Name) + tokenizer.CountText(req.Model, fn.Description)
 	}
 	for _, tool := range req.Tools {
-		totalChars += len(tool.Function.Name) + len(tool.Function.Description)
+		total += tokenizer.CountText(req.Model, tool.Function.Name) + tokenizer.CountText(req.Model, tool.Function.Description)
 	}
 	
-	// Rough approximation: 4 chars per token
-	return totalChars / 4
+	return total
+}
+
+// APIStatusCode extracts the HTTP status code from a go-openai error
+func APIStatusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// NewProviderError normalizes an error from a go-openai client, reading the
+// error code and message from the API's error body. Mistral and Ollama use
+// it too, since they are called through the same client.
+func NewProviderError(provider string, err error) *providers.ProviderError {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.Type
+		if apiErr.Code != nil {
+			code = fmt.Sprint(apiErr.Code)
+		}
+		return providers.NewProviderError(provider, apiErr.HTTPStatusCode, code, apiErr.Message, err)
+	}
+	return providers.NewProviderError(provider, APIStatusCode(err), "", "", err)
 }
 
 // getString safely gets string value from pointer
@@ -664,6 +814,58 @@ func getString(s *string) string {
 	return *s
 }
 
+// getInt64 safely gets an int64 from an int pointer
+func getInt64(i *int) int64 {
+	if i == nil {
+		return 0
+	}
+	return int64(*i)
+}
+
+// batchError wraps a batch API error with its upstream status code
+func batchError(err error) error {
+	return NewProviderError("openai", err)
+}
+
+// convertBatch converts an OpenAI batch to our format
+func convertBatch(batch *openai.Batch) *types.BatchResponse {
+	resp := &types.BatchResponse{
+		ID:               batch.ID,
+		Object:           batch.Object,
+		Endpoint:         string(batch.Endpoint),
+		InputFileID:      batch.InputFileID,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		OutputFileID:     getString(batch.OutputFileID),
+		ErrorFileID:      getString(batch.ErrorFileID),
+		CreatedAt:        int64(batch.CreatedAt),
+		InProgressAt:     getInt64(batch.InProgressAt),
+		ExpiresAt:        getInt64(batch.ExpiresAt),
+		CompletedAt:      getInt64(batch.CompletedAt),
+		FailedAt:         getInt64(batch.FailedAt),
+		ExpiredAt:        getInt64(batch.ExpiredAt),
+		CancelledAt:      getInt64(batch.CancelledAt),
+		RequestCounts: types.BatchRequestCounts{
+			Total:     batch.RequestCounts.Total,
+			Completed: batch.RequestCounts.Completed,
+			Failed:    batch.RequestCounts.Failed,
+		},
+		Metadata: batch.Metadata,
+	}
+
+	if batch.Errors != nil {
+		for _, batchErr := range batch.Errors.Data {
+			resp.Errors = append(resp.Errors, types.BatchError{
+				Code:    batchErr.Code,
+				Message: batchErr.Message,
+				Param:   getString(batchErr.Param),
+				Line:    int(getInt64(batchErr.Line)),
+			})
+		}
+	}
+	return resp
+}
+
 // Ensure OpenAIProvider implements all the interfaces
 var _ providers.LLMProvider = (*OpenAIProvider)(nil)
 var _ providers.FunctionCallingProvider = (*OpenAIProvider)(nil)
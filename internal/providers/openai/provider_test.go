@@ -1,10 +1,25 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
@@ -175,6 +190,143 @@ func TestOpenAIProvider_ConvertRequest(t *testing.T) {
 	}
 }
 
+func TestOpenAIProvider_MultipleChoices(t *testing.T) {
+	var received struct {
+		N int `json:"n"`
+	}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[` +
+			`{"index":0,"message":{"role":"assistant","content":"a"},"finish_reason":"stop"},` +
+			`{"index":1,"message":{"role":"assistant","content":"b"},"finish_reason":"stop"}]}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+
+	req := &types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		N:        intPtr(2),
+	}
+	resp, err := provider.ChatCompletion(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if received.N != 2 {
+		t.Errorf("Expected n=2 to be passed through, got %d", received.N)
+	}
+	if len(resp.Choices) != 2 {
+		t.Errorf("Expected 2 choices, got %d", len(resp.Choices))
+	}
+
+	// Each completion's output is billed, the prompt once
+	single, _ := createTestProvider(t).EstimateCost(&types.ChatRequest{Model: "gpt-4o", Messages: req.Messages, MaxTokens: intPtr(100)})
+	multiple, _ := createTestProvider(t).EstimateCost(&types.ChatRequest{Model: "gpt-4o", Messages: req.Messages, MaxTokens: intPtr(100), N: intPtr(3)})
+	if multiple.OutputTokens != 3*single.OutputTokens || multiple.InputTokens != single.InputTokens {
+		t.Errorf("Expected n=3 to triple output tokens only, got %+v vs %+v", multiple, single)
+	}
+}
+
+func TestOpenAIProvider_ConvertRequestJSONSchema(t *testing.T) {
+	provider := createTestProvider(t)
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		"required":             []string{"city"},
+		"additionalProperties": false,
+	}
+
+	req, err := provider.convertToOpenAIRequest(&types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Where is the Eiffel Tower?"}},
+		ResponseFormat: &types.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &types.JSONSchema{Name: "location", Description: "A city", Schema: schema, Strict: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	format := req.ResponseFormat
+	if format == nil || format.Type != "json_schema" || format.JSONSchema == nil {
+		t.Fatalf("Expected a json_schema response format, got %+v", format)
+	}
+	if format.JSONSchema.Name != "location" || format.JSONSchema.Description != "A city" {
+		t.Errorf("Expected schema name and description to propagate, got %+v", format.JSONSchema)
+	}
+	if !format.JSONSchema.Strict {
+		t.Error("Expected strict flag to propagate")
+	}
+
+	got, err := json.Marshal(format.JSONSchema.Schema)
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	want, _ := json.Marshal(schema)
+	if string(got) != string(want) {
+		t.Errorf("Expected schema %s, got %s", want, got)
+	}
+}
+
+func TestConvertChunk_ParallelToolCallFragments(t *testing.T) {
+	index := func(i int) *int { return &i }
+	delta := func(calls ...openai.ToolCall) *openai.ChatCompletionStreamResponse {
+		return &openai.ChatCompletionStreamResponse{
+			ID:      "chatcmpl-1",
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{ToolCalls: calls}}},
+		}
+	}
+
+	// Two parallel calls whose argument fragments interleave
+	chunks := []*openai.ChatCompletionStreamResponse{
+		delta(openai.ToolCall{Index: index(0), ID: "call_a", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_weather"}}),
+		delta(openai.ToolCall{Index: index(0), Function: openai.FunctionCall{Arguments: `{"city":`}}),
+		delta(openai.ToolCall{Index: index(1), ID: "call_b", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_time"}}),
+		delta(openai.ToolCall{Index: index(1), Function: openai.FunctionCall{Arguments: `{"zone":"Europe/`}}),
+		delta(openai.ToolCall{Index: index(0), Function: openai.FunctionCall{Arguments: `"Paris"}`}}),
+		delta(openai.ToolCall{Index: index(1), Function: openai.FunctionCall{Arguments: `Paris"}`}}),
+	}
+
+	var calls types.ToolCallAccumulator
+	for _, chunk := range chunks {
+		converted := ConvertChunk(chunk)
+		for _, fragment := range converted.Choices[0].Delta.ToolCalls {
+			if fragment.Index == nil {
+				t.Fatalf("Expected streamed tool call fragments to carry their index")
+			}
+			if fragment.Function.Parameters != nil {
+				t.Errorf("Expected arguments in Function.Arguments only, got parameters %v", fragment.Function.Parameters)
+			}
+		}
+		calls.Add(converted.Choices[0].Delta.ToolCalls)
+	}
+
+	got := calls.ToolCalls()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 reassembled tool calls, got %d", len(got))
+	}
+	want := []struct{ id, name, arguments string }{
+		{"call_a", "get_weather", `{"city":"Paris"}`},
+		{"call_b", "get_time", `{"zone":"Europe/Paris"}`},
+	}
+	for i, call := range got {
+		if call.ID != want[i].id || call.Function.Name != want[i].name || call.Type != "function" {
+			t.Errorf("Call %d: expected %s %s, got %+v", i, want[i].id, want[i].name, call)
+		}
+		if !json.Valid([]byte(call.Function.Arguments)) {
+			t.Errorf("Call %d: reassembled arguments are not valid JSON: %s", i, call.Function.Arguments)
+		}
+		if call.Function.Arguments != want[i].arguments {
+			t.Errorf("Call %d: expected arguments %s, got %s", i, want[i].arguments, call.Function.Arguments)
+		}
+	}
+}
+
 func TestOpenAIProvider_Interfaces(t *testing.T) {
 	provider := createTestProvider(t)
 	
@@ -217,7 +369,252 @@ func TestOpenAIProvider_Interfaces(t *testing.T) {
 	}
 }
 
+func TestOpenAIProvider_ChatCompletionTimeout(t *testing.T) {
+	release := make(chan struct{})
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer gateway.Close()
+	defer close(release)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	provider := NewOpenAIProvider(&OpenAIConfig{
+		APIKey:  "test-api-key",
+		BaseURL: gateway.URL + "/v1",
+		Timeout: 50 * time.Millisecond,
+	}, logger)
+
+	start := time.Now()
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected a timeout error from a slow upstream")
+	}
+	if !providers.IsTimeout(err) {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the call to give up after the configured timeout, took %s", elapsed)
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOpenAIProvider_HealthCheckUsesSharedClient(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	probe := &providers.HealthProbeConfig{Path: "/health"}
+	if err := probe.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	for name, config := range map[string]*OpenAIConfig{
+		"health probe": {CommonConfig: providers.CommonConfig{HealthProbe: probe}},
+		"ping":         {CommonConfig: providers.CommonConfig{DisableLiveHealthCheck: true}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			transport := &countingTransport{}
+			config.APIKey = "test-api-key"
+			config.BaseURL = gateway.URL + "/v1"
+			config.HTTPClient = &http.Client{Transport: transport}
+
+			provider := NewOpenAIProvider(config, logger)
+			if err := provider.HealthCheck(context.Background()); err != nil {
+				t.Fatalf("HealthCheck() error = %v", err)
+			}
+			if transport.requests.Load() != 1 {
+				t.Errorf("Expected the health check to go through the shared client, got %d requests", transport.requests.Load())
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_ErrorClass(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"upstream failure","type":"api_error"}}`))
+		}))
+
+		provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+		_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+			Model:    "gpt-4o",
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		gateway.Close()
+
+		if got, want := providers.ErrorClass(err), strconv.Itoa(status); got != want {
+			t.Errorf("Expected class %s for status %d, got %s (%v)", want, status, got, err)
+		}
+	}
+
+	// Nothing listening
+	gateway := httptest.NewServer(http.NotFoundHandler())
+	gateway.Close()
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+	_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if got := providers.ErrorClass(err); got != providers.ErrorClassConnection {
+		t.Errorf("Expected class connection for an unreachable upstream, got %s (%v)", got, err)
+	}
+}
+
+func TestOpenAIProvider_NormalizedError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name      string
+		body      string
+		errType   string
+		code      string
+		retryable bool
+	}{
+		{
+			name:      "rate limit",
+			body:      `{"error":{"message":"Rate limit reached for gpt-4o in organization org-123 on requests per min (RPM): Limit 500, Used 500, Requested 1.","type":"requests","param":null,"code":"rate_limit_exceeded"}}`,
+			errType:   providers.ErrorTypeRateLimit,
+			code:      "rate_limit_exceeded",
+			retryable: true,
+		},
+		{
+			name:      "quota",
+			body:      `{"error":{"message":"You exceeded your current quota, please check your plan and billing details.","type":"insufficient_quota","param":null,"code":"insufficient_quota"}}`,
+			errType:   providers.ErrorTypeQuotaExceeded,
+			code:      "insufficient_quota",
+			retryable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(tt.body))
+		}))
+
+		provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+		_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+			Model:    "gpt-4o",
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		gateway.Close()
+
+		var providerErr *providers.ProviderError
+		if !errors.As(err, &providerErr) {
+			t.Fatalf("%s: expected a provider error, got %v", tt.name, err)
+		}
+		if providerErr.Provider != "openai" || providerErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("%s: expected an openai 429, got %s %d", tt.name, providerErr.Provider, providerErr.StatusCode)
+		}
+		if providerErr.Type != tt.errType || providerErr.Code != tt.code || providerErr.Retryable != tt.retryable {
+			t.Errorf("%s: expected type %s, code %s, retryable %v, got %s, %s, %v",
+				tt.name, tt.errType, tt.code, tt.retryable, providerErr.Type, providerErr.Code, providerErr.Retryable)
+		}
+		if providerErr.Message == "" || !strings.Contains(tt.body, providerErr.Message) {
+			t.Errorf("%s: expected the API's message, got %q", tt.name, providerErr.Message)
+		}
+		if strings.Contains(err.Error(), "status code:") {
+			t.Errorf("%s: expected the SDK's decoration to be dropped, got %q", tt.name, err.Error())
+		}
+	}
+}
+
 // Helper functions
+func TestOpenAIProvider_DiscoverModels(t *testing.T) {
+	var failing atomic.Bool
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[
+			{"id":"gpt-4o","object":"model","owned_by":"openai"},
+			{"id":"llama-3-70b","object":"model","owned_by":"meta"}
+		]}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	provider := NewOpenAIProvider(&OpenAIConfig{
+		APIKey:  "test-api-key",
+		BaseURL: gateway.URL + "/v1",
+		Models: []types.ModelInfo{
+			{Name: "gpt-4o", ProviderModelID: "gpt-4o", InputCostPer1K: 0.005, OutputCostPer1K: 0.015},
+		},
+		Discovery: &DiscoveryConfig{
+			Enabled:               true,
+			DefaultInputCostPer1K: 0.001,
+			DefaultContextWindow:  8192,
+		},
+	}, logger)
+
+	provider.StartDiscovery(context.Background())
+
+	models := provider.GetCapabilities().SupportedModels
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models after discovery, got %d", len(models))
+	}
+
+	byName := make(map[string]types.ModelInfo)
+	for _, model := range models {
+		byName[model.Name] = model
+	}
+
+	// Explicit configuration takes precedence over discovered defaults
+	if byName["gpt-4o"].InputCostPer1K != 0.005 {
+		t.Errorf("Expected configured pricing for gpt-4o, got %f", byName["gpt-4o"].InputCostPer1K)
+	}
+
+	llama, ok := byName["llama-3-70b"]
+	if !ok {
+		t.Fatal("Expected discovered model llama-3-70b")
+	}
+	if llama.InputCostPer1K != 0.001 || llama.MaxContextWindow != 8192 {
+		t.Errorf("Expected discovery defaults for llama-3-70b, got %+v", llama)
+	}
+
+	// Discovered models can be priced once indexed
+	if _, err := provider.EstimateCost(&types.ChatRequest{Model: "llama-3-70b", Messages: []types.Message{{Role: "user", Content: "Hi"}}}); err != nil {
+		t.Errorf("Expected discovered model to be priced: %v", err)
+	}
+
+	// A failed refresh keeps the last-known list
+	failing.Store(true)
+	if err := provider.DiscoverModels(context.Background()); err == nil {
+		t.Error("Expected discovery error from failing gateway")
+	}
+	if got := len(provider.GetCapabilities().SupportedModels); got != 2 {
+		t.Errorf("Expected last-known 2 models to be retained, got %d", got)
+	}
+}
+
 func createTestProvider(t *testing.T) *OpenAIProvider {
 	logger := logrus.New()
 	logger.SetLevel(logrus.WarnLevel)
@@ -259,6 +656,217 @@ func float32Ptr(f float32) *float32 {
 }
 
 // Benchmark tests
+func TestOpenAIProvider_CreateEmbedding(t *testing.T) {
+	var received map[string]interface{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","model":"text-embedding-3-small",
+			"data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]},{"object":"embedding","index":1,"embedding":[0.3,0.4]}],
+			"usage":{"prompt_tokens":8,"total_tokens":8}}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+
+	req := &types.EmbeddingRequest{
+		Model:      "text-embedding-3-small",
+		Input:      []interface{}{"hello", "world"},
+		Dimensions: 2,
+	}
+	resp, err := provider.CreateEmbedding(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateEmbedding failed: %v", err)
+	}
+
+	if inputs, ok := received["input"].([]interface{}); !ok || len(inputs) != 2 {
+		t.Errorf("Expected two inputs to be sent, got %v", received["input"])
+	}
+	if received["dimensions"] != float64(2) {
+		t.Errorf("Expected dimensions to be sent, got %v", received["dimensions"])
+	}
+	if len(resp.Data) != 2 || resp.Data[1].Embedding[0] != 0.3 {
+		t.Errorf("Unexpected embeddings: %+v", resp.Data)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 8 {
+		t.Errorf("Expected usage to be carried through, got %+v", resp.Usage)
+	}
+
+	// Built-in pricing applies to unconfigured embedding models
+	estimate, err := provider.EstimateEmbeddingCost(req)
+	if err != nil {
+		t.Fatalf("EstimateEmbeddingCost failed: %v", err)
+	}
+	if estimate.CostPer1KTokens != 0.00002 {
+		t.Errorf("Expected text-embedding-3-small pricing, got %f", estimate.CostPer1KTokens)
+	}
+
+	if _, err := provider.EstimateEmbeddingCost(&types.EmbeddingRequest{Model: "unknown-model", Input: "hi"}); err == nil {
+		t.Error("Expected error for unknown embedding model")
+	}
+}
+
+func TestOpenAIProvider_BatchRetrieveAndCancel(t *testing.T) {
+	var paths []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/batches/batch_123":
+			w.Write([]byte(`{"id":"batch_123","object":"batch","endpoint":"/v1/chat/completions","status":"completed",
+				"input_file_id":"file-in","output_file_id":"file-out","completion_window":"24h","created_at":1700000000,
+				"completed_at":1700003600,"request_counts":{"total":10,"completed":9,"failed":1},
+				"errors":{"object":"list","data":[{"code":"invalid_request","message":"bad line","line":4}]}}`))
+		case "/v1/batches/batch_123/cancel":
+			w.Write([]byte(`{"id":"batch_123","object":"batch","status":"cancelling","cancelling_at":1700000100,"request_counts":{"total":10}}`))
+		case "/v1/batches":
+			w.Write([]byte(`{"object":"list","data":[{"id":"batch_123","object":"batch","status":"completed"}],"first_id":"batch_123","last_id":"batch_123","has_more":false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"No batch found","type":"invalid_request_error"}}`))
+		}
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+	ctx := context.Background()
+
+	batch, err := provider.GetBatch(ctx, "batch_123")
+	if err != nil {
+		t.Fatalf("GetBatch failed: %v", err)
+	}
+	if batch.Status != "completed" || batch.OutputFileID != "file-out" || batch.CompletedAt != 1700003600 {
+		t.Errorf("Unexpected batch: %+v", batch)
+	}
+	if batch.RequestCounts.Completed != 9 || len(batch.Errors) != 1 || batch.Errors[0].Line != 4 {
+		t.Errorf("Expected request counts and errors to be converted, got %+v", batch)
+	}
+
+	batch, err = provider.CancelBatch(ctx, "batch_123")
+	if err != nil {
+		t.Fatalf("CancelBatch failed: %v", err)
+	}
+	if batch.Status != "cancelling" {
+		t.Errorf("Expected status cancelling, got %s", batch.Status)
+	}
+
+	list, err := provider.ListBatches(ctx)
+	if err != nil {
+		t.Fatalf("ListBatches failed: %v", err)
+	}
+	if list.Object != "list" || len(list.Data) != 1 || list.Data[0].ID != "batch_123" {
+		t.Errorf("Unexpected batch list: %+v", list)
+	}
+
+	expected := []string{"GET /v1/batches/batch_123", "POST /v1/batches/batch_123/cancel", "GET /v1/batches"}
+	if strings.Join(paths, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected calls %v, got %v", expected, paths)
+	}
+
+	// Unknown batches keep the upstream status for the caller
+	_, err = provider.GetBatch(ctx, "batch_missing")
+	if code := providers.StatusCode(err); code != http.StatusNotFound {
+		t.Errorf("Expected a 404 provider error, got %v", err)
+	}
+}
+
+func TestOpenAIProvider_APIKeyRotation(t *testing.T) {
+	var mu sync.Mutex
+	var used []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		used = append(used, key)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if key == "key-revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	send := func(provider *OpenAIProvider) error {
+		_, err := provider.ChatCompletion(context.Background(), &types.ChatRequest{
+			Model:    "gpt-4o",
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		return err
+	}
+
+	// Three healthy keys are used in turn
+	provider := NewOpenAIProvider(&OpenAIConfig{
+		APIKeys: []string{"key-a", "key-b", "key-c"},
+		BaseURL: gateway.URL + "/v1",
+	}, logger)
+	for i := 0; i < 6; i++ {
+		if err := send(provider); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	expected := []string{"key-a", "key-b", "key-c", "key-a", "key-b", "key-c"}
+	if strings.Join(used, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected round-robin key usage %v, got %v", expected, used)
+	}
+
+	// A revoked key is retried on the next key and then skipped
+	used = nil
+	provider = NewOpenAIProvider(&OpenAIConfig{
+		APIKey:  "key-a",
+		APIKeys: []string{"key-revoked", "key-b"},
+		BaseURL: gateway.URL + "/v1",
+	}, logger)
+	for i := 0; i < 4; i++ {
+		if err := send(provider); err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+	}
+	revokedUses := 0
+	for _, key := range used {
+		if key == "key-revoked" {
+			revokedUses++
+		}
+	}
+	if revokedUses != 1 {
+		t.Errorf("Expected revoked key to be tried once, got %d uses in %v", revokedUses, used)
+	}
+	if provider.keys.live() != 2 {
+		t.Errorf("Expected 2 live keys, got %d", provider.keys.live())
+	}
+
+	// With every key revoked the provider reports an error
+	provider = NewOpenAIProvider(&OpenAIConfig{APIKey: "key-revoked", BaseURL: gateway.URL + "/v1"}, logger)
+	if err := send(provider); err == nil {
+		t.Error("Expected error when the only key is revoked")
+	}
+	if err := send(provider); err == nil || !strings.Contains(err.Error(), "no valid openai api keys") {
+		t.Errorf("Expected no-keys error, got %v", err)
+	}
+}
+
+func TestOpenAIConfig_Keys(t *testing.T) {
+	config := &OpenAIConfig{APIKey: "key-a", APIKeys: []string{"key-b", "key-a", ""}}
+	keys := config.Keys()
+	if strings.Join(keys, ",") != "key-b,key-a" {
+		t.Errorf("Expected deduplicated keys [key-b key-a], got %v", keys)
+	}
+}
+
 func BenchmarkOpenAIProvider_EstimateCost(b *testing.B) {
 	provider := createTestProvider(&testing.T{})
 	req := &types.ChatRequest{
@@ -288,4 +896,101 @@ func BenchmarkOpenAIProvider_ConvertRequest(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = provider.convertToOpenAIRequest(req)
 	}
-}
\ No newline at end of file
+}
+func TestOpenAIProvider_ChatCompletionTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracerProvider)
+	defer otel.SetTracerProvider(previous)
+
+	var traceparent string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "router.route")
+	_, err := provider.ChatCompletion(ctx, &types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	parent.End()
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	var span *tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "openai.chat_completion" {
+			span = &s
+		}
+	}
+	if span == nil {
+		t.Fatal("Expected an openai.chat_completion span")
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Provider span should be a child of the routing span")
+	}
+
+	// The outgoing request carries the provider span's context
+	want := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("Expected traceparent %s, got %q", want, traceparent)
+	}
+}
+
+func TestOpenAIProvider_StreamCompletionUsage(t *testing.T) {
+	var includeUsage atomic.Bool
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		includeUsage.Store(body.StreamOptions.IncludeUsage)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer gateway.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	provider := NewOpenAIProvider(&OpenAIConfig{APIKey: "test-api-key", BaseURL: gateway.URL + "/v1"}, logger)
+
+	chunks, err := provider.StreamCompletion(context.Background(), &types.ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("StreamCompletion failed: %v", err)
+	}
+
+	var usage *types.Usage
+	for chunk := range chunks {
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if !includeUsage.Load() {
+		t.Error("Expected stream_options.include_usage to be requested")
+	}
+	if usage == nil {
+		t.Fatal("Expected the final chunk to carry usage")
+	}
+	if usage.PromptTokens != 9 || usage.CompletionTokens != 2 || usage.TotalTokens != 11 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+}
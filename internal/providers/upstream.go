@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultUpstreamHeaders are the provider response headers worth passing on
+// to clients: rate-limit budgets and the upstream request ID
+var DefaultUpstreamHeaders = []string{
+	"x-request-id",
+	"x-ratelimit-remaining-requests",
+	"x-ratelimit-remaining-tokens",
+	"request-id",
+	"anthropic-ratelimit-requests-remaining",
+	"anthropic-ratelimit-tokens-remaining",
+}
+
+type headerCaptureKey struct{}
+
+// HeaderCapture collects allowlisted headers from the provider responses made
+// with a context. Each response replaces the headers of the one before, so
+// after retries it holds the last attempt's headers.
+type HeaderCapture struct {
+	mu        sync.Mutex
+	allowlist []string
+	headers   map[string]string
+}
+
+// WithHeaderCapture returns a context whose provider responses are captured
+// into the returned HeaderCapture. Header names are matched case-insensitively.
+func WithHeaderCapture(ctx context.Context, allowlist []string) (context.Context, *HeaderCapture) {
+	capture := &HeaderCapture{allowlist: make([]string, 0, len(allowlist))}
+	for _, name := range allowlist {
+		capture.allowlist = append(capture.allowlist, strings.ToLower(name))
+	}
+	return context.WithValue(ctx, headerCaptureKey{}, capture), capture
+}
+
+// Headers returns the captured headers keyed by lower-case name, or nil when
+// none were seen
+func (c *HeaderCapture) Headers() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(c.headers))
+	for name, value := range c.headers {
+		headers[name] = value
+	}
+	return headers
+}
+
+// record replaces the captured headers with the allowlisted ones in header
+func (c *HeaderCapture) record(header http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.headers = make(map[string]string)
+	for _, name := range c.allowlist {
+		if value := header.Get(name); value != "" {
+			c.headers[name] = value
+		}
+	}
+}
+
+// CaptureTransport returns a RoundTripper that records response headers into
+// the request context's HeaderCapture, if any. A nil base uses
+// http.DefaultTransport.
+func CaptureTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &captureTransport{base: base}
+}
+
+type captureTransport struct {
+	base http.RoundTripper
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if capture := HeaderCaptureFrom(req.Context()); capture != nil {
+		capture.record(resp.Header)
+	}
+	return resp, nil
+}
+
+// HeaderCaptureFrom returns the HeaderCapture installed on ctx, or nil
+func HeaderCaptureFrom(ctx context.Context) *HeaderCapture {
+	capture, _ := ctx.Value(headerCaptureKey{}).(*HeaderCapture)
+	return capture
+}
@@ -0,0 +1,30 @@
+package routing
+
+// SetModelAliases configures logical model names clients can request in place
+// of provider model IDs, e.g. "fast" -> "gpt-4o-mini". Aliases resolve one
+// level only, so an alias naming another alias is not followed.
+func (r *Router) SetModelAliases(aliases map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelAliases = copyStringMap(aliases)
+}
+
+// resolveAlias returns the model an alias stands for. Names that aren't
+// aliases are returned unchanged.
+func (r *Router) resolveAlias(model string) (string, bool) {
+	target, ok := r.modelAliases[model]
+	if !ok || target == "" {
+		return model, false
+	}
+	return target, true
+}
+
+// copyStringMap copies a mapping so later changes by the caller don't leak in
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
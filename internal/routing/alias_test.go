@@ -0,0 +1,106 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createAliasRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", &flakyProvider{})
+	router.RegisterProvider("anthropic", &flakyProvider{})
+	router.SetModelAliases(map[string]string{
+		"fast":        "gpt-4o-mini",
+		"smart":       "claude-3-5-sonnet-20241022",
+		"gpt-4o-mini": "gpt-4o", // an alias target that is itself aliased
+	})
+	// Suppress background health checks so tests control health
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func aliasRequest(model string) *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:    model,
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	}
+}
+
+func TestRouter_Route_ResolvesModelAlias(t *testing.T) {
+	router := createAliasRouter(t)
+
+	tests := []struct {
+		model            string
+		expectedModel    string
+		expectedProvider string
+	}{
+		{model: "fast", expectedModel: "gpt-4o-mini", expectedProvider: "openai"},
+		{model: "smart", expectedModel: "claude-3-5-sonnet-20241022", expectedProvider: "anthropic"},
+	}
+
+	for _, tt := range tests {
+		req := aliasRequest(tt.model)
+		metadata, _, err := router.Route(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Routing %s failed: %v", tt.model, err)
+		}
+		if req.Model != tt.expectedModel {
+			t.Errorf("Expected %s to resolve to %s, got %s", tt.model, tt.expectedModel, req.Model)
+		}
+		if metadata.Provider != tt.expectedProvider {
+			t.Errorf("Expected %s to route to %s, got %s", tt.model, tt.expectedProvider, metadata.Provider)
+		}
+	}
+}
+
+func TestRouter_Route_AliasResolvesOneLevel(t *testing.T) {
+	router := createAliasRouter(t)
+
+	// "fast" targets gpt-4o-mini, which is also an alias; only one hop is taken
+	req := aliasRequest("fast")
+	if _, _, err := router.Route(context.Background(), req); err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if req.Model != "gpt-4o-mini" {
+		t.Errorf("Expected fast to resolve to gpt-4o-mini only, got %s", req.Model)
+	}
+
+	// Unknown names pass through unchanged
+	req = aliasRequest("claude-3-haiku-20240307")
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if req.Model != "claude-3-haiku-20240307" || metadata.ModelAlias != "" {
+		t.Errorf("Expected unknown model to pass through, got model %s alias %q", req.Model, metadata.ModelAlias)
+	}
+}
+
+func TestRouter_Route_AliasMetadata(t *testing.T) {
+	router := createAliasRouter(t)
+
+	metadata, _, err := router.Route(context.Background(), aliasRequest("smart"))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+
+	if metadata.ModelAlias != "smart" {
+		t.Errorf("Expected model alias 'smart', got %q", metadata.ModelAlias)
+	}
+	if metadata.Model != "claude-3-5-sonnet-20241022" {
+		t.Errorf("Expected resolved model in metadata, got %s", metadata.Model)
+	}
+
+	found := false
+	for _, reason := range metadata.RoutingReason {
+		if reason == "Resolved model alias smart to claude-3-5-sonnet-20241022" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected alias resolution in routing reasons, got %v", metadata.RoutingReason)
+	}
+}
@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// DefaultBalancedAlpha weighs cost and latency equally in balanced routing
+const DefaultBalancedAlpha = 0.5
+
+// SetBalancedAlpha sets the weight of cost against latency for balanced
+// routing: 1 routes purely on cost, 0 purely on latency. Values outside
+// [0, 1] are clamped.
+func (r *Router) SetBalancedAlpha(alpha float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.balancedAlpha = min(max(alpha, 0), 1)
+}
+
+// routeBalanced scores each candidate as alpha*cost + (1-alpha)*latency,
+// with both terms min-max normalized across the candidates so neither unit
+// dominates, and selects the lowest score. Latency comes from the measured
+// moving average, falling back to static estimates.
+func (r *Router) routeBalanced(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
+	candidates := r.getHealthyProviders()
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoHealthyProviders
+	}
+
+	// Filter providers by feature requirements
+	candidates = r.filterByFeatures(candidates, req)
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no providers support required features")
+	}
+
+	costComparison := make(map[string]float64, len(candidates))
+	performanceComparison := make(map[string]time.Duration, len(candidates))
+	var scored []string
+	for _, name := range candidates {
+		costEst, err := r.providers[name].EstimateCost(req)
+		if err != nil {
+			r.logger.WithError(err).Warnf("Failed to estimate cost for %s", name)
+			continue
+		}
+		costComparison[name] = costEst.TotalCost
+		performanceComparison[name] = r.estimateLatency(name)
+		scored = append(scored, name)
+	}
+
+	if len(scored) == 0 {
+		return nil, nil, fmt.Errorf("could not estimate costs for any provider")
+	}
+
+	alpha := r.balancedAlpha
+	scores := balancedScores(alpha, costComparison, performanceComparison)
+
+	sort.Slice(scored, func(i, j int) bool {
+		if scores[scored[i]] != scores[scored[j]] {
+			return scores[scored[i]] < scores[scored[j]]
+		}
+		return scored[i] < scored[j]
+	})
+	selected := scored[0]
+	provider := r.providers[selected]
+
+	reasoning := []string{
+		fmt.Sprintf("Balanced routing selected %s (score %.3f, alpha %.2f)", selected, scores[selected], alpha),
+		fmt.Sprintf("Estimated cost: $%.6f, estimated latency: %s", costComparison[selected], performanceComparison[selected]),
+	}
+
+	routingContext := r.buildRoutingContextWithCosts("balanced", req, scored, costComparison)
+	routingContext.PerformanceComparison = performanceComparison
+	routingContext.ScoreComparison = scores
+
+	decision := &RoutingDecision{
+		SelectedProvider:     selected,
+		Reasoning:            reasoning,
+		EstimatedCost:        costComparison[selected],
+		EstimatedLatency:     performanceComparison[selected],
+		FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+		FallbackChain:        r.buildFallbackChain(selected, req),
+		RoutingContext:       routingContext,
+	}
+
+	return decision, provider, nil
+}
+
+// balancedScores returns each provider's weighted score. A term that is the
+// same for every provider normalizes to zero and doesn't affect the ranking.
+func balancedScores(alpha float64, costs map[string]float64, latencies map[string]time.Duration) map[string]float64 {
+	minCost, maxCost := 0.0, 0.0
+	var minLatency, maxLatency time.Duration
+	first := true
+	for name, cost := range costs {
+		latency := latencies[name]
+		if first {
+			minCost, maxCost, minLatency, maxLatency = cost, cost, latency, latency
+			first = false
+			continue
+		}
+		minCost, maxCost = min(minCost, cost), max(maxCost, cost)
+		minLatency, maxLatency = min(minLatency, latency), max(maxLatency, latency)
+	}
+
+	scores := make(map[string]float64, len(costs))
+	for name, cost := range costs {
+		normCost := 0.0
+		if maxCost > minCost {
+			normCost = (cost - minCost) / (maxCost - minCost)
+		}
+		normLatency := 0.0
+		if maxLatency > minLatency {
+			normLatency = float64(latencies[name]-minLatency) / float64(maxLatency-minLatency)
+		}
+		scores[name] = alpha*normCost + (1-alpha)*normLatency
+	}
+	return scores
+}
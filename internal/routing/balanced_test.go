@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createBalancedRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("cheap-slow", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("fast-pricey", &pricedProvider{cost: 0.10})
+	router.RecordLatency("cheap-slow", 3*time.Second)
+	router.RecordLatency("fast-pricey", 400*time.Millisecond)
+	// Suppress background health checks so tests control health
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func balancedRequest() *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:       "test-model",
+		Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+		OptimizeFor: types.OptimizeBalanced,
+	}
+}
+
+func TestRouter_Route_BalancedAlphaFlipsSelection(t *testing.T) {
+	router := createBalancedRouter(t)
+
+	tests := []struct {
+		alpha    float64
+		expected string
+	}{
+		{alpha: 1, expected: "cheap-slow"},
+		{alpha: 0.9, expected: "cheap-slow"},
+		{alpha: 0.1, expected: "fast-pricey"},
+		{alpha: 0, expected: "fast-pricey"},
+	}
+
+	for _, tt := range tests {
+		router.SetBalancedAlpha(tt.alpha)
+
+		metadata, _, err := router.Route(context.Background(), balancedRequest())
+		if err != nil {
+			t.Fatalf("Routing failed: %v", err)
+		}
+		if metadata.Provider != tt.expected {
+			t.Errorf("alpha %.1f: expected %s, got %s", tt.alpha, tt.expected, metadata.Provider)
+		}
+	}
+}
+
+func TestRouter_RouteBalanced_RoutingContext(t *testing.T) {
+	router := createBalancedRouter(t)
+	router.SetBalancedAlpha(0.9)
+
+	decision, _, err := router.routeBalanced(context.Background(), balancedRequest())
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+
+	routingContext := decision.RoutingContext
+	if routingContext.Strategy != "balanced" {
+		t.Errorf("Expected strategy 'balanced', got %s", routingContext.Strategy)
+	}
+	if routingContext.CostComparison["fast-pricey"] != 0.10 {
+		t.Errorf("Expected cost comparison for fast-pricey, got %v", routingContext.CostComparison)
+	}
+	if routingContext.PerformanceComparison["cheap-slow"] != 3*time.Second {
+		t.Errorf("Expected performance comparison for cheap-slow, got %v", routingContext.PerformanceComparison)
+	}
+
+	// Cheapest has normalized cost 0 and slowest latency 1; fastest the reverse
+	scores := routingContext.ScoreComparison
+	if diff := scores["cheap-slow"] - 0.1; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected cheap-slow score 0.1, got %v", scores["cheap-slow"])
+	}
+	if diff := scores["fast-pricey"] - 0.9; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected fast-pricey score 0.9, got %v", scores["fast-pricey"])
+	}
+}
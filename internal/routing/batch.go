@@ -0,0 +1,35 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// RouteBatch selects the provider for batch operations: the first healthy
+// provider, in registration order, with a batch API. Batches live with the
+// provider that created them, so every batch call routes the same way.
+func (r *Router) RouteBatch(ctx context.Context) (string, providers.BatchProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Update health status if needed
+	r.checkHealthIfDue()
+
+	for _, name := range r.providerNames {
+		provider, ok := r.providers[name].(providers.BatchProvider)
+		if !ok || !provider.SupportsBatch() || !r.isProviderHealthy(name) {
+			continue
+		}
+
+		// Enforce the per-provider rate limit
+		if err := r.checkLimit(ctx, security.ProviderRateLimitKey(name)); err != nil {
+			return "", nil, err
+		}
+		return name, provider, nil
+	}
+
+	return "", nil, fmt.Errorf("%w that support batch processing", ErrNoHealthyProviders)
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// BudgetError is returned when no provider can serve a request within its
+// cost budget
+type BudgetError struct {
+	Budget   float64
+	Rejected map[string]float64 // Estimated cost of each rejected provider
+}
+
+func (e *BudgetError) Error() string {
+	names := make([]string, 0, len(e.Rejected))
+	for name := range e.Rejected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rejected := make([]string, 0, len(names))
+	for _, name := range names {
+		rejected = append(rejected, fmt.Sprintf("%s $%.6f", name, e.Rejected[name]))
+	}
+	return fmt.Sprintf("no provider within max_cost budget of $%.6f (rejected: %s)", e.Budget, strings.Join(rejected, ", "))
+}
+
+// SetMaxCostThreshold sets a global cap on the estimated cost of a single
+// request. It applies on top of each request's max_cost; zero disables it.
+func (r *Router) SetMaxCostThreshold(threshold float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxCostThreshold = threshold
+}
+
+// costBudget returns the effective cost cap for a request: the lower of its
+// max_cost and the global threshold
+func (r *Router) costBudget(req *types.ChatRequest) (float64, bool) {
+	budget, capped := r.maxCostThreshold, r.maxCostThreshold > 0
+	if req.MaxCost != nil && (!capped || *req.MaxCost < budget) {
+		budget, capped = *req.MaxCost, true
+	}
+	return budget, capped
+}
+
+// withinBudget reports whether a provider's estimated cost fits the request's
+// budget, returning the estimate. Providers that can't estimate the request
+// are let through, as there is nothing to compare.
+func (r *Router) withinBudget(name string, provider providers.LLMProvider, req *types.ChatRequest) (bool, float64) {
+	budget, capped := r.costBudget(req)
+	if !capped {
+		return true, 0
+	}
+
+	estimate, err := provider.EstimateCost(req)
+	if err != nil {
+		r.logger.WithError(err).WithField("provider", name).Debug("Cannot estimate cost against budget")
+		return true, 0
+	}
+	return estimate.TotalCost <= budget, estimate.TotalCost
+}
+
+// enforceBudget keeps a routing decision within the request's cost budget.
+// When the selected provider's estimate exceeds it, the cheapest other
+// candidate that fits is selected instead. The returned map holds the
+// estimates of every provider rejected for cost.
+func (r *Router) enforceBudget(req *types.ChatRequest, decision *RoutingDecision, provider providers.LLMProvider) (*RoutingDecision, providers.LLMProvider, map[string]float64, error) {
+	fits, cost := r.withinBudget(decision.SelectedProvider, provider, req)
+	if fits {
+		return decision, provider, nil, nil
+	}
+	budget, _ := r.costBudget(req)
+	rejected := map[string]float64{decision.SelectedProvider: cost}
+
+	type candidateWithCost struct {
+		name     string
+		provider providers.LLMProvider
+		cost     float64
+	}
+
+	var affordable []candidateWithCost
+	for _, name := range r.filterByFeatures(r.getHealthyProviders(), req) {
+		if name == decision.SelectedProvider {
+			continue
+		}
+
+		candidate := r.providers[name]
+		estimate, err := candidate.EstimateCost(req)
+		if err != nil {
+			// Typically the provider doesn't serve the requested model
+			continue
+		}
+		if estimate.TotalCost > budget {
+			rejected[name] = estimate.TotalCost
+			continue
+		}
+		affordable = append(affordable, candidateWithCost{name: name, provider: candidate, cost: estimate.TotalCost})
+	}
+
+	if len(affordable) == 0 {
+		return nil, nil, rejected, &BudgetError{Budget: budget, Rejected: rejected}
+	}
+
+	sort.Slice(affordable, func(i, j int) bool {
+		return affordable[i].cost < affordable[j].cost
+	})
+	selected := affordable[0]
+
+	decision.Reasoning = append(decision.Reasoning, fmt.Sprintf(
+		"%s estimate $%.6f exceeds budget $%.6f, selected %s at $%.6f",
+		decision.SelectedProvider, cost, budget, selected.name, selected.cost))
+	decision.SelectedProvider = selected.name
+	decision.EstimatedCost = selected.cost
+	decision.EstimatedLatency = r.estimateLatency(selected.name)
+	decision.FeatureCompatibility = r.checkFeatureCompatibility(selected.provider, req)
+	decision.FallbackChain = r.buildFallbackChain(selected.name, req)
+
+	return decision, selected.provider, rejected, nil
+}
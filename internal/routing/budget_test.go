@@ -0,0 +1,175 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// pricedProvider estimates every request at a fixed cost
+type pricedProvider struct {
+	flakyProvider
+	cost float64
+}
+
+func (p *pricedProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	return &types.CostEstimate{TotalCost: p.cost}, nil
+}
+
+func createBudgetRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("cheap", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("mid", &pricedProvider{cost: 0.05})
+	router.RegisterProvider("premium", &pricedProvider{cost: 0.20})
+	return router
+}
+
+func budgetRequest(maxCost *float64) *types.ChatRequest {
+	return &types.ChatRequest{
+		ID:          "budget-request",
+		Model:       "test-model",
+		Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+		OptimizeFor: types.OptimizePerformance,
+		MaxCost:     maxCost,
+	}
+}
+
+func TestRouter_Route_MaxCostSelectsAffordableProvider(t *testing.T) {
+	router := createBudgetRouter(t)
+	router.RecordLatency("premium", 10*time.Millisecond)
+	router.RecordLatency("mid", 50*time.Millisecond)
+	router.RecordLatency("cheap", 200*time.Millisecond)
+
+	maxCost := 0.10
+	metadata, _, err := router.Route(context.Background(), budgetRequest(&maxCost))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+
+	// Performance routing prefers premium, which is over budget, so the
+	// cheapest candidate that fits replaces it
+	if metadata.Provider != "cheap" {
+		t.Errorf("Expected cheapest affordable provider, got %s", metadata.Provider)
+	}
+	if metadata.EstimatedCost != 0.01 {
+		t.Errorf("Expected estimated cost 0.01, got %f", metadata.EstimatedCost)
+	}
+	if cost, ok := metadata.OverBudgetProviders["premium"]; !ok || cost != 0.20 {
+		t.Errorf("Expected premium recorded as over budget, got %v", metadata.OverBudgetProviders)
+	}
+}
+
+func TestRouter_Route_MaxCostNoProviderFits(t *testing.T) {
+	router := createBudgetRouter(t)
+
+	maxCost := 0.001
+	_, _, err := router.Route(context.Background(), budgetRequest(&maxCost))
+
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected BudgetError, got %v", err)
+	}
+	if len(budgetErr.Rejected) != 3 {
+		t.Errorf("Expected all 3 providers rejected, got %v", budgetErr.Rejected)
+	}
+	if !strings.Contains(err.Error(), "no provider within max_cost budget") || !strings.Contains(err.Error(), "premium $0.200000") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestRouter_Route_GlobalMaxCostThreshold(t *testing.T) {
+	router := createBudgetRouter(t)
+	router.SetMaxCostThreshold(0.03)
+	router.RecordLatency("premium", 10*time.Millisecond)
+	router.RecordLatency("mid", 20*time.Millisecond)
+	router.RecordLatency("cheap", 200*time.Millisecond)
+
+	// No per-request cap: the global threshold still applies
+	metadata, _, err := router.Route(context.Background(), budgetRequest(nil))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "cheap" {
+		t.Errorf("Expected global threshold to force cheap provider, got %s", metadata.Provider)
+	}
+	if len(metadata.OverBudgetProviders) != 2 {
+		t.Errorf("Expected premium and mid over budget, got %v", metadata.OverBudgetProviders)
+	}
+
+	// A looser per-request cap doesn't lift the global threshold
+	maxCost := 1.0
+	metadata, _, err = router.Route(context.Background(), budgetRequest(&maxCost))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "cheap" {
+		t.Errorf("Expected global threshold to cap per-request budget, got %s", metadata.Provider)
+	}
+}
+
+func TestRouter_Route_WithinBudgetUnchanged(t *testing.T) {
+	router := createBudgetRouter(t)
+	router.RecordLatency("premium", 10*time.Millisecond)
+	router.RecordLatency("cheap", 200*time.Millisecond)
+	router.RecordLatency("mid", 200*time.Millisecond)
+
+	maxCost := 0.50
+	metadata, _, err := router.Route(context.Background(), budgetRequest(&maxCost))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "premium" {
+		t.Errorf("Expected strategy choice within budget to stand, got %s", metadata.Provider)
+	}
+	if len(metadata.OverBudgetProviders) != 0 {
+		t.Errorf("Expected no over-budget providers, got %v", metadata.OverBudgetProviders)
+	}
+}
+
+func TestRouter_Route_SpendLimits(t *testing.T) {
+	router := createBudgetRouter(t)
+	store := accounting.NewMemoryStore(0)
+	now := time.Now().UTC()
+	for _, record := range []accounting.UsageRecord{
+		{RequestID: "1", UserID: "under", CostUSD: 2.00, Timestamp: now},
+		{RequestID: "2", UserID: "over", CostUSD: 4.00, Timestamp: now},
+		{RequestID: "3", UserID: "over", CostUSD: 1.50, Timestamp: now},
+	} {
+		if err := store.Record(record); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	router.SetSpendLimiter(accounting.NewSpendLimiter(store, accounting.SpendLimits{
+		"under": {DailyUSD: 5.00},
+		"over":  {DailyUSD: 5.00, MonthlyUSD: 100.00},
+	}))
+
+	req := budgetRequest(nil)
+	req.UserID = "under"
+	if _, _, err := router.Route(context.Background(), req); err != nil {
+		t.Fatalf("Expected user under cap to be routed, got %v", err)
+	}
+
+	req = budgetRequest(nil)
+	req.UserID = "over"
+	_, _, err := router.Route(context.Background(), req)
+	var spendErr *accounting.SpendLimitError
+	if !errors.As(err, &spendErr) {
+		t.Fatalf("Expected SpendLimitError, got %v", err)
+	}
+	if spendErr.Period != accounting.PeriodDaily {
+		t.Errorf("Expected daily limit, got %s", spendErr.Period)
+	}
+	if spendErr.SpentUSD != 5.50 {
+		t.Errorf("Expected $5.50 spent, got %v", spendErr.SpentUSD)
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if !spendErr.ResetAt.Equal(midnight) {
+		t.Errorf("Expected reset at %v, got %v", midnight, spendErr.ResetAt)
+	}
+}
@@ -0,0 +1,174 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// BulkheadFullError is returned when a provider is already serving its
+// maximum number of concurrent requests. The provider is skipped rather than
+// waited on, so callers fall back to another one.
+type BulkheadFullError struct {
+	Provider string
+	Limit    int
+}
+
+func (e *BulkheadFullError) Error() string {
+	return fmt.Sprintf("provider %s unavailable: bulkhead full with %d requests in flight", e.Provider, e.Limit)
+}
+
+// bulkhead is a non-blocking semaphore isolating one provider's in-flight
+// requests, so a slow provider can't take every worker
+type bulkhead struct {
+	slots    chan struct{} // nil when unlimited
+	inFlight atomic.Int64
+}
+
+// newBulkhead creates a bulkhead admitting limit requests at once; zero or
+// less only counts requests
+func newBulkhead(limit int) *bulkhead {
+	b := &bulkhead{}
+	if limit > 0 {
+		b.slots = make(chan struct{}, limit)
+	}
+	return b
+}
+
+// full reports whether every slot is taken
+func (b *bulkhead) full() bool {
+	return b.slots != nil && len(b.slots) >= cap(b.slots)
+}
+
+// setBulkheads applies per-provider concurrency limits. Bulkheads whose
+// limit is unchanged are kept so in-flight requests stay counted. Callers
+// hold mu.
+func (r *Router) setBulkheads(limits map[string]int) {
+	bulkheads := make(map[string]*bulkhead, len(r.providerNames))
+	for _, name := range r.providerNames {
+		limit := limits[name]
+		if existing, ok := r.bulkheads[name]; ok && cap(existing.slots) == max(limit, 0) {
+			bulkheads[name] = existing
+			continue
+		}
+		bulkheads[name] = newBulkhead(limit)
+	}
+	r.bulkheads = bulkheads
+	r.providerConcurrency = copyIntMap(limits)
+}
+
+// isBulkheadFull reports whether a provider has no free slot. Callers hold mu.
+func (r *Router) isBulkheadFull(name string) bool {
+	b, exists := r.bulkheads[name]
+	return exists && b.full()
+}
+
+// AcquireProvider reserves one of a provider's concurrent request slots,
+// returning a BulkheadFullError when all are taken. The returned release
+// func must be called when the provider call finishes; further calls are
+// no-ops.
+func (r *Router) AcquireProvider(name string) (func(), error) {
+	r.mu.RLock()
+	b, exists := r.bulkheads[name]
+	r.mu.RUnlock()
+	if !exists {
+		return func() {}, nil
+	}
+
+	if b.slots != nil {
+		select {
+		case b.slots <- struct{}{}:
+		default:
+			return nil, &BulkheadFullError{Provider: name, Limit: cap(b.slots)}
+		}
+	}
+	b.inFlight.Add(1)
+
+	var released atomic.Bool
+	return func() {
+		if !released.CompareAndSwap(false, true) {
+			return
+		}
+		b.inFlight.Add(-1)
+		if b.slots != nil {
+			<-b.slots
+		}
+	}, nil
+}
+
+// ProviderInFlight returns the number of requests each provider is serving
+func (r *Router) ProviderInFlight() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int, len(r.bulkheads))
+	for name, b := range r.bulkheads {
+		counts[name] = int(b.inFlight.Load())
+	}
+	return counts
+}
+
+// StreamCompletion starts a streaming completion on provider inside its
+// bulkhead. The slot is held until the stream's channel closes. Errors
+// ending the stream are classified like those starting it.
+func (r *Router) StreamCompletion(ctx context.Context, req *types.ChatRequest, providerName string, provider providers.LLMProvider) (<-chan *types.ChatChunk, error) {
+	if err := validateStopSequences(providerName, provider, req.Stop); err != nil {
+		return nil, err
+	}
+	release, err := r.AcquireProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := provider.StreamCompletion(ctx, req)
+	if err != nil {
+		release()
+		r.checkCredentials(ctx, providerName, provider, err)
+		return nil, upstreamError(err)
+	}
+
+	out := make(chan *types.ChatChunk)
+	go func() {
+		defer close(out)
+		defer release()
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				chunk = &types.ChatChunk{Err: upstreamError(chunk.Err)}
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// Drain so the provider's goroutine can finish
+				for range chunks {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// copyIntMap returns a copy of m
+func copyIntMap(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// chatCompletion runs one completion attempt on provider inside its bulkhead
+func (r *Router) chatCompletion(ctx context.Context, req *types.ChatRequest, providerName string, provider providers.LLMProvider) (*types.ChatResponse, error) {
+	if err := validateStopSequences(providerName, provider, req.Stop); err != nil {
+		return nil, err
+	}
+	release, err := r.AcquireProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return provider.ChatCompletion(ctx, req)
+}
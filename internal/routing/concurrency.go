@@ -0,0 +1,129 @@
+package routing
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// overloadRetryAfter is the Retry-After hint given to shed requests
+const overloadRetryAfter = time.Second
+
+// Shares of the concurrency limit lower priorities may fill, in percent.
+// The rest is held back for higher priorities, so low priority requests are
+// shed first as the router fills up and high priority ones get the last slots.
+const (
+	normalPriorityShare = 90
+	lowPriorityShare    = 70
+)
+
+// OverloadedError is returned when the router is already serving its maximum
+// number of concurrent requests. Requests are shed immediately rather than
+// queued so bursts fail fast.
+type OverloadedError struct {
+	Limit      int // slots available to the request's priority
+	Priority   types.Priority
+	RetryAfter time.Duration
+}
+
+func (e *OverloadedError) Error() string {
+	return fmt.Sprintf("router overloaded: %d concurrent requests in flight", e.Limit)
+}
+
+// concurrencyLimiter is a non-blocking counting semaphore tracking
+// in-flight requests overall and per priority
+type concurrencyLimiter struct {
+	limit      int // 0 when unlimited
+	inFlight   int64
+	byPriority map[types.Priority]*int64
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	limiter := &concurrencyLimiter{limit: max(limit, 0), byPriority: make(map[types.Priority]*int64, len(types.Priorities))}
+	for _, priority := range types.Priorities {
+		limiter.byPriority[priority] = new(int64)
+	}
+	return limiter
+}
+
+// limitFor returns how many requests may be in flight when a request of the
+// given priority is admitted, 0 if unlimited
+func (l *concurrencyLimiter) limitFor(priority types.Priority) int {
+	switch {
+	case l.limit == 0:
+		return 0
+	case priority == types.PriorityLow:
+		return max(l.limit*lowPriorityShare/100, 1)
+	case priority == types.PriorityNormal:
+		return l.limit - l.limit*(100-normalPriorityShare)/100
+	default:
+		return l.limit
+	}
+}
+
+// SetMaxConcurrentRequests caps the number of requests served at once and
+// must be called before serving traffic. Zero removes the cap; in-flight
+// requests are still counted.
+func (r *Router) SetMaxConcurrentRequests(limit int) {
+	r.concurrency = newConcurrencyLimiter(limit)
+}
+
+// Acquire reserves a request slot at normal priority; see AcquirePriority
+func (r *Router) Acquire() (func(), error) {
+	return r.AcquirePriority(types.PriorityNormal)
+}
+
+// AcquirePriority reserves a request slot, returning an OverloadedError when
+// the slots open to the priority are taken. The returned release func must
+// be called when the request finishes, whatever its outcome; further calls
+// are no-ops.
+func (r *Router) AcquirePriority(priority types.Priority) (func(), error) {
+	limiter := r.concurrency
+	priority = priority.OrDefault()
+	counter, ok := limiter.byPriority[priority]
+	if !ok {
+		return nil, priority.Validate()
+	}
+
+	limit := int64(limiter.limitFor(priority))
+	for {
+		inFlight := atomic.LoadInt64(&limiter.inFlight)
+		if limit > 0 && inFlight >= limit {
+			return nil, &OverloadedError{Limit: int(limit), Priority: priority, RetryAfter: overloadRetryAfter}
+		}
+		if atomic.CompareAndSwapInt64(&limiter.inFlight, inFlight, inFlight+1) {
+			break
+		}
+	}
+	atomic.AddInt64(counter, 1)
+
+	var released int32
+	return func() {
+		if !atomic.CompareAndSwapInt32(&released, 0, 1) {
+			return
+		}
+		atomic.AddInt64(counter, -1)
+		atomic.AddInt64(&limiter.inFlight, -1)
+	}, nil
+}
+
+// InFlight returns the number of requests currently holding a slot
+func (r *Router) InFlight() int {
+	return int(atomic.LoadInt64(&r.concurrency.inFlight))
+}
+
+// InFlightByPriority returns the number of requests holding a slot per priority
+func (r *Router) InFlightByPriority() map[types.Priority]int {
+	counts := make(map[types.Priority]int, len(r.concurrency.byPriority))
+	for priority, counter := range r.concurrency.byPriority {
+		counts[priority] = int(atomic.LoadInt64(counter))
+	}
+	return counts
+}
+
+// MaxConcurrentRequests returns the configured concurrency cap, 0 if unlimited
+func (r *Router) MaxConcurrentRequests() int {
+	return r.concurrency.limit
+}
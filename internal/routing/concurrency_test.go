@@ -0,0 +1,188 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestRouter_Acquire_ShedsWhenSaturated(t *testing.T) {
+	router := createTestRouter(t)
+	router.SetMaxConcurrentRequests(2)
+
+	first, err := router.Acquire()
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring first slot: %v", err)
+	}
+	second, err := router.Acquire()
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring second slot: %v", err)
+	}
+
+	_, err = router.Acquire()
+	var overloaded *OverloadedError
+	if !errors.As(err, &overloaded) {
+		t.Fatalf("Expected OverloadedError when saturated, got %v", err)
+	}
+	if overloaded.Limit != 2 || overloaded.RetryAfter <= 0 {
+		t.Errorf("Unexpected overload details %+v", overloaded)
+	}
+	if router.InFlight() != 2 {
+		t.Errorf("Expected 2 in flight, got %d", router.InFlight())
+	}
+
+	// Releasing twice must not free an extra slot
+	first()
+	first()
+	if router.InFlight() != 1 {
+		t.Errorf("Expected 1 in flight after release, got %d", router.InFlight())
+	}
+	third, err := router.Acquire()
+	if err != nil {
+		t.Fatalf("Expected freed slot to be reusable, got %v", err)
+	}
+	if _, err := router.Acquire(); err == nil {
+		t.Error("Double release should not have freed a second slot")
+	}
+
+	second()
+	third()
+	if router.InFlight() != 0 {
+		t.Errorf("Expected nothing in flight, got %d", router.InFlight())
+	}
+}
+
+func TestRouter_Acquire_Unlimited(t *testing.T) {
+	router := createTestRouter(t)
+
+	releases := make([]func(), 0, 100)
+	for i := 0; i < 100; i++ {
+		release, err := router.Acquire()
+		if err != nil {
+			t.Fatalf("Unlimited router shed request %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+	if router.InFlight() != 100 || router.MaxConcurrentRequests() != 0 {
+		t.Errorf("Expected 100 in flight with no limit, got %d (limit %d)", router.InFlight(), router.MaxConcurrentRequests())
+	}
+	for _, release := range releases {
+		release()
+	}
+}
+
+func TestRouter_AcquirePriority_ShedsLowFirst(t *testing.T) {
+	router := createTestRouter(t)
+	router.SetMaxConcurrentRequests(10)
+
+	// Low priority requests may only fill 7 of the 10 slots
+	var releases []func()
+	for i := 0; i < 7; i++ {
+		release, err := router.AcquirePriority(types.PriorityLow)
+		if err != nil {
+			t.Fatalf("Unexpected error admitting low priority request %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+	_, err := router.AcquirePriority(types.PriorityLow)
+	var overloaded *OverloadedError
+	if !errors.As(err, &overloaded) || overloaded.Priority != types.PriorityLow || overloaded.Limit != 7 {
+		t.Fatalf("Expected low priority request shed at 7, got %v", err)
+	}
+
+	// Normal priority fills up to 9, leaving the last slot to high priority
+	for i := 0; i < 2; i++ {
+		release, err := router.AcquirePriority(types.PriorityNormal)
+		if err != nil {
+			t.Fatalf("Unexpected error admitting normal priority request %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+	if _, err := router.Acquire(); err == nil {
+		t.Error("Normal priority request should not take the reserved slot")
+	}
+	release, err := router.AcquirePriority(types.PriorityHigh)
+	if err != nil {
+		t.Fatalf("Expected high priority request admitted, got %v", err)
+	}
+	releases = append(releases, release)
+	if _, err := router.AcquirePriority(types.PriorityHigh); err == nil {
+		t.Error("High priority request should not exceed the limit")
+	}
+
+	counts := router.InFlightByPriority()
+	if counts[types.PriorityLow] != 7 || counts[types.PriorityNormal] != 2 || counts[types.PriorityHigh] != 1 {
+		t.Errorf("Unexpected per-priority counts %v", counts)
+	}
+	for _, release := range releases {
+		release()
+	}
+	if router.InFlight() != 0 || router.InFlightByPriority()[types.PriorityLow] != 0 {
+		t.Errorf("Expected nothing in flight after release, got %d", router.InFlight())
+	}
+
+	if _, err := router.AcquirePriority("urgent"); err == nil {
+		t.Error("Expected an error for an unknown priority")
+	}
+}
+
+func TestRouter_ProviderBulkhead_FallsBackWhenFull(t *testing.T) {
+	router := createTestRouter(t)
+	cheap := &pricedProvider{cost: 0.01}
+	mid := &pricedProvider{cost: 0.05}
+	router.Reload(Settings{
+		DefaultStrategy:     RoutingStrategyCostOptimized,
+		ProviderConcurrency: map[string]int{"cheap": 1},
+	}, []NamedProvider{
+		{Name: "cheap", Provider: cheap},
+		{Name: "mid", Provider: mid},
+	})
+	router.lastHealthCheck = time.Now()
+
+	metadata, _, err := router.Route(context.Background(), costRequest())
+	if err != nil || metadata.Provider != "cheap" {
+		t.Fatalf("Expected cheap with a free slot, got %v (err %v)", metadata, err)
+	}
+
+	// Saturate cheap's bulkhead; requests route to mid instead of waiting
+	release, err := router.AcquireProvider("cheap")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring cheap's slot: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		metadata, _, err = router.Route(context.Background(), costRequest())
+		if err != nil || metadata.Provider != "mid" {
+			t.Fatalf("Expected mid while cheap is full, got %v (err %v)", metadata, err)
+		}
+	}
+	if inFlight := router.ProviderInFlight(); inFlight["cheap"] != 1 || inFlight["mid"] != 0 {
+		t.Errorf("Unexpected in-flight counts %v", inFlight)
+	}
+
+	// A request that raced onto the full provider fails fast without retrying
+	req := costRequest()
+	req.RetryConfig = &types.RetryConfig{MaxAttempts: 3}
+	_, err = router.ExecuteCompletion(context.Background(), req, "cheap", cheap, &types.RouterMetadata{})
+	var full *BulkheadFullError
+	if !errors.As(err, &full) || full.Provider != "cheap" || full.Limit != 1 {
+		t.Fatalf("Expected BulkheadFullError, got %v", err)
+	}
+	if cheap.calls != 0 {
+		t.Errorf("Full provider should not have been called, got %d calls", cheap.calls)
+	}
+
+	release()
+	metadata, _, err = router.Route(context.Background(), costRequest())
+	if err != nil || metadata.Provider != "cheap" {
+		t.Fatalf("Expected cheap once its slot is free, got %v (err %v)", metadata, err)
+	}
+	if _, err := router.ExecuteCompletion(context.Background(), costRequest(), "cheap", cheap, &types.RouterMetadata{}); err != nil {
+		t.Fatalf("Unexpected error with a free slot: %v", err)
+	}
+	if inFlight := router.ProviderInFlight(); inFlight["cheap"] != 0 {
+		t.Errorf("Expected slot released after completion, got %v", inFlight)
+	}
+}
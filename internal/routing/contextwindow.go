@@ -0,0 +1,107 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ExecuteCompletion runs a chat completion against provider with retries, see
+// executeWithRetry. When it fails, provider is added to
+// metadata.FailedProviders. When the prompt overflows the model's context window and
+// the request enables fallback, it is retried on the provider's next larger
+// model of the same family that the context's model filter allows, see
+// WithModelFilter, until one fits or none is left. Successful
+// completions are mirrored to the shadow provider when one is configured.
+func (r *Router) ExecuteCompletion(ctx context.Context, req *types.ChatRequest, providerName string, provider providers.LLMProvider, metadata *types.RouterMetadata) (*types.ChatResponse, error) {
+	start := time.Now()
+	for {
+		resp, err := r.executeWithRetry(ctx, req, providerName, provider, metadata)
+		if err == nil {
+			r.shadowCompletion(req, providerName, resp, time.Since(start))
+			return resp, nil
+		}
+		if !providers.IsContextLengthError(err) || req.FallbackConfig == nil || !req.FallbackConfig.Enabled {
+			abandonProvider(metadata, providerName)
+			return nil, upstreamError(err)
+		}
+
+		larger, found := largerContextModel(ctx, provider, req.Model)
+		if !found {
+			abandonProvider(metadata, providerName)
+			return nil, upstreamError(err)
+		}
+		r.promoteForContext(req, providerName, larger, metadata)
+	}
+}
+
+// modelFilterKey marks a context with the models a request may be promoted to
+type modelFilterKey struct{}
+
+// WithModelFilter returns a context whose requests are only promoted to
+// models allowed reports true for, e.g. those the caller could request
+// directly
+func WithModelFilter(ctx context.Context, allowed func(model string) bool) context.Context {
+	return context.WithValue(ctx, modelFilterKey{}, allowed)
+}
+
+// modelAllowed reports whether ctx's model filter, if any, allows model
+func modelAllowed(ctx context.Context, model string) bool {
+	allowed, ok := ctx.Value(modelFilterKey{}).(func(string) bool)
+	return !ok || allowed(model)
+}
+
+// largerContextModel returns the provider's model of model's family with the
+// smallest context window larger than model's that ctx's model filter allows
+func largerContextModel(ctx context.Context, provider providers.LLMProvider, model string) (types.ModelInfo, bool) {
+	models := provider.GetCapabilities().SupportedModels
+
+	window := 0
+	for _, info := range models {
+		if info.Name == model || info.ProviderModelID == model {
+			window = info.MaxContextWindow
+			break
+		}
+	}
+
+	family := modelFamily(model)
+	var best types.ModelInfo
+	found := false
+	for _, info := range models {
+		if modelFamily(info.Name) != family || info.MaxContextWindow <= window {
+			continue
+		}
+		if !modelAllowed(ctx, info.Name) {
+			continue
+		}
+		if !found || info.MaxContextWindow < best.MaxContextWindow {
+			best, found = info, true
+		}
+	}
+	return best, found
+}
+
+// promoteForContext rewrites the request to a larger-context model and
+// records the substitution in metadata
+func (r *Router) promoteForContext(req *types.ChatRequest, providerName string, target types.ModelInfo, metadata *types.RouterMetadata) {
+	reason := fmt.Sprintf("Prompt exceeded the context window of %s, promoted to %s (%d tokens)", req.Model, target.Name, target.MaxContextWindow)
+	r.logger.WithFields(logrus.Fields{
+		"provider":       providerName,
+		"original_model": req.Model,
+		"model":          target.Name,
+	}).Warn(reason)
+
+	if metadata.OriginalModel == "" {
+		metadata.OriginalModel = req.Model
+	}
+	metadata.ModelSubstituted = true
+	metadata.ContextPromoted = true
+	metadata.Model = target.Name
+	metadata.RoutingReason = append(metadata.RoutingReason, reason)
+	req.Model = target.Name
+}
@@ -0,0 +1,63 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// AuthFailureHandler is told when a provider rejects the router's
+// credentials, e.g. to alert an operator that an API key was revoked
+type AuthFailureHandler func(ctx context.Context, provider string, err error)
+
+// SetAuthFailureHandler sets the handler told about rejected provider
+// credentials. nil removes it.
+func (r *Router) SetAuthFailureHandler(handler AuthFailureHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.authFailureHandler = handler
+}
+
+// checkCredentials takes a provider whose credentials err shows were rejected
+// out of rotation right away rather than at its next health check, so
+// requests stop failing on it. The handler is told once per outage: only
+// when the provider wasn't already unhealthy.
+func (r *Router) checkCredentials(ctx context.Context, name string, provider providers.LLMProvider, err error) {
+	if !providers.IsAuthenticationError(err) {
+		return
+	}
+
+	r.mu.Lock()
+	// Skip providers removed by a reload while the request ran
+	if r.providers[name] != provider {
+		r.mu.Unlock()
+		return
+	}
+	previous, exists := r.healthStatus[name]
+	alreadyDown := exists && previous.Status == "unhealthy"
+	status := &types.HealthStatus{
+		Status:       "unhealthy",
+		LastChecked:  time.Now().Unix(),
+		ErrorMessage: fmt.Sprintf("credentials rejected: %v", err),
+	}
+	if exists {
+		status.Maintenance = previous.Maintenance
+	}
+	r.healthStatus[name] = status
+	handler := r.authFailureHandler
+	r.mu.Unlock()
+
+	// The rejection counts as a check, so the next one waits its interval
+	r.healthCheckMu.Lock()
+	r.lastChecked[name] = time.Now()
+	r.healthCheckMu.Unlock()
+
+	r.logger.WithError(err).WithField("provider", name).Error("Provider rejected its credentials, marking it unhealthy")
+	if handler != nil && !alreadyDown {
+		handler(ctx, name, err)
+	}
+}
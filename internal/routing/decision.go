@@ -24,6 +24,9 @@ type RoutingDecision struct {
 	
 	// Additional routing context
 	RoutingContext RoutingContext `json:"routing_context"`
+	
+	// Originally requested model when an equivalent model was substituted
+	OriginalModel string `json:"original_model,omitempty"`
 }
 
 // RoutingContext contains additional context about the routing decision
@@ -48,4 +51,7 @@ type RoutingContext struct {
 	
 	// Performance comparison data  
 	PerformanceComparison map[string]time.Duration `json:"performance_comparison,omitempty"`
+	
+	// Weighted cost/latency scores for balanced routing (lower is better)
+	ScoreComparison map[string]float64 `json:"score_comparison,omitempty"`
 }
\ No newline at end of file
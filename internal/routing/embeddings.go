@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// embeddingModelPrefixes maps embedding model prefixes to their owning provider
+var embeddingModelPrefixes = map[string]string{
+	"text-embedding-": "openai",
+}
+
+// RouteEmbedding selects a provider for an embeddings request. Models with a
+// known prefix go to their owning provider; other models go to the cheapest
+// healthy provider that can price them. Providers without embeddings support
+// are never selected.
+func (r *Router) RouteEmbedding(ctx context.Context, req *types.EmbeddingRequest) (*types.RouterMetadata, providers.EmbeddingProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start := time.Now()
+
+	// Update health status if needed
+	r.checkHealthIfDue()
+
+	candidates := r.getEmbeddingProviders()
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("%w that support embeddings", ErrNoHealthyProviders)
+	}
+
+	for prefix, owner := range embeddingModelPrefixes {
+		if !strings.HasPrefix(req.Model, prefix) {
+			continue
+		}
+		if _, exists := r.providers[owner]; !exists {
+			return nil, nil, fmt.Errorf("%w for embedding model %s", ErrModelNotFound, req.Model)
+		}
+		provider, available := candidates[owner]
+		if !available {
+			return nil, nil, fmt.Errorf("%w: provider %s is not healthy or does not support embeddings", ErrNoHealthyProviders, owner)
+		}
+		candidates = map[string]providers.EmbeddingProvider{owner: provider}
+		break
+	}
+
+	var selected string
+	var cost *types.CostEstimate
+	for _, name := range r.providerNames {
+		provider, exists := candidates[name]
+		if !exists {
+			continue
+		}
+		estimate, err := provider.EstimateEmbeddingCost(req)
+		if err != nil {
+			r.logger.WithError(err).WithField("provider", name).Debug("Provider cannot price embedding model")
+			continue
+		}
+		if cost == nil || estimate.TotalCost < cost.TotalCost {
+			selected, cost = name, estimate
+		}
+	}
+
+	if selected == "" {
+		return nil, nil, fmt.Errorf("%w for embedding model %s", ErrModelNotFound, req.Model)
+	}
+
+	// Enforce per-model and per-provider rate limits
+	if err := r.checkResourceLimits(ctx, req.Model, selected); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &types.RouterMetadata{
+		Provider:       selected,
+		Model:          req.Model,
+		RoutingReason:  []string{fmt.Sprintf("Embedding model %s served by %s", req.Model, selected)},
+		EstimatedCost:  cost.TotalCost,
+		ProcessingTime: time.Since(start),
+		RequestID:      req.ID,
+		AttemptCount:   1,
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"provider":    selected,
+		"model":       req.Model,
+		"cost":        cost.TotalCost,
+		"duration_ms": metadata.ProcessingTime.Milliseconds(),
+	}).Info("Embedding request routed")
+
+	return metadata, candidates[selected], nil
+}
+
+// getEmbeddingProviders returns healthy providers that support embeddings
+func (r *Router) getEmbeddingProviders() map[string]providers.EmbeddingProvider {
+	candidates := make(map[string]providers.EmbeddingProvider)
+	for name, provider := range r.providers {
+		embeddingProvider, ok := provider.(providers.EmbeddingProvider)
+		if !ok || !embeddingProvider.SupportsEmbeddings() || !r.isProviderHealthy(name) {
+			continue
+		}
+		candidates[name] = embeddingProvider
+	}
+	return candidates
+}
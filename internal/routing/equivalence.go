@@ -0,0 +1,38 @@
+package routing
+
+// ModelEquivalent identifies a model on another provider that can serve
+// requests for a given model, e.g. a Claude model hosted on Bedrock
+type ModelEquivalent struct {
+	Provider string `yaml:"provider" json:"provider"`
+	Model    string `yaml:"model" json:"model"`
+}
+
+// SetModelEquivalents configures the cross-provider model equivalence map used
+// for model substitution. Keys are requested model names; values are tried in
+// order when the model's owning provider is unhealthy.
+func (r *Router) SetModelEquivalents(equivalents map[string][]ModelEquivalent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelEquivalents = make(map[string][]ModelEquivalent, len(equivalents))
+	for model, targets := range equivalents {
+		r.modelEquivalents[model] = append([]ModelEquivalent(nil), targets...)
+	}
+}
+
+// findEquivalent returns the first healthy registered provider serving an
+// equivalent of the given model, skipping the unhealthy owning provider
+func (r *Router) findEquivalent(model, owner string) (ModelEquivalent, bool) {
+	for _, target := range r.modelEquivalents[model] {
+		if target.Provider == owner {
+			continue
+		}
+		if _, exists := r.providers[target.Provider]; !exists {
+			continue
+		}
+		if r.isProviderHealthy(target.Provider) {
+			return target, true
+		}
+	}
+	return ModelEquivalent{}, false
+}
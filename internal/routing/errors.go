@@ -0,0 +1,50 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+)
+
+// Error classes returned by the router. Match them with errors.Is; the
+// concrete errors carry the details.
+var (
+	// ErrNoHealthyProviders means no registered provider could take the request
+	ErrNoHealthyProviders = errors.New("no healthy providers available")
+
+	// ErrModelNotFound means no provider serves the requested model
+	ErrModelNotFound = errors.New("no provider found")
+
+	// ErrBudgetExceeded means no provider fits the request's cost budget
+	ErrBudgetExceeded = errors.New("cost budget exceeded")
+
+	// ErrProviderTimeout means the provider didn't answer in time
+	ErrProviderTimeout = errors.New("provider timed out")
+
+	// ErrUpstream means the provider answered with an error
+	ErrUpstream = errors.New("upstream provider error")
+)
+
+func (e *UnknownModelError) Is(target error) bool { return target == ErrModelNotFound }
+
+func (e *BudgetError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+// upstreamError classifies a failed provider call as ErrProviderTimeout or
+// ErrUpstream, keeping err in the chain. Client cancellations and the
+// router's own admission and validation errors are returned unchanged.
+func upstreamError(err error) error {
+	var bulkheadErr *BulkheadFullError
+	var stopErr *StopSequenceError
+	if errors.Is(err, context.Canceled) || errors.As(err, &bulkheadErr) || errors.As(err, &stopErr) {
+		return err
+	}
+
+	status := providers.StatusCode(err)
+	if (status == 0 && providers.IsTimeout(err)) || status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout {
+		return fmt.Errorf("%w: %w", ErrProviderTimeout, err)
+	}
+	return fmt.Errorf("%w: %w", ErrUpstream, err)
+}
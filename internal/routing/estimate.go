@@ -0,0 +1,96 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/tokenizer"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ProviderEstimate is one provider's projected cost for a request
+type ProviderEstimate struct {
+	*types.CostEstimate
+	Selected bool `json:"selected"` // The provider the strategy would pick
+}
+
+// CostComparison compares every healthy provider's cost for a request
+type CostComparison struct {
+	Strategy         string                       `json:"strategy"`
+	SelectedProvider string                       `json:"selected_provider"`
+	Reasoning        []string                     `json:"reasoning"`
+	Estimates        map[string]*ProviderEstimate `json:"estimates"`
+}
+
+// EstimateCosts prices a request on every healthy provider and reports which
+// one the request's strategy would pick, without calling any provider API or
+// consuming rate limit budgets. Providers that can't price the request are
+// left out of the comparison.
+func (r *Router) EstimateCosts(ctx context.Context, req *types.ChatRequest) (*CostComparison, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.checkHealthIfDue()
+
+	strategy := r.determineStrategy(req)
+	decision, provider, err := r.routeByStrategy(ctx, req, strategy)
+	if err != nil {
+		return nil, err
+	}
+	decision, _, _, err = r.enforceBudget(req, decision, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := r.getHealthyProviders()
+	estimates := make(map[string]*ProviderEstimate, len(candidates))
+	for _, name := range candidates {
+		estimate, err := r.providers[name].EstimateCost(req)
+		if err != nil {
+			r.logger.WithError(err).Debugf("Failed to estimate cost for %s", name)
+			continue
+		}
+		estimates[name] = &ProviderEstimate{
+			CostEstimate: estimate,
+			Selected:     name == decision.SelectedProvider,
+		}
+	}
+
+	return &CostComparison{
+		Strategy:         string(strategy),
+		SelectedProvider: decision.SelectedProvider,
+		Reasoning:        decision.Reasoning,
+		Estimates:        estimates,
+	}, nil
+}
+
+// staticCostEstimate prices a request from the provider's advertised
+// CostPer1KTokens, for when live estimation fails. It reports false when the
+// provider publishes no pricing.
+func staticCostEstimate(provider providers.LLMProvider, req *types.ChatRequest) (*types.CostEstimate, bool) {
+	pricing := provider.GetCapabilities().CostPer1KTokens
+	if pricing.InputCostPer1K <= 0 && pricing.OutputCostPer1K <= 0 {
+		return nil, false
+	}
+
+	inputTokens := tokenizer.CountTokens(req.Model, req.Messages)
+
+	// Estimate output tokens (use max_tokens or default)
+	outputTokens := 100
+	if req.MaxTokens != nil {
+		outputTokens = *req.MaxTokens
+	}
+
+	inputCost := float64(inputTokens) * pricing.InputCostPer1K / 1000
+	outputCost := float64(outputTokens) * pricing.OutputCostPer1K / 1000
+	totalTokens := inputTokens + outputTokens
+	return &types.CostEstimate{
+		InputTokens:     inputTokens,
+		OutputTokens:    outputTokens,
+		TotalTokens:     totalTokens,
+		InputCost:       inputCost,
+		OutputCost:      outputCost,
+		TotalCost:       inputCost + outputCost,
+		CostPer1KTokens: (inputCost + outputCost) / float64(totalTokens) * 1000,
+	}, true
+}
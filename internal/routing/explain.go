@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// explainRouting builds the routing trace for a request routed to
+// metadata.Provider, listing every registered provider with the reason it
+// wasn't selected. Callers hold mu.
+func (r *Router) explainRouting(req *types.ChatRequest, strategy RoutingStrategy, metadata *types.RouterMetadata) *types.RoutingTrace {
+	trace := &types.RoutingTrace{
+		Strategy:   string(strategy),
+		Candidates: make([]types.CandidateTrace, 0, len(r.providerNames)),
+	}
+
+	for _, name := range r.providerNames {
+		provider := r.providers[name]
+		candidate := types.CandidateTrace{
+			Provider:             name,
+			Health:               r.traceHealth(name),
+			EstimatedLatencyMs:   r.estimateLatency(name).Milliseconds(),
+			FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+			Selected:             name == metadata.Provider,
+		}
+		if estimate, err := provider.EstimateCost(req); err == nil {
+			candidate.EstimatedCost = estimate.TotalCost
+		} else if estimate, ok := staticCostEstimate(provider, req); ok {
+			candidate.EstimatedCost = estimate.TotalCost
+		}
+		if !candidate.Selected {
+			candidate.ExclusionReason = r.exclusionReason(name, req, strategy, metadata)
+		}
+		trace.Candidates = append(trace.Candidates, candidate)
+	}
+	return trace
+}
+
+// traceHealth returns the health a provider is routed on
+func (r *Router) traceHealth(name string) string {
+	status, exists := r.healthStatus[name]
+	if !exists {
+		return "unknown"
+	}
+	if status.Maintenance {
+		return "maintenance"
+	}
+	return status.Status
+}
+
+// exclusionReason explains why a provider wasn't selected, checking the
+// filters in the order routing applies them
+func (r *Router) exclusionReason(name string, req *types.ChatRequest, strategy RoutingStrategy, metadata *types.RouterMetadata) string {
+	if status, exists := r.healthStatus[name]; exists && status.Maintenance {
+		return "in maintenance"
+	}
+	if r.isBulkheadFull(name) {
+		return "at concurrency limit"
+	}
+	if !r.isProviderHealthy(name) {
+		return "unhealthy"
+	}
+	if feature := r.missingFeature(r.providers[name], req); feature != "" {
+		return "missing feature: " + feature
+	}
+	if cost, overBudget := metadata.OverBudgetProviders[name]; overBudget {
+		return fmt.Sprintf("over budget: estimated $%.6f", cost)
+	}
+	if slices.Contains(metadata.FailedProviders, name) {
+		return "failed or rate limited"
+	}
+	if slices.Contains(metadata.LowQualityProviders, name) {
+		return "below min_quality_score"
+	}
+	return fmt.Sprintf("not selected by %s routing", strategy)
+}
@@ -0,0 +1,84 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// visionProvider accepts image input
+type visionProvider struct {
+	pricedProvider
+}
+
+func (p *visionProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "vision", SupportsVision: true}
+}
+
+func TestRouter_Route_Explain(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("text", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("down", &visionProvider{pricedProvider{cost: 0.02}})
+	router.RegisterProvider("vision", &visionProvider{pricedProvider{cost: 0.05}})
+	router.RegisterProvider("pricey", &visionProvider{pricedProvider{cost: 0.10}})
+	router.healthStatus["down"] = &types.HealthStatus{Status: "unhealthy"}
+	router.lastHealthCheck = time.Now()
+
+	req := &types.ChatRequest{
+		Model:            "test-model",
+		Messages:         []types.Message{{Role: "user", Content: "Describe this image"}},
+		OptimizeFor:      types.OptimizeCost,
+		RequiredFeatures: []string{"vision"},
+		Explain:          true,
+	}
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "vision" {
+		t.Fatalf("Expected vision provider to be selected, got %s", metadata.Provider)
+	}
+
+	trace := metadata.RoutingTrace
+	if trace == nil {
+		t.Fatal("Expected a routing trace for an explain request")
+	}
+	if trace.Strategy != string(RoutingStrategyCostOptimized) {
+		t.Errorf("Expected cost_optimized strategy in trace, got %s", trace.Strategy)
+	}
+	if len(trace.Candidates) != 4 {
+		t.Fatalf("Expected every provider in the trace, got %d", len(trace.Candidates))
+	}
+
+	expected := map[string]string{
+		"text":   "missing feature: vision",
+		"down":   "unhealthy",
+		"vision": "",
+		"pricey": "not selected by cost_optimized routing",
+	}
+	for _, candidate := range trace.Candidates {
+		if candidate.ExclusionReason != expected[candidate.Provider] {
+			t.Errorf("Expected %s exclusion reason %q, got %q", candidate.Provider, expected[candidate.Provider], candidate.ExclusionReason)
+		}
+		if candidate.Selected != (candidate.Provider == "vision") {
+			t.Errorf("Expected only vision to be selected, %s has selected=%v", candidate.Provider, candidate.Selected)
+		}
+	}
+
+	down := trace.Candidates[1]
+	if down.Health != "unhealthy" || down.EstimatedCost != 0.02 || !down.FeatureCompatibility["vision"] {
+		t.Errorf("Expected unhealthy provider's health, cost and features in trace, got %+v", down)
+	}
+
+	// Requests without explain don't pay for the trace
+	req.Explain = false
+	metadata, _, err = router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.RoutingTrace != nil {
+		t.Error("Expected no routing trace without explain")
+	}
+}
@@ -0,0 +1,269 @@
+package routing
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// functionProvider is a pricedProvider that supports function calling
+type functionProvider struct {
+	pricedProvider
+}
+
+func (p *functionProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "functions", SupportsFunctions: true}
+}
+
+// ratedProvider is a pricedProvider serving models with quality scores
+type ratedProvider struct {
+	pricedProvider
+	models []types.ModelInfo
+}
+
+func (p *ratedProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "rated", SupportedModels: p.models}
+}
+
+func createFallbackRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("delta", &pricedProvider{cost: 0.05})
+	router.RegisterProvider("alpha", &pricedProvider{cost: 0.20})
+	router.RegisterProvider("charlie", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("bravo", &pricedProvider{cost: 0.05})
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func TestRouter_BuildFallbackChain_CostOrder(t *testing.T) {
+	router := createFallbackRouter(t)
+	req := &types.ChatRequest{Model: "test-model", OptimizeFor: types.OptimizeCost}
+
+	// Cheapest first, equal costs by name
+	expected := []string{"charlie", "bravo", "delta"}
+	for i := 0; i < 20; i++ {
+		chain := router.buildFallbackChain("alpha", req)
+		if !reflect.DeepEqual(chain, expected) {
+			t.Fatalf("Expected chain %v, got %v on attempt %d", expected, chain, i)
+		}
+	}
+}
+
+func TestRouter_BuildFallbackChain_PerformanceOrder(t *testing.T) {
+	router := createFallbackRouter(t)
+	router.RecordLatency("alpha", 50*time.Millisecond)
+	router.RecordLatency("bravo", 300*time.Millisecond)
+	router.RecordLatency("delta", 100*time.Millisecond)
+	router.RecordLatency("charlie", 100*time.Millisecond)
+	req := &types.ChatRequest{Model: "test-model", OptimizeFor: types.OptimizePerformance}
+
+	expected := []string{"alpha", "charlie", "delta"}
+	for i := 0; i < 20; i++ {
+		chain := router.buildFallbackChain("bravo", req)
+		if !reflect.DeepEqual(chain, expected) {
+			t.Fatalf("Expected chain %v, got %v on attempt %d", expected, chain, i)
+		}
+	}
+}
+
+func TestRouter_BuildFallbackChain_RequireSameFeatures(t *testing.T) {
+	router := createFallbackRouter(t)
+	router.RegisterProvider("echo", &functionProvider{pricedProvider{cost: 0.30}})
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		OptimizeFor: types.OptimizeCost,
+		Tools:       []types.Tool{{Type: "function"}},
+		FallbackConfig: &types.FallbackConfig{
+			Enabled:             true,
+			RequireSameFeatures: true,
+		},
+	}
+
+	chain := router.buildFallbackChain("alpha", req)
+	if !reflect.DeepEqual(chain, []string{"echo"}) {
+		t.Errorf("Expected only the function-capable provider, got %v", chain)
+	}
+
+	// Function calling is required whatever the fallback config says
+	req.FallbackConfig.RequireSameFeatures = false
+	chain = router.buildFallbackChain("alpha", req)
+	if !reflect.DeepEqual(chain, []string{"echo"}) {
+		t.Errorf("Expected tools to still require function calling, got %v", chain)
+	}
+
+	// Optional features are relaxed
+	req.Tools = nil
+	req.RequiredFeatures = []string{"batch"}
+	chain = router.buildFallbackChain("alpha", req)
+	expected := []string{"charlie", "bravo", "delta", "echo"}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Expected chain %v without feature requirement, got %v", expected, chain)
+	}
+}
+
+func TestRouter_RouteWithFallback_ImageRequestKeepsVision(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("vision", &visionProvider{pricedProvider{cost: 0.05}})
+	router.RegisterProvider("cheap-text", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("vision-backup", &visionProvider{pricedProvider{cost: 0.10}})
+	router.lastHealthCheck = time.Now()
+
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		OptimizeFor: types.OptimizeCost,
+		Messages: []types.Message{{Role: "user", Content: []types.ContentPart{
+			{Type: "text", Text: "What is in this picture?"},
+			{Type: "image_url", ImageURL: &types.ImageURL{URL: "https://example.com/cat.png"}},
+		}}},
+		FallbackConfig: &types.FallbackConfig{Enabled: true, RequireSameFeatures: false},
+	}
+	original := &RoutingDecision{
+		SelectedProvider: "vision",
+		EstimatedCost:    0.05,
+		FallbackChain:    router.buildFallbackChain("vision", req),
+	}
+	if contains(original.FallbackChain, "cheap-text") {
+		t.Errorf("Expected the fallback chain to skip the non-vision provider, got %v", original.FallbackChain)
+	}
+
+	// Neither the built chain nor a client chain falls back to a provider
+	// that can't see the image
+	for _, chain := range [][]string{nil, {"cheap-text", "vision-backup"}} {
+		req.FallbackConfig.PreferredChain = chain
+		metadata, _, err := router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+		if err != nil {
+			t.Fatalf("Fallback failed: %v", err)
+		}
+		if metadata.Provider != "vision-backup" {
+			t.Errorf("Expected fallback to the vision-capable provider, got %s", metadata.Provider)
+		}
+	}
+
+	if router.IsFallbackCompatible("cheap-text", req) {
+		t.Error("Expected a non-vision provider not to be fallback compatible with an image request")
+	}
+}
+
+func TestRouter_RouteWithFallback_ZeroCostOriginal(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("local", &pricedProvider{cost: 0})
+	router.RegisterProvider("paid", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("local-backup", &pricedProvider{cost: 0})
+	router.lastHealthCheck = time.Now()
+
+	maxIncrease := 0.5
+	req := &types.ChatRequest{
+		Model: "test-model",
+		FallbackConfig: &types.FallbackConfig{
+			Enabled:         true,
+			MaxCostIncrease: &maxIncrease,
+			PreferredChain:  []string{"paid", "local-backup"},
+		},
+	}
+	original := &RoutingDecision{SelectedProvider: "local", EstimatedCost: 0}
+
+	// A paid provider is an unbounded increase over a free one
+	metadata, _, err := router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.Provider != "local-backup" {
+		t.Errorf("Expected free fallback 'local-backup', got %s", metadata.Provider)
+	}
+
+	req.FallbackConfig.PreferredChain = []string{"paid"}
+	if _, _, err := router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{}); err == nil {
+		t.Error("Expected paid fallback to exceed the cost increase limit")
+	}
+
+	// Without a cost limit the paid provider is allowed
+	req.FallbackConfig.MaxCostIncrease = nil
+	metadata, _, err = router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.Provider != "paid" {
+		t.Errorf("Expected fallback to 'paid', got %s", metadata.Provider)
+	}
+}
+
+func TestRouter_RouteWithFallback_MinQualityScore(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("premium", &ratedProvider{pricedProvider{cost: 0.20}, []types.ModelInfo{{Name: "big-model", QualityScore: 0.95}}})
+	router.RegisterProvider("budget", &ratedProvider{pricedProvider{cost: 0.01}, []types.ModelInfo{{Name: "small-model", QualityScore: 0.6}}})
+	router.RegisterProvider("unrated", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("strong", &ratedProvider{pricedProvider{cost: 0.10}, []types.ModelInfo{{Name: "big-model", QualityScore: 0.9}}})
+	router.lastHealthCheck = time.Now()
+
+	minQuality := 0.8
+	req := &types.ChatRequest{
+		Model: "big-model",
+		FallbackConfig: &types.FallbackConfig{
+			Enabled:         true,
+			MinQualityScore: &minQuality,
+			PreferredChain:  []string{"budget"},
+		},
+	}
+	original := &RoutingDecision{SelectedProvider: "premium", EstimatedCost: 0.20}
+
+	// The only fallback is too low quality
+	_, _, err := router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+	if err == nil || !strings.Contains(err.Error(), "no fallback meets min_quality_score 0.80") {
+		t.Fatalf("Expected a min_quality_score error, got %v", err)
+	}
+
+	// Low-quality and unrated providers are skipped and recorded
+	req.FallbackConfig.PreferredChain = []string{"budget", "unrated", "strong"}
+	metadata, _, err := router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.Provider != "strong" {
+		t.Errorf("Expected fallback to 'strong', got %s", metadata.Provider)
+	}
+	if !reflect.DeepEqual(metadata.LowQualityProviders, []string{"budget", "unrated"}) {
+		t.Errorf("Expected budget and unrated recorded as low quality, got %v", metadata.LowQualityProviders)
+	}
+
+	// Without a minimum the cheapest fallback is allowed
+	req.FallbackConfig.MinQualityScore = nil
+	metadata, _, err = router.routeWithFallback(context.Background(), req, original, &types.RouterMetadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.Provider != "budget" {
+		t.Errorf("Expected fallback to 'budget', got %s", metadata.Provider)
+	}
+}
+
+func TestRelativeCostIncrease(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  float64
+		candidate float64
+		expected  float64
+	}{
+		{"increase", 0.01, 0.015, 0.5},
+		{"decrease", 0.02, 0.01, -0.5},
+		{"free to free", 0, 0, 0},
+		{"free to paid", 0, 0.001, math.Inf(1)},
+		{"negative baseline", -0.01, 0.001, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := relativeCostIncrease(tt.original, tt.candidate)
+			if math.IsNaN(got) {
+				t.Fatal("Expected a number, got NaN")
+			}
+			if math.Abs(got-tt.expected) > 1e-9 && got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// SystemPromptMode controls how the guardrail system prompt combines with a
+// request's own system message
+type SystemPromptMode string
+
+const (
+	SystemPromptPrepend SystemPromptMode = "prepend" // guardrail first, then the request's system prompt
+	SystemPromptReplace SystemPromptMode = "replace" // guardrail instead of the request's system prompt
+	SystemPromptAppend  SystemPromptMode = "append"  // request's system prompt, then the guardrail
+)
+
+// GuardrailConfig injects a mandatory system prompt into every chat request
+// before dispatch. An empty SystemPromptPrefix disables it.
+type GuardrailConfig struct {
+	SystemPromptPrefix string           `yaml:"system_prompt_prefix"`
+	SystemPromptMode   SystemPromptMode `yaml:"system_prompt_mode"` // defaults to prepend
+
+	// API key scopes exempt from the guardrail, e.g. "guardrails:exempt"
+	OptOutScopes []string `yaml:"opt_out_scopes"`
+}
+
+// Validate checks the system prompt mode
+func (c GuardrailConfig) Validate() error {
+	switch c.SystemPromptMode {
+	case "", SystemPromptPrepend, SystemPromptReplace, SystemPromptAppend:
+		return nil
+	}
+	return fmt.Errorf("invalid system prompt mode %q: must be prepend, replace or append", c.SystemPromptMode)
+}
+
+// SetGuardrail configures the system prompt injected into chat requests
+func (r *Router) SetGuardrail(config GuardrailConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.guardrail = config
+}
+
+// applyGuardrail merges the guardrail system prompt into req unless the
+// caller's key carries an opt-out scope. The first system message is merged
+// into, or one is inserted at the start; with replace, other system messages
+// are dropped. The merged message is plain text so providers that take the
+// system prompt separately, like Anthropic, accept it. Callers hold mu.
+func (r *Router) applyGuardrail(ctx context.Context, req *types.ChatRequest) {
+	config := r.guardrail
+	if config.SystemPromptPrefix == "" {
+		return
+	}
+	if authInfo, ok := security.GetAuthInfo(ctx); ok {
+		for _, scope := range config.OptOutScopes {
+			if slices.Contains(authInfo.Permissions, scope) {
+				return
+			}
+		}
+	}
+
+	mode := config.SystemPromptMode
+	if mode == "" {
+		mode = SystemPromptPrepend
+	}
+
+	// Build a new slice so the caller's messages are left untouched
+	messages := make([]types.Message, 0, len(req.Messages)+1)
+	merged := false
+	for _, msg := range req.Messages {
+		if msg.Role != "system" {
+			messages = append(messages, msg)
+			continue
+		}
+		if merged {
+			if mode != SystemPromptReplace {
+				messages = append(messages, msg)
+			}
+			continue
+		}
+
+		text := types.MessageText(msg.Content)
+		switch {
+		case mode == SystemPromptReplace || text == "":
+			msg.Content = config.SystemPromptPrefix
+		case mode == SystemPromptAppend:
+			msg.Content = text + "\n\n" + config.SystemPromptPrefix
+		default:
+			msg.Content = config.SystemPromptPrefix + "\n\n" + text
+		}
+		messages = append(messages, msg)
+		merged = true
+	}
+
+	if !merged {
+		messages = append([]types.Message{{Role: "system", Content: config.SystemPromptPrefix}}, messages...)
+	}
+	req.Messages = messages
+}
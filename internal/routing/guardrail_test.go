@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func guardrailRouter(t *testing.T, mode SystemPromptMode) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+	router.SetGuardrail(GuardrailConfig{
+		SystemPromptPrefix: "Be safe.",
+		SystemPromptMode:   mode,
+		OptOutScopes:       []string{"guardrails:exempt"},
+	})
+	return router
+}
+
+func TestRouter_Route_GuardrailPrependsToSystemMessage(t *testing.T) {
+	router := guardrailRouter(t, SystemPromptPrepend)
+	messages := []types.Message{
+		{Role: "system", Content: "You are a pirate."},
+		{Role: "user", Content: "Hello"},
+	}
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: messages}
+
+	if _, _, err := router.Route(context.Background(), req); err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if len(req.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(req.Messages))
+	}
+	if req.Messages[0].Content != "Be safe.\n\nYou are a pirate." {
+		t.Errorf("Unexpected system prompt %q", req.Messages[0].Content)
+	}
+	if messages[0].Content != "You are a pirate." {
+		t.Errorf("Caller's messages were modified: %q", messages[0].Content)
+	}
+}
+
+func TestRouter_Route_GuardrailInsertsSystemMessage(t *testing.T) {
+	router := guardrailRouter(t, SystemPromptAppend)
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+
+	if _, _, err := router.Route(context.Background(), req); err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "Be safe." {
+		t.Fatalf("Expected an inserted guardrail system message, got %+v", req.Messages)
+	}
+
+	// Keys with an opt-out scope are left alone
+	ctx := context.WithValue(context.Background(), "auth_info", &security.AuthInfo{UserID: "red-team", Permissions: []string{"guardrails:exempt"}})
+	req = &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	if _, _, err := router.Route(ctx, req); err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if len(req.Messages) != 1 {
+		t.Errorf("Expected opted-out request to be unchanged, got %+v", req.Messages)
+	}
+}
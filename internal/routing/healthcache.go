@@ -0,0 +1,134 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// healthCache is the on-disk snapshot of provider health used to warm-start
+// routing after a restart
+type healthCache struct {
+	Providers map[string]cachedHealth `json:"providers"`
+}
+
+// cachedHealth is one provider's last known health and latency estimate
+type cachedHealth struct {
+	Status       string `json:"status"`
+	ResponseTime int64  `json:"response_time_ms"`
+	LastChecked  int64  `json:"last_checked"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	LatencyMs    int64  `json:"latency_ms,omitempty"` // average completion latency
+}
+
+// loadHealthCache reads a health cache file. A missing file is an empty cache.
+func loadHealthCache(path string) (*healthCache, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &healthCache{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cache healthCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// warmStart seeds health status and latency estimates of providers without a
+// status of their own from the health cache, reporting whether any were
+// seeded. Callers hold mu.
+func (r *Router) warmStart(health map[string]*types.HealthStatus, fresh []string) bool {
+	if r.healthCachePath == "" || len(fresh) == 0 {
+		return false
+	}
+
+	cache, err := loadHealthCache(r.healthCachePath)
+	if err != nil {
+		r.logger.WithError(err).WithField("path", r.healthCachePath).Warn("Failed to read health cache, starting with unknown health")
+		return false
+	}
+
+	seeded := false
+	for _, name := range fresh {
+		cached, exists := cache.Providers[name]
+		if !exists || cached.Status == "" {
+			continue
+		}
+		health[name] = &types.HealthStatus{
+			Status:       cached.Status,
+			ResponseTime: cached.ResponseTime,
+			LastChecked:  cached.LastChecked,
+			ErrorMessage: cached.ErrorMessage,
+		}
+		r.latencies.seed(name, time.Duration(cached.LatencyMs)*time.Millisecond)
+		seeded = true
+	}
+	if seeded {
+		r.logger.WithField("path", r.healthCachePath).Info("Provider health warm-started from cache")
+	}
+	return seeded
+}
+
+// saveHealthCache writes the current health status and latency estimates to
+// the health cache file, if one is configured
+func (r *Router) saveHealthCache() {
+	r.mu.RLock()
+	path := r.healthCachePath
+	cache := healthCache{Providers: make(map[string]cachedHealth, len(r.healthStatus))}
+	for name, status := range r.healthStatus {
+		if status.Status == "unknown" {
+			continue
+		}
+		entry := cachedHealth{
+			Status:       status.Status,
+			ResponseTime: status.ResponseTime,
+			LastChecked:  status.LastChecked,
+			ErrorMessage: status.ErrorMessage,
+		}
+		if latency, ok := r.latencies.get(name); ok {
+			entry.LatencyMs = latency.Milliseconds()
+		}
+		cache.Providers[name] = entry
+	}
+	r.mu.RUnlock()
+
+	if path == "" {
+		return
+	}
+	if err := writeHealthCache(path, &cache); err != nil {
+		r.logger.WithError(err).WithField("path", path).Warn("Failed to write health cache")
+	}
+}
+
+// writeHealthCache replaces the cache file atomically so a crash mid-write
+// never leaves a truncated file behind
+func writeHealthCache(path string, cache *healthCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gatedProvider blocks health checks until released
+type gatedProvider struct {
+	flakyProvider
+	release chan struct{}
+	checked chan struct{}
+}
+
+func (g *gatedProvider) HealthCheck(ctx context.Context) error {
+	<-g.release
+	g.checked <- struct{}{}
+	return nil
+}
+
+func TestRouter_HealthCacheWarmStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	err := os.WriteFile(path, []byte(`{
+  "providers": {
+    "primary": {"status": "healthy", "response_time_ms": 120, "last_checked": 1700000000, "latency_ms": 450},
+    "backup": {"status": "unhealthy", "last_checked": 1700000000, "error_message": "connection refused"}
+  }
+}`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write health cache: %v", err)
+	}
+
+	primary := &gatedProvider{release: make(chan struct{}), checked: make(chan struct{}, 1)}
+	backup := &gatedProvider{release: make(chan struct{}), checked: make(chan struct{}, 1)}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	router := NewRouter(logger)
+	router.Reload(Settings{DefaultStrategy: RoutingStrategyCostOptimized, HealthCachePath: path}, []NamedProvider{
+		{Name: "primary", Provider: primary},
+		{Name: "backup", Provider: backup},
+	})
+
+	// Health comes from the cache while the live checks are still blocked
+	status := router.GetHealthStatus()
+	if status["primary"].Status != "healthy" {
+		t.Errorf("Expected primary to warm-start healthy, got %s", status["primary"].Status)
+	}
+	if status["backup"].Status != "unhealthy" {
+		t.Errorf("Expected backup to warm-start unhealthy, got %s", status["backup"].Status)
+	}
+	router.mu.RLock()
+	primaryHealthy, backupHealthy := router.isProviderHealthy("primary"), router.isProviderHealthy("backup")
+	router.mu.RUnlock()
+	if !primaryHealthy || backupHealthy {
+		t.Errorf("Expected only primary to be routable, got primary=%v backup=%v", primaryHealthy, backupHealthy)
+	}
+	if latency, ok := router.latencies.get("primary"); !ok || latency != 450*time.Millisecond {
+		t.Errorf("Expected cached latency 450ms, got %v (%v)", latency, ok)
+	}
+
+	// A live check was scheduled immediately and its result is persisted
+	close(primary.release)
+	close(backup.release)
+	for _, provider := range []*gatedProvider{primary, backup} {
+		select {
+		case <-provider.checked:
+		case <-time.After(time.Second):
+			t.Fatal("Expected an immediate health check after warm start")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cache, err := loadHealthCache(path)
+		if err != nil {
+			t.Fatalf("Failed to read health cache: %v", err)
+		}
+		if cache.Providers["backup"].Status == "healthy" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the live check result to be persisted, got %+v", cache.Providers["backup"])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRouter_HealthCacheDisabled(t *testing.T) {
+	router := NewRouter(logrus.New())
+	router.Reload(Settings{DefaultStrategy: RoutingStrategyCostOptimized}, []NamedProvider{
+		{Name: "primary", Provider: &flakyProvider{}},
+	})
+
+	if status := router.GetHealthStatus()["primary"].Status; status != "unknown" {
+		t.Errorf("Expected unknown status without a health cache, got %s", status)
+	}
+	router.updateHealthStatus(context.Background(), []string{"primary"}) // must not write anywhere
+}
@@ -0,0 +1,135 @@
+package routing
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultLatencySmoothing is the EWMA weight given to each new sample
+const defaultLatencySmoothing = 0.3
+
+// latencyWindowSize is how many recent samples per provider percentiles are
+// computed over
+const latencyWindowSize = 200
+
+// latencyTracker keeps an exponentially-weighted moving average of observed
+// provider latencies, plus a sliding window of recent samples for tail
+// percentiles
+type latencyTracker struct {
+	mu       sync.RWMutex
+	alpha    float64
+	averages map[string]float64 // EWMA in nanoseconds
+	observed map[string]bool    // true once real traffic has been recorded
+	windows  map[string]*latencyWindow
+}
+
+// latencyWindow is a ring buffer of the most recent samples
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// add records a sample, evicting the oldest once the window is full
+func (w *latencyWindow) add(d time.Duration) {
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// newLatencyTracker creates an empty latency tracker
+func newLatencyTracker(alpha float64) *latencyTracker {
+	return &latencyTracker{
+		alpha:    alpha,
+		averages: make(map[string]float64),
+		observed: make(map[string]bool),
+		windows:  make(map[string]*latencyWindow),
+	}
+}
+
+// record folds a measured completion latency into the provider's average
+func (t *latencyTracker) record(provider string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	window, exists := t.windows[provider]
+	if !exists {
+		window = &latencyWindow{}
+		t.windows[provider] = window
+	}
+	window.add(d)
+
+	// The first real sample replaces any health-check seed outright
+	if !t.observed[provider] {
+		t.averages[provider] = float64(d)
+		t.observed[provider] = true
+		return
+	}
+
+	t.averages[provider] = t.alpha*float64(d) + (1-t.alpha)*t.averages[provider]
+}
+
+// seed sets a provisional latency from a health check until real traffic arrives
+func (t *latencyTracker) seed(provider string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.observed[provider] {
+		return
+	}
+	t.averages[provider] = float64(d)
+}
+
+// get returns the current average latency for a provider
+func (t *latencyTracker) get(provider string) (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	avg, exists := t.averages[provider]
+	if !exists {
+		return 0, false
+	}
+	return time.Duration(avg), true
+}
+
+// percentile returns the q-th quantile (0-1) of a provider's recent samples
+// by nearest rank. Health-check seeds don't count, so it reports false until
+// real traffic has been recorded.
+func (t *latencyTracker) percentile(provider string, q float64) (time.Duration, bool) {
+	t.mu.RLock()
+	window, exists := t.windows[provider]
+	var samples []time.Duration
+	if exists {
+		samples = slices.Clone(window.samples)
+	}
+	t.mu.RUnlock()
+
+	if len(samples) == 0 {
+		return 0, false
+	}
+	slices.Sort(samples)
+	rank := int(math.Ceil(q*float64(len(samples)))) - 1
+	return samples[min(max(rank, 0), len(samples)-1)], true
+}
+
+// forget drops a provider's latency history
+func (t *latencyTracker) forget(provider string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.averages, provider)
+	delete(t.observed, provider)
+	delete(t.windows, provider)
+}
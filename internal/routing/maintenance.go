@@ -0,0 +1,44 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// SetMaintenance takes a provider out of rotation, or returns it, regardless
+// of its live health. The flag survives health checks and reloads that keep
+// the provider's name.
+func (r *Router) SetMaintenance(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status, exists := r.healthStatus[name]
+	if !exists {
+		return fmt.Errorf("provider %s not found", name)
+	}
+	status.Maintenance = enabled
+
+	r.logger.WithField("provider", name).WithField("maintenance", enabled).Info("Provider maintenance mode changed")
+	return nil
+}
+
+// RecheckHealth runs a provider's health check now, rather than waiting for
+// its interval, and returns the recorded status
+func (r *Router) RecheckHealth(ctx context.Context, name string) (*types.HealthStatus, error) {
+	r.mu.RLock()
+	provider, exists := r.providers[name]
+	r.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("provider %s not found", name)
+	}
+
+	r.healthCheckMu.Lock()
+	r.lastChecked[name] = time.Now()
+	r.healthCheckMu.Unlock()
+
+	status := r.checkProviderHealth(ctx, name, provider)
+	return &status, nil
+}
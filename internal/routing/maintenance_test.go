@@ -0,0 +1,105 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRouter_SetMaintenance(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("primary", &flakyProvider{})
+	router.RegisterProvider("secondary", &flakyProvider{})
+	router.lastHealthCheck = time.Now()
+
+	if err := router.SetMaintenance("primary", true); err != nil {
+		t.Fatalf("SetMaintenance failed: %v", err)
+	}
+	if err := router.SetMaintenance("missing", true); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+
+	healthy := router.getHealthyProviders()
+	if len(healthy) != 1 || healthy[0] != "secondary" {
+		t.Errorf("Expected only secondary in rotation, got %v", healthy)
+	}
+
+	// A passing health check doesn't clear maintenance
+	status, err := router.RecheckHealth(context.Background(), "primary")
+	if err != nil {
+		t.Fatalf("RecheckHealth failed: %v", err)
+	}
+	if status.Status != "healthy" || !status.Maintenance {
+		t.Errorf("Expected a healthy status still in maintenance, got %+v", status)
+	}
+	if router.isProviderHealthy("primary") {
+		t.Error("Expected primary to stay out of rotation after a recheck")
+	}
+
+	// Nor does a reload that keeps the provider
+	router.Reload(Settings{}, []NamedProvider{{Name: "primary", Provider: &flakyProvider{}}})
+	if router.isProviderHealthy("primary") {
+		t.Error("Expected maintenance to survive a reload")
+	}
+
+	if err := router.SetMaintenance("primary", false); err != nil {
+		t.Fatalf("SetMaintenance failed: %v", err)
+	}
+	if !router.isProviderHealthy("primary") {
+		t.Error("Expected primary back in rotation once maintenance is cleared")
+	}
+}
+
+func TestRouter_DeregisterProvider(t *testing.T) {
+	router := createTestRouter(t)
+	router.SetDefaultStrategy(RoutingStrategyRoundRobin)
+	for _, name := range []string{"first", "second", "third"} {
+		router.RegisterProvider(name, &flakyProvider{})
+	}
+	router.lastHealthCheck = time.Now()
+
+	req := costRequest()
+	metadata, inFlight, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "first" {
+		t.Fatalf("Expected round-robin to start with first, got %s", metadata.Provider)
+	}
+
+	if err := router.DeregisterProvider("first"); err != nil {
+		t.Fatalf("DeregisterProvider failed: %v", err)
+	}
+	if err := router.DeregisterProvider("first"); err == nil {
+		t.Error("Expected an error deregistering an unknown provider")
+	}
+
+	providers := router.ListProviders()
+	if len(providers) != 2 || providers[0] != "second" || providers[1] != "third" {
+		t.Errorf("Expected [second third] after deregistration, got %v", providers)
+	}
+	if _, exists := router.GetHealthStatus()["first"]; exists {
+		t.Error("Expected the removed provider's health status to be dropped")
+	}
+
+	// The request routed before removal still completes on its provider
+	if _, err := inFlight.ChatCompletion(context.Background(), req); err != nil {
+		t.Errorf("Expected the in-flight request to complete, got %v", err)
+	}
+
+	// The rotation carries on where it was and never picks the removed provider
+	var routed []string
+	for i := 0; i < 4; i++ {
+		metadata, _, err := router.Route(context.Background(), costRequest())
+		if err != nil {
+			t.Fatalf("Routing failed: %v", err)
+		}
+		routed = append(routed, metadata.Provider)
+	}
+	want := []string{"second", "third", "second", "third"}
+	for i := range want {
+		if routed[i] != want[i] {
+			t.Fatalf("Expected rotation %v, got %v", want, routed)
+		}
+	}
+}
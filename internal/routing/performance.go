@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"fmt"
+	"time"
+)
+
+// PerformanceMetric is the latency statistic performance routing ranks
+// providers by
+type PerformanceMetric string
+
+const (
+	PerformanceMetricMean PerformanceMetric = "mean" // moving average
+	PerformanceMetricP95  PerformanceMetric = "p95"
+	PerformanceMetricP99  PerformanceMetric = "p99"
+)
+
+// Validate reports whether m is a known metric; empty means mean
+func (m PerformanceMetric) Validate() error {
+	switch m {
+	case "", PerformanceMetricMean, PerformanceMetricP95, PerformanceMetricP99:
+		return nil
+	default:
+		return fmt.Errorf("invalid performance metric %q: must be mean, p95 or p99", m)
+	}
+}
+
+// orDefault returns m, or mean when m is empty
+func (m PerformanceMetric) orDefault() PerformanceMetric {
+	if m == "" {
+		return PerformanceMetricMean
+	}
+	return m
+}
+
+// SetPerformanceMetric sets the latency statistic performance routing ranks
+// providers by. Tail percentiles favor providers that are consistently fast
+// over ones that are usually fast with occasional stalls.
+func (r *Router) SetPerformanceMetric(metric PerformanceMetric) error {
+	if err := metric.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.performanceMetric = metric.orDefault()
+	return nil
+}
+
+// estimateP95Latency returns a provider's 95th percentile latency over its
+// recent samples, falling back to the average estimate until it has served
+// traffic
+func (r *Router) estimateP95Latency(providerName string) time.Duration {
+	return r.estimatePercentileLatency(providerName, 0.95)
+}
+
+// estimatePercentileLatency returns the q-th quantile of a provider's recent
+// latencies, falling back to estimateLatency without samples
+func (r *Router) estimatePercentileLatency(providerName string, q float64) time.Duration {
+	if latency, ok := r.latencies.percentile(providerName, q); ok {
+		return latency
+	}
+	return r.estimateLatency(providerName)
+}
+
+// performanceLatency returns a provider's latency by the configured
+// performance metric. Callers hold mu.
+func (r *Router) performanceLatency(providerName string) time.Duration {
+	switch r.performanceMetric {
+	case PerformanceMetricP95:
+		return r.estimateP95Latency(providerName)
+	case PerformanceMetricP99:
+		return r.estimatePercentileLatency(providerName, 0.99)
+	default:
+		return r.estimateLatency(providerName)
+	}
+}
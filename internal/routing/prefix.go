@@ -0,0 +1,69 @@
+package routing
+
+import "strings"
+
+// DefaultModelPrefixes maps model name prefixes to the provider that serves
+// them, so a request for e.g. "gpt-4o" goes straight to OpenAI
+var DefaultModelPrefixes = map[string]string{
+	"gpt-":          "openai",
+	"claude-":       "anthropic",
+	"command-":      "cohere",
+	"mistral-":      "mistral",
+	"open-mistral-": "mistral",
+	"open-mixtral-": "mistral",
+	"anthropic.":    "bedrock",
+	"amazon.titan-": "bedrock",
+}
+
+// RegisterModelPrefix routes models whose names start with prefix to the
+// named provider, replacing any existing mapping for the prefix. New
+// providers register their model families here instead of editing the router.
+func (r *Router) RegisterModelPrefix(prefix, provider string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelPrefixes[prefix] = provider
+}
+
+// SetModelPrefixes replaces the prefix map; nil restores
+// DefaultModelPrefixes. A prefix mapped to an empty provider is ignored.
+func (r *Router) SetModelPrefixes(prefixes map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelPrefixes = modelPrefixesOrDefault(prefixes)
+}
+
+// SetModelOverrides routes exact model names to a provider ahead of the
+// prefix map, e.g. a fine-tuned model served by a different provider
+func (r *Router) SetModelOverrides(overrides map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelOverrides = copyStringMap(overrides)
+}
+
+// mappedProvider returns the provider a model is pinned to: an exact
+// override first, otherwise the longest matching prefix, so a specific
+// family can override a broader one
+func (r *Router) mappedProvider(model string) (string, bool) {
+	if provider := r.modelOverrides[model]; provider != "" {
+		return provider, true
+	}
+
+	matched, provider := "", ""
+	for prefix, name := range r.modelPrefixes {
+		if name != "" && strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			matched, provider = prefix, name
+		}
+	}
+	return provider, matched != ""
+}
+
+// modelPrefixesOrDefault copies prefixes, falling back to the defaults
+func modelPrefixesOrDefault(prefixes map[string]string) map[string]string {
+	if prefixes == nil {
+		return copyStringMap(DefaultModelPrefixes)
+	}
+	return copyStringMap(prefixes)
+}
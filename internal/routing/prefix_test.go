@@ -0,0 +1,96 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createPrefixRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", &flakyProvider{})
+	router.RegisterProvider("mistral", &flakyProvider{})
+	router.RegisterProvider("ollama", &flakyProvider{})
+	// Suppress background health checks so tests control health
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func TestRouter_Route_MistralModels(t *testing.T) {
+	router := createPrefixRouter(t)
+
+	for _, model := range []string{"mistral-large-latest", "mistral-small-latest", "open-mixtral-8x7b", "open-mistral-nemo"} {
+		metadata, _, err := router.Route(context.Background(), &types.ChatRequest{
+			Model:    model,
+			Messages: []types.Message{{Role: "user", Content: "Hello"}},
+		})
+		if err != nil {
+			t.Fatalf("Routing %s failed: %v", model, err)
+		}
+		if metadata.Provider != "mistral" {
+			t.Errorf("Expected %s to route to mistral, got %s", model, metadata.Provider)
+		}
+	}
+}
+
+func TestRouter_RegisterModelPrefix(t *testing.T) {
+	router := createPrefixRouter(t)
+
+	if _, found := router.getProviderForModel("llama3.1:8b"); found {
+		t.Fatal("Expected no provider for an unmapped model family")
+	}
+
+	// New providers register their model families without router changes
+	router.RegisterModelPrefix("llama", "ollama")
+	if provider, _ := router.getProviderForModel("llama3.1:8b"); provider != "ollama" {
+		t.Errorf("Expected llama models to map to ollama, got %q", provider)
+	}
+
+	// The longest matching prefix wins over a broader one
+	router.RegisterModelPrefix("gpt-oss-", "ollama")
+	if provider, _ := router.getProviderForModel("gpt-oss-20b"); provider != "ollama" {
+		t.Errorf("Expected gpt-oss models to map to ollama, got %q", provider)
+	}
+	if provider, _ := router.getProviderForModel("gpt-4o"); provider != "openai" {
+		t.Errorf("Expected gpt-4o to still map to openai, got %q", provider)
+	}
+}
+
+func TestRouter_ModelPrefixesAndOverrides(t *testing.T) {
+	router := createPrefixRouter(t)
+	router.SetModelPrefixes(map[string]string{"gpt-": "openai", "acme-": "ollama"})
+	router.SetModelOverrides(map[string]string{"gpt-4o-acme-ft": "ollama"})
+
+	tests := []struct {
+		model    string
+		provider string
+		found    bool
+	}{
+		{model: "acme-7b", provider: "ollama", found: true},        // custom prefix
+		{model: "gpt-4o-acme-ft", provider: "ollama", found: true}, // exact override beats the gpt- prefix
+		{model: "gpt-4o", provider: "openai", found: true},
+		{model: "mistral-large-latest", found: false}, // replaced map drops the defaults
+		{model: "unknown-model", found: false},
+	}
+
+	for _, tt := range tests {
+		provider, found := router.getProviderForModel(tt.model)
+		if provider != tt.provider || found != tt.found {
+			t.Errorf("%s: expected (%q, %v), got (%q, %v)", tt.model, tt.provider, tt.found, provider, found)
+		}
+	}
+
+	// An override to an unregistered provider pins nothing
+	router.SetModelOverrides(map[string]string{"gpt-4o": "azure"})
+	if _, found := router.getProviderForModel("gpt-4o"); found {
+		t.Error("Expected no provider for an override to an unregistered provider")
+	}
+
+	// Reloading without a prefix map restores the defaults
+	router.Reload(Settings{}, []NamedProvider{{Name: "mistral", Provider: &flakyProvider{}}})
+	if provider, _ := router.getProviderForModel("mistral-large-latest"); provider != "mistral" {
+		t.Errorf("Expected default prefixes after reload, got %q", provider)
+	}
+}
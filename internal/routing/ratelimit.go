@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// RateLimitError is returned when a per-model or per-provider rate limit is
+// exhausted and the request could not be routed elsewhere
+type RateLimitError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.Key)
+}
+
+// SetRateLimiter configures the limiter used for per-model and per-provider
+// limits. Keys are built with security.ModelRateLimitKey and
+// security.ProviderRateLimitKey.
+func (r *Router) SetRateLimiter(limiter security.RateLimiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rateLimiter = limiter
+}
+
+// dryRunKey marks a context whose routing decision is never executed
+type dryRunKey struct{}
+
+// WithDryRun returns a context for routing decisions that won't be executed,
+// e.g. previews, so they don't consume rate-limit budget
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// isDryRun reports whether ctx was marked with WithDryRun
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// checkResourceLimits consumes the provider and model budgets for a request.
+// The model budget is only spent once the provider has capacity, so a
+// fallback candidate can check the same model again.
+func (r *Router) checkResourceLimits(ctx context.Context, model, provider string) error {
+	if err := r.checkLimit(ctx, security.ProviderRateLimitKey(provider)); err != nil {
+		return err
+	}
+	return r.checkLimit(ctx, security.ModelRateLimitKey(model))
+}
+
+// checkLimit consumes a single token for key, returning a RateLimitError when
+// denied. Dry runs consume nothing.
+func (r *Router) checkLimit(ctx context.Context, key string) error {
+	if r.rateLimiter == nil || isDryRun(ctx) {
+		return nil
+	}
+
+	result, err := r.rateLimiter.Allow(ctx, key)
+	if err != nil {
+		// Fail open so limiter outages don't take routing down
+		r.logger.WithError(err).WithField("key", key).Warn("Rate limit check failed")
+		return nil
+	}
+
+	if !result.Allowed {
+		return &RateLimitError{Key: key, RetryAfter: result.RetryAfter}
+	}
+	return nil
+}
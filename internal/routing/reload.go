@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// NamedProvider pairs a provider with the name it is registered under
+type NamedProvider struct {
+	Name     string
+	Provider providers.LLMProvider
+}
+
+// Settings holds the routing configuration that can change at runtime
+type Settings struct {
+	DefaultStrategy   RoutingStrategy
+	Weights           map[string]int
+	ModelEquivalents  map[string][]ModelEquivalent
+	ModelAliases      map[string]string
+	ModelPrefixes     map[string]string // nil uses DefaultModelPrefixes
+	ModelOverrides    map[string]string // exact model name -> provider
+	MaxCostThreshold  float64
+	BalancedAlpha     float64              // cost weight for balanced routing, 0-1
+	PerformanceMetric PerformanceMetric    // empty uses mean; invalid values are ignored
+	RateLimiter       security.RateLimiter // nil disables per-model and per-provider limits
+	Shadow            ShadowConfig         // traffic mirroring for provider evaluation
+	Guardrail         GuardrailConfig      // mandatory system prompt for chat requests
+	DefaultRetry      *types.RetryConfig   // retry policy for requests without their own, nil disables
+
+	// Fraction of requests that may be retried across all requests, e.g.
+	// 0.1; once spent, failures skip their retries. 0 leaves retries
+	// unlimited.
+	RetryBudgetRatio float64
+
+	// Chains run on every chat request before routing and on every
+	// completed response, in order
+	RequestTransformers  []RequestTransformer
+	ResponseTransformers []ResponseTransformer
+
+	// Default health check interval for providers without their own; zero
+	// keeps the current interval
+	HealthCheckInterval time.Duration
+
+	// Upper bound on one provider's health check, after which it is
+	// recorded as unhealthy, and how many providers are checked at once;
+	// zero keeps the current values
+	HealthCheckTimeout     time.Duration
+	HealthCheckConcurrency int
+
+	// File the last known provider health is persisted to, so a restart
+	// routes on it instead of starting every provider as unknown. Empty
+	// disables persistence.
+	HealthCachePath string
+
+	// Maximum concurrent requests per provider. A provider at its limit is
+	// treated as unavailable so requests fall back instead of queueing.
+	// Providers without a positive limit are unlimited.
+	ProviderConcurrency map[string]int
+}
+
+// Reload replaces the registered providers and routing settings in one step.
+// Requests already routed keep the provider they were given; new requests
+// see either the old or the new configuration, never a mix. Health status
+// carries over for providers that keep their name; new providers are seeded
+// from the health cache when one is configured, in which case a health check
+// starts right away. Otherwise the next request triggers a fresh check.
+func (r *Router) Reload(settings Settings, registered []NamedProvider) {
+	providerMap := make(map[string]providers.LLMProvider, len(registered))
+	names := make([]string, 0, len(registered))
+	for _, np := range registered {
+		if _, exists := providerMap[np.Name]; !exists {
+			names = append(names, np.Name)
+		}
+		providerMap[np.Name] = np.Provider
+	}
+
+	equivalents := make(map[string][]ModelEquivalent, len(settings.ModelEquivalents))
+	for model, targets := range settings.ModelEquivalents {
+		equivalents[model] = append([]ModelEquivalent(nil), targets...)
+	}
+
+	r.mu.Lock()
+	health := make(map[string]*types.HealthStatus, len(names))
+	var fresh []string
+	for _, name := range names {
+		if status, exists := r.healthStatus[name]; exists {
+			health[name] = status
+			continue
+		}
+		health[name] = &types.HealthStatus{Status: "unknown"}
+		fresh = append(fresh, name)
+	}
+	r.healthCachePath = settings.HealthCachePath
+	warmStarted := r.warmStart(health, fresh)
+
+	r.providers = providerMap
+	r.providerNames = names
+	r.roundRobinIndex.Store(0)
+	r.healthStatus = health
+	r.defaultStrategy = settings.DefaultStrategy
+	r.modelEquivalents = equivalents
+	r.modelAliases = copyStringMap(settings.ModelAliases)
+	r.modelPrefixes = modelPrefixesOrDefault(settings.ModelPrefixes)
+	r.modelOverrides = copyStringMap(settings.ModelOverrides)
+	r.maxCostThreshold = settings.MaxCostThreshold
+	r.balancedAlpha = min(max(settings.BalancedAlpha, 0), 1)
+	if settings.PerformanceMetric.Validate() == nil {
+		r.performanceMetric = settings.PerformanceMetric.orDefault()
+	}
+	r.rateLimiter = settings.RateLimiter
+	r.shadow = settings.Shadow
+	r.guardrail = settings.Guardrail
+	r.defaultRetry = settings.DefaultRetry
+	r.retryBudget.setRatio(settings.RetryBudgetRatio)
+	r.requestTransformers = append([]RequestTransformer(nil), settings.RequestTransformers...)
+	r.responseTransformers = append([]ResponseTransformer(nil), settings.ResponseTransformers...)
+	r.setBulkheads(settings.ProviderConcurrency)
+	if settings.HealthCheckInterval > 0 {
+		r.healthCheckInterval = settings.HealthCheckInterval
+	}
+	if settings.HealthCheckTimeout > 0 {
+		r.healthCheckTimeout = settings.HealthCheckTimeout
+	}
+	if settings.HealthCheckConcurrency > 0 {
+		r.healthCheckConcurrency = settings.HealthCheckConcurrency
+	}
+
+	// Weights are swapped under mu too so weighted routing never sees
+	// weights for the old provider set
+	r.SetProviderWeights(settings.Weights)
+	r.mu.Unlock()
+
+	r.healthCheckMu.Lock()
+	r.lastHealthCheck = time.Time{}
+	r.lastChecked = make(map[string]time.Time)
+	r.healthCheckMu.Unlock()
+
+	// Confirm warm-started health with a live check rather than waiting
+	// for the first request
+	if warmStarted {
+		r.mu.RLock()
+		r.checkHealthIfDue()
+		r.mu.RUnlock()
+	}
+
+	r.logger.WithField("providers", names).Info("Router configuration reloaded")
+}
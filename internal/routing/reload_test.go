@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func costRequest() *types.ChatRequest {
+	return &types.ChatRequest{
+		ID:       "reload-request",
+		Model:    "test-model",
+		Messages: []types.Message{{Role: "user", Content: "Hello"}},
+	}
+}
+
+func TestRouter_Reload_SwapsProvidersAndSettings(t *testing.T) {
+	router := createBudgetRouter(t)
+	router.healthStatus["mid"].Status = "unhealthy"
+
+	router.Reload(Settings{
+		DefaultStrategy:  RoutingStrategyCostOptimized,
+		MaxCostThreshold: 0.5,
+	}, []NamedProvider{
+		{Name: "mid", Provider: &pricedProvider{cost: 0.05}},
+		{Name: "premium", Provider: &pricedProvider{cost: 0.001}},
+	})
+
+	providers := router.ListProviders()
+	if len(providers) != 2 || providers[0] != "mid" || providers[1] != "premium" {
+		t.Errorf("Expected [mid premium] after reload, got %v", providers)
+	}
+
+	// Health status carries over for kept providers
+	if status := router.GetHealthStatus()["mid"].Status; status != "unhealthy" {
+		t.Errorf("Expected mid to stay unhealthy, got %s", status)
+	}
+
+	router.lastHealthCheck = time.Now()
+	metadata, _, err := router.Route(context.Background(), costRequest())
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "premium" {
+		t.Errorf("Expected repriced premium to be cheapest, got %s", metadata.Provider)
+	}
+	if metadata.EstimatedCost != 0.001 {
+		t.Errorf("Expected estimated cost 0.001, got %f", metadata.EstimatedCost)
+	}
+}
+
+func TestRouter_Reload_ConcurrentWithRouting(t *testing.T) {
+	router := createBudgetRouter(t)
+	router.lastHealthCheck = time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, _, err := router.Route(context.Background(), costRequest()); err != nil {
+					t.Errorf("Routing failed during reload: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		router.Reload(Settings{DefaultStrategy: RoutingStrategyCostOptimized}, []NamedProvider{
+			{Name: "cheap", Provider: &pricedProvider{cost: 0.01}},
+		})
+	}
+	wg.Wait()
+}
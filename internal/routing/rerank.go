@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// RouteRerank selects a provider for a rerank request: the first healthy
+// provider, in registration order, that supports reranking.
+func (r *Router) RouteRerank(ctx context.Context, req *types.RerankRequest) (*types.RouterMetadata, providers.RerankProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start := time.Now()
+
+	// Update health status if needed
+	r.checkHealthIfDue()
+
+	var selected string
+	var reranker providers.RerankProvider
+	for _, name := range r.providerNames {
+		provider, ok := r.providers[name].(providers.RerankProvider)
+		if ok && r.isProviderHealthy(name) {
+			selected, reranker = name, provider
+			break
+		}
+	}
+
+	if reranker == nil {
+		return nil, nil, fmt.Errorf("%w that support reranking", ErrNoHealthyProviders)
+	}
+
+	// Enforce the per-provider rate limit
+	if err := r.checkLimit(ctx, security.ProviderRateLimitKey(selected)); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &types.RouterMetadata{
+		Provider:       selected,
+		RoutingReason:  []string{fmt.Sprintf("Rerank served by %s", selected)},
+		ProcessingTime: time.Since(start),
+		RequestID:      req.ID,
+		AttemptCount:   1,
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"provider":    selected,
+		"documents":   len(req.Documents),
+		"duration_ms": metadata.ProcessingTime.Milliseconds(),
+	}).Info("Rerank request routed")
+
+	return metadata, reranker, nil
+}
@@ -0,0 +1,237 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// DefaultRetryableErrors is used when a RetryConfig does not list its own
+var DefaultRetryableErrors = []string{"timeout", "connection", "unavailable", "rate limit", "429", "5xx"}
+
+// ValidateRetryConfig checks a retry policy's attempts, backoff and delays
+func ValidateRetryConfig(config *types.RetryConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.MaxAttempts < 0 || config.MaxAttempts > 5 {
+		return fmt.Errorf("retry max_attempts must be between 0 and 5")
+	}
+	switch config.BackoffType {
+	case "", "linear", "exponential":
+	default:
+		return fmt.Errorf("invalid retry backoff_type %q: must be linear or exponential", config.BackoffType)
+	}
+	if config.BaseDelay < 0 || config.MaxDelay < 0 {
+		return fmt.Errorf("retry delays must not be negative")
+	}
+	return nil
+}
+
+// SetDefaultRetry sets the retry policy for requests without a retry_config.
+// nil leaves them unretried.
+func (r *Router) SetDefaultRetry(config *types.RetryConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.defaultRetry = config
+}
+
+// retryConfig returns the retry policy for req: its own retry_config or the
+// router default. A request policy without retryable errors inherits the
+// default's.
+func (r *Router) retryConfig(req *types.ChatRequest) *types.RetryConfig {
+	r.mu.RLock()
+	defaultRetry := r.defaultRetry
+	r.mu.RUnlock()
+
+	if req.RetryConfig == nil {
+		return defaultRetry
+	}
+	if len(req.RetryConfig.RetryableErrors) == 0 && defaultRetry != nil && len(defaultRetry.RetryableErrors) > 0 {
+		config := *req.RetryConfig
+		config.RetryableErrors = defaultRetry.RetryableErrors
+		return &config
+	}
+	return req.RetryConfig
+}
+
+// executeWithRetry runs a chat completion against provider, retrying
+// retryable failures according to the request's retry policy. Every failed
+// attempt is recorded in metadata; the last error is returned once attempts
+// run out, a non-retryable error is hit or the retry budget is exhausted.
+func (r *Router) executeWithRetry(ctx context.Context, req *types.ChatRequest, providerName string, provider providers.LLMProvider, metadata *types.RouterMetadata) (*types.ChatResponse, error) {
+	retryConfig := r.retryConfig(req)
+	maxAttempts := 1
+	if retryConfig != nil && retryConfig.MaxAttempts > 1 {
+		maxAttempts = retryConfig.MaxAttempts
+	}
+
+	// Fallback providers and larger-context models are part of the same request
+	if len(metadata.AttemptErrors) == 0 {
+		r.retryBudget.deposit()
+	}
+
+	retryStart := time.Now()
+	var lastError error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// Apply backoff delay for retries
+		if attempt > 1 {
+			if !r.retryBudget.withdraw() {
+				metadata.RetryBudgetExhausted = true
+				r.logger.WithFields(logrus.Fields{
+					"provider": providerName,
+					"attempt":  attempt,
+				}).Warn("Retry budget exhausted, skipping retry")
+				break
+			}
+
+			delay := r.calculateBackoffDelay(retryConfig, attempt-1)
+			metadata.RetryDelays = append(metadata.RetryDelays, delay.Milliseconds())
+
+			r.logger.WithFields(logrus.Fields{
+				"provider": providerName,
+				"attempt":  attempt,
+				"delay_ms": delay.Milliseconds(),
+			}).Debug("Retrying completion after backoff")
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, fmt.Errorf("request cancelled during retry backoff: %w", ctx.Err())
+			}
+		}
+
+		// Attempts on earlier providers (before fallback) count towards the total
+		metadata.AttemptCount = len(metadata.AttemptErrors) + 1
+
+		attemptStart := time.Now()
+		resp, err := r.chatCompletion(ctx, req, providerName, provider)
+		if err == nil {
+			r.RecordLatency(providerName, time.Since(attemptStart))
+			if attempt > 1 {
+				metadata.TotalRetryTime += time.Since(retryStart).Milliseconds()
+			}
+			return resp, nil
+		}
+
+		lastError = err
+		r.checkCredentials(ctx, providerName, provider, err)
+		metadata.AttemptErrors = append(metadata.AttemptErrors, fmt.Sprintf("%s: %v", providerName, err))
+
+		r.logger.WithFields(logrus.Fields{
+			"provider": providerName,
+			"attempt":  attempt,
+			"error":    err.Error(),
+		}).Warn("Completion attempt failed")
+
+		// A full bulkhead won't drain within a backoff, nor will the request
+		// change; fall back instead
+		var bulkheadErr *BulkheadFullError
+		var stopErr *StopSequenceError
+		if ctx.Err() != nil || errors.As(err, &bulkheadErr) || errors.As(err, &stopErr) || !IsRetryableError(err, retryConfig) {
+			break
+		}
+	}
+
+	if maxAttempts > 1 {
+		metadata.TotalRetryTime += time.Since(retryStart).Milliseconds()
+	}
+	return nil, lastError
+}
+
+// abandonProvider records in metadata that the request gave up on
+// providerName, once however many of its attempts failed
+func abandonProvider(metadata *types.RouterMetadata, providerName string) {
+	if !contains(metadata.FailedProviders, providerName) {
+		metadata.FailedProviders = append(metadata.FailedProviders, providerName)
+	}
+}
+
+// IsRetryableError reports whether err matches one of the retryable error
+// patterns in config (DefaultRetryableErrors when none are set). Patterns are
+// matched against the error's providers.ErrorClass and may be an HTTP status
+// code ("429"), a status class ("5xx"), "timeout", "connection",
+// "rate limit", "unavailable", or any other substring of the error message.
+// Client errors other than 408 and 429, such as 400, 401 and 403, are never
+// retried, nor are provider errors normalized as not retryable, such as an
+// exhausted OpenAI quota.
+func IsRetryableError(err error, config *types.RetryConfig) bool {
+	if err == nil {
+		return false
+	}
+
+	class := providers.ErrorClass(err)
+	if class == providers.ErrorClassCanceled {
+		return false
+	}
+
+	status := providers.StatusCode(err)
+	if status >= 400 && status < 500 && status != 408 && status != 429 {
+		return false
+	}
+
+	var providerErr *providers.ProviderError
+	if errors.As(err, &providerErr) && providerErr.Type != "" && providerErr.Type != providers.ErrorTypeUnknown && !providerErr.Retryable {
+		return false
+	}
+
+	patterns := DefaultRetryableErrors
+	if config != nil && len(config.RetryableErrors) > 0 {
+		patterns = config.RetryableErrors
+	}
+
+	for _, pattern := range patterns {
+		if matchesRetryPattern(err, class, status, strings.ToLower(strings.TrimSpace(pattern))) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRetryPattern checks a single lower-cased retry pattern against err
+// and its error class
+func matchesRetryPattern(err error, class string, status int, pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	if pattern == class {
+		return true
+	}
+
+	// Status classes such as "5xx"
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") && pattern[0] >= '1' && pattern[0] <= '5' {
+		return status/100 == int(pattern[0]-'0')
+	}
+
+	// Exact status codes such as "429" only match their own class
+	if _, convErr := strconv.Atoi(pattern); convErr == nil {
+		return false
+	}
+
+	switch pattern {
+	case providers.ErrorClassTimeout:
+		if status == 408 || status == 504 {
+			return true
+		}
+	case "rate limit", "rate_limit":
+		if status == 429 {
+			return true
+		}
+	case "unavailable":
+		if status == 503 {
+			return true
+		}
+	}
+
+	return strings.Contains(strings.ToLower(err.Error()), pattern)
+}
@@ -0,0 +1,396 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// flakyProvider fails its first `failures` completions with err
+type flakyProvider struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "flaky"}
+}
+
+func (f *flakyProvider) GetProviderName() string { return "flaky" }
+
+func (f *flakyProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &types.ChatResponse{ID: "ok", Model: req.Model}, nil
+}
+
+func (f *flakyProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *flakyProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	return &types.CostEstimate{}, nil
+}
+
+func (f *flakyProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func statusError(status int) error {
+	return &providers.ProviderError{Provider: "flaky", StatusCode: status, Err: fmt.Errorf("status %d", status)}
+}
+
+func TestRouter_ExecuteCompletion_RetriesUntilSuccess(t *testing.T) {
+	router := createTestRouter(t)
+	provider := &flakyProvider{failures: 2, err: statusError(503)}
+
+	req := &types.ChatRequest{
+		Model: "test-model",
+		RetryConfig: &types.RetryConfig{
+			MaxAttempts: 3,
+			BackoffType: "linear",
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		},
+	}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+	resp, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata)
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if resp.ID != "ok" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if provider.calls != 3 {
+		t.Errorf("Expected 3 provider calls, got %d", provider.calls)
+	}
+	if metadata.AttemptCount != 3 {
+		t.Errorf("Expected attempt count 3, got %d", metadata.AttemptCount)
+	}
+	if len(metadata.AttemptErrors) != 2 {
+		t.Errorf("Expected 2 recorded attempt errors, got %v", metadata.AttemptErrors)
+	}
+	if len(metadata.FailedProviders) != 0 {
+		t.Errorf("Expected a provider that recovered not to be recorded as failed, got %v", metadata.FailedProviders)
+	}
+	// Delays are capped at MaxDelay
+	for _, delay := range metadata.RetryDelays {
+		if delay > 1 {
+			t.Errorf("Retry delay %dms exceeds MaxDelay", delay)
+		}
+	}
+}
+
+func TestRouter_ExecuteCompletion_RecordsFailedProviderOnce(t *testing.T) {
+	router := createTestRouter(t)
+	provider := &flakyProvider{failures: 3, err: statusError(503)}
+
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		RetryConfig: &types.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+	if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata); err == nil {
+		t.Fatal("Expected error once attempts run out")
+	}
+	if len(metadata.AttemptErrors) != 3 {
+		t.Errorf("Expected an error per attempt, got %v", metadata.AttemptErrors)
+	}
+	if len(metadata.FailedProviders) != 1 || metadata.FailedProviders[0] != "flaky" {
+		t.Errorf("Expected the provider to be recorded once, got %v", metadata.FailedProviders)
+	}
+}
+
+func TestRouter_ExecuteCompletion_StopsOnNonRetryableError(t *testing.T) {
+	router := createTestRouter(t)
+	provider := &flakyProvider{failures: 2, err: statusError(400)}
+
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		RetryConfig: &types.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+	if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata); err == nil {
+		t.Fatal("Expected error for bad request")
+	}
+	if provider.calls != 1 {
+		t.Errorf("Expected a single attempt for a 400, got %d", provider.calls)
+	}
+	if metadata.AttemptCount != 1 || len(metadata.RetryDelays) != 0 {
+		t.Errorf("Expected no retries, got %d attempts and delays %v", metadata.AttemptCount, metadata.RetryDelays)
+	}
+}
+
+func TestRouter_ExecuteCompletion_NoRetryConfig(t *testing.T) {
+	router := createTestRouter(t)
+	provider := &flakyProvider{failures: 1, err: statusError(503)}
+
+	req := &types.ChatRequest{Model: "test-model"}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+	if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata); err == nil {
+		t.Fatal("Expected error without retries configured")
+	}
+	if provider.calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", provider.calls)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{statusError(429), "429"},
+		{statusError(500), "500"},
+		{fmt.Errorf("route: %w", statusError(401)), "401"},
+		{&providers.ProviderError{Provider: "openai", Err: fmt.Errorf("post: %w", context.DeadlineExceeded)}, providers.ErrorClassTimeout},
+		{&providers.ProviderError{Provider: "openai", Err: &url.Error{Op: "Post", URL: "http://upstream", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}}, providers.ErrorClassConnection},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), providers.ErrorClassConnection},
+		{context.Canceled, providers.ErrorClassCanceled},
+		{errors.New("invalid schema"), providers.ErrorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := providers.ErrorClass(tt.err); got != tt.expected {
+			t.Errorf("ErrorClass(%v) = %s, want %s", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestRouter_ExecuteCompletion_DefaultRetry(t *testing.T) {
+	router := createTestRouter(t)
+	router.SetDefaultRetry(&types.RetryConfig{
+		MaxAttempts:     3,
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        time.Millisecond,
+		RetryableErrors: []string{"429"},
+	})
+
+	// Requests without a retry_config use the default
+	provider := &flakyProvider{failures: 2, err: statusError(429)}
+	req := &types.ChatRequest{Model: "test-model"}
+	if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, &types.RouterMetadata{Provider: "flaky"}); err != nil {
+		t.Fatalf("Expected the default policy to retry a 429, got %v", err)
+	}
+	if provider.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", provider.calls)
+	}
+
+	// Classes the default doesn't list, and client errors, fail at once
+	for _, err := range []error{statusError(503), statusError(401), statusError(403)} {
+		provider = &flakyProvider{failures: 3, err: err}
+		if _, execErr := router.ExecuteCompletion(context.Background(), req, "flaky", provider, &types.RouterMetadata{Provider: "flaky"}); execErr == nil {
+			t.Fatalf("Expected %v to fail", err)
+		}
+		if provider.calls != 1 {
+			t.Errorf("Expected a single attempt for %v, got %d", err, provider.calls)
+		}
+	}
+
+	// A request policy without retryable errors inherits the default's
+	provider = &flakyProvider{failures: 1, err: statusError(503)}
+	req = &types.ChatRequest{Model: "test-model", RetryConfig: &types.RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond}}
+	if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, &types.RouterMetadata{Provider: "flaky"}); err == nil {
+		t.Fatal("Expected a 503 not to be retried under the inherited classes")
+	}
+	if provider.calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", provider.calls)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		patterns []string
+		expected bool
+	}{
+		{"server error default", statusError(502), nil, true},
+		{"rate limited default", statusError(429), nil, true},
+		{"bad request default", statusError(400), nil, false},
+		{"unauthorized never retried", statusError(401), []string{"api call failed"}, false},
+		{"deadline exceeded", fmt.Errorf("call failed: %w", context.DeadlineExceeded), nil, true},
+		{"cancelled", context.Canceled, nil, false},
+		{"message substring", errors.New("connection reset by peer"), nil, true},
+		{"exact status only", statusError(503), []string{"429"}, false},
+		{"status class", statusError(503), []string{"5xx"}, true},
+		{"gateway timeout", statusError(504), []string{"timeout"}, true},
+		{"provider timeout", &providers.ProviderError{Provider: "openai", Err: fmt.Errorf("post: %w", context.DeadlineExceeded)}, []string{"timeout"}, true},
+		{"unknown error", errors.New("invalid schema"), nil, false},
+		{"forbidden never retried", statusError(403), []string{"403"}, false},
+		{"connection refused", &providers.ProviderError{Provider: "openai", Err: &url.Error{Op: "Post", URL: "http://upstream", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}}, []string{"connection"}, true},
+		{"connection not configured", &providers.ProviderError{Provider: "openai", Err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF)}, []string{"429", "5xx"}, false},
+		{"rate limit class only", statusError(429), []string{"429"}, true},
+		{"server error not configured", statusError(500), []string{"429", "timeout"}, false},
+		{"unavailable", statusError(503), []string{"unavailable"}, true},
+		{"quota exhausted never retried", providers.NewProviderError("openai", 429, "insufficient_quota", "You exceeded your current quota", errors.New("quota")), nil, false},
+		{"normalized rate limit", providers.NewProviderError("openai", 429, "rate_limit_exceeded", "Rate limit reached", errors.New("rate limit")), nil, true},
+		{"normalized overloaded", providers.NewProviderError("anthropic", 529, "overloaded_error", "Overloaded", errors.New("overloaded")), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.RetryConfig{RetryableErrors: tt.patterns}
+			if got := IsRetryableError(tt.err, config); got != tt.expected {
+				t.Errorf("IsRetryableError(%v, %v) = %v, want %v", tt.err, tt.patterns, got, tt.expected)
+			}
+		})
+	}
+}
+
+// contextProvider rejects prompts for models whose context window is below
+// minWindow, the way an upstream reports an oversized prompt
+type contextProvider struct {
+	flakyProvider
+	models    []types.ModelInfo
+	minWindow int
+	requested []string
+}
+
+func (c *contextProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "context", SupportedModels: c.models}
+}
+
+func (c *contextProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	c.requested = append(c.requested, req.Model)
+	for _, model := range c.models {
+		if model.Name == req.Model && model.MaxContextWindow < c.minWindow {
+			return nil, &providers.ProviderError{Provider: "context", StatusCode: 400, Err: errors.New("This model's maximum context length is exceeded (context_length_exceeded)")}
+		}
+	}
+	return &types.ChatResponse{ID: "ok", Model: req.Model}, nil
+}
+
+func TestRouter_ExecuteCompletion_PromotesOnContextLength(t *testing.T) {
+	router := createTestRouter(t)
+	newProvider := func() *contextProvider {
+		return &contextProvider{
+			minWindow: 100000,
+			models: []types.ModelInfo{
+				{Name: "gpt-small", MaxContextWindow: 8000},
+				{Name: "gpt-huge", MaxContextWindow: 1000000},
+				{Name: "gpt-large", MaxContextWindow: 128000},
+				{Name: "claude-big", MaxContextWindow: 200000},
+			},
+		}
+	}
+
+	provider := newProvider()
+	req := &types.ChatRequest{Model: "gpt-small", FallbackConfig: &types.FallbackConfig{Enabled: true}}
+	metadata := &types.RouterMetadata{Provider: "context", Model: "gpt-small"}
+
+	resp, err := router.ExecuteCompletion(context.Background(), req, "context", provider, metadata)
+	if err != nil {
+		t.Fatalf("Expected promotion to succeed, got %v", err)
+	}
+	// The smallest larger window of the same family is chosen
+	if resp.Model != "gpt-large" || metadata.Model != "gpt-large" {
+		t.Errorf("Expected promotion to gpt-large, got response %s, metadata %s", resp.Model, metadata.Model)
+	}
+	if !metadata.ContextPromoted || !metadata.ModelSubstituted || metadata.OriginalModel != "gpt-small" {
+		t.Errorf("Expected the promotion to be recorded, got %+v", metadata)
+	}
+	if len(provider.requested) != 2 {
+		t.Errorf("Expected 2 calls, got %v", provider.requested)
+	}
+
+	// Without fallback the original error is returned
+	provider = newProvider()
+	req = &types.ChatRequest{Model: "gpt-small"}
+	_, err = router.ExecuteCompletion(context.Background(), req, "context", provider, &types.RouterMetadata{})
+	if !providers.IsContextLengthError(err) || len(provider.requested) != 1 {
+		t.Errorf("Expected the context length error without promotion, got %v after %v", err, provider.requested)
+	}
+
+	// Nothing larger in the family: the original error is returned
+	provider = newProvider()
+	provider.minWindow = 2000000
+	req = &types.ChatRequest{Model: "gpt-large", FallbackConfig: &types.FallbackConfig{Enabled: true}}
+	_, err = router.ExecuteCompletion(context.Background(), req, "context", provider, &types.RouterMetadata{})
+	if !providers.IsContextLengthError(err) {
+		t.Errorf("Expected the context length error once no larger model is left, got %v", err)
+	}
+	if want := []string{"gpt-large", "gpt-huge"}; fmt.Sprint(provider.requested) != fmt.Sprint(want) {
+		t.Errorf("Expected calls %v, got %v", want, provider.requested)
+	}
+
+	// The only larger model is outside the caller's models: the original error is returned
+	provider = newProvider()
+	req = &types.ChatRequest{Model: "gpt-large", FallbackConfig: &types.FallbackConfig{Enabled: true}}
+	metadata = &types.RouterMetadata{Provider: "context", Model: "gpt-large"}
+	provider.minWindow = 200000
+	ctx := WithModelFilter(context.Background(), func(model string) bool { return model != "gpt-huge" })
+	_, err = router.ExecuteCompletion(ctx, req, "context", provider, metadata)
+	if !providers.IsContextLengthError(err) {
+		t.Errorf("Expected the context length error when no larger model is allowed, got %v", err)
+	}
+	if len(provider.requested) != 1 || req.Model != "gpt-large" || metadata.ContextPromoted {
+		t.Errorf("Expected no promotion to a disallowed model, got calls %v", provider.requested)
+	}
+}
+
+func TestRouter_ExecuteCompletion_RetryBudget(t *testing.T) {
+	router := createTestRouter(t)
+	router.SetRetryBudget(0.1)
+	provider := &flakyProvider{failures: 1 << 30, err: statusError(503)}
+
+	const requests = 200
+	exhausted := 0
+	for i := 0; i < requests; i++ {
+		req := &types.ChatRequest{
+			Model: "test-model",
+			RetryConfig: &types.RetryConfig{
+				MaxAttempts: 3,
+				BackoffType: "linear",
+				BaseDelay:   time.Microsecond,
+				MaxDelay:    time.Microsecond,
+			},
+		}
+		metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+		if _, err := router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata); err == nil {
+			t.Fatal("Expected the request to fail")
+		}
+		if metadata.RetryBudgetExhausted {
+			exhausted++
+		}
+	}
+
+	// The initial burst plus 10% of the requests may be retried
+	retries := provider.calls - requests
+	if maxRetries := retryBudgetBurst + requests/10; retries > maxRetries {
+		t.Errorf("Expected at most %d retries, got %d", maxRetries, retries)
+	}
+	if retries < requests/10 {
+		t.Errorf("Expected the budget to allow some retries, got %d", retries)
+	}
+	if exhausted < requests/2 {
+		t.Errorf("Expected most requests to find the budget exhausted, got %d", exhausted)
+	}
+
+	// Removing the budget retries every failure again
+	router.SetRetryBudget(0)
+	provider.calls = 0
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		RetryConfig: &types.RetryConfig{MaxAttempts: 3, BaseDelay: time.Microsecond, MaxDelay: time.Microsecond},
+	}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+	router.ExecuteCompletion(context.Background(), req, "flaky", provider, metadata)
+	if provider.calls != 3 || metadata.RetryBudgetExhausted {
+		t.Errorf("Expected 3 unthrottled attempts, got %d", provider.calls)
+	}
+}
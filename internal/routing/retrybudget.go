@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"fmt"
+	"sync"
+)
+
+// retryBudgetBurst is the most retries the budget holds, and what it starts
+// with, so low traffic can still retry a handful of failures
+const retryBudgetBurst = 10
+
+// ValidateRetryBudgetRatio checks a retry budget ratio: the fraction of
+// requests that may be retried, from 0 (unlimited) to 1
+func ValidateRetryBudgetRatio(ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return fmt.Errorf("retry_budget_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// retryBudget is a token bucket capping retries across all requests. Every
+// request deposits ratio tokens and every retry spends one, so during a
+// provider outage retries stay at about ratio times the request rate
+// instead of multiplying the load. A zero ratio leaves retries unlimited.
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget() *retryBudget {
+	return &retryBudget{tokens: retryBudgetBurst}
+}
+
+// setRatio changes the ratio, keeping the tokens already deposited
+func (b *retryBudget) setRatio(ratio float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.ratio = min(max(ratio, 0), 1)
+}
+
+// deposit credits the budget for a new request
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, retryBudgetBurst)
+}
+
+// withdraw spends a token for a retry, reporting false when the budget is
+// exhausted
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ratio == 0 {
+		return true
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// SetRetryBudget caps retries across all requests to ratio times the
+// request rate, see Settings.RetryBudgetRatio. 0 removes the cap.
+func (r *Router) SetRetryBudget(ratio float64) {
+	r.retryBudget.setRatio(ratio)
+}
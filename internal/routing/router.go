@@ -2,29 +2,80 @@ package routing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
 	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
 // Router handles intelligent request routing to LLM providers
 type Router struct {
+	// mu guards the provider registry, health status and routing settings so
+	// a config reload swaps them atomically with respect to routing
+	mu                sync.RWMutex
 	providers         map[string]providers.LLMProvider
 	providerNames     []string // for round-robin
-	roundRobinIndex   int
+	roundRobinIndex   atomic.Uint64 // advanced by concurrent requests holding only the read lock
 	healthStatus      map[string]*types.HealthStatus
 	logger            *logrus.Logger
-	lastHealthCheck   time.Time
-	healthCheckInterval time.Duration
+	healthCheckMu     sync.Mutex // guards lastHealthCheck and lastChecked
+	lastHealthCheck   time.Time  // last check of every provider
+	lastChecked       map[string]time.Time // last check per provider
+	healthCheckInterval time.Duration // default for providers without their own
+	healthCheckTimeout  time.Duration // bounds each provider's check
+	healthCheckConcurrency int        // providers checked at once
+	healthCachePath   string // persisted health for warm starts, empty disables
+	latencies         *latencyTracker
+	modelEquivalents  map[string][]ModelEquivalent
+	modelAliases      map[string]string // logical name -> model ID
+	modelPrefixes     map[string]string // model name prefix -> provider
+	modelOverrides    map[string]string // exact model name -> provider
+	rateLimiter       security.RateLimiter // per-model and per-provider limits
+	spendLimiter      *accounting.SpendLimiter // per-user spend caps, nil disables
+	defaultStrategy   RoutingStrategy
+	maxCostThreshold  float64 // global per-request cost cap, 0 disables
+	balancedAlpha     float64 // weight of cost vs latency for balanced routing
+	performanceMetric PerformanceMetric // latency statistic for performance routing
+	shadow            ShadowConfig // traffic mirrored to a candidate provider
+	defaultRetry      *types.RetryConfig // retry policy for requests without their own, nil disables
+	retryBudget       *retryBudget // caps retries across all requests
+	authFailureHandler AuthFailureHandler // told when a provider rejects its credentials
+	guardrail         GuardrailConfig // mandatory system prompt
+	requestTransformers  []RequestTransformer  // run in order before routing
+	responseTransformers []ResponseTransformer // run in order on completed responses
+	shadowSampler     func() float64 // overrides rand.Float64 for shadow sampling in tests
+	concurrency       *concurrencyLimiter
+	bulkheads         map[string]*bulkhead // per-provider concurrency isolation
+	providerConcurrency map[string]int     // per-provider limits, 0 or absent is unlimited
+	
+	// Weighted round-robin state
+	weightedMu        sync.Mutex
+	providerWeights   map[string]int
+	currentWeights    map[string]int
 }
 
+// Health check defaults, see Settings.HealthCheckTimeout and
+// Settings.HealthCheckConcurrency
+const (
+	DefaultHealthCheckTimeout     = 10 * time.Second
+	DefaultHealthCheckConcurrency = 4
+)
+
 // RoutingStrategy defines how to route requests
 type RoutingStrategy string
 
@@ -33,6 +84,9 @@ const (
 	RoutingStrategyPerformance   RoutingStrategy = "performance"
 	RoutingStrategyRoundRobin    RoutingStrategy = "round_robin"
 	RoutingStrategySpecific      RoutingStrategy = "specific"
+	RoutingStrategyWeighted      RoutingStrategy = "weighted"
+	RoutingStrategySticky        RoutingStrategy = "sticky"
+	RoutingStrategyBalanced      RoutingStrategy = "balanced"
 )
 
 // NewRouter creates a new router instance
@@ -40,17 +94,41 @@ func NewRouter(logger *logrus.Logger) *Router {
 	return &Router{
 		providers:           make(map[string]providers.LLMProvider),
 		providerNames:       make([]string, 0),
-		roundRobinIndex:     0,
 		healthStatus:        make(map[string]*types.HealthStatus),
 		logger:              logger,
+		lastChecked:         make(map[string]time.Time),
 		healthCheckInterval: 30 * time.Second,
+		healthCheckTimeout:  DefaultHealthCheckTimeout,
+		healthCheckConcurrency: DefaultHealthCheckConcurrency,
+		latencies:           newLatencyTracker(defaultLatencySmoothing),
+		modelEquivalents:    make(map[string][]ModelEquivalent),
+		modelAliases:        make(map[string]string),
+		modelPrefixes:       copyStringMap(DefaultModelPrefixes),
+		modelOverrides:      make(map[string]string),
+		defaultStrategy:     RoutingStrategyCostOptimized,
+		balancedAlpha:       DefaultBalancedAlpha,
+		performanceMetric:   PerformanceMetricMean,
+		providerWeights:     make(map[string]int),
+		currentWeights:      make(map[string]int),
+		concurrency:         newConcurrencyLimiter(0),
+		bulkheads:           make(map[string]*bulkhead),
+		providerConcurrency: make(map[string]int),
+		retryBudget:         newRetryBudget(),
 	}
 }
 
 // RegisterProvider adds a provider to the router
 func (r *Router) RegisterProvider(name string, provider providers.LLMProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[name]; !exists {
+		r.providerNames = append(r.providerNames, name)
+	}
 	r.providers[name] = provider
-	r.providerNames = append(r.providerNames, name)
+	if _, exists := r.bulkheads[name]; !exists {
+		r.bulkheads[name] = newBulkhead(r.providerConcurrency[name])
+	}
 	
 	// Initialize health status
 	r.healthStatus[name] = &types.HealthStatus{
@@ -61,14 +139,67 @@ func (r *Router) RegisterProvider(name string, provider providers.LLMProvider) {
 	r.logger.WithField("provider", name).Info("Provider registered")
 }
 
+// DeregisterProvider removes a provider so no new requests are routed to it.
+// Requests already routed to it keep their provider reference and complete
+// normally. A config reload that still lists the provider registers it again.
+func (r *Router) DeregisterProvider(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[name]; !exists {
+		return fmt.Errorf("provider %s not found", name)
+	}
+
+	position := slices.Index(r.providerNames, name)
+	names := slices.Delete(slices.Clone(r.providerNames), position, position+1)
+
+	// Keep the provider that was next in the rotation next
+	next := r.roundRobinIndex.Load() % uint64(len(r.providerNames))
+	if uint64(position) < next {
+		next--
+	}
+	if len(names) > 0 {
+		next %= uint64(len(names))
+	}
+	r.roundRobinIndex.Store(next)
+
+	delete(r.providers, name)
+	delete(r.healthStatus, name)
+	delete(r.bulkheads, name)
+	r.providerNames = names
+	r.latencies.forget(name)
+
+	r.healthCheckMu.Lock()
+	delete(r.lastChecked, name)
+	r.healthCheckMu.Unlock()
+
+	r.weightedMu.Lock()
+	delete(r.currentWeights, name)
+	r.weightedMu.Unlock()
+
+	r.logger.WithField("provider", name).Info("Provider deregistered")
+	return nil
+}
+
 // GetProvider returns a provider by name
 func (r *Router) GetProvider(name string) (providers.LLMProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	provider, exists := r.providers[name]
 	return provider, exists
 }
 
+// RecordLatency records an observed completion latency for a provider.
+// The router keeps an exponentially-weighted moving average per provider
+// which drives latency estimates and performance routing.
+func (r *Router) RecordLatency(provider string, d time.Duration) {
+	r.latencies.record(provider, d)
+}
+
 // ListProviders returns all registered provider names
 func (r *Router) ListProviders() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	names := make([]string, len(r.providerNames))
 	copy(names, r.providerNames)
 	return names
@@ -76,17 +207,48 @@ func (r *Router) ListProviders() []string {
 
 // Route selects the best provider for a request with retry and fallback support
 func (r *Router) Route(ctx context.Context, req *types.ChatRequest) (*types.RouterMetadata, providers.LLMProvider, error) {
+	ctx, span := tracing.Start(ctx, "router.route", tracing.AttrModel.String(req.Model))
+	if err := r.transformRequest(ctx, req); err != nil {
+		tracing.End(span, err)
+		return nil, nil, err
+	}
+	metadata, provider, err := r.route(ctx, req)
+	if err == nil {
+		span.SetAttributes(
+			tracing.AttrProvider.String(metadata.Provider),
+			tracing.AttrEstimatedCost.Float64(metadata.EstimatedCost),
+		)
+	}
+	tracing.End(span, err)
+	return metadata, provider, err
+}
+
+// route implements Route within its tracing span
+func (r *Router) route(ctx context.Context, req *types.ChatRequest) (*types.RouterMetadata, providers.LLMProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	start := time.Now()
 	
 	// Update health status if needed
-	if time.Since(r.lastHealthCheck) > r.healthCheckInterval {
-		// Use background context for health checks to avoid cancellation when request completes
-		go r.updateHealthStatus(context.Background())
-		r.lastHealthCheck = time.Now()
+	r.checkHealthIfDue()
+	
+	// Resolve logical model names before anything looks at the model
+	requestedModel := req.Model
+	model, aliased := r.resolveAlias(req.Model)
+	req.Model = model
+	
+	// Inject the mandatory system prompt before anything is dispatched
+	r.applyGuardrail(ctx, req)
+	
+	// Reject users who have reached their spend cap before spending more
+	if err := r.checkSpend(ctx, req); err != nil {
+		return nil, nil, err
 	}
 	
 	// Determine routing strategy
 	strategy := r.determineStrategy(req)
+	trace.SpanFromContext(ctx).SetAttributes(tracing.AttrStrategy.String(string(strategy)))
 	
 	// Route based on strategy to get initial decision
 	decision, provider, err := r.routeByStrategy(ctx, req, strategy)
@@ -94,6 +256,12 @@ func (r *Router) Route(ctx context.Context, req *types.ChatRequest) (*types.Rout
 		return nil, nil, err
 	}
 	
+	// Keep the selection within max_cost and the global cost threshold
+	decision, provider, overBudget, err := r.enforceBudget(req, decision, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	
 	// Initialize metadata tracking
 	metadata := &types.RouterMetadata{
 		Provider:        decision.SelectedProvider,
@@ -104,15 +272,29 @@ func (r *Router) Route(ctx context.Context, req *types.ChatRequest) (*types.Rout
 		RequestID:       req.ID,
 		AttemptCount:    1,
 		FallbackUsed:    false,
+		OverBudgetProviders: overBudget,
+		Tags:            req.Tags,
 	}
 	
-	// Check if retry is configured  
-	if req.RetryConfig != nil && req.RetryConfig.MaxAttempts > 1 {
-		// Perform routing with retry
-		metadata, provider, err = r.routeWithRetry(ctx, req, decision, metadata)
-		if err != nil {
-			return nil, nil, err
+	if aliased {
+		metadata.ModelAlias = requestedModel
+		metadata.RoutingReason = append(metadata.RoutingReason, fmt.Sprintf("Resolved model alias %s to %s", requestedModel, model))
+	}
+	
+	if decision.OriginalModel != "" {
+		metadata.OriginalModel = decision.OriginalModel
+		metadata.ModelSubstituted = true
+	}
+	
+	// Enforce per-model and per-provider rate limits
+	if limitErr := r.checkResourceLimits(ctx, req.Model, metadata.Provider); limitErr != nil {
+		if req.FallbackConfig == nil || !req.FallbackConfig.Enabled {
+			return nil, nil, limitErr
 		}
+		
+		// Route around the exhausted budget via the fallback chain
+		metadata.FailedProviders = append(metadata.FailedProviders, metadata.Provider)
+		metadata.RoutingReason = append(metadata.RoutingReason, limitErr.Error())
 	}
 	
 	// Check if fallback is configured and we have failures
@@ -124,6 +306,10 @@ func (r *Router) Route(ctx context.Context, req *types.ChatRequest) (*types.Rout
 		}
 	}
 	
+	if req.Explain {
+		metadata.RoutingTrace = r.explainRouting(req, strategy, metadata)
+	}
+	
 	// Update final processing time
 	metadata.ProcessingTime = time.Since(start)
 	
@@ -139,64 +325,6 @@ func (r *Router) Route(ctx context.Context, req *types.ChatRequest) (*types.Rout
 	return metadata, provider, nil
 }
 
-// routeWithRetry attempts to route with retry logic
-func (r *Router) routeWithRetry(ctx context.Context, req *types.ChatRequest, decision *RoutingDecision, metadata *types.RouterMetadata) (*types.RouterMetadata, providers.LLMProvider, error) {
-	provider := r.providers[decision.SelectedProvider]
-	maxAttempts := req.RetryConfig.MaxAttempts
-	var lastError error
-	
-	// Track retry attempts
-	var retryDelays []int64
-	totalRetryStart := time.Now()
-	
-	// Attempt up to maxAttempts times
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		metadata.AttemptCount = attempt
-		
-		// For attempts beyond the first, apply backoff delay
-		if attempt > 1 {
-			delay := r.calculateBackoffDelay(req.RetryConfig, attempt-1)
-			retryDelays = append(retryDelays, delay.Milliseconds())
-			
-			r.logger.WithFields(logrus.Fields{
-				"provider": decision.SelectedProvider,
-				"attempt":  attempt,
-				"delay_ms": delay.Milliseconds(),
-			}).Debug("Retrying request after backoff delay")
-			
-			select {
-			case <-time.After(delay):
-				// Continue with retry
-			case <-ctx.Done():
-				return nil, nil, fmt.Errorf("request cancelled during retry backoff: %w", ctx.Err())
-			}
-		}
-		
-		// Check provider health before retry
-		if !r.isProviderHealthy(decision.SelectedProvider) {
-			lastError = fmt.Errorf("provider %s is not healthy", decision.SelectedProvider)
-			r.logger.WithField("provider", decision.SelectedProvider).Warn("Provider unhealthy during retry")
-			continue
-		}
-		
-		// Attempt would succeed - return provider for actual request
-		metadata.RetryDelays = retryDelays
-		metadata.TotalRetryTime = time.Since(totalRetryStart).Milliseconds()
-		
-		r.logger.WithFields(logrus.Fields{
-			"provider":     decision.SelectedProvider,
-			"attempt":      attempt,
-			"retry_delays": retryDelays,
-		}).Info("Retry attempt ready")
-		
-		return metadata, provider, nil
-	}
-	
-	// All retry attempts exhausted
-	metadata.FailedProviders = append(metadata.FailedProviders, decision.SelectedProvider)
-	return metadata, nil, fmt.Errorf("all retry attempts failed for provider %s: %w", decision.SelectedProvider, lastError)
-}
-
 // routeWithFallback attempts fallback to alternative providers
 func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest, originalDecision *RoutingDecision, metadata *types.RouterMetadata) (*types.RouterMetadata, providers.LLMProvider, error) {
 	// Build fallback chain based on configuration
@@ -214,7 +342,7 @@ func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest,
 	fallbackChain = r.filterFallbackChain(fallbackChain, req, originalDecision)
 	
 	if len(fallbackChain) == 0 {
-		return metadata, nil, fmt.Errorf("no suitable fallback providers available")
+		return metadata, nil, fmt.Errorf("%w: no suitable fallback providers", ErrNoHealthyProviders)
 	}
 	
 	r.logger.WithFields(logrus.Fields{
@@ -222,8 +350,64 @@ func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest,
 		"fallback_chain":   fallbackChain,
 	}).Info("Attempting fallback routing")
 	
-	// Try each fallback provider
-	for _, providerName := range fallbackChain {
+	provider, err := r.selectFallback(ctx, req, fallbackChain, originalDecision.EstimatedCost, metadata)
+	if err != nil {
+		return metadata, nil, err
+	}
+	
+	r.logger.WithFields(logrus.Fields{
+		"original_provider": originalDecision.SelectedProvider,
+		"fallback_provider": metadata.Provider,
+	}).Info("Fallback routing successful")
+	
+	return metadata, provider, nil
+}
+
+// NextFallback selects a provider to take over req after the provider in
+// metadata failed at execution. The chain and the checks on each candidate
+// are those of routing-time fallback, priced against the routed estimate in
+// metadata; providers in metadata.FailedProviders are skipped. On success
+// metadata records the selected provider.
+func (r *Router) NextFallback(ctx context.Context, req *types.ChatRequest, metadata *types.RouterMetadata) (providers.LLMProvider, error) {
+	if req.FallbackConfig == nil || !req.FallbackConfig.Enabled {
+		return nil, fmt.Errorf("%w: fallback not enabled", ErrNoHealthyProviders)
+	}
+	
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	fallbackChain := req.FallbackConfig.PreferredChain
+	if len(fallbackChain) == 0 {
+		fallbackChain = r.buildFallbackChain(metadata.Provider, req)
+	}
+	current := &RoutingDecision{SelectedProvider: metadata.Provider, EstimatedCost: metadata.EstimatedCost}
+	fallbackChain = r.filterFallbackChain(fallbackChain, req, current)
+	
+	failed := metadata.Provider
+	provider, err := r.selectFallback(ctx, req, fallbackChain, metadata.EstimatedCost, metadata)
+	if err != nil {
+		return nil, err
+	}
+	
+	r.logger.WithFields(logrus.Fields{
+		"failed_provider":   failed,
+		"fallback_provider": metadata.Provider,
+	}).Info("Execution fallback selected")
+	
+	return provider, nil
+}
+
+// selectFallback returns the first provider in chain fit to take over req:
+// healthy, supporting its features, within max_cost_increase of originalCost,
+// its cost budget and min_quality_score, and under its rate limits. Callers
+// hold mu.
+func (r *Router) selectFallback(ctx context.Context, req *types.ChatRequest, chain []string, originalCost float64, metadata *types.RouterMetadata) (providers.LLMProvider, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: no suitable fallback providers", ErrNoHealthyProviders)
+	}
+	
+	var limitErr error
+	for _, providerName := range chain {
 		// Skip if provider already failed
 		if contains(metadata.FailedProviders, providerName) {
 			continue
@@ -239,7 +423,7 @@ func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest,
 		provider := r.providers[providerName]
 		
 		// Check feature compatibility
-		if req.FallbackConfig.RequireSameFeatures && !r.supportsRequiredFeatures(provider, req) {
+		if !r.supportsFallback(provider, req) {
 			r.logger.WithField("provider", providerName).Debug("Fallback provider doesn't support required features")
 			continue
 		}
@@ -248,7 +432,7 @@ func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest,
 		if req.FallbackConfig.MaxCostIncrease != nil {
 			costEst, err := provider.EstimateCost(req)
 			if err == nil {
-				costIncrease := (costEst.TotalCost - originalDecision.EstimatedCost) / originalDecision.EstimatedCost
+				costIncrease := relativeCostIncrease(originalCost, costEst.TotalCost)
 				if costIncrease > *req.FallbackConfig.MaxCostIncrease {
 					r.logger.WithFields(logrus.Fields{
 						"provider":       providerName,
@@ -260,20 +444,64 @@ func (r *Router) routeWithFallback(ctx context.Context, req *types.ChatRequest,
 			}
 		}
 		
+		// Check the request's cost budget
+		if fits, cost := r.withinBudget(providerName, provider, req); !fits {
+			r.logger.WithFields(logrus.Fields{
+				"provider": providerName,
+				"cost":     cost,
+			}).Debug("Fallback provider exceeds cost budget")
+			continue
+		}
+		
+		// Check quality degradation
+		if minQuality := req.FallbackConfig.MinQualityScore; minQuality != nil {
+			if quality := modelQuality(provider, req.Model); quality < *minQuality {
+				r.logger.WithFields(logrus.Fields{
+					"provider":    providerName,
+					"quality":     quality,
+					"min_quality": *minQuality,
+				}).Debug("Fallback provider below minimum quality")
+				metadata.LowQualityProviders = append(metadata.LowQualityProviders, providerName)
+				continue
+			}
+		}
+		
+		// Check provider and model rate limits
+		if err := r.checkResourceLimits(ctx, req.Model, providerName); err != nil {
+			limitErr = err
+			r.logger.WithField("provider", providerName).Debug("Skipping rate-limited fallback provider")
+			continue
+		}
+		
 		// Fallback provider is suitable
 		metadata.Provider = providerName
 		metadata.FallbackUsed = true
 		metadata.RoutingReason = append(metadata.RoutingReason, fmt.Sprintf("Fallback to %s", providerName))
-		
-		r.logger.WithFields(logrus.Fields{
-			"original_provider": originalDecision.SelectedProvider,
-			"fallback_provider": providerName,
-		}).Info("Fallback routing successful")
-		
-		return metadata, provider, nil
+		return provider, nil
 	}
 	
-	return metadata, nil, fmt.Errorf("all fallback providers failed or unavailable")
+	if len(metadata.LowQualityProviders) > 0 {
+		return nil, fmt.Errorf("no fallback meets min_quality_score %.2f (below minimum: %s)",
+			*req.FallbackConfig.MinQualityScore, strings.Join(metadata.LowQualityProviders, ", "))
+	}
+	if limitErr != nil {
+		return nil, limitErr
+	}
+	return nil, fmt.Errorf("%w: all fallback providers failed or unavailable", ErrNoHealthyProviders)
+}
+
+// modelQuality returns the quality score of model on provider. Providers that
+// don't list the model are scored by their best rated model, the most they
+// could offer; unrated models score zero.
+func modelQuality(provider providers.LLMProvider, model string) float64 {
+	best := 0.0
+	for _, info := range provider.GetCapabilities().SupportedModels {
+		if info.Name == model || info.ProviderModelID == model {
+			return info.QualityScore
+		}
+		best = max(best, info.QualityScore)
+	}
+	return best
 }
 
 // calculateBackoffDelay calculates retry delay based on backoff strategy
@@ -317,12 +545,29 @@ func (r *Router) filterFallbackChain(chain []string, req *types.ChatRequest, ori
 			continue
 		}
 		
+		if !r.supportsFallback(r.providers[providerName], req) {
+			continue
+		}
+		
 		filtered = append(filtered, providerName)
 	}
 	
 	return filtered
 }
 
+// relativeCostIncrease returns how much more candidate costs than original as
+// a fraction of original. Any paid candidate is an unbounded increase over a
+// free original, such as a local model.
+func relativeCostIncrease(original, candidate float64) float64 {
+	if original <= 0 {
+		if candidate <= 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (candidate - original) / original
+}
+
 // contains checks if a string slice contains a value
 func contains(slice []string, value string) bool {
 	for _, item := range slice {
@@ -346,48 +591,59 @@ func (r *Router) determineStrategy(req *types.ChatRequest) RoutingStrategy {
 		return RoutingStrategyCostOptimized
 	case types.OptimizePerformance:
 		return RoutingStrategyPerformance
+	case types.OptimizeWeighted:
+		return RoutingStrategyWeighted
+	case types.OptimizeSticky:
+		return RoutingStrategySticky
+	case types.OptimizeBalanced:
+		return RoutingStrategyBalanced
 	default:
-		return RoutingStrategyCostOptimized // Default to cost optimization
+		return r.defaultStrategy // Cost optimization unless configured otherwise
 	}
 }
 
 // isSpecificProviderRequested checks if a specific provider is requested
 func (r *Router) isSpecificProviderRequested(model string) bool {
 	// Check if model name contains provider-specific prefixes
-	providerPrefixes := map[string]string{
-		"gpt-":    "openai",
-		"claude-": "anthropic",
-	}
-	
-	for prefix := range providerPrefixes {
-		if strings.HasPrefix(model, prefix) {
-			return true
-		}
-	}
-	
-	return false
+	_, found := r.mappedProvider(model)
+	return found
 }
 
 // getProviderForModel returns the provider that should handle a specific model
 func (r *Router) getProviderForModel(model string) (string, bool) {
-	providerPrefixes := map[string]string{
-		"gpt-":    "openai",
-		"claude-": "anthropic",
+	providerName, found := r.mappedProvider(model)
+	if !found {
+		return "", false
 	}
-	
-	for prefix, providerName := range providerPrefixes {
-		if strings.HasPrefix(model, prefix) {
-			if _, exists := r.providers[providerName]; exists {
-				return providerName, true
-			}
-		}
+	if _, exists := r.providers[providerName]; !exists {
+		return "", false
 	}
-	
-	return "", false
+	return providerName, true
 }
 
 // routeByStrategy routes the request using the specified strategy
 func (r *Router) routeByStrategy(ctx context.Context, req *types.ChatRequest, strategy RoutingStrategy) (*RoutingDecision, providers.LLMProvider, error) {
+	ctx, span := tracing.Start(ctx, "router.route_by_strategy",
+		tracing.AttrStrategy.String(string(strategy)),
+		tracing.AttrModel.String(req.Model),
+	)
+	decision, provider, err := r.selectByStrategy(ctx, req, strategy)
+	if err == nil {
+		span.SetAttributes(
+			tracing.AttrProvider.String(decision.SelectedProvider),
+			tracing.AttrEstimatedCost.Float64(decision.EstimatedCost),
+		)
+	}
+	tracing.End(span, err)
+	return decision, provider, err
+}
+
+// selectByStrategy dispatches to the strategy's routing function
+func (r *Router) selectByStrategy(ctx context.Context, req *types.ChatRequest, strategy RoutingStrategy) (*RoutingDecision, providers.LLMProvider, error) {
+	// A client that has gone away doesn't need a provider picked
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	switch strategy {
 	case RoutingStrategySpecific:
 		return r.routeToSpecificProvider(ctx, req)
@@ -397,6 +653,12 @@ func (r *Router) routeByStrategy(ctx context.Context, req *types.ChatRequest, st
 		return r.routeByPerformance(ctx, req)
 	case RoutingStrategyRoundRobin:
 		return r.routeRoundRobin(ctx, req)
+	case RoutingStrategyWeighted:
+		return r.routeWeightedRoundRobin(ctx, req)
+	case RoutingStrategySticky:
+		return r.routeSticky(ctx, req)
+	case RoutingStrategyBalanced:
+		return r.routeBalanced(ctx, req)
 	default:
 		return r.routeByCost(ctx, req)
 	}
@@ -406,14 +668,25 @@ func (r *Router) routeByStrategy(ctx context.Context, req *types.ChatRequest, st
 func (r *Router) routeToSpecificProvider(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
 	providerName, found := r.getProviderForModel(req.Model)
 	if !found {
-		return nil, nil, fmt.Errorf("no provider found for model %s", req.Model)
+		providerName, found = r.listingProvider(req.Model)
+	}
+	if !found || !r.isKnownModel(providerName, req.Model) {
+		return r.routeUnknownModel(req)
 	}
 	
 	provider := r.providers[providerName]
 	
+	// Reject rather than degrade schema-constrained output to plain text
+	if !supportsResponseFormat(provider.GetCapabilities(), req) {
+		return nil, nil, &UnsupportedFeatureError{Provider: providerName, Feature: responseFormatFeature(req)}
+	}
+	
 	// Check if provider is healthy
 	if !r.isProviderHealthy(providerName) {
-		return nil, nil, fmt.Errorf("provider %s is not healthy", providerName)
+		if req.FallbackConfig == nil || !req.FallbackConfig.Enabled || !req.FallbackConfig.AllowModelSubstitution {
+			return nil, nil, fmt.Errorf("%w: provider %s is not healthy", ErrNoHealthyProviders, providerName)
+		}
+		return r.routeToEquivalentModel(req, providerName)
 	}
 	
 	// Get cost estimate
@@ -436,11 +709,54 @@ func (r *Router) routeToSpecificProvider(ctx context.Context, req *types.ChatReq
 	return decision, provider, nil
 }
 
+// routeToEquivalentModel substitutes an equivalent model on another provider
+// when the requested model's owning provider is unhealthy. The request model is
+// rewritten so the selected provider receives its own model identifier.
+func (r *Router) routeToEquivalentModel(req *types.ChatRequest, owner string) (*RoutingDecision, providers.LLMProvider, error) {
+	target, found := r.findEquivalent(req.Model, owner)
+	if !found {
+		return nil, nil, fmt.Errorf("%w: provider %s is not healthy and no equivalent model is available for %s", ErrNoHealthyProviders, owner, req.Model)
+	}
+	
+	originalModel := req.Model
+	req.Model = target.Model
+	provider := r.providers[target.Provider]
+	
+	costEst, err := provider.EstimateCost(req)
+	if err != nil {
+		r.logger.WithError(err).Warnf("Failed to estimate cost for %s", target.Provider)
+		costEst = &types.CostEstimate{TotalCost: 0}
+	}
+	
+	r.logger.WithFields(logrus.Fields{
+		"original_provider": owner,
+		"original_model":    originalModel,
+		"provider":          target.Provider,
+		"model":             target.Model,
+	}).Warn("Owning provider unhealthy, substituting equivalent model")
+	
+	decision := &RoutingDecision{
+		SelectedProvider: target.Provider,
+		Reasoning: []string{
+			fmt.Sprintf("Specific model requested: %s", originalModel),
+			fmt.Sprintf("Provider %s unhealthy, substituted equivalent model %s on %s", owner, target.Model, target.Provider),
+		},
+		EstimatedCost:        costEst.TotalCost,
+		EstimatedLatency:     r.estimateLatency(target.Provider),
+		FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+		FallbackChain:        r.buildFallbackChain(target.Provider, req),
+		RoutingContext:       r.buildRoutingContext("specific", req, []string{owner, target.Provider}),
+		OriginalModel:        originalModel,
+	}
+	
+	return decision, provider, nil
+}
+
 // routeByCost routes to the most cost-effective provider
 func (r *Router) routeByCost(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
 	candidates := r.getHealthyProviders()
 	if len(candidates) == 0 {
-		return nil, nil, fmt.Errorf("no healthy providers available")
+		return nil, nil, ErrNoHealthyProviders
 	}
 	
 	// Filter providers by feature requirements
@@ -458,13 +774,25 @@ func (r *Router) routeByCost(ctx context.Context, req *types.ChatRequest) (*Rout
 	}
 	
 	var costsAndProviders []candidateWithCost
+	var staticPriced []string
 	
 	for _, name := range candidates {
+		// Stop estimating once the request is cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
 		provider := r.providers[name]
 		costEst, err := provider.EstimateCost(req)
 		if err != nil {
-			r.logger.WithError(err).Warnf("Failed to estimate cost for %s", name)
-			continue
+			// Fall back to the provider's advertised pricing
+			staticEst, ok := staticCostEstimate(provider, req)
+			if !ok {
+				r.logger.WithError(err).Warnf("Failed to estimate cost for %s and no static pricing is available", name)
+				continue
+			}
+			r.logger.WithError(err).Warnf("Failed to estimate cost for %s, using static pricing", name)
+			costEst = staticEst
+			staticPriced = append(staticPriced, name)
 		}
 		
 		costsAndProviders = append(costsAndProviders, candidateWithCost{
@@ -476,7 +804,7 @@ func (r *Router) routeByCost(ctx context.Context, req *types.ChatRequest) (*Rout
 	}
 	
 	if len(costsAndProviders) == 0 {
-		return nil, nil, fmt.Errorf("could not estimate costs for any provider")
+		return nil, nil, fmt.Errorf("could not estimate costs for any provider: no pricing information available")
 	}
 	
 	// Sort by cost (ascending)
@@ -499,6 +827,11 @@ func (r *Router) routeByCost(ctx context.Context, req *types.ChatRequest) (*Rout
 		reasoning = append(reasoning, fmt.Sprintf("Saves $%.6f vs %s", savings, next.name))
 	}
 	
+	if len(staticPriced) > 0 {
+		sort.Strings(staticPriced)
+		reasoning = append(reasoning, fmt.Sprintf("Live cost estimate unavailable, used static pricing for: %s", strings.Join(staticPriced, ", ")))
+	}
+	
 	// Build cost comparison data
 	costComparison := make(map[string]float64)
 	for _, candidate := range costsAndProviders {
@@ -522,7 +855,7 @@ func (r *Router) routeByCost(ctx context.Context, req *types.ChatRequest) (*Rout
 func (r *Router) routeByPerformance(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
 	candidates := r.getHealthyProviders()
 	if len(candidates) == 0 {
-		return nil, nil, fmt.Errorf("no healthy providers available")
+		return nil, nil, ErrNoHealthyProviders
 	}
 	
 	// Filter providers by feature requirements
@@ -531,15 +864,15 @@ func (r *Router) routeByPerformance(ctx context.Context, req *types.ChatRequest)
 		return nil, nil, fmt.Errorf("no providers support required features")
 	}
 	
-	// For now, use a simple heuristic: OpenAI tends to be faster
-	// In a real implementation, we'd track actual latencies
-	selected := candidates[0]
-	for _, name := range candidates {
-		if name == "openai" {
-			selected = name
-			break
+	// Sort by measured latency (ascending), breaking ties by name for stable selection
+	sort.Slice(candidates, func(i, j int) bool {
+		li, lj := r.performanceLatency(candidates[i]), r.performanceLatency(candidates[j])
+		if li != lj {
+			return li < lj
 		}
-	}
+		return candidates[i] < candidates[j]
+	})
+	selected := candidates[0]
 	
 	provider := r.providers[selected]
 	
@@ -553,12 +886,12 @@ func (r *Router) routeByPerformance(ctx context.Context, req *types.ChatRequest)
 	// Build performance comparison data
 	performanceComparison := make(map[string]time.Duration)
 	for _, name := range candidates {
-		performanceComparison[name] = r.estimateLatency(name)
+		performanceComparison[name] = r.performanceLatency(name)
 	}
 	
 	decision := &RoutingDecision{
 		SelectedProvider:     selected,
-		Reasoning:           []string{fmt.Sprintf("Performance-optimized routing selected %s", selected)},
+		Reasoning:           []string{fmt.Sprintf("Performance-optimized routing selected %s by %s latency (%s)", selected, r.performanceMetric, performanceComparison[selected])},
 		EstimatedCost:       costEst.TotalCost,
 		EstimatedLatency:    r.estimateLatency(selected),
 		FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
@@ -573,7 +906,7 @@ func (r *Router) routeByPerformance(ctx context.Context, req *types.ChatRequest)
 func (r *Router) routeRoundRobin(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
 	candidates := r.getHealthyProviders()
 	if len(candidates) == 0 {
-		return nil, nil, fmt.Errorf("no healthy providers available")
+		return nil, nil, ErrNoHealthyProviders
 	}
 	
 	// Filter providers by feature requirements
@@ -583,8 +916,8 @@ func (r *Router) routeRoundRobin(ctx context.Context, req *types.ChatRequest) (*
 	}
 	
 	// Select next provider in round-robin fashion
-	selected := candidates[r.roundRobinIndex%len(candidates)]
-	r.roundRobinIndex++
+	index := r.roundRobinIndex.Add(1) - 1
+	selected := candidates[index%uint64(len(candidates))]
 	
 	provider := r.providers[selected]
 	
@@ -608,10 +941,11 @@ func (r *Router) routeRoundRobin(ctx context.Context, req *types.ChatRequest) (*
 	return decision, provider, nil
 }
 
-// getHealthyProviders returns a list of healthy provider names
+// getHealthyProviders returns the healthy provider names in registration
+// order, so strategies that walk the list behave deterministically
 func (r *Router) getHealthyProviders() []string {
 	var healthy []string
-	for name := range r.providers {
+	for _, name := range r.providerNames {
 		if r.isProviderHealthy(name) {
 			healthy = append(healthy, name)
 		}
@@ -626,13 +960,23 @@ func (r *Router) isProviderHealthy(name string) bool {
 		return false
 	}
 	
+	// Providers in maintenance stay out of rotation until it is cleared
+	if status.Maintenance {
+		return false
+	}
+	
+	// A provider at its concurrency limit is skipped rather than queued on
+	if r.isBulkheadFull(name) {
+		return false
+	}
+	
 	// Consider provider healthy if status is "healthy" or "unknown" (untested)
 	return status.Status == "healthy" || status.Status == "unknown"
 }
 
 // filterByFeatures filters providers based on required features
 func (r *Router) filterByFeatures(candidates []string, req *types.ChatRequest) []string {
-	if len(req.RequiredFeatures) == 0 && len(req.Tools) == 0 && len(req.Functions) == 0 {
+	if len(req.RequiredFeatures) == 0 && len(req.Tools) == 0 && len(req.Functions) == 0 && !requestsJSONSchema(req) && !needsVision(req) {
 		return candidates // No special features required
 	}
 	
@@ -650,6 +994,12 @@ func (r *Router) filterByFeatures(candidates []string, req *types.ChatRequest) [
 
 // supportsRequiredFeatures checks if a provider supports the required features
 func (r *Router) supportsRequiredFeatures(provider providers.LLMProvider, req *types.ChatRequest) bool {
+	return r.missingFeature(provider, req) == ""
+}
+
+// missingFeature returns the first feature req needs that provider lacks, or
+// "" when it supports them all
+func (r *Router) missingFeature(provider providers.LLMProvider, req *types.ChatRequest) string {
 	capabilities := provider.GetCapabilities()
 	
 	// Check explicit required features
@@ -657,27 +1007,27 @@ func (r *Router) supportsRequiredFeatures(provider providers.LLMProvider, req *t
 		switch feature {
 		case "functions", "function_calling":
 			if !capabilities.SupportsFunctions {
-				return false
+				return feature
 			}
 		case "vision":
 			if !capabilities.SupportsVision {
-				return false
+				return feature
 			}
 		case "structured_output":
 			if !capabilities.SupportsStructuredOutput {
-				return false
+				return feature
 			}
 		case "streaming":
 			if !capabilities.SupportsStreaming {
-				return false
+				return feature
 			}
 		case "assistants":
 			if !capabilities.SupportsAssistants {
-				return false
+				return feature
 			}
 		case "batch":
 			if !capabilities.SupportsBatch {
-				return false
+				return feature
 			}
 		}
 	}
@@ -685,24 +1035,20 @@ func (r *Router) supportsRequiredFeatures(provider providers.LLMProvider, req *t
 	// Check if tools/functions are requested
 	if len(req.Tools) > 0 || len(req.Functions) > 0 {
 		if !capabilities.SupportsFunctions {
-			return false
+			return "functions"
 		}
 	}
 	
+	if !supportsResponseFormat(capabilities, req) {
+		return responseFormatFeature(req)
+	}
+	
 	// Check multimodal content
-	for _, msg := range req.Messages {
-		if parts, ok := msg.Content.([]types.ContentPart); ok {
-			for _, part := range parts {
-				if part.Type == "image_url" {
-					if !capabilities.SupportsVision {
-						return false
-					}
-				}
-			}
-		}
+	if !capabilities.SupportsVision && needsVision(req) {
+		return "vision"
 	}
 	
-	return true
+	return ""
 }
 
 // checkFeatureCompatibility returns feature compatibility status
@@ -720,27 +1066,130 @@ func (r *Router) checkFeatureCompatibility(provider providers.LLMProvider, req *
 	return compatibility
 }
 
-// buildFallbackChain creates a fallback chain for the request
+// buildFallbackChain creates a fallback chain for the request, ordered by
+// the request's routing strategy: fastest first for performance routing,
+// cheapest first otherwise. Ties are broken by provider name so the same
+// request always produces the same chain.
 func (r *Router) buildFallbackChain(primary string, req *types.ChatRequest) []string {
-	candidates := r.getHealthyProviders()
 	var fallbacks []string
-	
-	for _, name := range candidates {
-		if name != primary {
-			provider := r.providers[name]
-			if r.supportsRequiredFeatures(provider, req) {
-				fallbacks = append(fallbacks, name)
-			}
+	for _, name := range r.getHealthyProviders() {
+		if name == primary {
+			continue
+		}
+		if !r.supportsFallback(r.providers[name], req) {
+			continue
 		}
+		fallbacks = append(fallbacks, name)
 	}
 	
+	r.orderFallbacks(fallbacks, req)
 	return fallbacks
 }
 
-// estimateLatency provides a rough latency estimate
+// requireSameFeatures reports whether fallbacks must support every feature
+// the request uses. This is the default unless the request's fallback config
+// explicitly opts out.
+func requireSameFeatures(req *types.ChatRequest) bool {
+	return req.FallbackConfig == nil || req.FallbackConfig.RequireSameFeatures
+}
+
+// supportsFallback reports whether provider can take over req as a fallback.
+// Vision and function calling are always required, since a provider without
+// them can only fail the request; the request's other features only when
+// fallbacks must support the same features.
+func (r *Router) supportsFallback(provider providers.LLMProvider, req *types.ChatRequest) bool {
+	if requireSameFeatures(req) {
+		return r.supportsRequiredFeatures(provider, req)
+	}
+	return missingEssentialFeature(provider.GetCapabilities(), req) == ""
+}
+
+// missingEssentialFeature returns "vision" or "functions" when req needs it,
+// explicitly in required_features or implicitly through image content or
+// tools, and the provider lacks it, or "" otherwise
+func missingEssentialFeature(capabilities types.ProviderCapabilities, req *types.ChatRequest) string {
+	if !capabilities.SupportsVision && needsVision(req) {
+		return "vision"
+	}
+	if !capabilities.SupportsFunctions && needsFunctions(req) {
+		return "functions"
+	}
+	return ""
+}
+
+// needsVision reports whether req requires or sends images
+func needsVision(req *types.ChatRequest) bool {
+	if slices.Contains(req.RequiredFeatures, "vision") {
+		return true
+	}
+	for _, msg := range req.Messages {
+		if types.HasImage(msg.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsFunctions reports whether req requires function calling or sends tools
+func needsFunctions(req *types.ChatRequest) bool {
+	return len(req.Tools) > 0 || len(req.Functions) > 0 ||
+		slices.Contains(req.RequiredFeatures, "functions") || slices.Contains(req.RequiredFeatures, "function_calling")
+}
+
+// IsFallbackCompatible reports whether the named provider can serve req as a
+// fallback, see supportsFallback
+func (r *Router) IsFallbackCompatible(name string, req *types.ChatRequest) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, exists := r.providers[name]
+	return exists && r.supportsFallback(provider, req)
+}
+
+// orderFallbacks sorts fallback providers in place by the request's strategy.
+// Providers that can't price the request sort after those that can.
+func (r *Router) orderFallbacks(names []string, req *types.ChatRequest) {
+	if r.determineStrategy(req) == RoutingStrategyPerformance {
+		latencies := make(map[string]time.Duration, len(names))
+		for _, name := range names {
+			latencies[name] = r.estimateLatency(name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if latencies[names[i]] != latencies[names[j]] {
+				return latencies[names[i]] < latencies[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		return
+	}
+	
+	costs := make(map[string]float64, len(names))
+	for _, name := range names {
+		if estimate, err := r.providers[name].EstimateCost(req); err == nil {
+			costs[name] = estimate.TotalCost
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		costI, pricedI := costs[names[i]]
+		costJ, pricedJ := costs[names[j]]
+		if pricedI != pricedJ {
+			return pricedI
+		}
+		if costI != costJ {
+			return costI < costJ
+		}
+		return names[i] < names[j]
+	})
+}
+
+// estimateLatency returns the measured latency average for a provider,
+// falling back to a static heuristic until measurements are available
 func (r *Router) estimateLatency(providerName string) time.Duration {
-	// Simple heuristic based on provider characteristics
-	// In production, this would be based on actual measurements
+	if latency, ok := r.latencies.get(providerName); ok {
+		return latency
+	}
+	
+	// No measurements yet, use provider characteristics
 	switch providerName {
 	case "openai":
 		return 800 * time.Millisecond
@@ -751,33 +1200,124 @@ func (r *Router) estimateLatency(providerName string) time.Duration {
 	}
 }
 
-// updateHealthStatus performs health checks on all providers
-func (r *Router) updateHealthStatus(ctx context.Context) {
+// checkHealthIfDue starts a background health check of the providers whose
+// last check is older than their health check interval. Callers hold mu.
+func (r *Router) checkHealthIfDue() {
+	r.healthCheckMu.Lock()
+	defer r.healthCheckMu.Unlock()
+
+	now := time.Now()
+	var due []string
 	for name, provider := range r.providers {
-		start := time.Now()
-		err := provider.HealthCheck(ctx)
-		duration := time.Since(start)
-		
-		status := &types.HealthStatus{
-			LastChecked:  time.Now().Unix(),
-			ResponseTime: duration.Milliseconds(),
+		last := r.lastChecked[name]
+		if r.lastHealthCheck.After(last) {
+			last = r.lastHealthCheck
 		}
-		
-		if err != nil {
-			status.Status = "unhealthy"
-			status.ErrorMessage = err.Error()
-			r.logger.WithError(err).Warnf("Health check failed for %s", name)
-		} else {
-			status.Status = "healthy"
-			r.logger.WithField("provider", name).Debug("Health check passed")
+		if now.Sub(last) > r.healthCheckIntervalFor(provider) {
+			due = append(due, name)
+			r.lastChecked[name] = now
 		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	if len(due) == len(r.providers) {
+		r.lastHealthCheck = now
+	}
+
+	// Use background context for health checks to avoid cancellation when request completes
+	go r.updateHealthStatus(context.Background(), due)
+}
+
+// healthCheckIntervalFor returns how often a provider is health checked
+func (r *Router) healthCheckIntervalFor(provider providers.LLMProvider) time.Duration {
+	if scheduler, ok := provider.(providers.HealthCheckScheduler); ok {
+		if interval := scheduler.HealthCheckInterval(); interval > 0 {
+			return interval
+		}
+	}
+	return r.healthCheckInterval
+}
+
+// updateHealthStatus performs health checks on the named providers, a
+// bounded number at a time so one slow provider doesn't hold up the others
+func (r *Router) updateHealthStatus(ctx context.Context, names []string) {
+	// Snapshot the registry so slow checks don't hold up routing or reloads
+	r.mu.RLock()
+	registered := make(map[string]providers.LLMProvider, len(names))
+	for _, name := range names {
+		if provider, exists := r.providers[name]; exists {
+			registered[name] = provider
+		}
+	}
+	concurrency := r.healthCheckConcurrency
+	r.mu.RUnlock()
+
+	var group errgroup.Group
+	group.SetLimit(max(concurrency, 1))
+	for name, provider := range registered {
+		group.Go(func() error {
+			r.checkProviderHealth(ctx, name, provider)
+			return nil
+		})
+	}
+	group.Wait()
+	r.saveHealthCache()
+}
+
+// checkProviderHealth runs one provider's health check and records the
+// result, keeping any maintenance flag set by an operator. It returns a copy
+// of the recorded status.
+func (r *Router) checkProviderHealth(ctx context.Context, name string, provider providers.LLMProvider) types.HealthStatus {
+	r.mu.RLock()
+	timeout := r.healthCheckTimeout
+	r.mu.RUnlock()
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := provider.HealthCheck(checkCtx)
+	duration := time.Since(start)
+	if err != nil && errors.Is(checkCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("health check timed out after %s", timeout)
+	}
+	
+	status := &types.HealthStatus{
+		LastChecked:  time.Now().Unix(),
+		ResponseTime: duration.Milliseconds(),
+	}
+	
+	if err != nil {
+		status.Status = "unhealthy"
+		status.ErrorMessage = err.Error()
+		r.logger.WithError(err).Warnf("Health check failed for %s", name)
+	} else {
+		status.Status = "healthy"
+		r.logger.WithField("provider", name).Debug("Health check passed")
 		
+		// Seed latency estimates until real traffic is recorded
+		r.latencies.seed(name, duration)
+	}
+	
+	r.mu.Lock()
+	// Skip providers removed by a reload while the check ran
+	if r.providers[name] == provider {
+		if previous, exists := r.healthStatus[name]; exists {
+			status.Maintenance = previous.Maintenance
+		}
 		r.healthStatus[name] = status
 	}
+	recorded := *status
+	r.mu.Unlock()
+	
+	return recorded
 }
 
 // GetHealthStatus returns the health status of all providers
 func (r *Router) GetHealthStatus() map[string]*types.HealthStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	status := make(map[string]*types.HealthStatus)
 	for name, health := range r.healthStatus {
 		// Create a copy to avoid external modification
@@ -786,6 +1326,7 @@ func (r *Router) GetHealthStatus() map[string]*types.HealthStatus {
 			ResponseTime:  health.ResponseTime,
 			LastChecked:   health.LastChecked,
 			ErrorMessage:  health.ErrorMessage,
+			Maintenance:   health.Maintenance,
 		}
 	}
 	return status
@@ -793,6 +1334,8 @@ func (r *Router) GetHealthStatus() map[string]*types.HealthStatus {
 
 // GetCapabilities returns capabilities of all providers
 func (r *Router) GetCapabilities() map[string]types.ProviderCapabilities {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	capabilities := make(map[string]types.ProviderCapabilities)
 	for name, provider := range r.providers {
 		capabilities[name] = provider.GetCapabilities()
@@ -847,13 +1390,9 @@ func (r *Router) extractRequestFeatures(req *types.ChatRequest) []string {
 	
 	// Check for vision requirements in messages
 	for _, msg := range req.Messages {
-		if parts, ok := msg.Content.([]types.ContentPart); ok {
-			for _, part := range parts {
-				if part.Type == "image_url" {
-					features = append(features, "vision")
-					break
-				}
-			}
+		if types.HasImage(msg.Content) {
+			features = append(features, "vision")
+			break
 		}
 	}
 	
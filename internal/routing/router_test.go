@@ -2,11 +2,18 @@ package routing
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/anthropic"
 	"github.com/tributary-ai/llm-router-waf/internal/providers/openai"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
@@ -81,6 +88,57 @@ func TestRouter_Route_CostOptimized(t *testing.T) {
 	}
 }
 
+// staticPricedProvider can't estimate live costs but advertises pricing
+type staticPricedProvider struct {
+	flakyProvider
+	inputCostPer1K float64
+}
+
+func (p *staticPricedProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{
+		ProviderName:    "static",
+		CostPer1KTokens: types.CostStructure{InputCostPer1K: p.inputCostPer1K, OutputCostPer1K: p.inputCostPer1K},
+	}
+}
+
+func (p *staticPricedProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	return nil, errors.New("pricing service unavailable")
+}
+
+func TestRouter_Route_CostOptimizedStaticPricingFallback(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("live", &pricedProvider{cost: 0.05})
+	router.RegisterProvider("static-cheap", &staticPricedProvider{inputCostPer1K: 0.0001})
+	router.RegisterProvider("static-pricey", &staticPricedProvider{inputCostPer1K: 0.01})
+	router.lastHealthCheck = time.Now()
+
+	req := &types.ChatRequest{
+		Model:       "custom-model",
+		Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+		OptimizeFor: types.OptimizeCost,
+	}
+
+	decision, _, err := router.routeByCost(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if decision.SelectedProvider != "static-cheap" {
+		t.Errorf("Expected static-cheap to win on static pricing, got %s", decision.SelectedProvider)
+	}
+	reasoning := strings.Join(decision.Reasoning, "; ")
+	if !strings.Contains(reasoning, "static pricing for: static-cheap, static-pricey") {
+		t.Errorf("Expected reasoning to name statically priced providers, got %q", reasoning)
+	}
+
+	// Only providers without any pricing information fail routing
+	router = createTestRouter(t)
+	router.RegisterProvider("unpriced", &staticPricedProvider{})
+	router.lastHealthCheck = time.Now()
+	if _, _, err := router.routeByCost(context.Background(), req); err == nil || !strings.Contains(err.Error(), "no pricing information") {
+		t.Errorf("Expected a missing pricing error, got %v", err)
+	}
+}
+
 func TestRouter_Route_SpecificProvider(t *testing.T) {
 	router := createTestRouter(t)
 	
@@ -141,6 +199,446 @@ func TestRouter_Route_PerformanceOptimized(t *testing.T) {
 	}
 }
 
+func TestRouter_Route_PerformanceUsesMeasuredLatency(t *testing.T) {
+	router := createTestRouter(t)
+	
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+	router.RegisterProvider("anthropic", createTestOpenAIProvider())
+	
+	// Measured latencies override the static heuristic
+	router.RecordLatency("openai", 2*time.Second)
+	router.RecordLatency("anthropic", 300*time.Millisecond)
+	
+	req := &types.ChatRequest{
+		ID:    "test-request",
+		Model: "test-model",
+		Messages: []types.Message{
+			{Role: "user", Content: "Hello"},
+		},
+		OptimizeFor: types.OptimizePerformance,
+		Timestamp:   time.Now(),
+	}
+	
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	
+	if metadata.Provider != "anthropic" {
+		t.Errorf("Expected routing to the fastest measured provider 'anthropic', got %s", metadata.Provider)
+	}
+}
+
+func TestRouter_Route_PerformanceP95(t *testing.T) {
+	router := createTestRouter(t)
+	
+	router.RegisterProvider("spiky", createTestOpenAIProvider())
+	router.RegisterProvider("steady", createTestOpenAIProvider())
+	
+	// spiky is usually fast but stalls one request in ten; its recent
+	// requests were fast, so its moving average is low
+	for i := 0; i < 20; i++ {
+		if i%10 == 0 {
+			router.RecordLatency("spiky", 3*time.Second)
+		} else {
+			router.RecordLatency("spiky", 100*time.Millisecond)
+		}
+		router.RecordLatency("steady", 400*time.Millisecond)
+	}
+	
+	if p95 := router.estimateP95Latency("spiky"); p95 != 3*time.Second {
+		t.Errorf("Expected spiky p95 of 3s, got %s", p95)
+	}
+	
+	route := func() *types.RouterMetadata {
+		req := &types.ChatRequest{
+			ID:          "test-request",
+			Model:       "test-model",
+			Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+			OptimizeFor: types.OptimizePerformance,
+			Timestamp:   time.Now(),
+		}
+		metadata, _, err := router.Route(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Routing failed: %v", err)
+		}
+		return metadata
+	}
+	
+	if metadata := route(); metadata.Provider != "spiky" {
+		t.Errorf("Expected mean latency to favor 'spiky', got %s", metadata.Provider)
+	}
+	
+	if err := router.SetPerformanceMetric(PerformanceMetricP95); err != nil {
+		t.Fatalf("SetPerformanceMetric failed: %v", err)
+	}
+	metadata := route()
+	if metadata.Provider != "steady" {
+		t.Errorf("Expected p95 latency to favor 'steady', got %s", metadata.Provider)
+	}
+	if !strings.Contains(strings.Join(metadata.RoutingReason, " "), "by p95 latency") {
+		t.Errorf("Expected reasoning to name the p95 metric, got %v", metadata.RoutingReason)
+	}
+	
+	if err := router.SetPerformanceMetric("median"); err == nil {
+		t.Error("Expected an error for an unknown metric")
+	}
+}
+
+func TestRouter_Route_ModelSubstitution(t *testing.T) {
+	newRequest := func(allowSubstitution bool) *types.ChatRequest {
+		return &types.ChatRequest{
+			ID:    "test-request",
+			Model: "claude-3-haiku",
+			Messages: []types.Message{
+				{Role: "user", Content: "Hello"},
+			},
+			FallbackConfig: &types.FallbackConfig{
+				Enabled:                true,
+				AllowModelSubstitution: allowSubstitution,
+			},
+			Timestamp: time.Now(),
+		}
+	}
+	
+	setup := func(t *testing.T) *Router {
+		router := createTestRouter(t)
+		router.RegisterProvider("anthropic", createTestOpenAIProvider())
+		router.RegisterProvider("bedrock", createTestOpenAIProvider())
+		router.SetModelEquivalents(map[string][]ModelEquivalent{
+			"claude-3-haiku": {{Provider: "bedrock", Model: "anthropic.claude-3-haiku"}},
+		})
+		
+		// Mark the owning provider unhealthy and suppress background health checks
+		router.healthStatus["anthropic"].Status = "unhealthy"
+		router.lastHealthCheck = time.Now()
+		return router
+	}
+	
+	t.Run("strict failure by default", func(t *testing.T) {
+		router := setup(t)
+		
+		_, _, err := router.Route(context.Background(), newRequest(false))
+		if err == nil {
+			t.Fatal("Expected routing to fail when the owning provider is unhealthy")
+		}
+		if !strings.Contains(err.Error(), "not healthy") {
+			t.Errorf("Expected unhealthy provider error, got %v", err)
+		}
+	})
+	
+	t.Run("substitutes equivalent model when permitted", func(t *testing.T) {
+		router := setup(t)
+		req := newRequest(true)
+		
+		metadata, _, err := router.Route(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Routing failed: %v", err)
+		}
+		
+		if metadata.Provider != "bedrock" {
+			t.Errorf("Expected substitution to 'bedrock', got %s", metadata.Provider)
+		}
+		if !metadata.ModelSubstituted || metadata.OriginalModel != "claude-3-haiku" {
+			t.Errorf("Expected substitution noted in metadata, got %+v", metadata)
+		}
+		if req.Model != "anthropic.claude-3-haiku" || metadata.Model != "anthropic.claude-3-haiku" {
+			t.Errorf("Expected request model rewritten to equivalent, got %s", req.Model)
+		}
+	})
+	
+	t.Run("fails when no equivalent is healthy", func(t *testing.T) {
+		router := setup(t)
+		router.healthStatus["bedrock"].Status = "unhealthy"
+		
+		_, _, err := router.Route(context.Background(), newRequest(true))
+		if err == nil {
+			t.Fatal("Expected routing to fail without a healthy equivalent")
+		}
+	})
+}
+
+func TestRouter_Route_PerModelRateLimit(t *testing.T) {
+	newRequest := func(fallback bool) *types.ChatRequest {
+		req := &types.ChatRequest{
+			ID:    "test-request",
+			Model: "test-model",
+			Messages: []types.Message{
+				{Role: "user", Content: "Hello"},
+			},
+			OptimizeFor: types.OptimizePerformance,
+			Timestamp:   time.Now(),
+		}
+		if fallback {
+			req.FallbackConfig = &types.FallbackConfig{Enabled: true}
+		}
+		return req
+	}
+	
+	setup := func(t *testing.T, perModel, perProvider map[string]int) *Router {
+		router := createTestRouter(t)
+		router.RegisterProvider("primary", createTestOpenAIProvider())
+		router.RegisterProvider("secondary", createTestOpenAIProvider())
+		router.lastHealthCheck = time.Now()
+		
+		limiter := security.NewInMemoryRateLimiter(&security.RateLimitConfig{
+			Enabled:           true,
+			RequestsPerMinute: 1000, // global budget is plentiful
+			PerModelLimits:    perModel,
+			PerProviderLimits: perProvider,
+		}, logrus.New())
+		t.Cleanup(limiter.Stop)
+		router.SetRateLimiter(limiter)
+		return router
+	}
+	
+	modelLimit := map[string]int{"test-model": 1}
+	
+	t.Run("rejects once the model limit is exhausted", func(t *testing.T) {
+		router := setup(t, modelLimit, nil)
+		
+		if _, _, err := router.Route(context.Background(), newRequest(false)); err != nil {
+			t.Fatalf("First request should be routed: %v", err)
+		}
+		
+		_, _, err := router.Route(context.Background(), newRequest(false))
+		var limitErr *RateLimitError
+		if !errors.As(err, &limitErr) {
+			t.Fatalf("Expected RateLimitError, got %v", err)
+		}
+		if limitErr.Key != "model:test-model" {
+			t.Errorf("Expected key 'model:test-model', got %s", limitErr.Key)
+		}
+		
+		// Other models are still routed
+		req := newRequest(false)
+		req.Model = "other-model"
+		if _, _, err := router.Route(context.Background(), req); err != nil {
+			t.Errorf("Unlimited model should still be routed: %v", err)
+		}
+	})
+	
+	t.Run("falls back when the provider limit is exhausted", func(t *testing.T) {
+		router := setup(t, nil, map[string]int{"primary": 1, "secondary": 1})
+		
+		first, _, err := router.Route(context.Background(), newRequest(true))
+		if err != nil {
+			t.Fatalf("First request should be routed: %v", err)
+		}
+		
+		second, _, err := router.Route(context.Background(), newRequest(true))
+		if err != nil {
+			t.Fatalf("Expected fallback routing, got %v", err)
+		}
+		if !second.FallbackUsed {
+			t.Error("Expected fallback to be used")
+		}
+		if second.Provider == first.Provider {
+			t.Errorf("Expected a different provider than %s", first.Provider)
+		}
+	})
+	
+	t.Run("applies the model limit to fallback candidates", func(t *testing.T) {
+		router := setup(t, modelLimit, nil)
+		
+		if _, _, err := router.Route(context.Background(), newRequest(true)); err != nil {
+			t.Fatalf("First request should be routed: %v", err)
+		}
+		
+		_, _, err := router.Route(context.Background(), newRequest(true))
+		var limitErr *RateLimitError
+		if !errors.As(err, &limitErr) || limitErr.Key != "model:test-model" {
+			t.Errorf("Expected RateLimitError for model:test-model, got %v", err)
+		}
+	})
+	
+	t.Run("dry runs consume no budget", func(t *testing.T) {
+		router := setup(t, modelLimit, nil)
+		
+		for i := 0; i < 3; i++ {
+			if _, _, err := router.Route(WithDryRun(context.Background()), newRequest(false)); err != nil {
+				t.Fatalf("Dry run %d should be routed: %v", i, err)
+			}
+		}
+		if _, _, err := router.Route(context.Background(), newRequest(false)); err != nil {
+			t.Errorf("Dry runs should leave the model budget untouched: %v", err)
+		}
+	})
+}
+
+func TestRouter_RouteEmbedding(t *testing.T) {
+	newRequest := func(model string) *types.EmbeddingRequest {
+		return &types.EmbeddingRequest{
+			ID:        "test-embedding",
+			Model:     model,
+			Input:     "The quick brown fox",
+			Timestamp: time.Now(),
+		}
+	}
+	
+	router := createTestRouter(t)
+	router.RegisterProvider("anthropic", anthropic.NewAnthropicProvider(&anthropic.AnthropicConfig{APIKey: "test-api-key"}, router.logger))
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+	router.lastHealthCheck = time.Now()
+	
+	metadata, provider, err := router.RouteEmbedding(context.Background(), newRequest("text-embedding-3-small"))
+	if err != nil {
+		t.Fatalf("Expected embedding request to be routed: %v", err)
+	}
+	if metadata.Provider != "openai" || provider.GetProviderName() != "openai" {
+		t.Errorf("Expected openai to serve embeddings, got %s", metadata.Provider)
+	}
+	if metadata.EstimatedCost <= 0 {
+		t.Errorf("Expected a positive cost estimate, got %f", metadata.EstimatedCost)
+	}
+	
+	// Models no embedding provider can price are rejected
+	if _, _, err := router.RouteEmbedding(context.Background(), newRequest("claude-3-haiku")); err == nil {
+		t.Error("Expected error for a model without an embedding provider")
+	}
+	
+	// Providers without embeddings support are never selected
+	router.healthStatus["openai"].Status = "unhealthy"
+	_, _, err = router.RouteEmbedding(context.Background(), newRequest("text-embedding-3-small"))
+	if err == nil || !strings.Contains(err.Error(), "embeddings") {
+		t.Errorf("Expected embeddings support error, got %v", err)
+	}
+}
+
+func TestRouter_Route_WeightedRoundRobin(t *testing.T) {
+	newRequest := func() *types.ChatRequest {
+		return &types.ChatRequest{
+			ID:    "test-request",
+			Model: "test-model",
+			Messages: []types.Message{
+				{Role: "user", Content: "Hello"},
+			},
+			OptimizeFor: types.OptimizeWeighted,
+			Timestamp:   time.Now(),
+		}
+	}
+	
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+	router.RegisterProvider("anthropic", createTestOpenAIProvider())
+	router.SetProviderWeights(map[string]int{"openai": 3, "anthropic": 1})
+	router.lastHealthCheck = time.Now()
+	
+	route := func(n int) map[string]int {
+		counts := make(map[string]int)
+		for i := 0; i < n; i++ {
+			metadata, _, err := router.Route(context.Background(), newRequest())
+			if err != nil {
+				t.Fatalf("Routing failed on iteration %d: %v", i, err)
+			}
+			counts[metadata.Provider]++
+		}
+		return counts
+	}
+	
+	counts := route(1000)
+	ratio := float64(counts["openai"]) / float64(counts["anthropic"])
+	if ratio < 2.9 || ratio > 3.1 {
+		t.Errorf("Expected a 3:1 split, got openai=%d anthropic=%d", counts["openai"], counts["anthropic"])
+	}
+	
+	// Unhealthy providers are skipped
+	router.healthStatus["anthropic"].Status = "unhealthy"
+	if counts := route(10); counts["openai"] != 10 {
+		t.Errorf("Expected all requests on openai while anthropic is down, got %v", counts)
+	}
+	
+	// A recovered provider gets its fair share straight away, without a burst
+	router.healthStatus["anthropic"].Status = "healthy"
+	counts = route(4)
+	if counts["openai"] != 3 || counts["anthropic"] != 1 {
+		t.Errorf("Expected a 3:1 split after recovery, got %v", counts)
+	}
+}
+
+func TestRouter_DefaultStrategy(t *testing.T) {
+	router := createTestRouter(t)
+	
+	req := &types.ChatRequest{Model: "test-model"}
+	if strategy := router.determineStrategy(req); strategy != RoutingStrategyCostOptimized {
+		t.Errorf("Expected cost optimization by default, got %s", strategy)
+	}
+	
+	router.SetDefaultStrategy(RoutingStrategyWeighted)
+	if strategy := router.determineStrategy(req); strategy != RoutingStrategyWeighted {
+		t.Errorf("Expected configured default strategy, got %s", strategy)
+	}
+	
+	// Explicit preferences still win
+	req.OptimizeFor = types.OptimizePerformance
+	if strategy := router.determineStrategy(req); strategy != RoutingStrategyPerformance {
+		t.Errorf("Expected performance strategy, got %s", strategy)
+	}
+}
+
+func TestRouter_Route_ConfiguredDefaultStrategy(t *testing.T) {
+	router := createTestRouter(t)
+	router.Reload(Settings{DefaultStrategy: RoutingStrategyPerformance}, []NamedProvider{
+		{Name: "cheap", Provider: &pricedProvider{cost: 0.01}},
+		{Name: "fast", Provider: &pricedProvider{cost: 0.10}},
+	})
+	router.latencies.seed("cheap", 900*time.Millisecond)
+	router.latencies.seed("fast", 100*time.Millisecond)
+	router.lastHealthCheck = time.Now()
+	
+	// Without optimize_for the configured default applies, not cost
+	req := &types.ChatRequest{Model: "test-model", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "fast" {
+		t.Errorf("Expected the default performance strategy to pick the faster provider, got %s", metadata.Provider)
+	}
+	if len(metadata.RoutingReason) == 0 || !strings.HasPrefix(metadata.RoutingReason[0], "Performance-optimized") {
+		t.Errorf("Expected performance routing reasoning, got %v", metadata.RoutingReason)
+	}
+	
+	req.OptimizeFor = types.OptimizeCost
+	metadata, _, err = router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "cheap" {
+		t.Errorf("Expected an explicit cost preference to override the default, got %s", metadata.Provider)
+	}
+}
+
+func TestRouter_RecordLatency_EWMA(t *testing.T) {
+	router := createTestRouter(t)
+	
+	// Falls back to heuristic before any measurement
+	if got := router.estimateLatency("openai"); got != 800*time.Millisecond {
+		t.Errorf("Expected heuristic latency 800ms, got %v", got)
+	}
+	
+	// Health-check seed is used until real traffic arrives
+	router.latencies.seed("openai", 100*time.Millisecond)
+	if got := router.estimateLatency("openai"); got != 100*time.Millisecond {
+		t.Errorf("Expected seeded latency 100ms, got %v", got)
+	}
+	
+	// First real sample replaces the seed, later samples are smoothed
+	router.RecordLatency("openai", time.Second)
+	router.RecordLatency("openai", 2*time.Second)
+	
+	expected := time.Duration(defaultLatencySmoothing*float64(2*time.Second) + (1-defaultLatencySmoothing)*float64(time.Second))
+	if got := router.estimateLatency("openai"); got != expected {
+		t.Errorf("Expected EWMA latency %v, got %v", expected, got)
+	}
+	
+	// Seeds no longer apply once traffic has been observed
+	router.latencies.seed("openai", time.Millisecond)
+	if got := router.estimateLatency("openai"); got != expected {
+		t.Errorf("Seed should not override measured latency, got %v", got)
+	}
+}
+
 func TestRouter_Route_RoundRobin(t *testing.T) {
 	router := createTestRouter(t)
 	
@@ -374,7 +872,148 @@ func createTestOpenAIProvider() *openai.OpenAIProvider {
 	return openai.NewOpenAIProvider(config, logger)
 }
 
+// estimatingProvider counts cost estimates and runs onEstimate on each
+type estimatingProvider struct {
+	flakyProvider
+	estimates  atomic.Int32
+	onEstimate func()
+}
+
+func (p *estimatingProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	p.estimates.Add(1)
+	if p.onEstimate != nil {
+		p.onEstimate()
+	}
+	return &types.CostEstimate{TotalCost: 0.01}, nil
+}
+
+func TestRouter_Route_CancelledContext(t *testing.T) {
+	router := createTestRouter(t)
+	candidates := []*estimatingProvider{{}, {}, {}}
+	for i, provider := range candidates {
+		router.RegisterProvider(fmt.Sprintf("provider-%d", i), provider)
+	}
+	router.lastHealthCheck = time.Now()
+	estimates := func() int32 {
+		var total int32
+		for _, provider := range candidates {
+			total += provider.estimates.Load()
+		}
+		return total
+	}
+	req := func(strategy types.OptimizationType) *types.ChatRequest {
+		return &types.ChatRequest{
+			Model:       "test-model",
+			Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+			OptimizeFor: strategy,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, strategy := range []types.OptimizationType{types.OptimizeCost, types.OptimizePerformance} {
+		_, _, err := router.Route(ctx, req(strategy))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a context error for %s routing, got %v", strategy, err)
+		}
+	}
+	router.SetDefaultStrategy(RoutingStrategyRoundRobin)
+	if _, _, err := router.Route(ctx, req("")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a context error for round-robin routing, got %v", err)
+	}
+	router.SetDefaultStrategy(RoutingStrategyCostOptimized)
+	if n := estimates(); n != 0 {
+		t.Errorf("Expected no cost estimates for a cancelled request, got %d", n)
+	}
+
+	// A client that goes away mid-routing stops the remaining estimates
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	candidates[0].onEstimate = cancel
+	_, _, err := router.Route(ctx, req(types.OptimizeCost))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a context error after cancellation, got %v", err)
+	}
+	if n := estimates(); n != 1 {
+		t.Errorf("Expected estimation to stop after the cancelling provider, got %d estimates", n)
+	}
+}
+
 // Benchmark tests
+// scheduledProvider is a flakyProvider with its own health check interval
+type scheduledProvider struct {
+	flakyProvider
+	interval time.Duration
+}
+
+func (s *scheduledProvider) HealthCheckInterval() time.Duration { return s.interval }
+
+func TestRouter_HealthCheckIntervalPerProvider(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("default", &flakyProvider{})
+	router.RegisterProvider("frequent", &scheduledProvider{interval: time.Millisecond})
+	router.lastHealthCheck = time.Now()
+	time.Sleep(5 * time.Millisecond)
+	
+	router.mu.RLock()
+	router.checkHealthIfDue()
+	router.mu.RUnlock()
+	
+	router.healthCheckMu.Lock()
+	defer router.healthCheckMu.Unlock()
+	if _, checked := router.lastChecked["frequent"]; !checked {
+		t.Error("Expected provider with a short interval to be checked")
+	}
+	if _, checked := router.lastChecked["default"]; checked {
+		t.Error("Expected provider on the default interval not to be checked yet")
+	}
+}
+
+// hangingProvider's health check never returns on its own
+type hangingProvider struct {
+	flakyProvider
+}
+
+func (h *hangingProvider) HealthCheck(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRouter_HealthCheckTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	router := createTestRouter(t)
+	router.Reload(Settings{
+		DefaultStrategy:    RoutingStrategyRoundRobin,
+		HealthCheckTimeout: timeout,
+	}, []NamedProvider{
+		{Name: "fast", Provider: &flakyProvider{}},
+		{Name: "slow", Provider: &hangingProvider{}},
+		{Name: "slower", Provider: &hangingProvider{}},
+	})
+	
+	start := time.Now()
+	router.updateHealthStatus(context.Background(), []string{"fast", "slow", "slower"})
+	elapsed := time.Since(start)
+	
+	// Sequential checks would take a timeout per hanging provider
+	if elapsed >= 2*timeout {
+		t.Errorf("Expected checks to run concurrently within about %v, took %v", timeout, elapsed)
+	}
+	
+	health := router.GetHealthStatus()
+	if health["fast"].Status != "healthy" {
+		t.Errorf("Expected fast provider to be healthy, got %s", health["fast"].Status)
+	}
+	for _, name := range []string{"slow", "slower"} {
+		if health[name].Status != "unhealthy" {
+			t.Errorf("Expected %s provider to be unhealthy, got %s", name, health[name].Status)
+		}
+		if !strings.Contains(health[name].ErrorMessage, "timed out") {
+			t.Errorf("Expected a timeout error for %s, got %q", name, health[name].ErrorMessage)
+		}
+	}
+}
+
 func BenchmarkRouter_Route(b *testing.B) {
 	router := createTestRouter(&testing.T{})
 	provider := createTestOpenAIProvider()
@@ -411,4 +1050,58 @@ func BenchmarkRouter_HealthCheck(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = router.GetHealthStatus()
 	}
-}
\ No newline at end of file
+}
+func TestRouter_Route_RoundRobinConcurrent(t *testing.T) {
+	router := createTestRouter(t)
+	names := []string{"first", "second", "third"}
+	for _, name := range names {
+		router.RegisterProvider(name, &flakyProvider{})
+	}
+	router.SetDefaultStrategy(RoutingStrategyRoundRobin)
+	router.lastHealthCheck = time.Now()
+
+	const workers, perWorker = 12, 25
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				metadata, _, err := router.Route(context.Background(), &types.ChatRequest{
+					Model:    "custom-model",
+					Messages: []types.Message{{Role: "user", Content: "Hello"}},
+				})
+				if err != nil {
+					t.Errorf("Routing failed: %v", err)
+					return
+				}
+				mu.Lock()
+				counts[metadata.Provider]++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Health updates and readers race with routing
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				router.updateHealthStatus(context.Background(), names)
+				router.GetHealthStatus()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every provider stays healthy, so the shared index spreads requests evenly
+	for _, name := range names {
+		if counts[name] != workers*perWorker/len(names) {
+			t.Errorf("Expected %d requests per provider, got %v", workers*perWorker/len(names), counts)
+			break
+		}
+	}
+}
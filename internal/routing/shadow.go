@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// shadowTimeout bounds how long a mirrored request may run
+const shadowTimeout = 2 * time.Minute
+
+// ShadowConfig mirrors a sample of live completions to a candidate provider
+// for evaluation. Shadow responses are logged alongside the primary's
+// latency, token counts and cost, and never reach the client.
+type ShadowConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	Provider   string  `yaml:"provider"`
+	Model      string  `yaml:"model"`       // empty mirrors the request's model
+	SampleRate float64 `yaml:"sample_rate"` // fraction of requests mirrored, 0-1
+}
+
+// Validate checks that an enabled shadow names a provider and a sample rate
+// between 0 and 1
+func (c ShadowConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Provider == "" {
+		return fmt.Errorf("shadow provider is required when shadowing is enabled")
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("shadow sample rate must be between 0 and 1")
+	}
+	return nil
+}
+
+// SetShadow configures traffic mirroring
+func (r *Router) SetShadow(config ShadowConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.shadow = config
+}
+
+// shadowCompletion mirrors a completion the primary provider served to the
+// shadow provider when the request is sampled. It returns immediately; the
+// shadow runs in the background, detached from the client's context.
+func (r *Router) shadowCompletion(req *types.ChatRequest, providerName string, primary *types.ChatResponse, primaryLatency time.Duration) {
+	r.mu.RLock()
+	config := r.shadow
+	shadowProvider, exists := r.providers[config.Provider]
+	primaryProvider := r.providers[providerName]
+	r.mu.RUnlock()
+
+	if !config.Enabled || config.Provider == providerName || r.shadowSample() >= config.SampleRate {
+		return
+	}
+	if !exists {
+		r.logger.WithField("shadow_provider", config.Provider).Warn("Shadow provider is not registered")
+		return
+	}
+
+	shadowReq := *req
+	if config.Model != "" {
+		shadowReq.Model = config.Model
+	}
+	requestID, model := req.ID, req.Model
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := shadowProvider.ChatCompletion(ctx, &shadowReq)
+		fields := logrus.Fields{
+			"request_id":       requestID,
+			"primary_provider": providerName,
+			"shadow_provider":  config.Provider,
+			"model":            model,
+			"shadow_model":     shadowReq.Model,
+			"primary_latency":  primaryLatency,
+			"shadow_latency":   time.Since(start),
+		}
+		if err != nil {
+			r.logger.WithError(err).WithFields(fields).Warn("Shadow request failed")
+			return
+		}
+
+		if primary.Usage != nil {
+			fields["primary_tokens"] = primary.Usage.TotalTokens
+			if primaryProvider != nil {
+				fields["primary_cost"], _ = accounting.Cost(primaryProvider.GetCapabilities(), model, primary.Usage)
+			}
+		}
+		if resp.Usage != nil {
+			fields["shadow_tokens"] = resp.Usage.TotalTokens
+			fields["shadow_cost"], _ = accounting.Cost(shadowProvider.GetCapabilities(), shadowReq.Model, resp.Usage)
+		}
+		r.logger.WithFields(fields).Info("Shadow comparison")
+	}()
+}
+
+// shadowSample draws the value compared against the shadow sample rate
+func (r *Router) shadowSample() float64 {
+	if r.shadowSampler != nil {
+		return r.shadowSampler()
+	}
+	return rand.Float64()
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// shadowProvider reports each mirrored request on calls and fails with err
+type shadowProvider struct {
+	flakyProvider
+	calls chan *types.ChatRequest
+	err   error
+}
+
+func (s *shadowProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	s.calls <- req
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &types.ChatResponse{ID: "shadow", Model: req.Model}, nil
+}
+
+func TestRouter_ExecuteCompletion_ShadowsSampledRequests(t *testing.T) {
+	router := createTestRouter(t)
+	shadow := &shadowProvider{calls: make(chan *types.ChatRequest, 10), err: errors.New("shadow down")}
+	router.RegisterProvider("candidate", shadow)
+	router.SetShadow(ShadowConfig{Enabled: true, Provider: "candidate", Model: "candidate-model", SampleRate: 0.5})
+
+	samples := []float64{0, 0.25, 0.5, 0.75}
+	next := 0
+	router.shadowSampler = func() float64 {
+		sample := samples[next%len(samples)]
+		next++
+		return sample
+	}
+
+	primary := &flakyProvider{}
+	for i := 0; i < 8; i++ {
+		req := &types.ChatRequest{Model: "test-model"}
+		metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+		resp, err := router.ExecuteCompletion(context.Background(), req, "flaky", primary, metadata)
+		if err != nil {
+			t.Fatalf("Shadow failure leaked into the primary response: %v", err)
+		}
+		if resp.ID != "ok" || resp.Model != "test-model" {
+			t.Errorf("Expected the primary response, got %+v", resp)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case req := <-shadow.calls:
+			if req.Model != "candidate-model" {
+				t.Errorf("Expected shadow model candidate-model, got %s", req.Model)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 4 shadow requests, got %d", i)
+		}
+	}
+	select {
+	case <-shadow.calls:
+		t.Error("Expected only half of the requests to be shadowed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if primary.calls != 8 {
+		t.Errorf("Expected 8 primary calls, got %d", primary.calls)
+	}
+}
@@ -0,0 +1,41 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// SetSpendLimiter configures the per-user spend caps checked before routing.
+// A nil limiter disables them.
+func (r *Router) SetSpendLimiter(limiter *accounting.SpendLimiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spendLimiter = limiter
+}
+
+// checkSpend returns an accounting.SpendLimitError when the request's user or
+// caller has reached a spend cap
+func (r *Router) checkSpend(ctx context.Context, req *types.ChatRequest) error {
+	if r.spendLimiter == nil {
+		return nil
+	}
+
+	var callerID string
+	var permissions []string
+	if authInfo, ok := security.GetAuthInfo(ctx); ok {
+		callerID, permissions = authInfo.UserID, authInfo.Permissions
+	}
+
+	err := r.spendLimiter.Check(req.UserID, callerID, permissions, time.Now())
+	if _, limited := err.(*accounting.SpendLimitError); err != nil && !limited {
+		// Fail open so accounting outages don't take routing down
+		r.logger.WithError(err).WithField("user_id", req.UserID).Warn("Spend limit check failed")
+		return nil
+	}
+	return err
+}
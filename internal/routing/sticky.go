@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// stickyKey returns the identity a request is pinned by: its conversation ID,
+// or its user ID when no conversation is given
+func stickyKey(req *types.ChatRequest) string {
+	if req.ConversationID != "" {
+		return req.ConversationID
+	}
+	return req.UserID
+}
+
+// routeSticky pins a conversation (or user) to one provider so multi-turn
+// conversations don't switch behaviour mid-way. Providers are ranked by
+// rendezvous hashing of the sticky key, so the mapping is derived without
+// server-side state, survives restarts and moves as few keys as possible
+// when providers are added or removed. The top-ranked provider is used unless
+// it is unhealthy, in which case the next healthy one in the ranking serves
+// the request until it recovers.
+func (r *Router) routeSticky(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
+	key := stickyKey(req)
+	if key == "" {
+		decision, provider, err := r.routeByCost(ctx, req)
+		if err == nil {
+			decision.Reasoning = append(decision.Reasoning, "Sticky routing requested without conversation_id or user_id")
+		}
+		return decision, provider, err
+	}
+
+	// Rank every capable provider, healthy or not, so the preferred provider
+	// for a key doesn't change with the health of the others
+	candidates := r.filterByFeatures(append([]string(nil), r.providerNames...), req)
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no providers support required features")
+	}
+	ranked := rankByRendezvous(key, candidates)
+
+	for i, name := range ranked {
+		if !r.isProviderHealthy(name) {
+			continue
+		}
+
+		provider := r.providers[name]
+		reasoning := []string{fmt.Sprintf("Sticky routing pinned %q to %s", key, name)}
+		if i > 0 {
+			reasoning = []string{fmt.Sprintf("Sticky provider %s for %q is unhealthy, using %s", ranked[0], key, name)}
+		}
+
+		// Get cost estimate
+		costEst, err := provider.EstimateCost(req)
+		if err != nil {
+			r.logger.WithError(err).Warnf("Failed to estimate cost for %s", name)
+			costEst = &types.CostEstimate{TotalCost: 0}
+		}
+
+		decision := &RoutingDecision{
+			SelectedProvider:     name,
+			Reasoning:            reasoning,
+			EstimatedCost:        costEst.TotalCost,
+			EstimatedLatency:     r.estimateLatency(name),
+			FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+			FallbackChain:        r.buildFallbackChain(name, req),
+			RoutingContext:       r.buildRoutingContext("sticky", req, ranked),
+		}
+		return decision, provider, nil
+	}
+
+	return nil, nil, ErrNoHealthyProviders
+}
+
+// rankByRendezvous orders providers by their highest-random-weight score for key
+func rankByRendezvous(key string, names []string) []string {
+	scores := make(map[string]uint64, len(names))
+	for _, name := range names {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		scores[name] = h.Sum64()
+	}
+
+	ranked := make([]string, len(names))
+	copy(ranked, names)
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	return ranked
+}
@@ -0,0 +1,121 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createStickyRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	for _, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		router.RegisterProvider(name, &flakyProvider{})
+	}
+	// Suppress background health checks so tests control health
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func stickyRequest(conversationID, userID string) *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:          "test-model",
+		Messages:       []types.Message{{Role: "user", Content: "Hello"}},
+		OptimizeFor:    types.OptimizeSticky,
+		ConversationID: conversationID,
+		UserID:         userID,
+	}
+}
+
+func TestRouter_Route_StickyConsistentPerConversation(t *testing.T) {
+	router := createStickyRouter(t)
+	ctx := context.Background()
+
+	used := make(map[string]bool)
+	for c := 0; c < 20; c++ {
+		conversation := fmt.Sprintf("conv-%d", c)
+
+		first, _, err := router.Route(ctx, stickyRequest(conversation, "user-1"))
+		if err != nil {
+			t.Fatalf("Routing failed: %v", err)
+		}
+		used[first.Provider] = true
+
+		for i := 0; i < 50; i++ {
+			metadata, _, err := router.Route(ctx, stickyRequest(conversation, "user-1"))
+			if err != nil {
+				t.Fatalf("Routing failed: %v", err)
+			}
+			if metadata.Provider != first.Provider {
+				t.Fatalf("Conversation %s moved from %s to %s on call %d", conversation, first.Provider, metadata.Provider, i)
+			}
+		}
+	}
+
+	// Conversations from the same user still spread across providers
+	if len(used) < 2 {
+		t.Errorf("Expected conversations to spread across providers, all went to %v", used)
+	}
+
+	// A fresh router derives the same mapping with no shared state
+	other := createStickyRouter(t)
+	for c := 0; c < 20; c++ {
+		req := stickyRequest(fmt.Sprintf("conv-%d", c), "")
+		a, _, _ := router.Route(ctx, req)
+		b, _, _ := other.Route(ctx, req)
+		if a.Provider != b.Provider {
+			t.Errorf("Routers disagree for %s: %s vs %s", req.ConversationID, a.Provider, b.Provider)
+		}
+	}
+}
+
+func TestRouter_Route_StickyFallsBackToUserID(t *testing.T) {
+	router := createStickyRouter(t)
+	ctx := context.Background()
+
+	byUser, _, err := router.Route(ctx, stickyRequest("", "user-42"))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	byConversation, _, err := router.Route(ctx, stickyRequest("user-42", ""))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if byUser.Provider != byConversation.Provider {
+		t.Errorf("Expected user ID to be used as the sticky key, got %s and %s", byUser.Provider, byConversation.Provider)
+	}
+}
+
+func TestRouter_Route_StickyUnhealthyFallback(t *testing.T) {
+	router := createStickyRouter(t)
+	ctx := context.Background()
+
+	sticky, _, err := router.Route(ctx, stickyRequest("conv-outage", ""))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+
+	router.healthStatus[sticky.Provider].Status = "unhealthy"
+	fallback, _, err := router.Route(ctx, stickyRequest("conv-outage", ""))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if fallback.Provider == sticky.Provider {
+		t.Fatalf("Expected fallback away from unhealthy provider %s", sticky.Provider)
+	}
+
+	// The fallback is itself stable while the outage lasts
+	again, _, _ := router.Route(ctx, stickyRequest("conv-outage", ""))
+	if again.Provider != fallback.Provider {
+		t.Errorf("Expected stable fallback %s, got %s", fallback.Provider, again.Provider)
+	}
+
+	// Recovery returns the conversation to its sticky provider
+	router.healthStatus[sticky.Provider].Status = "healthy"
+	recovered, _, _ := router.Route(ctx, stickyRequest("conv-outage", ""))
+	if recovered.Provider != sticky.Provider {
+		t.Errorf("Expected return to %s after recovery, got %s", sticky.Provider, recovered.Provider)
+	}
+}
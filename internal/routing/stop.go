@@ -0,0 +1,53 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+)
+
+// StopSequenceError is returned for stop sequences the provider a request is
+// dispatched to would reject. It is raised before the provider is called and
+// is never retried.
+type StopSequenceError struct {
+	Provider string
+	Reason   string
+}
+
+func (e *StopSequenceError) Error() string {
+	return fmt.Sprintf("invalid stop sequences for provider %s: %s", e.Provider, e.Reason)
+}
+
+// validateStopSequences checks a request's stop sequences against the
+// provider's advertised limits
+func validateStopSequences(providerName string, provider providers.LLMProvider, stop []string) error {
+	if len(stop) == 0 {
+		return nil
+	}
+	capabilities := provider.GetCapabilities()
+
+	if limit := capabilities.MaxStopSequences; limit > 0 && len(stop) > limit {
+		return &StopSequenceError{
+			Provider: providerName,
+			Reason:   fmt.Sprintf("got %d stop sequences, at most %d are supported", len(stop), limit),
+		}
+	}
+
+	anthropic := capabilities.AnthropicSpecific
+	if anthropic == nil {
+		return nil
+	}
+	if !anthropic.SupportsStopSequences {
+		return &StopSequenceError{Provider: providerName, Reason: "stop sequences are not supported"}
+	}
+	for _, sequence := range stop {
+		if strings.TrimSpace(sequence) == "" {
+			return &StopSequenceError{
+				Provider: providerName,
+				Reason:   fmt.Sprintf("stop sequence %q must contain non-whitespace characters", sequence),
+			}
+		}
+	}
+	return nil
+}
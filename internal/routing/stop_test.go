@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/anthropic"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestValidateStopSequences(t *testing.T) {
+	router := createTestRouter(t)
+	registered := map[string]providers.LLMProvider{
+		"openai":    createTestOpenAIProvider(),
+		"anthropic": anthropic.NewAnthropicProvider(&anthropic.AnthropicConfig{APIKey: "test-api-key"}, router.logger),
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		stop     []string
+		reason   string // empty when valid
+	}{
+		{name: "openai within limit", provider: "openai", stop: []string{"a", "b", "c", "d"}},
+		{name: "openai over limit", provider: "openai", stop: []string{"a", "b", "c", "d", "e"}, reason: "got 5 stop sequences, at most 4 are supported"},
+		{name: "anthropic sequence", provider: "anthropic", stop: []string{"\n\nHuman:", "END"}},
+		{name: "anthropic whitespace only", provider: "anthropic", stop: []string{"END", "\n "}, reason: `stop sequence "\n " must contain non-whitespace characters`},
+		{name: "no stop sequences", provider: "anthropic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStopSequences(tt.provider, registered[tt.provider], tt.stop)
+
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("Expected stop sequences to be accepted, got %v", err)
+				}
+				return
+			}
+			var stopErr *StopSequenceError
+			if !errors.As(err, &stopErr) {
+				t.Fatalf("Expected a StopSequenceError, got %v", err)
+			}
+			if stopErr.Provider != tt.provider || stopErr.Reason != tt.reason {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestRouter_ExecuteCompletion_RejectsStopSequencesBeforeDispatch(t *testing.T) {
+	router := createTestRouter(t)
+	provider := &flakyProvider{}
+
+	// flakyProvider advertises no limit, so wrap it with one
+	limited := &stopLimitedProvider{flakyProvider: provider, limit: 1}
+	req := &types.ChatRequest{
+		Model:       "test-model",
+		Stop:        []string{"a", "b"},
+		RetryConfig: &types.RetryConfig{MaxAttempts: 3},
+	}
+	metadata := &types.RouterMetadata{Provider: "flaky", AttemptCount: 1}
+
+	_, err := router.ExecuteCompletion(context.Background(), req, "flaky", limited, metadata)
+	var stopErr *StopSequenceError
+	if !errors.As(err, &stopErr) {
+		t.Fatalf("Expected a StopSequenceError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "at most 1 are supported") {
+		t.Errorf("Expected the limit in the message, got %v", err)
+	}
+	if provider.calls != 0 || metadata.AttemptCount != 1 {
+		t.Errorf("Expected no provider calls and no retries, got %d calls and %d attempts", provider.calls, metadata.AttemptCount)
+	}
+}
+
+// stopLimitedProvider advertises a stop sequence limit
+type stopLimitedProvider struct {
+	*flakyProvider
+	limit int
+}
+
+func (s *stopLimitedProvider) GetCapabilities() types.ProviderCapabilities {
+	capabilities := s.flakyProvider.GetCapabilities()
+	capabilities.MaxStopSequences = s.limit
+	return capabilities
+}
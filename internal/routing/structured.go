@@ -0,0 +1,49 @@
+package routing
+
+import (
+	"fmt"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// UnsupportedFeatureError is returned when a request explicitly targets a
+// provider that can't honor a feature the request depends on
+type UnsupportedFeatureError struct {
+	Provider string
+	Feature  string
+}
+
+func (e *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("provider %s does not support %s", e.Provider, e.Feature)
+}
+
+// requestsJSONSchema reports whether req asks for output constrained to a
+// JSON schema, which providers without structured output would silently
+// return as plain text
+func requestsJSONSchema(req *types.ChatRequest) bool {
+	return req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema"
+}
+
+// requestsStrictSchema reports whether req asks for a strict JSON schema,
+// which only native structured output guarantees
+func requestsStrictSchema(req *types.ChatRequest) bool {
+	return requestsJSONSchema(req) && req.ResponseFormat.JSONSchema != nil && req.ResponseFormat.JSONSchema.Strict
+}
+
+// supportsResponseFormat reports whether a provider can honor req's response
+// format. Strict schemas need native structured output; other schemas are
+// also served by a best-effort JSON mode.
+func supportsResponseFormat(capabilities types.ProviderCapabilities, req *types.ChatRequest) bool {
+	if !requestsJSONSchema(req) || capabilities.SupportsStructuredOutput {
+		return true
+	}
+	return capabilities.SupportsJSONMode && !requestsStrictSchema(req)
+}
+
+// responseFormatFeature names the response format req needs for errors
+func responseFormatFeature(req *types.ChatRequest) string {
+	if requestsStrictSchema(req) {
+		return "strict json_schema response format"
+	}
+	return "json_schema response format"
+}
@@ -0,0 +1,164 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/anthropic"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// structuredProvider supports JSON-schema structured output
+type structuredProvider struct {
+	pricedProvider
+}
+
+func (p *structuredProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "structured", SupportsStructuredOutput: true}
+}
+
+func schemaRequest(model string) *types.ChatRequest {
+	return &types.ChatRequest{
+		Model:       model,
+		Messages:    []types.Message{{Role: "user", Content: "Hello"}},
+		OptimizeFor: types.OptimizeCost,
+		ResponseFormat: &types.ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &types.JSONSchema{Name: "greeting", Schema: map[string]interface{}{"type": "object"}, Strict: true},
+		},
+	}
+}
+
+func TestRouter_Route_JSONSchemaRequiresStructuredOutput(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("anthropic", &pricedProvider{cost: 0.01})
+	router.RegisterProvider("openai", &structuredProvider{pricedProvider{cost: 0.05}})
+	router.lastHealthCheck = time.Now()
+
+	// Cost routing skips the cheaper provider that can't honor the schema
+	metadata, _, err := router.Route(context.Background(), schemaRequest("test-model"))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "openai" {
+		t.Errorf("Expected json_schema request to route to openai, got %s", metadata.Provider)
+	}
+
+	// Other response formats don't need structured output support
+	req := schemaRequest("test-model")
+	req.ResponseFormat = &types.ResponseFormat{Type: "json_object"}
+	metadata, _, err = router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "anthropic" {
+		t.Errorf("Expected json_object request to route to the cheapest provider, got %s", metadata.Provider)
+	}
+
+	// A model pinned to an unsupported provider is rejected, not degraded
+	_, _, err = router.Route(context.Background(), schemaRequest("claude-3-haiku-20240307"))
+	var featureErr *UnsupportedFeatureError
+	if !errors.As(err, &featureErr) {
+		t.Fatalf("Expected UnsupportedFeatureError, got %v", err)
+	}
+	if featureErr.Provider != "anthropic" {
+		t.Errorf("Expected the error to name anthropic, got %s", featureErr.Provider)
+	}
+}
+
+func TestRouter_Route_CapabilityOverride(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	newAnthropic := func(overrides providers.CapabilityOverrides) *anthropic.AnthropicProvider {
+		return anthropic.NewAnthropicProvider(&anthropic.AnthropicConfig{
+			APIKey:       "test-api-key",
+			Models:       []types.ModelInfo{{Name: "claude-3-haiku-20240307", InputCostPer1K: 0.00025, OutputCostPer1K: 0.00125}},
+			CommonConfig: providers.CommonConfig{Capabilities: overrides},
+		}, logger)
+	}
+	req := schemaRequest("claude-3-haiku-20240307")
+	req.RequiredFeatures = []string{"structured_output"}
+
+	// Built in, Anthropic has no structured output
+	router := createTestRouter(t)
+	router.RegisterProvider("anthropic", newAnthropic(nil))
+	router.lastHealthCheck = time.Now()
+	_, _, err := router.Route(context.Background(), req)
+	var featureErr *UnsupportedFeatureError
+	if !errors.As(err, &featureErr) {
+		t.Fatalf("Expected UnsupportedFeatureError without the override, got %v", err)
+	}
+
+	// The config override is merged over the code defaults
+	overrides := providers.CapabilityOverrides{"supports_structured_output": true}
+	if err := overrides.Validate(); err != nil {
+		t.Fatalf("Expected a known capability key to validate, got %v", err)
+	}
+	router = createTestRouter(t)
+	router.RegisterProvider("anthropic", newAnthropic(overrides))
+	router.lastHealthCheck = time.Now()
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed with the override: %v", err)
+	}
+	if metadata.Provider != "anthropic" {
+		t.Errorf("Expected the structured output request to route to anthropic, got %s", metadata.Provider)
+	}
+	if capabilities := router.GetCapabilities()["anthropic"]; !capabilities.SupportsStructuredOutput || !capabilities.SupportsVision {
+		t.Errorf("Expected the override on top of the other built-in capabilities, got %+v", capabilities)
+	}
+
+	if err := (providers.CapabilityOverrides{"supports_structured_outputs": true}).Validate(); err == nil {
+		t.Error("Expected an unknown capability key to fail validation")
+	}
+}
+
+// jsonModeProvider only offers best-effort JSON output
+type jsonModeProvider struct {
+	pricedProvider
+}
+
+func (p *jsonModeProvider) GetCapabilities() types.ProviderCapabilities {
+	return types.ProviderCapabilities{ProviderName: "json_mode", SupportsJSONMode: true}
+}
+
+func TestRouter_Route_StrictSchemaPrefersStructuredOutput(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("anthropic", &jsonModeProvider{pricedProvider{cost: 0.01}})
+	router.RegisterProvider("openai", &structuredProvider{pricedProvider{cost: 0.05}})
+	router.lastHealthCheck = time.Now()
+
+	// A strict schema skips the cheaper provider whose JSON mode isn't enforced
+	metadata, _, err := router.Route(context.Background(), schemaRequest("test-model"))
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "openai" {
+		t.Errorf("Expected strict json_schema request to route to openai, got %s", metadata.Provider)
+	}
+
+	// Without strict, best-effort JSON mode is good enough
+	req := schemaRequest("test-model")
+	req.ResponseFormat.JSONSchema.Strict = false
+	metadata, _, err = router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "anthropic" {
+		t.Errorf("Expected non-strict json_schema request to route to the cheapest provider, got %s", metadata.Provider)
+	}
+
+	// A strict schema pinned to a JSON mode provider is rejected
+	_, _, err = router.Route(context.Background(), schemaRequest("claude-3-haiku-20240307"))
+	var featureErr *UnsupportedFeatureError
+	if !errors.As(err, &featureErr) {
+		t.Fatalf("Expected UnsupportedFeatureError, got %v", err)
+	}
+	if featureErr.Feature != "strict json_schema response format" {
+		t.Errorf("Unexpected feature %q", featureErr.Feature)
+	}
+}
@@ -0,0 +1,183 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// UnknownModelError is returned when no registered provider serves the
+// requested model
+type UnknownModelError struct {
+	Model      string
+	Suggestion string // closest configured model, empty when none is close
+}
+
+func (e *UnknownModelError) Error() string {
+	if e.Suggestion == "" {
+		return fmt.Sprintf("no provider found for model %s", e.Model)
+	}
+	return fmt.Sprintf("no provider found for model %s; did you mean %s?", e.Model, e.Suggestion)
+}
+
+// isKnownModel reports whether model appears anywhere in the configuration:
+// a provider's model list, a model equivalent or an override. A model pinned
+// to a provider that doesn't list its models is assumed known. Callers hold
+// mu.
+func (r *Router) isKnownModel(owner, model string) bool {
+	if len(r.providers[owner].GetCapabilities().SupportedModels) == 0 {
+		return true
+	}
+	if _, exists := r.modelEquivalents[model]; exists {
+		return true
+	}
+	if _, exists := r.modelOverrides[model]; exists {
+		return true
+	}
+	for _, name := range r.providerNames {
+		for _, info := range r.providers[name].GetCapabilities().SupportedModels {
+			if info.Name == model || info.ProviderModelID == model {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listingProvider returns a registered provider whose model list includes
+// model, preferring a healthy one, for models no prefix or override maps to
+// a registered provider. Callers hold mu.
+func (r *Router) listingProvider(model string) (string, bool) {
+	listed := ""
+	for _, name := range r.providerNames {
+		for _, info := range r.providers[name].GetCapabilities().SupportedModels {
+			if info.Name != model && info.ProviderModelID != model {
+				continue
+			}
+			if r.isProviderHealthy(name) {
+				return name, true
+			}
+			if listed == "" {
+				listed = name
+			}
+		}
+	}
+	return listed, listed != ""
+}
+
+// routeUnknownModel handles a request for a model no provider serves. With
+// fallback enabled the closest model of the same family, e.g. "gpt" for
+// "gpt-4p", is substituted; otherwise the error suggests the closest model.
+// Callers hold mu.
+func (r *Router) routeUnknownModel(req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
+	if req.FallbackConfig != nil && req.FallbackConfig.Enabled {
+		if target, found := r.closestModel(req.Model, true); found {
+			reason := fmt.Sprintf("Model %s not found, substituted closest %s model %s on %s", req.Model, modelFamily(req.Model), target.Model, target.Provider)
+			return r.substituteModel(req, target, reason), r.providers[target.Provider], nil
+		}
+	}
+
+	err := &UnknownModelError{Model: req.Model}
+	if target, found := r.closestModel(req.Model, false); found {
+		err.Suggestion = target.Model
+	}
+	return nil, nil, err
+}
+
+// closestModel returns the configured model, other than model itself, with
+// the smallest edit distance to model, provided it is close enough to
+// plausibly be a typo. With sameFamily, only healthy providers' models of
+// model's family are considered.
+func (r *Router) closestModel(model string, sameFamily bool) (ModelEquivalent, bool) {
+	family := modelFamily(model)
+	best, bestDistance := ModelEquivalent{}, -1
+	for _, name := range r.providerNames {
+		if sameFamily && !r.isProviderHealthy(name) {
+			continue
+		}
+		for _, info := range r.providers[name].GetCapabilities().SupportedModels {
+			if sameFamily && modelFamily(info.Name) != family {
+				continue
+			}
+			if strings.EqualFold(info.Name, model) {
+				continue
+			}
+			distance := levenshtein(strings.ToLower(model), strings.ToLower(info.Name))
+			if bestDistance < 0 || distance < bestDistance {
+				best, bestDistance = ModelEquivalent{Provider: name, Model: info.Name}, distance
+			}
+		}
+	}
+
+	if bestDistance < 0 || bestDistance > max(2, len(model)/3) {
+		return ModelEquivalent{}, false
+	}
+	return best, true
+}
+
+// modelFamily returns the family a model name belongs to, the part before
+// its first hyphen: "gpt" for "gpt-4o", "claude" for "claude-3-haiku"
+func modelFamily(model string) string {
+	family, _, _ := strings.Cut(strings.ToLower(model), "-")
+	return family
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(t)]
+}
+
+// substituteModel rewrites the request to target's model and builds the
+// decision routing it there, recording the original model. Callers hold mu.
+func (r *Router) substituteModel(req *types.ChatRequest, target ModelEquivalent, reason string) *RoutingDecision {
+	originalModel := req.Model
+	req.Model = target.Model
+	provider := r.providers[target.Provider]
+
+	costEst, err := provider.EstimateCost(req)
+	if err != nil {
+		r.logger.WithError(err).Warnf("Failed to estimate cost for %s", target.Provider)
+		costEst = &types.CostEstimate{TotalCost: 0}
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"original_model": originalModel,
+		"provider":       target.Provider,
+		"model":          target.Model,
+	}).Warn(reason)
+
+	return &RoutingDecision{
+		SelectedProvider: target.Provider,
+		Reasoning: []string{
+			fmt.Sprintf("Specific model requested: %s", originalModel),
+			reason,
+		},
+		EstimatedCost:        costEst.TotalCost,
+		EstimatedLatency:     r.estimateLatency(target.Provider),
+		FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+		FallbackChain:        r.buildFallbackChain(target.Provider, req),
+		RoutingContext:       r.buildRoutingContext("specific", req, []string{target.Provider}),
+		OriginalModel:        originalModel,
+	}
+}
@@ -0,0 +1,121 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func createSuggestRouter(t *testing.T) *Router {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+	router.lastHealthCheck = time.Now()
+	return router
+}
+
+func TestRouter_Route_UnknownModelSuggestion(t *testing.T) {
+	router := createSuggestRouter(t)
+
+	req := &types.ChatRequest{Model: "gpt-4p", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	_, _, err := router.Route(context.Background(), req)
+	var unknown *UnknownModelError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected UnknownModelError, got %v", err)
+	}
+	if unknown.Suggestion != "gpt-4o" {
+		t.Errorf("Expected suggestion gpt-4o, got %q", unknown.Suggestion)
+	}
+	if err.Error() != "no provider found for model gpt-4p; did you mean gpt-4o?" {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+	if req.Model != "gpt-4p" {
+		t.Errorf("Request model should be untouched without fallback, got %s", req.Model)
+	}
+
+	// Nothing configured is close enough to suggest
+	_, _, err = router.Route(context.Background(), &types.ChatRequest{Model: "gpt-next-generation-ultra"})
+	if !errors.As(err, &unknown) || unknown.Suggestion != "" {
+		t.Errorf("Expected no suggestion for a distant model, got %v", err)
+	}
+}
+
+func TestRouter_Route_UnknownModelSubstitution(t *testing.T) {
+	router := createSuggestRouter(t)
+
+	req := &types.ChatRequest{
+		Model:          "gpt-4p",
+		Messages:       []types.Message{{Role: "user", Content: "Hello"}},
+		FallbackConfig: &types.FallbackConfig{Enabled: true},
+	}
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "openai" || metadata.Model != "gpt-4o" || req.Model != "gpt-4o" {
+		t.Errorf("Expected substitution to gpt-4o on openai, got %s on %s", metadata.Model, metadata.Provider)
+	}
+	if !metadata.ModelSubstituted || metadata.OriginalModel != "gpt-4p" {
+		t.Errorf("Expected substitution recorded in metadata, got %+v", metadata)
+	}
+
+	// Models of another family are never substituted
+	router.RegisterModelPrefix("claude-", "openai")
+	_, _, err = router.Route(context.Background(), &types.ChatRequest{
+		Model:          "claude-3-haiku",
+		FallbackConfig: &types.FallbackConfig{Enabled: true},
+	})
+	var unknown *UnknownModelError
+	if !errors.As(err, &unknown) {
+		t.Errorf("Expected UnknownModelError across families, got %v", err)
+	}
+
+	// Distant models of the same family are not substituted either
+	_, _, err = router.Route(context.Background(), &types.ChatRequest{
+		Model:          "gpt-next-generation-ultra",
+		FallbackConfig: &types.FallbackConfig{Enabled: true},
+	})
+	if !errors.As(err, &unknown) || unknown.Suggestion != "" {
+		t.Errorf("Expected UnknownModelError without suggestion for a distant model, got %v", err)
+	}
+}
+
+func TestRouter_Route_ListingProvider(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("primary", createTestOpenAIProvider())
+	router.lastHealthCheck = time.Now()
+
+	// The gpt- prefix maps to "openai", which isn't registered
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if metadata.Provider != "primary" || metadata.Model != "gpt-4o" {
+		t.Errorf("Expected gpt-4o on primary, got %s on %s", metadata.Model, metadata.Provider)
+	}
+	if metadata.ModelSubstituted {
+		t.Error("A listed model should not be reported as substituted")
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"gpt-4o", "gpt-4o", 0},
+		{"gpt-4p", "gpt-4o", 1},
+		{"gpt4o", "gpt-4o", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
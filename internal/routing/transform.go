@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// RequestTransformer rewrites a chat request before it is routed, e.g. to
+// inject metadata or rewrite the model. An error aborts the request.
+type RequestTransformer interface {
+	TransformRequest(ctx context.Context, req *types.ChatRequest) error
+}
+
+// ResponseTransformer rewrites a completed, non-streaming chat response
+// before it is returned. An error fails the request.
+type ResponseTransformer interface {
+	TransformResponse(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error
+}
+
+// RequestTransformerFunc adapts a function to RequestTransformer
+type RequestTransformerFunc func(ctx context.Context, req *types.ChatRequest) error
+
+func (f RequestTransformerFunc) TransformRequest(ctx context.Context, req *types.ChatRequest) error {
+	return f(ctx, req)
+}
+
+// ResponseTransformerFunc adapts a function to ResponseTransformer
+type ResponseTransformerFunc func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error
+
+func (f ResponseTransformerFunc) TransformResponse(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+	return f(ctx, req, resp)
+}
+
+// Transformer stages reported by TransformError
+const (
+	TransformStageRequest  = "request"
+	TransformStageResponse = "response"
+)
+
+// TransformError is a transformer aborting a request
+type TransformError struct {
+	Stage string // TransformStageRequest or TransformStageResponse
+	Err   error
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("%s transformer failed: %v", e.Stage, e.Err)
+}
+
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// TransformerAuthUser names AuthUserTransformer in router.request_transformers
+const TransformerAuthUser = "auth_user"
+
+// AuthUserTransformer sets the request's user to the authenticated one, so
+// spend caps and usage records follow the credential rather than whatever
+// user the client sent. Unauthenticated requests are left alone.
+type AuthUserTransformer struct{}
+
+func (AuthUserTransformer) TransformRequest(ctx context.Context, req *types.ChatRequest) error {
+	if authInfo, ok := security.GetAuthInfo(ctx); ok && authInfo.UserID != "" {
+		req.UserID = authInfo.UserID
+	}
+	return nil
+}
+
+// builtinRequestTransformers are the request transformers configuration can
+// name
+var builtinRequestTransformers = map[string]RequestTransformer{
+	TransformerAuthUser: AuthUserTransformer{},
+}
+
+// RequestTransformersByName returns the built-in request transformers names
+// refer to, in order
+func RequestTransformersByName(names []string) ([]RequestTransformer, error) {
+	transformers := make([]RequestTransformer, 0, len(names))
+	for _, name := range names {
+		transformer, ok := builtinRequestTransformers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown request transformer %q", name)
+		}
+		transformers = append(transformers, transformer)
+	}
+	return transformers, nil
+}
+
+// AddRequestTransformer appends t to the chain run on every chat request
+// before it is routed. Reload replaces the chain.
+func (r *Router) AddRequestTransformer(t RequestTransformer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requestTransformers = append(r.requestTransformers, t)
+}
+
+// AddResponseTransformer appends t to the chain run on every completed chat
+// response. Reload replaces the chain.
+func (r *Router) AddResponseTransformer(t ResponseTransformer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.responseTransformers = append(r.responseTransformers, t)
+}
+
+// transformRequest runs the request transformers in order, stopping at the
+// first error
+func (r *Router) transformRequest(ctx context.Context, req *types.ChatRequest) error {
+	// Transformers run without mu so they may call back into the router
+	r.mu.RLock()
+	chain := r.requestTransformers
+	r.mu.RUnlock()
+
+	for _, transformer := range chain {
+		if err := transformer.TransformRequest(ctx, req); err != nil {
+			return &TransformError{Stage: TransformStageRequest, Err: err}
+		}
+	}
+	return nil
+}
+
+// TransformResponse runs the response transformers on resp in order,
+// stopping at the first error
+func (r *Router) TransformResponse(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+	r.mu.RLock()
+	chain := r.responseTransformers
+	r.mu.RUnlock()
+
+	for _, transformer := range chain {
+		if err := transformer.TransformResponse(ctx, req, resp); err != nil {
+			return &TransformError{Stage: TransformStageResponse, Err: err}
+		}
+	}
+	return nil
+}
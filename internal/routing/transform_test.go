@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestRouter_Route_RequestTransformersRunInOrder(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+
+	var order []string
+	router.AddRequestTransformer(RequestTransformerFunc(func(ctx context.Context, req *types.ChatRequest) error {
+		order = append(order, "first")
+		req.Model = "gpt-4o"
+		return nil
+	}))
+	router.AddRequestTransformer(RequestTransformerFunc(func(ctx context.Context, req *types.ChatRequest) error {
+		order = append(order, "second:"+req.Model)
+		return nil
+	}))
+
+	req := &types.ChatRequest{Model: "rewritten-away", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	metadata, _, err := router.Route(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Routing failed: %v", err)
+	}
+	if !slices.Equal(order, []string{"first", "second:gpt-4o"}) {
+		t.Errorf("Unexpected transformer order %v", order)
+	}
+	if metadata.Model != "gpt-4o" {
+		t.Errorf("Expected the rewritten model to be routed, got %s", metadata.Model)
+	}
+}
+
+func TestRouter_Route_RequestTransformerErrorShortCircuits(t *testing.T) {
+	router := createTestRouter(t)
+	router.RegisterProvider("openai", createTestOpenAIProvider())
+
+	rejected := errors.New("missing tenant header")
+	called := false
+	router.AddRequestTransformer(RequestTransformerFunc(func(ctx context.Context, req *types.ChatRequest) error {
+		return rejected
+	}))
+	router.AddRequestTransformer(RequestTransformerFunc(func(ctx context.Context, req *types.ChatRequest) error {
+		called = true
+		return nil
+	}))
+
+	req := &types.ChatRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "Hello"}}}
+	_, _, err := router.Route(context.Background(), req)
+
+	var transformErr *TransformError
+	if !errors.As(err, &transformErr) {
+		t.Fatalf("Expected a TransformError, got %v", err)
+	}
+	if transformErr.Stage != TransformStageRequest || !errors.Is(err, rejected) {
+		t.Errorf("Unexpected transform error %v", err)
+	}
+	if called {
+		t.Error("Transformers after a failing one must not run")
+	}
+}
+
+func TestRouter_TransformResponse(t *testing.T) {
+	router := createTestRouter(t)
+
+	var order []string
+	for _, name := range []string{"first", "second"} {
+		router.AddResponseTransformer(ResponseTransformerFunc(func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+			order = append(order, name)
+			resp.SystemFingerprint += name
+			return nil
+		}))
+	}
+	failed := errors.New("redaction service down")
+	router.AddResponseTransformer(ResponseTransformerFunc(func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+		return failed
+	}))
+	router.AddResponseTransformer(ResponseTransformerFunc(func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+		order = append(order, "after failure")
+		return nil
+	}))
+
+	resp := &types.ChatResponse{}
+	err := router.TransformResponse(context.Background(), &types.ChatRequest{}, resp)
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the transformer's error, got %v", err)
+	}
+	if !slices.Equal(order, []string{"first", "second"}) || resp.SystemFingerprint != "firstsecond" {
+		t.Errorf("Unexpected transformer runs %v, fingerprint %q", order, resp.SystemFingerprint)
+	}
+}
+
+func TestAuthUserTransformer(t *testing.T) {
+	transformers, err := RequestTransformersByName([]string{TransformerAuthUser})
+	if err != nil {
+		t.Fatalf("Failed to look up transformer: %v", err)
+	}
+
+	req := &types.ChatRequest{UserID: "client-supplied"}
+	ctx := context.WithValue(context.Background(), "auth_info", &security.AuthInfo{UserID: "key-owner"})
+	if err := transformers[0].TransformRequest(ctx, req); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if req.UserID != "key-owner" {
+		t.Errorf("Expected the authenticated user, got %s", req.UserID)
+	}
+
+	// Unauthenticated requests keep their user
+	req = &types.ChatRequest{UserID: "client-supplied"}
+	if err := transformers[0].TransformRequest(context.Background(), req); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if req.UserID != "client-supplied" {
+		t.Errorf("Expected the user to be kept, got %s", req.UserID)
+	}
+
+	if _, err := RequestTransformersByName([]string{"nope"}); err == nil {
+		t.Error("Expected an unknown transformer to be rejected")
+	}
+}
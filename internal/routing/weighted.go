@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// defaultProviderWeight applies to providers without a configured weight
+const defaultProviderWeight = 1
+
+// SetProviderWeights configures relative provider capacity for weighted
+// round-robin routing. Providers without an entry get weight 1; providers
+// with a weight of zero are never selected by the weighted strategy.
+func (r *Router) SetProviderWeights(weights map[string]int) {
+	r.weightedMu.Lock()
+	defer r.weightedMu.Unlock()
+
+	r.providerWeights = make(map[string]int, len(weights))
+	for name, weight := range weights {
+		r.providerWeights[name] = weight
+	}
+	r.currentWeights = make(map[string]int)
+}
+
+// SetDefaultStrategy sets the strategy used when a request has no
+// optimization preference and doesn't name a provider-specific model
+func (r *Router) SetDefaultStrategy(strategy RoutingStrategy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.defaultStrategy = strategy
+}
+
+// routeWeightedRoundRobin distributes requests across healthy providers in
+// proportion to their weights using smooth weighted round-robin (as in nginx),
+// which interleaves selections instead of sending bursts to one provider.
+func (r *Router) routeWeightedRoundRobin(ctx context.Context, req *types.ChatRequest) (*RoutingDecision, providers.LLMProvider, error) {
+	candidates := r.getHealthyProviders()
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoHealthyProviders
+	}
+
+	// Filter providers by feature requirements
+	candidates = r.filterByFeatures(candidates, req)
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("no providers support required features")
+	}
+
+	selected, weight := r.nextWeighted(candidates)
+	if selected == "" {
+		return nil, nil, fmt.Errorf("no providers with a positive routing weight")
+	}
+
+	provider := r.providers[selected]
+
+	// Get cost estimate
+	costEst, err := provider.EstimateCost(req)
+	if err != nil {
+		r.logger.WithError(err).Warnf("Failed to estimate cost for %s", selected)
+		costEst = &types.CostEstimate{TotalCost: 0}
+	}
+
+	decision := &RoutingDecision{
+		SelectedProvider:     selected,
+		Reasoning:            []string{fmt.Sprintf("Weighted round-robin selected %s (weight %d)", selected, weight)},
+		EstimatedCost:        costEst.TotalCost,
+		EstimatedLatency:     r.estimateLatency(selected),
+		FeatureCompatibility: r.checkFeatureCompatibility(provider, req),
+		FallbackChain:        r.buildFallbackChain(selected, req),
+		RoutingContext:       r.buildRoutingContext("weighted", req, candidates),
+	}
+
+	return decision, provider, nil
+}
+
+// nextWeighted performs one smooth weighted round-robin step over candidates.
+// Providers that aren't candidates have their running weight reset, so a
+// provider recovering from an outage rejoins with neither a backlog of credit
+// nor a debt and immediately receives its fair share.
+func (r *Router) nextWeighted(candidates []string) (string, int) {
+	r.weightedMu.Lock()
+	defer r.weightedMu.Unlock()
+
+	eligible := make(map[string]bool, len(candidates))
+	for _, name := range candidates {
+		eligible[name] = true
+	}
+
+	for name := range r.currentWeights {
+		if !eligible[name] {
+			delete(r.currentWeights, name)
+		}
+	}
+
+	// Walk providers in registration order so ties break deterministically
+	selected, total := "", 0
+	for _, name := range r.providerNames {
+		if !eligible[name] {
+			continue
+		}
+		weight := r.weightFor(name)
+		if weight <= 0 {
+			continue
+		}
+
+		r.currentWeights[name] += weight
+		total += weight
+		if selected == "" || r.currentWeights[name] > r.currentWeights[selected] {
+			selected = name
+		}
+	}
+
+	if selected == "" {
+		return "", 0
+	}
+
+	r.currentWeights[selected] -= total
+	return selected, r.weightFor(selected)
+}
+
+// weightFor returns the configured weight for a provider
+func (r *Router) weightFor(name string) int {
+	if weight, exists := r.providerWeights[name]; exists {
+		return weight
+	}
+	return defaultProviderWeight
+}
@@ -1,11 +1,15 @@
 package security
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -64,8 +68,48 @@ type AuditConfig struct {
 	SensitiveFields []string      `yaml:"sensitive_fields"`
 	RemoteEndpoint  string        `yaml:"remote_endpoint"`
 	RemoteToken     string        `yaml:"remote_token"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // Upper bound on draining buffered events in Stop
+	
+	// What happens to events that arrive while the buffer is full
+	OverflowPolicy OverflowPolicy `yaml:"overflow_policy"`
+	OverflowPath   string         `yaml:"overflow_path"` // file overflow_file appends events to
+}
+
+// OverflowPolicy decides what happens to audit events when the buffer is full
+type OverflowPolicy string
+
+const (
+	// OverflowDrop discards the event and counts it as dropped
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock makes the caller wait for buffer space
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowFile writes the event synchronously to OverflowPath
+	OverflowFile OverflowPolicy = "overflow_file"
+)
+
+// Validate checks the overflow policy
+func (c *AuditConfig) Validate() error {
+	switch c.OverflowPolicy {
+	case "", OverflowDrop, OverflowBlock:
+	case OverflowFile:
+		if c.OverflowPath == "" {
+			return fmt.Errorf("audit overflow_path is required with overflow_policy %s", OverflowFile)
+		}
+	default:
+		return fmt.Errorf("unsupported audit overflow_policy %q: must be drop, block or overflow_file", c.OverflowPolicy)
+	}
+	return nil
 }
 
+const (
+	// remoteDeliveryTimeout bounds sending one event to the remote endpoint
+	remoteDeliveryTimeout = 5 * time.Second
+
+	// drainDeliveryTimeout bounds remote delivery of each event while
+	// draining on shutdown, where delivery is best-effort
+	drainDeliveryTimeout = 500 * time.Millisecond
+)
+
 // AuditLogger handles security audit logging
 type AuditLogger struct {
 	config     *AuditConfig
@@ -76,6 +120,12 @@ type AuditLogger struct {
 	eventCount int64
 	mu         sync.RWMutex
 	stopped    bool
+	dropped    atomic.Int64 // events never written: buffer overflow or drain timeout
+	overflowed atomic.Int64 // events written to the overflow file
+	overflowMu sync.Mutex   // serializes overflow file writes
+	overflow   *os.File     // open when OverflowPolicy is OverflowFile
+	drainCtx   context.Context // bounds the final drain, set before stopChan closes
+	client     *http.Client
 }
 
 // NewAuditLogger creates a new audit logger
@@ -92,12 +142,24 @@ func NewAuditLogger(config *AuditConfig, logger *logrus.Logger) *AuditLogger {
 	if config.MaxFiles == 0 {
 		config.MaxFiles = 10
 	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = 10 * time.Second
+	}
 
 	auditor := &AuditLogger{
 		config:   config,
 		logger:   logger,
 		buffer:   make(chan *AuditEvent, config.BufferSize),
 		stopChan: make(chan bool),
+		client:   &http.Client{},
+	}
+
+	if config.Enabled && config.OverflowPolicy == OverflowFile {
+		file, err := os.OpenFile(config.OverflowPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			logger.WithError(err).Error("Failed to open audit overflow file, overflowing events will be dropped")
+		}
+		auditor.overflow = file
 	}
 
 	if config.Enabled {
@@ -144,13 +206,58 @@ func (a *AuditLogger) LogEvent(ctx context.Context, eventType AuditEventType, me
 	// Try to add event to buffer
 	select {
 	case a.buffer <- event:
-		a.mu.Lock()
-		a.eventCount++
-		a.mu.Unlock()
+		a.countEvent()
 	default:
-		// Buffer full, log warning and drop event
-		a.logger.Warn("Audit buffer full, dropping event")
+		a.handleOverflow(ctx, event)
+	}
+}
+
+func (a *AuditLogger) countEvent() {
+	a.mu.Lock()
+	a.eventCount++
+	a.mu.Unlock()
+}
+
+// handleOverflow applies the overflow policy to an event that found the
+// buffer full
+func (a *AuditLogger) handleOverflow(ctx context.Context, event *AuditEvent) {
+	switch a.config.OverflowPolicy {
+	case OverflowBlock:
+		select {
+		case a.buffer <- event:
+			a.countEvent()
+			return
+		case <-a.stopChan:
+		case <-ctx.Done():
+		}
+	case OverflowFile:
+		err := a.writeOverflow(event)
+		if err == nil {
+			a.overflowed.Add(1)
+			return
+		}
+		a.logger.WithError(err).Warn("Failed to write audit event to overflow file")
+	}
+
+	a.dropped.Add(1)
+	a.logger.Warn("Audit buffer full, dropping event")
+}
+
+// writeOverflow appends event to the overflow file as a JSON line
+func (a *AuditLogger) writeOverflow(event *AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.overflowMu.Lock()
+	defer a.overflowMu.Unlock()
+	if a.overflow == nil {
+		return fmt.Errorf("overflow file is not open")
 	}
+	_, err = a.overflow.Write(line)
+	return err
 }
 
 // LogAuthenticationAttempt logs authentication attempts
@@ -220,10 +327,12 @@ func (a *AuditLogger) AuditMiddleware() func(http.Handler) http.Handler {
 				statusCode:     200,
 			}
 			
+			r, telemetry := RequestTelemetry(r)
+			
 			// Add request ID to context
 			requestID := generateRequestID()
 			ctx := context.WithValue(r.Context(), "request_id", requestID)
-			ctx = context.WithValue(ctx, "client_ip", getClientIPFromRequest(r))
+			ctx = context.WithValue(ctx, "client_ip", ClientIP(r))
 			
 			// Process request
 			next.ServeHTTP(wrapper, r.WithContext(ctx))
@@ -273,6 +382,21 @@ func (a *AuditLogger) AuditMiddleware() func(http.Handler) http.Handler {
 				}
 			}
 			
+			// Opted-out traffic keeps only security-relevant events, without
+			// client fingerprinting details
+			if telemetry.OptedOut() {
+				if wrapper.statusCode < 400 {
+					return
+				}
+				details = map[string]interface{}{
+					"method":            r.Method,
+					"path":              r.URL.Path,
+					"status_code":       wrapper.statusCode,
+					"duration_ms":       duration.Milliseconds(),
+					"telemetry_opt_out": true,
+				}
+			}
+			
 			a.LogEvent(ctx, eventType, message, details)
 		})
 	}
@@ -285,24 +409,51 @@ func (a *AuditLogger) GetEventCount() int64 {
 	return a.eventCount
 }
 
-// Stop stops the audit logger
+// GetDroppedCount returns the number of events that were never written,
+// because the buffer was full or the shutdown drain ran out of time
+func (a *AuditLogger) GetDroppedCount() int64 {
+	return a.dropped.Load()
+}
+
+// GetOverflowCount returns the number of events written to the overflow file
+// because the buffer was full
+func (a *AuditLogger) GetOverflowCount() int64 {
+	return a.overflowed.Load()
+}
+
+// Stop stops the audit logger, writing buffered events until the configured
+// ShutdownTimeout. Events still buffered at the deadline are dropped and
+// counted, so a slow remote endpoint can't hold up server shutdown.
 func (a *AuditLogger) Stop() {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	
 	if !a.config.Enabled || a.stopped {
+		a.mu.Unlock()
 		return
 	}
-	
 	a.stopped = true
+	a.mu.Unlock()
+	
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+	defer cancel()
+	
+	droppedBefore := a.dropped.Load()
+	a.drainCtx = ctx
 	close(a.stopChan)
 	a.wg.Wait()
-	close(a.buffer)
 	
-	// Flush remaining events
-	for event := range a.buffer {
-		a.writeEvent(event)
+	if dropped := a.dropped.Load() - droppedBefore; dropped > 0 {
+		a.logger.WithFields(logrus.Fields{
+			"dropped_events":   dropped,
+			"shutdown_timeout": a.config.ShutdownTimeout,
+		}).Warn("Audit drain timed out, dropping buffered events")
 	}
+	
+	a.overflowMu.Lock()
+	if a.overflow != nil {
+		a.overflow.Close()
+		a.overflow = nil
+	}
+	a.overflowMu.Unlock()
 }
 
 // Private methods
@@ -340,9 +491,7 @@ func (a *AuditLogger) eventProcessor() {
 			
 		case <-a.stopChan:
 			// Final flush on shutdown
-			if len(events) > 0 {
-				a.flushEvents(events)
-			}
+			a.drain(a.drainCtx, events)
 			return
 		}
 	}
@@ -350,11 +499,30 @@ func (a *AuditLogger) eventProcessor() {
 
 func (a *AuditLogger) flushEvents(events []*AuditEvent) {
 	for _, event := range events {
-		a.writeEvent(event)
+		a.writeEvent(context.Background(), event, remoteDeliveryTimeout)
 	}
 }
 
-func (a *AuditLogger) writeEvent(event *AuditEvent) {
+// drain writes the pending batch and everything left in the buffer until ctx
+// expires, then counts the rest as dropped
+func (a *AuditLogger) drain(ctx context.Context, events []*AuditEvent) {
+	// The processor is the only reader, so the buffer can't empty under us
+	for len(a.buffer) > 0 {
+		events = append(events, <-a.buffer)
+	}
+	
+	for i, event := range events {
+		if ctx.Err() != nil {
+			a.dropped.Add(int64(len(events) - i))
+			return
+		}
+		a.writeEvent(ctx, event, drainDeliveryTimeout)
+	}
+}
+
+// writeEvent logs an event and sends it to the remote endpoint, if
+// configured, giving up on delivery after timeout
+func (a *AuditLogger) writeEvent(ctx context.Context, event *AuditEvent, timeout time.Duration) {
 	// Write to structured log
 	fields := logrus.Fields{
 		"audit_event":  true,
@@ -391,14 +559,41 @@ func (a *AuditLogger) writeEvent(event *AuditEvent) {
 	
 	// Send to remote endpoint if configured
 	if a.config.RemoteEndpoint != "" {
-		go a.sendToRemoteEndpoint(event)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := a.sendToRemoteEndpoint(ctx, event); err != nil {
+			a.logger.WithError(err).WithField("event_id", event.ID).Warn("Failed to send audit event to remote endpoint")
+		}
 	}
 }
 
-func (a *AuditLogger) sendToRemoteEndpoint(event *AuditEvent) {
-	// Implementation would send to external SIEM/logging system
-	// This is a placeholder for the actual implementation
-	a.logger.Debug("Would send audit event to remote endpoint", event.ID)
+// sendToRemoteEndpoint posts an event as JSON to the external SIEM/logging
+// system
+func (a *AuditLogger) sendToRemoteEndpoint(ctx context.Context, event *AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.RemoteEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.config.RemoteToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.config.RemoteToken)
+	}
+	
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("remote endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
 }
 
 func (a *AuditLogger) sanitizeDetails(details map[string]interface{}) map[string]interface{} {
@@ -419,6 +614,13 @@ func (a *AuditLogger) sanitizeDetails(details map[string]interface{}) map[string
 }
 
 func (a *AuditLogger) isSensitiveField(field string) bool {
+	return IsSensitiveField(field, a.config.SensitiveFields)
+}
+
+// IsSensitiveField reports whether a field name looks like it holds a
+// credential, either by containing a well-known word such as "token" or
+// "secret" or by matching one of extra case-insensitively
+func IsSensitiveField(field string, extra []string) bool {
 	fieldLower := strings.ToLower(field)
 	
 	// Default sensitive fields
@@ -435,7 +637,7 @@ func (a *AuditLogger) isSensitiveField(field string) bool {
 	}
 	
 	// Check configured sensitive fields
-	for _, sensitive := range a.config.SensitiveFields {
+	for _, sensitive := range extra {
 		if strings.EqualFold(field, sensitive) {
 			return true
 		}
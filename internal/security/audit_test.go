@@ -2,13 +2,19 @@ package security
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewAuditLogger(t *testing.T) {
@@ -286,6 +292,71 @@ func TestAuditLogger_BufferOverflow(t *testing.T) {
 	assert.LessOrEqual(t, count, int64(5))
 }
 
+func TestAuditLogger_BufferOverflow_OverflowFile(t *testing.T) {
+	// Remote sink that holds the processor in its first flush, so the
+	// buffer fills up
+	delivering := make(chan struct{}, 1)
+	release := make(chan struct{})
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case delivering <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer remote.Close()
+
+	path := filepath.Join(t.TempDir(), "audit-overflow.log")
+	config := &AuditConfig{
+		Enabled:         true,
+		BufferSize:      2,
+		FlushInterval:   time.Millisecond,
+		RemoteEndpoint:  remote.URL,
+		ShutdownTimeout: 100 * time.Millisecond,
+		OverflowPolicy:  OverflowFile,
+		OverflowPath:    path,
+	}
+	require.NoError(t, config.Validate())
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	auditor := NewAuditLogger(config, logger)
+
+	ctx := context.Background()
+	auditor.LogEvent(ctx, AuthenticationSuccess, "first event", nil)
+	select {
+	case <-delivering:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Processor never started delivering")
+	}
+
+	// Two events fill the buffer, the rest overflow
+	for i := 0; i < 5; i++ {
+		auditor.LogEvent(ctx, SecurityViolation, fmt.Sprintf("event %d", i), nil)
+	}
+	close(release)
+	auditor.Stop()
+
+	assert.Equal(t, int64(3), auditor.GetOverflowCount())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	for i, line := range lines {
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.Equal(t, SecurityViolation, event.EventType)
+		assert.Equal(t, fmt.Sprintf("event %d", i+2), event.Message)
+	}
+}
+
+func TestAuditConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&AuditConfig{}).Validate())
+	assert.NoError(t, (&AuditConfig{OverflowPolicy: OverflowBlock}).Validate())
+	assert.Error(t, (&AuditConfig{OverflowPolicy: OverflowFile}).Validate(), "overflow_file needs a path")
+	assert.Error(t, (&AuditConfig{OverflowPolicy: "spill"}).Validate())
+}
+
 func TestAuditLogger_Stop(t *testing.T) {
 	config := &AuditConfig{
 		Enabled:       true,
@@ -311,6 +382,70 @@ func TestAuditLogger_Stop(t *testing.T) {
 	// The test just verifies that Stop() completes successfully
 }
 
+func TestAuditLogger_Stop_SlowRemoteEndpoint(t *testing.T) {
+	// Remote sink that doesn't answer until the test is over
+	release := make(chan struct{})
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer remote.Close()
+	defer close(release)
+
+	config := &AuditConfig{
+		Enabled:         true,
+		BufferSize:      50,
+		FlushInterval:   time.Hour, // keep events buffered until Stop
+		RemoteEndpoint:  remote.URL,
+		ShutdownTimeout: 200 * time.Millisecond,
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	auditor := NewAuditLogger(config, logger)
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		auditor.LogEvent(ctx, AuthenticationSuccess, "test event", nil)
+	}
+
+	start := time.Now()
+	auditor.Stop()
+	elapsed := time.Since(start)
+
+	assert.Less(t, elapsed, time.Second, "Stop should return shortly after the shutdown timeout")
+	assert.Greater(t, auditor.GetDroppedCount(), int64(0), "Events not drained in time should be counted as dropped")
+	assert.Less(t, auditor.GetDroppedCount(), int64(20), "Events delivered before the deadline shouldn't count as dropped")
+}
+
+func TestAuditLogger_SendToRemoteEndpoint(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer remote.Close()
+
+	config := &AuditConfig{
+		Enabled:        true,
+		FlushInterval:  10 * time.Millisecond,
+		RemoteEndpoint: remote.URL,
+		RemoteToken:    "siem-token",
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	auditor := NewAuditLogger(config, logger)
+	defer auditor.Stop()
+
+	auditor.LogEvent(context.Background(), SecurityViolation, "test event", nil)
+
+	select {
+	case r := <-received:
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer siem-token", r.Header.Get("Authorization"))
+	case <-time.After(2 * time.Second):
+		t.Fatal("Event was not sent to the remote endpoint")
+	}
+}
+
 func TestGenerateEventID(t *testing.T) {
 	id1 := generateEventID()
 	id2 := generateEventID()
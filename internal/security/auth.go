@@ -21,6 +21,15 @@ type AuthProvider interface {
 	ValidateJWT(tokenString string) (*JWTClaims, error)
 }
 
+// TokenEndpointPrefix is the path prefix of the token issuance endpoints
+const TokenEndpointPrefix = "/v1/auth/"
+
+// isProbePath reports whether path is a health or liveness/readiness
+// endpoint, which orchestrators call without credentials
+func isProbePath(path string) bool {
+	return strings.HasPrefix(path, "/health") || path == "/livez" || path == "/readyz"
+}
+
 // AuthInfo contains authenticated user information
 type AuthInfo struct {
 	UserID      string            `json:"user_id"`
@@ -40,18 +49,27 @@ type JWTClaims struct {
 
 // Config holds authentication configuration
 type Config struct {
-	APIKeys          []string      `yaml:"api_keys"`
-	JWTSecret        string        `yaml:"jwt_secret"`
-	JWTExpiry        time.Duration `yaml:"jwt_expiry"`
-	RequireAuth      bool          `yaml:"require_auth"`
-	AllowedOrigins   []string      `yaml:"allowed_origins"`
-	TrustedProxies   []string      `yaml:"trusted_proxies"`
+	APIKeys            []string      `yaml:"api_keys"`
+	JWTSecret          string        `yaml:"jwt_secret"`
+	JWTExpiry          time.Duration `yaml:"jwt_expiry"`
+	RefreshTokenExpiry time.Duration `yaml:"refresh_token_expiry"`
+	RequireAuth        bool          `yaml:"require_auth"`
+	AllowedOrigins     []string      `yaml:"allowed_origins"`
+	TrustedProxies     []string      `yaml:"trusted_proxies"`
+	
+	// API keys whose traffic is opted out of non-essential telemetry
+	TelemetryOptOutKeys []string `yaml:"telemetry_opt_out_keys"`
+	
+	// Permission scopes per API key, e.g. "models:gpt-4o" or
+	// "endpoints:/v1/chat/completions". Keys without scopes get api:access.
+	KeyScopes map[string][]string `yaml:"key_scopes"`
 }
 
 // DefaultAuthProvider implements the AuthProvider interface
 type DefaultAuthProvider struct {
-	config *Config
-	logger *logrus.Logger
+	config        *Config
+	logger        *logrus.Logger
+	refreshTokens RefreshTokenStore
 }
 
 // NewDefaultAuthProvider creates a new authentication provider
@@ -59,10 +77,14 @@ func NewDefaultAuthProvider(config *Config, logger *logrus.Logger) *DefaultAuthP
 	if config.JWTExpiry == 0 {
 		config.JWTExpiry = 24 * time.Hour
 	}
+	if config.RefreshTokenExpiry == 0 {
+		config.RefreshTokenExpiry = defaultRefreshTokenExpiry
+	}
 	
 	return &DefaultAuthProvider{
-		config: config,
-		logger: logger,
+		config:        config,
+		logger:        logger,
+		refreshTokens: NewMemoryRefreshTokenStore(),
 	}
 }
 
@@ -95,15 +117,19 @@ func (a *DefaultAuthProvider) ValidateAPIKey(ctx context.Context, apiKey string)
 	// Use constant-time comparison to prevent timing attacks
 	for i, validKey := range a.config.APIKeys {
 		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(validKey)) == 1 {
-			return &AuthInfo{
+			authInfo := &AuthInfo{
 				UserID:      generateUserID(apiKey),
 				APIKey:      apiKey,
-				Permissions: []string{"api:access"},
+				Permissions: a.permissionsFor(validKey),
 				Metadata: map[string]string{
 					"key_index": string(rune(i)),
 					"auth_type": "api_key",
 				},
-			}, nil
+			}
+			if a.isTelemetryOptOutKey(apiKey) {
+				authInfo.Metadata[telemetryOptOutMetadata] = "true"
+			}
+			return authInfo, nil
 		}
 	}
 	
@@ -173,12 +199,15 @@ func (a *DefaultAuthProvider) ValidateJWT(tokenString string) (*JWTClaims, error
 func (a *DefaultAuthProvider) AuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip auth for health check endpoints
-			if strings.HasPrefix(r.URL.Path, "/health") {
+			// Skip auth for health check and token endpoints, which
+			// authenticate their own credentials
+			if isProbePath(r.URL.Path) || strings.HasPrefix(r.URL.Path, TokenEndpointPrefix) {
 				next.ServeHTTP(w, r)
 				return
 			}
 			
+			r, telemetry := RequestTelemetry(r)
+			
 			// Skip auth if not required
 			if !a.config.RequireAuth {
 				next.ServeHTTP(w, r)
@@ -186,21 +215,21 @@ func (a *DefaultAuthProvider) AuthMiddleware() func(http.Handler) http.Handler {
 			}
 			
 			// Extract token from Authorization header or API-Key header
-			token := extractToken(r)
+			token := ExtractToken(r)
 			if token == "" {
 				a.writeUnauthorized(w, "Missing authentication token")
 				return
 			}
 			
 			// Authenticate token
-			ctx := context.WithValue(r.Context(), "client_ip", getClientIPFromRequest(r))
+			ctx := context.WithValue(r.Context(), "client_ip", ClientIP(r))
 			authInfo, err := a.Authenticate(ctx, token)
 			if err != nil {
 				a.logger.WithFields(logrus.Fields{
 					"error":     err.Error(),
 					"path":      r.URL.Path,
 					"method":    r.Method,
-					"remote_ip": getClientIPFromRequest(r),
+					"remote_ip": ClientIP(r),
 					"user_agent": r.UserAgent(),
 				}).Warn("Authentication failed")
 				
@@ -211,13 +240,20 @@ func (a *DefaultAuthProvider) AuthMiddleware() func(http.Handler) http.Handler {
 			// Add auth info to request context
 			ctx = context.WithValue(r.Context(), "auth_info", authInfo)
 			
+			// Per-key telemetry opt-out applies to the whole request
+			if authInfoOptedOut(authInfo) {
+				telemetry.OptOut()
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			
 			// Log successful authentication
 			a.logger.WithFields(logrus.Fields{
 				"user_id":    authInfo.UserID,
 				"auth_type":  authInfo.Metadata["auth_type"],
 				"path":       r.URL.Path,
 				"method":     r.Method,
-				"remote_ip":  getClientIPFromRequest(r),
+				"remote_ip":  ClientIP(r),
 			}).Debug("Authentication successful")
 			
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -225,9 +261,20 @@ func (a *DefaultAuthProvider) AuthMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+// isTelemetryOptOutKey reports whether an API key is configured to opt out of telemetry
+func (a *DefaultAuthProvider) isTelemetryOptOutKey(apiKey string) bool {
+	for _, key := range a.config.TelemetryOptOutKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper functions
 
-func extractToken(r *http.Request) string {
+// ExtractToken returns the bearer token or API key presented by a request
+func ExtractToken(r *http.Request) string {
 	// Try Authorization header first (Bearer token)
 	authHeader := r.Header.Get("Authorization")
 	if strings.HasPrefix(authHeader, "Bearer ") {
@@ -269,7 +316,8 @@ func getClientIP(ctx context.Context) string {
 	return "unknown"
 }
 
-func getClientIPFromRequest(r *http.Request) string {
+// ClientIP returns the client address of a request, preferring proxy headers
+func ClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		ips := strings.Split(xff, ",")
@@ -296,7 +344,7 @@ func (a *DefaultAuthProvider) writeUnauthorized(w http.ResponseWriter, message s
 	
 	// Simple JSON response without using the json package to keep it lightweight
 	timestamp := time.Now().Unix()
-	response := fmt.Sprintf(`{"error":{"message":"%s","type":"authentication_error","code":401},"timestamp":%d}`, message, timestamp)
+	response := fmt.Sprintf(`{"error":{"message":"%s","type":"authentication_error","code":"unauthorized","status":401},"timestamp":%d}`, message, timestamp)
 	w.Write([]byte(response))
 }
 
@@ -0,0 +1,108 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHMACSecret = "test-hmac-secret"
+
+func signedRequest(body string, signedAt time.Time) *http.Request {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set(DefaultHMACTimestampHeader, timestamp)
+	req.Header.Set(DefaultHMACHeader, SignRequest(testHMACSecret, timestamp, []byte(body)))
+	return req
+}
+
+func TestNewHMACVerifier(t *testing.T) {
+	_, err := NewHMACVerifier(&HMACAuthConfig{}, logrus.New())
+	assert.Error(t, err)
+
+	verifier, err := NewHMACVerifier(&HMACAuthConfig{Secret: testHMACSecret}, logrus.New())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultHMACHeader, verifier.config.Header)
+	assert.Equal(t, DefaultHMACTimestampHeader, verifier.config.TimestampHeader)
+	assert.Equal(t, DefaultHMACMaxSkew, verifier.config.MaxSkew)
+}
+
+func TestHMACVerifier_Verify(t *testing.T) {
+	verifier, err := NewHMACVerifier(&HMACAuthConfig{Secret: testHMACSecret, MaxSkew: time.Minute}, logrus.New())
+	require.NoError(t, err)
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`
+
+	t.Run("valid signature", func(t *testing.T) {
+		req := signedRequest(body, time.Now())
+		authInfo, err := verifier.Verify(req)
+		require.NoError(t, err)
+		assert.Equal(t, "hmac", authInfo.Metadata["auth_type"])
+		assert.Equal(t, []string{PermissionAPIAccess}, authInfo.Permissions)
+
+		// The body is still readable by later handlers
+		model, err := RequestModel(req)
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o", model)
+	})
+
+	t.Run("prefixed signature", func(t *testing.T) {
+		req := signedRequest(body, time.Now())
+		req.Header.Set(DefaultHMACHeader, "sha256="+req.Header.Get(DefaultHMACHeader))
+		_, err := verifier.Verify(req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := signedRequest(body, time.Now())
+		req.Body = httptest.NewRequest("POST", "/", strings.NewReader(strings.Replace(body, "gpt-4o", "gpt-4", 1))).Body
+		_, err := verifier.Verify(req)
+		assert.EqualError(t, err, "invalid request signature")
+	})
+
+	t.Run("expired timestamp", func(t *testing.T) {
+		req := signedRequest(body, time.Now().Add(-2*time.Minute))
+		_, err := verifier.Verify(req)
+		assert.ErrorContains(t, err, "outside allowed skew")
+	})
+
+	t.Run("future timestamp", func(t *testing.T) {
+		req := signedRequest(body, time.Now().Add(2*time.Minute))
+		_, err := verifier.Verify(req)
+		assert.ErrorContains(t, err, "outside allowed skew")
+	})
+
+	t.Run("missing timestamp", func(t *testing.T) {
+		req := signedRequest(body, time.Now())
+		req.Header.Del(DefaultHMACTimestampHeader)
+		_, err := verifier.Verify(req)
+		assert.Error(t, err)
+	})
+}
+
+func TestHMACVerifier_Middleware(t *testing.T) {
+	verifier, err := NewHMACVerifier(&HMACAuthConfig{Secret: testHMACSecret}, logrus.New())
+	require.NoError(t, err)
+
+	handler := verifier.HMACMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authInfo, ok := GetAuthInfo(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, "hmac", authInfo.UserID)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest(`{"model":"gpt-4o"}`, time.Now()))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "authentication_error")
+}
@@ -0,0 +1,156 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultModerationThreshold is the category score at which content is
+// blocked when no threshold is configured
+const DefaultModerationThreshold = 0.5
+
+// ErrModerationUnavailable is returned when a fail-closed moderator could not
+// screen content
+var ErrModerationUnavailable = errors.New("content moderation unavailable")
+
+// ModerationConfig screens prompts with a moderation API before they are
+// routed
+type ModerationConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Provider string `yaml:"provider"` // only "openai" is supported
+	Model    string `yaml:"model"`    // provider default when empty
+
+	// Category score from 0 to 1 at or above which content is blocked,
+	// defaults to DefaultModerationThreshold
+	Threshold float64 `yaml:"threshold"`
+
+	// Categories that block, e.g. ["violence", "self-harm"]; empty blocks on
+	// every category
+	BlockCategories []string `yaml:"block_categories"`
+
+	// Reject requests when the moderation call fails instead of forwarding
+	// them unscreened
+	FailClosed bool `yaml:"fail_closed"`
+
+	Timeout time.Duration `yaml:"timeout"` // per moderation call, defaults to 5s
+}
+
+// Validate checks the provider and threshold
+func (c *ModerationConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Provider != "openai" {
+		return fmt.Errorf("unsupported moderation provider %q: must be openai", c.Provider)
+	}
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return fmt.Errorf("moderation threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// ModerationClient scores text against a moderation API's categories
+type ModerationClient interface {
+	Moderate(ctx context.Context, text string) (map[string]float64, error)
+}
+
+// ModerationViolation is returned when content scores at or above the
+// threshold in a blocked category
+type ModerationViolation struct {
+	Category  string
+	Score     float64
+	Threshold float64
+}
+
+func (v *ModerationViolation) Error() string {
+	return fmt.Sprintf("content flagged for %s (score %.2f, threshold %.2f)", v.Category, v.Score, v.Threshold)
+}
+
+// Moderator applies a ModerationConfig using a ModerationClient
+type Moderator struct {
+	config *ModerationConfig
+	client ModerationClient
+	logger *logrus.Logger
+}
+
+// NewModerator creates a moderator screening content with client
+func NewModerator(config *ModerationConfig, client ModerationClient, logger *logrus.Logger) *Moderator {
+	return &Moderator{config: config, client: client, logger: logger}
+}
+
+// Check screens text. It returns a ModerationViolation for the highest
+// scoring blocked category at or above the threshold. When the moderation
+// call fails, it returns an error wrapping ErrModerationUnavailable if the
+// moderator fails closed, and nil if it fails open.
+func (m *Moderator) Check(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+
+	timeout := m.config.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	scores, err := m.client.Moderate(ctx, text)
+	if err != nil {
+		if m.config.FailClosed {
+			return fmt.Errorf("%w: %v", ErrModerationUnavailable, err)
+		}
+		m.logger.WithError(err).Warn("Content moderation failed, forwarding request unscreened")
+		return nil
+	}
+
+	threshold := m.config.Threshold
+	if threshold <= 0 {
+		threshold = DefaultModerationThreshold
+	}
+
+	categories := make([]string, 0, len(scores))
+	for category := range scores {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var violation *ModerationViolation
+	for _, category := range categories {
+		score := scores[category]
+		if score < threshold || !m.blocks(category) {
+			continue
+		}
+		if violation == nil || score > violation.Score {
+			violation = &ModerationViolation{Category: category, Score: score, Threshold: threshold}
+		}
+	}
+	if violation != nil {
+		return violation
+	}
+	return nil
+}
+
+// blocks reports whether a category is blocked. Subcategories such as
+// "self-harm/intent" are blocked by their parent.
+func (m *Moderator) blocks(category string) bool {
+	if len(m.config.BlockCategories) == 0 {
+		return true
+	}
+	for parent := category; ; {
+		if slices.Contains(m.config.BlockCategories, parent) {
+			return true
+		}
+		i := strings.LastIndex(parent, "/")
+		if i < 0 {
+			return false
+		}
+		parent = parent[:i]
+	}
+}
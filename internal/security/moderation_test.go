@@ -0,0 +1,55 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockModerationClient returns fixed scores or err
+type mockModerationClient struct {
+	scores map[string]float64
+	err    error
+	inputs []string
+}
+
+func (m *mockModerationClient) Moderate(ctx context.Context, text string) (map[string]float64, error) {
+	m.inputs = append(m.inputs, text)
+	return m.scores, m.err
+}
+
+func TestModerator_Check(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	config := &ModerationConfig{Enabled: true, Provider: "openai", Threshold: 0.7, BlockCategories: []string{"violence", "self-harm"}}
+
+	// Flagged above threshold in a blocked category, matched via its parent
+	client := &mockModerationClient{scores: map[string]float64{"violence": 0.2, "self-harm/intent": 0.91, "sexual": 0.99}}
+	err := NewModerator(config, client, logger).Check(context.Background(), "bad content")
+	var violation *ModerationViolation
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, "self-harm/intent", violation.Category)
+	assert.Equal(t, 0.91, violation.Score)
+	assert.Equal(t, []string{"bad content"}, client.inputs)
+
+	// Clean content, and categories that aren't blocked, pass
+	client = &mockModerationClient{scores: map[string]float64{"violence": 0.69, "sexual": 0.99}}
+	assert.NoError(t, NewModerator(config, client, logger).Check(context.Background(), "hello"))
+}
+
+func TestModerator_FailureModes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	client := &mockModerationClient{err: errors.New("connection refused")}
+
+	open := &ModerationConfig{Enabled: true, Provider: "openai"}
+	assert.NoError(t, NewModerator(open, client, logger).Check(context.Background(), "hello"))
+
+	closed := &ModerationConfig{Enabled: true, Provider: "openai", FailClosed: true}
+	err := NewModerator(closed, client, logger).Check(context.Background(), "hello")
+	assert.ErrorIs(t, err, ErrModerationUnavailable)
+}
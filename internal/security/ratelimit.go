@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,6 +43,112 @@ type RateLimitConfig struct {
 	WindowDuration    time.Duration `yaml:"window_duration"`
 	CleanupInterval   time.Duration `yaml:"cleanup_interval"`
 	RedisURL          string        `yaml:"redis_url"`
+	
+	// Requests per minute for individual models and providers, keyed by name.
+	// Applied to "model:<name>" and "provider:<name>" keys; resources without
+	// an entry are not limited.
+	PerModelLimits    map[string]int `yaml:"per_model_limits"`
+	PerProviderLimits map[string]int `yaml:"per_provider_limits"`
+	
+	// Models grouped into tiers, e.g. cheap: [gpt-4o-mini], and each tier's
+	// requests per minute per client. Requests for a tiered model use the
+	// "tier:<name>:<client>" bucket instead of the client's default one;
+	// models outside every tier get the default limit. A trailing "*"
+	// matches model names by prefix.
+	ModelTiers map[string][]string `yaml:"model_tiers"`
+	TierLimits map[string]int      `yaml:"tier_limits"`
+}
+
+// Key prefixes for per-resource rate limits
+const (
+	ModelKeyPrefix    = "model:"
+	ProviderKeyPrefix = "provider:"
+	TierKeyPrefix     = "tier:"
+)
+
+// ModelRateLimitKey returns the rate limit key for a model
+func ModelRateLimitKey(model string) string {
+	return ModelKeyPrefix + model
+}
+
+// ProviderRateLimitKey returns the rate limit key for a provider
+func ProviderRateLimitKey(provider string) string {
+	return ProviderKeyPrefix + provider
+}
+
+// TierRateLimitKey returns the rate limit key for a client's requests to a
+// model tier, e.g. "tier:expensive:user:alice"
+func TierRateLimitKey(tier, clientKey string) string {
+	return TierKeyPrefix + tier + ":" + clientKey
+}
+
+// TierFor returns the tier a model belongs to, or "" if it isn't in a tier
+// with a limit. Exact model names take precedence over prefix patterns, and
+// longer prefixes over shorter ones.
+func (c *RateLimitConfig) TierFor(model string) string {
+	if model == "" {
+		return ""
+	}
+
+	tier, matched := "", -1
+	for name, limit := range c.TierLimits {
+		if limit <= 0 {
+			continue
+		}
+		for _, pattern := range c.ModelTiers[name] {
+			length := -1
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				if strings.HasPrefix(model, prefix) {
+					length = len(prefix)
+				}
+			} else if pattern == model {
+				length = len(model) + 1 // exact matches win
+			}
+			if length > matched || (length == matched && length >= 0 && name < tier) {
+				tier, matched = name, length
+			}
+		}
+	}
+	return tier
+}
+
+// HasTierLimits reports whether any model tier has a limit configured
+func (c *RateLimitConfig) HasTierLimits() bool {
+	for name, limit := range c.TierLimits {
+		if limit > 0 && len(c.ModelTiers[name]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HasResourceLimits reports whether any per-model or per-provider limits are configured
+func (c *RateLimitConfig) HasResourceLimits() bool {
+	return len(c.PerModelLimits) > 0 || len(c.PerProviderLimits) > 0
+}
+
+// limitFor returns the requests per minute and burst size for a key.
+// limited is false for resource keys without a configured limit.
+func (c *RateLimitConfig) limitFor(key string) (rate, burst int, limited bool) {
+	var limits map[string]int
+	var name string
+	switch {
+	case strings.HasPrefix(key, ModelKeyPrefix):
+		limits, name = c.PerModelLimits, strings.TrimPrefix(key, ModelKeyPrefix)
+	case strings.HasPrefix(key, ProviderKeyPrefix):
+		limits, name = c.PerProviderLimits, strings.TrimPrefix(key, ProviderKeyPrefix)
+	case strings.HasPrefix(key, TierKeyPrefix):
+		limits = c.TierLimits
+		name, _, _ = strings.Cut(strings.TrimPrefix(key, TierKeyPrefix), ":")
+	default:
+		return c.RequestsPerMinute, c.BurstSize, true
+	}
+	
+	limit, exists := limits[name]
+	if !exists || limit <= 0 {
+		return 0, 0, false
+	}
+	return limit, limit, true
 }
 
 // InMemoryRateLimiter implements rate limiting using in-memory storage
@@ -101,8 +208,18 @@ func (rl *InMemoryRateLimiter) Allow(ctx context.Context, key string) (*RateLimi
 		}, nil
 	}
 	
+	rate, burst, limited := rl.config.limitFor(key)
+	if !limited {
+		// No limit configured for this model or provider
+		return &RateLimitResult{
+			Allowed:   true,
+			Remaining: rl.config.RequestsPerMinute,
+			ResetTime: time.Now().Add(rl.config.WindowDuration),
+		}, nil
+	}
+	
 	now := time.Now()
-	bucket := rl.getOrCreateBucket(key)
+	bucket := rl.getOrCreateBucket(key, burst)
 	
 	bucket.mutex.Lock()
 	defer bucket.mutex.Unlock()
@@ -110,8 +227,8 @@ func (rl *InMemoryRateLimiter) Allow(ctx context.Context, key string) (*RateLimi
 	// Refill tokens based on elapsed time
 	elapsed := now.Sub(bucket.lastRefill)
 	if elapsed > 0 {
-		tokensToAdd := int(elapsed.Minutes() * float64(rl.config.RequestsPerMinute))
-		bucket.tokens = minInt(bucket.tokens+tokensToAdd, rl.config.BurstSize)
+		tokensToAdd := int(elapsed.Minutes() * float64(rate))
+		bucket.tokens = minInt(bucket.tokens+tokensToAdd, burst)
 		bucket.lastRefill = now
 	}
 	
@@ -126,7 +243,7 @@ func (rl *InMemoryRateLimiter) Allow(ctx context.Context, key string) (*RateLimi
 	}
 	
 	// Request denied
-	retryAfter := time.Duration(float64(time.Minute) / float64(rl.config.RequestsPerMinute))
+	retryAfter := time.Duration(float64(time.Minute) / float64(rate))
 	
 	rl.logger.WithFields(logrus.Fields{
 		"key":         maskKey(key),
@@ -154,7 +271,8 @@ func (rl *InMemoryRateLimiter) Reset(ctx context.Context, key string) error {
 
 // GetLimits returns current rate limit information for a key
 func (rl *InMemoryRateLimiter) GetLimits(ctx context.Context, key string) (*RateLimitInfo, error) {
-	bucket := rl.getOrCreateBucket(key)
+	rate, burst, _ := rl.config.limitFor(key)
+	bucket := rl.getOrCreateBucket(key, burst)
 	
 	bucket.mutex.Lock()
 	defer bucket.mutex.Unlock()
@@ -163,26 +281,26 @@ func (rl *InMemoryRateLimiter) GetLimits(ctx context.Context, key string) (*Rate
 	
 	// Calculate current state
 	elapsed := now.Sub(bucket.lastRefill)
-	tokensToAdd := int(elapsed.Minutes() * float64(rl.config.RequestsPerMinute))
-	currentTokens := minInt(bucket.tokens+tokensToAdd, rl.config.BurstSize)
+	tokensToAdd := int(elapsed.Minutes() * float64(rate))
+	currentTokens := minInt(bucket.tokens+tokensToAdd, burst)
 	
 	return &RateLimitInfo{
-		Limit:     rl.config.RequestsPerMinute,
-		Used:      rl.config.BurstSize - currentTokens,
+		Limit:     rate,
+		Used:      burst - currentTokens,
 		Remaining: currentTokens,
 		ResetTime: now.Add(rl.config.WindowDuration),
 	}, nil
 }
 
 // getOrCreateBucket gets or creates a token bucket for a key
-func (rl *InMemoryRateLimiter) getOrCreateBucket(key string) *tokenBucket {
+func (rl *InMemoryRateLimiter) getOrCreateBucket(key string, burst int) *tokenBucket {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 	
 	bucket, exists := rl.buckets[key]
 	if !exists {
 		bucket = &tokenBucket{
-			tokens:    rl.config.BurstSize,
+			tokens:    burst,
 			lastRefill: time.Now(),
 		}
 		rl.buckets[key] = bucket
@@ -280,7 +398,8 @@ func RateLimitMiddleware(rateLimiter RateLimiter, keyExtractor func(*http.Reques
 					"error": {
 						"message": "Rate limit exceeded",
 						"type": "rate_limit_error",
-						"code": 429,
+						"code": "rate_limited",
+						"status": 429,
 						"retry_after": %d
 					},
 					"timestamp": %d
@@ -303,16 +422,37 @@ func DefaultKeyExtractor(r *http.Request) string {
 	}
 	
 	// Fall back to IP address
-	return "ip:" + getClientIPFromRequest(r)
+	return "ip:" + ClientIP(r)
+}
+
+// TierKeyExtractor wraps a key extractor so requests for a model in a
+// limited tier are keyed by tier and client. Other requests keep the
+// wrapped extractor's key and its default limit.
+func TierKeyExtractor(config *RateLimitConfig, base func(*http.Request) string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		key := base(r)
+		if key == "" {
+			return ""
+		}
+
+		model, err := RequestModel(r)
+		if err != nil {
+			return key
+		}
+		if tier := config.TierFor(model); tier != "" {
+			return TierRateLimitKey(tier, key)
+		}
+		return key
+	}
 }
 
 // APIKeyExtractor extracts rate limiting key from API key
 func APIKeyExtractor(r *http.Request) string {
-	token := extractToken(r)
+	token := ExtractToken(r)
 	if token != "" {
 		return "key:" + maskKey(token)
 	}
-	return "ip:" + getClientIPFromRequest(r)
+	return "ip:" + ClientIP(r)
 }
 
 // Helper functions
@@ -0,0 +1,209 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// redisKeyPrefix namespaces rate limit buckets in Redis
+const redisKeyPrefix = "llm_router:ratelimit:"
+
+// redisAllowScript refills and takes a token from a bucket atomically.
+// Tokens are stored as a string so fractional refills survive between calls.
+//
+// KEYS[1] bucket key
+// ARGV[1] requests per minute, ARGV[2] burst size, ARGV[3] now (ms), ARGV[4] ttl (ms)
+// Returns {allowed (0/1), remaining tokens}
+var redisAllowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+
+local elapsed = math.max(0, now - last)
+tokens = math.min(burst, tokens + elapsed * rate / 60000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+
+return {allowed, math.floor(tokens)}
+`)
+
+// RedisRateLimiter implements token-bucket rate limiting backed by Redis so
+// that limits are shared across router instances. Requests are allowed when
+// Redis is unreachable rather than failing closed.
+type RedisRateLimiter struct {
+	config *RateLimitConfig
+	logger *logrus.Logger
+	client *redis.Client
+}
+
+// NewRedisRateLimiter creates a rate limiter using the Redis server at config.RedisURL
+func NewRedisRateLimiter(config *RateLimitConfig, logger *logrus.Logger) (*RedisRateLimiter, error) {
+	options, err := redis.ParseURL(config.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	if config.WindowDuration == 0 {
+		config.WindowDuration = time.Minute
+	}
+	if config.BurstSize == 0 {
+		config.BurstSize = config.RequestsPerMinute
+	}
+
+	return &RedisRateLimiter{
+		config: config,
+		logger: logger,
+		client: redis.NewClient(options),
+	}, nil
+}
+
+// Allow checks if a request is allowed under the rate limit
+func (rl *RedisRateLimiter) Allow(ctx context.Context, key string) (*RateLimitResult, error) {
+	if !rl.config.Enabled {
+		return rl.allowAll(), nil
+	}
+
+	rate, burst, limited := rl.config.limitFor(key)
+	if !limited {
+		// No limit configured for this model or provider
+		return rl.allowAll(), nil
+	}
+
+	now := time.Now()
+	values, err := redisAllowScript.Run(ctx, rl.client, []string{redisKeyPrefix + key},
+		rate, burst, now.UnixMilli(), rl.bucketTTL(rate, burst).Milliseconds()).Int64Slice()
+	if err != nil {
+		rl.logger.WithError(err).WithField("key", maskKey(key)).Warn("Redis rate limiter unavailable, allowing request")
+		return rl.allowAll(), nil
+	}
+
+	if values[0] == 1 {
+		return &RateLimitResult{
+			Allowed:   true,
+			Remaining: int(values[1]),
+			ResetTime: now.Add(rl.config.WindowDuration),
+		}, nil
+	}
+
+	// Request denied
+	retryAfter := time.Duration(float64(time.Minute) / float64(rate))
+
+	rl.logger.WithFields(logrus.Fields{
+		"key":         maskKey(key),
+		"retry_after": retryAfter,
+	}).Warn("Rate limit exceeded")
+
+	return &RateLimitResult{
+		Allowed:    false,
+		Remaining:  0,
+		ResetTime:  now.Add(retryAfter),
+		RetryAfter: retryAfter,
+	}, nil
+}
+
+// Reset resets the rate limit for a key
+func (rl *RedisRateLimiter) Reset(ctx context.Context, key string) error {
+	if err := rl.client.Del(ctx, redisKeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+
+	rl.logger.WithField("key", maskKey(key)).Info("Rate limit reset")
+	return nil
+}
+
+// GetLimits returns current rate limit information for a key
+func (rl *RedisRateLimiter) GetLimits(ctx context.Context, key string) (*RateLimitInfo, error) {
+	rate, burst, _ := rl.config.limitFor(key)
+	now := time.Now()
+
+	state, err := rl.client.HMGet(ctx, redisKeyPrefix+key, "tokens", "last").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+
+	// A missing bucket is full
+	currentTokens := burst
+	tokens, tokensErr := parseRedisFloat(state[0])
+	last, lastErr := parseRedisFloat(state[1])
+	if tokensErr == nil && lastErr == nil {
+		elapsed := math.Max(0, float64(now.UnixMilli())-last)
+		currentTokens = int(math.Min(float64(burst), tokens+elapsed*float64(rate)/60000))
+	}
+
+	return &RateLimitInfo{
+		Limit:     rate,
+		Used:      burst - currentTokens,
+		Remaining: currentTokens,
+		ResetTime: now.Add(rl.config.WindowDuration),
+	}, nil
+}
+
+// Stop closes the Redis connection
+func (rl *RedisRateLimiter) Stop() {
+	if err := rl.client.Close(); err != nil {
+		rl.logger.WithError(err).Warn("Failed to close redis rate limiter")
+	}
+}
+
+// allowAll returns an allowed result for disabled, unlimited or degraded checks
+func (rl *RedisRateLimiter) allowAll() *RateLimitResult {
+	return &RateLimitResult{
+		Allowed:   true,
+		Remaining: rl.config.RequestsPerMinute,
+		ResetTime: time.Now().Add(rl.config.WindowDuration),
+	}
+}
+
+// bucketTTL keeps idle buckets until they would have refilled completely,
+// matching the in-memory limiter's cleanup window at minimum
+func (rl *RedisRateLimiter) bucketTTL(rate, burst int) time.Duration {
+	ttl := 2 * rl.config.WindowDuration
+	if rate > 0 {
+		if refill := time.Duration(float64(burst) / float64(rate) * float64(time.Minute)); refill > ttl {
+			ttl = refill
+		}
+	}
+	return ttl
+}
+
+func parseRedisFloat(value interface{}) (float64, error) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("missing value")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// NewRateLimiter creates the rate limiter selected by config: Redis-backed when
+// RedisURL is set, in-memory otherwise
+func NewRateLimiter(config *RateLimitConfig, logger *logrus.Logger) (RateLimiter, error) {
+	if config.RedisURL != "" {
+		limiter, err := NewRedisRateLimiter(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		return limiter, nil
+	}
+	return NewInMemoryRateLimiter(config, logger), nil
+}
@@ -0,0 +1,166 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisRateLimiter(t *testing.T, config *RateLimitConfig) (*RedisRateLimiter, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	config.RedisURL = "redis://" + server.Addr()
+
+	limiter, err := NewRedisRateLimiter(config, logrus.New())
+	require.NoError(t, err)
+	t.Cleanup(limiter.Stop)
+
+	return limiter, server
+}
+
+func TestRedisRateLimiter_Allow_ExceedLimit(t *testing.T) {
+	limiter, _ := newTestRedisRateLimiter(t, &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         3,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "test-key")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d should be allowed", i)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "test-key")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// Other keys have their own bucket
+	result, err = limiter.Allow(ctx, "other-key")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRedisRateLimiter_SharedAcrossInstances(t *testing.T) {
+	first, server := newTestRedisRateLimiter(t, &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         1,
+	})
+	second, err := NewRedisRateLimiter(&RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         1,
+		RedisURL:          "redis://" + server.Addr(),
+	}, logrus.New())
+	require.NoError(t, err)
+	defer second.Stop()
+	ctx := context.Background()
+
+	result, err := first.Allow(ctx, "test-key")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = second.Allow(ctx, "test-key")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRedisRateLimiter_ResetAndGetLimits(t *testing.T) {
+	limiter, server := newTestRedisRateLimiter(t, &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         10,
+	})
+	ctx := context.Background()
+
+	info, err := limiter.GetLimits(ctx, "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, 60, info.Limit)
+	assert.Equal(t, 0, info.Used)
+	assert.Equal(t, 10, info.Remaining)
+
+	_, err = limiter.Allow(ctx, "test-key")
+	require.NoError(t, err)
+	_, err = limiter.Allow(ctx, "test-key")
+	require.NoError(t, err)
+
+	info, err = limiter.GetLimits(ctx, "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Used)
+	assert.Equal(t, 8, info.Remaining)
+	assert.True(t, server.Exists(redisKeyPrefix+"test-key"))
+	assert.Greater(t, server.TTL(redisKeyPrefix+"test-key"), time.Duration(0))
+
+	require.NoError(t, limiter.Reset(ctx, "test-key"))
+	assert.False(t, server.Exists(redisKeyPrefix+"test-key"))
+
+	info, err = limiter.GetLimits(ctx, "test-key")
+	require.NoError(t, err)
+	assert.Equal(t, 10, info.Remaining)
+}
+
+func TestRedisRateLimiter_PerModelLimit(t *testing.T) {
+	limiter, _ := newTestRedisRateLimiter(t, &RateLimitConfig{
+		Enabled:        true,
+		PerModelLimits: map[string]int{"gpt-4o": 1},
+	})
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, ModelRateLimitKey("gpt-4o"))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(ctx, ModelRateLimitKey("gpt-4o"))
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Models without a configured limit are not limited
+	for i := 0; i < 5; i++ {
+		result, err = limiter.Allow(ctx, ModelRateLimitKey("gpt-4o-mini"))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+}
+
+func TestRedisRateLimiter_FailsOpen(t *testing.T) {
+	limiter, server := newTestRedisRateLimiter(t, &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         1,
+	})
+	ctx := context.Background()
+
+	server.Close()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "test-key")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+}
+
+func TestNewRateLimiter_SelectsBackend(t *testing.T) {
+	logger := logrus.New()
+
+	limiter, err := NewRateLimiter(&RateLimitConfig{Enabled: true, RequestsPerMinute: 60}, logger)
+	require.NoError(t, err)
+	assert.IsType(t, &InMemoryRateLimiter{}, limiter)
+	limiter.(*InMemoryRateLimiter).Stop()
+
+	server := miniredis.RunT(t)
+	limiter, err = NewRateLimiter(&RateLimitConfig{Enabled: true, RequestsPerMinute: 60, RedisURL: "redis://" + server.Addr()}, logger)
+	require.NoError(t, err)
+	assert.IsType(t, &RedisRateLimiter{}, limiter)
+	limiter.(*RedisRateLimiter).Stop()
+
+	_, err = NewRateLimiter(&RateLimitConfig{RedisURL: "not a url"}, logger)
+	assert.Error(t, err)
+}
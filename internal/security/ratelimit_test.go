@@ -2,6 +2,9 @@ package security
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -127,6 +130,139 @@ func TestInMemoryRateLimiter_Allow_DifferentKeys(t *testing.T) {
 	assert.False(t, result.Allowed)
 }
 
+func TestInMemoryRateLimiter_Allow_PerModelLimit(t *testing.T) {
+	config := &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         10,
+		WindowDuration:    time.Minute,
+		PerModelLimits:    map[string]int{"gpt-4o": 2},
+		PerProviderLimits: map[string]int{"anthropic": 1},
+	}
+	logger := logrus.New()
+	limiter := NewInMemoryRateLimiter(config, logger)
+	defer limiter.Stop()
+	ctx := context.Background()
+
+	// Exhaust the model budget
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, ModelRateLimitKey("gpt-4o"))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := limiter.Allow(ctx, ModelRateLimitKey("gpt-4o"))
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	// The global per-client budget is unaffected
+	result, err = limiter.Allow(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 9, result.Remaining)
+
+	// Models and providers without a configured limit are not limited
+	for i := 0; i < 20; i++ {
+		result, err = limiter.Allow(ctx, ModelRateLimitKey("gpt-4o-mini"))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	// Provider limits are tracked separately
+	result, err = limiter.Allow(ctx, ProviderRateLimitKey("anthropic"))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(ctx, ProviderRateLimitKey("anthropic"))
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	info, err := limiter.GetLimits(ctx, ModelRateLimitKey("gpt-4o"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Limit)
+}
+
+func TestRateLimitConfig_TierFor(t *testing.T) {
+	config := &RateLimitConfig{
+		ModelTiers: map[string][]string{
+			"cheap":     {"gpt-4o-mini", "claude-3-5-haiku*"},
+			"expensive": {"gpt-4o", "claude-3*"},
+			"unlimited": {"llama3"},
+		},
+		TierLimits: map[string]int{"cheap": 100, "expensive": 5},
+	}
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{model: "gpt-4o-mini", want: "cheap"},
+		{model: "gpt-4o", want: "expensive"},
+		{model: "claude-3-5-haiku-20241022", want: "cheap"}, // longer prefix wins
+		{model: "claude-3-opus-20240229", want: "expensive"},
+		{model: "gpt-4o-2024-08-06", want: ""}, // exact names don't match by prefix
+		{model: "llama3", want: ""},            // tier without a limit
+		{model: "mistral-large", want: ""},
+		{model: "", want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, config.TierFor(tt.model), "model %q", tt.model)
+	}
+	assert.True(t, config.HasTierLimits())
+	assert.False(t, (&RateLimitConfig{TierLimits: map[string]int{"cheap": 10}}).HasTierLimits())
+}
+
+func TestRateLimitMiddleware_TierLimits(t *testing.T) {
+	config := &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 3,
+		WindowDuration:    time.Minute,
+		ModelTiers: map[string][]string{
+			"cheap":     {"gpt-4o-mini"},
+			"expensive": {"gpt-4o"},
+		},
+		TierLimits: map[string]int{"cheap": 2, "expensive": 1},
+	}
+	limiter := NewInMemoryRateLimiter(config, logrus.New())
+	defer limiter.Stop()
+
+	var models []string
+	handler := RateLimitMiddleware(limiter, TierKeyExtractor(config, DefaultKeyExtractor))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body is still readable after the extractor peeked at the model
+		model, err := RequestModel(r)
+		require.NoError(t, err)
+		models = append(models, model)
+	}))
+
+	send := func(model string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"`+model+`"}`))
+		req = req.WithContext(context.WithValue(req.Context(), "auth_info", &AuthInfo{UserID: "alice"}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Exhausting the expensive tier leaves the cheap tier untouched
+	assert.Equal(t, http.StatusOK, send("gpt-4o"))
+	assert.Equal(t, http.StatusTooManyRequests, send("gpt-4o"))
+	assert.Equal(t, http.StatusOK, send("gpt-4o-mini"))
+	assert.Equal(t, http.StatusOK, send("gpt-4o-mini"))
+	assert.Equal(t, http.StatusTooManyRequests, send("gpt-4o-mini"))
+	assert.Equal(t, http.StatusTooManyRequests, send("gpt-4o"))
+
+	// Unlisted models still get the default per-user budget
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("mistral-large"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("mistral-large"))
+
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini", "gpt-4o-mini", "mistral-large", "mistral-large", "mistral-large"}, models)
+
+	info, err := limiter.GetLimits(context.Background(), TierRateLimitKey("expensive", "user:alice"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Limit)
+}
+
 func TestInMemoryRateLimiter_Reset(t *testing.T) {
 	config := &RateLimitConfig{
 		Enabled:           true,
@@ -0,0 +1,170 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Permission scopes. A key with no configured scopes gets PermissionAPIAccess,
+// which allows everything. Scoped keys are limited per dimension: a key with
+// any "models:" scope may only use matching models, and a key with any
+// "endpoints:" scope may only call matching paths. Scope values ending in "*"
+// match by prefix, so "models:gpt-4*" covers every GPT-4 model.
+//
+// Operations that change provider state for every caller, such as
+// deregistering a provider, also need PermissionAdminWrite (or
+// PermissionAll); api:access alone doesn't grant them.
+const (
+	PermissionAPIAccess  = "api:access"
+	PermissionAdminWrite = "admin:write"
+	PermissionAll        = "*"
+
+	ScopeModelPrefix    = "models:"
+	ScopeEndpointPrefix = "endpoints:"
+)
+
+// maxScopedBodySize bounds how much of a request body is read to find its model
+const maxScopedBodySize = 10 << 20
+
+// AuthorizationError is returned when a request falls outside its key's scopes
+type AuthorizationError struct {
+	Scope string // e.g. "models:gpt-4o"
+}
+
+func (e *AuthorizationError) Error() string {
+	return fmt.Sprintf("API key is not permitted to access %s", e.Scope)
+}
+
+// permissionsFor returns the permissions granted to an API key
+func (a *DefaultAuthProvider) permissionsFor(apiKey string) []string {
+	if scopes, ok := a.config.KeyScopes[apiKey]; ok && len(scopes) > 0 {
+		return append([]string(nil), scopes...)
+	}
+	return []string{PermissionAPIAccess}
+}
+
+// Authorize checks a request's endpoint and model against the caller's
+// permissions. An empty model skips the model check.
+func (info *AuthInfo) Authorize(endpoint, model string) error {
+	if !scopeAllows(info.Permissions, ScopeEndpointPrefix, endpoint) {
+		return &AuthorizationError{Scope: ScopeEndpointPrefix + endpoint}
+	}
+	if model == "" {
+		return nil
+	}
+	return info.AuthorizeModel(model)
+}
+
+// AuthorizeModel checks a model against the caller's model scopes, e.g. a
+// model routing substituted for the requested one
+func (info *AuthInfo) AuthorizeModel(model string) error {
+	if !scopeAllows(info.Permissions, ScopeModelPrefix, model) {
+		return &AuthorizationError{Scope: ScopeModelPrefix + model}
+	}
+	return nil
+}
+
+// AuthorizeAdmin checks a request against PermissionAdminWrite when it is an
+// admin operation, see requiresAdmin
+func (info *AuthInfo) AuthorizeAdmin(method, path string) error {
+	if !requiresAdmin(method, path) {
+		return nil
+	}
+	if slices.Contains(info.Permissions, PermissionAdminWrite) || slices.Contains(info.Permissions, PermissionAll) {
+		return nil
+	}
+	return &AuthorizationError{Scope: PermissionAdminWrite}
+}
+
+// requiresAdmin reports whether a request changes provider state for every
+// caller: deregistering a provider, forcing its health recheck or setting
+// its maintenance mode
+func requiresAdmin(method, path string) bool {
+	rest, ok := strings.CutPrefix(path, "/v1/providers/")
+	if !ok {
+		return false
+	}
+	name, action, _ := strings.Cut(rest, "/")
+	if name == "" {
+		return false
+	}
+	switch method {
+	case http.MethodDelete:
+		return action == ""
+	case http.MethodPost:
+		return action == "health/recheck" || action == "maintenance"
+	}
+	return false
+}
+
+// RequiresModelCheck reports whether the caller is limited to specific models
+func (info *AuthInfo) RequiresModelCheck() bool {
+	return !unrestricted(info.Permissions) && hasScope(info.Permissions, ScopeModelPrefix)
+}
+
+// scopeAllows reports whether permissions allow value for a scope dimension
+func scopeAllows(permissions []string, prefix, value string) bool {
+	if unrestricted(permissions) || !hasScope(permissions, prefix) {
+		return true
+	}
+	for _, permission := range permissions {
+		pattern, ok := strings.CutPrefix(permission, prefix)
+		if !ok {
+			continue
+		}
+		if pattern == PermissionAll || pattern == value {
+			return true
+		}
+		if stem, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(value, stem) {
+			return true
+		}
+	}
+	return false
+}
+
+func unrestricted(permissions []string) bool {
+	for _, permission := range permissions {
+		if permission == PermissionAPIAccess || permission == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+func hasScope(permissions []string, prefix string) bool {
+	for _, permission := range permissions {
+		if strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestModel returns the "model" field of a JSON request body, restoring
+// the body for later handlers. It returns "" if the body has no model.
+func RequestModel(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxScopedBodySize))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Leave malformed bodies for the handler to reject
+		return "", nil
+	}
+	return payload.Model, nil
+}
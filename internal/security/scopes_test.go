@@ -0,0 +1,123 @@
+package security
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthInfo_Authorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []string
+		endpoint    string
+		model       string
+		wantErr     bool
+	}{
+		{
+			name:        "allowed model",
+			permissions: []string{"models:gpt-4o", "endpoints:/v1/chat/completions"},
+			endpoint:    "/v1/chat/completions",
+			model:       "gpt-4o",
+		},
+		{
+			name:        "denied model",
+			permissions: []string{"models:gpt-4o", "endpoints:/v1/chat/completions"},
+			endpoint:    "/v1/chat/completions",
+			model:       "claude-3-opus",
+			wantErr:     true,
+		},
+		{
+			name:        "denied endpoint",
+			permissions: []string{"models:gpt-4o", "endpoints:/v1/chat/completions"},
+			endpoint:    "/v1/embeddings",
+			model:       "gpt-4o",
+			wantErr:     true,
+		},
+		{
+			name:        "wildcard model scope",
+			permissions: []string{"models:gpt-4*"},
+			endpoint:    "/v1/chat/completions",
+			model:       "gpt-4o-mini",
+		},
+		{
+			name:        "wildcard model scope rejects other families",
+			permissions: []string{"models:gpt-4*"},
+			endpoint:    "/v1/chat/completions",
+			model:       "gpt-3.5-turbo",
+			wantErr:     true,
+		},
+		{
+			name:        "wildcard grants everything",
+			permissions: []string{"*"},
+			endpoint:    "/v1/embeddings",
+			model:       "claude-3-opus",
+		},
+		{
+			name:        "unscoped key",
+			permissions: []string{"api:access"},
+			endpoint:    "/v1/chat/completions",
+			model:       "claude-3-opus",
+		},
+		{
+			name:        "model scope leaves endpoints open",
+			permissions: []string{"models:gpt-4o"},
+			endpoint:    "/v1/models",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &AuthInfo{Permissions: tt.permissions}
+			err := info.Authorize(tt.endpoint, tt.model)
+			if tt.wantErr {
+				var authzErr *AuthorizationError
+				assert.ErrorAs(t, err, &authzErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDefaultAuthProvider_ValidateAPIKey_Scopes(t *testing.T) {
+	provider := NewDefaultAuthProvider(&Config{
+		APIKeys: []string{"scoped-key-12345", "open-key-12345"},
+		KeyScopes: map[string][]string{
+			"scoped-key-12345": {"models:gpt-4o"},
+		},
+	}, logrus.New())
+
+	authInfo, err := provider.ValidateAPIKey(context.Background(), "scoped-key-12345")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"models:gpt-4o"}, authInfo.Permissions)
+	assert.True(t, authInfo.RequiresModelCheck())
+
+	authInfo, err = provider.ValidateAPIKey(context.Background(), "open-key-12345")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api:access"}, authInfo.Permissions)
+	assert.False(t, authInfo.RequiresModelCheck())
+}
+
+func TestRequestModel(t *testing.T) {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","messages":[]}`))
+
+	model, err := RequestModel(req)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", model)
+
+	// The body is still readable by the handler
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"model":"gpt-4o","messages":[]}`, string(body))
+
+	model, err = RequestModel(httptest.NewRequest("GET", "/v1/models", nil))
+	require.NoError(t, err)
+	assert.Empty(t, model)
+}
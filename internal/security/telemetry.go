@@ -0,0 +1,71 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// TelemetryOptOutHeader lets a client opt a single request out of telemetry
+const TelemetryOptOutHeader = "X-Telemetry-Opt-Out"
+
+// telemetryOptOutMetadata marks opted-out keys in AuthInfo metadata and JWT claims
+const telemetryOptOutMetadata = "telemetry_opt_out"
+
+type telemetryContextKey struct{}
+
+// Telemetry carries the telemetry preference for a single request. It is
+// stored in the request context as a pointer so that inner middleware (such as
+// authentication) can opt the request out after outer middleware has started.
+type Telemetry struct {
+	optOut atomic.Bool
+}
+
+// OptOut marks the request as opted out of telemetry
+func (t *Telemetry) OptOut() {
+	if t != nil {
+		t.optOut.Store(true)
+	}
+}
+
+// OptedOut reports whether the request has opted out of telemetry
+func (t *Telemetry) OptedOut() bool {
+	return t != nil && t.optOut.Load()
+}
+
+// TelemetryFromContext returns the request's telemetry preference, or nil
+func TelemetryFromContext(ctx context.Context) *Telemetry {
+	telemetry, _ := ctx.Value(telemetryContextKey{}).(*Telemetry)
+	return telemetry
+}
+
+// TelemetryOptedOut reports whether the request in ctx has opted out of telemetry
+func TelemetryOptedOut(ctx context.Context) bool {
+	return TelemetryFromContext(ctx).OptedOut()
+}
+
+// RequestTelemetry returns the request with a telemetry preference attached,
+// reusing one set by outer middleware, and applies the per-request opt-out header
+func RequestTelemetry(r *http.Request) (*http.Request, *Telemetry) {
+	telemetry := TelemetryFromContext(r.Context())
+	if telemetry == nil {
+		telemetry = &Telemetry{}
+		r = r.WithContext(context.WithValue(r.Context(), telemetryContextKey{}, telemetry))
+	}
+
+	if optOut, err := strconv.ParseBool(r.Header.Get(TelemetryOptOutHeader)); err == nil && optOut {
+		telemetry.OptOut()
+	}
+
+	return r, telemetry
+}
+
+// authInfoOptedOut reports whether an authenticated identity opted out of telemetry
+func authInfoOptedOut(authInfo *AuthInfo) bool {
+	if authInfo == nil {
+		return false
+	}
+	optOut, _ := strconv.ParseBool(authInfo.Metadata[telemetryOptOutMetadata])
+	return optOut
+}
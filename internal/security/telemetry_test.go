@@ -0,0 +1,67 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTelemetry_Header(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req, telemetry := RequestTelemetry(req)
+	assert.False(t, telemetry.OptedOut())
+
+	req.Header.Set(TelemetryOptOutHeader, "true")
+	req, again := RequestTelemetry(req)
+	assert.Same(t, telemetry, again, "preference is shared across middleware")
+	assert.True(t, TelemetryOptedOut(req.Context()))
+
+	var nilTelemetry *Telemetry
+	assert.False(t, nilTelemetry.OptedOut())
+}
+
+func TestAuditMiddleware_TelemetryOptOut(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	auditor := NewAuditLogger(&AuditConfig{Enabled: true, BufferSize: 10, FlushInterval: time.Second}, logger)
+	defer auditor.Stop()
+
+	auth := NewDefaultAuthProvider(&Config{
+		APIKeys:             []string{"regular-key-123", "private-key-456"},
+		TelemetryOptOutKeys: []string{"private-key-456"},
+		RequireAuth:         true,
+	}, logger)
+
+	handler := auditor.AuditMiddleware()(auth.AuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	send := func(apiKey string, header bool) int {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		if header {
+			req.Header.Set(TelemetryOptOutHeader, "1")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Regular traffic is recorded
+	assert.Equal(t, http.StatusOK, send("regular-key-123", false))
+	assert.Equal(t, int64(1), auditor.GetEventCount())
+
+	// Opted-out key and opted-out request are not recorded
+	assert.Equal(t, http.StatusOK, send("private-key-456", false))
+	assert.Equal(t, http.StatusOK, send("regular-key-123", true))
+	assert.Equal(t, int64(1), auditor.GetEventCount())
+
+	// Security-relevant failures are still audited
+	assert.Equal(t, http.StatusUnauthorized, send("wrong-key-789", true))
+	assert.Equal(t, int64(2), auditor.GetEventCount())
+}
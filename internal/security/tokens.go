@@ -0,0 +1,160 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultRefreshTokenExpiry applies when Config.RefreshTokenExpiry is unset
+const defaultRefreshTokenExpiry = 30 * 24 * time.Hour
+
+var (
+	// ErrJWTNotConfigured is returned when token issuance is requested
+	// without a JWT secret
+	ErrJWTNotConfigured = errors.New("JWT issuance is not configured")
+
+	// ErrInvalidRefreshToken is returned for unknown or already used refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+	// ErrRefreshTokenExpired is returned for refresh tokens past their expiry
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+)
+
+// RefreshToken is a single-use credential exchanged for a new access token
+type RefreshToken struct {
+	Token       string
+	UserID      string
+	Permissions []string
+	ExpiresAt   time.Time
+}
+
+// RefreshTokenStore persists refresh tokens between issuance and use
+type RefreshTokenStore interface {
+	Save(ctx context.Context, token *RefreshToken) error
+	// Consume removes and returns a token so it can only be used once
+	Consume(ctx context.Context, token string) (*RefreshToken, error)
+}
+
+// MemoryRefreshTokenStore keeps refresh tokens in process memory. Tokens
+// don't survive restarts and aren't shared across instances.
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+// NewMemoryRefreshTokenStore creates an empty in-memory refresh token store
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{tokens: make(map[string]*RefreshToken)}
+}
+
+// Save stores a refresh token, dropping any that have expired
+func (s *MemoryRefreshTokenStore) Save(ctx context.Context, token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.tokens {
+		if now.After(existing.ExpiresAt) {
+			delete(s.tokens, key)
+		}
+	}
+	s.tokens[token.Token] = token
+	return nil
+}
+
+// Consume removes and returns a refresh token
+func (s *MemoryRefreshTokenStore) Consume(ctx context.Context, token string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.tokens[token]
+	if !exists {
+		return nil, ErrInvalidRefreshToken
+	}
+	delete(s.tokens, token)
+	return stored, nil
+}
+
+// TokenPair is the response to a token issuance or refresh
+type TokenPair struct {
+	AccessToken           string `json:"access_token"`
+	TokenType             string `json:"token_type"`
+	ExpiresIn             int64  `json:"expires_in"` // seconds
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresIn int64  `json:"refresh_token_expires_in"` // seconds
+}
+
+// SetRefreshTokenStore replaces the default in-memory refresh token store
+func (a *DefaultAuthProvider) SetRefreshTokenStore(store RefreshTokenStore) {
+	a.refreshTokens = store
+}
+
+// IssueTokens exchanges a valid API key for a signed access token and a
+// refresh token
+func (a *DefaultAuthProvider) IssueTokens(ctx context.Context, apiKey string) (*TokenPair, error) {
+	if a.config.JWTSecret == "" {
+		return nil, ErrJWTNotConfigured
+	}
+
+	authInfo, err := a.ValidateAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return a.issueTokenPair(ctx, authInfo.UserID, authInfo.Permissions)
+}
+
+// RefreshTokens exchanges a refresh token for a new access token. Refresh
+// tokens are single use: the presented token is revoked and a new one is
+// returned alongside the access token.
+func (a *DefaultAuthProvider) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if a.config.JWTSecret == "" {
+		return nil, ErrJWTNotConfigured
+	}
+
+	stored, err := a.refreshTokens.Consume(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+	return a.issueTokenPair(ctx, stored.UserID, stored.Permissions)
+}
+
+// issueTokenPair signs an access token and stores a new refresh token
+func (a *DefaultAuthProvider) issueTokenPair(ctx context.Context, userID string, permissions []string) (*TokenPair, error) {
+	accessToken, err := a.GenerateJWT(userID, map[string]interface{}{
+		"permissions": permissions,
+		"auth_type":   "jwt",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refresh := &RefreshToken{
+		Token:       base64.RawURLEncoding.EncodeToString(secret),
+		UserID:      userID,
+		Permissions: permissions,
+		ExpiresAt:   time.Now().Add(a.config.RefreshTokenExpiry),
+	}
+	if err := a.refreshTokens.Save(ctx, refresh); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(a.config.JWTExpiry / time.Second),
+		RefreshToken:          refresh.Token,
+		RefreshTokenExpiresIn: int64(a.config.RefreshTokenExpiry / time.Second),
+	}, nil
+}
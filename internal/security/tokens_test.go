@@ -0,0 +1,86 @@
+package security
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenAuthProvider() *DefaultAuthProvider {
+	return NewDefaultAuthProvider(&Config{
+		APIKeys:   []string{"test-api-key-12345"},
+		JWTSecret: "test-jwt-secret",
+		JWTExpiry: time.Hour,
+	}, logrus.New())
+}
+
+func TestDefaultAuthProvider_IssueTokens(t *testing.T) {
+	provider := newTokenAuthProvider()
+
+	pair, err := provider.IssueTokens(context.Background(), "test-api-key-12345")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, int64(3600), pair.ExpiresIn)
+	assert.Equal(t, int64(defaultRefreshTokenExpiry/time.Second), pair.RefreshTokenExpiresIn)
+	assert.NotEmpty(t, pair.RefreshToken)
+
+	// The access token authenticates as the API key's user
+	authInfo, err := provider.Authenticate(context.Background(), pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user_test-api", authInfo.UserID)
+	assert.Equal(t, []string{"api:access"}, authInfo.Permissions)
+	assert.Equal(t, "jwt", authInfo.Metadata["auth_type"])
+}
+
+func TestDefaultAuthProvider_IssueTokens_Rejected(t *testing.T) {
+	provider := newTokenAuthProvider()
+	_, err := provider.IssueTokens(context.Background(), "wrong-key")
+	assert.Error(t, err)
+
+	unconfigured := NewDefaultAuthProvider(&Config{APIKeys: []string{"test-api-key-12345"}}, logrus.New())
+	_, err = unconfigured.IssueTokens(context.Background(), "test-api-key-12345")
+	assert.ErrorIs(t, err, ErrJWTNotConfigured)
+}
+
+func TestDefaultAuthProvider_RefreshTokens(t *testing.T) {
+	provider := newTokenAuthProvider()
+	issued, err := provider.IssueTokens(context.Background(), "test-api-key-12345")
+	require.NoError(t, err)
+
+	refreshed, err := provider.RefreshTokens(context.Background(), issued.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.RefreshToken, refreshed.RefreshToken)
+
+	claims, err := provider.ValidateJWT(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user_test-api", claims.UserID)
+
+	// Refresh tokens are single use
+	_, err = provider.RefreshTokens(context.Background(), issued.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// The rotated token still works
+	_, err = provider.RefreshTokens(context.Background(), refreshed.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestDefaultAuthProvider_RefreshTokens_Expired(t *testing.T) {
+	provider := newTokenAuthProvider()
+	store := NewMemoryRefreshTokenStore()
+	provider.SetRefreshTokenStore(store)
+	require.NoError(t, store.Save(context.Background(), &RefreshToken{
+		Token:     "expired-token",
+		UserID:    "user_test-api",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	_, err := provider.RefreshTokens(context.Background(), "expired-token")
+	assert.ErrorIs(t, err, ErrRefreshTokenExpired)
+
+	_, err = provider.RefreshTokens(context.Background(), "unknown-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mime"
+	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -119,7 +121,7 @@ func (v *RequestValidator) ValidateRequest(ctx context.Context, r *http.Request)
 	}
 
 	// IP validation
-	clientIP := getClientIPFromRequest(r)
+	clientIP := ClientIP(r)
 	if !v.isAllowedIP(clientIP) {
 		result.Valid = false
 		result.Errors = append(result.Errors, fmt.Sprintf("IP %s not allowed", clientIP))
@@ -233,14 +235,21 @@ func (v *RequestValidator) ValidationMiddleware() func(http.Handler) http.Handle
 			}
 
 			if !result.Valid {
+				// A declared length over the limit is rejected before
+				// reading anything
+				status, code := http.StatusBadRequest, "invalid_request"
+				if r.ContentLength > v.config.MaxRequestSize {
+					status, code = http.StatusRequestEntityTooLarge, "request_too_large"
+				}
 				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusBadRequest)
+				w.WriteHeader(status)
 				
 				response := map[string]interface{}{
 					"error": map[string]interface{}{
 						"message": "Request validation failed",
 						"type":    "validation_error",
-						"code":    http.StatusBadRequest,
+						"code":    code,
+						"status":  status,
 						"details": result.Errors,
 					},
 					"timestamp": time.Now().Unix(),
@@ -255,6 +264,12 @@ func (v *RequestValidator) ValidationMiddleware() func(http.Handler) http.Handle
 				w.Header().Set("X-Validation-Warnings", strings.Join(result.Warnings, "; "))
 			}
 
+			// Content-Length can be missing (chunked bodies) or understate
+			// the body, so the limit is also enforced as the body is read
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, v.config.MaxRequestSize)
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -281,8 +296,7 @@ func (v *RequestValidator) isAllowedContentType(contentType string) bool {
 	}
 	
 	// Extract main content type (ignore charset, etc.)
-	mainType := strings.Split(contentType, ";")[0]
-	mainType = strings.TrimSpace(mainType)
+	mainType := MediaType(contentType)
 	
 	for _, allowed := range v.config.ContentTypes {
 		if strings.EqualFold(mainType, allowed) {
@@ -292,6 +306,17 @@ func (v *RequestValidator) isAllowedContentType(contentType string) bool {
 	return false
 }
 
+// MediaType returns the lower-cased media type of a Content-Type header value,
+// ignoring any parameters such as charset
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Fall back to a plain split for malformed parameter lists
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
 func (v *RequestValidator) isAllowedIP(ip string) bool {
 	if len(v.config.IPWhitelist) == 0 {
 		return true // Allow all if no whitelist
@@ -314,22 +339,30 @@ func (v *RequestValidator) isBlockedIP(ip string) bool {
 	return false
 }
 
+// matchesIPPattern reports whether ip equals an IPv4/IPv6 address pattern or
+// falls inside a CIDR range pattern. Unparseable IPs or patterns never match.
 func (v *RequestValidator) matchesIPPattern(ip, pattern string) bool {
-	// Simple IP matching (in production, use proper CIDR matching)
+	// Fast path for exact matches
 	if ip == pattern {
 		return true
 	}
 	
-	// Check for CIDR notation
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false
+	}
+	
 	if strings.Contains(pattern, "/") {
-		// This is a simplified check - use net.ParseCIDR in production
-		parts := strings.Split(pattern, "/")
-		if len(parts) == 2 {
-			return strings.HasPrefix(ip, parts[0][:strings.LastIndex(parts[0], ".")])
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return false
 		}
+		return network.Contains(parsedIP)
 	}
 	
-	return false
+	// Exact match across equivalent notations, e.g. compressed IPv6
+	parsedPattern := net.ParseIP(pattern)
+	return parsedPattern != nil && parsedPattern.Equal(parsedIP)
 }
 
 func (v *RequestValidator) isValidUserAgent(userAgent string) bool {
@@ -309,6 +309,60 @@ func TestRequestValidator_IsAllowedContentType(t *testing.T) {
 	assert.True(t, validator.isAllowedContentType("text/html"))
 }
 
+func TestRequestValidator_MatchesIPPattern(t *testing.T) {
+	validator, err := NewRequestValidator(&ValidationConfig{}, logrus.New())
+	require.NoError(t, err)
+
+	// Exact addresses
+	assert.True(t, validator.matchesIPPattern("10.0.0.1", "10.0.0.1"))
+	assert.True(t, validator.matchesIPPattern("2001:db8::1", "2001:0db8:0000::1"))
+	assert.False(t, validator.matchesIPPattern("10.0.0.1", "10.0.0.10"))
+
+	// IPv4 ranges
+	assert.True(t, validator.matchesIPPattern("192.168.1.5", "192.168.1.0/24"))
+	assert.False(t, validator.matchesIPPattern("192.168.2.5", "192.168.1.0/24"))
+	assert.True(t, validator.matchesIPPattern("10.1.1.1", "10.1.1.0/24"))
+	assert.False(t, validator.matchesIPPattern("10.11.1.1", "10.1.1.0/24"))
+	assert.True(t, validator.matchesIPPattern("172.20.3.4", "172.16.0.0/12"))
+
+	// IPv6 ranges
+	assert.True(t, validator.matchesIPPattern("2001:db8:abcd::42", "2001:db8::/32"))
+	assert.False(t, validator.matchesIPPattern("2001:db9::42", "2001:db8::/32"))
+	assert.True(t, validator.matchesIPPattern("fe80::1", "fe80::/10"))
+	assert.False(t, validator.matchesIPPattern("192.168.1.5", "2001:db8::/32"))
+
+	// Malformed input never matches
+	assert.False(t, validator.matchesIPPattern("192.168.1.5", "192.168.1.0/33"))
+	assert.False(t, validator.matchesIPPattern("192.168.1.5", "not-an-ip/24"))
+	assert.False(t, validator.matchesIPPattern("192.168.1.5", "/"))
+	assert.False(t, validator.matchesIPPattern("192.168.1.5", "192.168.1"))
+	assert.False(t, validator.matchesIPPattern("not-an-ip", "192.168.1.0/24"))
+}
+
+func TestRequestValidator_IPWhitelistAndBlacklist(t *testing.T) {
+	validator, err := NewRequestValidator(&ValidationConfig{
+		IPWhitelist: []string{"192.168.1.0/24", "2001:db8::/32"},
+		IPBlacklist: []string{"192.168.1.13", "2001:db8:bad::/48"},
+	}, logrus.New())
+	require.NoError(t, err)
+
+	assert.True(t, validator.isAllowedIP("192.168.1.100"))
+	assert.False(t, validator.isAllowedIP("192.168.2.5"))
+	assert.True(t, validator.isAllowedIP("2001:db8::7"))
+	assert.True(t, validator.isBlockedIP("192.168.1.13"))
+	assert.False(t, validator.isBlockedIP("192.168.1.14"))
+	assert.True(t, validator.isBlockedIP("2001:db8:bad::1"))
+	assert.False(t, validator.isBlockedIP("2001:db8:600d::1"))
+}
+
+func TestMediaType(t *testing.T) {
+	assert.Equal(t, "application/json", MediaType("application/json"))
+	assert.Equal(t, "application/json", MediaType("application/json; charset=utf-8"))
+	assert.Equal(t, "application/json", MediaType("Application/JSON; charset=UTF-8"))
+	assert.Equal(t, "application/json", MediaType("application/json; charset"))
+	assert.Equal(t, "", MediaType(""))
+}
+
 func TestRequestValidator_ContainsBlockedPattern(t *testing.T) {
 	config := &ValidationConfig{
 		BlockedPatterns: []string{"(?i)script", "javascript:"},
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// TenantHeader names the tenant a request is billed to, typically set by
+// the gateway in front of the router. It is stored as the tenant_id tag.
+const TenantHeader = "X-Tenant-ID"
+
+// tenantTag is the request tag TenantHeader populates
+const tenantTag = "tenant_id"
+
+// applyTenantHeader copies TenantHeader into the request's tags, overriding
+// a tenant_id the body set, since the header comes from trusted
+// infrastructure rather than the client
+func applyTenantHeader(r *http.Request, req *types.ChatRequest) {
+	tenant := r.Header.Get(TenantHeader)
+	if tenant == "" {
+		return
+	}
+	if req.Tags == nil {
+		req.Tags = make(map[string]string, 1)
+	}
+	req.Tags[tenantTag] = tenant
+}
+
+// recordUsage prices the provider-reported usage of a completed request,
+// stores it as the actual cost in metadata and writes it to the accounting
+// sink when one is configured
+func (s *Server) recordUsage(ctx context.Context, req *types.ChatRequest, metadata *types.RouterMetadata, usage *types.Usage) {
+	if usage == nil {
+		return
+	}
+
+	var cost float64
+	if provider, exists := s.router.GetProvider(metadata.Provider); exists {
+		var priced bool
+		cost, priced = accounting.Cost(provider.GetCapabilities(), req.Model, usage)
+		if !priced {
+			s.logger.WithField("provider", metadata.Provider).WithField("model", req.Model).
+				Warn("No pricing configured for model, recording zero cost")
+		}
+	}
+	metadata.ActualCost = cost
+	s.metrics.RecordTaggedUsage(metadata.Provider, req.Model, req.Tags, usage.PromptTokens, usage.CompletionTokens, cost)
+
+	if s.usageRecorder == nil {
+		return
+	}
+
+	record := accounting.UsageRecord{
+		RequestID:        req.ID,
+		UserID:           req.UserID,
+		Provider:         metadata.Provider,
+		Model:            req.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          cost,
+		Timestamp:        time.Now().UTC(),
+		Tags:             req.Tags,
+	}
+	if authInfo, ok := security.GetAuthInfo(ctx); ok {
+		record.CallerID = authInfo.UserID
+	}
+	if err := s.usageRecorder.Record(record); err != nil {
+		s.logger.WithError(err).WithField("request_id", req.ID).Error("Failed to record usage")
+	}
+}
+
+// addUsage accumulates streamed usage into total
+func addUsage(total *types.Usage, chunk *types.Usage) *types.Usage {
+	if chunk == nil {
+		return total
+	}
+	if total == nil {
+		total = &types.Usage{}
+	}
+	total.PromptTokens += chunk.PromptTokens
+	total.CompletionTokens += chunk.CompletionTokens
+	total.TotalTokens += chunk.TotalTokens
+	return total
+}
+
+// newUsageStore creates the in-memory store behind /v1/usage, loading the
+// records already in the accounting file
+func newUsageStore(config *accounting.Config, logger *logrus.Logger) *accounting.MemoryStore {
+	store := accounting.NewMemoryStore(config.MaxRecords)
+
+	file, err := os.Open(config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read accounting file, usage reports start empty")
+		return store
+	}
+	defer file.Close()
+	if err := store.Load(file); err != nil {
+		logger.WithError(err).Warn("Failed to load accounting file, usage reports are incomplete")
+	}
+	return store
+}
+
+// handleUsage reports aggregated usage for an optional user and time window,
+// optionally grouped by provider or model. Callers must be authenticated;
+// with accounting.scope_to_caller they only see requests made with their
+// own credentials.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	authInfo, ok := security.GetAuthInfo(r.Context())
+	if !ok {
+		s.writeErrorResponse(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	params := r.URL.Query()
+	query := accounting.UsageQuery{
+		UserID:  params.Get("user_id"),
+		GroupBy: params.Get("group_by"),
+	}
+	if s.config.Accounting != nil && s.config.Accounting.ScopeToCaller {
+		query.CallerID = authInfo.UserID
+	}
+
+	var err error
+	if query.From, err = parseUsageTime(params.Get("from")); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid from: %v", err))
+		return
+	}
+	if query.To, err = parseUsageTime(params.Get("to")); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid to: %v", err))
+		return
+	}
+
+	report, err := s.usageStore.Query(query)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// parseUsageTime parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC
+// midnight). An empty value is the zero time.
+func parseUsageTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 timestamp or YYYY-MM-DD date, got %q", value)
+	}
+	return t, nil
+}
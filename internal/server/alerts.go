@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// defaultAlertTimeout bounds delivering one alert to the webhook
+const defaultAlertTimeout = 5 * time.Second
+
+// Alert types
+const (
+	AlertProviderAuthFailure = "provider_auth_failure"
+)
+
+// AlertConfig posts operational alerts that need an operator, such as a
+// provider rejecting its API key, to a webhook
+type AlertConfig struct {
+	WebhookURL string        `yaml:"webhook_url"` // empty disables alerts
+	Timeout    time.Duration `yaml:"timeout"`     // per delivery, defaults to 5s
+}
+
+// Validate checks the webhook URL
+func (c *AlertConfig) Validate() error {
+	if c.WebhookURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(c.WebhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("alerts.webhook_url must be an http or https URL")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("alerts.timeout must not be negative")
+	}
+	return nil
+}
+
+// Alert is the JSON body posted to the alert webhook
+type Alert struct {
+	Type      string    `json:"type"`
+	Severity  string    `json:"severity"`
+	Provider  string    `json:"provider,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// providerAuthFailed is the router's AuthFailureHandler: a provider rejected
+// the router's credentials and was taken out of rotation, which an operator
+// has to fix
+func (s *Server) providerAuthFailed(ctx context.Context, provider string, err error) {
+	message := fmt.Sprintf("Provider %s rejected its API key and was marked unhealthy", provider)
+	if s.securityMiddleware != nil {
+		s.securityMiddleware.LogSecurityEvent(ctx, security.SecurityViolation, message, map[string]interface{}{
+			"violation_type": "provider_auth_failure",
+			"provider":       provider,
+			"error":          err.Error(),
+		})
+	}
+	s.sendAlert(Alert{
+		Type:      AlertProviderAuthFailure,
+		Severity:  "critical",
+		Provider:  provider,
+		Message:   message,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// sendAlert posts alert to the configured webhook in the background
+func (s *Server) sendAlert(alert Alert) {
+	config := s.config.Alerts
+	if config == nil || config.WebhookURL == "" {
+		return
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultAlertTimeout
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := postAlert(ctx, config.WebhookURL, alert); err != nil {
+			s.logger.WithError(err).WithField("alert_type", alert.Type).Warn("Failed to deliver alert")
+		}
+	}()
+}
+
+// postAlert posts alert as JSON to webhookURL
+func postAlert(ctx context.Context, webhookURL string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
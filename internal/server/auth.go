@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// tokenRequest is the body of POST /v1/auth/token
+type tokenRequest struct {
+	APIKey string `json:"api_key"`
+}
+
+// refreshRequest is the body of POST /v1/auth/refresh
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// authProvider returns the configured authentication provider, if any
+func (s *Server) authProvider() *security.DefaultAuthProvider {
+	if s.securityMiddleware == nil {
+		return nil
+	}
+	return s.securityMiddleware.AuthProvider()
+}
+
+// handleIssueToken exchanges an API key, from the body or the usual auth
+// headers, for an access token and refresh token
+func (s *Server) handleIssueToken(w http.ResponseWriter, r *http.Request) {
+	var req tokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON request body")
+			return
+		}
+	}
+	if req.APIKey == "" {
+		req.APIKey = security.ExtractToken(r)
+	}
+
+	ctx := context.WithValue(r.Context(), "client_ip", security.ClientIP(r))
+	pair, err := s.authProvider().IssueTokens(ctx, req.APIKey)
+	if err != nil {
+		s.writeTokenError(ctx, w, "api_key", err)
+		return
+	}
+	s.writeTokenPair(ctx, w, "api_key", pair)
+}
+
+// handleRefreshToken exchanges a refresh token for a new token pair
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON request body")
+		return
+	}
+	if req.RefreshToken == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "client_ip", security.ClientIP(r))
+	pair, err := s.authProvider().RefreshTokens(ctx, req.RefreshToken)
+	if err != nil {
+		s.writeTokenError(ctx, w, "refresh_token", err)
+		return
+	}
+	s.writeTokenPair(ctx, w, "refresh_token", pair)
+}
+
+// writeTokenPair responds with newly issued tokens and audits the issuance
+func (s *Server) writeTokenPair(ctx context.Context, w http.ResponseWriter, grant string, pair *security.TokenPair) {
+	s.securityMiddleware.LogSecurityEvent(ctx, security.JWTTokenIssued, "JWT access token issued", map[string]interface{}{
+		"grant_type": grant,
+		"expires_in": pair.ExpiresIn,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pair)
+}
+
+// writeTokenError maps issuance failures to HTTP responses
+func (s *Server) writeTokenError(ctx context.Context, w http.ResponseWriter, grant string, err error) {
+	if errors.Is(err, security.ErrJWTNotConfigured) {
+		s.writeErrorResponse(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+
+	s.securityMiddleware.LogSecurityEvent(ctx, security.AuthenticationFailure, "Token issuance failed", map[string]interface{}{
+		"grant_type": grant,
+		"error":      err.Error(),
+	})
+	s.writeErrorResponse(w, http.StatusUnauthorized, err.Error())
+}
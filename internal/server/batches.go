@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// handleCreateBatch starts a batch on a batch-capable provider
+func (s *Server) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req types.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if req.InputFileID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "input_file_id is required")
+		return
+	}
+	if req.Endpoint == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+	if req.CompletionWindow == "" {
+		req.CompletionWindow = "24h"
+	}
+
+	s.serveBatch(w, r, func(provider providers.BatchProvider) (interface{}, error) {
+		return provider.CreateBatch(r.Context(), &req)
+	})
+}
+
+// handleListBatches lists the most recent batches
+func (s *Server) handleListBatches(w http.ResponseWriter, r *http.Request) {
+	s.serveBatch(w, r, func(provider providers.BatchProvider) (interface{}, error) {
+		return provider.ListBatches(r.Context())
+	})
+}
+
+// handleGetBatch returns a batch's status, including its output file once
+// complete
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s.serveBatch(w, r, func(provider providers.BatchProvider) (interface{}, error) {
+		return provider.GetBatch(r.Context(), id)
+	})
+}
+
+// handleCancelBatch cancels an in-progress batch
+func (s *Server) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s.serveBatch(w, r, func(provider providers.BatchProvider) (interface{}, error) {
+		return provider.CancelBatch(r.Context(), id)
+	})
+}
+
+// serveBatch routes a batch operation and writes its result. Upstream client
+// errors, such as an unknown batch ID, keep their status code.
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request, call func(providers.BatchProvider) (interface{}, error)) {
+	name, provider, err := s.router.RouteBatch(r.Context())
+	if err != nil {
+		s.writeRoutingError(w, err)
+		return
+	}
+
+	resp, err := call(provider)
+	if err != nil {
+		s.logger.WithError(err).WithField("provider", name).Error("Batch request failed")
+		status := http.StatusBadGateway
+		if code := providers.StatusCode(err); code >= 400 && code < 500 {
+			status = code
+		}
+		s.writeErrorResponse(w, status, fmt.Sprintf("Batch request failed: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/cache"
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// CacheHeader reports whether a completion was served from the response cache
+const CacheHeader = "X-Cache"
+
+// Cache kinds reported in router_metadata.cache_hit
+const (
+	CacheHitExact    = "exact"
+	CacheHitSemantic = "semantic"
+)
+
+// cacheLookup is what storeCachedResponse needs to cache the response to a
+// request that missed
+type cacheLookup struct {
+	key   string               // exact-match key, empty when not cached
+	probe *cache.SemanticProbe // embedded prompt, nil when not cached
+}
+
+// cached reports whether the response will be cached
+func (l cacheLookup) cached() bool {
+	return l.key != "" || l.probe != nil
+}
+
+// cachedResponse is a cache hit
+type cachedResponse struct {
+	resp       *types.ChatResponse
+	kind       string  // CacheHitExact or CacheHitSemantic
+	similarity float64 // prompt similarity of a semantic hit
+}
+
+// lookupCachedResponse checks the exact-match cache, then the semantic
+// cache, returning a hit if either has one. A semantic cache that can't
+// embed the prompt is skipped.
+func (s *Server) lookupCachedResponse(ctx context.Context, req *types.ChatRequest) (cacheLookup, *cachedResponse) {
+	var lookup cacheLookup
+	if s.responseCache != nil && cache.Cacheable(req) {
+		key, err := cache.Key(req)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to compute response cache key")
+		} else if resp, hit := s.responseCache.Get(key); hit {
+			return lookup, &cachedResponse{resp: resp, kind: CacheHitExact}
+		} else {
+			lookup.key = key
+		}
+	}
+
+	if s.semanticCache != nil {
+		probe, hit, err := s.semanticCache.Lookup(cache.WithCaller(ctx, cacheCaller(ctx, req)), req)
+		if err != nil {
+			s.logger.WithError(err).Warn("Semantic cache lookup failed")
+			return lookup, nil
+		}
+		if hit != nil {
+			return lookup, &cachedResponse{resp: hit.Response, kind: CacheHitSemantic, similarity: hit.Similarity}
+		}
+		lookup.probe = probe
+	}
+	return lookup, nil
+}
+
+// cacheCaller identifies who a semantic cache entry belongs to: the
+// authenticated caller and the tenant
+func cacheCaller(ctx context.Context, req *types.ChatRequest) string {
+	caller := ""
+	if authInfo, ok := security.GetAuthInfo(ctx); ok {
+		caller = authInfo.UserID
+	}
+	return fmt.Sprintf("%q %q", caller, req.Tags[tenantTag])
+}
+
+// storeCachedResponse caches a successful response in the caches lookup
+// missed
+func (s *Server) storeCachedResponse(lookup cacheLookup, resp *types.ChatResponse) {
+	// Routing metadata describes the original request, not later cache hits
+	cached := *resp
+	cached.RouterMetadata = nil
+
+	if lookup.key != "" && s.responseCache != nil {
+		s.responseCache.Set(lookup.key, &cached, s.config.Cache.TTL)
+	}
+	if lookup.probe != nil && s.semanticCache != nil {
+		s.semanticCache.Store(lookup.probe, &cached)
+	}
+}
+
+// recordCacheHit counts a cache hit, marks it in the routing metadata and
+// sets CacheHeader; the caller writes the response
+func (s *Server) recordCacheHit(w http.ResponseWriter, req *types.ChatRequest, hit *cachedResponse, metadata *types.RouterMetadata) {
+	status := metrics.StatusCacheHit
+	if hit.kind == CacheHitSemantic {
+		status = metrics.StatusSemanticCacheHit
+	}
+	s.metrics.RecordRequest(metadata.Provider, req.Model, status, false, time.Since(req.Timestamp))
+
+	metadata.CacheHit = hit.kind
+	metadata.CacheSimilarity = hit.similarity
+	w.Header().Set(CacheHeader, "HIT")
+}
+
+// writeCachedResponse serves a cache hit with the current routing metadata
+func (s *Server) writeCachedResponse(w http.ResponseWriter, req *types.ChatRequest, hit *cachedResponse, metadata *types.RouterMetadata) {
+	s.recordCacheHit(w, req, hit, metadata)
+
+	resp := hit.resp
+	resp.RouterMetadata = metadata
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// routerEmbedder embeds semantic cache prompts with an embedding model
+// routed like a client's embeddings request
+type routerEmbedder struct {
+	router *routing.Router
+	model  string
+}
+
+func (e *routerEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	req := &types.EmbeddingRequest{Model: e.model, Input: text}
+	_, provider, err := e.router.RouteEmbedding(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.CreateEmbedding(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("embedding provider returned no embeddings")
+	}
+	return resp.Data[0].Embedding, nil
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// handleCompletion handles legacy OpenAI text completion requests. The
+// prompt is sent to the routed provider as a single user message and the
+// result is returned in the text completion shape (choices[].text).
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	release, ok := s.acquireSlot(w, types.PriorityNormal, s.writeErrorResponse)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var completionReq types.CompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&completionReq); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	req, err := completionReq.ToChatRequest()
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.checkModelAllowed(req.Model); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
+	req.ID = fmt.Sprintf("cmpl-%d", time.Now().UnixNano())
+	req.Timestamp = time.Now()
+	applyTenantHeader(r, req)
+
+	r, cancel := s.withRequestTimeout(r, req)
+	defer cancel()
+	r = s.withUpstreamCapture(r)
+	r = s.withModelFilter(r)
+
+	// Screen the prompt before it reaches any provider
+	if err := s.screenPrompt(r.Context(), req); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
+
+	// Route the request
+	metadata, provider, err := s.router.Route(r.Context(), req)
+	if err != nil {
+		status := metrics.StatusRoutingError
+		if routingErrorStatus(w, err) == http.StatusTooManyRequests {
+			status = metrics.StatusRateLimited
+		}
+		s.metrics.RecordRequest("", req.Model, status, false, time.Since(req.Timestamp))
+		s.writeRoutingError(w, err)
+		return
+	}
+	if err := s.checkRoutedModel(r.Context(), metadata); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
+
+	if req.Stream {
+		s.handleStreamingTextCompletion(w, r, req, provider, metadata)
+	} else {
+		s.handleNonStreamingTextCompletion(w, r, req, provider, metadata)
+	}
+}
+
+// handleNonStreamingTextCompletion completes a legacy request with retry/fallback
+func (s *Server) handleNonStreamingTextCompletion(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	lookup, cached := s.lookupCachedResponse(r.Context(), req)
+	if cached != nil {
+		s.recordCacheHit(w, req, cached, metadata)
+		s.writeTextCompletionResponse(w, cached.resp, metadata)
+		return
+	}
+
+	resp, err := s.attemptCompletionWithRetryAndFallback(r.Context(), req, provider, metadata)
+	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, s.writeErrorResponse) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All completion attempts failed")
+		s.writeFailure(w, err, http.StatusInternalServerError, "Completion failed")
+		return
+	}
+	s.storeCachedResponse(lookup, resp)
+	if lookup.cached() {
+		w.Header().Set(CacheHeader, "MISS")
+	}
+
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if resp.Usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+	s.recordUsage(r.Context(), req, metadata, resp.Usage)
+
+	writeUpstreamHeaders(r.Context(), w, metadata)
+	s.writeTextCompletionResponse(w, resp, metadata)
+}
+
+// writeTextCompletionResponse writes a completion in the legacy text shape
+func (s *Server) writeTextCompletionResponse(w http.ResponseWriter, resp *types.ChatResponse, metadata *types.RouterMetadata) {
+	out := types.NewCompletionResponse(resp)
+	out.RouterMetadata = metadata
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(out)
+}
+
+// handleStreamingTextCompletion streams a completion as legacy text
+// completion chunks, ending with a usage and metadata chunk and [DONE]
+func (s *Server) handleStreamingTextCompletion(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	w, r, cancel := streamContext(w, r)
+	defer cancel()
+
+	chunks, err := s.attemptStreamingWithFallback(r.Context(), req, provider, metadata)
+	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, s.writeErrorResponse) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All streaming attempts failed")
+		s.writeFailure(w, err, http.StatusInternalServerError, "Streaming failed")
+		return
+	}
+
+	writeUpstreamHeaders(r.Context(), w, metadata)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	var usage *types.Usage
+	streamErr := s.forEachChunk(r.Context(), w, chunks, func(chunk *types.ChatChunk) {
+		usage = addUsage(usage, chunk.Usage)
+		if len(chunk.Choices) == 0 {
+			return // usage-only chunks are reported in the summary
+		}
+
+		out := types.NewCompletionChunk(chunk)
+		out.ID, out.Model, out.Usage = req.ID, req.Model, nil
+		s.writeTextCompletionEvent(w, out)
+	})
+	if streamErr != nil {
+		s.recordUsage(r.Context(), req, metadata, usage)
+		if clientGone(r.Context()) {
+			s.recordStreamCancelled(req, metadata)
+			return
+		}
+		s.recordStreamFailure(req, metadata, streamErr)
+		writeStreamError(w, streamErr)
+		return
+	}
+
+	s.writeTextCompletionEvent(w, &types.CompletionResponse{
+		ID:             req.ID,
+		Object:         "text_completion",
+		Created:        time.Now().Unix(),
+		Model:          req.Model,
+		Choices:        []types.CompletionChoice{},
+		Usage:          usage,
+		RouterMetadata: metadata,
+	})
+	fmt.Fprintf(w, "data: [DONE]\n\n")
+	w.(http.Flusher).Flush()
+
+	s.recordUsage(r.Context(), req, metadata, usage)
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, usage.PromptTokens, usage.CompletionTokens)
+	}
+}
+
+// writeTextCompletionEvent writes one server-sent text completion chunk
+func (s *Server) writeTextCompletionEvent(w http.ResponseWriter, chunk *types.CompletionResponse) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to marshal completion chunk")
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// maxCapturedBodyBytes bounds how much of a body is buffered for debug
+// logging; larger bodies can't be redacted and are logged as a placeholder
+const maxCapturedBodyBytes = 1 << 20
+
+// redactedValue replaces redacted values in logged bodies
+const redactedValue = "***REDACTED***"
+
+// messageContentFields hold prompts and completions rather than request
+// parameters
+var messageContentFields = map[string]bool{
+	"content":   true,
+	"prompt":    true,
+	"input":     true,
+	"system":    true,
+	"text":      true,
+	"query":     true,
+	"documents": true,
+	"arguments": true,
+}
+
+// DebugLoggingConfig controls debug-level logging of request and response
+// bodies. Credential-like fields are always redacted; telemetry opt-out
+// traffic is never logged.
+type DebugLoggingConfig struct {
+	Enabled              bool `yaml:"enabled"`
+	MaxBodyBytes         int  `yaml:"max_body_bytes"`         // logged bytes per body, defaults to 4096
+	RedactMessageContent bool `yaml:"redact_message_content"` // also redact prompts and completions
+}
+
+// debugLoggingMiddleware logs request and response bodies at debug level
+func (s *Server) debugLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := s.config.DebugLogging
+		if !s.logger.IsLevelEnabled(logrus.DebugLevel) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r, telemetry := security.RequestTelemetry(r)
+		if telemetry.OptedOut() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fields := logrus.Fields{"method": r.Method, "path": r.URL.Path}
+		if r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err == nil {
+				// Restore the body for downstream handlers
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				if len(body) > 0 {
+					s.logger.WithFields(fields).WithField("body", config.formatBody(body, r.Header.Get("Content-Type"))).Debug("HTTP request body")
+				}
+			}
+		}
+
+		recorder := &debugRecordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// Authentication further in may have opted the request out
+		if telemetry.OptedOut() || (recorder.body.Len() == 0 && !recorder.overflow) {
+			return
+		}
+		body := recorder.body.Bytes()
+		if recorder.overflow {
+			body = nil
+		}
+		fields["status"] = recorder.statusCode
+		s.logger.WithFields(fields).WithField("body", config.formatBody(body, recorder.Header().Get("Content-Type"))).Debug("HTTP response body")
+	})
+}
+
+// formatBody redacts and truncates a body for logging. JSON bodies and
+// server-sent event streams of JSON are redacted field by field; bodies that
+// can't be parsed are only logged when message content isn't redacted.
+func (c *DebugLoggingConfig) formatBody(body []byte, contentType string) string {
+	limit := c.MaxBodyBytes
+	if limit <= 0 {
+		limit = maxLoggedBodyBytes
+	}
+	if body == nil {
+		return "[body exceeds capture limit]"
+	}
+
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		lines := strings.Split(string(body), "\n")
+		for i, line := range lines {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				if redacted, ok := c.redactJSON([]byte(data)); ok {
+					lines[i] = "data: " + string(redacted)
+				}
+			}
+		}
+		return truncateBody([]byte(strings.Join(lines, "\n")), limit)
+	}
+
+	if redacted, ok := c.redactJSON(body); ok {
+		return truncateBody(redacted, limit)
+	}
+	if c.RedactMessageContent {
+		return "[unparsed body redacted]"
+	}
+	return truncateBody(body, limit)
+}
+
+// redactJSON redacts a JSON document, reporting false if it isn't JSON
+func (c *DebugLoggingConfig) redactJSON(body []byte) ([]byte, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, false
+	}
+	redacted, err := json.Marshal(c.redactValue(doc))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// redactValue walks a decoded JSON value, replacing credential strings and,
+// when configured, message content. Only string credentials are redacted so
+// counts such as max_tokens stay readable.
+func (c *DebugLoggingConfig) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			_, isString := field.(string)
+			switch {
+			case isString && security.IsSensitiveField(key, nil):
+				v[key] = redactedValue
+			case c.RedactMessageContent && messageContentFields[key] && field != nil:
+				v[key] = redactedValue
+			default:
+				v[key] = c.redactValue(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = c.redactValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// debugRecordingWriter passes a response through while keeping a bounded
+// copy for the debug log
+type debugRecordingWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool // body exceeded maxCapturedBodyBytes
+}
+
+func (rw *debugRecordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.statusCode = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *debugRecordingWriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.overflow {
+		if rw.body.Len()+len(data) > maxCapturedBodyBytes {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(data)
+		}
+	}
+	return rw.ResponseWriter.Write(data)
+}
+
+// Flush implements http.Flusher interface for streaming support
+func (rw *debugRecordingWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
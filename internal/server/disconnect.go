@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// errClientGone cancels a stream whose client stopped accepting writes
+var errClientGone = errors.New("client disconnected")
+
+// streamContext derives the context a stream's provider call runs under. It
+// ends when the client disconnects, which cancels r's context, or when a
+// write through the returned writer fails, so the upstream request is closed
+// instead of generating tokens nobody reads.
+func streamContext(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(r.Context())
+	return &streamWriter{ResponseWriter: w, cancel: cancel}, r.WithContext(ctx), func() { cancel(nil) }
+}
+
+// streamWriter cancels its stream's context on the first failed write
+type streamWriter struct {
+	http.ResponseWriter
+	cancel context.CancelCauseFunc
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if err != nil {
+		w.cancel(errClientGone)
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher for the SSE writes
+func (w *streamWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *streamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// clientGone reports whether a stream's context ended because the client
+// went away, as opposed to the request deadline passing
+func clientGone(ctx context.Context) bool {
+	cause := context.Cause(ctx)
+	return errors.Is(cause, errClientGone) || errors.Is(cause, context.Canceled)
+}
+
+// recordStreamCancelled logs and counts a stream the client abandoned. Its
+// provider call has already been cancelled and nothing more is written.
+func (s *Server) recordStreamCancelled(req *types.ChatRequest, metadata *types.RouterMetadata) {
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusCancelled, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.logger.WithField("provider", metadata.Provider).WithField("request_id", req.ID).
+		Info("Client disconnected mid-stream, cancelled provider stream")
+}
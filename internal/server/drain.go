@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// drainState tracks in-flight LLM requests so shutdown can let them finish
+type drainState struct {
+	mu       sync.Mutex // orders Add against draining so Wait never races it
+	draining bool
+	inFlight sync.WaitGroup
+}
+
+// trackRequest registers an in-flight LLM request. Once the server is
+// draining it instead writes a 503 via write, asking the client to retry
+// elsewhere, and ok is false.
+func (s *Server) trackRequest(w http.ResponseWriter, write func(http.ResponseWriter, int, string)) (done func(), ok bool) {
+	s.drain.mu.Lock()
+	if s.drain.draining {
+		s.drain.mu.Unlock()
+		w.Header().Set("Connection", "close")
+		write(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return nil, false
+	}
+	s.drain.inFlight.Add(1)
+	s.drain.mu.Unlock()
+
+	return s.drain.inFlight.Done, true
+}
+
+// draining reports whether the server has stopped accepting LLM requests
+func (s *Server) draining() bool {
+	s.drain.mu.Lock()
+	defer s.drain.mu.Unlock()
+	return s.drain.draining
+}
+
+// drainRequests stops accepting LLM requests and waits for those in flight
+// to finish, giving up when ctx is done
+func (s *Server) drainRequests(ctx context.Context) error {
+	s.drain.mu.Lock()
+	s.drain.draining = true
+	s.drain.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.drain.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("requests still in flight: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/privacy"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// Error types in error.type, telling client mistakes apart from routing and
+// provider failures
+const (
+	ErrorTypeInvalidRequest = "invalid_request_error"
+	ErrorTypeAuthentication = "authentication_error"
+	ErrorTypeAuthorization  = "authorization_error"
+	ErrorTypeNotFound       = "not_found_error"
+	ErrorTypeRateLimit      = "rate_limit_error"
+	ErrorTypeRouting        = "routing_error"
+	ErrorTypeProvider       = "provider_error"
+	ErrorTypeContentPolicy  = "content_policy_error"
+	ErrorTypeAPI            = "api_error"
+)
+
+// Error codes in error.code. They are stable so clients can branch on them
+// instead of on messages.
+const (
+	ErrorCodeInvalidRequest        = "invalid_request"
+	ErrorCodeUnauthorized          = "unauthorized"
+	ErrorCodeForbidden             = "forbidden"
+	ErrorCodeNotFound              = "not_found"
+	ErrorCodeMethodNotAllowed      = "method_not_allowed"
+	ErrorCodeConflict              = "conflict"
+	ErrorCodeRequestTooLarge       = "request_too_large"
+	ErrorCodeRateLimited           = "rate_limited"
+	ErrorCodeInternal              = "internal_error"
+	ErrorCodeUnavailable           = "service_unavailable"
+	ErrorCodeTimeout               = "timeout"
+	ErrorCodeModelNotFound         = "model_not_found"
+	ErrorCodeUnsupportedFeature    = "unsupported_feature"
+	ErrorCodeBudgetExceeded        = "budget_exceeded"
+	ErrorCodeSpendLimitExceeded    = "spend_limit_exceeded"
+	ErrorCodeNoHealthyProviders    = "no_healthy_providers"
+	ErrorCodeOverloaded            = "overloaded"
+	ErrorCodeProviderTimeout       = "provider_timeout"
+	ErrorCodeUpstream              = "upstream_error"
+	ErrorCodeContentFlagged        = "content_flagged"
+	ErrorCodePIIDetected           = "pii_detected"
+	ErrorCodeModerationUnavailable = "moderation_unavailable"
+	ErrorCodeModelNotAllowed       = "model_not_allowed"
+	ErrorCodeTransformRejected     = "transform_rejected"
+	ErrorCodeTransformFailed       = "transform_failed"
+	ErrorCodeInvalidStopSequences  = "invalid_stop_sequences"
+)
+
+// errorClass is the status, type and code an error response is written with
+type errorClass struct {
+	Status int
+	Type   string
+	Code   string
+}
+
+// statusErrorClass returns the default type and code for a status
+func statusErrorClass(status int) errorClass {
+	class := errorClass{Status: status, Type: ErrorTypeInvalidRequest, Code: ErrorCodeInvalidRequest}
+	switch status {
+	case http.StatusUnauthorized:
+		class.Type, class.Code = ErrorTypeAuthentication, ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		class.Type, class.Code = ErrorTypeAuthorization, ErrorCodeForbidden
+	case http.StatusNotFound:
+		class.Type, class.Code = ErrorTypeNotFound, ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		class.Code = ErrorCodeMethodNotAllowed
+	case http.StatusConflict:
+		class.Code = ErrorCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		class.Code = ErrorCodeRequestTooLarge
+	case http.StatusTooManyRequests:
+		class.Type, class.Code = ErrorTypeRateLimit, ErrorCodeRateLimited
+	case http.StatusBadGateway:
+		class.Type, class.Code = ErrorTypeProvider, ErrorCodeUpstream
+	case http.StatusServiceUnavailable:
+		class.Type, class.Code = ErrorTypeAPI, ErrorCodeUnavailable
+	case http.StatusGatewayTimeout:
+		class.Type, class.Code = ErrorTypeAPI, ErrorCodeTimeout
+	default:
+		if status >= 500 {
+			class.Type, class.Code = ErrorTypeAPI, ErrorCodeInternal
+		}
+	}
+	return class
+}
+
+// classifyError returns the error class for a routing or completion
+// failure, setting Retry-After when the router knows when capacity frees
+// up. Errors the router doesn't classify get fallback's defaults.
+func classifyError(w http.ResponseWriter, err error, fallback int) errorClass {
+	var overloadErr *routing.OverloadedError
+	if errors.As(err, &overloadErr) {
+		setRetryAfter(w, overloadErr.RetryAfter)
+		return errorClass{http.StatusServiceUnavailable, ErrorTypeRouting, ErrorCodeOverloaded}
+	}
+
+	var bulkheadErr *routing.BulkheadFullError
+	if errors.As(err, &bulkheadErr) {
+		return errorClass{http.StatusServiceUnavailable, ErrorTypeRouting, ErrorCodeOverloaded}
+	}
+
+	var limitErr *routing.RateLimitError
+	if errors.As(err, &limitErr) {
+		setRetryAfter(w, limitErr.RetryAfter)
+		return errorClass{http.StatusTooManyRequests, ErrorTypeRateLimit, ErrorCodeRateLimited}
+	}
+
+	var spendErr *accounting.SpendLimitError
+	if errors.As(err, &spendErr) {
+		setRetryAfter(w, time.Until(spendErr.ResetAt))
+		return errorClass{http.StatusTooManyRequests, ErrorTypeRateLimit, ErrorCodeSpendLimitExceeded}
+	}
+
+	var violation *security.ModerationViolation
+	if errors.As(err, &violation) {
+		return errorClass{http.StatusForbidden, ErrorTypeContentPolicy, ErrorCodeContentFlagged}
+	}
+	var piiErr *privacy.DetectedError
+	if errors.As(err, &piiErr) {
+		return errorClass{http.StatusForbidden, ErrorTypeContentPolicy, ErrorCodePIIDetected}
+	}
+	if errors.Is(err, security.ErrModerationUnavailable) {
+		return errorClass{http.StatusServiceUnavailable, ErrorTypeAPI, ErrorCodeModerationUnavailable}
+	}
+
+	var notAllowedErr *ModelNotAllowedError
+	if errors.As(err, &notAllowedErr) {
+		return errorClass{http.StatusForbidden, ErrorTypeAuthorization, ErrorCodeModelNotAllowed}
+	}
+	var authzErr *security.AuthorizationError
+	if errors.As(err, &authzErr) {
+		return errorClass{http.StatusForbidden, ErrorTypeAuthorization, ErrorCodeForbidden}
+	}
+
+	var transformErr *routing.TransformError
+	if errors.As(err, &transformErr) {
+		if transformErr.Stage == routing.TransformStageRequest {
+			return errorClass{http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeTransformRejected}
+		}
+		return errorClass{http.StatusInternalServerError, ErrorTypeAPI, ErrorCodeTransformFailed}
+	}
+
+	var stopErr *routing.StopSequenceError
+	if errors.As(err, &stopErr) {
+		return errorClass{http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeInvalidStopSequences}
+	}
+
+	var featureErr *routing.UnsupportedFeatureError
+	if errors.As(err, &featureErr) {
+		return errorClass{http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeUnsupportedFeature}
+	}
+
+	switch {
+	case errors.Is(err, routing.ErrModelNotFound):
+		return errorClass{http.StatusNotFound, ErrorTypeNotFound, ErrorCodeModelNotFound}
+	case errors.Is(err, routing.ErrBudgetExceeded):
+		return errorClass{http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeBudgetExceeded}
+	case errors.Is(err, routing.ErrNoHealthyProviders):
+		return errorClass{http.StatusServiceUnavailable, ErrorTypeRouting, ErrorCodeNoHealthyProviders}
+	case errors.Is(err, routing.ErrProviderTimeout):
+		return errorClass{http.StatusGatewayTimeout, ErrorTypeProvider, ErrorCodeProviderTimeout}
+	case errors.Is(err, routing.ErrUpstream):
+		// Providers rejecting the request itself, e.g. an oversized prompt,
+		// are the client's to fix
+		switch status := providers.StatusCode(err); status {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+			return errorClass{status, ErrorTypeInvalidRequest, ErrorCodeInvalidRequest}
+		}
+		return errorClass{http.StatusBadGateway, ErrorTypeProvider, ErrorCodeUpstream}
+	}
+	return statusErrorClass(fallback)
+}
+
+// routingErrorStatus returns the HTTP status for a routing failure, setting
+// Retry-After where known
+func routingErrorStatus(w http.ResponseWriter, err error) int {
+	return classifyError(w, err, http.StatusServiceUnavailable).Status
+}
+
+// setRetryAfter sets Retry-After to wait rounded up to whole seconds, if
+// positive
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	}
+}
+
+// writeErrorResponse writes an error with the default type and code for
+// statusCode
+func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	writeErrorClass(w, statusErrorClass(statusCode), message, nil)
+}
+
+// writeFailure writes err classified by classifyError, prefixing its
+// message with what failed
+func (s *Server) writeFailure(w http.ResponseWriter, err error, fallback int, what string) {
+	writeErrorClass(w, classifyError(w, err, fallback), fmt.Sprintf("%s: %v", what, err), err)
+}
+
+// writeRoutingError maps routing failures to HTTP responses. Exhausted model
+// or provider limits and spend caps surface as 429 so clients can back off.
+func (s *Server) writeRoutingError(w http.ResponseWriter, err error) {
+	s.writeFailure(w, err, http.StatusServiceUnavailable, "Routing failed")
+}
+
+// writeStreamError ends an SSE stream that failed after its headers were
+// sent with an error event shaped like an error response. No [DONE] follows,
+// so clients can tell the stream was cut short.
+func writeStreamError(w http.ResponseWriter, err error) {
+	class := classifyError(w, err, http.StatusBadGateway)
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": errorObject(class, fmt.Sprintf("Streaming failed: %v", err), err),
+	})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+func writeErrorClass(w http.ResponseWriter, class errorClass, message string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(class.Status)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":     errorObject(class, message, err),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// errorObject builds the error object of an error response. When err is a
+// normalized provider failure it also carries the upstream error in
+// provider_error, shaped the same whichever provider failed.
+func errorObject(class errorClass, message string, err error) map[string]interface{} {
+	object := map[string]interface{}{
+		"message": message,
+		"type":    class.Type,
+		"code":    class.Code,
+		"status":  class.Status,
+	}
+
+	var providerErr *providers.ProviderError
+	if errors.As(err, &providerErr) && providerErr.Type != "" {
+		upstream := map[string]interface{}{
+			"provider":  providerErr.Provider,
+			"type":      providerErr.Type,
+			"retryable": providerErr.Retryable,
+		}
+		if providerErr.StatusCode != 0 {
+			upstream["status"] = providerErr.StatusCode
+		}
+		if providerErr.Code != "" {
+			upstream["code"] = providerErr.Code
+		}
+		if providerErr.Message != "" {
+			upstream["message"] = providerErr.Message
+		}
+		object["provider_error"] = upstream
+	}
+	return object
+}
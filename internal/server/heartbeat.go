@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// DefaultStreamHeartbeatInterval is how long a stream may sit idle before a
+// keepalive comment is sent, comfortably under common 60s proxy timeouts
+const DefaultStreamHeartbeatInterval = 15 * time.Second
+
+// sseKeepalive is an SSE comment, which clients ignore
+const sseKeepalive = ": keepalive\n\n"
+
+// heartbeatInterval returns the configured idle interval between keepalives,
+// zero when heartbeats are disabled
+func (s *Server) heartbeatInterval() time.Duration {
+	switch {
+	case s.config.StreamHeartbeatInterval < 0:
+		return 0
+	case s.config.StreamHeartbeatInterval == 0:
+		return DefaultStreamHeartbeatInterval
+	default:
+		return s.config.StreamHeartbeatInterval
+	}
+}
+
+// forEachChunk calls fn for each streamed chunk until the stream ends,
+// returning the error of a stream that failed part way, or the cause of ctx
+// ending, such as errClientGone, so an abandoned stream isn't drained. While
+// waiting on a slow provider it writes a keepalive comment whenever nothing
+// has been sent for the heartbeat interval. Keepalives are written between
+// fn calls on this goroutine, so they never split a data frame.
+func (s *Server) forEachChunk(ctx context.Context, w http.ResponseWriter, chunks <-chan *types.ChatChunk, fn func(*types.ChatChunk)) error {
+	var heartbeat <-chan time.Time
+	interval := s.heartbeatInterval()
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				// A provider cancelled with the stream closes it early
+				if ctx.Err() != nil {
+					return context.Cause(ctx)
+				}
+				return nil
+			}
+			if chunk.Err != nil {
+				if ctx.Err() != nil {
+					return context.Cause(ctx)
+				}
+				return chunk.Err
+			}
+			fn(chunk)
+			if ticker != nil {
+				ticker.Reset(interval)
+			}
+		case <-heartbeat:
+			fmt.Fprint(w, sseKeepalive)
+			w.(http.Flusher).Flush()
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tributary-ai/llm-router-waf/internal/cache"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+)
+
+// Idempotency headers
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotency-Replayed"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds client-supplied keys
+	maxIdempotencyKeyLength = 255
+
+	// maxStoredResponseBytes bounds responses kept for replay; larger
+	// responses are served but not stored
+	maxStoredResponseBytes = 1 << 20
+)
+
+// idempotencyMiddleware makes POSTs carrying an Idempotency-Key safe to
+// retry. The first request is processed and a successful response stored;
+// repeats with the same body replay it without calling a provider. Keys are
+// scoped to the caller and endpoint.
+func (s *Server) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+		if s.idempotencyStore == nil || r.Method != http.MethodPost || idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			s.writeErrorResponse(w, bodyReadStatus(err), fmt.Sprintf("Failed to read request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := scopedIdempotencyKey(r, idempotencyKey)
+		bodySum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(bodySum[:])
+		ttl := s.config.Idempotency.TTL
+
+		record, reserved := s.idempotencyStore.Reserve(key, bodyHash, ttl)
+		if !reserved {
+			switch {
+			case record.BodyHash != bodyHash:
+				s.writeErrorResponse(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used with a different request body", IdempotencyKeyHeader))
+			case record.Response == nil:
+				s.writeErrorResponse(w, http.StatusConflict, fmt.Sprintf("A request with this %s is still in progress", IdempotencyKeyHeader))
+			default:
+				replayResponse(w, record.Response)
+			}
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		stored := false
+		defer func() {
+			// Failed requests free the key so the client can retry them
+			if !stored {
+				s.idempotencyStore.Release(key)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode < 200 || recorder.statusCode >= 300 || recorder.overflow {
+			return
+		}
+		s.idempotencyStore.Complete(key, recorder.response(), ttl)
+		stored = true
+	})
+}
+
+// scopedIdempotencyKey namespaces a client key by caller and endpoint so
+// clients can't replay each other's responses
+func scopedIdempotencyKey(r *http.Request, idempotencyKey string) string {
+	caller := ""
+	if authInfo, ok := security.GetAuthInfo(r.Context()); ok {
+		caller = authInfo.UserID
+		if caller == "" {
+			caller = authInfo.APIKey
+		}
+	}
+
+	sum := sha256.Sum256([]byte(caller + "\x00" + r.URL.Path + "\x00" + idempotencyKey))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// replayResponse writes a stored response
+func replayResponse(w http.ResponseWriter, resp *cache.StoredResponse) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotencyReplayedHeader, "true")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// recordingWriter passes a response through while keeping a copy for replay
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	header      http.Header // headers as sent
+	body        bytes.Buffer
+	overflow    bool // body exceeded maxStoredResponseBytes
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.statusCode = code
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.overflow {
+		if rw.body.Len()+len(data) > maxStoredResponseBytes {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(data)
+		}
+	}
+	return rw.ResponseWriter.Write(data)
+}
+
+// Flush implements http.Flusher interface for streaming support
+func (rw *recordingWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// response returns the recorded response
+func (rw *recordingWriter) response() *cache.StoredResponse {
+	header := rw.header
+	if header == nil {
+		header = rw.ResponseWriter.Header().Clone()
+	}
+	return &cache.StoredResponse{
+		StatusCode: rw.statusCode,
+		Header:     header,
+		Body:       bytes.Clone(rw.body.Bytes()),
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// MessageLimits caps the size of chat conversations; a zero limit is not
+// enforced
+type MessageLimits struct {
+	MaxMessages      int `yaml:"max_messages"`
+	MaxMessageLength int `yaml:"max_message_length"` // total characters of text content
+	MaxChoices       int `yaml:"max_choices"`        // completions per request (n)
+}
+
+// limitViolation describes one exceeded message limit
+type limitViolation struct {
+	Limit  string `json:"limit"`
+	Max    int    `json:"max"`
+	Actual int    `json:"actual"`
+	param  string // request field the limit applies to
+}
+
+// check returns the limits req exceeds. Only text counts towards the length,
+// whether sent as a string or as content parts.
+func (l *MessageLimits) check(req *types.ChatRequest) []limitViolation {
+	if l == nil {
+		return nil
+	}
+
+	messages := req.Messages
+	var violations []limitViolation
+	if l.MaxMessages > 0 && len(messages) > l.MaxMessages {
+		violations = append(violations, limitViolation{Limit: "max_messages", Max: l.MaxMessages, Actual: len(messages), param: "messages"})
+	}
+	if l.MaxMessageLength > 0 {
+		length := 0
+		for _, msg := range messages {
+			length += utf8.RuneCountInString(types.MessageText(msg.Content))
+		}
+		if length > l.MaxMessageLength {
+			violations = append(violations, limitViolation{Limit: "max_message_length", Max: l.MaxMessageLength, Actual: length, param: "messages"})
+		}
+	}
+	if l.MaxChoices > 0 && req.ChoiceCount() > l.MaxChoices {
+		violations = append(violations, limitViolation{Limit: "max_choices", Max: l.MaxChoices, Actual: req.ChoiceCount(), param: "n"})
+	}
+	return violations
+}
+
+// writeLimitViolations rejects a request that exceeds message limits with a
+// 400 listing each limit hit
+func (s *Server) writeLimitViolations(w http.ResponseWriter, violations []limitViolation) {
+	details := make([]string, 0, len(violations))
+	for _, v := range violations {
+		details = append(details, fmt.Sprintf("%s is %d (got %d)", v.Limit, v.Max, v.Actual))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message":    "Request exceeds message limits: " + strings.Join(details, ", "),
+			"type":       "invalid_request_error",
+			"code":       ErrorCodeInvalidRequest,
+			"status":     http.StatusBadRequest,
+			"param":      violations[0].param,
+			"violations": violations,
+		},
+		"timestamp": time.Now().Unix(),
+	})
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// acquireSlot reserves one of the router's concurrent request slots for a
+// request of the given priority. When no slot is open to the priority the
+// request is shed immediately: it is recorded as overloaded, a 503 with
+// Retry-After is written via write, and ok is false. Requests arriving
+// while the server drains for shutdown are refused the same way.
+func (s *Server) acquireSlot(w http.ResponseWriter, priority types.Priority, write func(http.ResponseWriter, int, string)) (release func(), ok bool) {
+	finished, ok := s.trackRequest(w, write)
+	if !ok {
+		return nil, false
+	}
+
+	releaseSlot, err := s.router.AcquirePriority(priority)
+	if err != nil {
+		finished()
+		s.metrics.RecordRequest("", "", metrics.StatusOverloaded, false, 0)
+		write(w, routingErrorStatus(w, err), err.Error())
+		return nil, false
+	}
+
+	done := s.metrics.TrackInFlight(string(priority.OrDefault()))
+	return func() {
+		done()
+		releaseSlot()
+		finished()
+	}, true
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// handleMessages handles Anthropic Messages API requests. The request is
+// decoded into a provider-neutral chat request, so claude-* models go to
+// Anthropic while other models are translated to whichever provider serves
+// them, and the result is returned in Anthropic's response shape.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	release, ok := s.acquireSlot(w, types.PriorityNormal, writeAnthropicError)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var msgReq types.MessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&msgReq); err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	req, err := msgReq.ToChatRequest()
+	if err != nil {
+		writeAnthropicError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.checkModelAllowed(req.Model); err != nil {
+		writeAnthropicError(w, http.StatusForbidden, fmt.Sprintf("Request rejected: %v", err))
+		return
+	}
+	req.ID = fmt.Sprintf("msg_%d", time.Now().UnixNano())
+	req.Timestamp = time.Now()
+	applyTenantHeader(r, req)
+
+	r, cancel := s.withRequestTimeout(r, req)
+	defer cancel()
+	r = s.withUpstreamCapture(r)
+	r = s.withModelFilter(r)
+
+	// Screen the prompt before it reaches any provider
+	if err := s.screenPrompt(r.Context(), req); err != nil {
+		writeAnthropicError(w, classifyError(w, err, http.StatusForbidden).Status, fmt.Sprintf("Request rejected: %v", err))
+		return
+	}
+
+	// Route the request
+	metadata, provider, err := s.router.Route(r.Context(), req)
+	if err != nil {
+		status := routingErrorStatus(w, err)
+		recorded := metrics.StatusRoutingError
+		if status == http.StatusTooManyRequests {
+			recorded = metrics.StatusRateLimited
+		}
+		s.metrics.RecordRequest("", req.Model, recorded, false, time.Since(req.Timestamp))
+		writeAnthropicError(w, status, fmt.Sprintf("Routing failed: %v", err))
+		return
+	}
+	if err := s.checkRoutedModel(r.Context(), metadata); err != nil {
+		writeAnthropicError(w, http.StatusForbidden, fmt.Sprintf("Request rejected: %v", err))
+		return
+	}
+
+	if req.Stream {
+		s.handleStreamingMessages(w, r, req, provider, metadata)
+	} else {
+		s.handleNonStreamingMessages(w, r, req, provider, metadata)
+	}
+}
+
+// handleNonStreamingMessages completes a Messages request with retry/fallback
+func (s *Server) handleNonStreamingMessages(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	lookup, cached := s.lookupCachedResponse(r.Context(), req)
+	if cached != nil {
+		s.recordCacheHit(w, req, cached, metadata)
+		s.writeMessagesResponse(w, cached.resp, metadata)
+		return
+	}
+
+	resp, err := s.attemptCompletionWithRetryAndFallback(r.Context(), req, provider, metadata)
+	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, writeAnthropicError) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All message attempts failed")
+		writeAnthropicError(w, classifyError(w, err, http.StatusInternalServerError).Status, fmt.Sprintf("Completion failed: %v", err))
+		return
+	}
+	s.storeCachedResponse(lookup, resp)
+	if lookup.cached() {
+		w.Header().Set(CacheHeader, "MISS")
+	}
+
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if resp.Usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+	s.recordUsage(r.Context(), req, metadata, resp.Usage)
+
+	writeUpstreamHeaders(r.Context(), w, metadata)
+	s.writeMessagesResponse(w, resp, metadata)
+}
+
+// writeMessagesResponse writes a completion in Anthropic's message shape
+func (s *Server) writeMessagesResponse(w http.ResponseWriter, resp *types.ChatResponse, metadata *types.RouterMetadata) {
+	out := types.NewMessagesResponse(resp)
+	out.RouterMetadata = metadata
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(out)
+}
+
+// handleStreamingMessages streams a completion as Anthropic server-sent events
+func (s *Server) handleStreamingMessages(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	w, r, cancel := streamContext(w, r)
+	defer cancel()
+
+	chunks, err := s.attemptStreamingWithFallback(r.Context(), req, provider, metadata)
+	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, writeAnthropicError) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All streaming message attempts failed")
+		writeAnthropicError(w, classifyError(w, err, http.StatusInternalServerError).Status, fmt.Sprintf("Streaming failed: %v", err))
+		return
+	}
+
+	writeUpstreamHeaders(r.Context(), w, metadata)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &messagesStream{w: w}
+	stream.send("message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id":              req.ID,
+			"type":            "message",
+			"role":            "assistant",
+			"model":           req.Model,
+			"content":         []interface{}{},
+			"stop_reason":     nil,
+			"stop_sequence":   nil,
+			"usage":           types.MessagesUsage{},
+			"router_metadata": metadata,
+		},
+	})
+
+	var usage *types.Usage
+	stopReason := "end_turn"
+	streamErr := s.forEachChunk(r.Context(), w, chunks, func(chunk *types.ChatChunk) {
+		usage = addUsage(usage, chunk.Usage)
+		for _, choice := range chunk.Choices {
+			if choice.Delta != nil {
+				stream.delta(choice.Delta)
+			}
+			if choice.FinishReason != "" {
+				stopReason = types.StopReason(choice.FinishReason)
+			}
+		}
+	})
+	if streamErr != nil {
+		s.recordUsage(r.Context(), req, metadata, usage)
+		if clientGone(r.Context()) {
+			s.recordStreamCancelled(req, metadata)
+			return
+		}
+		s.recordStreamFailure(req, metadata, streamErr)
+		stream.fail(classifyError(w, streamErr, http.StatusBadGateway).Status, fmt.Sprintf("Streaming failed: %v", streamErr))
+		return
+	}
+	stream.finish()
+
+	final := types.MessagesUsage{}
+	if usage != nil {
+		final = types.MessagesUsage{InputTokens: usage.PromptTokens, OutputTokens: usage.CompletionTokens}
+	}
+	stream.send("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
+		"usage": final,
+	})
+	stream.send("message_stop", map[string]interface{}{"type": "message_stop"})
+
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, usage.PromptTokens, usage.CompletionTokens)
+	}
+	s.recordUsage(r.Context(), req, metadata, usage)
+}
+
+// messagesStream converts chat deltas into Anthropic content block events.
+// Text streams as it arrives; tool calls are reassembled from their
+// fragments and sent as complete tool_use blocks when the stream ends, since
+// parallel calls may interleave.
+type messagesStream struct {
+	w         http.ResponseWriter
+	index     int
+	blockType string // type of the open content block, empty when none is open
+	toolCalls types.ToolCallAccumulator
+}
+
+// delta emits the events for one streamed chat delta
+func (m *messagesStream) delta(delta *types.Message) {
+	if text := types.MessageText(delta.Content); text != "" {
+		if m.blockType != "text" {
+			// Spelled out so the empty text field is not omitted
+			m.openBlock("text", map[string]interface{}{"type": "text", "text": ""})
+		}
+		m.send("content_block_delta", map[string]interface{}{
+			"type":  "content_block_delta",
+			"index": m.index,
+			"delta": map[string]interface{}{"type": "text_delta", "text": text},
+		})
+	}
+
+	m.toolCalls.Add(delta.ToolCalls)
+}
+
+// finish closes the open content block and sends the reassembled tool calls
+func (m *messagesStream) finish() {
+	for _, call := range m.toolCalls.ToolCalls() {
+		m.openBlock("tool_use", types.ContentBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: json.RawMessage("{}")})
+		if call.Function.Arguments != "" {
+			m.send("content_block_delta", map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": m.index,
+				"delta": map[string]interface{}{"type": "input_json_delta", "partial_json": call.Function.Arguments},
+			})
+		}
+	}
+	m.closeBlock()
+}
+
+// fail ends the stream with an error event in place of message_stop
+func (m *messagesStream) fail(statusCode int, message string) {
+	m.send("error", map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    anthropicErrorType(statusCode),
+			"message": message,
+		},
+	})
+}
+
+// openBlock closes any open content block and starts a new one
+func (m *messagesStream) openBlock(blockType string, block interface{}) {
+	if m.blockType != "" {
+		m.closeBlock()
+		m.index++
+	}
+	m.blockType = blockType
+	m.send("content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         m.index,
+		"content_block": block,
+	})
+}
+
+// closeBlock ends the open content block, if any
+func (m *messagesStream) closeBlock() {
+	if m.blockType == "" {
+		return
+	}
+	m.send("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": m.index})
+	m.blockType = ""
+}
+
+// send writes a single server-sent event
+func (m *messagesStream) send(event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(m.w, "event: %s\ndata: %s\n\n", event, payload)
+	if flusher, ok := m.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeAnthropicError writes an error in Anthropic's error response shape
+func writeAnthropicError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    anthropicErrorType(statusCode),
+			"message": message,
+		},
+	})
+}
+
+// anthropicErrorType maps an HTTP status onto Anthropic's error types
+func anthropicErrorType(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
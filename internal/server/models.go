@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// ModelNotAllowedError is returned for a request naming a model outside
+// router.allowed_models
+type ModelNotAllowedError struct {
+	Model string
+}
+
+func (e *ModelNotAllowedError) Error() string {
+	return fmt.Sprintf("model %q is not allowed", e.Model)
+}
+
+// ValidateModelPatterns checks that allowed_models entries are valid glob
+// patterns
+func ValidateModelPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("allowed model pattern must not be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allowed model pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// modelAllowed reports whether model matches one of the configured
+// AllowedModels patterns. An empty list allows every model.
+func (s *Server) modelAllowed(model string) bool {
+	if len(s.config.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range s.config.AllowedModels {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModelAllowed returns a ModelNotAllowedError when model is outside the
+// allowlist. Models are matched by the name the client sent, so aliases must
+// be allowed by their alias.
+func (s *Server) checkModelAllowed(model string) error {
+	if !s.modelAllowed(model) {
+		return &ModelNotAllowedError{Model: model}
+	}
+	return nil
+}
+
+// modelPermitted reports whether model is within the allowlist and the
+// caller's model scopes
+func (s *Server) modelPermitted(ctx context.Context, model string) bool {
+	if !s.modelAllowed(model) {
+		return false
+	}
+	authInfo, ok := security.GetAuthInfo(ctx)
+	return !ok || authInfo.AuthorizeModel(model) == nil
+}
+
+// withModelFilter limits the models routing may promote r's request to, e.g.
+// for a larger context window, to those the caller could request directly
+func (s *Server) withModelFilter(r *http.Request) *http.Request {
+	ctx := r.Context()
+	return r.WithContext(routing.WithModelFilter(ctx, func(model string) bool {
+		return s.modelPermitted(ctx, model)
+	}))
+}
+
+// checkRoutedModel re-checks the model routing settled on: against the
+// allowlist when it substituted another, e.g. the closest known model or an
+// equivalent on another provider, and always against the caller's model
+// scopes, which also covers resolved aliases. Larger-context promotion
+// happens later and is limited by withModelFilter instead.
+func (s *Server) checkRoutedModel(ctx context.Context, metadata *types.RouterMetadata) error {
+	if metadata.ModelSubstituted {
+		if err := s.checkModelAllowed(metadata.Model); err != nil {
+			return err
+		}
+	}
+	if authInfo, ok := security.GetAuthInfo(ctx); ok {
+		return authInfo.AuthorizeModel(metadata.Model)
+	}
+	return nil
+}
+
+// modelEntry is a model in an OpenAI-compatible /v1/models listing
+type modelEntry struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// handleListModels lists the models registered providers serve, in the
+// OpenAI format, leaving out models outside the allowlist
+func (s *Server) handleListModels(w http.ResponseWriter, r *http.Request) {
+	created := s.startTime.Unix()
+	seen := make(map[string]bool)
+	models := []modelEntry{}
+	for name, capabilities := range s.router.GetCapabilities() {
+		for _, model := range capabilities.SupportedModels {
+			if seen[model.Name] || !s.modelAllowed(model.Name) {
+				continue
+			}
+			seen[model.Name] = true
+			models = append(models, modelEntry{ID: model.Name, Object: "model", Created: created, OwnedBy: name})
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object":    "list",
+		"data":      models,
+		"timestamp": time.Now().Unix(),
+	})
+}
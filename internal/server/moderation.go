@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// moderate screens the user content of req before it is routed. Blocked
+// content returns a security.ModerationViolation and is audited as a
+// security violation; a fail-closed moderator that can't be reached returns
+// an error wrapping security.ErrModerationUnavailable.
+func (s *Server) moderate(ctx context.Context, req *types.ChatRequest) error {
+	if s.moderator == nil {
+		return nil
+	}
+
+	var text strings.Builder
+	for _, msg := range req.Messages {
+		if msg.Role != "user" {
+			continue
+		}
+		if content := types.MessageText(msg.Content); content != "" {
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(content)
+		}
+	}
+
+	err := s.moderator.Check(ctx, text.String())
+	if err == nil {
+		return nil
+	}
+
+	var violation *security.ModerationViolation
+	if !errors.As(err, &violation) {
+		s.metrics.RecordRequest("", req.Model, metrics.StatusError, false, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("request_id", req.ID).Error("Content moderation unavailable, rejecting request")
+		return err
+	}
+
+	s.metrics.RecordRequest("", req.Model, metrics.StatusBlocked, false, time.Since(req.Timestamp))
+	if s.securityMiddleware != nil {
+		s.securityMiddleware.LogSecurityEvent(ctx, security.SecurityViolation, "Request blocked by content moderation", map[string]interface{}{
+			"violation_type": "content_moderation",
+			"category":       violation.Category,
+			"score":          violation.Score,
+			"threshold":      violation.Threshold,
+			"model":          req.Model,
+			"request_id":     req.ID,
+		})
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/privacy"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// screenPrompt applies the pre-routing checks to req. PII is redacted first
+// so the moderation API doesn't receive it either.
+func (s *Server) screenPrompt(ctx context.Context, req *types.ChatRequest) error {
+	if err := s.redactPII(ctx, req); err != nil {
+		return err
+	}
+	return s.moderate(ctx, req)
+}
+
+// redactPII replaces PII in req's messages, or returns a
+// privacy.DetectedError when the redactor blocks. Detections are audited as
+// security violations with counts per category, never the matched values.
+func (s *Server) redactPII(ctx context.Context, req *types.ChatRequest) error {
+	if s.redactor == nil {
+		return nil
+	}
+
+	messages, findings := s.redactor.RedactMessages(req.Messages)
+	if findings.Total() == 0 {
+		return nil
+	}
+
+	action := s.redactor.Action()
+	if s.securityMiddleware != nil {
+		s.securityMiddleware.LogSecurityEvent(ctx, security.SecurityViolation, "PII detected in request", map[string]interface{}{
+			"violation_type": "pii_detected",
+			"action":         string(action),
+			"categories":     map[string]int(findings),
+			"total":          findings.Total(),
+			"model":          req.Model,
+			"request_id":     req.ID,
+		})
+	}
+
+	if action == privacy.ActionBlock {
+		s.metrics.RecordRequest("", req.Model, metrics.StatusBlocked, false, time.Since(req.Timestamp))
+		return &privacy.DetectedError{Findings: findings}
+	}
+	req.Messages = messages
+	return nil
+}
@@ -1,19 +1,31 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"runtime"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/cache"
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
 	"github.com/tributary-ai/llm-router-waf/internal/middleware"
 	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/privacy"
 	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
 	"github.com/tributary-ai/llm-router-waf/internal/types"
 )
 
@@ -25,6 +37,16 @@ type Server struct {
 	config           *ServerConfig
 	securityMiddleware *middleware.SecurityMiddleware
 	validationMiddleware *middleware.ValidationMiddleware
+	startTime        time.Time
+	metrics          *metrics.Metrics
+	responseCache    cache.ResponseCache
+	semanticCache    *cache.SemanticCache // nil when disabled
+	idempotencyStore cache.IdempotencyStore
+	usageRecorder    accounting.UsageRecorder
+	usageStore       accounting.UsageStore // backs /v1/usage, nil when unavailable
+	moderator        *security.Moderator   // screens prompts before routing, nil when disabled
+	redactor         *privacy.Redactor     // redacts or blocks PII in prompts, nil when disabled
+	drain            drainState            // in-flight LLM requests, waited on at shutdown
 }
 
 // ServerConfig holds server configuration
@@ -35,14 +57,163 @@ type ServerConfig struct {
 	MaxHeaderBytes int                               `yaml:"max_header_bytes"`
 	Security       *middleware.SecurityMiddlewareConfig `yaml:"security"`
 	Validation     *middleware.ValidationConfig     `yaml:"validation"`
+	BuildInfo      *BuildInfo                        `yaml:"-"`
+	
+	// MessageLimits rejects chat requests with too many or too long messages
+	MessageLimits *MessageLimits `yaml:"message_limits"`
+	
+	// LogRequestBodies writes request bodies to the debug log. Requests that
+	// opt out of telemetry are never body-logged.
+	LogRequestBodies bool `yaml:"log_request_bodies"`
+	
+	// DebugLogging writes redacted request and response bodies to the debug log
+	DebugLogging *DebugLoggingConfig `yaml:"debug_logging"`
+	
+	// Metrics shared with the router; a private instance is created when nil
+	Metrics *metrics.Metrics `yaml:"-"`
+	
+	// MetricTagLabels are the request tag keys, such as tenant_id, the
+	// private metrics instance labels usage with. Other tags stay out of
+	// metrics to bound cardinality.
+	MetricTagLabels []string `yaml:"metric_tag_labels"`
+	
+	// Cache enables response caching for deterministic completions
+	Cache *cache.CacheConfig `yaml:"cache"`
+	
+	// Embedder overrides the routed embedding model of the semantic cache
+	Embedder cache.Embedder `yaml:"-"`
+	
+	// Idempotency enables replay of POSTs retried with an Idempotency-Key
+	Idempotency *cache.IdempotencyConfig `yaml:"idempotency"`
+	
+	// IdempotencyStore overrides the in-memory store from Idempotency when set
+	IdempotencyStore cache.IdempotencyStore `yaml:"-"`
+	
+	// Privacy redacts or blocks PII in prompts before routing
+	Privacy *privacy.Config `yaml:"privacy"`
+	
+	// Moderation screens prompts with a moderation API before routing
+	Moderation *security.ModerationConfig `yaml:"moderation"`
+	
+	// ModerationClient scores content for Moderation; required when it is enabled
+	ModerationClient security.ModerationClient `yaml:"-"`
+	
+	// Accounting writes per-request usage records to a JSONL file
+	Accounting *accounting.Config `yaml:"accounting"`
+	
+	// UsageRecorder overrides the file sink from Accounting when set
+	UsageRecorder accounting.UsageRecorder `yaml:"-"`
+	
+	// UsageStore answers /v1/usage queries and is fed every usage record.
+	// Defaults to an in-memory store loaded from the accounting file.
+	UsageStore accounting.UsageStore `yaml:"-"`
+	
+	// RequestTimeout bounds each completion (router.request_timeout); requests
+	// may ask for less via timeout_seconds. Zero disables the deadline.
+	RequestTimeout time.Duration `yaml:"-"`
+	
+	// StreamHeartbeatInterval is how long a stream may sit idle before an SSE
+	// keepalive comment is sent, so proxies don't drop slow streams. Zero uses
+	// DefaultStreamHeartbeatInterval; negative disables heartbeats.
+	StreamHeartbeatInterval time.Duration `yaml:"stream_heartbeat_interval"`
+	
+	// UpstreamHeaders lists provider response headers, matched
+	// case-insensitively, returned to clients as X-Upstream-* headers and in
+	// router metadata. Empty disables propagation.
+	UpstreamHeaders []string `yaml:"upstream_headers"`
+	
+	// AllowedModels are glob patterns (router.allowed_models) of the models
+	// the router serves; others are rejected with 403 before routing. Empty
+	// allows every model.
+	AllowedModels []string `yaml:"allowed_models"`
+	
+	// Alerts posts operational alerts, such as a provider rejecting its API
+	// key, to a webhook
+	Alerts *AlertConfig `yaml:"alerts"`
+}
+
+// BuildInfo holds build metadata injected at link time
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
 }
 
 // NewServer creates a new server instance
 func NewServer(router *routing.Router, config *ServerConfig, logger *logrus.Logger) (*Server, error) {
 	server := &Server{
-		router: router,
-		logger: logger,
-		config: config,
+		router:    router,
+		logger:    logger,
+		config:    config,
+		startTime: time.Now(),
+		metrics:   config.Metrics,
+	}
+	if server.metrics == nil {
+		server.metrics = metrics.New(config.MetricTagLabels...)
+	}
+	
+	// Initialize response cache if configured
+	if config.Cache != nil && config.Cache.Enabled {
+		server.responseCache = cache.NewLRUCache(config.Cache.MaxEntries)
+	}
+	if config.Cache != nil && config.Cache.Semantic.Enabled {
+		embedder := config.Embedder
+		if embedder == nil {
+			embedder = &routerEmbedder{router: router, model: config.Cache.Semantic.EmbeddingModel}
+		}
+		server.semanticCache = cache.NewSemanticCache(config.Cache.Semantic, embedder)
+	}
+	
+	// Initialize idempotency key store if configured
+	if config.Idempotency != nil && config.Idempotency.Enabled {
+		server.idempotencyStore = config.IdempotencyStore
+		if server.idempotencyStore == nil {
+			server.idempotencyStore = cache.NewMemoryIdempotencyStore(config.Idempotency.MaxEntries)
+		}
+	}
+	
+	// Initialize PII redaction if configured
+	if config.Privacy != nil && config.Privacy.Enabled {
+		redactor, err := privacy.NewRedactor(config.Privacy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize PII redaction: %w", err)
+		}
+		server.redactor = redactor
+	}
+	
+	// Initialize content moderation if configured
+	if config.Moderation != nil && config.Moderation.Enabled {
+		if config.ModerationClient == nil {
+			return nil, fmt.Errorf("content moderation is enabled without a moderation client")
+		}
+		server.moderator = security.NewModerator(config.Moderation, config.ModerationClient, logger)
+	}
+	
+	// Initialize usage accounting sink if configured
+	server.usageRecorder = config.UsageRecorder
+	server.usageStore = config.UsageStore
+	if server.usageRecorder == nil && config.Accounting != nil && config.Accounting.Enabled {
+		if server.usageStore == nil {
+			server.usageStore = newUsageStore(config.Accounting, logger)
+		}
+		recorder, err := accounting.NewFileRecorder(config.Accounting.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize usage accounting: %w", err)
+		}
+		server.usageRecorder = recorder
+	}
+	// Audit and alert on providers rejecting their credentials
+	router.SetAuthFailureHandler(server.providerAuthFailed)
+	
+	if server.usageStore != nil && config.Accounting != nil && len(config.Accounting.SpendLimits) > 0 {
+		router.SetSpendLimiter(accounting.NewSpendLimiter(server.usageStore, config.Accounting.SpendLimits))
+	}
+	if server.usageStore != nil {
+		if server.usageRecorder != nil {
+			server.usageRecorder = accounting.MultiRecorder{server.usageRecorder, server.usageStore}
+		} else {
+			server.usageRecorder = server.usageStore
+		}
 	}
 	
 	// Initialize security middleware if configured
@@ -82,23 +253,47 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
-// Stop stops the HTTP server gracefully
+// Stop stops the HTTP server gracefully. New LLM requests are refused with
+// 503 while those in flight, including long streams, are given until ctx is
+// done to finish.
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping LLM Router server")
 	
+	if err := s.drainRequests(ctx); err != nil {
+		s.logger.WithError(err).Warn("Shutdown deadline reached before in-flight requests finished")
+	}
+	
+	var err error
+	if s.httpServer != nil {
+		err = s.httpServer.Shutdown(ctx)
+	}
+	
 	// Stop security middleware
 	if s.securityMiddleware != nil {
 		s.securityMiddleware.Stop()
 	}
 	
-	return s.httpServer.Shutdown(ctx)
+	if s.usageRecorder != nil {
+		if err := s.usageRecorder.Close(); err != nil {
+			s.logger.WithError(err).Warn("Failed to close usage recorder")
+		}
+	}
+	
+	return err
 }
 
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() *mux.Router {
 	r := mux.NewRouter()
 
-	// Add security middleware first (if enabled)
+	// Continue incoming traces before anything else runs
+	r.Use(tracing.Middleware)
+	
+	// CORS runs ahead of authentication so preflights, which carry no
+	// credentials, are answered and error responses stay readable
+	r.Use(s.corsMiddleware)
+	
+	// Add security middleware (if enabled)
 	if s.securityMiddleware != nil {
 		r.Use(s.securityMiddleware.Handler())
 	}
@@ -110,15 +305,26 @@ func (s *Server) setupRoutes() *mux.Router {
 
 	// Add other middleware
 	r.Use(s.loggingMiddleware)
-	r.Use(s.corsMiddleware)
+	if s.config.DebugLogging != nil && s.config.DebugLogging.Enabled {
+		r.Use(s.debugLoggingMiddleware)
+	}
 	r.Use(s.contentTypeMiddleware)
 
 	// API routes
 	api := r.PathPrefix("/v1").Subrouter()
+	
+	// Replay retried POSTs that carry an Idempotency-Key
+	api.Use(s.idempotencyMiddleware)
 
 	// OpenAI compatible endpoints
 	api.HandleFunc("/chat/completions", s.handleChatCompletion).Methods("POST")
 	api.HandleFunc("/completions", s.handleCompletion).Methods("POST")
+	api.HandleFunc("/embeddings", s.handleEmbeddings).Methods("POST")
+	api.HandleFunc("/rerank", s.handleRerank).Methods("POST")
+	api.HandleFunc("/batches", s.handleCreateBatch).Methods("POST")
+	api.HandleFunc("/batches", s.handleListBatches).Methods("GET")
+	api.HandleFunc("/batches/{id}", s.handleGetBatch).Methods("GET")
+	api.HandleFunc("/batches/{id}/cancel", s.handleCancelBatch).Methods("POST")
 
 	// Anthropic compatible endpoints
 	api.HandleFunc("/messages", s.handleMessages).Methods("POST")
@@ -126,19 +332,48 @@ func (s *Server) setupRoutes() *mux.Router {
 	// Router management endpoints
 	api.HandleFunc("/providers", s.handleListProviders).Methods("GET")
 	api.HandleFunc("/providers/{name}", s.handleGetProvider).Methods("GET")
+	api.HandleFunc("/providers/{name}", s.handleDeregisterProvider).Methods("DELETE")
+	api.HandleFunc("/providers/{name}/health/recheck", s.handleRecheckProviderHealth).Methods("POST")
+	api.HandleFunc("/providers/{name}/maintenance", s.handleProviderMaintenance).Methods("POST")
 	api.HandleFunc("/health", s.handleHealthCheck).Methods("GET")
 	api.HandleFunc("/health/{name}", s.handleProviderHealth).Methods("GET")
 	api.HandleFunc("/capabilities", s.handleCapabilities).Methods("GET")
+	api.HandleFunc("/models", s.handleListModels).Methods("GET")
 	api.HandleFunc("/routing/decision", s.handleRoutingDecision).Methods("POST")
+	api.HandleFunc("/estimate", s.handleEstimate).Methods("POST")
+	
+	// Usage reporting (only when usage is being recorded)
+	if s.usageStore != nil {
+		api.HandleFunc("/usage", s.handleUsage).Methods("GET")
+	}
+
+	// JWT issuance (only when authentication is configured)
+	if s.authProvider() != nil {
+		api.HandleFunc("/auth/token", s.handleIssueToken).Methods("POST")
+		api.HandleFunc("/auth/refresh", s.handleRefreshToken).Methods("POST")
+	}
 
 	// Health check endpoint (no /v1 prefix)
 	r.HandleFunc("/health", s.handleHealthCheck).Methods("GET")
 	
+	// Orchestrator probes: liveness ignores provider health, readiness needs
+	// one healthy provider
+	r.HandleFunc("/livez", s.handleLiveness).Methods("GET")
+	r.HandleFunc("/readyz", s.handleReadiness).Methods("GET")
+	
+	// Build and runtime information
+	r.HandleFunc("/version", s.handleVersion).Methods("GET")
+	
 	// Metrics endpoint for Prometheus scraping
 	r.HandleFunc("/metrics", s.handleMetrics).Methods("GET")
 	
 	// Swagger UI documentation endpoints
 	s.setupSwaggerRoutes(r)
+	
+	// Match preflights for every path so the middleware chain answers them
+	r.PathPrefix("/").Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
 
 	return r
 }
@@ -148,51 +383,105 @@ func (s *Server) setupRoutes() *mux.Router {
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		r, telemetry := security.RequestTelemetry(r)
+		
+		if s.config.LogRequestBodies && !telemetry.OptedOut() && r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			if err == nil {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				if len(body) > 0 {
+					s.logger.WithFields(logrus.Fields{
+						"method": r.Method,
+						"path":   r.URL.Path,
+						"body":   truncateBody(body, maxLoggedBodyBytes),
+					}).Debug("HTTP request body")
+				}
+			}
+		}
 		
 		// Create a custom response writer to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: 200}
 		
 		next.ServeHTTP(wrapped, r)
 		
-		s.logger.WithFields(logrus.Fields{
+		// The opt-out may have been set by authentication further in
+		optedOut := telemetry.OptedOut()
+		s.metrics.RecordHTTPRequest(r.Method, wrapped.statusCode, requestClientIP(r), optedOut)
+		
+		fields := logrus.Fields{
 			"method":      r.Method,
 			"path":        r.URL.Path,
 			"status":      wrapped.statusCode,
 			"duration_ms": time.Since(start).Milliseconds(),
-			"user_agent":  r.UserAgent(),
-			"remote_addr": r.RemoteAddr,
-		}).Info("HTTP request")
+		}
+		if optedOut {
+			fields["telemetry_opt_out"] = true
+		} else {
+			fields["user_agent"] = r.UserAgent()
+			fields["remote_addr"] = r.RemoteAddr
+		}
+		s.logger.WithFields(fields).Info("HTTP request")
 	})
 }
 
+// corsMiddleware applies the configured CORS policy, allowing any origin
+// when none is configured
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-		
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		
-		next.ServeHTTP(w, r)
-	})
+	var corsConfig *middleware.CORSConfig
+	if s.config.Security != nil {
+		corsConfig = s.config.Security.CORS
+	}
+	return middleware.CORS(corsConfig)(next)
 }
 
 func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" || r.Method == "PUT" {
 			contentType := r.Header.Get("Content-Type")
-			if contentType != "application/json" && contentType != "" {
-				s.writeErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
-				return
+			if contentType != "" {
+				// Compare the media type only so charset-qualified JSON is accepted
+				if security.MediaType(contentType) != "application/json" {
+					s.writeErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+					return
+				}
+				
+				if _, params, err := mime.ParseMediaType(contentType); err == nil {
+					if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "utf8") {
+						s.writeErrorResponse(w, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported charset %s: request body must be UTF-8", charset))
+						return
+					}
+				}
+			}
+			
+			// Reject non-UTF-8 bodies up front instead of failing deep in decoding
+			if r.Body != nil {
+				body, err := io.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					s.writeErrorResponse(w, bodyReadStatus(err), fmt.Sprintf("Failed to read request body: %v", err))
+					return
+				}
+				if !utf8.Valid(body) {
+					s.writeErrorResponse(w, http.StatusBadRequest, "Request body contains invalid UTF-8")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
 			}
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// bodyReadStatus returns the status for a failed request body read: 413
+// when the body ran past the validation size limit, 400 otherwise
+func bodyReadStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
 // Handlers
 
 // handleChatCompletion handles OpenAI-compatible chat completion requests
@@ -202,17 +491,59 @@ func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
 		return
 	}
+	if err := req.Priority.Validate(); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.N != nil && *req.N < 1 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "n must be at least 1")
+		return
+	}
+	if err := s.checkModelAllowed(req.Model); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
+	
+	// Admission depends on the request's priority, so it follows decoding
+	release, ok := s.acquireSlot(w, req.Priority, s.writeErrorResponse)
+	if !ok {
+		return
+	}
+	defer release()
+	
+	if violations := s.config.MessageLimits.check(&req); len(violations) > 0 {
+		s.writeLimitViolations(w, violations)
+		return
+	}
 
 	// Generate request ID if not provided
 	if req.ID == "" {
 		req.ID = fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
 	}
 	req.Timestamp = time.Now()
+	applyTenantHeader(r, &req)
+
+	r, cancel := s.withRequestTimeout(r, &req)
+	defer cancel()
+	r = s.withUpstreamCapture(r)
+	r = s.withModelFilter(r)
+
+	// Screen the prompt before it reaches any provider
+	if err := s.screenPrompt(r.Context(), &req); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
 
 	// Route the request
 	metadata, provider, err := s.router.Route(r.Context(), &req)
 	if err != nil {
-		s.writeErrorResponse(w, http.StatusServiceUnavailable, fmt.Sprintf("Routing failed: %v", err))
+		status := routingMetricStatus(err)
+		s.metrics.RecordRequest("", req.Model, status, false, time.Since(req.Timestamp))
+		s.writeRoutingError(w, err)
+		return
+	}
+	if err := s.checkRoutedModel(r.Context(), metadata); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
 		return
 	}
 
@@ -224,33 +555,153 @@ func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleCompletion handles legacy OpenAI completion requests (maps to chat completion)
-func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
-	// For simplicity, redirect to chat completion
-	// In production, you'd implement proper completion endpoint
-	s.handleChatCompletion(w, r)
+// handleEmbeddings handles OpenAI-compatible embedding requests
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	release, ok := s.acquireSlot(w, types.PriorityNormal, s.writeErrorResponse)
+	if !ok {
+		return
+	}
+	defer release()
+	
+	var req types.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if _, err := req.Inputs(); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.checkModelAllowed(req.Model); err != nil {
+		s.writeFailure(w, err, http.StatusForbidden, "Request rejected")
+		return
+	}
+
+	// Generate request ID if not provided
+	if req.ID == "" {
+		req.ID = fmt.Sprintf("embd-%d", time.Now().UnixNano())
+	}
+	req.Timestamp = time.Now()
+
+	// Route the request
+	metadata, provider, err := s.router.RouteEmbedding(r.Context(), &req)
+	if err != nil {
+		status := routingMetricStatus(err)
+		s.metrics.RecordRequest("", req.Model, status, false, time.Since(req.Timestamp))
+		s.writeRoutingError(w, err)
+		return
+	}
+
+	resp, err := provider.CreateEmbedding(r.Context(), &req)
+	if err != nil {
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, false, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("Embedding request failed")
+		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Embedding failed: %v", err))
+		return
+	}
+
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, false, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if resp.Usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, resp.Usage.PromptTokens, 0)
+	}
+
+	// Add routing metadata to response
+	resp.RouterMetadata = metadata
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
 }
 
-// handleMessages handles Anthropic-compatible message requests
-func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
-	// For now, treat as chat completion
-	// In production, you'd implement Anthropic-specific handling
-	s.handleChatCompletion(w, r)
+// handleRerank ranks documents by relevance to a query
+func (s *Server) handleRerank(w http.ResponseWriter, r *http.Request) {
+	release, ok := s.acquireSlot(w, types.PriorityNormal, s.writeErrorResponse)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var req types.RerankRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if len(req.Documents) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "documents must not be empty")
+		return
+	}
+	if req.TopN < 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "top_n must not be negative")
+		return
+	}
+
+	// Generate request ID if not provided
+	if req.ID == "" {
+		req.ID = fmt.Sprintf("rerank-%d", time.Now().UnixNano())
+	}
+	req.Timestamp = time.Now()
+
+	// Route the request
+	metadata, provider, err := s.router.RouteRerank(r.Context(), &req)
+	if err != nil {
+		status := routingMetricStatus(err)
+		s.metrics.RecordRequest("", "", status, false, time.Since(req.Timestamp))
+		s.writeRoutingError(w, err)
+		return
+	}
+
+	results, err := provider.Rerank(r.Context(), req.Query, req.Documents)
+	if err != nil {
+		s.metrics.RecordRequest(metadata.Provider, "", metrics.StatusError, false, time.Since(req.Timestamp))
+		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("Rerank request failed")
+		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Rerank failed: %v", err))
+		return
+	}
+	s.metrics.RecordRequest(metadata.Provider, "", metrics.StatusSuccess, false, time.Since(req.Timestamp))
+
+	if req.TopN > 0 && len(results) > req.TopN {
+		results = results[:req.TopN]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(&types.RerankResponse{
+		Object:         "list",
+		Results:        results,
+		RouterMetadata: metadata,
+	})
 }
 
 // handleNonStreamingCompletion handles non-streaming chat completions
 func (s *Server) handleNonStreamingCompletion(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	lookup, cached := s.lookupCachedResponse(r.Context(), req)
+	if cached != nil {
+		s.writeCachedResponse(w, req, cached, metadata)
+		return
+	}
+
 	resp, err := provider.ChatCompletion(r.Context(), req)
 	if err != nil {
 		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("Chat completion failed")
-		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Completion failed: %v", err))
+		s.writeFailure(w, err, http.StatusInternalServerError, "Completion failed")
 		return
 	}
+	s.storeCachedResponse(lookup, resp)
+	if lookup.cached() {
+		w.Header().Set(CacheHeader, "MISS")
+	}
+	s.recordUsage(r.Context(), req, metadata, resp.Usage)
 
 	// Add routing metadata to response
 	if resp.RouterMetadata == nil {
 		resp.RouterMetadata = metadata
 	}
+	writeUpstreamHeaders(r.Context(), w, resp.RouterMetadata)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -259,14 +710,18 @@ func (s *Server) handleNonStreamingCompletion(w http.ResponseWriter, r *http.Req
 
 // handleStreamingCompletion handles streaming chat completions
 func (s *Server) handleStreamingCompletion(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider providers.LLMProvider, metadata *types.RouterMetadata) {
+	w, r, cancel := streamContext(w, r)
+	defer cancel()
+
 	chunks, err := provider.StreamCompletion(r.Context(), req)
 	if err != nil {
 		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("Streaming completion failed")
-		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Streaming failed: %v", err))
+		s.writeFailure(w, err, http.StatusInternalServerError, "Streaming failed")
 		return
 	}
 
 	// Set up SSE headers
+	writeUpstreamHeaders(r.Context(), w, metadata)
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -286,20 +741,30 @@ func (s *Server) handleStreamingCompletion(w http.ResponseWriter, r *http.Reques
 	w.(http.Flusher).Flush()
 
 	// Stream chunks
-	for chunk := range chunks {
+	var usage *types.Usage
+	streamErr := s.forEachChunk(r.Context(), w, chunks, func(chunk *types.ChatChunk) {
+		usage = addUsage(usage, chunk.Usage)
 		data, err := json.Marshal(chunk)
 		if err != nil {
 			s.logger.WithError(err).Error("Failed to marshal chunk")
-			continue
+			return
 		}
 		
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		w.(http.Flusher).Flush()
-	}
+	})
 
-	// Send final chunk
-	fmt.Fprintf(w, "data: [DONE]\n\n")
-	w.(http.Flusher).Flush()
+	s.recordUsage(r.Context(), req, metadata, usage)
+	if streamErr != nil && clientGone(r.Context()) {
+		s.recordStreamCancelled(req, metadata)
+		return
+	}
+	if streamErr != nil {
+		s.recordStreamFailure(req, metadata, streamErr)
+		writeStreamError(w, streamErr)
+		return
+	}
+	s.writeStreamSummary(w, req, metadata, usage)
 }
 
 // handleNonStreamingCompletionWithRetry handles non-streaming completions with retry/fallback
@@ -307,16 +772,38 @@ func (s *Server) handleNonStreamingCompletionWithRetry(w http.ResponseWriter, r
 	var resp *types.ChatResponse
 	var err error
 	
+	lookup, cached := s.lookupCachedResponse(r.Context(), req)
+	if cached != nil {
+		s.writeCachedResponse(w, req, cached, metadata)
+		return
+	}
+	
 	// Perform actual completion with retry logic
 	resp, err = s.attemptCompletionWithRetryAndFallback(r.Context(), req, initialProvider, metadata)
 	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, s.writeErrorResponse) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
 		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All completion attempts failed")
-		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Completion failed: %v", err))
+		s.writeFailure(w, err, http.StatusInternalServerError, "Completion failed")
 		return
 	}
+	s.storeCachedResponse(lookup, resp)
+	if lookup.cached() {
+		w.Header().Set(CacheHeader, "MISS")
+	}
+
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if resp.Usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	}
+	s.recordUsage(r.Context(), req, metadata, resp.Usage)
 
 	// Add routing metadata to response
 	resp.RouterMetadata = metadata
+	writeUpstreamHeaders(r.Context(), w, metadata)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -325,18 +812,27 @@ func (s *Server) handleNonStreamingCompletionWithRetry(w http.ResponseWriter, r
 
 // handleStreamingCompletionWithRetry handles streaming completions with retry/fallback
 func (s *Server) handleStreamingCompletionWithRetry(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, initialProvider providers.LLMProvider, metadata *types.RouterMetadata) {
+	// Cancel the provider stream as soon as the client goes away
+	w, r, cancel := streamContext(w, r)
+	defer cancel()
+
 	// For streaming, we'll use the first successful provider (no mid-stream retry)
 	var chunks <-chan *types.ChatChunk
 	var err error
 	
 	chunks, err = s.attemptStreamingWithFallback(r.Context(), req, initialProvider, metadata)
 	if err != nil {
+		if s.writeTimeoutError(w, r, req, metadata.Provider, s.writeErrorResponse) {
+			return
+		}
+		s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
 		s.logger.WithError(err).WithField("provider", metadata.Provider).Error("All streaming attempts failed")
-		s.writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Streaming failed: %v", err))
+		s.writeFailure(w, err, http.StatusInternalServerError, "Streaming failed")
 		return
 	}
 
 	// Set up SSE headers
+	writeUpstreamHeaders(r.Context(), w, metadata)
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -356,17 +852,64 @@ func (s *Server) handleStreamingCompletionWithRetry(w http.ResponseWriter, r *ht
 	w.(http.Flusher).Flush()
 
 	// Stream chunks
-	for chunk := range chunks {
+	var usage *types.Usage
+	streamErr := s.forEachChunk(r.Context(), w, chunks, func(chunk *types.ChatChunk) {
+		usage = addUsage(usage, chunk.Usage)
 		data, err := json.Marshal(chunk)
 		if err != nil {
 			s.logger.WithError(err).Error("Failed to marshal chunk")
-			continue
+			return
 		}
 		
 		fmt.Fprintf(w, "data: %s\n\n", data)
 		w.(http.Flusher).Flush()
+	})
+
+	s.recordUsage(r.Context(), req, metadata, usage)
+	if streamErr != nil && clientGone(r.Context()) {
+		s.recordStreamCancelled(req, metadata)
+		return
+	}
+	if streamErr != nil {
+		s.recordStreamFailure(req, metadata, streamErr)
+		writeStreamError(w, streamErr)
+		return
+	}
+	s.writeStreamSummary(w, req, metadata, usage)
+	
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusSuccess, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.metrics.RecordEstimatedCost(metadata.Provider, metadata.EstimatedCost)
+	if usage != nil {
+		s.metrics.RecordTokens(metadata.Provider, usage.PromptTokens, usage.CompletionTokens)
 	}
+}
+
+// recordStreamFailure logs and counts a stream that failed part way; the
+// caller ends the stream with an error event
+func (s *Server) recordStreamFailure(req *types.ChatRequest, metadata *types.RouterMetadata, err error) {
+	s.metrics.RecordRequest(metadata.Provider, req.Model, metrics.StatusError, metadata.FallbackUsed, time.Since(req.Timestamp))
+	s.logger.WithError(err).WithField("provider", metadata.Provider).Error("Stream failed part way")
+}
 
+// writeStreamSummary ends a completion stream with a metadata chunk carrying
+// the total usage and actual cost, followed by [DONE]. Usage is omitted when
+// the provider reported none.
+func (s *Server) writeStreamSummary(w http.ResponseWriter, req *types.ChatRequest, metadata *types.RouterMetadata, usage *types.Usage) {
+	summaryChunk := &types.ChatChunk{
+		ID:             req.ID,
+		Object:         "chat.completion.chunk",
+		Created:        time.Now().Unix(),
+		Model:          req.Model,
+		Choices:        []types.ChoiceChunk{},
+		Usage:          usage,
+		RouterMetadata: metadata,
+	}
+	if data, err := json.Marshal(summaryChunk); err != nil {
+		s.logger.WithError(err).Error("Failed to marshal stream summary chunk")
+	} else {
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	
 	// Send final chunk
 	fmt.Fprintf(w, "data: [DONE]\n\n")
 	w.(http.Flusher).Flush()
@@ -374,27 +917,29 @@ func (s *Server) handleStreamingCompletionWithRetry(w http.ResponseWriter, r *ht
 
 // attemptCompletionWithRetryAndFallback performs completion with retry and fallback logic
 func (s *Server) attemptCompletionWithRetryAndFallback(ctx context.Context, req *types.ChatRequest, initialProvider providers.LLMProvider, metadata *types.RouterMetadata) (*types.ChatResponse, error) {
-	// Try initial provider with retries
-	resp, err := s.attemptCompletionWithRetry(ctx, req, initialProvider, metadata.Provider, req.RetryConfig)
-	if err == nil {
-		return resp, nil
+	// Try initial provider with retries; failed attempts are recorded in metadata
+	resp, err := s.router.ExecuteCompletion(ctx, req, metadata.Provider, initialProvider, metadata)
+	if err != nil && req.FallbackConfig != nil && req.FallbackConfig.Enabled {
+		// Try fallback if configured
+		resp, err = s.attemptCompletionFallback(ctx, req, metadata)
 	}
-	
-	// Add initial provider to failed list
-	metadata.FailedProviders = append(metadata.FailedProviders, metadata.Provider)
-	
-	// Try fallback if configured
-	if req.FallbackConfig != nil && req.FallbackConfig.Enabled {
-		return s.attemptCompletionFallback(ctx, req, metadata)
+	if metadata.RetryBudgetExhausted {
+		s.metrics.RecordRetryBudgetExhausted()
+	}
+	if err != nil {
+		return nil, err
 	}
 	
-	return nil, err
+	if err := s.router.TransformResponse(ctx, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 // attemptStreamingWithFallback performs streaming with fallback (no mid-stream retry)
 func (s *Server) attemptStreamingWithFallback(ctx context.Context, req *types.ChatRequest, initialProvider providers.LLMProvider, metadata *types.RouterMetadata) (<-chan *types.ChatChunk, error) {
 	// Try initial provider
-	chunks, err := initialProvider.StreamCompletion(ctx, req)
+	chunks, err := s.router.StreamCompletion(ctx, req, metadata.Provider, initialProvider)
 	if err == nil {
 		return chunks, nil
 	}
@@ -410,177 +955,42 @@ func (s *Server) attemptStreamingWithFallback(ctx context.Context, req *types.Ch
 	return nil, err
 }
 
-// attemptCompletionWithRetry performs completion with retry logic for a single provider
-func (s *Server) attemptCompletionWithRetry(ctx context.Context, req *types.ChatRequest, provider providers.LLMProvider, providerName string, retryConfig *types.RetryConfig) (*types.ChatResponse, error) {
-	maxAttempts := 1
-	if retryConfig != nil {
-		maxAttempts = retryConfig.MaxAttempts
-		if maxAttempts < 1 {
-			maxAttempts = 1
-		}
-	}
-	
-	var lastError error
-	
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		// Apply backoff delay for retries
-		if attempt > 1 && retryConfig != nil {
-			delay := s.calculateRetryDelay(retryConfig, attempt-1)
-			s.logger.WithFields(logrus.Fields{
-				"provider": providerName,
-				"attempt":  attempt,
-				"delay_ms": delay.Milliseconds(),
-			}).Debug("Retrying completion after backoff")
-			
-			select {
-			case <-time.After(delay):
-				// Continue with retry
-			case <-ctx.Done():
-				return nil, fmt.Errorf("request cancelled during retry: %w", ctx.Err())
-			}
-		}
-		
-		// Attempt completion
-		resp, err := provider.ChatCompletion(ctx, req)
-		if err == nil {
-			return resp, nil
-		}
-		
-		lastError = err
-		s.logger.WithFields(logrus.Fields{
-			"provider": providerName,
-			"attempt":  attempt,
-			"error":    err.Error(),
-		}).Warn("Completion attempt failed")
-		
-		// Check if error is retryable
-		if retryConfig != nil && !s.isRetryableError(err, retryConfig) {
-			s.logger.WithField("provider", providerName).Debug("Error not retryable, stopping retries")
-			break
-		}
-	}
-	
-	return nil, lastError
-}
-
-// attemptCompletionFallback tries fallback providers for completion
+// attemptCompletionFallback tries fallback providers for completion, each
+// selected by the router with the checks of routing-time fallback
 func (s *Server) attemptCompletionFallback(ctx context.Context, req *types.ChatRequest, metadata *types.RouterMetadata) (*types.ChatResponse, error) {
-	// Get fallback providers from router (this would need to be implemented)
-	fallbackProviders := s.getFallbackProviders(req, metadata)
-	
-	for _, providerName := range fallbackProviders {
-		if contains(metadata.FailedProviders, providerName) {
-			continue
-		}
-		
-		provider, exists := s.router.GetProvider(providerName)
-		if !exists {
-			continue
+	for {
+		provider, err := s.router.NextFallback(ctx, req, metadata)
+		if err != nil {
+			return nil, err
 		}
 		
-		s.logger.WithField("fallback_provider", providerName).Info("Trying fallback provider")
+		s.logger.WithField("fallback_provider", metadata.Provider).Info("Trying fallback provider")
 		
-		resp, err := s.attemptCompletionWithRetry(ctx, req, provider, providerName, req.RetryConfig)
+		resp, err := s.router.ExecuteCompletion(ctx, req, metadata.Provider, provider, metadata)
 		if err == nil {
-			metadata.Provider = providerName
-			metadata.FallbackUsed = true
-			metadata.RoutingReason = append(metadata.RoutingReason, fmt.Sprintf("Fallback to %s", providerName))
 			return resp, nil
 		}
-		
-		metadata.FailedProviders = append(metadata.FailedProviders, providerName)
 	}
-	
-	return nil, fmt.Errorf("all fallback providers failed")
 }
 
-// attemptStreamingFallback tries fallback providers for streaming
+// attemptStreamingFallback tries fallback providers for streaming, each
+// selected by the router with the checks of routing-time fallback
 func (s *Server) attemptStreamingFallback(ctx context.Context, req *types.ChatRequest, metadata *types.RouterMetadata) (<-chan *types.ChatChunk, error) {
-	fallbackProviders := s.getFallbackProviders(req, metadata)
-	
-	for _, providerName := range fallbackProviders {
-		if contains(metadata.FailedProviders, providerName) {
-			continue
-		}
-		
-		provider, exists := s.router.GetProvider(providerName)
-		if !exists {
-			continue
+	for {
+		provider, err := s.router.NextFallback(ctx, req, metadata)
+		if err != nil {
+			return nil, err
 		}
 		
-		s.logger.WithField("fallback_provider", providerName).Info("Trying fallback streaming provider")
+		s.logger.WithField("fallback_provider", metadata.Provider).Info("Trying fallback streaming provider")
 		
-		chunks, err := provider.StreamCompletion(ctx, req)
+		chunks, err := s.router.StreamCompletion(ctx, req, metadata.Provider, provider)
 		if err == nil {
-			metadata.Provider = providerName
-			metadata.FallbackUsed = true
-			metadata.RoutingReason = append(metadata.RoutingReason, fmt.Sprintf("Fallback to %s", providerName))
 			return chunks, nil
 		}
 		
-		metadata.FailedProviders = append(metadata.FailedProviders, providerName)
-	}
-	
-	return nil, fmt.Errorf("all streaming fallback providers failed")
-}
-
-// calculateRetryDelay calculates delay for retry attempts
-func (s *Server) calculateRetryDelay(config *types.RetryConfig, attempt int) time.Duration {
-	var delay time.Duration
-	
-	switch config.BackoffType {
-	case "exponential":
-		multiplier := float64(uint(1) << uint(attempt)) // 2^attempt
-		delay = time.Duration(float64(config.BaseDelay) * multiplier)
-	case "linear":
-		delay = time.Duration(int64(config.BaseDelay) * int64(attempt+1))
-	default:
-		// Default to exponential
-		multiplier := float64(uint(1) << uint(attempt))
-		delay = time.Duration(float64(config.BaseDelay) * multiplier)
-	}
-	
-	// Cap at MaxDelay
-	if config.MaxDelay > 0 && delay > config.MaxDelay {
-		delay = config.MaxDelay
-	}
-	
-	return delay
-}
-
-// isRetryableError checks if an error should be retried
-func (s *Server) isRetryableError(err error, config *types.RetryConfig) bool {
-	if len(config.RetryableErrors) == 0 {
-		// Default retryable errors
-		errStr := err.Error()
-		return strings.Contains(errStr, "timeout") ||
-			strings.Contains(errStr, "connection") ||
-			strings.Contains(errStr, "unavailable") ||
-			strings.Contains(errStr, "rate limit")
-	}
-	
-	errStr := err.Error()
-	for _, retryableError := range config.RetryableErrors {
-		if strings.Contains(errStr, retryableError) {
-			return true
-		}
-	}
-	return false
-}
-
-// getFallbackProviders gets list of fallback providers (placeholder)
-func (s *Server) getFallbackProviders(req *types.ChatRequest, metadata *types.RouterMetadata) []string {
-	// This is a simplified implementation
-	// In practice, this should use the router's fallback chain logic
-	providers := s.router.ListProviders()
-	var fallbacks []string
-	
-	for _, provider := range providers {
-		if provider != metadata.Provider {
-			fallbacks = append(fallbacks, provider)
-		}
+		metadata.FailedProviders = append(metadata.FailedProviders, metadata.Provider)
 	}
-	return fallbacks
 }
 
 // contains checks if slice contains value (utility function)
@@ -642,6 +1052,10 @@ func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"status":    func() string { if overallHealthy { return "healthy" } else { return "degraded" } }(),
 		"providers": health,
+		"in_flight_requests":      s.router.InFlight(),
+		"max_concurrent_requests": s.router.MaxConcurrentRequests(),
+		"in_flight_by_priority":   s.router.InFlightByPriority(),
+		"provider_in_flight":      s.router.ProviderInFlight(),
 		"timestamp": time.Now().Unix(),
 	}
 	
@@ -650,11 +1064,60 @@ func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 		statusCode = http.StatusServiceUnavailable
 	}
 	
+	// Load balancers stop sending traffic once the server starts draining
+	if s.draining() {
+		response["status"] = "draining"
+		statusCode = http.StatusServiceUnavailable
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleLiveness reports that the process is up. Provider health doesn't
+// affect it, so an upstream outage doesn't get the pod restarted.
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	status, statusCode := "ok", http.StatusOK
+	if s.draining() {
+		status, statusCode = "draining", http.StatusServiceUnavailable
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// handleReadiness reports whether the router can serve requests: at least
+// one provider is healthy and out of maintenance, and it isn't draining
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	healthy := 0
+	for _, status := range s.router.GetHealthStatus() {
+		if status.Status == "healthy" && !status.Maintenance {
+			healthy++
+		}
+	}
+	
+	status, statusCode := "ready", http.StatusOK
+	switch {
+	case s.draining():
+		status, statusCode = "draining", http.StatusServiceUnavailable
+	case healthy == 0:
+		status, statusCode = "not_ready", http.StatusServiceUnavailable
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":            status,
+		"healthy_providers": healthy,
+		"timestamp":         time.Now().Unix(),
+	})
+}
+
 // handleProviderHealth returns health status for specific provider
 func (s *Server) handleProviderHealth(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -677,6 +1140,100 @@ func (s *Server) handleProviderHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleRecheckProviderHealth runs a provider's health check immediately
+func (s *Server) handleRecheckProviderHealth(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	
+	status, err := s.router.RecheckHealth(r.Context(), name)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Provider %s not found", name))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"provider":  name,
+		"status":    status,
+		"timestamp": time.Now().Unix(),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDeregisterProvider removes a provider from routing without a restart
+func (s *Server) handleDeregisterProvider(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	
+	if err := s.router.DeregisterProvider(name); err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Provider %s not found", name))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"provider":     name,
+		"deregistered": true,
+		"timestamp":    time.Now().Unix(),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleProviderMaintenance takes a provider out of rotation or returns it
+func (s *Server) handleProviderMaintenance(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if req.Enabled == nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	
+	if err := s.router.SetMaintenance(name, *req.Enabled); err != nil {
+		s.writeErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Provider %s not found", name))
+		return
+	}
+	
+	response := map[string]interface{}{
+		"provider":    name,
+		"maintenance": *req.Enabled,
+		"timestamp":   time.Now().Unix(),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleVersion returns build and runtime information
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	buildInfo := s.config.BuildInfo
+	if buildInfo == nil {
+		buildInfo = &BuildInfo{Version: "unknown", GitCommit: "unknown", BuildDate: "unknown"}
+	}
+	
+	uptime := time.Since(s.startTime)
+	
+	response := map[string]interface{}{
+		"version":        buildInfo.Version,
+		"git_commit":     buildInfo.GitCommit,
+		"build_date":     buildInfo.BuildDate,
+		"go_version":     runtime.Version(),
+		"start_time":     s.startTime.UTC().Format(time.RFC3339),
+		"uptime":         uptime.Round(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"timestamp":      time.Now().Unix(),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleCapabilities returns capabilities of all providers
 func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
 	capabilities := s.router.GetCapabilities()
@@ -705,9 +1262,10 @@ func (s *Server) handleRoutingDecision(w http.ResponseWriter, r *http.Request) {
 	req.Timestamp = time.Now()
 
 	// Get routing decision
-	metadata, _, err := s.router.Route(r.Context(), &req)
+	// The decision is only previewed, so it mustn't spend rate-limit budget
+	metadata, _, err := s.router.Route(routing.WithDryRun(r.Context()), &req)
 	if err != nil {
-		s.writeErrorResponse(w, http.StatusServiceUnavailable, fmt.Sprintf("Routing failed: %v", err))
+		s.writeRoutingError(w, err)
 		return
 	}
 
@@ -715,22 +1273,35 @@ func (s *Server) handleRoutingDecision(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metadata)
 }
 
-// Helper functions
-
-func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// handleEstimate compares the cost of a request across all healthy providers
+// without executing it
+func (s *Server) handleEstimate(w http.ResponseWriter, r *http.Request) {
+	var req types.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
 	
-	errorResp := map[string]interface{}{
-		"error": map[string]interface{}{
-			"message": message,
-			"type":    "api_error",
-			"code":    statusCode,
-		},
-		"timestamp": time.Now().Unix(),
+	comparison, err := s.router.EstimateCosts(r.Context(), &req)
+	if err != nil {
+		s.writeRoutingError(w, err)
+		return
 	}
 	
-	json.NewEncoder(w).Encode(errorResp)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// Helper functions
+
+// routingMetricStatus returns the metrics status recorded for a routing failure
+func routingMetricStatus(err error) string {
+	var limitErr *routing.RateLimitError
+	var spendErr *accounting.SpendLimitError
+	if errors.As(err, &limitErr) || errors.As(err, &spendErr) {
+		return metrics.StatusRateLimited
+	}
+	return metrics.StatusRoutingError
 }
 
 // responseWriter wraps http.ResponseWriter to capture status code
@@ -753,125 +1324,10 @@ func (rw *responseWriter) Flush() {
 
 // handleMetrics serves Prometheus metrics endpoint
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	
-	// Basic metrics in Prometheus format
-	// This is a minimal implementation for demo purposes
-	// In production, this should use the prometheus/client_golang library
-	
-	// Get provider health status
-	healthStatus := s.router.GetHealthStatus()
-	
-	// Generate basic metrics
-	metrics := "# HELP llm_router_provider_health Provider health status (1=healthy, 0=unhealthy)\n"
-	metrics += "# TYPE llm_router_provider_health gauge\n"
+	// Refresh provider health gauges at scrape time
+	for provider, health := range s.router.GetHealthStatus() {
+		s.metrics.SetProviderHealth(provider, health.Status == "healthy")
+	}
 	
-	for provider, health := range healthStatus {
-		status := 0
-		if health.Status == "healthy" {
-			status = 1
-		}
-		metrics += fmt.Sprintf("llm_router_provider_health{service=\"llm-router\",provider=\"%s\"} %d\n", provider, status)
-	}
-	
-	// Active connections (mock data for now)
-	metrics += "\n# HELP llm_router_active_connections Current number of active connections\n"
-	metrics += "# TYPE llm_router_active_connections gauge\n"
-	metrics += "llm_router_active_connections{service=\"llm-router\"} 5\n"
-	
-	// Request count (incremental mock data based on time)
-	now := time.Now().Unix()
-	baseRequests := now / 10 // Increments every 10 seconds
-	
-	metrics += "\n# HELP llm_router_requests_total Total number of requests\n"
-	metrics += "# TYPE llm_router_requests_total counter\n"
-	metrics += fmt.Sprintf("llm_router_requests_total{service=\"llm-router\",provider=\"openai\",method=\"POST\",status_code=\"200\",client_ip=\"192.168.1.100\"} %d\n", 150+baseRequests*3)
-	metrics += fmt.Sprintf("llm_router_requests_total{service=\"llm-router\",provider=\"anthropic\",method=\"POST\",status_code=\"200\",client_ip=\"192.168.1.101\"} %d\n", 75+baseRequests*2)
-	metrics += fmt.Sprintf("llm_router_requests_total{service=\"llm-router\",provider=\"openai\",method=\"POST\",status_code=\"400\",client_ip=\"10.0.0.50\"} %d\n", 5+baseRequests/10)
-	metrics += fmt.Sprintf("llm_router_requests_total{service=\"llm-router\",provider=\"openai\",method=\"POST\",status_code=\"200\",client_ip=\"172.16.0.25\"} %d\n", 80+baseRequests*2)
-	metrics += fmt.Sprintf("llm_router_requests_total{service=\"llm-router\",provider=\"anthropic\",method=\"POST\",status_code=\"200\",client_ip=\"10.0.0.75\"} %d\n", 45+baseRequests)
-	
-	// Token usage (incremental mock data based on time)
-	metrics += "\n# HELP llm_router_tokens_total Total number of tokens processed\n"
-	metrics += "# TYPE llm_router_tokens_total counter\n"
-	metrics += fmt.Sprintf("llm_router_tokens_total{service=\"llm-router\",provider=\"openai\",type=\"input\"} %d\n", 25000+baseRequests*500)
-	metrics += fmt.Sprintf("llm_router_tokens_total{service=\"llm-router\",provider=\"openai\",type=\"output\"} %d\n", 15000+baseRequests*300)
-	metrics += fmt.Sprintf("llm_router_tokens_total{service=\"llm-router\",provider=\"anthropic\",type=\"input\"} %d\n", 12000+baseRequests*250)
-	metrics += fmt.Sprintf("llm_router_tokens_total{service=\"llm-router\",provider=\"anthropic\",type=\"output\"} %d\n", 8000+baseRequests*150)
-	
-	// Cost tracking (incremental mock data based on time)
-	metrics += "\n# HELP llm_router_cost_total Total cost in USD\n"
-	metrics += "# TYPE llm_router_cost_total counter\n"
-	metrics += fmt.Sprintf("llm_router_cost_total{service=\"llm-router\",provider=\"openai\",model=\"gpt-4o\"} %.2f\n", 12.50+float64(baseRequests)*0.05)
-	metrics += fmt.Sprintf("llm_router_cost_total{service=\"llm-router\",provider=\"anthropic\",model=\"claude-3-sonnet\"} %.2f\n", 8.75+float64(baseRequests)*0.03)
-	
-	// Error tracking (mock data)
-	metrics += "\n# HELP llm_router_errors_total Total number of errors\n"
-	metrics += "# TYPE llm_router_errors_total counter\n"
-	metrics += "llm_router_errors_total{service=\"llm-router\",provider=\"openai\",error_type=\"timeout\"} 2\n"
-	metrics += "llm_router_errors_total{service=\"llm-router\",provider=\"anthropic\",error_type=\"rate_limit\"} 1\n"
-	
-	// Rate limiting (mock data)
-	metrics += "\n# HELP llm_router_rate_limit_usage Rate limit usage as fraction (0-1)\n"
-	metrics += "# TYPE llm_router_rate_limit_usage gauge\n"
-	metrics += "llm_router_rate_limit_usage{service=\"llm-router\",provider=\"openai\"} 0.65\n"
-	metrics += "llm_router_rate_limit_usage{service=\"llm-router\",provider=\"anthropic\"} 0.32\n"
-	
-	// Security metrics (incremental mock data)
-	metrics += "\n# HELP llm_router_auth_attempts_total Total authentication attempts\n"
-	metrics += "# TYPE llm_router_auth_attempts_total counter\n"
-	metrics += fmt.Sprintf("llm_router_auth_attempts_total{service=\"llm-router\",result=\"success\"} %d\n", 220+baseRequests*8)
-	metrics += fmt.Sprintf("llm_router_auth_attempts_total{service=\"llm-router\",result=\"failure\"} %d\n", 8+baseRequests/15)
-	
-	// Security score (mock data)
-	metrics += "\n# HELP llm_router_security_score Security score (0-100)\n"
-	metrics += "# TYPE llm_router_security_score gauge\n"
-	metrics += "llm_router_security_score{service=\"llm-router\"} 85\n"
-	
-	// Threat level (mock data)
-	metrics += "\n# HELP llm_router_threat_level Current threat level (0-3)\n"
-	metrics += "# TYPE llm_router_threat_level gauge\n"
-	metrics += "llm_router_threat_level{service=\"llm-router\"} 0\n"
-	
-	// Rate limiting hits (incremental mock data)
-	metrics += "\n# HELP llm_router_rate_limit_hits_total Total rate limit hits\n"
-	metrics += "# TYPE llm_router_rate_limit_hits_total counter\n"
-	metrics += fmt.Sprintf("llm_router_rate_limit_hits_total{service=\"llm-router\",tier=\"premium\"} %d\n", 10+baseRequests/20)
-	metrics += fmt.Sprintf("llm_router_rate_limit_hits_total{service=\"llm-router\",tier=\"standard\"} %d\n", 25+baseRequests/10)
-	
-	// Blocked requests (incremental mock data)
-	metrics += "\n# HELP llm_router_blocked_requests_total Total blocked requests\n"
-	metrics += "# TYPE llm_router_blocked_requests_total counter\n"
-	metrics += fmt.Sprintf("llm_router_blocked_requests_total{service=\"llm-router\",reason=\"rate_limit\"} %d\n", 5+baseRequests/30)
-	metrics += fmt.Sprintf("llm_router_blocked_requests_total{service=\"llm-router\",reason=\"auth_failure\"} %d\n", 3+baseRequests/50)
-	
-	// Security events (incremental mock data)
-	metrics += "\n# HELP llm_router_security_events_total Total security events\n"
-	metrics += "# TYPE llm_router_security_events_total counter\n"
-	metrics += fmt.Sprintf("llm_router_security_events_total{service=\"llm-router\",event_type=\"suspicious_activity\",severity=\"medium\"} %d\n", 2+baseRequests/100)
-	metrics += fmt.Sprintf("llm_router_security_events_total{service=\"llm-router\",event_type=\"malicious_input\",severity=\"high\"} %d\n", 1+baseRequests/200)
-	
-	// Validation failures (incremental mock data)
-	metrics += "\n# HELP llm_router_validation_failures_total Total validation failures\n"
-	metrics += "# TYPE llm_router_validation_failures_total counter\n"
-	metrics += fmt.Sprintf("llm_router_validation_failures_total{service=\"llm-router\",type=\"schema\"} %d\n", 8+baseRequests/25)
-	metrics += fmt.Sprintf("llm_router_validation_failures_total{service=\"llm-router\",type=\"content\"} %d\n", 12+baseRequests/15)
-	
-	// Input sanitization (incremental mock data)
-	metrics += "\n# HELP llm_router_input_sanitized_total Total inputs sanitized\n"
-	metrics += "# TYPE llm_router_input_sanitized_total counter\n"
-	metrics += fmt.Sprintf("llm_router_input_sanitized_total{service=\"llm-router\"} %d\n", 45+baseRequests*2)
-	
-	// Audit events (incremental mock data)
-	metrics += "\n# HELP llm_router_audit_events_total Total audit events\n"
-	metrics += "# TYPE llm_router_audit_events_total counter\n"
-	metrics += fmt.Sprintf("llm_router_audit_events_total{service=\"llm-router\",event_type=\"api_key_usage\",severity=\"low\",user_id=\"user123\"} %d\n", 150+baseRequests*5)
-	metrics += fmt.Sprintf("llm_router_audit_events_total{service=\"llm-router\",event_type=\"config_change\",severity=\"medium\",user_id=\"admin\"} %d\n", 3+baseRequests/50)
-	
-	// Active API keys (mock data)
-	metrics += "\n# HELP llm_router_active_api_keys Number of active API keys\n"
-	metrics += "# TYPE llm_router_active_api_keys gauge\n"
-	metrics += "llm_router_active_api_keys{service=\"llm-router\"} 12\n"
-	
-	fmt.Fprint(w, metrics)
+	s.metrics.Handler().ServeHTTP(w, r)
 }
\ No newline at end of file
@@ -0,0 +1,2564 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/tributary-ai/llm-router-waf/internal/accounting"
+	"github.com/tributary-ai/llm-router-waf/internal/cache"
+	"github.com/tributary-ai/llm-router-waf/internal/middleware"
+	"github.com/tributary-ai/llm-router-waf/internal/privacy"
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/providers/openai"
+	"github.com/tributary-ai/llm-router-waf/internal/routing"
+	"github.com/tributary-ai/llm-router-waf/internal/security"
+	"github.com/tributary-ai/llm-router-waf/internal/tracing"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+func TestContentTypeMiddleware_CharsetQualifiedJSON(t *testing.T) {
+	handler := createTestServer(t).setupRoutes()
+
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestContentTypeMiddleware_RejectsUnsupportedMediaType(t *testing.T) {
+	handler := createTestServer(t).setupRoutes()
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestContentTypeMiddleware_RejectsNonUTF8Body(t *testing.T) {
+	handler := createTestServer(t).setupRoutes()
+
+	body := []byte(`{"model":"mock-model","messages":[{"role":"user","content":"`)
+	body = append(body, 0xff, 0xfe)
+	body = append(body, []byte(`"}]}`)...)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid UTF-8")
+}
+
+func TestRequestSizeLimit_EnforcedOnRead(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	router := routing.NewRouter(logger)
+	provider := newMockProvider("mock")
+	router.RegisterProvider("mock", provider)
+	server, err := NewServer(router, &ServerConfig{
+		Port: "0",
+		Security: &middleware.SecurityMiddlewareConfig{
+			Validation: &security.ValidationConfig{MaxRequestSize: 1024},
+		},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	oversized := `{"model":"mock-model","messages":[{"role":"user","content":"` + strings.Repeat("a", 4096) + `"}]}`
+
+	// A chunked body declares no length at all
+	upstream := httptest.NewServer(handler)
+	defer upstream.Close()
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte(oversized))
+		writer.Close()
+	}()
+	resp, err := http.Post(upstream.URL+"/v1/chat/completions", "application/json", reader)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Equal(t, 0, provider.calls, "an oversized request must not reach the provider")
+
+	// An understated Content-Length passes the fast path but not the read
+	req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(oversized))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = 100
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), ErrorCodeRequestTooLarge)
+
+	// A declared length over the limit is rejected without reading
+	w = postJSON(handler, "/v1/chat/completions", oversized, nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestHandleVersion(t *testing.T) {
+	server := createTestServer(t)
+	server.config.BuildInfo = &BuildInfo{
+		Version:   "v9.9.9",
+		GitCommit: "abc1234",
+		BuildDate: "2026-01-02T03:04:05Z",
+	}
+	handler := server.setupRoutes()
+
+	req := httptest.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "v9.9.9", body["version"])
+	assert.Equal(t, "abc1234", body["git_commit"])
+	assert.Equal(t, "2026-01-02T03:04:05Z", body["build_date"])
+	assert.Equal(t, runtime.Version(), body["go_version"])
+	assert.Contains(t, body, "uptime")
+	assert.Contains(t, body, "uptime_seconds")
+	assert.Contains(t, body, "start_time")
+}
+
+func TestHandleChatCompletion_ModelRateLimited(t *testing.T) {
+	server := createTestServer(t)
+	limiter := security.NewInMemoryRateLimiter(&security.RateLimitConfig{
+		Enabled:        true,
+		PerModelLimits: map[string]int{"mock-model": 1},
+	}, logrus.New())
+	defer limiter.Stop()
+	server.router.SetRateLimiter(limiter)
+	handler := server.setupRoutes()
+
+	send := func() *httptest.ResponseRecorder {
+		body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, send().Code)
+
+	w := send()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "model:mock-model")
+}
+
+func TestLoggingMiddleware_TelemetryOptOut(t *testing.T) {
+	server := createTestServer(t)
+	server.config.LogRequestBodies = true
+	server.logger.SetLevel(logrus.DebugLevel)
+	hook := test.NewLocal(server.logger)
+	handler := server.setupRoutes()
+
+	send := func(optOut bool) {
+		body := `{"model":"mock-model","messages":[{"role":"user","content":"secret"}]}`
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.1.2.3:4567"
+		if optOut {
+			req.Header.Set(security.TelemetryOptOutHeader, "true")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	bodyLogs := func() int {
+		count := 0
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == "HTTP request body" {
+				count++
+			}
+		}
+		return count
+	}
+
+	send(false)
+	assert.Equal(t, 1, bodyLogs())
+
+	hook.Reset()
+	send(true)
+
+	// No body log or per-client recording for opted-out traffic...
+	assert.Equal(t, 0, bodyLogs())
+	for _, entry := range hook.AllEntries() {
+		assert.NotContains(t, entry.Data, "remote_addr")
+		assert.NotContains(t, entry.Data, "user_agent")
+	}
+
+	// ...but it is still counted in the aggregate
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `llm_router_http_requests_total{method="POST",status_code="200"} 2`)
+	assert.Contains(t, w.Body.String(), `llm_router_client_requests_total{client_ip="10.1.2.3",method="POST",status_code="200"} 1`)
+}
+
+func TestDebugLoggingMiddleware(t *testing.T) {
+	server := createTestServer(t)
+	server.logger.SetLevel(logrus.DebugLevel)
+	hook := test.NewLocal(server.logger)
+
+	bodyLog := func(message string) string {
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == message {
+				return entry.Data["body"].(string)
+			}
+		}
+		return ""
+	}
+
+	body := `{"model":"mock-model","api_key":"sk-live-123","max_tokens":50,"messages":[{"role":"user","content":"my private prompt"}]}`
+
+	t.Run("redacts credentials and message content", func(t *testing.T) {
+		hook.Reset()
+		server.config.DebugLogging = &DebugLoggingConfig{Enabled: true, RedactMessageContent: true}
+		w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+		require.Equal(t, http.StatusOK, w.Code, "the handler should still see the full request body")
+
+		logged := bodyLog("HTTP request body")
+		assert.NotContains(t, logged, "sk-live-123")
+		assert.NotContains(t, logged, "my private prompt")
+		assert.Contains(t, logged, `"max_tokens":50`)
+		assert.Contains(t, logged, `"model":"mock-model"`)
+
+		logged = bodyLog("HTTP response body")
+		assert.Contains(t, logged, `"content":"***REDACTED***"`)
+		assert.NotContains(t, logged, `"content":"Hi"`)
+	})
+
+	t.Run("keeps message content when not redacting", func(t *testing.T) {
+		hook.Reset()
+		server.config.DebugLogging = &DebugLoggingConfig{Enabled: true}
+		postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+
+		logged := bodyLog("HTTP request body")
+		assert.NotContains(t, logged, "sk-live-123")
+		assert.Contains(t, logged, "my private prompt")
+	})
+
+	t.Run("truncates long bodies", func(t *testing.T) {
+		hook.Reset()
+		server.config.DebugLogging = &DebugLoggingConfig{Enabled: true, MaxBodyBytes: 32}
+		postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+
+		logged := bodyLog("HTTP request body")
+		assert.True(t, strings.HasPrefix(logged, `{"api_key":"***REDACTED***"`), logged)
+		assert.Contains(t, logged, "...(truncated ")
+		assert.LessOrEqual(t, len(strings.SplitN(logged, "...", 2)[0]), 32)
+	})
+}
+
+func TestHandleMetrics(t *testing.T) {
+	server := createTestServer(t)
+	handler := server.setupRoutes()
+
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	metrics := w.Body.String()
+	assert.Contains(t, metrics, `llm_router_requests_total{fallback="false",model="mock-model",provider="mock",status="success"} 1`)
+	assert.Contains(t, metrics, `llm_router_request_duration_seconds_count{provider="mock"} 1`)
+	assert.Contains(t, metrics, `llm_router_estimated_cost_usd_total{provider="mock"} 0.0002`)
+	assert.Contains(t, metrics, `llm_router_tokens_total{provider="mock",type="input"} 5`)
+	assert.Contains(t, metrics, `llm_router_provider_health{provider="mock"}`)
+}
+
+func TestHandleChatCompletion_ResponseCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{
+		Port:  "0",
+		Cache: &cache.CacheConfig{Enabled: true, TTL: time.Minute, MaxEntries: 10},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	deterministic := `{"model":"mock-model","temperature":0,"messages":[{"role":"user","content":"Hello"}]}`
+
+	w := send(deterministic)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get(CacheHeader))
+
+	w = send(deterministic)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get(CacheHeader))
+	assert.Contains(t, w.Body.String(), "mock-response")
+	assert.Equal(t, 1, provider.calls, "cache hit must not call the provider")
+
+	// Sampled requests bypass the cache
+	sampled := `{"model":"mock-model","temperature":0.7,"messages":[{"role":"user","content":"Hello"}]}`
+	send(sampled)
+	w = send(sampled)
+	assert.Empty(t, w.Header().Get(CacheHeader))
+	assert.Equal(t, 3, provider.calls)
+}
+
+// keywordEmbedder embeds prompts mentioning weather close together
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if strings.Contains(strings.ToLower(text), "weather") {
+		return []float32{1, 0.05}, nil
+	}
+	return []float32{0, 1}, nil
+}
+
+func TestHandleChatCompletion_SemanticCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{
+		Port:     "0",
+		Cache:    &cache.CacheConfig{Semantic: cache.SemanticConfig{Enabled: true, Threshold: 0.9}},
+		Embedder: keywordEmbedder{},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"What's the weather in Paris?"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get(CacheHeader))
+
+	// A near-duplicate prompt is served from the cache
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"How is the weather in Paris today?"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get(CacheHeader))
+	assert.Equal(t, 1, provider.calls)
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.RouterMetadata)
+	assert.Equal(t, CacheHitSemantic, resp.RouterMetadata.CacheHit)
+	assert.Greater(t, resp.RouterMetadata.CacheSimilarity, 0.9)
+
+	// A dissimilar prompt misses
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Write a haiku"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get(CacheHeader))
+	assert.Equal(t, 2, provider.calls)
+
+	metricsBody := httptest.NewRecorder()
+	server.metrics.Handler().ServeHTTP(metricsBody, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, metricsBody.Body.String(), `status="semantic_cache_hit"`)
+}
+
+func TestHandleChatCompletion_UsageAccounting(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	var sink bytes.Buffer
+	server, err := NewServer(router, &ServerConfig{
+		Port:          "0",
+		UsageRecorder: accounting.NewWriterRecorder(&sink),
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	for _, stream := range []bool{false, true} {
+		body := fmt.Sprintf(`{"model":"mock-model","user_id":"user-1","stream":%t,"messages":[{"role":"user","content":"Hello"}]}`, stream)
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	decoder := json.NewDecoder(&sink)
+	for _, mode := range []string{"non-streaming", "streaming"} {
+		var record accounting.UsageRecord
+		require.NoError(t, decoder.Decode(&record), mode)
+		assert.Equal(t, "mock", record.Provider, mode)
+		assert.Equal(t, "mock-model", record.Model, mode)
+		assert.Equal(t, "user-1", record.UserID, mode)
+		assert.Equal(t, 5, record.PromptTokens, mode)
+		assert.Equal(t, 1, record.CompletionTokens, mode)
+		// Priced from the provider's per-1K rates, not the estimate
+		assert.InDelta(t, 5*0.001/1000+1*0.002/1000, record.CostUSD, 1e-12, mode)
+		assert.NotEmpty(t, record.RequestID, mode)
+	}
+	assert.False(t, decoder.More(), "expected exactly one record per request")
+}
+
+func TestHandleChatCompletion_RequestTags(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	var sink bytes.Buffer
+	server, err := NewServer(router, &ServerConfig{
+		Port:            "0",
+		UsageRecorder:   accounting.NewWriterRecorder(&sink),
+		MetricTagLabels: []string{"tenant_id"},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	// The tenant header overrides a tenant_id in the body
+	body := `{"model":"mock-model","tags":{"tenant_id":"spoofed","cost_center":"cc-7"},"messages":[{"role":"user","content":"Hello"}]}`
+	w := postJSON(handler, "/v1/chat/completions", body, map[string]string{TenantHeader: "acme"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.RouterMetadata)
+	expected := map[string]string{"tenant_id": "acme", "cost_center": "cc-7"}
+	assert.Equal(t, expected, resp.RouterMetadata.Tags)
+
+	var record accounting.UsageRecord
+	require.NoError(t, json.NewDecoder(&sink).Decode(&record))
+	assert.Equal(t, expected, record.Tags)
+
+	// Only the allowlisted tag becomes a metric label
+	metricsBody := httptest.NewRecorder()
+	server.metrics.Handler().ServeHTTP(metricsBody, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, metricsBody.Body.String(), `llm_router_tagged_requests_total{model="mock-model",provider="mock",tenant_id="acme"} 1`)
+	assert.NotContains(t, metricsBody.Body.String(), "cc-7")
+}
+
+func TestHandleUsage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	store := accounting.NewMemoryStore(0)
+	require.NoError(t, store.Record(accounting.UsageRecord{
+		RequestID: "other", CallerID: "someone-else", Provider: "openai", Model: "gpt-4o",
+		PromptTokens: 100, CostUSD: 1, Timestamp: time.Now().UTC(),
+	}))
+	server, err := NewServer(router, &ServerConfig{
+		Port:       "0",
+		Accounting: &accounting.Config{ScopeToCaller: true},
+		UsageStore: store,
+		Security: &middleware.SecurityMiddlewareConfig{
+			Auth: &security.Config{APIKeys: []string{"test-api-key-12345"}, RequireAuth: true},
+		},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	apiKey := map[string]string{"X-API-Key": "test-api-key-12345"}
+	for _, user := range []string{"user-1", "user-2"} {
+		w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","user_id":"`+user+`","messages":[{"role":"user","content":"Hello"}]}`, apiKey)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	get := func(query string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/usage"+query, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("", nil).Code)
+
+	// Scoped to the caller, so the other key's usage is excluded
+	w := get("?group_by=provider", apiKey)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report accounting.UsageReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Totals.Requests)
+	assert.Equal(t, 12, report.Totals.TotalTokens)
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, "mock", report.Groups[0].Key)
+
+	w = get("?user_id=user-1&from="+time.Now().UTC().Format(time.DateOnly), apiKey)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	report = accounting.UsageReport{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Totals.Requests)
+
+	w = get("?to="+time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), apiKey)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	report = accounting.UsageReport{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 0, report.Totals.Requests)
+
+	assert.Equal(t, http.StatusBadRequest, get("?from=yesterday", apiKey).Code)
+	assert.Equal(t, http.StatusBadRequest, get("?group_by=user", apiKey).Code)
+}
+
+func TestHandleMessages_AnthropicFormat(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	provider.capabilities.SupportsFunctions = true
+	provider.capabilities.SupportsVision = true
+	provider.response = &types.ChatResponse{
+		ID:    "resp-1",
+		Model: "mock-model",
+		Choices: []types.Choice{{
+			Message: types.Message{
+				Role:    "assistant",
+				Content: "Let me check.",
+				ToolCalls: []types.ToolCall{{
+					ID:       "toolu_02",
+					Type:     "function",
+					Function: types.Function{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+				}},
+			},
+			FinishReason: "tool_calls",
+		}},
+		Usage: &types.Usage{PromptTokens: 20, CompletionTokens: 7, TotalTokens: 27},
+	}
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+
+	// Body as sent by the Anthropic SDK
+	body := `{
+		"model": "mock-model",
+		"max_tokens": 256,
+		"system": [{"type": "text", "text": "You are terse."}],
+		"metadata": {"user_id": "user-1"},
+		"stop_sequences": ["END"],
+		"tools": [{"name": "get_weather", "description": "Weather lookup", "input_schema": {"type": "object", "properties": {"city": {"type": "string"}}}}],
+		"tool_choice": {"type": "any"},
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "toolu_01", "name": "get_weather", "input": {"city": "Paris"}}]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_01", "content": "Sunny"},
+				{"type": "text", "text": "And the photo?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+			]}
+		]
+	}`
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	w := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Request was decoded into the internal representation
+	sent := provider.lastRequest
+	require.NotNil(t, sent)
+	require.NotNil(t, sent.MaxTokens)
+	assert.Equal(t, 256, *sent.MaxTokens)
+	assert.Equal(t, "user-1", sent.UserID)
+	assert.Equal(t, []string{"END"}, sent.Stop)
+	assert.Equal(t, "required", sent.ToolChoice)
+	require.Len(t, sent.Tools, 1)
+	assert.Equal(t, "get_weather", sent.Tools[0].Function.Name)
+	require.Len(t, sent.Messages, 5)
+	assert.Equal(t, types.Message{Role: "system", Content: "You are terse."}, sent.Messages[0])
+	assert.Equal(t, "Weather in Paris?", sent.Messages[1].Content)
+	require.Len(t, sent.Messages[2].ToolCalls, 1)
+	assert.JSONEq(t, `{"city":"Paris"}`, sent.Messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, types.Message{Role: "tool", Content: "Sunny", ToolCallID: "toolu_01"}, sent.Messages[3])
+	parts, ok := sent.Messages[4].Content.([]types.ContentPart)
+	require.True(t, ok, "expected multimodal content, got %T", sent.Messages[4].Content)
+	require.Len(t, parts, 2)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", parts[1].ImageURL.URL)
+
+	// Response is serialized in Anthropic's shape
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "message", resp["type"])
+	assert.Equal(t, "assistant", resp["role"])
+	assert.Equal(t, "tool_use", resp["stop_reason"])
+	assert.Equal(t, map[string]interface{}{"input_tokens": float64(20), "output_tokens": float64(7)}, resp["usage"])
+	content := resp["content"].([]interface{})
+	require.Len(t, content, 2)
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "Let me check."}, content[0])
+	assert.Equal(t, map[string]interface{}{
+		"type": "tool_use", "id": "toolu_02", "name": "get_weather",
+		"input": map[string]interface{}{"city": "Paris"},
+	}, content[1])
+
+	// Invalid requests get Anthropic-style errors
+	req = httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens must be positive"}}`, w.Body.String())
+}
+
+func TestHandleMessages_Streaming(t *testing.T) {
+	server := createTestServer(t)
+
+	body := `{"model":"mock-model","max_tokens":32,"stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	var events []string
+	var textDelta, finalUsage map[string]interface{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			switch event["type"] {
+			case "content_block_delta":
+				textDelta = event["delta"].(map[string]interface{})
+			case "message_delta":
+				finalUsage = event["usage"].(map[string]interface{})
+			}
+		}
+	}
+
+	assert.Equal(t, []string{"message_start", "content_block_start", "content_block_delta", "content_block_stop", "message_delta", "message_stop"}, events)
+	assert.Equal(t, map[string]interface{}{"type": "text_delta", "text": "Hi"}, textDelta)
+	assert.Equal(t, float64(5), finalUsage["input_tokens"])
+	assert.Equal(t, float64(1), finalUsage["output_tokens"])
+}
+
+func TestHandleCompletion_LegacyFormat(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+
+	body := `{"model":"mock-model","prompt":["Say hi"],"max_tokens":16,"stop":"\n","user":"user-1"}`
+	w := postJSON(server.setupRoutes(), "/v1/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// The prompt is sent as a single user message
+	sent := provider.lastRequest
+	require.NotNil(t, sent)
+	assert.Equal(t, []types.Message{{Role: "user", Content: "Say hi"}}, sent.Messages)
+	require.NotNil(t, sent.MaxTokens)
+	assert.Equal(t, 16, *sent.MaxTokens)
+	assert.Equal(t, []string{"\n"}, sent.Stop)
+	assert.Equal(t, "user-1", sent.UserID)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "text_completion", resp["object"])
+	choices := resp["choices"].([]interface{})
+	require.Len(t, choices, 1)
+	choice := choices[0].(map[string]interface{})
+	assert.Equal(t, "Hi", choice["text"])
+	assert.Equal(t, "stop", choice["finish_reason"])
+	assert.Contains(t, choice, "logprobs")
+	assert.NotContains(t, choice, "message")
+	assert.Contains(t, resp, "router_metadata")
+
+	// Unsupported shapes are rejected
+	for _, body := range []string{
+		`{"model":"mock-model"}`,
+		`{"model":"mock-model","prompt":["a","b"]}`,
+		`{"model":"mock-model","prompt":[1,2,3]}`,
+		`{"model":"mock-model","prompt":"a","n":2}`,
+	} {
+		w := postJSON(server.setupRoutes(), "/v1/completions", body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestHandleCompletion_LegacyStreaming(t *testing.T) {
+	server := createTestServer(t)
+	w := postJSON(server.setupRoutes(), "/v1/completions", `{"model":"mock-model","prompt":"Say hi","stream":true}`, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Len(t, events, 3, w.Body.String())
+	assert.Equal(t, "data: [DONE]", events[2])
+
+	var chunk map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[0], "data: ")), &chunk))
+	assert.Equal(t, "text_completion", chunk["object"])
+	choice := chunk["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Hi", choice["text"])
+	assert.Nil(t, choice["finish_reason"])
+	assert.NotContains(t, choice, "delta")
+
+	// The summary chunk reports usage and routing metadata
+	var summary map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), &summary))
+	assert.Empty(t, summary["choices"])
+	assert.Equal(t, float64(6), summary["usage"].(map[string]interface{})["total_tokens"])
+	assert.Contains(t, summary, "router_metadata")
+}
+
+func TestHandleChatCompletion_StreamingUsageSummary(t *testing.T) {
+	server := createTestServer(t)
+
+	body := `{"model":"mock-model","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var payloads []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			payloads = append(payloads, data)
+		}
+	}
+	require.GreaterOrEqual(t, len(payloads), 2)
+	assert.Equal(t, "[DONE]", payloads[len(payloads)-1])
+
+	// The chunk before [DONE] summarizes usage and cost for the whole stream
+	var summary types.ChatChunk
+	require.NoError(t, json.Unmarshal([]byte(payloads[len(payloads)-2]), &summary))
+	require.NotNil(t, summary.Usage)
+	assert.Equal(t, 5, summary.Usage.PromptTokens)
+	assert.Equal(t, 1, summary.Usage.CompletionTokens)
+	assert.Equal(t, 6, summary.Usage.TotalTokens)
+	assert.Empty(t, summary.Choices)
+	require.NotNil(t, summary.RouterMetadata)
+	assert.Equal(t, "mock", summary.RouterMetadata.Provider)
+	assert.InDelta(t, 0.000007, summary.RouterMetadata.ActualCost, 1e-12)
+}
+
+// slowStreamProvider streams two content chunks with a pause before each
+type slowStreamProvider struct {
+	*mockProvider
+	pause time.Duration
+}
+
+func (p *slowStreamProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	chunks := make(chan *types.ChatChunk)
+	go func() {
+		defer close(chunks)
+		for _, text := range []string{"Hello", " world"} {
+			time.Sleep(p.pause)
+			chunks <- &types.ChatChunk{
+				ID:      "slow-chunk",
+				Object:  "chat.completion.chunk",
+				Model:   req.Model,
+				Choices: []types.ChoiceChunk{{Index: 0, Delta: &types.Message{Role: "assistant", Content: text}}},
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// failingStreamProvider streams two content chunks, then fails
+type failingStreamProvider struct {
+	*mockProvider
+}
+
+func (p *failingStreamProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	chunks := make(chan *types.ChatChunk, 3)
+	for _, text := range []string{"Hello", " world"} {
+		chunks <- &types.ChatChunk{
+			ID:      "failing-chunk",
+			Object:  "chat.completion.chunk",
+			Model:   req.Model,
+			Choices: []types.ChoiceChunk{{Index: 0, Delta: &types.Message{Role: "assistant", Content: text}}},
+		}
+	}
+	chunks <- &types.ChatChunk{Err: &providers.ProviderError{Provider: "mock", StatusCode: http.StatusInternalServerError, Err: errors.New("connection reset mid-stream")}}
+	close(chunks)
+	return chunks, nil
+}
+
+func TestHandleChatCompletion_StreamingError(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", &failingStreamProvider{mockProvider: newMockProvider("mock")})
+	server, err := NewServer(router, &ServerConfig{Port: "0", StreamHeartbeatInterval: -1}, logger)
+	require.NoError(t, err)
+
+	body := `{"model":"mock-model","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	frames := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+	require.Len(t, frames, 4, w.Body.String()) // metadata, two content chunks, error
+	for _, frame := range frames[1:3] {
+		data, ok := strings.CutPrefix(frame, "data: ")
+		require.True(t, ok, "unexpected frame %q", frame)
+		var chunk types.ChatChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		require.Len(t, chunk.Choices, 1)
+	}
+
+	// The stream ends with a structured error event instead of [DONE]
+	data, ok := strings.CutPrefix(frames[3], "event: error\ndata: ")
+	require.True(t, ok, "expected an error event, got %q", frames[3])
+	var event struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Status  int    `json:"status"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Equal(t, ErrorTypeProvider, event.Error.Type)
+	assert.Equal(t, ErrorCodeUpstream, event.Error.Code)
+	assert.Equal(t, http.StatusBadGateway, event.Error.Status)
+	assert.Contains(t, event.Error.Message, "connection reset mid-stream")
+	assert.NotContains(t, w.Body.String(), "[DONE]")
+}
+
+// endlessStreamProvider streams a chunk every few milliseconds until its
+// context is cancelled, then reports how many it sent
+type endlessStreamProvider struct {
+	*mockProvider
+	cancelled chan int
+}
+
+func (p *endlessStreamProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	chunks := make(chan *types.ChatChunk)
+	go func() {
+		defer close(chunks)
+		for sent := 0; ; sent++ {
+			chunk := &types.ChatChunk{
+				ID:      "endless-chunk",
+				Object:  "chat.completion.chunk",
+				Model:   req.Model,
+				Choices: []types.ChoiceChunk{{Index: 0, Delta: &types.Message{Role: "assistant", Content: "token "}}},
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				p.cancelled <- sent
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	return chunks, nil
+}
+
+func TestHandleChatCompletion_StreamingClientDisconnect(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := routing.NewRouter(logger)
+	provider := &endlessStreamProvider{mockProvider: newMockProvider("mock"), cancelled: make(chan int, 1)}
+	router.RegisterProvider("mock", provider)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	ts := httptest.NewServer(server.setupRoutes())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"mock-model","stream":true,"messages":[{"role":"user","content":"Hello"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Read a few events, then hang up
+	buf := make([]byte, 512)
+	_, err = io.ReadAtLeast(resp.Body, buf, len(buf))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	select {
+	case sent := <-provider.cancelled:
+		assert.Less(t, sent, 1000, "provider stream should stop soon after the disconnect")
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider stream context was not cancelled after the client disconnected")
+	}
+
+	assert.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		server.metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return strings.Contains(w.Body.String(), `status="cancelled"`)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHandleChatCompletion_StreamingHeartbeat(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", &slowStreamProvider{mockProvider: newMockProvider("mock"), pause: 80 * time.Millisecond})
+	server, err := NewServer(router, &ServerConfig{Port: "0", StreamHeartbeatInterval: 20 * time.Millisecond}, logger)
+	require.NoError(t, err)
+
+	body := `{"model":"mock-model","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+	w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Every frame is either a complete data event or a keepalive comment
+	frames := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+	var sequence []string
+	for _, frame := range frames {
+		if frame == ": keepalive" {
+			sequence = append(sequence, "keepalive")
+			continue
+		}
+		data, ok := strings.CutPrefix(frame, "data: ")
+		require.True(t, ok, "unexpected frame %q", frame)
+		if data == "[DONE]" {
+			sequence = append(sequence, "done")
+			continue
+		}
+		var chunk types.ChatChunk
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk), "corrupt data frame %q", data)
+		if len(chunk.Choices) > 0 {
+			sequence = append(sequence, "content")
+		} else {
+			sequence = append(sequence, "metadata")
+		}
+	}
+
+	// Keepalives fill the pauses before and between content chunks and stop
+	// once the stream ends
+	joined := strings.Join(sequence, ",")
+	assert.Regexp(t, `^metadata,(keepalive,)+content,(keepalive,)+content,metadata,done$`, joined)
+}
+
+func TestServer_HeartbeatInterval(t *testing.T) {
+	server := createTestServer(t)
+	assert.Equal(t, DefaultStreamHeartbeatInterval, server.heartbeatInterval())
+
+	server.config.StreamHeartbeatInterval = time.Second
+	assert.Equal(t, time.Second, server.heartbeatInterval())
+
+	server.config.StreamHeartbeatInterval = -1
+	assert.Zero(t, server.heartbeatInterval())
+}
+
+func TestHandleProviderMaintenance(t *testing.T) {
+	server := createTestServer(t)
+	handler := server.setupRoutes()
+	chat := `{"model":"test-model","messages":[{"role":"user","content":"Hello"}]}`
+
+	w := postJSON(handler, "/v1/providers/mock/maintenance", `{"enabled":true}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, server.router.GetHealthStatus()["mock"].Maintenance)
+
+	// The only provider is out of rotation, even after a passing recheck
+	w = postJSON(handler, "/v1/providers/mock/health/recheck", ``, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var recheck struct {
+		Status types.HealthStatus `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &recheck))
+	assert.Equal(t, "healthy", recheck.Status.Status)
+	assert.True(t, recheck.Status.Maintenance)
+
+	w = postJSON(handler, "/v1/chat/completions", chat, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = postJSON(handler, "/v1/providers/mock/maintenance", `{"enabled":false}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postJSON(handler, "/v1/chat/completions", chat, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Unknown providers and malformed bodies are rejected
+	w = postJSON(handler, "/v1/providers/missing/maintenance", `{"enabled":true}`, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = postJSON(handler, "/v1/providers/missing/health/recheck", ``, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = postJSON(handler, "/v1/providers/mock/maintenance", `{}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleDeregisterProvider(t *testing.T) {
+	server := createTestServer(t)
+	server.router.RegisterProvider("backup", newMockProvider("backup"))
+	handler := server.setupRoutes()
+
+	deleteProvider := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/v1/providers/"+name, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := deleteProvider("mock")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"backup"}, server.router.ListProviders())
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"test-model","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "backup", resp.RouterMetadata.Provider)
+
+	assert.Equal(t, http.StatusNotFound, deleteProvider("mock").Code)
+}
+
+func TestHandleProviderMaintenance_RequiresAuth(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	server := createAuthTestServer(t, logger)
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/providers/mock/maintenance", `{"enabled":true}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.False(t, server.router.GetHealthStatus()["mock"].Maintenance)
+
+	// Authenticated isn't enough without admin:write
+	w = postJSON(handler, "/v1/providers/mock/maintenance", `{"enabled":true}`, map[string]string{"X-API-Key": "test-api-key-12345"})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.False(t, server.router.GetHealthStatus()["mock"].Maintenance)
+
+	w = postJSON(handler, "/v1/providers/mock/maintenance", `{"enabled":true}`, map[string]string{"X-API-Key": "admin-api-key-12345"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, server.router.GetHealthStatus()["mock"].Maintenance)
+}
+
+func createAuthTestServer(t *testing.T, logger *logrus.Logger) *Server {
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	server, err := NewServer(router, &ServerConfig{
+		Port: "0",
+		Security: &middleware.SecurityMiddlewareConfig{
+			Auth: &security.Config{
+				APIKeys:     []string{"test-api-key-12345", "admin-api-key-12345"},
+				RequireAuth: true,
+				JWTSecret:   "test-jwt-secret",
+				KeyScopes: map[string][]string{
+					"admin-api-key-12345": {security.PermissionAdminWrite},
+				},
+			},
+			Audit: &security.AuditConfig{Enabled: true},
+		},
+	}, logger)
+	require.NoError(t, err)
+	return server
+}
+
+func postJSON(handler http.Handler, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleAuthToken_IssueAndRefresh(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	server := createAuthTestServer(t, logger)
+	handler := server.setupRoutes()
+
+	// Exchange the API key for a token pair
+	w := postJSON(handler, "/v1/auth/token", `{"api_key":"test-api-key-12345"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var issued security.TokenPair
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.Equal(t, "Bearer", issued.TokenType)
+	assert.NotEmpty(t, issued.RefreshToken)
+
+	// The access token authorizes API calls
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`,
+		map[string]string{"Authorization": "Bearer " + issued.AccessToken})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Refresh rotates the refresh token
+	w = postJSON(handler, "/v1/auth/refresh", `{"refresh_token":"`+issued.RefreshToken+`"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var refreshed security.TokenPair
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	assert.NotEqual(t, issued.RefreshToken, refreshed.RefreshToken)
+
+	// A used refresh token is rejected
+	w = postJSON(handler, "/v1/auth/refresh", `{"refresh_token":"`+issued.RefreshToken+`"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Both issuances are audited
+	server.securityMiddleware.Stop()
+	issuedEvents := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["event_type"] == security.JWTTokenIssued {
+			issuedEvents++
+		}
+	}
+	assert.Equal(t, 2, issuedEvents)
+}
+
+func TestHandleAuthToken_Rejected(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	server := createAuthTestServer(t, logger)
+	defer server.securityMiddleware.Stop()
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/auth/token", `{"api_key":"wrong-key"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postJSON(handler, "/v1/auth/refresh", `{"refresh_token":"unknown"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postJSON(handler, "/v1/auth/refresh", `{}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The API key can also be presented in the usual auth header
+	w = postJSON(handler, "/v1/auth/token", ``, map[string]string{"X-API-Key": "test-api-key-12345"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Token endpoints aren't served without authentication configured
+	w = postJSON(createTestServer(t).setupRoutes(), "/v1/auth/token", `{"api_key":"test-api-key-12345"}`, nil)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+}
+
+func TestHandleChatCompletion_RequestTimeout(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	provider.delay = time.Second
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{Port: "0", RequestTimeout: 50 * time.Millisecond}, logger)
+	require.NoError(t, err)
+
+	start := time.Now()
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "timed out after 50ms")
+	assert.Less(t, time.Since(start), provider.delay, "handler should not wait for the provider")
+}
+
+func TestHandleChatCompletion_AllowedModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		model   string
+		status  int
+	}{
+		{name: "glob match", allowed: []string{"gpt-4o*", "mock-*"}, model: "mock-model", status: http.StatusOK},
+		{name: "denied model", allowed: []string{"gpt-4o*"}, model: "mock-model", status: http.StatusForbidden},
+		{name: "empty list allows all", allowed: nil, model: "mock-model", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.WarnLevel)
+			router := routing.NewRouter(logger)
+			mock := newMockProvider("mock")
+			router.RegisterProvider("mock", mock)
+			server, err := NewServer(router, &ServerConfig{Port: "0", AllowedModels: tt.allowed}, logger)
+			require.NoError(t, err)
+			handler := server.setupRoutes()
+
+			w := postJSON(handler, "/v1/chat/completions", `{"model":"`+tt.model+`","messages":[{"role":"user","content":"Hi"}]}`, nil)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status != http.StatusForbidden {
+				return
+			}
+
+			var resp struct {
+				Error struct {
+					Type string `json:"type"`
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, ErrorTypeAuthorization, resp.Error.Type)
+			assert.Equal(t, ErrorCodeModelNotAllowed, resp.Error.Code)
+			assert.Zero(t, mock.calls, "denied requests must not reach a provider")
+		})
+	}
+}
+
+func TestHandleChatCompletion_AllowedModelsSubstitution(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	mock := newMockProvider("mock")
+	router.RegisterProvider("mock", mock)
+	router.RegisterModelPrefix("mock-", "mock")
+	server, err := NewServer(router, &ServerConfig{Port: "0", AllowedModels: []string{"mock-modl"}}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	// The typo is allowed, but the model it is substituted with isn't
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-modl","messages":[{"role":"user","content":"Hi"}],"fallback_config":{"enabled":true}}`, nil)
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `model \"mock-model\" is not allowed`)
+	assert.Zero(t, mock.calls, "denied substitutes must not reach a provider")
+
+	// An oversized prompt isn't promoted to a larger model outside the allowlist
+	server.config.AllowedModels = []string{"mock-model"}
+	mock.capabilities.SupportedModels = []types.ModelInfo{
+		{Name: "mock-model", MaxContextWindow: 8000},
+		{Name: "mock-model-large", MaxContextWindow: 128000},
+	}
+	mock.err = &providers.ProviderError{Provider: "mock", StatusCode: 400, Err: errors.New("maximum context length exceeded (context_length_exceeded)")}
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}],"fallback_config":{"enabled":true}}`, nil)
+	assert.NotEqual(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "mock-model", mock.lastRequest.Model, "the request must not be promoted to a disallowed model")
+}
+
+func TestHandleChatCompletion_RoutedModelScopes(t *testing.T) {
+	server := createTestServer(t)
+	server.router.SetModelAliases(map[string]string{"fast": "mock-model"})
+	handler := server.setupRoutes()
+
+	send := func(permissions ...string) *httptest.ResponseRecorder {
+		body := `{"model":"fast","messages":[{"role":"user","content":"Hi"}]}`
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		authInfo := &security.AuthInfo{UserID: "tenant", Permissions: permissions}
+		req = req.WithContext(context.WithValue(req.Context(), "auth_info", authInfo))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// The alias is in scope, but the model it resolves to isn't
+	w := send("models:fast")
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "models:mock-model")
+
+	w = send("models:fast", "models:mock-*")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestHandleChatCompletion_StopSequenceLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	mock := newMockProvider("mock")
+	mock.capabilities.MaxStopSequences = 2
+	router.RegisterProvider("mock", mock)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}],"stop":["a","b","c"]}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrorCodeInvalidStopSequences, resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, "got 3 stop sequences, at most 2 are supported")
+	assert.Zero(t, mock.calls, "invalid stop sequences must not reach the provider")
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}],"stop":["a","b"]}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestHandleChatCompletion_Explain(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}],"explain":true}`
+	for _, path := range []string{"/v1/chat/completions", "/v1/routing/decision"} {
+		w := postJSON(handler, path, body, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var metadata types.RouterMetadata
+		if path == "/v1/chat/completions" {
+			var resp types.ChatResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.NotNil(t, resp.RouterMetadata)
+			metadata = *resp.RouterMetadata
+		} else {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metadata))
+		}
+		require.NotNil(t, metadata.RoutingTrace, path)
+		require.Len(t, metadata.RoutingTrace.Candidates, 1, path)
+		assert.Equal(t, "mock", metadata.RoutingTrace.Candidates[0].Provider)
+		assert.True(t, metadata.RoutingTrace.Candidates[0].Selected)
+	}
+}
+
+func TestHandleChatCompletion_ProviderAuthFailure(t *testing.T) {
+	alerts := make(chan Alert, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		json.NewDecoder(r.Body).Decode(&alert)
+		alerts <- alert
+	}))
+	defer webhook.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	router := routing.NewRouter(logger)
+	revoked := newMockProvider("revoked")
+	revoked.err = &providers.ProviderError{Provider: "revoked", StatusCode: http.StatusUnauthorized, Err: errors.New("invalid x-api-key")}
+	backup := newMockProvider("backup")
+	backup.capabilities.SupportedModels[0].InputCostPer1K = 0.01
+	router.RegisterProvider("revoked", revoked)
+	router.RegisterProvider("backup", backup)
+	for _, name := range []string{"revoked", "backup"} {
+		// Checked now, so no background check races the request
+		_, err := router.RecheckHealth(context.Background(), name)
+		require.NoError(t, err)
+	}
+	server, err := NewServer(router, &ServerConfig{Port: "0", Alerts: &AlertConfig{WebhookURL: webhook.URL}}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}],` +
+		`"retry_config":{"max_attempts":3,"base_delay":1000000},"fallback_config":{"enabled":true}}`
+	w := postJSON(handler, "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "backup", resp.RouterMetadata.Provider)
+	assert.True(t, resp.RouterMetadata.FallbackUsed)
+	assert.Equal(t, 1, revoked.calls, "401s must not be retried")
+
+	health := router.GetHealthStatus()["revoked"]
+	assert.Equal(t, "unhealthy", health.Status)
+	assert.Contains(t, health.ErrorMessage, "credentials rejected")
+
+	select {
+	case alert := <-alerts:
+		assert.Equal(t, AlertProviderAuthFailure, alert.Type)
+		assert.Equal(t, "revoked", alert.Provider)
+		assert.Equal(t, "critical", alert.Severity)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an alert to be posted")
+	}
+
+	// The provider is out of rotation, so the next request skips it
+	w = postJSON(handler, "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 1, revoked.calls)
+	assert.Empty(t, alerts, "an outage is alerted once")
+}
+
+func TestHandleChatCompletion_ImageFallbackKeepsVision(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	router := routing.NewRouter(logger)
+	vision := newMockProvider("vision")
+	vision.capabilities.SupportsVision = true
+	vision.err = &providers.ProviderError{Provider: "vision", StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")}
+	text := newMockProvider("text")
+	backup := newMockProvider("backup")
+	backup.capabilities.SupportsVision = true
+	backup.capabilities.SupportedModels[0].InputCostPer1K = 0.01
+	router.RegisterProvider("vision", vision)
+	router.RegisterProvider("text", text)
+	router.RegisterProvider("backup", backup)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	// Fallback doesn't require the same features, but the image still
+	// needs a provider with vision
+	body := `{"model":"mock-model","messages":[{"role":"user","content":[` +
+		`{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}],` +
+		`"retry_config":{"max_attempts":1},"fallback_config":{"enabled":true,"require_same_features":false}}`
+	w := postJSON(handler, "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "backup", resp.RouterMetadata.Provider)
+	assert.True(t, resp.RouterMetadata.FallbackUsed)
+	assert.Equal(t, 1, vision.calls)
+	assert.Zero(t, text.calls, "an image request must not fall back to a provider without vision")
+}
+
+func TestHandleChatCompletion_ExecutionFallbackChecksCost(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	router := routing.NewRouter(logger)
+	primary := &pricedMockProvider{mockProvider: newMockProvider("primary"), cost: 0.0001}
+	primary.err = &providers.ProviderError{Provider: "primary", StatusCode: http.StatusInternalServerError, Err: errors.New("internal error")}
+	pricey := &pricedMockProvider{mockProvider: newMockProvider("pricey"), cost: 0.01}
+	backup := &pricedMockProvider{mockProvider: newMockProvider("backup"), cost: 0.00012}
+	router.RegisterProvider("primary", primary)
+	router.RegisterProvider("pricey", pricey)
+	router.RegisterProvider("backup", backup)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	// The primary routes fine but fails on execution; the fallback taking
+	// over must still be within max_cost_increase of its estimate
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}],"retry_config":{"max_attempts":1},` +
+		`"fallback_config":{"enabled":true,"preferred_chain":["pricey","backup"],"max_cost_increase":0.5}}`
+	w := postJSON(handler, "/v1/chat/completions", body, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "backup", resp.RouterMetadata.Provider)
+	assert.True(t, resp.RouterMetadata.FallbackUsed)
+	assert.Equal(t, 1, primary.calls)
+	assert.Zero(t, pricey.calls, "execution fallback must skip providers over max_cost_increase")
+	assert.Equal(t, 1, backup.calls)
+}
+
+func TestHandleListModels_AllowedModels(t *testing.T) {
+	server := createTestServer(t)
+	mock := newMockProvider("other")
+	mock.capabilities.SupportedModels = append(mock.capabilities.SupportedModels, types.ModelInfo{Name: "other-model"})
+	server.router.RegisterProvider("other", mock)
+	handler := server.setupRoutes()
+
+	list := func() []string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/models", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Object string       `json:"object"`
+			Data   []modelEntry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "list", resp.Object)
+		ids := make([]string, 0, len(resp.Data))
+		for _, model := range resp.Data {
+			ids = append(ids, model.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"mock-model", "other-model"}, list())
+
+	server.config.AllowedModels = []string{"other-*"}
+	assert.Equal(t, []string{"other-model"}, list())
+}
+
+func TestHandleChatCompletion_Transformers(t *testing.T) {
+	errorCode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		var resp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Error.Code
+	}
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}]}`
+
+	t.Run("response transformer adds fields", func(t *testing.T) {
+		server := createTestServer(t)
+		server.router.AddResponseTransformer(routing.ResponseTransformerFunc(func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+			resp.SystemFingerprint = "transformed"
+			return nil
+		}))
+
+		w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp types.ChatResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "transformed", resp.SystemFingerprint)
+	})
+
+	t.Run("request transformer error aborts with 400", func(t *testing.T) {
+		server := createTestServer(t)
+		server.router.AddRequestTransformer(routing.RequestTransformerFunc(func(ctx context.Context, req *types.ChatRequest) error {
+			return errors.New("tenant header required")
+		}))
+		mock, _ := server.router.GetProvider("mock")
+
+		w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, ErrorCodeTransformRejected, errorCode(t, w))
+		assert.Contains(t, w.Body.String(), "tenant header required")
+		assert.Zero(t, mock.(*mockProvider).calls)
+	})
+
+	t.Run("response transformer error fails with 500", func(t *testing.T) {
+		server := createTestServer(t)
+		server.router.AddResponseTransformer(routing.ResponseTransformerFunc(func(ctx context.Context, req *types.ChatRequest, resp *types.ChatResponse) error {
+			return errors.New("enrichment failed")
+		}))
+
+		w := postJSON(server.setupRoutes(), "/v1/chat/completions", body, nil)
+		require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+		assert.Equal(t, ErrorCodeTransformFailed, errorCode(t, w))
+	})
+}
+
+func TestHandleChatCompletion_MessageLimits(t *testing.T) {
+	server := createTestServer(t)
+	server.config.MessageLimits = &MessageLimits{MaxMessages: 2, MaxMessageLength: 10, MaxChoices: 2}
+	mock, _ := server.router.GetProvider("mock")
+	mock.(*mockProvider).capabilities.SupportsVision = true // image requests only route to vision providers
+	handler := server.setupRoutes()
+
+	tests := []struct {
+		name     string
+		messages string
+		limit    string
+	}{
+		{
+			name:     "too many messages",
+			messages: `[{"role":"system","content":"Hi"},{"role":"user","content":"Hi"},{"role":"user","content":"Hi"}]`,
+			limit:    "max_messages",
+		},
+		{
+			name:     "over-length string content",
+			messages: `[{"role":"user","content":"Hello, world!"}]`,
+			limit:    "max_message_length",
+		},
+		{
+			name:     "over-length content parts",
+			messages: `[{"role":"user","content":[{"type":"text","text":"Hello, "},{"type":"text","text":"world!"}]}]`,
+			limit:    "max_message_length",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":`+tt.messages+`}`, nil)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp struct {
+				Error struct {
+					Type       string           `json:"type"`
+					Param      string           `json:"param"`
+					Violations []limitViolation `json:"violations"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "invalid_request_error", resp.Error.Type)
+			assert.Equal(t, "messages", resp.Error.Param)
+			require.Len(t, resp.Error.Violations, 1)
+			assert.Equal(t, tt.limit, resp.Error.Violations[0].Limit)
+		})
+	}
+
+	// Text is measured in characters, not bytes, and image parts don't count
+	body := `{"model":"mock-model","messages":[{"role":"user","content":[{"type":"text","text":"héllo wörl"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`
+	w := postJSON(handler, "/v1/chat/completions", body, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// n is capped too, and must be positive
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","n":3,"messages":[{"role":"user","content":"Hi"}]}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"param":"n"`)
+	assert.Contains(t, w.Body.String(), `"limit":"max_choices"`)
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","n":0,"messages":[{"role":"user","content":"Hi"}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","n":2,"messages":[{"role":"user","content":"Hi"}]}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_StopDrainsInFlightRequests(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	provider := newMockProvider("mock")
+	provider.delay = 300 * time.Millisecond
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+	handled := make(chan time.Time, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		handled <- time.Now()
+	}))
+	defer ts.Close()
+	server.httpServer = ts.Config
+
+	// Start a slow request and wait for it to be in flight
+	finished := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`))
+		assert.NoError(t, err)
+		finished <- resp
+	}()
+	require.Eventually(t, func() bool { return router.InFlight() == 1 }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan time.Time, 1)
+	go func() {
+		assert.NoError(t, server.Stop(ctx))
+		stopped <- time.Now()
+	}()
+
+	// New requests are refused while draining, and health reports it
+	require.Eventually(t, server.draining, time.Second, 5*time.Millisecond)
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hi"}]}`, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	health := httptest.NewRecorder()
+	handler.ServeHTTP(health, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, health.Code)
+	assert.Contains(t, health.Body.String(), `"status":"draining"`)
+
+	// The in-flight request completes before the server exits
+	resp := <-finished
+	require.NotNil(t, resp)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, (<-stopped).Before(<-handled), "server stopped before the in-flight request finished")
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestHandleChatCompletion_LoadShedding(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	provider.delay = 300 * time.Millisecond
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+	router.SetMaxConcurrentRequests(2)
+
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	const requests = 6
+	codes := make(chan *httptest.ResponseRecorder, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			codes <- w
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	var succeeded, shed int
+	for w := range codes {
+		switch w.Code {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusServiceUnavailable:
+			shed++
+			assert.Equal(t, "1", w.Header().Get("Retry-After"))
+			assert.Contains(t, w.Body.String(), "overloaded")
+		default:
+			t.Errorf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+	}
+	assert.Equal(t, 2, succeeded, "only the concurrency limit should be served")
+	assert.Equal(t, requests-2, shed)
+	assert.Equal(t, 0, router.InFlight(), "slots should be released after completion")
+
+	// Slots freed by completed requests are reusable
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandleChatCompletion_PriorityAdmission(t *testing.T) {
+	server := createTestServer(t)
+	server.router.SetMaxConcurrentRequests(4)
+	handler := server.setupRoutes()
+
+	// Saturate the share of capacity open to low priority traffic
+	for i := 0; i < 2; i++ {
+		release, err := server.router.AcquirePriority(types.PriorityLow)
+		require.NoError(t, err)
+		defer release()
+	}
+
+	chat := func(priority string) *httptest.ResponseRecorder {
+		return postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","priority":"`+priority+`","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	}
+
+	w := chat("low")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "overloaded")
+
+	w = chat("high")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = chat("urgent")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req := httptest.NewRequest("GET", "/v1/health", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var body struct {
+		InFlightByPriority map[string]int `json:"in_flight_by_priority"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]int{"high": 0, "normal": 0, "low": 2}, body.InFlightByPriority)
+}
+
+func TestHandleChatCompletion_UpstreamHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-remaining-requests", "42")
+		w.Header().Set("x-request-id", "req_abc")
+		w.Header().Set("x-internal-debug", "secret")
+		if r.URL.Path != "/chat/completions" {
+			fmt.Fprint(w, `{"object":"list","data":[]}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	}))
+	defer upstream.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("openai", openai.NewOpenAIProvider(&openai.OpenAIConfig{
+		APIKey:  "test-key",
+		BaseURL: upstream.URL,
+		Models:  []types.ModelInfo{{Name: "gpt-4o-mini", ProviderModelID: "gpt-4o-mini"}},
+	}, logger))
+
+	server, err := NewServer(router, &ServerConfig{Port: "0", UpstreamHeaders: providers.DefaultUpstreamHeaders}, logger)
+	require.NoError(t, err)
+
+	w := postJSON(server.setupRoutes(), "/v1/chat/completions", `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, "42", w.Header().Get("X-Upstream-Ratelimit-Remaining-Requests"))
+	assert.Equal(t, "req_abc", w.Header().Get("X-Upstream-Request-Id"))
+	assert.Empty(t, w.Header().Get("X-Upstream-Internal-Debug"), "headers outside the allowlist must not leak")
+
+	var resp types.ChatResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.RouterMetadata)
+	assert.Equal(t, map[string]string{
+		"x-ratelimit-remaining-requests": "42",
+		"x-request-id":                   "req_abc",
+	}, resp.RouterMetadata.UpstreamHeaders)
+}
+
+func TestHandleHealthCheck_ReportsInFlight(t *testing.T) {
+	server := createTestServer(t)
+	server.router.SetMaxConcurrentRequests(4)
+	release, err := server.router.Acquire()
+	require.NoError(t, err)
+	defer release()
+
+	req := httptest.NewRequest("GET", "/v1/health", nil)
+	w := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, float64(1), body["in_flight_requests"])
+	assert.Equal(t, float64(4), body["max_concurrent_requests"])
+}
+
+func TestProbes_LivenessAndReadiness(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	router := routing.NewRouter(logger)
+	primary, secondary := newMockProvider("primary"), newMockProvider("secondary")
+	router.RegisterProvider("primary", primary)
+	router.RegisterProvider("secondary", secondary)
+	recheck := func() {
+		for _, name := range []string{"primary", "secondary"} {
+			_, err := router.RecheckHealth(context.Background(), name)
+			require.NoError(t, err)
+		}
+	}
+	recheck()
+
+	// Probes skip authentication, since orchestrators send no credentials
+	server, err := NewServer(router, &ServerConfig{
+		Port: "0",
+		Security: &middleware.SecurityMiddlewareConfig{
+			Auth: &security.Config{APIKeys: []string{"test-api-key-12345"}, RequireAuth: true},
+		},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("/livez"))
+	assert.Equal(t, http.StatusOK, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/health"))
+
+	// One unhealthy provider degrades /health but leaves the pod ready
+	primary.healthErr = errors.New("upstream unavailable")
+	recheck()
+	assert.Equal(t, http.StatusServiceUnavailable, get("/health"))
+	assert.Equal(t, http.StatusOK, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/livez"))
+
+	// With no healthy provider the pod is unready but still alive
+	secondary.healthErr = errors.New("upstream unavailable")
+	recheck()
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/livez"))
+
+	secondary.healthErr = nil
+	recheck()
+	assert.Equal(t, http.StatusOK, get("/readyz"))
+
+	// Both probes fail once the server is shutting down
+	require.NoError(t, server.drainRequests(context.Background()))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/livez"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/readyz"))
+}
+
+func TestHandleChatCompletion_TracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tracerProvider)
+	defer otel.SetTracerProvider(previous)
+
+	handler := createTestServer(t).setupRoutes()
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(`{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	require.Contains(t, spans, "POST /v1/chat/completions")
+	require.Contains(t, spans, "router.route")
+	require.Contains(t, spans, "router.route_by_strategy")
+
+	server := spans["POST /v1/chat/completions"]
+	route := spans["router.route"]
+	strategy := spans["router.route_by_strategy"]
+	for _, span := range []tracetest.SpanStub{server, route, strategy} {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), span.Name)
+	}
+	assert.Equal(t, server.SpanContext.SpanID(), route.Parent.SpanID())
+	assert.Equal(t, route.SpanContext.SpanID(), strategy.Parent.SpanID())
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range route.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	assert.Equal(t, "mock", attrs[tracing.AttrProvider].AsString())
+	assert.Equal(t, "mock-model", attrs[tracing.AttrModel].AsString())
+	assert.Equal(t, "cost_optimized", attrs[tracing.AttrStrategy].AsString())
+	assert.Greater(t, attrs[tracing.AttrEstimatedCost].AsFloat64(), 0.0)
+}
+
+func TestServer_RequestTimeoutOverride(t *testing.T) {
+	server := createTestServer(t)
+	server.config.RequestTimeout = 30 * time.Second
+
+	assert.Equal(t, 30*time.Second, server.requestTimeout(&types.ChatRequest{}))
+	assert.Equal(t, 5*time.Second, server.requestTimeout(&types.ChatRequest{TimeoutSeconds: 5}))
+	// Requests cannot extend the configured maximum
+	assert.Equal(t, 30*time.Second, server.requestTimeout(&types.ChatRequest{TimeoutSeconds: 600}))
+
+	server.config.RequestTimeout = 0
+	assert.Equal(t, 5*time.Second, server.requestTimeout(&types.ChatRequest{TimeoutSeconds: 5}))
+}
+
+func TestHandleEmbeddings(t *testing.T) {
+	server := createTestServer(t)
+	server.router.RegisterProvider("openai", &mockEmbeddingProvider{mockProvider: newMockProvider("openai")})
+	handler := server.setupRoutes()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/embeddings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(`{"model":"text-embedding-3-small","input":["hello","world"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.EmbeddingResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 2)
+	require.NotNil(t, resp.RouterMetadata)
+	assert.Equal(t, "openai", resp.RouterMetadata.Provider)
+
+	// Invalid input is rejected before routing
+	w = send(`{"model":"text-embedding-3-small","input":42}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Chat-only providers are never selected
+	req := httptest.NewRequest("POST", "/v1/embeddings", bytes.NewBufferString(`{"model":"mock-model","input":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	createTestServer(t).setupRoutes().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "support embeddings")
+}
+
+func TestHandleRerank(t *testing.T) {
+	server := createTestServer(t)
+	server.router.RegisterProvider("cohere", &mockRerankProvider{mockProvider: newMockProvider("cohere")})
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/rerank", `{"query":"fruit","documents":["car","apple","banana"],"top_n":2}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp types.RerankResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "banana", resp.Results[0].Document)
+	assert.Equal(t, 2, resp.Results[0].Index)
+	require.NotNil(t, resp.RouterMetadata)
+	assert.Equal(t, "cohere", resp.RouterMetadata.Provider)
+
+	// Invalid requests are rejected before routing
+	w = postJSON(handler, "/v1/rerank", `{"query":"fruit","documents":[]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = postJSON(handler, "/v1/rerank", `{"query":" ","documents":["apple"]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Chat-only providers are never selected
+	w = postJSON(createTestServer(t).setupRoutes(), "/v1/rerank", `{"query":"fruit","documents":["apple"]}`, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "support reranking")
+}
+
+func TestHandleBatches(t *testing.T) {
+	server := createTestServer(t)
+	server.router.RegisterProvider("batcher", &mockBatchProvider{mockProvider: newMockProvider("batcher"), batches: map[string]*types.BatchResponse{}})
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/batches", `{"input_file_id":"file-in","endpoint":"/v1/chat/completions","completion_window":"24h"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var batch types.BatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, "batch_1", batch.ID)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/batches/batch_1", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, "validating", batch.Status)
+
+	w = postJSON(handler, "/v1/batches/batch_1/cancel", ``, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, "cancelling", batch.Status)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/batches", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list types.BatchListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 1)
+
+	// Unknown batches keep the provider's 404
+	req = httptest.NewRequest(http.MethodGet, "/v1/batches/batch_missing", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Invalid requests are rejected before routing
+	w = postJSON(handler, "/v1/batches", `{"endpoint":"/v1/chat/completions"}`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Providers without a batch API are never selected
+	w = postJSON(createTestServer(t).setupRoutes(), "/v1/batches", `{"input_file_id":"file-in","endpoint":"/v1/chat/completions"}`, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "batch processing")
+}
+
+func TestHandleEstimate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	router := routing.NewRouter(logger)
+	router.SetDefaultStrategy(routing.RoutingStrategyCostOptimized)
+	cheap := &pricedMockProvider{mockProvider: newMockProvider("cheap"), cost: 0.0001}
+	premium := &pricedMockProvider{mockProvider: newMockProvider("premium"), cost: 0.003}
+	router.RegisterProvider("cheap", cheap)
+	router.RegisterProvider("premium", premium)
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+
+	w := postJSON(server.setupRoutes(), "/v1/estimate", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var comparison routing.CostComparison
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &comparison))
+	assert.Equal(t, "cost_optimized", comparison.Strategy)
+	assert.Equal(t, "cheap", comparison.SelectedProvider)
+	require.Len(t, comparison.Estimates, 2)
+	assert.True(t, comparison.Estimates["cheap"].Selected)
+	assert.False(t, comparison.Estimates["premium"].Selected)
+	assert.Equal(t, 0.003, comparison.Estimates["premium"].TotalCost)
+	assert.Equal(t, 5, comparison.Estimates["premium"].InputTokens)
+
+	// Nothing is sent upstream
+	assert.Zero(t, cheap.calls)
+	assert.Zero(t, premium.calls)
+}
+
+func createIdempotencyTestServer(t *testing.T, provider *mockProvider) http.Handler {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{
+		Port:        "0",
+		Idempotency: &cache.IdempotencyConfig{Enabled: true, TTL: time.Minute},
+	}, logger)
+	require.NoError(t, err)
+	return server.setupRoutes()
+}
+
+func TestIdempotencyKey_Replay(t *testing.T) {
+	provider := newMockProvider("mock")
+	handler := createIdempotencyTestServer(t, provider)
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+	headers := map[string]string{IdempotencyKeyHeader: "retry-1"}
+
+	first := postJSON(handler, "/v1/chat/completions", body, headers)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	assert.Empty(t, first.Header().Get(IdempotencyReplayedHeader))
+
+	second := postJSON(handler, "/v1/chat/completions", body, headers)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, 1, provider.calls)
+
+	// Requests without a key are processed every time
+	postJSON(handler, "/v1/chat/completions", body, nil)
+	assert.Equal(t, 2, provider.calls)
+}
+
+func TestIdempotencyKey_BodyMismatch(t *testing.T) {
+	provider := newMockProvider("mock")
+	handler := createIdempotencyTestServer(t, provider)
+	headers := map[string]string{IdempotencyKeyHeader: "retry-1"}
+
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`, headers)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Goodbye"}]}`, headers)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestIdempotencyKey_ConcurrentFirstRequests(t *testing.T) {
+	provider := newMockProvider("mock")
+	provider.delay = 50 * time.Millisecond
+	handler := createIdempotencyTestServer(t, provider)
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+	headers := map[string]string{IdempotencyKeyHeader: "retry-1"}
+
+	const clients = 10
+	codes := make([]int, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = postJSON(handler, "/v1/chat/completions", body, headers).Code
+		}(i)
+	}
+	wg.Wait()
+
+	// Only one request reaches the provider; the rest are told it's in flight
+	provider.mu.Lock()
+	assert.Equal(t, 1, provider.calls)
+	provider.mu.Unlock()
+	for _, code := range codes {
+		assert.Contains(t, []int{http.StatusOK, http.StatusConflict}, code)
+	}
+
+	w := postJSON(handler, "/v1/chat/completions", body, headers)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get(IdempotencyReplayedHeader))
+}
+
+func TestIdempotencyKey_FailedRequestCanBeRetried(t *testing.T) {
+	provider := newMockProvider("mock")
+	provider.err = errors.New("upstream unavailable")
+	handler := createIdempotencyTestServer(t, provider)
+	body := `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`
+	headers := map[string]string{IdempotencyKeyHeader: "retry-1"}
+
+	w := postJSON(handler, "/v1/chat/completions", body, headers)
+	require.GreaterOrEqual(t, w.Code, http.StatusInternalServerError)
+
+	provider.err = nil
+	w = postJSON(handler, "/v1/chat/completions", body, headers)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotencyReplayedHeader))
+}
+
+// Helper functions
+
+// pricedMockProvider is a mockProvider with a fixed estimated cost
+type pricedMockProvider struct {
+	*mockProvider
+	cost float64
+}
+
+func (m *pricedMockProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	return &types.CostEstimate{InputTokens: 5, OutputTokens: 100, TotalTokens: 105, TotalCost: m.cost}, nil
+}
+
+// mockProvider is a minimal LLMProvider used to exercise the HTTP layer
+type mockProvider struct {
+	name         string
+	capabilities types.ProviderCapabilities
+	response     *types.ChatResponse
+	err          error
+	calls        int
+	lastRequest  *types.ChatRequest
+	delay        time.Duration // simulated provider latency
+	healthErr    error         // returned by health checks
+	mu           sync.Mutex    // guards calls and lastRequest for concurrent tests
+}
+
+func newMockProvider(name string) *mockProvider {
+	return &mockProvider{
+		name: name,
+		capabilities: types.ProviderCapabilities{
+			ProviderName: name,
+			SupportedModels: []types.ModelInfo{
+				{Name: "mock-model", InputCostPer1K: 0.001, OutputCostPer1K: 0.002},
+			},
+			SupportsStreaming: true,
+		},
+	}
+}
+
+func (m *mockProvider) GetCapabilities() types.ProviderCapabilities {
+	return m.capabilities
+}
+
+func (m *mockProvider) GetProviderName() string {
+	return m.name
+}
+
+func (m *mockProvider) ChatCompletion(ctx context.Context, req *types.ChatRequest) (*types.ChatResponse, error) {
+	m.mu.Lock()
+	m.calls++
+	m.lastRequest = req
+	m.mu.Unlock()
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.response != nil {
+		return m.response, nil
+	}
+	return &types.ChatResponse{
+		ID:     "mock-response",
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []types.Choice{
+			{Index: 0, Message: types.Message{Role: "assistant", Content: "Hi"}, FinishReason: "stop"},
+		},
+		Usage: &types.Usage{PromptTokens: 5, CompletionTokens: 1, TotalTokens: 6},
+	}, nil
+}
+
+func (m *mockProvider) StreamCompletion(ctx context.Context, req *types.ChatRequest) (<-chan *types.ChatChunk, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	chunks := make(chan *types.ChatChunk, 2)
+	chunks <- &types.ChatChunk{
+		ID:     "mock-chunk",
+		Object: "chat.completion.chunk",
+		Model:  req.Model,
+		Choices: []types.ChoiceChunk{
+			{Index: 0, Delta: &types.Message{Role: "assistant", Content: "Hi"}},
+		},
+	}
+	chunks <- &types.ChatChunk{
+		ID:     "mock-chunk",
+		Object: "chat.completion.chunk",
+		Model:  req.Model,
+		Usage:  &types.Usage{PromptTokens: 5, CompletionTokens: 1, TotalTokens: 6},
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+func (m *mockProvider) EstimateCost(req *types.ChatRequest) (*types.CostEstimate, error) {
+	return &types.CostEstimate{InputTokens: 5, OutputTokens: 100, TotalTokens: 105, TotalCost: 0.0002}, nil
+}
+
+func (m *mockProvider) HealthCheck(ctx context.Context) error {
+	return m.healthErr
+}
+
+func createTestServer(t *testing.T) *Server {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel) // Reduce noise during tests
+
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	server, err := NewServer(router, &ServerConfig{Port: "0"}, logger)
+	require.NoError(t, err)
+	return server
+}
+
+// mockRerankProvider scores documents by their position, last first
+type mockRerankProvider struct {
+	*mockProvider
+}
+
+func (m *mockRerankProvider) Rerank(ctx context.Context, query string, documents []string) ([]types.RankedDoc, error) {
+	ranked := make([]types.RankedDoc, 0, len(documents))
+	for i := len(documents) - 1; i >= 0; i-- {
+		ranked = append(ranked, types.RankedDoc{Index: i, RelevanceScore: float64(i+1) / float64(len(documents)), Document: documents[i]})
+	}
+	return ranked, nil
+}
+
+// mockBatchProvider adds an in-memory batch API to mockProvider
+type mockBatchProvider struct {
+	*mockProvider
+	batches map[string]*types.BatchResponse
+}
+
+func (m *mockBatchProvider) SupportsBatch() bool { return true }
+
+func (m *mockBatchProvider) CreateBatch(ctx context.Context, req *types.BatchRequest) (*types.BatchResponse, error) {
+	batch := &types.BatchResponse{ID: fmt.Sprintf("batch_%d", len(m.batches)+1), Object: "batch", InputFileID: req.InputFileID, Endpoint: req.Endpoint, Status: "validating"}
+	m.batches[batch.ID] = batch
+	return batch, nil
+}
+
+func (m *mockBatchProvider) GetBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	batch, ok := m.batches[id]
+	if !ok {
+		return nil, &providers.ProviderError{Provider: "mock", StatusCode: http.StatusNotFound, Err: errors.New("no such batch")}
+	}
+	return batch, nil
+}
+
+func (m *mockBatchProvider) CancelBatch(ctx context.Context, id string) (*types.BatchResponse, error) {
+	batch, err := m.GetBatch(ctx, id)
+	if err == nil {
+		batch.Status = "cancelling"
+	}
+	return batch, err
+}
+
+func (m *mockBatchProvider) ListBatches(ctx context.Context) (*types.BatchListResponse, error) {
+	list := &types.BatchListResponse{Object: "list"}
+	for _, batch := range m.batches {
+		list.Data = append(list.Data, *batch)
+	}
+	return list, nil
+}
+
+// mockEmbeddingProvider adds embeddings support to mockProvider
+type mockEmbeddingProvider struct {
+	*mockProvider
+}
+
+func (m *mockEmbeddingProvider) SupportsEmbeddings() bool {
+	return true
+}
+
+func (m *mockEmbeddingProvider) CreateEmbedding(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]types.Embedding, len(inputs))
+	for i := range inputs {
+		data[i] = types.Embedding{Object: "embedding", Index: i, Embedding: []float32{0.1, 0.2}}
+	}
+	return &types.EmbeddingResponse{
+		Object: "list",
+		Data:   data,
+		Model:  req.Model,
+		Usage:  &types.Usage{PromptTokens: len(inputs), TotalTokens: len(inputs)},
+	}, nil
+}
+
+func (m *mockEmbeddingProvider) EstimateEmbeddingCost(req *types.EmbeddingRequest) (*types.CostEstimate, error) {
+	return &types.CostEstimate{InputTokens: 2, TotalTokens: 2, TotalCost: 0.00001}, nil
+}
+
+func TestCORSMiddleware_UsesConfiguredOrigins(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", newMockProvider("mock"))
+
+	server, err := NewServer(router, &ServerConfig{
+		Port: "0",
+		Security: &middleware.SecurityMiddlewareConfig{
+			CORS: &middleware.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+		},
+	}, logger)
+	require.NoError(t, err)
+	defer server.securityMiddleware.Stop()
+	handler := server.setupRoutes()
+
+	for origin, expected := range map[string]string{
+		"https://app.example.com": "https://app.example.com",
+		"https://evil.com":        "",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+}
+
+func TestWriteFailure_StructuredErrors(t *testing.T) {
+	server := createTestServer(t)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+		code   string
+	}{
+		{"no healthy providers", fmt.Errorf("%w: provider openai is not healthy", routing.ErrNoHealthyProviders), http.StatusServiceUnavailable, ErrorTypeRouting, ErrorCodeNoHealthyProviders},
+		{"model not found", &routing.UnknownModelError{Model: "gpt-5"}, http.StatusNotFound, ErrorTypeNotFound, ErrorCodeModelNotFound},
+		{"budget exceeded", &routing.BudgetError{Budget: 0.01}, http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeBudgetExceeded},
+		{"provider timeout", fmt.Errorf("%w: %w", routing.ErrProviderTimeout, context.DeadlineExceeded), http.StatusGatewayTimeout, ErrorTypeProvider, ErrorCodeProviderTimeout},
+		{"upstream error", fmt.Errorf("%w: %w", routing.ErrUpstream, &providers.ProviderError{Provider: "openai", StatusCode: 500, Err: errors.New("boom")}), http.StatusBadGateway, ErrorTypeProvider, ErrorCodeUpstream},
+		{"upstream rejected request", fmt.Errorf("%w: %w", routing.ErrUpstream, &providers.ProviderError{Provider: "openai", StatusCode: 400, Err: errors.New("bad")}), http.StatusBadRequest, ErrorTypeInvalidRequest, ErrorCodeInvalidRequest},
+		{"unclassified", errors.New("something else"), http.StatusInternalServerError, ErrorTypeAPI, ErrorCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.writeFailure(w, tt.err, http.StatusInternalServerError, "Completion failed")
+			assert.Equal(t, tt.status, w.Code)
+
+			var body struct {
+				Error struct {
+					Type   string `json:"type"`
+					Code   string `json:"code"`
+					Status int    `json:"status"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.typ, body.Error.Type)
+			assert.Equal(t, tt.code, body.Error.Code)
+			assert.Equal(t, tt.status, body.Error.Status)
+		})
+	}
+}
+
+func TestHandleChatCompletion_UpstreamErrorCode(t *testing.T) {
+	server := createTestServer(t)
+	provider := newMockProvider("mock")
+	provider.err = &providers.ProviderError{Provider: "mock", StatusCode: http.StatusInternalServerError, Err: errors.New("overloaded")}
+	server.router.RegisterProvider("mock", provider)
+
+	w := postJSON(server.setupRoutes(), "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"upstream_error"`)
+}
+
+func TestHandleChatCompletion_ProviderErrorDetail(t *testing.T) {
+	server := createTestServer(t)
+	provider := newMockProvider("mock")
+	provider.err = providers.NewProviderError("mock", 529, "overloaded_error", "Overloaded", errors.New("529 Overloaded"))
+	server.router.RegisterProvider("mock", provider)
+
+	w := postJSON(server.setupRoutes(), "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"Hello"}]}`, nil)
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	var body struct {
+		Error struct {
+			Code          string                 `json:"code"`
+			ProviderError map[string]interface{} `json:"provider_error"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, ErrorCodeUpstream, body.Error.Code)
+	assert.Equal(t, map[string]interface{}{
+		"provider":  "mock",
+		"status":    529.0,
+		"type":      providers.ErrorTypeOverloaded,
+		"code":      "overloaded_error",
+		"message":   "Overloaded",
+		"retryable": true,
+	}, body.Error.ProviderError)
+}
+
+// stubModerationClient flags content containing "attack"
+type stubModerationClient struct{}
+
+func (stubModerationClient) Moderate(ctx context.Context, text string) (map[string]float64, error) {
+	if strings.Contains(text, "attack") {
+		return map[string]float64{"violence": 0.95}, nil
+	}
+	return map[string]float64{"violence": 0.01}, nil
+}
+
+func TestHandleChatCompletion_Moderation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	provider := newMockProvider("mock")
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	server, err := NewServer(router, &ServerConfig{
+		Port:             "0",
+		Moderation:       &security.ModerationConfig{Enabled: true, Provider: "openai", Threshold: 0.8},
+		ModerationClient: stubModerationClient{},
+	}, logger)
+	require.NoError(t, err)
+	handler := server.setupRoutes()
+
+	w := postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"plan an attack"}]}`, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"content_flagged"`)
+	assert.Equal(t, 0, provider.calls, "flagged prompts are never forwarded")
+
+	w = postJSON(handler, "/v1/chat/completions", `{"model":"mock-model","messages":[{"role":"user","content":"plan a picnic"}]}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 1, provider.calls)
+}
+
+func TestHandleChatCompletion_PIIRedaction(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	provider := newMockProvider("mock")
+	router := routing.NewRouter(logger)
+	router.RegisterProvider("mock", provider)
+
+	var servers []*Server
+	newServer := func(action privacy.Action) http.Handler {
+		server, err := NewServer(router, &ServerConfig{
+			Port: "0",
+			Security: &middleware.SecurityMiddlewareConfig{
+				Auth:  &security.Config{APIKeys: []string{"test-api-key-12345"}, RequireAuth: true},
+				Audit: &security.AuditConfig{Enabled: true},
+			},
+			Privacy: &privacy.Config{Enabled: true, Action: action},
+		}, logger)
+		require.NoError(t, err)
+		servers = append(servers, server)
+		return server.setupRoutes()
+	}
+	headers := map[string]string{"X-API-Key": "test-api-key-12345"}
+	body := `{"model":"mock-model","messages":[{"role":"user","content":[{"type":"text","text":"charge 4111 1111 1111 1111"}]}]}`
+
+	// Redacted before it reaches the provider
+	w := postJSON(newServer(privacy.ActionRedact), "/v1/chat/completions", body, headers)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "charge [REDACTED_CREDIT_CARD]", types.MessageText(provider.lastRequest.Messages[0].Content))
+
+	// Blocked outright
+	w = postJSON(newServer(privacy.ActionBlock), "/v1/chat/completions", body, headers)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"pii_detected"`)
+	assert.Equal(t, 1, provider.calls)
+
+	// The audit trail records the category and count, never the value
+	for _, server := range servers {
+		server.securityMiddleware.Stop()
+	}
+	var violations []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		line, err := entry.String()
+		require.NoError(t, err)
+		assert.NotContains(t, line, "4111")
+		if entry.Data["event_type"] == security.SecurityViolation && entry.Data["detail_violation_type"] == "pii_detected" {
+			violations = append(violations, entry)
+		}
+	}
+	require.Len(t, violations, 2)
+	assert.Equal(t, map[string]int{privacy.CategoryCreditCard: 1}, violations[0].Data["detail_categories"])
+}
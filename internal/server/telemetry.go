@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// maxLoggedBodyBytes caps request bodies written to the debug log
+const maxLoggedBodyBytes = 4096
+
+// requestClientIP returns the client IP recorded by the security middleware,
+// falling back to the connection's remote address
+func requestClientIP(r *http.Request) string {
+	if clientIP, ok := r.Context().Value("client_ip").(string); ok && clientIP != "" {
+		return clientIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// truncateBody shortens a body to at most limit bytes for logging
+func truncateBody(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	return string(body[:limit]) + "...(truncated " + strconv.Itoa(len(body)-limit) + " bytes)"
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tributary-ai/llm-router-waf/internal/metrics"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// requestTimeout returns the deadline for a completion: the request's
+// timeout_seconds when set, capped at the configured RequestTimeout
+func (s *Server) requestTimeout(req *types.ChatRequest) time.Duration {
+	timeout := s.config.RequestTimeout
+	if req.TimeoutSeconds > 0 {
+		requested := time.Duration(req.TimeoutSeconds) * time.Second
+		if timeout <= 0 || requested < timeout {
+			timeout = requested
+		}
+	}
+	return timeout
+}
+
+// withRequestTimeout bounds the request context by the completion timeout
+func (s *Server) withRequestTimeout(r *http.Request, req *types.ChatRequest) (*http.Request, context.CancelFunc) {
+	timeout := s.requestTimeout(req)
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return r.WithContext(ctx), cancel
+}
+
+// writeTimeoutError answers with 504 when the request's deadline has passed.
+// It reports whether a response was written.
+func (s *Server) writeTimeoutError(w http.ResponseWriter, r *http.Request, req *types.ChatRequest, provider string, write func(http.ResponseWriter, int, string)) bool {
+	if !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		return false
+	}
+
+	timeout := s.requestTimeout(req)
+	s.metrics.RecordRequest(provider, req.Model, metrics.StatusTimeout, false, time.Since(req.Timestamp))
+	s.logger.WithField("provider", provider).WithField("timeout", timeout).Warn("Completion timed out")
+	write(w, http.StatusGatewayTimeout, fmt.Sprintf("Request timed out after %s", timeout))
+	return true
+}
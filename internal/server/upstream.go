@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/tributary-ai/llm-router-waf/internal/providers"
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// UpstreamHeaderPrefix prefixes provider response headers passed on to
+// clients, e.g. X-Upstream-Ratelimit-Remaining-Requests
+const UpstreamHeaderPrefix = "X-Upstream-"
+
+// withUpstreamCapture records the allowlisted headers of provider responses
+// made while serving r
+func (s *Server) withUpstreamCapture(r *http.Request) *http.Request {
+	if len(s.config.UpstreamHeaders) == 0 {
+		return r
+	}
+	ctx, _ := providers.WithHeaderCapture(r.Context(), s.config.UpstreamHeaders)
+	return r.WithContext(ctx)
+}
+
+// writeUpstreamHeaders adds the captured provider headers to metadata and
+// sets them as X-Upstream-* response headers. It must run before the status
+// is written.
+func writeUpstreamHeaders(ctx context.Context, w http.ResponseWriter, metadata *types.RouterMetadata) {
+	capture := providers.HeaderCaptureFrom(ctx)
+	if capture == nil {
+		return
+	}
+
+	headers := capture.Headers()
+	for name, value := range headers {
+		w.Header().Set(upstreamHeaderName(name), value)
+	}
+	metadata.UpstreamHeaders = headers
+}
+
+// upstreamHeaderName maps a provider header to the name it is returned under,
+// dropping a leading "x-": x-ratelimit-remaining-tokens becomes
+// X-Upstream-Ratelimit-Remaining-Tokens
+func upstreamHeaderName(name string) string {
+	name = strings.TrimPrefix(strings.ToLower(name), "x-")
+	return http.CanonicalHeaderKey(UpstreamHeaderPrefix + name)
+}
@@ -0,0 +1,173 @@
+// Package tokenizer counts prompt tokens for cost estimation. OpenAI models
+// use their tiktoken encoding; Claude models use an approximation derived
+// from cl100k_base, since Anthropic's tokenizer is not published.
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+const (
+	// defaultEncoding is used for models tiktoken doesn't know, such as
+	// models served by OpenAI-compatible gateways
+	defaultEncoding = "cl100k_base"
+
+	// claudeTokenRatio scales cl100k_base counts to Claude's smaller
+	// vocabulary, which produces roughly 10% more tokens for the same text
+	claudeTokenRatio = 1.1
+
+	// Chat framing overhead, following OpenAI's published counting rules
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+
+	// Typical cost of an image whose size is unknown: a 1024x1024 image at
+	// high detail for OpenAI, and a ~1.15 megapixel image for Claude
+	openAIImageTokens = 765
+	claudeImageTokens = 1600
+)
+
+func init() {
+	// Use the embedded BPE ranks rather than downloading them at runtime
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// o200kPrefixes covers newer OpenAI models missing from tiktoken-go's tables
+var o200kPrefixes = []string{"o1", "o3", "o4", "gpt-4.1", "gpt-4.5", "gpt-5"}
+
+var (
+	encodersMu sync.Mutex
+	encoders   = make(map[string]*tiktoken.Tiktoken)
+)
+
+// CountTokens estimates the prompt tokens for a list of chat messages sent to model
+func CountTokens(model string, messages []types.Message) int {
+	claude := isClaude(model)
+
+	total := 0
+	for _, msg := range messages {
+		total += tokensPerMessage
+		total += CountText(model, msg.Role)
+		if msg.Name != "" {
+			total += tokensPerName + CountText(model, msg.Name)
+		}
+		total += countContent(model, msg.Content, claude)
+		for _, call := range msg.ToolCalls {
+			total += CountText(model, call.Function.Name) + CountText(model, call.Function.Arguments)
+		}
+	}
+	if len(messages) > 0 {
+		total += tokensPerReply
+	}
+
+	return total
+}
+
+// CountText estimates the tokens in a piece of text for model
+func CountText(model, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	encoder := encoderFor(model)
+	if encoder == nil {
+		// Rough approximation: 4 chars per token
+		return (len(text) + 3) / 4
+	}
+
+	count := len(encoder.EncodeOrdinary(text))
+	if isClaude(model) {
+		count = int(float64(count)*claudeTokenRatio + 0.5)
+	}
+	return count
+}
+
+// countContent counts string and multimodal message content. Multimodal parts
+// may be typed or decoded from JSON as generic maps.
+func countContent(model string, content interface{}, claude bool) int {
+	imageTokens := openAIImageTokens
+	if claude {
+		imageTokens = claudeImageTokens
+	}
+
+	total := 0
+	switch content := content.(type) {
+	case string:
+		total += CountText(model, content)
+	case []types.ContentPart:
+		for _, part := range content {
+			switch part.Type {
+			case "text":
+				total += CountText(model, part.Text)
+			case "image_url":
+				total += imageTokens
+			}
+		}
+	case []interface{}:
+		for _, item := range content {
+			part, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch part["type"] {
+			case "text":
+				text, _ := part["text"].(string)
+				total += CountText(model, text)
+			case "image_url", "image":
+				total += imageTokens
+			}
+		}
+	}
+	return total
+}
+
+// encoderFor returns the tiktoken encoder for model, or nil if none can be loaded
+func encoderFor(model string) *tiktoken.Tiktoken {
+	encoding := encodingFor(model)
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if encoder, exists := encoders[encoding]; exists {
+		return encoder
+	}
+
+	encoder, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		encoder = nil
+	}
+	// Cache failures too so we don't retry on every request
+	encoders[encoding] = encoder
+	return encoder
+}
+
+// encodingFor returns the tiktoken encoding name for model
+func encodingFor(model string) string {
+	if isClaude(model) {
+		return defaultEncoding
+	}
+	if encoding, exists := tiktoken.MODEL_TO_ENCODING[model]; exists {
+		return encoding
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return encoding
+		}
+	}
+	for _, prefix := range o200kPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return tiktoken.MODEL_O200K_BASE
+		}
+	}
+	return defaultEncoding
+}
+
+func isClaude(model string) bool {
+	return strings.HasPrefix(model, "claude")
+}
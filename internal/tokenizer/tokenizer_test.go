@@ -0,0 +1,105 @@
+package tokenizer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tributary-ai/llm-router-waf/internal/types"
+)
+
+// cookbookMessages is the example conversation from OpenAI's token counting
+// guide, which reports 129 prompt tokens for gpt-3.5-turbo/gpt-4 and 124 for gpt-4o
+var cookbookMessages = []types.Message{
+	{Role: "system", Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+	{Role: "system", Name: "example_user", Content: "New synergies will help drive top-line growth."},
+	{Role: "system", Name: "example_assistant", Content: "Things working well together will increase revenue."},
+	{Role: "system", Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+	{Role: "system", Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+	{Role: "user", Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+}
+
+func withinTolerance(got, want int, tolerance float64) bool {
+	return math.Abs(float64(got-want)) <= float64(want)*tolerance
+}
+
+func TestCountTokens_KnownCounts(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-3.5-turbo", 129},
+		{"gpt-4", 129},
+		{"gpt-4-0613", 129},
+		{"gpt-4o", 124},
+		{"gpt-4o-mini", 124},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got := CountTokens(tt.model, cookbookMessages)
+			if !withinTolerance(got, tt.want, 0.02) {
+				t.Errorf("Expected ~%d tokens, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCountText_KnownCounts(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4", "Hello, world!", 4},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"text-embedding-3-small", "tiktoken is great!", 6},
+		{"gpt-4o", "你好，世界", 3},
+		{"gpt-4", "func main() {\n\tfmt.Println(\"hi\")\n}", 10},
+	}
+
+	for _, tt := range tests {
+		if got := CountText(tt.model, tt.text); got != tt.want {
+			t.Errorf("%s %q: expected %d tokens, got %d", tt.model, tt.text, tt.want, got)
+		}
+	}
+}
+
+func TestCountTokens_Claude(t *testing.T) {
+	got := CountTokens("claude-3-haiku-20240307", cookbookMessages)
+
+	// Claude tokenizes somewhat less efficiently than cl100k_base
+	if got <= 129 || !withinTolerance(got, 129, 0.2) {
+		t.Errorf("Expected a Claude estimate slightly above 129 tokens, got %d", got)
+	}
+}
+
+func TestCountTokens_NonEnglishAndImages(t *testing.T) {
+	// CJK text packs far fewer characters per token than chars/4 assumes
+	text := "机器学习模型的路由决策应该考虑成本和延迟"
+	if got := CountText("gpt-4", text); got <= len(text)/4/2 || got > len(text) {
+		t.Errorf("Unexpected token count %d for %d bytes of CJK text", got, len(text))
+	}
+
+	messages := []types.Message{{
+		Role: "user",
+		Content: []interface{}{
+			map[string]interface{}{"type": "text", "text": "What's this?"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "test.jpg"}},
+		},
+	}}
+	if got := CountTokens("gpt-4o", messages); got < openAIImageTokens {
+		t.Errorf("Expected image tokens to be counted, got %d", got)
+	}
+	if got := CountTokens("claude-3-haiku", messages); got < claudeImageTokens {
+		t.Errorf("Expected Claude image tokens to be counted, got %d", got)
+	}
+}
+
+func TestCountTokens_UnknownModelUsesDefaultEncoding(t *testing.T) {
+	if got, want := CountTokens("llama-3-70b", cookbookMessages), CountTokens("gpt-4", cookbookMessages); got != want {
+		t.Errorf("Expected unknown models to use cl100k_base (%d tokens), got %d", want, got)
+	}
+	if CountTokens("gpt-4", nil) != 0 {
+		t.Error("Expected no tokens for an empty conversation")
+	}
+}
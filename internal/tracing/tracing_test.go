@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs an in-memory exporter as the global tracer provider
+// for the duration of the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := recordSpans(t)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "child")
+		End(span, nil)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", incomingTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	server := findSpan(spans, "POST /v1/chat/completions")
+	child := findSpan(spans, "child")
+	if server == nil || child == nil {
+		t.Fatalf("Expected server and child spans, got %d spans", len(spans))
+	}
+
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Server span should continue the incoming trace, got trace %s", got)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Server span should be parented by the caller's span, got %s", got)
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected server span kind, got %v", server.SpanKind)
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("Handler spans should be children of the server span")
+	}
+
+	var status int64
+	for _, attr := range server.Attributes {
+		if attr.Key == "http.response.status_code" {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if status != http.StatusAccepted {
+		t.Errorf("Expected status code attribute 202, got %d", status)
+	}
+}
+
+func TestTransport_InjectsTraceparent(t *testing.T) {
+	recordSpans(t)
+
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	ctx, span := Start(context.Background(), "provider.call")
+	req, _ := http.NewRequestWithContext(ctx, "GET", upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	End(span, nil)
+
+	sc := span.SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if received != want {
+		t.Errorf("Expected traceparent %s, got %q", want, received)
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("Transport must not modify the caller's request")
+	}
+
+	// Requests without an active trace are sent untouched
+	received = ""
+	req, _ = http.NewRequest("GET", upstream.URL, nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if received != "" {
+		t.Errorf("Expected no traceparent without a span, got %q", received)
+	}
+}
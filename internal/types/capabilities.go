@@ -8,10 +8,13 @@ type ProviderCapabilities struct {
 	SupportsParallelFunctions bool                       `json:"supports_parallel_functions"`
 	SupportsVision            bool                       `json:"supports_vision"`
 	SupportsStructuredOutput  bool                       `json:"supports_structured_output"`
+	SupportsJSONMode          bool                       `json:"supports_json_mode"` // best-effort JSON by instruction, not enforced like structured output
 	SupportsStreaming         bool                       `json:"supports_streaming"`
 	SupportsAssistants        bool                       `json:"supports_assistants"`
 	SupportsBatch             bool                       `json:"supports_batch"`
+	SupportsEmbeddings        bool                       `json:"supports_embeddings"`
 	MaxContextWindow          int                        `json:"max_context_window"`
+	MaxStopSequences          int                        `json:"max_stop_sequences,omitempty"` // 0 is unlimited
 	SupportedImageFormats     []string                   `json:"supported_image_formats"`
 	CostPer1KTokens           CostStructure              `json:"cost_per_1k_tokens"`
 	
@@ -21,19 +24,20 @@ type ProviderCapabilities struct {
 }
 
 type ModelInfo struct {
-	Name                 string   `json:"name"`
-	DisplayName          string   `json:"display_name"`
-	MaxContextWindow     int      `json:"max_context_window"`
-	MaxOutputTokens      int      `json:"max_output_tokens"`
-	SupportsFunctions    bool     `json:"supports_functions"`
-	SupportsVision       bool     `json:"supports_vision"`
-	SupportsStructured   bool     `json:"supports_structured_output"`
-	InputCostPer1K       float64  `json:"input_cost_per_1k"`
-	OutputCostPer1K      float64  `json:"output_cost_per_1k"`
+	Name                 string   `json:"name" yaml:"name"`
+	DisplayName          string   `json:"display_name" yaml:"display_name"`
+	MaxContextWindow     int      `json:"max_context_window" yaml:"max_context_window"`
+	MaxOutputTokens      int      `json:"max_output_tokens" yaml:"max_output_tokens"`
+	SupportsFunctions    bool     `json:"supports_functions" yaml:"supports_functions"`
+	SupportsVision       bool     `json:"supports_vision" yaml:"supports_vision"`
+	SupportsStructured   bool     `json:"supports_structured_output" yaml:"supports_structured_output"`
+	InputCostPer1K       float64  `json:"input_cost_per_1k" yaml:"input_cost_per_1k"`
+	OutputCostPer1K      float64  `json:"output_cost_per_1k" yaml:"output_cost_per_1k"`
+	QualityScore         float64  `json:"quality_score,omitempty" yaml:"quality_score,omitempty"` // Relative quality from 0 to 1, zero when unrated
 	
 	// Provider-specific model info
-	ProviderModelID      string   `json:"provider_model_id,omitempty"`
-	Tags                 []string `json:"tags,omitempty"`
+	ProviderModelID      string   `json:"provider_model_id,omitempty" yaml:"provider_model_id,omitempty"`
+	Tags                 []string `json:"tags,omitempty" yaml:"tags,omitempty"`
 }
 
 type CostStructure struct {
@@ -69,11 +73,12 @@ type HealthStatus struct {
 	ResponseTime  int64  `json:"response_time_ms"`
 	LastChecked   int64  `json:"last_checked"`
 	ErrorMessage  string `json:"error_message,omitempty"`
+	Maintenance   bool   `json:"maintenance,omitempty"` // Out of rotation regardless of live health
 }
 
 // Routing configuration
 type RoutingStrategy struct {
-	Type               string             `json:"type"` // "cost_optimized", "performance", "round_robin", "weighted"
+	Type               string             `json:"type"` // "cost_optimized", "performance", "round_robin", "weighted", "sticky"
 	Weights            map[string]float64 `json:"weights,omitempty"`
 	CostThreshold      float64            `json:"cost_threshold,omitempty"`
 	LatencyThreshold   int64              `json:"latency_threshold_ms,omitempty"`
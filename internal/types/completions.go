@@ -0,0 +1,185 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CompletionRequest is a legacy OpenAI text completion request
+type CompletionRequest struct {
+	Model            string          `json:"model"`
+	Prompt           json.RawMessage `json:"prompt"` // string or an array holding one string
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	N                int             `json:"n,omitempty"`
+	Stream           bool            `json:"stream,omitempty"`
+	Stop             json.RawMessage `json:"stop,omitempty"` // string or array of strings
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	User             string          `json:"user,omitempty"`
+
+	// Routing hints and controls, as on chat requests
+	OptimizeFor    OptimizationType `json:"optimize_for,omitempty"`
+	MaxCost        *float64         `json:"max_cost,omitempty"`
+	TimeoutSeconds int              `json:"timeout_seconds,omitempty"`
+	RetryConfig    *RetryConfig     `json:"retry_config,omitempty"`
+	FallbackConfig *FallbackConfig  `json:"fallback_config,omitempty"`
+}
+
+// CompletionResponse is a completion, or a streamed chunk of one, in the
+// legacy text completion format
+type CompletionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []CompletionChoice `json:"choices"`
+	Usage   *Usage             `json:"usage,omitempty"`
+
+	// Routing metadata (added by router)
+	RouterMetadata *RouterMetadata `json:"router_metadata,omitempty"`
+}
+
+// CompletionChoice is one generated text. FinishReason is null on streamed
+// chunks until the last one.
+type CompletionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// ToChatRequest converts a legacy completion request into the router's chat
+// request, sending the prompt as a single user message
+func (r *CompletionRequest) ToChatRequest() (*ChatRequest, error) {
+	if r.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	if r.N > 1 {
+		return nil, fmt.Errorf("n greater than 1 is not supported")
+	}
+
+	prompt, err := decodePrompt(r.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	stop, err := decodeStop(r.Stop)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChatRequest{
+		Model:            r.Model,
+		Messages:         []Message{{Role: "user", Content: prompt}},
+		MaxTokens:        r.MaxTokens,
+		Temperature:      r.Temperature,
+		TopP:             r.TopP,
+		FrequencyPenalty: r.FrequencyPenalty,
+		PresencePenalty:  r.PresencePenalty,
+		Stop:             stop,
+		Stream:           r.Stream,
+		Seed:             r.Seed,
+		UserID:           r.User,
+		OptimizeFor:      r.OptimizeFor,
+		MaxCost:          r.MaxCost,
+		TimeoutSeconds:   r.TimeoutSeconds,
+		RetryConfig:      r.RetryConfig,
+		FallbackConfig:   r.FallbackConfig,
+	}, nil
+}
+
+// decodePrompt accepts a prompt string or an array holding one string.
+// Token-array prompts and batches of prompts aren't supported.
+func decodePrompt(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", fmt.Errorf("prompt is required")
+	}
+
+	var prompt string
+	if err := json.Unmarshal(raw, &prompt); err == nil {
+		return prompt, nil
+	}
+
+	var prompts []string
+	if err := json.Unmarshal(raw, &prompts); err != nil {
+		return "", fmt.Errorf("prompt must be a string or an array of strings")
+	}
+	if len(prompts) != 1 {
+		return "", fmt.Errorf("exactly one prompt is supported, got %d", len(prompts))
+	}
+	return prompts[0], nil
+}
+
+// decodeStop accepts a stop sequence string or an array of them
+func decodeStop(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var stop string
+	if err := json.Unmarshal(raw, &stop); err == nil {
+		return []string{stop}, nil
+	}
+
+	var stops []string
+	if err := json.Unmarshal(raw, &stops); err != nil {
+		return nil, fmt.Errorf("stop must be a string or an array of strings")
+	}
+	return stops, nil
+}
+
+// NewCompletionResponse converts a chat completion into the legacy text
+// completion shape
+func NewCompletionResponse(resp *ChatResponse) *CompletionResponse {
+	out := &CompletionResponse{
+		ID:      resp.ID,
+		Object:  "text_completion",
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: []CompletionChoice{},
+		Usage:   resp.Usage,
+	}
+	for _, choice := range resp.Choices {
+		out.Choices = append(out.Choices, CompletionChoice{
+			Text:         MessageText(choice.Message.Content),
+			Index:        choice.Index,
+			FinishReason: finishReason(choice.FinishReason),
+		})
+	}
+	return out
+}
+
+// NewCompletionChunk converts a streamed chat chunk into a legacy text
+// completion chunk
+func NewCompletionChunk(chunk *ChatChunk) *CompletionResponse {
+	out := &CompletionResponse{
+		ID:      chunk.ID,
+		Object:  "text_completion",
+		Created: chunk.Created,
+		Model:   chunk.Model,
+		Choices: []CompletionChoice{},
+		Usage:   chunk.Usage,
+	}
+	for _, choice := range chunk.Choices {
+		text := ""
+		if choice.Delta != nil {
+			text = MessageText(choice.Delta.Content)
+		}
+		out.Choices = append(out.Choices, CompletionChoice{
+			Text:         text,
+			Index:        choice.Index,
+			FinishReason: finishReason(choice.FinishReason),
+		})
+	}
+	return out
+}
+
+// finishReason returns nil for an empty finish reason so it encodes as null
+func finishReason(reason string) *string {
+	if reason == "" {
+		return nil
+	}
+	return &reason
+}
@@ -0,0 +1,391 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MessagesRequest is a request in Anthropic's Messages API format
+type MessagesRequest struct {
+	Model         string              `json:"model"`
+	Messages      []MessagesMessage   `json:"messages"`
+	System        json.RawMessage     `json:"system,omitempty"` // string or text blocks
+	MaxTokens     int                 `json:"max_tokens"`
+	Metadata      *MessagesMetadata   `json:"metadata,omitempty"`
+	StopSequences []string            `json:"stop_sequences,omitempty"`
+	Stream        bool                `json:"stream,omitempty"`
+	Temperature   *float32            `json:"temperature,omitempty"`
+	TopP          *float32            `json:"top_p,omitempty"`
+	TopK          *int                `json:"top_k,omitempty"`
+	Tools         []MessagesTool      `json:"tools,omitempty"`
+	ToolChoice    *MessagesToolChoice `json:"tool_choice,omitempty"`
+}
+
+// MessagesMessage is a single conversation turn in Anthropic format
+type MessagesMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // string or content blocks
+}
+
+// MessagesMetadata carries Anthropic request metadata
+type MessagesMetadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// MessagesTool is a tool definition in Anthropic format
+type MessagesTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// MessagesToolChoice controls tool use in Anthropic format
+type MessagesToolChoice struct {
+	Type string `json:"type"` // "auto", "any", "tool" or "none"
+	Name string `json:"name,omitempty"`
+}
+
+// ContentBlock is an Anthropic content block in requests and responses
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *ImageSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"` // string or content blocks
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// ImageSource is the source of an Anthropic image block
+type ImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// MessagesResponse is a completion in Anthropic's Messages API format
+type MessagesResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []ContentBlock `json:"content"`
+	StopReason   string         `json:"stop_reason"`
+	StopSequence *string        `json:"stop_sequence"`
+	Usage        MessagesUsage  `json:"usage"`
+
+	// Routing metadata (added by router)
+	RouterMetadata *RouterMetadata `json:"router_metadata,omitempty"`
+}
+
+// MessagesUsage reports token usage in Anthropic format
+type MessagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ToChatRequest converts an Anthropic Messages request into the router's
+// provider-neutral chat request
+func (r *MessagesRequest) ToChatRequest() (*ChatRequest, error) {
+	if r.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	if len(r.Messages) == 0 {
+		return nil, fmt.Errorf("messages must not be empty")
+	}
+	if r.MaxTokens <= 0 {
+		return nil, fmt.Errorf("max_tokens must be positive")
+	}
+
+	maxTokens := r.MaxTokens
+	req := &ChatRequest{
+		Model:       r.Model,
+		MaxTokens:   &maxTokens,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+		Stop:        r.StopSequences,
+		Stream:      r.Stream,
+	}
+	if r.Metadata != nil {
+		req.UserID = r.Metadata.UserID
+	}
+
+	if len(r.System) > 0 {
+		system, err := decodeText(r.System)
+		if err != nil {
+			return nil, fmt.Errorf("invalid system prompt: %w", err)
+		}
+		if system != "" {
+			req.Messages = append(req.Messages, Message{Role: "system", Content: system})
+		}
+	}
+
+	for i, msg := range r.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return nil, fmt.Errorf("messages[%d]: role must be user or assistant", i)
+		}
+		converted, err := convertMessagesMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		req.Messages = append(req.Messages, converted...)
+	}
+
+	for _, tool := range r.Tools {
+		req.Tools = append(req.Tools, Tool{
+			Type: "function",
+			Function: Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	if r.ToolChoice != nil {
+		switch r.ToolChoice.Type {
+		case "auto", "none":
+			req.ToolChoice = r.ToolChoice.Type
+		case "any":
+			req.ToolChoice = "required"
+		case "tool":
+			req.ToolChoice = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": r.ToolChoice.Name},
+			}
+		default:
+			return nil, fmt.Errorf("unsupported tool_choice type %q", r.ToolChoice.Type)
+		}
+	}
+
+	return req, nil
+}
+
+// convertMessagesMessage converts one Anthropic turn into chat messages.
+// Tool results become separate tool-role messages, as providers using the
+// OpenAI message shape expect.
+func convertMessagesMessage(msg MessagesMessage) ([]Message, error) {
+	var text string
+	if err := json.Unmarshal(msg.Content, &text); err == nil {
+		return []Message{{Role: msg.Role, Content: text}}, nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("content must be a string or an array of content blocks")
+	}
+
+	var messages []Message
+	var parts []ContentPart
+	var toolCalls []ToolCall
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, ContentPart{Type: "text", Text: block.Text})
+		case "image":
+			url, err := block.Source.url()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}})
+		case "tool_use":
+			arguments := "{}"
+			if len(block.Input) > 0 {
+				arguments = string(block.Input)
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: Function{Name: block.Name, Arguments: arguments},
+			})
+		case "tool_result":
+			content, err := decodeText(block.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool_result content: %w", err)
+			}
+			if block.IsError {
+				content = "Error: " + content
+			}
+			messages = append(messages, Message{Role: "tool", Content: content, ToolCallID: block.ToolUseID})
+		default:
+			return nil, fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+	}
+
+	if len(parts) == 0 && len(toolCalls) == 0 {
+		return messages, nil
+	}
+
+	converted := Message{Role: msg.Role, ToolCalls: toolCalls}
+	if len(parts) == 1 && parts[0].Type == "text" {
+		converted.Content = parts[0].Text
+	} else if len(parts) > 0 {
+		converted.Content = parts
+	} else {
+		converted.Content = ""
+	}
+	return append(messages, converted), nil
+}
+
+// decodeText flattens a string or a list of text blocks into a string
+func decodeText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return "", fmt.Errorf("expected a string or an array of text blocks")
+	}
+
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != "text" {
+			return "", fmt.Errorf("unsupported content block type %q", block.Type)
+		}
+		texts = append(texts, block.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// url returns the image source as a URL, using a data URL for base64 data
+func (s *ImageSource) url() (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("image block requires a source")
+	}
+	switch s.Type {
+	case "base64":
+		return fmt.Sprintf("data:%s;base64,%s", s.MediaType, s.Data), nil
+	case "url":
+		return s.URL, nil
+	default:
+		return "", fmt.Errorf("unsupported image source type %q", s.Type)
+	}
+}
+
+// NewMessagesResponse converts a chat completion into Anthropic's Messages
+// response shape
+func NewMessagesResponse(resp *ChatResponse) *MessagesResponse {
+	out := &MessagesResponse{
+		ID:      resp.ID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   resp.Model,
+		Content: []ContentBlock{},
+	}
+	if resp.Usage != nil {
+		out.Usage = MessagesUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		}
+	}
+
+	if len(resp.Choices) == 0 {
+		out.StopReason = "end_turn"
+		return out
+	}
+
+	choice := resp.Choices[0]
+	if text := MessageText(choice.Message.Content); text != "" {
+		out.Content = append(out.Content, ContentBlock{Type: "text", Text: text})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		out.Content = append(out.Content, ContentBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: ToolInput(call.Function.Arguments),
+		})
+	}
+	out.StopReason = StopReason(choice.FinishReason)
+	return out
+}
+
+// HasImage reports whether a message content value carries an image,
+// including content parts decoded from JSON as generic maps
+func HasImage(content interface{}) bool {
+	switch c := content.(type) {
+	case []ContentPart:
+		for _, part := range c {
+			if part.Type == "image_url" {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range c {
+			if part, ok := item.(map[string]interface{}); ok && part["type"] == "image_url" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MessageText extracts the text of a message content value, including
+// content parts decoded from JSON as generic maps
+func MessageText(content interface{}) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []ContentPart:
+		var text strings.Builder
+		for _, part := range c {
+			if part.Type == "text" {
+				text.WriteString(part.Text)
+			}
+		}
+		return text.String()
+	case []interface{}:
+		var text strings.Builder
+		for _, item := range c {
+			if part, ok := item.(map[string]interface{}); ok && part["type"] == "text" {
+				partText, _ := part["text"].(string)
+				text.WriteString(partText)
+			}
+		}
+		return text.String()
+	default:
+		return ""
+	}
+}
+
+// ToolInput converts JSON-encoded tool call arguments into a tool_use input
+// object, falling back to an empty object for invalid JSON
+func ToolInput(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// StopReason maps a chat finish reason onto Anthropic's stop_reason values.
+// Reasons that are already in Anthropic form pass through unchanged.
+func StopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "end_turn", "max_tokens", "stop_sequence", "tool_use", "pause_turn", "refusal":
+		return finishReason
+	default:
+		return "end_turn"
+	}
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -22,11 +23,15 @@ type ChatRequest struct {
 	ToolChoice       interface{}            `json:"tool_choice,omitempty"`
 	ResponseFormat   *ResponseFormat        `json:"response_format,omitempty"`
 	Seed             *int                   `json:"seed,omitempty"`
+	N                *int                   `json:"n,omitempty"` // candidate completions to generate, default 1
 	
 	// Routing hints
 	OptimizeFor      OptimizationType       `json:"optimize_for,omitempty"`
 	RequiredFeatures []string               `json:"required_features,omitempty"`
 	MaxCost          *float64               `json:"max_cost,omitempty"`
+	TimeoutSeconds   int                    `json:"timeout_seconds,omitempty"` // capped at router.request_timeout
+	Priority         Priority               `json:"priority,omitempty"` // admission class when near capacity, default normal
+	Explain          bool                   `json:"explain,omitempty"`  // return a RoutingTrace of every candidate in the router metadata
 	
 	// Retry and fallback controls
 	RetryConfig      *RetryConfig           `json:"retry_config,omitempty"`
@@ -34,8 +39,21 @@ type ChatRequest struct {
 	
 	// Metadata
 	UserID           string                 `json:"user_id"`
+	ConversationID   string                 `json:"conversation_id,omitempty"` // pins sticky routing, defaults to user_id
 	ApplicationID    string                 `json:"application_id"`
 	Timestamp        time.Time              `json:"timestamp"`
+	
+	// Tags attribute the request, e.g. to a tenant for billing; they are
+	// returned in router metadata and written to usage records
+	Tags             map[string]string      `json:"tags,omitempty"`
+}
+
+// ChoiceCount returns the number of completions requested, at least 1
+func (r *ChatRequest) ChoiceCount() int {
+	if r.N == nil || *r.N < 1 {
+		return 1
+	}
+	return *r.N
 }
 
 type Message struct {
@@ -70,6 +88,7 @@ type Tool struct {
 }
 
 type ToolCall struct {
+	Index    *int     `json:"index,omitempty"` // Position among parallel calls; set on streamed deltas
 	ID       string   `json:"id"`
 	Type     string   `json:"type"`
 	Function Function `json:"function"`
@@ -94,8 +113,90 @@ const (
 	OptimizeCost        OptimizationType = "cost"
 	OptimizePerformance OptimizationType = "performance"
 	OptimizeQuality     OptimizationType = "quality"
+	OptimizeWeighted    OptimizationType = "weighted"
+	OptimizeSticky      OptimizationType = "sticky"
+	OptimizeBalanced    OptimizationType = "balanced"
+)
+
+// Priority decides which requests are admitted first when the router is
+// near its concurrency limit; low priority requests are shed first
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
 )
 
+// Priorities lists the priority classes, highest first
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// OrDefault returns the priority, or PriorityNormal when unset
+func (p Priority) OrDefault() Priority {
+	if p == "" {
+		return PriorityNormal
+	}
+	return p
+}
+
+// Validate reports an error for anything but an empty or known priority
+func (p Priority) Validate() error {
+	switch p {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return nil
+	}
+	return fmt.Errorf("priority must be %q, %q or %q", PriorityHigh, PriorityNormal, PriorityLow)
+}
+
+// Embedding types
+type EmbeddingRequest struct {
+	ID             string      `json:"id"`
+	Model          string      `json:"model"`
+	Input          interface{} `json:"input"` // string or []string
+	EncodingFormat string      `json:"encoding_format,omitempty"`
+	Dimensions     int         `json:"dimensions,omitempty"`
+	User           string      `json:"user,omitempty"`
+	
+	// Metadata
+	UserID         string      `json:"user_id"`
+	ApplicationID  string      `json:"application_id"`
+	Timestamp      time.Time   `json:"timestamp"`
+}
+
+// Inputs returns the request input as a list of strings
+func (r *EmbeddingRequest) Inputs() ([]string, error) {
+	switch input := r.Input.(type) {
+	case string:
+		return []string{input}, nil
+	case []string:
+		return input, nil
+	case []interface{}:
+		inputs := make([]string, 0, len(input))
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input must be a string or an array of strings")
+			}
+			inputs = append(inputs, text)
+		}
+		return inputs, nil
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+}
+
+// Rerank types
+type RerankRequest struct {
+	ID        string   `json:"id"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n,omitempty"`
+
+	// Metadata
+	UserID    string    `json:"user_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // Batch processing types
 type BatchRequest struct {
 	InputFileID      string `json:"input_file_id"`
@@ -125,6 +226,15 @@ type BatchResponse struct {
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// BatchListResponse is a page of batches, most recent first
+type BatchListResponse struct {
+	Object  string          `json:"object"`
+	Data    []BatchResponse `json:"data"`
+	FirstID string          `json:"first_id,omitempty"`
+	LastID  string          `json:"last_id,omitempty"`
+	HasMore bool            `json:"has_more"`
+}
+
 type BatchError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
@@ -164,16 +274,18 @@ type AssistantResponse struct {
 
 // Retry and fallback control structures
 type RetryConfig struct {
-	MaxAttempts     int           `json:"max_attempts"`               // 0 = no retry, 1-5 allowed  
-	BackoffType     string        `json:"backoff_type"`               // "linear", "exponential"
-	BaseDelay       time.Duration `json:"base_delay"`                 // Starting delay (e.g., 1s)
-	MaxDelay        time.Duration `json:"max_delay"`                  // Cap on delay (e.g., 30s)
-	RetryableErrors []string      `json:"retryable_errors,omitempty"` // Which errors to retry
+	MaxAttempts     int           `json:"max_attempts" yaml:"max_attempts"`                         // 0 = no retry, 1-5 allowed  
+	BackoffType     string        `json:"backoff_type" yaml:"backoff_type"`                         // "linear", "exponential"
+	BaseDelay       time.Duration `json:"base_delay" yaml:"base_delay"`                             // Starting delay (e.g., 1s)
+	MaxDelay        time.Duration `json:"max_delay" yaml:"max_delay"`                               // Cap on delay (e.g., 30s)
+	RetryableErrors []string      `json:"retryable_errors,omitempty" yaml:"retryable_errors"` // Error classes ("429", "5xx", "timeout", "connection") or message substrings to retry
 }
 
 type FallbackConfig struct {
-	Enabled             bool     `json:"enabled"`                          // Enable fallback to healthy providers
-	PreferredChain      []string `json:"preferred_chain,omitempty"`        // Custom fallback order
-	MaxCostIncrease     *float64 `json:"max_cost_increase,omitempty"`      // Max % cost increase allowed (e.g., 0.5 = 50%)
-	RequireSameFeatures bool     `json:"require_same_features"`            // Must support same capabilities
+	Enabled                bool     `json:"enabled"`                           // Enable fallback to healthy providers
+	PreferredChain         []string `json:"preferred_chain,omitempty"`         // Custom fallback order
+	MaxCostIncrease        *float64 `json:"max_cost_increase,omitempty"`       // Max % cost increase allowed (e.g., 0.5 = 50%)
+	RequireSameFeatures    bool     `json:"require_same_features"`             // Must support same capabilities
+	AllowModelSubstitution bool     `json:"allow_model_substitution,omitempty"` // Allow an equivalent model on another provider
+	MinQualityScore        *float64 `json:"min_quality_score,omitempty"`       // Skip fallbacks whose model quality score is lower
 }
\ No newline at end of file
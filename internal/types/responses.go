@@ -61,6 +61,10 @@ type ChatChunk struct {
 	
 	// Routing metadata (added by router)
 	RouterMetadata    *RouterMetadata    `json:"router_metadata,omitempty"`
+	
+	// Err is set on the last chunk of a stream that failed part way; the
+	// chunk carries nothing else
+	Err               error              `json:"-"`
 }
 
 type ChoiceChunk struct {
@@ -83,10 +87,87 @@ type RouterMetadata struct {
 	
 	// Retry and fallback metadata
 	AttemptCount     int      `json:"attempt_count"`                    // How many attempts made (1 = no retries)
-	FailedProviders  []string `json:"failed_providers,omitempty"`      // Providers that failed before success
+	FailedProviders  []string `json:"failed_providers,omitempty"`      // Providers given up on, once each; see AttemptErrors for each attempt
+	AttemptErrors    []string `json:"attempt_errors,omitempty"`        // Error from each failed attempt ("provider: error")
 	FallbackUsed     bool     `json:"fallback_used"`                   // Whether fallback was triggered
 	RetryDelays      []int64  `json:"retry_delays,omitempty"`          // Delay between attempts (ms)
 	TotalRetryTime   int64    `json:"total_retry_time,omitempty"`      // Total time spent on retries (ms)
+	RetryBudgetExhausted bool `json:"retry_budget_exhausted,omitempty"` // Whether retries were skipped by the router-wide retry budget
+	
+	// Cost budget metadata
+	OverBudgetProviders map[string]float64 `json:"over_budget_providers,omitempty"` // Estimated cost of providers rejected for exceeding max_cost
+	
+	// Model substitution metadata
+	ModelSubstituted bool     `json:"model_substituted,omitempty"`     // Whether an equivalent model was used
+	ContextPromoted  bool     `json:"context_promoted,omitempty"`      // Whether a larger-context model replaced one the prompt overflowed
+	OriginalModel    string   `json:"original_model,omitempty"`        // Model originally requested
+	
+	// Model alias metadata
+	ModelAlias string `json:"model_alias,omitempty"` // Logical name the request used; Model holds what it resolved to
+	
+	// Fallback providers skipped for scoring below min_quality_score
+	LowQualityProviders []string `json:"low_quality_providers,omitempty"`
+	
+	// Cache the response was served from: "exact" or "semantic"
+	CacheHit        string  `json:"cache_hit,omitempty"`
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"` // prompt similarity of a semantic hit
+	
+	// Allowlisted headers from the provider's response, keyed by lower-case name
+	UpstreamHeaders map[string]string `json:"upstream_headers,omitempty"`
+	
+	// Why each provider was or wasn't chosen, for requests with explain set
+	RoutingTrace *RoutingTrace `json:"routing_trace,omitempty"`
+	
+	// Request tags, including tenant_id from the X-Tenant-ID header
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// RoutingTrace explains a routing decision candidate by candidate
+type RoutingTrace struct {
+	Strategy   string           `json:"strategy"`
+	Candidates []CandidateTrace `json:"candidates"` // every registered provider, in registration order
+}
+
+// CandidateTrace is one provider's part in a routing decision
+type CandidateTrace struct {
+	Provider             string          `json:"provider"`
+	Health               string          `json:"health"` // healthy, unhealthy, unknown or maintenance
+	EstimatedCost        float64         `json:"estimated_cost"`
+	EstimatedLatencyMs   int64           `json:"estimated_latency_ms"`
+	FeatureCompatibility map[string]bool `json:"feature_compatibility"`
+	Selected             bool            `json:"selected"`
+	ExclusionReason      string          `json:"exclusion_reason,omitempty"` // why an unselected provider lost, e.g. "unhealthy"
+}
+
+type EmbeddingResponse struct {
+	Object         string          `json:"object"`
+	Data           []Embedding     `json:"data"`
+	Model          string          `json:"model"`
+	Usage          *Usage          `json:"usage,omitempty"`
+	
+	// Routing metadata (added by router)
+	RouterMetadata *RouterMetadata `json:"router_metadata,omitempty"`
+}
+
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type RerankResponse struct {
+	Object  string      `json:"object"`
+	Results []RankedDoc `json:"results"`
+
+	// Routing metadata (added by router)
+	RouterMetadata *RouterMetadata `json:"router_metadata,omitempty"`
+}
+
+// RankedDoc is a document scored against a rerank query
+type RankedDoc struct {
+	Index          int     `json:"index"` // Position in the request's documents
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       string  `json:"document"`
 }
 
 type CostEstimate struct {
@@ -0,0 +1,76 @@
+package types
+
+import "sort"
+
+// ToolCallAccumulator reassembles tool calls from streamed deltas. Streams
+// send a call's ID and name first and its arguments as JSON fragments after,
+// and parallel calls may interleave, so fragments are matched to their call
+// by index.
+type ToolCallAccumulator struct {
+	calls     []ToolCall
+	positions map[int]int // delta index -> position in calls
+}
+
+// Add folds the tool call fragments of one streamed delta into the calls.
+// Fragments without an index continue the most recent call unless they
+// carry an ID, which starts a new one.
+func (a *ToolCallAccumulator) Add(deltas []ToolCall) {
+	if a.positions == nil {
+		a.positions = make(map[int]int)
+	}
+
+	for _, delta := range deltas {
+		position := -1
+		switch {
+		case delta.Index != nil:
+			if existing, ok := a.positions[*delta.Index]; ok {
+				position = existing
+			}
+		case delta.ID == "" && len(a.calls) > 0:
+			position = len(a.calls) - 1
+		}
+
+		if position < 0 {
+			index := len(a.calls)
+			if delta.Index != nil {
+				index = *delta.Index
+			}
+			a.calls = append(a.calls, ToolCall{Index: &index})
+			position = len(a.calls) - 1
+			a.positions[index] = position
+		}
+
+		call := &a.calls[position]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+// Len returns the number of tool calls seen so far
+func (a *ToolCallAccumulator) Len() int {
+	return len(a.calls)
+}
+
+// ToolCalls returns the reassembled calls ordered by index. Calls without
+// a type default to "function".
+func (a *ToolCallAccumulator) ToolCalls() []ToolCall {
+	calls := make([]ToolCall, len(a.calls))
+	copy(calls, a.calls)
+	sort.SliceStable(calls, func(i, j int) bool {
+		return *calls[i].Index < *calls[j].Index
+	})
+	for i := range calls {
+		if calls[i].Type == "" {
+			calls[i].Type = "function"
+		}
+	}
+	return calls
+}